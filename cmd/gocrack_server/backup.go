@@ -0,0 +1,154 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/mandiant/gocrack/server"
+	"github.com/mandiant/gocrack/server/backup"
+	"github.com/mandiant/gocrack/server/filemanager"
+	"github.com/mandiant/gocrack/server/storage"
+)
+
+// runBackup writes a snapshot or a portable export of the server's database to a file
+func runBackup(cfg *server.Config, args []string) error {
+	var (
+		out          string
+		snapshot     bool
+		includeFiles bool
+	)
+
+	fs := flag.NewFlagSet("backup", flag.ExitOnError)
+	fs.StringVar(&out, "out", "", "path to write the backup to")
+	fs.BoolVar(&snapshot, "snapshot", false, "copy the database in the storage backend's native format instead of exporting it")
+	fs.BoolVar(&includeFiles, "include-files", false, "include the task & engine files in the export")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s [-config server.yaml] backup -out gocrack.tar.gz [-snapshot] [-include-files]\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if out == "" {
+		fs.Usage()
+		return errors.New("-out is required")
+	}
+
+	stor, err := storage.Open(cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer stor.Close()
+
+	fd, err := os.OpenFile(out, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+
+	if snapshot {
+		err = writeSnapshot(fd, stor)
+	} else {
+		err = writeExport(fd, stor, includeFiles)
+	}
+
+	if cerr := fd.Close(); err == nil {
+		err = cerr
+	}
+
+	if err != nil {
+		os.Remove(out)
+		return err
+	}
+	fmt.Printf("Backup saved to %s\n", out)
+	return nil
+}
+
+func writeSnapshot(fd *os.File, stor storage.Backend) error {
+	snapshotter, ok := stor.(storage.Snapshotter)
+	if !ok {
+		return errors.New("storage backend does not support snapshots")
+	}
+
+	n, err := snapshotter.Snapshot(fd)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Copied %d bytes\n", n)
+	return nil
+}
+
+func writeExport(fd *os.File, stor storage.Backend, includeFiles bool) error {
+	manifest, err := backup.Export(fd, stor, backup.ExportOptions{IncludeFiles: includeFiles})
+	if err != nil {
+		return err
+	}
+
+	printRecordCounts("Exported", manifest.Records)
+	for _, fileID := range manifest.MissingFiles {
+		fmt.Printf("  File %s is missing from disk and was not included\n", fileID)
+	}
+	return nil
+}
+
+// runRestore imports an export into the server's database. The server must not be running
+func runRestore(cfg *server.Config, args []string) error {
+	var (
+		in        string
+		skipFiles bool
+	)
+
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	fs.StringVar(&in, "in", "", "path to the export to restore")
+	fs.BoolVar(&skipFiles, "skip-files", false, "do not restore the task & engine files included in the export")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s [-config server.yaml] restore -in gocrack.tar.gz [-skip-files]\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if in == "" {
+		fs.Usage()
+		return errors.New("-in is required")
+	}
+
+	fd, err := os.Open(in)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+
+	stor, err := storage.Open(cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer stor.Close()
+
+	var opts backup.RestoreOptions
+	if !skipFiles {
+		if err = cfg.FileManager.Validate(); err != nil {
+			return err
+		}
+		opts.FileManager = filemanager.New(stor, cfg.FileManager)
+	}
+
+	report, err := backup.Restore(fd, stor, opts)
+	if err != nil {
+		return err
+	}
+
+	printRecordCounts("Restored", report.Records)
+	fmt.Printf("  %d file(s)\n", report.Files)
+	return nil
+}
+
+func printRecordCounts(verb string, records map[storage.RecordType]int) {
+	fmt.Printf("%s:\n", verb)
+	for _, rt := range storage.RecordTypes {
+		fmt.Printf("  %d %s record(s)\n", records[rt], rt)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/mandiant/gocrack/server"
+	"github.com/mandiant/gocrack/server/storage"
+)
+
+// runGarbageCollection reports the documents left behind by deleted tasks & files and optionally removes them
+func runGarbageCollection(cfg *server.Config, args []string) error {
+	var remove bool
+
+	fs := flag.NewFlagSet("gc", flag.ExitOnError)
+	fs.BoolVar(&remove, "remove", false, "remove the orphaned documents instead of only reporting them")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s [-config server.yaml] gc [-remove]\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	stor, err := storage.Open(cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer stor.Close()
+
+	gc, ok := stor.(storage.GarbageCollector)
+	if !ok {
+		return fmt.Errorf("storage backend %s does not support garbage collection", cfg.Database.Backend)
+	}
+
+	report, err := gc.CollectGarbage(!remove)
+	if err != nil {
+		return err
+	}
+
+	if report.Total() == 0 {
+		fmt.Println("No orphaned documents were found")
+		return nil
+	}
+
+	verb := "Removed"
+	if report.DryRun {
+		verb = "Found"
+	}
+	printRecordCounts(fmt.Sprintf("%s %d orphaned document(s)", verb, report.Total()), report.Orphans)
+	return nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/mandiant/gocrack/server"
+	"github.com/mandiant/gocrack/server/storage"
+)
+
+// runMigrate upgrades the database of the server to the latest schema and prints what was (or would be) changed
+func runMigrate(cfg *server.Config, args []string) error {
+	var dryRun bool
+
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	fs.BoolVar(&dryRun, "dry-run", false, "report the migrations that would be applied without modifying the database")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s [-config server.yaml] migrate [-dry-run]\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	dbcfg := cfg.Database
+	dbcfg.SkipMigrations = true
+
+	stor, err := storage.Open(dbcfg)
+	if err != nil {
+		return err
+	}
+	defer stor.Close()
+
+	migrator, ok := stor.(storage.Migrator)
+	if !ok {
+		return fmt.Errorf("storage backend %s does not support migrations", dbcfg.Backend)
+	}
+
+	report, err := migrator.Migrate(dryRun)
+	if err != nil {
+		return err
+	}
+	printMigrationReport(report)
+	return nil
+}
+
+func printMigrationReport(report *storage.MigrationReport) {
+	if len(report.Applied) == 0 {
+		fmt.Printf("Database is up to date at version %d\n", report.ToVersion)
+		return
+	}
+
+	verb := "Migrated"
+	if report.DryRun {
+		verb = "Would migrate"
+	}
+	fmt.Printf("%s database from version %d to %d\n", verb, report.FromVersion, report.ToVersion)
+
+	for _, m := range report.Applied {
+		fmt.Printf("  %d: %s\n", m.Version, m.Description)
+
+		buckets := make([]string, 0, len(m.Changes))
+		for bucket := range m.Changes {
+			buckets = append(buckets, bucket)
+		}
+		sort.Strings(buckets)
+
+		for _, bucket := range buckets {
+			fmt.Printf("      %s: %d document(s)\n", bucket, m.Changes[bucket])
+		}
+	}
+
+	if report.BackupPath != "" {
+		fmt.Printf("A backup of the database was saved to %s\n", report.BackupPath)
+	}
+}
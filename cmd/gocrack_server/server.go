@@ -47,6 +47,29 @@ func main() {
 		log.Logger = logger
 	}
 
+	switch flag.Arg(0) {
+	case "migrate":
+		if err := runMigrate(&cfg, flag.Args()[1:]); err != nil {
+			log.Fatal().Err(err).Msg("Failed to migrate database")
+		}
+		return
+	case "backup":
+		if err := runBackup(&cfg, flag.Args()[1:]); err != nil {
+			log.Fatal().Err(err).Msg("Failed to backup database")
+		}
+		return
+	case "restore":
+		if err := runRestore(&cfg, flag.Args()[1:]); err != nil {
+			log.Fatal().Err(err).Msg("Failed to restore database")
+		}
+		return
+	case "gc":
+		if err := runGarbageCollection(&cfg, flag.Args()[1:]); err != nil {
+			log.Fatal().Err(err).Msg("Failed to collect orphaned documents")
+		}
+		return
+	}
+
 	svr, err := server.New(&cfg)
 	if err != nil {
 		log.Fatal().Err(err).Msg("Failed to initialize server")
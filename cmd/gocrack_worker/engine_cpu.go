@@ -0,0 +1,3 @@
+package main
+
+import _ "github.com/mandiant/gocrack/worker/engines/cpu"
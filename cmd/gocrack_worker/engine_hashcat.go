@@ -0,0 +1,6 @@
+//go:build !no_hashcat
+// +build !no_hashcat
+
+package main
+
+import _ "github.com/mandiant/gocrack/worker/engines/hashcat"
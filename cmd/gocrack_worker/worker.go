@@ -23,6 +23,7 @@ import (
 var (
 	configPath   string
 	taskID       string
+	unitID       string
 	devicesToUse string
 	isWorker     bool
 	profile      bool
@@ -33,6 +34,7 @@ func init() {
 	flag.StringVar(&configPath, "config", "configs/worker.yaml", "path to configuration file")
 	flag.BoolVar(&isWorker, "worker", false, "Spawn an instance of the worker process in a child mode. DO NOT USE THIS")
 	flag.StringVar(&taskID, "taskid", "", "The Task ID to request for processing. Should only be used in worker mode")
+	flag.StringVar(&unitID, "unit", "", "The work unit of a distributed task to process. Should only be used in worker mode")
 	flag.StringVar(&devicesToUse, "devices", "", "Which devices to use for the task? Should only be used in worker mode")
 	flag.BoolVar(&profile, "profile", false, "enable pprof? should only be used in development")
 	flag.BoolVar(&debug, "debug", false, "enable debug logging")
@@ -73,7 +75,7 @@ func main() {
 			}
 			tmp = append(tmp, deviceID)
 		}
-		impl = child.New(&cfg, taskID, tmp)
+		impl = child.New(&cfg, taskID, unitID, tmp)
 	} else {
 		impl = parent.New(&cfg)
 	}
@@ -9,6 +9,7 @@ require (
 	github.com/google/uuid v1.3.0
 	github.com/gorilla/csrf v1.7.0
 	github.com/mandiant/gocat/v6 v6.1.0
+	github.com/mattn/go-sqlite3 v1.14.16
 	github.com/nightlyone/lockfile v0.0.0-20170804114028-6a197d5ea611
 	github.com/prometheus/client_golang v1.8.0
 	github.com/prometheus/client_model v0.2.0
@@ -18,6 +19,7 @@ require (
 	github.com/tankbusta/gzip v0.0.0-20171023233440-5ea045a82e8f
 	github.com/tankbusta/hashvalidate v0.11.1
 	github.com/tchap/go-exchange v0.0.0-20141009085351-ebe3feb493da
+	go.etcd.io/bbolt v1.3.3
 	golang.org/x/crypto v0.0.0-20211209193657-4570a0811e8b
 	gopkg.in/gomail.v2 v2.0.0-20160411212932-81ebce5c23df
 	gopkg.in/ldap.v2 v2.5.1
@@ -54,7 +56,6 @@ require (
 	github.com/tchap/go-patricia v2.3.0+incompatible // indirect
 	github.com/ugorji/go/codec v1.2.6 // indirect
 	github.com/vmihailenco/msgpack v4.0.4+incompatible // indirect
-	go.etcd.io/bbolt v1.3.3 // indirect
 	golang.org/x/sys v0.0.0-20211213223007-03aa0b5f6827 // indirect
 	golang.org/x/text v0.3.7 // indirect
 	google.golang.org/appengine v1.6.1 // indirect
@@ -0,0 +1,276 @@
+// Package analytics computes pipal style statistics from the cracked passwords of one or more tasks, evaluates
+// them against password policies and derives the masks & rules of follow up attacks from them
+package analytics
+
+import (
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// DefaultTop is the number of entries kept in each of the ranked lists of a report
+	DefaultTop = 10
+	// maxCurvePoints is the most points the crack time curve is split into
+	maxCurvePoints = 50
+	// minBaseWordLength is the shortest base word that is counted. Shorter words are mostly noise (e.g. "a1")
+	minBaseWordLength = 3
+)
+
+// Entry is a cracked password along with the number of accounts that use it
+type Entry struct {
+	Value     string
+	CrackedAt time.Time
+	// Accounts is the number of accounts whose hash cracked to the password. Task files without usernames count as 1
+	Accounts int
+}
+
+// Count is the number of passwords that share a value
+type Count struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// LengthCount is the number of passwords of a length
+type LengthCount struct {
+	Length int `json:"length"`
+	Count  int `json:"count"`
+}
+
+// CurvePoint is the number of passwords cracked by the end of an interval
+type CurvePoint struct {
+	At         time.Time `json:"at"`
+	Cracked    int       `json:"cracked"`
+	Cumulative int       `json:"cumulative"`
+}
+
+// Report is the analysis of a list of cracked passwords
+type Report struct {
+	Total         int           `json:"total"`
+	Unique        int           `json:"unique"`
+	Lengths       []LengthCount `json:"lengths"`
+	Charsets      []Count       `json:"charsets"`
+	Masks         []Count       `json:"masks"`
+	BaseWords     []Count       `json:"base_words"`
+	DigitSuffixes []Count       `json:"digit_suffixes"`
+	YearSuffixes  []Count       `json:"year_suffixes"`
+	// Reused are the passwords used by more than one account, ranked by the number of accounts
+	Reused         []Count      `json:"reused"`
+	ReusedAccounts int          `json:"reused_accounts"`
+	CrackCurve     []CurvePoint `json:"crack_curve"`
+}
+
+// Analyze builds a report from the cracked passwords keeping the top most common values of each ranked list
+func Analyze(entries []Entry, top int) *Report {
+	if top <= 0 {
+		top = DefaultTop
+	}
+
+	var (
+		lengths   = make(map[int]int)
+		charsets  = make(map[string]int)
+		masks     = make(map[string]int)
+		baseWords = make(map[string]int)
+		digits    = make(map[string]int)
+		years     = make(map[string]int)
+		accounts  = make(map[string]int)
+	)
+
+	report := &Report{Total: len(entries), Lengths: make([]LengthCount, 0)}
+	for _, entry := range entries {
+		value := entry.Value
+
+		lengths[utf8.RuneCountInString(value)]++
+		charsets[Charset(value)]++
+		masks[Mask(value)]++
+
+		if word := BaseWord(value); word != "" {
+			baseWords[word]++
+		}
+
+		if suffix := DigitSuffix(value); suffix != "" {
+			digits[suffix]++
+			if isYear(suffix) {
+				years[suffix[len(suffix)-4:]]++
+			}
+		}
+
+		n := entry.Accounts
+		if n <= 0 {
+			n = 1
+		}
+		accounts[value] += n
+	}
+
+	report.Unique = len(accounts)
+	for length, n := range lengths {
+		report.Lengths = append(report.Lengths, LengthCount{Length: length, Count: n})
+	}
+	sort.Slice(report.Lengths, func(i, j int) bool { return report.Lengths[i].Length < report.Lengths[j].Length })
+
+	report.Charsets = rank(charsets, top, 1)
+	report.Masks = rank(masks, top, 1)
+	report.BaseWords = rank(baseWords, top, 1)
+	report.DigitSuffixes = rank(digits, top, 1)
+	report.YearSuffixes = rank(years, top, 1)
+	report.Reused = rank(accounts, top, 2)
+	for _, n := range accounts {
+		if n > 1 {
+			report.ReusedAccounts += n
+		}
+	}
+
+	report.CrackCurve = crackCurve(entries)
+	return report
+}
+
+// rank returns the top values with at least min occurrences sorted by the most common first
+func rank(counts map[string]int, top, min int) []Count {
+	out := make([]Count, 0, len(counts))
+	for value, n := range counts {
+		if n >= min {
+			out = append(out, Count{Value: value, Count: n})
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Value < out[j].Value
+	})
+
+	if len(out) > top {
+		out = out[:top]
+	}
+	return out
+}
+
+// Charset names the classes of characters in the password (e.g. "lower+digit")
+func Charset(value string) string {
+	classes := characterClasses(value)
+	if len(classes) == 0 {
+		return "empty"
+	}
+	return strings.Join(classes, "+")
+}
+
+// characterClasses returns the classes of characters in the password in the order of Classes
+func characterClasses(value string) []string {
+	var lower, upper, digit, special bool
+	for _, r := range value {
+		switch {
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= '0' && r <= '9':
+			digit = true
+		default:
+			special = true
+		}
+	}
+
+	classes := make([]string, 0, len(Classes))
+	for i, set := range []bool{lower, upper, digit, special} {
+		if set {
+			classes = append(classes, Classes[i])
+		}
+	}
+	return classes
+}
+
+// Mask returns the hashcat mask that matches the password. Bytes outside of printable ASCII are ?b
+func Mask(value string) string {
+	var sb strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case c >= 'a' && c <= 'z':
+			sb.WriteString("?l")
+		case c >= 'A' && c <= 'Z':
+			sb.WriteString("?u")
+		case c >= '0' && c <= '9':
+			sb.WriteString("?d")
+		case c >= 0x20 && c <= 0x7e:
+			sb.WriteString("?s")
+		default:
+			sb.WriteString("?b")
+		}
+	}
+	return sb.String()
+}
+
+// BaseWord returns the lower cased password without the non-letters around it (e.g. "Summer2023!" is "summer")
+func BaseWord(value string) string {
+	isLetter := func(r rune) bool {
+		return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+	}
+
+	word := strings.TrimFunc(value, func(r rune) bool { return !isLetter(r) })
+	if len(word) < minBaseWordLength {
+		return ""
+	}
+	return strings.ToLower(word)
+}
+
+// DigitSuffix returns the digits the password ends with if it does not consist only of digits
+func DigitSuffix(value string) string {
+	i := len(value)
+	for i > 0 && value[i-1] >= '0' && value[i-1] <= '9' {
+		i--
+	}
+
+	if i == 0 || i == len(value) {
+		return ""
+	}
+	return value[i:]
+}
+
+// isYear returns true if the digits end with a year between 1950 & 2099
+func isYear(digits string) bool {
+	if len(digits) < 4 {
+		return false
+	}
+
+	year := digits[len(digits)-4:]
+	return (year[:2] == "19" && year[2] >= '5') || year[:2] == "20"
+}
+
+// crackCurve splits the time between the first & last crack into intervals of whole minutes and counts the passwords
+// cracked within each of them
+func crackCurve(entries []Entry) []CurvePoint {
+	if len(entries) == 0 {
+		return []CurvePoint{}
+	}
+
+	times := make([]time.Time, len(entries))
+	for i, entry := range entries {
+		times[i] = entry.CrackedAt.UTC()
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+
+	start := times[0].Truncate(time.Minute)
+	span := times[len(times)-1].Sub(start)
+
+	interval := time.Minute
+	if perPoint := span / maxCurvePoints; perPoint > interval {
+		interval = (perPoint + time.Minute - 1).Truncate(time.Minute)
+	}
+
+	points := make([]CurvePoint, 0)
+	cumulative := 0
+	for end := start.Add(interval); ; end = end.Add(interval) {
+		cracked := 0
+		for cumulative+cracked < len(times) && times[cumulative+cracked].Before(end) {
+			cracked++
+		}
+		cumulative += cracked
+		points = append(points, CurvePoint{At: end, Cracked: cracked, Cumulative: cumulative})
+
+		if cumulative == len(times) {
+			return points
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnalyze(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 30, 0, time.UTC)
+	report := Analyze([]Entry{
+		{Value: "Summer2023!", CrackedAt: start, Accounts: 3},
+		{Value: "summer2023", CrackedAt: start.Add(20 * time.Second), Accounts: 1},
+		{Value: "password1", CrackedAt: start.Add(time.Minute)},
+		{Value: "123456", CrackedAt: start.Add(3 * time.Minute)},
+	}, 2)
+
+	assert.Equal(t, 4, report.Total)
+	assert.Equal(t, 4, report.Unique)
+	assert.Equal(t, []LengthCount{{6, 1}, {9, 1}, {10, 1}, {11, 1}}, report.Lengths)
+	assert.Equal(t, []Count{{"lower+digit", 2}, {"digit", 1}}, report.Charsets)
+	assert.Equal(t, []Count{{"summer", 2}, {"password", 1}}, report.BaseWords)
+	assert.Equal(t, []Count{{"1", 1}, {"2023", 1}}, report.DigitSuffixes)
+	assert.Equal(t, []Count{{"2023", 1}}, report.YearSuffixes)
+	assert.Equal(t, []Count{{"Summer2023!", 3}}, report.Reused)
+	assert.Equal(t, 3, report.ReusedAccounts)
+	assert.Equal(t, []CurvePoint{
+		{At: start.Truncate(time.Minute).Add(time.Minute), Cracked: 2, Cumulative: 2},
+		{At: start.Truncate(time.Minute).Add(2 * time.Minute), Cracked: 1, Cumulative: 3},
+		{At: start.Truncate(time.Minute).Add(3 * time.Minute), Cracked: 0, Cumulative: 3},
+		{At: start.Truncate(time.Minute).Add(4 * time.Minute), Cracked: 1, Cumulative: 4},
+	}, report.CrackCurve)
+}
+
+func TestAnalyzeEmpty(t *testing.T) {
+	report := Analyze(nil, 0)
+	assert.Equal(t, 0, report.Total)
+	assert.Empty(t, report.Lengths)
+	assert.Empty(t, report.CrackCurve)
+}
+
+func TestMask(t *testing.T) {
+	assert.Equal(t, "?u?l?l?d?s", Mask("Abc1!"))
+	assert.Equal(t, "?l?b?b", Mask("aé"))
+}
+
+func TestCrackCurveInterval(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	entries := []Entry{{CrackedAt: start}, {CrackedAt: start.Add(100 * time.Hour)}}
+
+	points := crackCurve(entries)
+	assert.LessOrEqual(t, len(points), maxCurvePoints+1)
+	assert.Equal(t, 2*time.Hour, points[1].At.Sub(points[0].At))
+	assert.Equal(t, 2, points[len(points)-1].Cumulative)
+}
+
+func TestSuffixes(t *testing.T) {
+	assert.Equal(t, "", DigitSuffix("123456"))
+	assert.Equal(t, "99", DigitSuffix("abc99"))
+	assert.True(t, isYear("1987"))
+	assert.True(t, isYear("012024"))
+	assert.False(t, isYear("1234"))
+	assert.Equal(t, "", BaseWord("a1"))
+}
@@ -0,0 +1,221 @@
+package analytics
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The orders masks can be generated in
+const (
+	// MaskOrderEfficiency ranks masks by the passwords they cracked per candidate in their keyspace
+	MaskOrderEfficiency = "efficiency"
+	// MaskOrderFrequency ranks masks by the passwords they cracked
+	MaskOrderFrequency = "frequency"
+)
+
+// maxTogglePosition is the last position hashcat's T rule function can address (0-9 & A-Z)
+const maxTogglePosition = 35
+
+// leetSubstitutions are the characters commonly swapped for a letter
+var leetSubstitutions = map[byte]byte{
+	'@': 'a',
+	'4': 'a',
+	'3': 'e',
+	'1': 'i',
+	'!': 'i',
+	'0': 'o',
+	'$': 's',
+	'5': 's',
+	'7': 't',
+}
+
+// maskCharsetSizes is the number of candidates of every built in hashcat charset Mask emits
+var maskCharsetSizes = map[byte]float64{
+	'l': 26,
+	'u': 26,
+	'd': 10,
+	's': 33,
+	'b': 256,
+}
+
+// MaskKeyspace returns the number of candidates of a mask made of hashcat's built in charsets
+func MaskKeyspace(mask string) float64 {
+	keyspace := 1.0
+	for i := 1; i < len(mask); i += 2 {
+		keyspace *= maskCharsetSizes[mask[i]]
+	}
+	return keyspace
+}
+
+// GenerateMasks returns the masks of the passwords in the order they should be attacked. limit caps the number of
+// masks returned if it's above 0
+func GenerateMasks(entries []Entry, limit int, order string) []Count {
+	counts := make(map[string]int)
+	for _, entry := range entries {
+		if entry.Value != "" {
+			counts[Mask(entry.Value)]++
+		}
+	}
+
+	masks := rank(counts, len(counts), 1)
+	if order == MaskOrderEfficiency {
+		efficiency := func(c Count) float64 {
+			return float64(c.Count) / MaskKeyspace(c.Value)
+		}
+
+		sort.SliceStable(masks, func(i, j int) bool {
+			return efficiency(masks[i]) > efficiency(masks[j])
+		})
+	}
+
+	if limit > 0 && len(masks) > limit {
+		masks = masks[:limit]
+	}
+	return masks
+}
+
+// GenerateRules learns the hashcat rules that turn the base words of the passwords into the passwords and returns them
+// by the most common first. limit caps the number of rules returned if it's above 0
+func GenerateRules(entries []Entry, limit int) []Count {
+	counts := make(map[string]int)
+	for _, entry := range entries {
+		if _, rule, ok := Rule(entry.Value); ok {
+			counts[rule]++
+		}
+	}
+
+	if limit <= 0 {
+		limit = len(counts)
+	}
+	return rank(counts, limit, 1)
+}
+
+// Rule returns the dictionary word the password is based on and the hashcat rule that turns it into the password.
+// For example "P@ssw0rd1!" is "password" with the rule "sa@ so0 c $1 $!". ok is false if the password is not based on
+// a word or can't be expressed by the supported rule functions
+func Rule(value string) (word, rule string, ok bool) {
+	isLetter := func(c byte) bool {
+		return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+	}
+
+	// the word starts at the first letter and ends at the last one. Leet substitutions within it are undone
+	start, end := 0, len(value)
+	for start < end && !isLetter(value[start]) {
+		start++
+	}
+	for end > start && !isLetter(value[end-1]) {
+		end--
+	}
+
+	if end-start < minBaseWordLength || end-start-1 > maxTogglePosition {
+		return "", "", false
+	}
+
+	for i := 0; i < len(value); i++ {
+		if value[i] < 0x20 || value[i] > 0x7e {
+			return "", "", false
+		}
+	}
+
+	core := value[start:end]
+	subs := make(map[byte]byte)
+	buf := []byte(strings.ToLower(core))
+	for i := range buf {
+		if isLetter(buf[i]) {
+			continue
+		}
+
+		letter, ok := leetSubstitutions[buf[i]]
+		if !ok {
+			return "", "", false
+		}
+
+		if prev, seen := subs[letter]; seen && prev != buf[i] {
+			return "", "", false
+		}
+		subs[letter] = buf[i]
+		buf[i] = letter
+	}
+	word = string(buf)
+
+	var funcs []string
+	letters := make([]byte, 0, len(subs))
+	for letter := range subs {
+		letters = append(letters, letter)
+	}
+	sort.Slice(letters, func(i, j int) bool { return letters[i] < letters[j] })
+	for _, letter := range letters {
+		funcs = append(funcs, "s"+string(letter)+string(subs[letter]))
+	}
+
+	substituted := applyRule(word, funcs)
+	switch core {
+	case substituted:
+	case strings.ToUpper(substituted[:1]) + substituted[1:]:
+		funcs = append(funcs, "c")
+	case strings.ToUpper(substituted):
+		funcs = append(funcs, "u")
+	default:
+		for i := 0; i < len(core); i++ {
+			if core[i] >= 'A' && core[i] <= 'Z' {
+				funcs = append(funcs, "T"+strings.ToUpper(strconv.FormatInt(int64(i), 36)))
+			}
+		}
+	}
+
+	for i := end; i < len(value); i++ {
+		funcs = append(funcs, "$"+value[i:i+1])
+	}
+
+	for i := start - 1; i >= 0; i-- {
+		funcs = append(funcs, "^"+value[i:i+1])
+	}
+
+	// substitutions replace every occurrence of a letter so words that only swap some of them can't be expressed
+	if applyRule(word, funcs) != value {
+		return "", "", false
+	}
+
+	if len(funcs) == 0 {
+		return word, ":", true
+	}
+	return word, strings.Join(funcs, " "), true
+}
+
+// applyRule applies the rule functions that Rule generates to the word
+func applyRule(word string, funcs []string) string {
+	buf := []byte(word)
+	for _, fn := range funcs {
+		switch fn[0] {
+		case 's':
+			for i := range buf {
+				if buf[i] == fn[1] {
+					buf[i] = fn[2]
+				}
+			}
+		case 'c':
+			buf = []byte(strings.ToLower(string(buf)))
+			if len(buf) > 0 {
+				buf[0] = strings.ToUpper(string(buf[:1]))[0]
+			}
+		case 'u':
+			buf = []byte(strings.ToUpper(string(buf)))
+		case 'T':
+			pos, _ := strconv.ParseInt(fn[1:], 36, 0)
+			if int(pos) < len(buf) {
+				switch c := buf[pos]; {
+				case c >= 'a' && c <= 'z':
+					buf[pos] = c - 'a' + 'A'
+				case c >= 'A' && c <= 'Z':
+					buf[pos] = c - 'A' + 'a'
+				}
+			}
+		case '$':
+			buf = append(buf, fn[1])
+		case '^':
+			buf = append([]byte{fn[1]}, buf...)
+		}
+	}
+	return string(buf)
+}
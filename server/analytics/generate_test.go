@@ -0,0 +1,87 @@
+package analytics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRule(t *testing.T) {
+	for _, test := range []struct {
+		value string
+		word  string
+		rule  string
+		ok    bool
+	}{
+		{"password", "password", ":", true},
+		{"Password1", "password", "c $1", true},
+		{"P@ssw0rd1!", "password", "sa@ so0 c $1 $!", true},
+		{"SUMMER2023", "summer", "u $2 $0 $2 $3", true},
+		{"!!football", "football", "^! ^!", true},
+		{"monKey", "monkey", "T3", true},
+		{"b@nana", "", "", false},
+		{"123456", "", "", false},
+		{"foo-bar", "", "", false},
+		{"caf\xc3\xa9", "", "", false},
+	} {
+		word, rule, ok := Rule(test.value)
+		assert.Equal(t, test.ok, ok, test.value)
+		assert.Equal(t, test.word, word, test.value)
+		assert.Equal(t, test.rule, rule, test.value)
+
+		if ok {
+			assert.Equal(t, test.value, applyRule(word, splitRule(rule)), test.value)
+		}
+	}
+}
+
+func splitRule(rule string) []string {
+	if rule == ":" {
+		return nil
+	}
+
+	var funcs []string
+	for i := 0; i < len(rule); {
+		n := 1
+		switch rule[i] {
+		case ' ':
+			i++
+			continue
+		case '$', '^', 'T':
+			n = 2
+		case 's':
+			n = 3
+		}
+		funcs = append(funcs, rule[i:i+n])
+		i += n
+	}
+	return funcs
+}
+
+func TestGenerateMasks(t *testing.T) {
+	entries := []Entry{
+		{Value: "password"},
+		{Value: "monkeys1"},
+		{Value: "sunshine"},
+		{Value: "1234"},
+	}
+
+	assert.Equal(t, []Count{{"?l?l?l?l?l?l?l?l", 2}, {"?d?d?d?d", 1}, {"?l?l?l?l?l?l?l?d", 1}},
+		GenerateMasks(entries, 0, MaskOrderFrequency))
+	assert.Equal(t, []Count{{"?d?d?d?d", 1}, {"?l?l?l?l?l?l?l?d", 1}},
+		GenerateMasks(entries, 2, MaskOrderEfficiency))
+}
+
+func TestGenerateRules(t *testing.T) {
+	assert.Equal(t, []Count{{"c $1", 2}, {":", 1}}, GenerateRules([]Entry{
+		{Value: "Password1"},
+		{Value: "Monkey1"},
+		{Value: "sunshine"},
+		{Value: "1234"},
+	}, 0))
+}
+
+func TestMaskKeyspace(t *testing.T) {
+	assert.Equal(t, float64(26*10*33), MaskKeyspace("?l?d?s"))
+	assert.Equal(t, float64(1), MaskKeyspace(""))
+}
@@ -0,0 +1,134 @@
+package analytics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mandiant/gocrack/server/storage"
+)
+
+// The character classes a password policy may require
+const (
+	ClassLower   = "lower"
+	ClassUpper   = "upper"
+	ClassDigit   = "digit"
+	ClassSpecial = "special"
+)
+
+// Classes contains every character class
+var Classes = []string{ClassLower, ClassUpper, ClassDigit, ClassSpecial}
+
+var classDescriptions = map[string]string{
+	ClassLower:   "a lower case letter",
+	ClassUpper:   "an upper case letter",
+	ClassDigit:   "a digit",
+	ClassSpecial: "a special character",
+}
+
+// ValidatePolicy returns a list of user friendly errors with the rules of the policy
+func ValidatePolicy(policy storage.PasswordPolicy) []string {
+	errs := make([]string, 0)
+
+	if policy.MinLength < 0 {
+		errs = append(errs, "min_length cannot be negative")
+	}
+
+	if policy.MinClasses < 0 || policy.MinClasses > len(Classes) {
+		errs = append(errs, fmt.Sprintf("min_classes must be between 0 and %d", len(Classes)))
+	}
+
+	if policy.MaxRepeatedChars < 0 {
+		errs = append(errs, "max_repeated_chars cannot be negative")
+	}
+
+	for _, class := range policy.RequiredClasses {
+		if _, ok := classDescriptions[class]; !ok {
+			errs = append(errs, fmt.Sprintf("required_classes: `%s` is not one of %s", class, strings.Join(Classes, ", ")))
+		}
+	}
+	return errs
+}
+
+// BannedWordCandidates returns the words of the password that are looked up in the banned word lists of a policy.
+// A password is banned if it's a banned word or its base word is (e.g. "Summer2023!" if "summer" is banned)
+func BannedWordCandidates(value string) []string {
+	candidates := []string{strings.ToLower(value)}
+	if word := BaseWord(value); word != "" && word != candidates[0] {
+		candidates = append(candidates, word)
+	}
+	return candidates
+}
+
+// FindWords reads a word list one word per line and adds the words that are within candidates to found.
+// Words are compared case insensitively so the candidates must be lower case
+func FindWords(r io.Reader, candidates, found map[string]bool) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		word := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if candidates[word] {
+			found[word] = true
+		}
+	}
+	return scanner.Err()
+}
+
+// EvaluatePolicy returns the reasons the password violates the policy. banned contains the lower cased words of the
+// policy's word lists that the password may be based on (see BannedWordCandidates)
+func EvaluatePolicy(policy storage.PasswordPolicy, value string, banned map[string]bool) []string {
+	reasons := make([]string, 0)
+
+	if policy.MinLength > 0 && utf8.RuneCountInString(value) < policy.MinLength {
+		reasons = append(reasons, fmt.Sprintf("shorter than %d characters", policy.MinLength))
+	}
+
+	classes := characterClasses(value)
+	has := make(map[string]bool, len(classes))
+	for _, class := range classes {
+		has[class] = true
+	}
+
+	for _, class := range policy.RequiredClasses {
+		if !has[class] {
+			reasons = append(reasons, "missing "+classDescriptions[class])
+		}
+	}
+
+	if policy.MinClasses > 0 && len(classes) < policy.MinClasses {
+		reasons = append(reasons, fmt.Sprintf("contains %d of the %d required character classes", len(classes), policy.MinClasses))
+	}
+
+	if policy.MaxRepeatedChars > 0 && longestRun(value) > policy.MaxRepeatedChars {
+		reasons = append(reasons, fmt.Sprintf("repeats a character more than %d times in a row", policy.MaxRepeatedChars))
+	}
+
+	for _, word := range BannedWordCandidates(value) {
+		if banned[word] {
+			reasons = append(reasons, fmt.Sprintf("based on the banned word `%s`", word))
+			break
+		}
+	}
+	return reasons
+}
+
+// longestRun returns the length of the longest run of a single character
+func longestRun(value string) int {
+	var longest, run int
+	var prev rune = -1
+
+	for _, r := range value {
+		if r == prev {
+			run++
+		} else {
+			run = 1
+			prev = r
+		}
+
+		if run > longest {
+			longest = run
+		}
+	}
+	return longest
+}
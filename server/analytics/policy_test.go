@@ -0,0 +1,51 @@
+package analytics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mandiant/gocrack/server/storage"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidatePolicy(t *testing.T) {
+	assert.Empty(t, ValidatePolicy(storage.PasswordPolicy{MinLength: 8, MinClasses: 3, RequiredClasses: []string{ClassDigit}}))
+	assert.Len(t, ValidatePolicy(storage.PasswordPolicy{MinLength: -1, MinClasses: 5, MaxRepeatedChars: -1, RequiredClasses: []string{"emoji"}}), 4)
+}
+
+func TestEvaluatePolicy(t *testing.T) {
+	policy := storage.PasswordPolicy{
+		MinLength:        10,
+		RequiredClasses:  []string{ClassUpper, ClassSpecial},
+		MinClasses:       3,
+		MaxRepeatedChars: 2,
+	}
+	banned := map[string]bool{"summer": true}
+
+	assert.Empty(t, EvaluatePolicy(policy, "C0rrect-Horse", banned))
+	assert.Equal(t, []string{
+		"shorter than 10 characters",
+		"missing an upper case letter",
+		"missing a special character",
+		"contains 2 of the 3 required character classes",
+		"repeats a character more than 2 times in a row",
+	}, EvaluatePolicy(policy, "aaa111", banned))
+	assert.Equal(t, []string{"based on the banned word `summer`"}, EvaluatePolicy(policy, "Summer2023!!", banned))
+
+	// a policy without rules never fails
+	assert.Empty(t, EvaluatePolicy(storage.PasswordPolicy{}, "", nil))
+}
+
+func TestFindWords(t *testing.T) {
+	candidates := make(map[string]bool)
+	for _, value := range []string{"Summer2023!", "password", "Winter"} {
+		for _, word := range BannedWordCandidates(value) {
+			candidates[word] = true
+		}
+	}
+
+	found := make(map[string]bool)
+	assert.NoError(t, FindWords(strings.NewReader("Password\nsummer\nautumn\n"), candidates, found))
+	assert.Equal(t, map[string]bool{"password": true, "summer": true}, found)
+}
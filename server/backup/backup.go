@@ -0,0 +1,254 @@
+/* package backup exports the GoCrack database into a portable archive that can be restored into any storage backend */
+
+package backup
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"time"
+
+	"github.com/mandiant/gocrack/server/filemanager"
+	"github.com/mandiant/gocrack/server/storage"
+)
+
+const (
+	// FormatVersion is the version of the archive layout written by Export
+	FormatVersion = 1
+
+	manifestName   = "manifest.json"
+	recordsName    = "records.jsonl"
+	taskFilesDir   = "files/task/"
+	engineFilesDir = "files/engine/"
+)
+
+var (
+	// ErrNotSupported is returned when the storage backend cannot export or import records
+	ErrNotSupported = errors.New("backup: the storage backend does not support exports")
+	// ErrInvalidArchive is returned when restoring an archive that was not created by Export
+	ErrInvalidArchive = errors.New("backup: invalid archive")
+)
+
+// Manifest is the first entry of an archive and describes its contents
+type Manifest struct {
+	FormatVersion int                        `json:"format_version"`
+	CreatedAt     time.Time                  `json:"created_at"`
+	IncludesFiles bool                       `json:"includes_files"`
+	Records       map[storage.RecordType]int `json:"records"`
+	// MissingFiles contains the ID's of task & engine files that no longer exist on disk and were not included
+	MissingFiles []string `json:"missing_files,omitempty"`
+}
+
+// ExportOptions changes what is included in an archive
+type ExportOptions struct {
+	// IncludeFiles adds the task & engine files saved by the filemanager to the archive
+	IncludeFiles bool
+}
+
+// RestoreOptions changes how an archive is restored
+type RestoreOptions struct {
+	// FileManager saves the task & engine files included in the archive. If nil, the files are not restored and
+	// the file records keep pointing to the location they were saved at when exported
+	FileManager *filemanager.Context
+}
+
+// RestoreReport describes what was restored from an archive
+type RestoreReport struct {
+	Manifest Manifest
+	Records  map[storage.RecordType]int
+	Files    int
+}
+
+// line is a single record within records.jsonl
+type line struct {
+	Type storage.RecordType `json:"type"`
+	Data json.RawMessage    `json:"data"`
+}
+
+type archivedFile struct {
+	id   string
+	name string
+	path string
+}
+
+// Export writes a gzipped tarball containing every record of the database to w.
+// Records are written as JSON lines so the archive can be restored into any storage backend
+func Export(w io.Writer, stor storage.Backend, opts ExportOptions) (*Manifest, error) {
+	exporter, ok := stor.(storage.Exporter)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	// The record counts go into the manifest which comes first in the archive so the records are staged on disk
+	tmp, err := ioutil.TempFile("", "gocrack_export")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	manifest := &Manifest{
+		FormatVersion: FormatVersion,
+		CreatedAt:     time.Now().UTC(),
+		IncludesFiles: opts.IncludeFiles,
+		Records:       make(map[storage.RecordType]int),
+	}
+
+	var files []archivedFile
+	bw := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(bw)
+
+	if err = exporter.Export(func(rec storage.Record) error {
+		b, err := json.Marshal(rec.Value)
+		if err != nil {
+			return err
+		}
+
+		if opts.IncludeFiles {
+			switch v := rec.Value.(type) {
+			case storage.TaskFile:
+				files = append(files, archivedFile{id: v.FileID, name: taskFilesDir + v.FileID, path: v.SavedAt})
+			case storage.EngineFile:
+				files = append(files, archivedFile{id: v.FileID, name: engineFilesDir + v.FileID, path: v.SavedAt})
+			}
+		}
+
+		manifest.Records[rec.Type]++
+		return enc.Encode(&line{Type: rec.Type, Data: b})
+	}); err != nil {
+		return nil, err
+	}
+
+	if err = bw.Flush(); err != nil {
+		return nil, err
+	}
+
+	if _, err = tmp.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	// Skip any file that was deleted from disk rather than failing the whole export
+	available := files[:0]
+	for _, f := range files {
+		if _, err := os.Stat(f.path); err != nil {
+			manifest.MissingFiles = append(manifest.MissingFiles, f.id)
+			continue
+		}
+		available = append(available, f)
+	}
+
+	gzw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gzw)
+
+	mb, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	if err = writeEntry(tw, manifestName, int64(len(mb)), bytes.NewReader(mb)); err != nil {
+		return nil, err
+	}
+
+	fi, err := tmp.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = writeEntry(tw, recordsName, fi.Size(), tmp); err != nil {
+		return nil, err
+	}
+
+	for _, f := range available {
+		if err = writeFile(tw, f); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tw.Close(); err != nil {
+		return nil, err
+	}
+	return manifest, gzw.Close()
+}
+
+func writeEntry(tw *tar.Writer, name string, size int64, r io.Reader) error {
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0600,
+		Size:    size,
+		ModTime: time.Now().UTC(),
+	}); err != nil {
+		return err
+	}
+
+	_, err := io.Copy(tw, r)
+	return err
+}
+
+func writeFile(tw *tar.Writer, f archivedFile) error {
+	fd, err := os.Open(f.path)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+
+	fi, err := fd.Stat()
+	if err != nil {
+		return err
+	}
+	return writeEntry(tw, f.name, fi.Size(), fd)
+}
+
+// decodeRecord converts a line of records.jsonl into the storage type of the record
+func decodeRecord(l line) (storage.Record, error) {
+	var ptr interface{}
+
+	switch l.Type {
+	case storage.RecordUser:
+		ptr = new(storage.User)
+	case storage.RecordTaskFile:
+		ptr = new(storage.TaskFile)
+	case storage.RecordEngineFile:
+		ptr = new(storage.EngineFile)
+	case storage.RecordTask:
+		ptr = new(storage.Task)
+	case storage.RecordWorkUnit:
+		ptr = new(storage.WorkUnit)
+	case storage.RecordCrackedHash:
+		ptr = new(storage.ExportedCrackedHash)
+	case storage.RecordCheckpoint:
+		ptr = new(storage.CheckpointFile)
+	case storage.RecordEntitlement:
+		ptr = new(storage.ExportedEntitlement)
+	case storage.RecordAuditLog:
+		ptr = new(storage.ActivityLogEntry)
+	case storage.RecordTaskStatus:
+		ptr = new(storage.TaskStatusHistoryEntry)
+	case storage.RecordKnownHash:
+		ptr = new(storage.KnownHash)
+	case storage.RecordTaskFileAccount:
+		ptr = new(storage.TaskFileAccount)
+	case storage.RecordPasswordPolicy:
+		ptr = new(storage.PasswordPolicy)
+	case storage.RecordCrackedWordlist:
+		ptr = new(storage.CrackedWordlist)
+	case storage.RecordPipeline:
+		ptr = new(storage.Pipeline)
+	case storage.RecordTaskTemplate:
+		ptr = new(storage.TaskTemplate)
+	default:
+		return storage.Record{}, fmt.Errorf("%w: unknown record type `%s`", ErrInvalidArchive, l.Type)
+	}
+
+	if err := json.Unmarshal(l.Data, ptr); err != nil {
+		return storage.Record{}, fmt.Errorf("%w: failed to decode %s record: %s", ErrInvalidArchive, l.Type, err)
+	}
+	return storage.Record{Type: l.Type, Value: reflect.ValueOf(ptr).Elem().Interface()}, nil
+}
@@ -0,0 +1,170 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mandiant/gocrack/server/servertest"
+	"github.com/mandiant/gocrack/server/storage"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// populate saves one of every record type into the database
+func populate(t *testing.T, f *servertest.Fixture) (storage.User, storage.TaskFile, storage.Task) {
+	stor := f.Stor
+	user := f.CreateUser(t, false)
+	tf := f.CreateTaskFile(t, user, "5f4dcc3b5aa765d61d8327deb882cf99")
+
+	task := storage.Task{
+		TaskName: "backup task",
+		Engine:   storage.WorkerHashcatEngine,
+		FileID:   tf.FileID,
+		Status:   storage.TaskStatusFinished,
+	}
+	f.CreateTask(t, user, &task)
+
+	assert.Nil(t, stor.SaveCrackedHash(task.TaskID, "5f4dcc3b5aa765d61d8327deb882cf99", "password", time.Now().UTC()))
+	assert.Nil(t, stor.SaveTaskCheckpoint(storage.CheckpointFile{TaskID: task.TaskID, Data: []byte("checkpoint")}))
+	assert.Nil(t, stor.LogActivity(storage.ActivityLogEntry{
+		OccuredAt: time.Now().UTC(),
+		UserUUID:  user.UserUUID,
+		EntityID:  task.TaskID,
+		Type:      storage.ActivityViewTask,
+	}))
+	assert.Nil(t, stor.SavePasswordPolicy(storage.PasswordPolicy{
+		PolicyID:        "7c5b2a4e-4f0e-4a53-9d43-0f1c7c2f4d1e",
+		Name:            "backup policy",
+		MinLength:       12,
+		RequiredClasses: []string{"upper", "digit"},
+		CreatedByUUID:   user.UserUUID,
+		CreatedAt:       time.Now().UTC(),
+		LastUpdatedAt:   time.Now().UTC(),
+	}))
+	return user, tf, task
+}
+
+func TestExportRestoreBetweenBackends(t *testing.T) {
+	src, closeSrc := servertest.New(t, "bdb")
+	defer closeSrc()
+
+	user, tf, task := populate(t, src)
+
+	var archive bytes.Buffer
+	manifest, err := Export(&archive, src.Stor, ExportOptions{IncludeFiles: true})
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, 1, manifest.Records[storage.RecordUser])
+	assert.Equal(t, 1, manifest.Records[storage.RecordTask])
+	assert.Equal(t, 1, manifest.Records[storage.RecordCrackedHash])
+	assert.Equal(t, 2, manifest.Records[storage.RecordEntitlement])
+	assert.Empty(t, manifest.MissingFiles)
+
+	fixture, closeDst := servertest.New(t, "sqlite")
+	defer closeDst()
+	dst := fixture.Stor
+
+	report, err := Restore(bytes.NewReader(archive.Bytes()), dst, RestoreOptions{
+		FileManager: fixture.FM,
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, manifest.Records, report.Records)
+	assert.Equal(t, 1, report.Files)
+
+	restoredUser, err := dst.GetUserByID(user.UserUUID)
+	if assert.Nil(t, err) {
+		assert.Equal(t, user.Username, restoredUser.Username)
+		assert.Equal(t, user.Password, restoredUser.Password)
+	}
+
+	restoredTask, err := dst.GetTaskByID(task.TaskID)
+	if assert.Nil(t, err) {
+		assert.Equal(t, task.TaskName, restoredTask.TaskName)
+		assert.Equal(t, storage.TaskStatusFinished, restoredTask.Status)
+	}
+
+	cracked, err := dst.GetCrackedPasswords(task.TaskID)
+	if assert.Nil(t, err) && assert.Len(t, *cracked, 1) {
+		assert.Equal(t, "password", (*cracked)[0].Value)
+	}
+
+	checkpoint, err := dst.GetTaskCheckpoint(task.TaskID)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("checkpoint"), checkpoint)
+
+	entitled, err := dst.CheckEntitlement(user.UserUUID, task.TaskID, storage.EntitlementTask)
+	assert.Nil(t, err)
+	assert.True(t, entitled)
+
+	activity, err := dst.GetActivityLog(task.TaskID)
+	assert.Nil(t, err)
+	assert.Len(t, activity, 1)
+
+	policy, err := dst.GetPasswordPolicy("7c5b2a4e-4f0e-4a53-9d43-0f1c7c2f4d1e")
+	if assert.Nil(t, err) {
+		assert.Equal(t, 12, policy.MinLength)
+		assert.Equal(t, []string{"upper", "digit"}, policy.RequiredClasses)
+		assert.Empty(t, policy.BannedWordFiles)
+	}
+
+	// the task file is saved under the destination's filemanager
+	restoredFile, err := dst.GetTaskFileByID(tf.FileID)
+	if assert.Nil(t, err) {
+		assert.NotEqual(t, tf.SavedAt, restoredFile.SavedAt)
+		assert.True(t, strings.HasPrefix(restoredFile.SavedAt, fixture.Dir))
+
+		b, err := ioutil.ReadFile(restoredFile.SavedAt)
+		assert.Nil(t, err)
+		assert.Equal(t, "5f4dcc3b5aa765d61d8327deb882cf99\n", string(b))
+	}
+
+	// restoring the same archive twice must fail without leaving anything behind
+	_, err = Restore(bytes.NewReader(archive.Bytes()), dst, RestoreOptions{})
+	assert.NotNil(t, err)
+}
+
+func TestRestoreInvalidArchive(t *testing.T) {
+	f, closer := servertest.New(t, "sqlite")
+	defer closer()
+
+	_, err := Restore(strings.NewReader("not an archive"), f.Stor, RestoreOptions{})
+	assert.True(t, errors.Is(err, ErrInvalidArchive))
+}
+
+func TestRestoreRejectsUnsafeFileNames(t *testing.T) {
+	f, closer := servertest.New(t, "sqlite")
+	defer closer()
+
+	for _, name := range []string{"ab", "aaaaaaaaaaaaaa/../../../../escaped", "../../escaped"} {
+		var archive bytes.Buffer
+
+		gzw := gzip.NewWriter(&archive)
+		tw := tar.NewWriter(gzw)
+		manifest, _ := json.Marshal(Manifest{FormatVersion: FormatVersion, IncludesFiles: true})
+		assert.Nil(t, writeEntry(tw, manifestName, int64(len(manifest)), bytes.NewReader(manifest)))
+		assert.Nil(t, writeEntry(tw, recordsName, 0, strings.NewReader("")))
+		assert.Nil(t, writeEntry(tw, taskFilesDir+name, 5, strings.NewReader("owned")))
+		assert.Nil(t, tw.Close())
+		assert.Nil(t, gzw.Close())
+
+		_, err := Restore(&archive, f.Stor, RestoreOptions{FileManager: f.FM})
+		assert.Truef(t, errors.Is(err, ErrInvalidArchive), "%s must be rejected", name)
+	}
+
+	for _, path := range []string{filepath.Join(f.Dir, "escaped"), filepath.Join(filepath.Dir(f.Dir), "escaped")} {
+		_, err := os.Stat(path)
+		assert.True(t, os.IsNotExist(err))
+	}
+}
@@ -0,0 +1,165 @@
+package backup
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mandiant/gocrack/server/storage"
+
+	"github.com/google/uuid"
+)
+
+// maxRecordSize is the largest line accepted from records.jsonl
+const maxRecordSize = 64 * 1024 * 1024
+
+// Restore imports an archive created by Export into stor within a single transaction. The database should be empty
+// as records are restored as is and any that already exist will abort the restore
+func Restore(r io.Reader, stor storage.Backend, opts RestoreOptions) (report *RestoreReport, err error) {
+	var restoredFiles []string
+
+	importer, ok := stor.(storage.Importer)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	gzr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidArchive, err)
+	}
+	defer gzr.Close()
+	tr := tar.NewReader(gzr)
+
+	report = &RestoreReport{Records: make(map[storage.RecordType]int)}
+	if err = readManifest(tr, &report.Manifest); err != nil {
+		return nil, err
+	}
+
+	hdr, err := tr.Next()
+	if err != nil || hdr.Name != recordsName {
+		return nil, fmt.Errorf("%w: expected %s after the manifest", ErrInvalidArchive, recordsName)
+	}
+
+	txn, err := importer.NewImportTransaction()
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err == nil {
+			return
+		}
+
+		txn.Rollback()
+		for _, path := range restoredFiles {
+			os.Remove(path)
+		}
+	}()
+
+	restoreFiles := opts.FileManager != nil && report.Manifest.IncludesFiles
+	scanner := bufio.NewScanner(tr)
+	scanner.Buffer(make([]byte, 64*1024), maxRecordSize)
+
+	for scanner.Scan() {
+		var l line
+		if err = json.Unmarshal(scanner.Bytes(), &l); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidArchive, err)
+		}
+
+		rec, err := decodeRecord(l)
+		if err != nil {
+			return nil, err
+		}
+
+		// The files are saved where the filemanager of this server expects them to be
+		if restoreFiles {
+			if rec.Value, err = relocateFile(opts, rec.Value); err != nil {
+				return nil, err
+			}
+		}
+
+		if err = txn.Import(rec); err != nil {
+			return nil, fmt.Errorf("failed to restore %s record: %w", rec.Type, err)
+		}
+		report.Records[rec.Type]++
+	}
+
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidArchive, err)
+	}
+
+	for restoreFiles {
+		if hdr, err = tr.Next(); err != nil {
+			if err == io.EOF {
+				err = nil
+				break
+			}
+			return nil, fmt.Errorf("%w: %s", ErrInvalidArchive, err)
+		}
+
+		var filetype interface{}
+		var fileID string
+
+		switch {
+		case strings.HasPrefix(hdr.Name, taskFilesDir):
+			fileID, filetype = strings.TrimPrefix(hdr.Name, taskFilesDir), storage.TaskFileEngine(storage.TaskFileEngineAll)
+		case strings.HasPrefix(hdr.Name, engineFilesDir):
+			fileID, filetype = strings.TrimPrefix(hdr.Name, engineFilesDir), storage.EngineFileDictionary
+		default:
+			continue
+		}
+
+		// the ID is used to build the path the file is written to so it must never be trusted
+		if _, err = uuid.Parse(fileID); err != nil || len(fileID) != 36 {
+			return nil, fmt.Errorf("%w: %s is not a file saved by GoCrack", ErrInvalidArchive, hdr.Name)
+		}
+
+		path, err := opts.FileManager.RestoreFile(tr, fileID, filetype)
+		if err != nil {
+			return nil, fmt.Errorf("failed to restore file %s: %w", fileID, err)
+		}
+		restoredFiles = append(restoredFiles, path)
+		report.Files++
+	}
+
+	if err = txn.Commit(); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func readManifest(tr *tar.Reader, manifest *Manifest) error {
+	hdr, err := tr.Next()
+	if err != nil || hdr.Name != manifestName {
+		return fmt.Errorf("%w: expected the archive to start with %s", ErrInvalidArchive, manifestName)
+	}
+
+	if err = json.NewDecoder(tr).Decode(manifest); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidArchive, err)
+	}
+
+	if manifest.FormatVersion > FormatVersion {
+		return fmt.Errorf("%w: archive format %d is newer than this server supports", ErrInvalidArchive, manifest.FormatVersion)
+	}
+	return nil
+}
+
+// relocateFile points the file record at the location the filemanager will restore it to
+func relocateFile(opts RestoreOptions, value interface{}) (interface{}, error) {
+	var err error
+
+	switch v := value.(type) {
+	case storage.TaskFile:
+		v.SavedAt, err = opts.FileManager.FilePath(v.FileID, storage.TaskFileEngine(storage.TaskFileEngineAll))
+		return v, err
+	case storage.EngineFile:
+		v.SavedAt, err = opts.FileManager.FilePath(v.FileID, storage.EngineFileDictionary)
+		return v, err
+	}
+	return value, nil
+}
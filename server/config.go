@@ -7,6 +7,7 @@ import (
 	"github.com/mandiant/gocrack/server/filemanager"
 	"github.com/mandiant/gocrack/server/notifications"
 	"github.com/mandiant/gocrack/server/rpc"
+	"github.com/mandiant/gocrack/server/scheduler"
 	"github.com/mandiant/gocrack/server/storage"
 	"github.com/mandiant/gocrack/server/web"
 )
@@ -17,6 +18,7 @@ type Config struct {
 	Debug          bool                        `yaml:"debug"`
 	WebServer      web.Config                  `yaml:"web_server"`
 	RPC            rpc.Config                  `yaml:"rpc_server"`
+	Scheduler      scheduler.Config            `yaml:"scheduler"`
 	Database       storage.Config              `yaml:"database"`
 	FileManager    filemanager.Config          `yaml:"file_manager"`
 	Authentication authentication.AuthSettings `yaml:"authentication"`
@@ -36,6 +38,10 @@ func (s *Config) validate() error {
 		return err
 	}
 
+	if err := s.Scheduler.Validate(); err != nil {
+		return err
+	}
+
 	if err := s.Database.Validate(); err != nil {
 		return err
 	}
@@ -0,0 +1,7 @@
+package server
+
+// The server accepts tasks for every engine it has a definition of. Workers report which of them they can run
+import (
+	_ "github.com/mandiant/gocrack/shared/enginedef/cpu"
+	_ "github.com/mandiant/gocrack/shared/enginedef/hashcat"
+)
@@ -40,10 +40,24 @@ var (
 	ErrCannotImport = errors.New("filemanager: cannot import because import_directory is not set in the config")
 	// SystemUserUUID is the UUID of files uploaded by the GoCrack system
 	SystemUserUUID = "b2c9e661-74e5-4ba3-b1ce-624894e85622"
+	// ErrInvalidFileID is returned when a file ID is not made up of enough hex characters to be split into a path
+	ErrInvalidFileID = errors.New("filemanager: invalid file id")
 )
 
-func splitFilePath(rootPath, filename string) string {
+// splitFilePath nests the file under directories named after the first 14 characters of its ID. IDs must be hex
+// optionally separated by dashes (such as a UUID) so that they can never point outside of rootPath
+func splitFilePath(rootPath, filename string) (string, error) {
 	filename = strings.Replace(filename, "-", "", -1)
+	if len(filename) < 14 {
+		return "", ErrInvalidFileID
+	}
+
+	for _, c := range filename {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+			return "", ErrInvalidFileID
+		}
+	}
+
 	var parts = []string{rootPath}
 
 	for i := 0; i < 14; i += 2 {
@@ -51,7 +65,7 @@ func splitFilePath(rootPath, filename string) string {
 	}
 	parts = append(parts, filename)
 
-	return filepath.Join(parts...)
+	return filepath.Join(parts...), nil
 }
 
 // New creates a new FileManager for GoCrack
@@ -67,18 +81,62 @@ func (s *Context) Refresh() error {
 	return s.importDirectory()
 }
 
-// SaveFile returns metadata about the file and saves the file to disk
-func (s *Context) SaveFile(src io.ReadCloser, filename string, fileUUID string, filetype interface{}) (*FileSaveResponse, error) {
-	var rootpath string
-	var finalSavePath string
-
+// rootPath returns the directory files of filetype are saved in
+func (s *Context) rootPath(filetype interface{}) (string, error) {
 	switch t := filetype.(type) {
 	case storage.TaskFileEngine:
-		rootpath = s.cfg.TaskUploadPath
+		return s.cfg.TaskUploadPath, nil
 	case storage.EngineFileType:
-		rootpath = s.cfg.EngineFilePath
+		return s.cfg.EngineFilePath, nil
 	default:
-		return nil, fmt.Errorf("unknown filetype `%v`", t)
+		return "", fmt.Errorf("unknown filetype `%v`", t)
+	}
+}
+
+// FilePath returns the location a file of filetype is saved at
+func (s *Context) FilePath(fileUUID string, filetype interface{}) (string, error) {
+	rootpath, err := s.rootPath(filetype)
+	if err != nil {
+		return "", err
+	}
+	return splitFilePath(rootpath, fileUUID)
+}
+
+// RestoreFile writes the contents of a file that was previously saved by the filemanager back to disk and returns where it was saved to
+func (s *Context) RestoreFile(src io.Reader, fileUUID string, filetype interface{}) (string, error) {
+	path, err := s.FilePath(fileUUID, filetype)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0770); err != nil {
+		return "", err
+	}
+
+	fd, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0660)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err = io.Copy(fd, src); err != nil {
+		fd.Close()
+		os.Remove(path)
+		return "", err
+	}
+	return path, fd.Close()
+}
+
+// SaveFile returns metadata about the file and saves the file to disk
+func (s *Context) SaveFile(src io.ReadCloser, filename string, fileUUID string, filetype interface{}) (*FileSaveResponse, error) {
+	var finalSavePath string
+
+	rootpath, err := s.rootPath(filetype)
+	if err != nil {
+		return nil, err
+	}
+
+	if finalSavePath, err = splitFilePath(rootpath, fileUUID); err != nil {
+		return nil, err
 	}
 
 	// XXX(cschmitt): This is kinda messy how we have to handle file uploads.. we should create something like python's buffered temp file
@@ -103,7 +161,6 @@ func (s *Context) SaveFile(src io.ReadCloser, filename string, fileUUID string,
 	}
 
 	fileHash := fmt.Sprintf("%x", hasher.Sum(nil))
-	finalSavePath = splitFilePath(rootpath, fileUUID)
 	rootPath, _ := filepath.Split(finalSavePath)
 
 	// Create the nested directory
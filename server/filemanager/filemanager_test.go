@@ -4,6 +4,7 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"strings"
 	"testing"
 
 	fakestor "github.com/mandiant/gocrack/server/filemanager/test"
@@ -35,11 +36,17 @@ func TestSplitFilePath(t *testing.T) {
 			OutString:     "/tmp/d7/8d/df/ec/5e/12/42/d78ddfec5e124238a46b8e218d4cfcbe",
 		},
 	} {
-		out := splitFilePath(test.RootPath, test.InitialString)
+		out, err := splitFilePath(test.RootPath, test.InitialString)
+		assert.Nil(t, err)
 		if out != test.OutString {
 			assert.Equalf(t, test.OutString, out, "output from splitFilePath does not match in test %d", i)
 		}
 	}
+
+	for _, name := range []string{"", "d78ddfec", "aaaaaaaaaaaaaa/../../../..", "../../../../../../etc/passwd"} {
+		_, err := splitFilePath("/tmp", name)
+		assert.Equalf(t, ErrInvalidFileID, err, "%s must not be split into a path", name)
+	}
 }
 func TestSaveEngineFile(t *testing.T) {
 	simpl := &fakestor.TestStorageImpl{}
@@ -79,3 +86,38 @@ func TestSaveEngineFile(t *testing.T) {
 	assert.Equal(t, int64(2), fresp.NumberOfLines)
 	assert.NotEmpty(t, fresp.SavedTo)
 }
+
+func TestRestoreFile(t *testing.T) {
+	path, err := ioutil.TempDir("", "")
+	if err != nil {
+		assert.FailNow(t, "failed to create temp directory", err.Error())
+	}
+	defer os.RemoveAll(path)
+
+	fm := New(&fakestor.TestStorageImpl{}, Config{
+		TaskUploadPath: path,
+		EngineFilePath: path,
+		TempPath:       path,
+	})
+
+	fileID := uuid.NewString()
+	savedTo, err := fm.RestoreFile(strings.NewReader("helloworld"), fileID, storage.TaskFileEngine(storage.TaskFileEngineAll))
+	if err != nil {
+		assert.FailNow(t, "failed to restore test file", err.Error())
+	}
+
+	expected, err := fm.FilePath(fileID, storage.TaskFileEngine(storage.TaskFileEngineAll))
+	assert.Nil(t, err)
+	assert.Equal(t, expected, savedTo)
+
+	b, err := ioutil.ReadFile(savedTo)
+	assert.Nil(t, err)
+	assert.Equal(t, "helloworld", string(b))
+
+	// existing files must never be overwritten
+	_, err = fm.RestoreFile(strings.NewReader("goodbye"), fileID, storage.TaskFileEngine(storage.TaskFileEngineAll))
+	assert.NotNil(t, err)
+
+	_, err = fm.RestoreFile(strings.NewReader("goodbye"), "aaaaaaaaaaaaaa/../../../..", storage.TaskFileEngine(storage.TaskFileEngineAll))
+	assert.Equal(t, ErrInvalidFileID, err)
+}
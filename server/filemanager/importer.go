@@ -20,6 +20,7 @@ var extensionsToType = extlookup{
 	".dictionary": storage.EngineFileDictionary,
 	".rule":       storage.EngineFileRules,
 	".rules":      storage.EngineFileRules,
+	".hcchr":      storage.EngineFileCharset,
 }
 
 func (s *Context) importDirectory() error {
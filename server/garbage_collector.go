@@ -0,0 +1,41 @@
+package server
+
+import (
+	"time"
+
+	"github.com/mandiant/gocrack/server/storage"
+
+	"github.com/rs/zerolog/log"
+)
+
+// collectGarbage looks for documents left behind by deleted tasks & files when the server starts and then on every
+// interval until the server is stopped. Orphans are only reported unless the server is configured to remove them
+func (s *Server) collectGarbage(gc storage.GarbageCollector, cfg storage.GarbageCollectionConfig) {
+	defer s.wg.Done()
+
+	tickEvery := time.NewTicker(cfg.Interval.Duration)
+	defer tickEvery.Stop()
+
+	for {
+		report, err := gc.CollectGarbage(!cfg.RemoveOrphans)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to collect orphaned documents")
+		} else if report.Total() > 0 {
+			evt := log.Warn()
+			if !report.DryRun {
+				evt = log.Info()
+			}
+
+			for rt, n := range report.Orphans {
+				evt = evt.Int(string(rt), n)
+			}
+			evt.Bool("removed", !report.DryRun).Msg("Found orphaned documents in the database")
+		}
+
+		select {
+		case <-s.stop:
+			return
+		case <-tickEvery.C:
+		}
+	}
+}
@@ -0,0 +1,139 @@
+// Package hashfile recognises the formats of task files that prefix each hash with the account it belongs to
+// (e.g. pwdump) so cracked passwords can be joined back to their accounts
+package hashfile
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mandiant/gocrack/server/storage"
+
+	"github.com/tankbusta/hashvalidate"
+)
+
+const (
+	// hashcat modes with a format specific to them
+	modeLM         = 3000
+	modeNTLM       = 1000
+	modeNetNTLMv1  = 5500
+	modeNetNTLMv2  = 5600
+	lmNTHashLength = 32
+)
+
+// ErrMissingUsername is returned when a line does not contain the username required by the format
+var ErrMissingUsername = errors.New("line does not start with a username")
+
+// DetectFormat returns the format of a task file from its first line. Lines that are not recognised are treated
+// as a hash per line so they're validated the same way they always have been
+func DetectFormat(hashType int, line string) storage.TaskFileFormat {
+	switch {
+	case hashType == modeNetNTLMv1 || hashType == modeNetNTLMv2:
+		if fields := strings.Split(line, ":"); len(fields) >= 6 && fields[0] != "" {
+			return storage.TaskFileFormatNetNTLM
+		}
+	case isPwdump(hashType, line):
+		return storage.TaskFileFormatPwdump
+	}
+
+	if hashvalidate.ValidateHash(hashType, line) == nil {
+		return storage.TaskFileFormatHashes
+	}
+
+	if i := strings.IndexByte(line, ':'); i > 0 && hashvalidate.ValidateHash(hashType, line[i+1:]) == nil {
+		return storage.TaskFileFormatUserHash
+	}
+	return storage.TaskFileFormatHashes
+}
+
+// isPwdump returns true if the line is an account from a pwdump (user:rid:lmhash:nthash:::)
+func isPwdump(hashType int, line string) bool {
+	if hashType != modeLM && hashType != modeNTLM {
+		return false
+	}
+
+	fields := strings.Split(line, ":")
+	if len(fields) < 4 || fields[0] == "" || fields[1] == "" {
+		return false
+	}
+
+	for _, c := range fields[1] {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return len(fields[2]) == lmNTHashLength && len(fields[3]) == lmNTHashLength
+}
+
+// ParseLine returns the username and hash on a line of a task file. The username is empty for a file of hashes
+func ParseLine(format storage.TaskFileFormat, hashType int, line string) (username, hash string, err error) {
+	switch format {
+	case storage.TaskFileFormatUserHash:
+		i := strings.IndexByte(line, ':')
+		if i <= 0 {
+			return "", "", ErrMissingUsername
+		}
+		return line[:i], line[i+1:], nil
+	case storage.TaskFileFormatPwdump:
+		fields := strings.Split(line, ":")
+		if len(fields) < 4 || fields[0] == "" {
+			return "", "", errors.New("line is not in the pwdump format (user:rid:lmhash:nthash:::)")
+		}
+
+		if hashType == modeLM {
+			return fields[0], fields[2], nil
+		}
+		return fields[0], fields[3], nil
+	case storage.TaskFileFormatNetNTLM:
+		// the username & domain are part of the hash (user::domain:challenge:response:blob)
+		fields := strings.Split(line, ":")
+		if len(fields) < 3 || fields[0] == "" {
+			return "", "", ErrMissingUsername
+		}
+
+		if fields[2] != "" {
+			return fields[2] + `\` + fields[0], line, nil
+		}
+		return fields[0], line, nil
+	}
+	return "", line, nil
+}
+
+// Hash returns the hash on a line of a task file or an empty string if the line can't be parsed
+func Hash(format storage.TaskFileFormat, hashType int, line string) string {
+	_, hash, err := ParseLine(format, hashType, line)
+	if err != nil {
+		return ""
+	}
+	return hash
+}
+
+// Filter writes every line of the task file at path to w except for those whose hash is in exclude. Hashes are compared
+// case insensitively so the keys of exclude must be lower case
+func Filter(w io.Writer, path string, format storage.TaskFileFormat, hashType int, exclude map[string]bool) error {
+	fd, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+
+	bw := bufio.NewWriter(w)
+	scanner := bufio.NewScanner(fd)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if exclude[strings.ToLower(Hash(format, hashType, strings.TrimSpace(line)))] {
+			continue
+		}
+
+		if _, err = bw.WriteString(line + "\n"); err != nil {
+			return err
+		}
+	}
+
+	if err = scanner.Err(); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,78 @@
+package hashfile
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mandiant/gocrack/server/storage"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	ntHash       = "8846f7eaee8fb117ad06bdd830b7586c"
+	lmHash       = "aad3b435b51404eeaad3b435b51404ee"
+	netNTLMv2    = "admin::N46iSNekpT:08ca45b7d7ea58ee:88dcbe4446168966a153a0064958dac6:5c7830315c7830310000000000000b45c67103d07d7b95acd12ffa11230e0000000052920b85f78d013c31cdb3b92f5d765c783030"
+	pwdumpLine   = "Administrator:500:" + lmHash + ":" + ntHash + ":::"
+	userHashLine = "alice:" + ntHash
+)
+
+func TestDetectFormat(t *testing.T) {
+	for _, test := range []struct {
+		hashType int
+		line     string
+		expected storage.TaskFileFormat
+	}{
+		{1000, ntHash, storage.TaskFileFormatHashes},
+		{1000, userHashLine, storage.TaskFileFormatUserHash},
+		{1000, pwdumpLine, storage.TaskFileFormatPwdump},
+		{3000, pwdumpLine, storage.TaskFileFormatPwdump},
+		{5600, netNTLMv2, storage.TaskFileFormatNetNTLM},
+		{1000, "not a hash", storage.TaskFileFormatHashes},
+	} {
+		assert.Equal(t, test.expected, DetectFormat(test.hashType, test.line), test.line)
+	}
+}
+
+func TestParseLine(t *testing.T) {
+	for _, test := range []struct {
+		format   storage.TaskFileFormat
+		hashType int
+		line     string
+		username string
+		hash     string
+	}{
+		{storage.TaskFileFormatHashes, 1000, ntHash, "", ntHash},
+		{storage.TaskFileFormatUserHash, 1000, userHashLine, "alice", ntHash},
+		{storage.TaskFileFormatPwdump, 1000, pwdumpLine, "Administrator", ntHash},
+		{storage.TaskFileFormatPwdump, 3000, pwdumpLine, "Administrator", lmHash},
+		{storage.TaskFileFormatNetNTLM, 5600, netNTLMv2, `N46iSNekpT\admin`, netNTLMv2},
+	} {
+		username, hash, err := ParseLine(test.format, test.hashType, test.line)
+		assert.NoError(t, err, test.line)
+		assert.Equal(t, test.username, username, test.line)
+		assert.Equal(t, test.hash, hash, test.line)
+	}
+}
+
+func TestParseLineErrors(t *testing.T) {
+	_, _, err := ParseLine(storage.TaskFileFormatUserHash, 1000, "")
+	assert.Equal(t, ErrMissingUsername, err)
+
+	_, _, err = ParseLine(storage.TaskFileFormatPwdump, 1000, userHashLine)
+	assert.Error(t, err)
+
+	assert.Empty(t, Hash(storage.TaskFileFormatUserHash, 1000, ""))
+}
+
+func TestFilter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pwdump.txt")
+	guest := "Guest:501:" + lmHash + ":31D6CFE0D16AE931B73C59D7E0C089C0:::"
+	assert.NoError(t, os.WriteFile(path, []byte(pwdumpLine+"\n"+guest+"\n"), 0600))
+
+	var buf bytes.Buffer
+	assert.NoError(t, Filter(&buf, path, storage.TaskFileFormatPwdump, 1000, map[string]bool{"31d6cfe0d16ae931b73c59d7e0c089c0": true}))
+	assert.Equal(t, pwdumpLine+"\n", buf.String())
+}
@@ -3,13 +3,23 @@ package rpc
 import (
 	"errors"
 	"io"
+	"time"
 
 	"github.com/mandiant/gocrack/shared"
 )
 
 // Config describes the configuration used by the GoCrack RPC Listener.
 type Config struct {
-	Listener shared.ServerCfg `yaml:"listener"`
+	Listener  shared.ServerCfg `yaml:"listener"`
+	WorkUnits WorkUnitConfig   `yaml:"work_units"`
+}
+
+// WorkUnitConfig describes how the work units of a distributed task are tracked
+type WorkUnitConfig struct {
+	// LostAfter is how long a work unit can go without an update while its worker is not reporting the task before it's requeued
+	LostAfter shared.HumanDuration `yaml:"lost_after"`
+	// MaxAttempts is the number of times a work unit is dispatched before the task is marked as errored
+	MaxAttempts int `yaml:"max_attempts"`
 }
 
 // ErrNoCheckpoint is returned when a checkpoint does not exist for the task
@@ -21,6 +31,14 @@ func (s *Config) Validate() error {
 		s.Listener.Address = ":4014"
 	}
 
+	if s.WorkUnits.LostAfter.Duration == 0 {
+		s.WorkUnits.LostAfter = shared.HumanDuration{Duration: 5 * time.Minute}
+	}
+
+	if s.WorkUnits.MaxAttempts <= 0 {
+		s.WorkUnits.MaxAttempts = 3
+	}
+
 	if s.Listener.Certificate == "" || s.Listener.PrivateKey == "" {
 		return errors.New("rpc_server.listener.ssl_certificate and rpc_server.listener.ssl_private_key must not be empty")
 	}
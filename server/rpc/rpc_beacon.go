@@ -6,8 +6,10 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/mandiant/gocrack/server/scheduler"
 	"github.com/mandiant/gocrack/server/storage"
 	"github.com/mandiant/gocrack/shared"
+	"github.com/mandiant/gocrack/shared/enginedef"
 
 	"github.com/gin-gonic/gin"
 )
@@ -24,10 +26,11 @@ const (
 
 // NewTask describes the payload sent to the worker when a task should be executed
 type NewTask struct {
-	ID       string
-	Engine   storage.WorkerCrackEngine
-	Priority storage.WorkerPriority
-	Devices  storage.CLDevices
+	ID         string
+	WorkUnitID string
+	Engine     storage.WorkerCrackEngine
+	Priority   storage.WorkerPriority
+	Devices    storage.CLDevices
 }
 
 // ChangeTaskStatus describes the payload sent to the worker
@@ -64,6 +67,17 @@ func GetDevicesInUse(d shared.DeviceMap) storage.CLDevices {
 	return inuse
 }
 
+// GetHostEngines returns the engines the worker reported it can run. Engines unknown to the server are left out
+func GetHostEngines(versions shared.EngineVersion) []storage.WorkerCrackEngine {
+	out := make([]storage.WorkerCrackEngine, 0, len(versions))
+	for name := range versions {
+		if def, ok := enginedef.LookupByName(name); ok {
+			out = append(out, def.ID)
+		}
+	}
+	return out
+}
+
 func (s *RPCServer) workerBeacon(c *gin.Context) *RPCError {
 	var req BeaconRequest
 
@@ -88,6 +102,8 @@ func (s *RPCServer) workerBeacon(c *gin.Context) *RPCError {
 		DevicesInUse:    GetDevicesInUse(req.Devices),
 		RunningTasks:    host.GetRunningTaskIDs(),
 		CheckForNewTask: req.RequestNewTask,
+		Engines:         GetHostEngines(req.Engines),
+		Schedule:        s.scheduleTasks,
 	})
 
 	if err != nil {
@@ -119,6 +135,10 @@ func (s *RPCServer) workerBeacon(c *gin.Context) *RPCError {
 				Priority: nextTask.Priority,
 			}
 
+			if action.WorkUnit != nil {
+				ntreq.WorkUnitID = action.WorkUnit.UnitID
+			}
+
 			if nextTask.AssignedToDevices != nil && nextTask.AssignedToHost != "" {
 				ntreq.Devices = *nextTask.AssignedToDevices
 			}
@@ -155,3 +175,16 @@ func (s *RPCServer) workerBeacon(c *gin.Context) *RPCError {
 	c.JSON(http.StatusOK, &resp)
 	return nil
 }
+
+// scheduleTasks orders the queued tasks a worker can run with the scheduler and leaves out the ones it holds back
+func (s *RPCServer) scheduleTasks(candidates []storage.Task) ([]storage.Task, error) {
+	if len(candidates) == 0 {
+		return candidates, nil
+	}
+
+	load, err := scheduler.CurrentLoad(s.stor, s.wmgr)
+	if err != nil {
+		return nil, err
+	}
+	return scheduler.Ready(s.sched.Queue(candidates, load, time.Now().UTC())), nil
+}
@@ -0,0 +1,109 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mandiant/gocrack/server/scheduler"
+	"github.com/mandiant/gocrack/server/storage"
+	"github.com/mandiant/gocrack/shared"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// beacon sends the beacon to the server and returns the tasks it hands out
+func beacon(t *testing.T, s *RPCServer, req BeaconRequest) []NewTask {
+	e := gin.New()
+	e.POST("/beacon", WrapCallError(s.workerBeacon))
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		assert.FailNow(t, "failed to marshal beacon", err.Error())
+	}
+
+	w := httptest.NewRecorder()
+	hreq, _ := http.NewRequest("POST", "/beacon", bytes.NewReader(body))
+	e.ServeHTTP(w, hreq)
+	if !assert.Equal(t, http.StatusOK, w.Code) {
+		return nil
+	}
+
+	var resp BeaconResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		assert.FailNow(t, "failed to unmarshal beacon response", err.Error())
+	}
+
+	var tasks []NewTask
+	for _, item := range resp.Payloads {
+		if item.Type != BeaconNewTask {
+			continue
+		}
+
+		var task NewTask
+		assert.Nil(t, json.Unmarshal(item.Data, &task))
+		tasks = append(tasks, task)
+	}
+	return tasks
+}
+
+func TestGetHostEngines(t *testing.T) {
+	assert.ElementsMatch(t, []storage.WorkerCrackEngine{storage.WorkerHashcatEngine, storage.WorkerCPUEngine},
+		GetHostEngines(shared.EngineVersion{"hashcat": "6.1.1", "cpu": "1.0.0", "john": "1.9.0"}))
+	assert.Empty(t, GetHostEngines(nil))
+}
+
+func TestWorkerBeaconEngines(t *testing.T) {
+	s, f, _, cleanup := newTestServer(t)
+	defer cleanup()
+
+	sched, err := scheduler.New(scheduler.Config{Type: scheduler.PriorityScheduler})
+	if err != nil {
+		assert.FailNow(t, "failed to create scheduler", err.Error())
+	}
+	s.sched = sched
+
+	user := f.CreateUser(t, false)
+	now := time.Now().UTC()
+	hashcat := storage.Task{
+		Engine:    storage.WorkerHashcatEngine,
+		Priority:  storage.WorkerPriorityHigh,
+		Status:    storage.TaskStatusQueued,
+		CreatedAt: now.Add(-time.Hour),
+		QueuedAt:  now.Add(-time.Hour),
+	}
+	cpu := storage.Task{
+		Engine:    storage.WorkerCPUEngine,
+		Priority:  storage.WorkerPriorityLow,
+		Status:    storage.TaskStatusQueued,
+		CreatedAt: now,
+		QueuedAt:  now,
+	}
+	f.CreateTask(t, user, &hashcat)
+	f.CreateTask(t, user, &cpu)
+
+	// a worker built without hashcat is given the CPU task even though the hashcat task is first in the queue
+	cpuOnly := BeaconRequest{Hostname: "cpu-host", RequestNewTask: true, Engines: shared.EngineVersion{"cpu": "1.0.0"}}
+	tasks := beacon(t, s, cpuOnly)
+	if assert.Len(t, tasks, 1) {
+		assert.Equal(t, cpu.TaskID, tasks[0].ID)
+		assert.Equal(t, storage.WorkerCPUEngine, tasks[0].Engine)
+	}
+
+	_, err = f.Stor.ChangeTaskStatus(cpu.TaskID, storage.TaskStatusChange{Status: storage.TaskStatusFinished, ActorType: storage.StatusActorServer})
+	assert.Nil(t, err)
+	assert.Empty(t, beacon(t, s, cpuOnly))
+
+	tasks = beacon(t, s, BeaconRequest{
+		Hostname:       "gpu-host",
+		RequestNewTask: true,
+		Engines:        shared.EngineVersion{"hashcat": "6.1.1", "cpu": "1.0.0"},
+	})
+	if assert.Len(t, tasks, 1) {
+		assert.Equal(t, hashcat.TaskID, tasks[0].ID)
+	}
+}
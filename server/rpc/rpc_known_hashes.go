@@ -0,0 +1,79 @@
+package rpc
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/mandiant/gocrack/server/hashfile"
+	"github.com/mandiant/gocrack/server/storage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// indexCrackedHash adds a password cracked by a worker to the known hash index so other tasks can reuse it.
+// The password has already been saved against its task so failures are only logged
+func (s *RPCServer) indexCrackedHash(req CrackedPasswordRequest) {
+	task, err := s.stor.GetTaskByID(req.TaskID)
+	if err != nil {
+		log.Warn().Err(err).Str("task_id", req.TaskID).Msg("Failed to get task to index cracked hash")
+		return
+	}
+
+	hashType, ok := storage.TaskHashType(task)
+	if !ok {
+		return
+	}
+
+	if err = s.stor.SaveKnownHash(storage.KnownHash{
+		HashType:  hashType,
+		Hash:      req.Hash,
+		Value:     req.Value,
+		CrackedAt: req.CrackedAt,
+		TaskID:    req.TaskID,
+	}); err != nil {
+		log.Warn().Err(err).Str("task_id", req.TaskID).Msg("Failed to add cracked hash to the known hash index")
+	}
+}
+
+// reusedHashes returns the set of lower cased hashes the task was credited with from the known hash index
+func (s *RPCServer) reusedHashes(taskID string) (map[string]bool, error) {
+	cracked, err := s.stor.GetCrackedPasswords(taskID)
+	if err != nil {
+		if err == storage.ErrNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	out := make(map[string]bool)
+	for _, ch := range *cracked {
+		if ch.ReusedFromTaskID != "" {
+			out[strings.ToLower(ch.Hash)] = true
+		}
+	}
+	return out, nil
+}
+
+// sendFilteredTaskFile sends the task file without the known hashes. The file is read twice so the hash of the
+// filtered content can be sent in the header without holding it in memory
+func (s *RPCServer) sendFilteredTaskFile(c *gin.Context, tf *storage.TaskFile, hashType int, known map[string]bool) *RPCError {
+	h := sha1.New()
+	if err := hashfile.Filter(h, tf.SavedAt, tf.Format, hashType, known); err != nil {
+		return &RPCError{
+			StatusCode: http.StatusInternalServerError,
+			Err:        err,
+		}
+	}
+
+	c.Header("X-FileHash-SHA1", hex.EncodeToString(h.Sum(nil)))
+	if err := hashfile.Filter(c.Writer, tf.SavedAt, tf.Format, hashType, known); err != nil {
+		return &RPCError{
+			StatusCode: http.StatusInternalServerError,
+			Err:        err,
+		}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package rpc
+
+import (
+	"time"
+
+	"github.com/mandiant/gocrack/server/scheduler"
+	"github.com/mandiant/gocrack/server/storage"
+
+	"github.com/rs/zerolog/log"
+)
+
+// preemptionActor is recorded as the actor of status changes made when a task is preempted & queued again
+const preemptionActor = "preemption"
+
+// checkPreemptionEvery is how often the queue is checked for tasks that should preempt running tasks
+const checkPreemptionEvery = 30 * time.Second
+
+// preemptTask stops the task so that its devices can be handed to a task of a higher priority. The worker saves a checkpoint
+// when it stops the task and it's queued again once the worker reports it has stopped
+func (s *RPCServer) preemptTask(task storage.Task) error {
+	// Set before the status changes so that the task is always queued again once it has stopped
+	if err := s.stor.SetTaskPreempted(task.TaskID, true); err != nil {
+		return err
+	}
+
+	entry, err := s.stor.ChangeTaskStatus(task.TaskID, storage.TaskStatusChange{
+		Status:    storage.TaskStatusStopping,
+		ActorType: storage.StatusActorServer,
+		Actor:     preemptionActor,
+	})
+	if err != nil {
+		return err
+	}
+	return s.wmgr.BroadcastTaskStatusChange(*entry)
+}
+
+// requeuePreemptedTask queues the task again once the worker has stopped it for a preemption. A preempted task that
+// finished or errored before it could be stopped is left as is
+func (s *RPCServer) requeuePreemptedTask(taskID string) error {
+	task, err := s.stor.GetTaskByID(taskID)
+	if err != nil {
+		return err
+	}
+
+	if !task.Preempted || task.Status == storage.TaskStatusStopping {
+		return nil
+	}
+
+	if err := s.stor.SetTaskPreempted(taskID, false); err != nil {
+		return err
+	}
+
+	if task.Status != storage.TaskStatusStopped {
+		return nil
+	}
+
+	entry, err := s.stor.ChangeTaskStatus(taskID, storage.TaskStatusChange{
+		Status:    storage.TaskStatusQueued,
+		ActorType: storage.StatusActorServer,
+		Actor:     preemptionActor,
+	})
+	if err != nil {
+		return err
+	}
+	return s.wmgr.BroadcastTaskStatusChange(*entry)
+}
+
+// preemptTasks stops the running tasks the queued tasks of a higher priority are waiting on
+func (s *RPCServer) preemptTasks(now time.Time) error {
+	queued, err := s.stor.GetTasksByStatus(storage.TaskStatusQueued)
+	if err != nil || len(queued) == 0 {
+		return err
+	}
+
+	load, err := scheduler.CurrentLoad(s.stor, s.wmgr)
+	if err != nil {
+		return err
+	}
+
+	queue := scheduler.BuildQueue(s.sched, queued, load, now)
+	for _, p := range s.preempt.Preempt(queue, load.Tasks, s.wmgr.GetCurrentWorkers(), now) {
+		log.Info().
+			Str("task_id", p.Task.TaskID).
+			Str("host", p.Host).
+			Str("preempted_for", p.For.TaskID).
+			Msg("Preempting a task to free up devices for a task of a higher priority")
+
+		if err := s.preemptTask(p.Task); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// monitorPreemption periodically preempts running tasks for the queued tasks of a higher priority until the server is stopped
+func (s *RPCServer) monitorPreemption() {
+	defer s.wg.Done()
+
+	tickEvery := time.NewTicker(checkPreemptionEvery)
+	defer tickEvery.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-tickEvery.C:
+			if err := s.preemptTasks(time.Now().UTC()); err != nil {
+				log.Error().Err(err).Msg("Failed to preempt tasks")
+			}
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package rpc
+
+import (
+	"time"
+
+	"github.com/mandiant/gocrack/server/storage"
+
+	"github.com/rs/zerolog/log"
+)
+
+// executionWindowActor is recorded as the actor of status changes made when execution windows open or close
+const executionWindowActor = "execution window"
+
+// changeWindowedTaskStatus moves the task to the status once its execution windows open or close & broadcasts the change
+func (s *RPCServer) changeWindowedTaskStatus(task storage.Task, newStatus storage.TaskStatus) error {
+	s.unitMu.Lock()
+	defer s.unitMu.Unlock()
+
+	if task.WorkUnitCount > 0 {
+		switch newStatus {
+		case storage.TaskStatusQueued:
+			if err := s.stor.RequeueWorkUnits(task.TaskID); err != nil {
+				return err
+			}
+		case storage.TaskStatusStopping:
+			units, err := s.stor.GetWorkUnits(task.TaskID)
+			if err != nil {
+				return err
+			}
+
+			if storage.SummarizeWorkUnits(task.Keyspace, units).Active == 0 {
+				newStatus = storage.TaskStatusStopped
+			}
+		}
+	}
+
+	// Set before the status changes so that a task stopped by its window is never mistaken for one stopped by a user
+	if err := s.stor.SetTaskStoppedByWindow(task.TaskID, newStatus != storage.TaskStatusQueued); err != nil {
+		return err
+	}
+
+	entry, err := s.stor.ChangeTaskStatus(task.TaskID, storage.TaskStatusChange{
+		Status:    newStatus,
+		ActorType: storage.StatusActorServer,
+		Actor:     executionWindowActor,
+	})
+	if err != nil {
+		return err
+	}
+	return s.wmgr.BroadcastTaskStatusChange(*entry)
+}
+
+// enforceExecutionWindows stops the tasks whose execution windows have closed & queues the ones it stopped again once they reopen.
+// The workers save a checkpoint when they stop so the task resumes from where it left off
+func (s *RPCServer) enforceExecutionWindows(now time.Time) error {
+	tasks, err := s.stor.GetWindowedTasks()
+	if err != nil {
+		return err
+	}
+
+	for _, task := range tasks {
+		var newStatus storage.TaskStatus
+
+		open := task.Schedule.IsOpen(now)
+		switch {
+		case !open && (task.Status == storage.TaskStatusDequeued || task.Status == storage.TaskStatusRunning):
+			newStatus = storage.TaskStatusStopping
+		case open && task.StoppedByWindow && task.Status == storage.TaskStatusStopped:
+			newStatus = storage.TaskStatusQueued
+		default:
+			continue
+		}
+
+		log.Info().
+			Str("task_id", task.TaskID).
+			Str("status", string(newStatus)).
+			Msg("Changing the status of a task as its execution window has changed")
+
+		if err := s.changeWindowedTaskStatus(task, newStatus); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// monitorExecutionWindows periodically enforces the execution windows of tasks until the server is stopped
+func (s *RPCServer) monitorExecutionWindows() {
+	defer s.wg.Done()
+
+	tickEvery := time.NewTicker(time.Minute)
+	defer tickEvery.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-tickEvery.C:
+			if err := s.enforceExecutionWindows(time.Now().UTC()); err != nil {
+				log.Error().Err(err).Msg("Failed to enforce the execution windows of tasks")
+			}
+		}
+	}
+}
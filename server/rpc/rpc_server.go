@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"sync"
 
+	"github.com/mandiant/gocrack/server/scheduler"
 	"github.com/mandiant/gocrack/server/storage"
 	"github.com/mandiant/gocrack/server/workmgr"
 	"github.com/mandiant/gocrack/shared"
@@ -19,11 +21,17 @@ import (
 
 type (
 	RPCServer struct {
-		stor   storage.Backend
-		wmgr   *workmgr.WorkerManager
-		engine *gin.Engine
-		l      net.Listener
-		cfg    Config
+		stor    storage.Backend
+		wmgr    *workmgr.WorkerManager
+		sched   scheduler.Scheduler
+		preempt scheduler.PreemptionConfig
+		engine  *gin.Engine
+		l       net.Listener
+		cfg     Config
+		unitMu  sync.Mutex
+		stop    chan bool
+		stopped sync.Once
+		wg      *sync.WaitGroup
 
 		*http.Server
 	}
@@ -51,8 +59,9 @@ func WrapCallError(f RPCAPI) gin.HandlerFunc {
 	}
 }
 
-// NewRPCServer creates an RPC server that workers connect to
-func NewRPCServer(cfg Config, stor storage.Backend, wmgr *workmgr.WorkerManager) (*RPCServer, error) {
+// NewRPCServer creates an RPC server that workers connect to. The scheduler decides which queued task a worker is given next
+// and preempt configures when running tasks are stopped to free up their devices for a queued task
+func NewRPCServer(cfg Config, stor storage.Backend, wmgr *workmgr.WorkerManager, sched scheduler.Scheduler, preempt scheduler.PreemptionConfig) (*RPCServer, error) {
 	var l net.Listener
 	var err error
 
@@ -77,9 +86,14 @@ func NewRPCServer(cfg Config, stor storage.Backend, wmgr *workmgr.WorkerManager)
 	}
 
 	svr := &RPCServer{
-		stor: stor,
-		wmgr: wmgr,
-		l:    l,
+		stor:    stor,
+		wmgr:    wmgr,
+		sched:   sched,
+		preempt: preempt,
+		l:       l,
+		cfg:     cfg,
+		stop:    make(chan bool, 1),
+		wg:      &sync.WaitGroup{},
 	}
 	svr.initRPCEngineAndServer()
 
@@ -88,6 +102,15 @@ func NewRPCServer(cfg Config, stor storage.Backend, wmgr *workmgr.WorkerManager)
 
 // Start the RPC server and handle requests from workers
 func (s *RPCServer) Start() error {
+	s.wg.Add(2)
+	go s.monitorWorkUnits()
+	go s.monitorExecutionWindows()
+
+	if s.preempt.Enabled {
+		s.wg.Add(1)
+		go s.monitorPreemption()
+	}
+
 	return s.Serve(s.l)
 }
 
@@ -98,6 +121,9 @@ func (s *RPCServer) Address() string {
 
 // Stop the RPC server gracefully
 func (s *RPCServer) Stop() error {
+	s.stopped.Do(func() { close(s.stop) })
+	s.wg.Wait()
+
 	if err := s.Shutdown(context.Background()); err != nil {
 		if err == http.ErrServerClosed {
 			return nil
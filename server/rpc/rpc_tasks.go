@@ -21,13 +21,36 @@ const (
 )
 
 type ChangeTaskStatusRequest struct {
-	TaskID    string
-	NewStatus storage.TaskStatus
-	Error     *string
+	TaskID     string
+	WorkUnitID string
+	NewStatus  storage.TaskStatus
+	Error      *string
+	// Hostname & Devices describe the worker changing the status and are recorded in the task's status history
+	Hostname string
+	Devices  storage.CLDevices
+}
+
+// statusChange returns the change to the task's status requested by the worker
+func (s ChangeTaskStatusRequest) statusChange() storage.TaskStatusChange {
+	return storage.TaskStatusChange{
+		Status:    s.NewStatus,
+		Error:     s.Error,
+		ActorType: storage.StatusActorWorker,
+		Actor:     s.Hostname,
+		Devices:   s.Devices,
+	}
 }
 
 type RequestTaskPayload struct {
-	TaskID string
+	TaskID     string
+	WorkUnitID string
+}
+
+// WorkUnitRange is the slice of a distributed task's dictionary words or masks that a worker should process
+type WorkUnitRange struct {
+	UnitID string
+	Skip   uint64
+	Limit  uint64
 }
 
 type NewTaskPayloadResponse struct {
@@ -36,12 +59,17 @@ type NewTaskPayloadResponse struct {
 	Engine        storage.WorkerCrackEngine
 	Priority      storage.WorkerPriority
 	EnginePayload json.RawMessage
-	TaskDuration  int
+	// TaskDuration is the number of seconds left of the task's time budget. 0 means the task can run until it's done
+	TaskDuration int
+	WorkUnit     *WorkUnitRange
 }
 
 type TaskFileGetRequest struct {
 	FileID string
 	Type   FileType
+	// TaskID is the task the file is downloaded for. Hashes the task was credited with from the known hash index are
+	// removed from its task file so they are not cracked again
+	TaskID string
 }
 
 type CrackedPasswordRequest struct {
@@ -52,10 +80,13 @@ type CrackedPasswordRequest struct {
 }
 
 type TaskStatusUpdate struct {
-	Engine  storage.WorkerCrackEngine
-	TaskID  string
-	Final   bool
-	Payload interface{}
+	Engine     storage.WorkerCrackEngine
+	TaskID     string
+	WorkUnitID string
+	Final      bool
+	Payload    interface{}
+	// Progress is the percentage of the work unit that has been processed if the engine reports it
+	Progress *float64
 }
 
 type TaskCheckpointSaveRequest struct {
@@ -73,14 +104,40 @@ func (s *RPCServer) changeTaskStatus(c *gin.Context) *RPCError {
 		}
 	}
 
-	if err := s.stor.ChangeTaskStatus(req.TaskID, req.NewStatus, req.Error); err != nil {
+	// workers that do not identify themselves are recorded by their address
+	if req.Hostname == "" {
+		req.Hostname = c.ClientIP()
+	}
+
+	// The status of a distributed task is determined by the state of all its units
+	if req.WorkUnitID != "" {
+		if err := s.changeWorkUnitStatus(req); err != nil {
+			return &RPCError{
+				StatusCode: http.StatusInternalServerError,
+				Err:        err,
+			}
+		}
+
+		c.Status(http.StatusNoContent)
+		return nil
+	}
+
+	entry, err := s.stor.ChangeTaskStatus(req.TaskID, req.statusChange())
+	if err != nil {
+		return &RPCError{
+			StatusCode: http.StatusInternalServerError,
+			Err:        err,
+		}
+	}
+
+	if err := s.wmgr.BroadcastTaskStatusChange(*entry); err != nil {
 		return &RPCError{
 			StatusCode: http.StatusInternalServerError,
 			Err:        err,
 		}
 	}
 
-	if err := s.wmgr.BroadcastTaskStatusChange(req.TaskID, req.NewStatus); err != nil {
+	if err := s.requeuePreemptedTask(req.TaskID); err != nil {
 		return &RPCError{
 			StatusCode: http.StatusInternalServerError,
 			Err:        err,
@@ -116,14 +173,39 @@ func (s *RPCServer) getTaskPayload(c *gin.Context) *RPCError {
 		}
 	}
 
-	c.JSON(http.StatusOK, &NewTaskPayloadResponse{
+	resp := &NewTaskPayloadResponse{
 		TaskID:        task.TaskID,
 		FileID:        task.FileID,
 		Engine:        task.Engine,
 		EnginePayload: engineBytes,
 		Priority:      task.Priority,
-		TaskDuration:  task.TaskDuration,
-	})
+	}
+
+	// The task may have run before so the worker is only given what's left of the budget
+	if remaining, ok := task.TimeRemaining(time.Now().UTC()); ok {
+		if remaining < 1 {
+			remaining = 1
+		}
+		resp.TaskDuration = remaining
+	}
+
+	if req.WorkUnitID != "" {
+		unit, err := s.stor.GetWorkUnit(req.WorkUnitID)
+		if err != nil {
+			return &RPCError{
+				StatusCode: http.StatusInternalServerError,
+				Err:        err,
+			}
+		}
+
+		resp.WorkUnit = &WorkUnitRange{
+			UnitID: unit.UnitID,
+			Skip:   unit.Skip,
+			Limit:  unit.Limit,
+		}
+	}
+
+	c.JSON(http.StatusOK, resp)
 
 	return nil
 }
@@ -150,6 +232,29 @@ func (s *RPCServer) getTaskFile(c *gin.Context) *RPCError {
 				Err:        err,
 			}
 		}
+		if req.TaskID != "" {
+			known, err := s.reusedHashes(req.TaskID)
+			if err != nil {
+				return &RPCError{
+					StatusCode: http.StatusInternalServerError,
+					Err:        err,
+				}
+			}
+
+			if len(known) > 0 {
+				task, err := s.stor.GetTaskByID(req.TaskID)
+				if err != nil {
+					return &RPCError{
+						StatusCode: http.StatusInternalServerError,
+						Err:        err,
+					}
+				}
+
+				if hashType, ok := storage.TaskHashType(task); ok {
+					return s.sendFilteredTaskFile(c, tf, hashType, known)
+				}
+			}
+		}
 		c.Header("X-FileHash-SHA1", tf.SHA1Hash)
 		locationOnDisk = tf.SavedAt
 	case FileTypeEngine:
@@ -205,6 +310,7 @@ func (s *RPCServer) saveCrackedPassword(c *gin.Context) *RPCError {
 			Err:        err,
 		}
 	}
+	s.indexCrackedHash(req)
 
 	if err := s.wmgr.BroadcastCrackedPassword(req.TaskID, req.Hash, req.Value, req.CrackedAt); err != nil {
 		return &RPCError{
@@ -226,7 +332,17 @@ func (s *RPCServer) taskStatusUpdate(c *gin.Context) *RPCError {
 		}
 	}
 
-	switch req.Final {
+	if req.WorkUnitID != "" && req.Progress != nil {
+		if err := s.updateWorkUnitProgress(req.WorkUnitID, *req.Progress); err != nil {
+			return &RPCError{
+				StatusCode: http.StatusInternalServerError,
+				Err:        err,
+			}
+		}
+	}
+
+	// The final status of a single work unit is not the final status of the task
+	switch req.Final && req.WorkUnitID == "" {
 	case true:
 		if err := s.wmgr.BroadcastFinalStatus(req.TaskID, req.Payload); err != nil {
 			return &RPCError{
@@ -0,0 +1,240 @@
+package rpc
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mandiant/gocrack/server/storage"
+
+	"github.com/rs/zerolog/log"
+)
+
+// changeWorkUnitStatus records the status of a unit reported by a worker and updates the parent task
+func (s *RPCServer) changeWorkUnitStatus(req ChangeTaskStatusRequest) error {
+	if err := s.saveWorkUnitStatus(req); err != nil {
+		return err
+	}
+	return s.aggregateWorkUnits(req.TaskID, req.statusChange())
+}
+
+// saveWorkUnitStatus reads & saves the unit under unitMu so the change does not race the lost unit monitor
+func (s *RPCServer) saveWorkUnitStatus(req ChangeTaskStatusRequest) error {
+	s.unitMu.Lock()
+	defer s.unitMu.Unlock()
+
+	unit, err := s.stor.GetWorkUnit(req.WorkUnitID)
+	if err != nil {
+		return err
+	}
+
+	if unit.TaskID != req.TaskID {
+		return fmt.Errorf("work unit %s does not belong to task %s", req.WorkUnitID, req.TaskID)
+	}
+	return s.setWorkUnitStatus(unit, req.NewStatus, req.Error)
+}
+
+// setWorkUnitStatus saves the status of a unit. Units that stopped or errored without the task being stopped are
+// requeued so another worker can pick them up. The caller must hold unitMu
+func (s *RPCServer) setWorkUnitStatus(unit *storage.WorkUnit, status storage.TaskStatus, unitErr *string) error {
+	unit.Status = status
+	unit.LastUpdatedAt = time.Now().UTC()
+	if unitErr != nil {
+		unit.Error = unitErr
+	}
+
+	switch status {
+	case storage.TaskStatusExhausted, storage.TaskStatusFinished:
+		unit.Progress = 100
+	case storage.TaskStatusError, storage.TaskStatusStopped:
+		task, err := s.stor.GetTaskByID(unit.TaskID)
+		if err != nil {
+			return err
+		}
+
+		// the task is stopping or done so the unit should not run again
+		if task.Status != storage.TaskStatusQueued && task.Status != storage.TaskStatusDequeued && task.Status != storage.TaskStatusRunning {
+			break
+		}
+
+		if status == storage.TaskStatusError && unit.Attempts >= s.cfg.WorkUnits.MaxAttempts {
+			log.Warn().
+				Str("task_id", unit.TaskID).
+				Str("unit_id", unit.UnitID).
+				Int("attempts", unit.Attempts).
+				Msg("Work unit has failed too many times and will not be requeued")
+			break
+		}
+
+		unit.Status = storage.TaskStatusQueued
+		unit.AssignedToHost = ""
+		unit.Progress = 0
+	}
+
+	return s.stor.UpdateWorkUnit(*unit)
+}
+
+// aggregateWorkUnits calculates the status & progress of a distributed task from its units and broadcasts any changes.
+// cause describes who triggered the calculation and is recorded if the status of the task changes
+func (s *RPCServer) aggregateWorkUnits(taskID string, cause storage.TaskStatusChange) error {
+	s.unitMu.Lock()
+	defer s.unitMu.Unlock()
+
+	task, err := s.stor.GetTaskByID(taskID)
+	if err != nil {
+		return err
+	}
+
+	units, err := s.stor.GetWorkUnits(taskID)
+	if err != nil {
+		return err
+	}
+
+	summary := storage.SummarizeWorkUnits(task.Keyspace, units)
+	if err := s.stor.SetTaskProgress(taskID, summary.Progress); err != nil {
+		return err
+	}
+
+	if newStatus := summary.TaskStatus(task.Status); newStatus != task.Status {
+		change := cause
+		change.Status = newStatus
+		if newStatus != storage.TaskStatusError {
+			change.Error = nil
+		}
+
+		entry, err := s.stor.ChangeTaskStatus(taskID, change)
+		if err != nil {
+			return err
+		}
+
+		if err := s.wmgr.BroadcastTaskStatusChange(*entry); err != nil {
+			return err
+		}
+	}
+	return s.wmgr.BroadcastTaskProgress(taskID, summary)
+}
+
+// updateWorkUnitProgress records the progress of a unit reported by the engine
+func (s *RPCServer) updateWorkUnitProgress(unitID string, progress float64) error {
+	unit, err := s.saveWorkUnitProgress(unitID, progress)
+	if err != nil {
+		return err
+	}
+	return s.aggregateWorkUnits(unit.TaskID, storage.TaskStatusChange{
+		ActorType: storage.StatusActorWorker,
+		Actor:     unit.AssignedToHost,
+	})
+}
+
+// saveWorkUnitProgress reads & saves the unit under unitMu so the change does not race the lost unit monitor
+func (s *RPCServer) saveWorkUnitProgress(unitID string, progress float64) (*storage.WorkUnit, error) {
+	s.unitMu.Lock()
+	defer s.unitMu.Unlock()
+
+	unit, err := s.stor.GetWorkUnit(unitID)
+	if err != nil {
+		return nil, err
+	}
+
+	unit.Progress = progress
+	unit.LastUpdatedAt = time.Now().UTC()
+	if err := s.stor.UpdateWorkUnit(*unit); err != nil {
+		return nil, err
+	}
+	return unit, nil
+}
+
+// isWorkUnitLost returns true if the worker processing the unit has gone away or is no longer running its task
+func (s *RPCServer) isWorkUnitLost(unit storage.WorkUnit, now time.Time) bool {
+	lostAfter := s.cfg.WorkUnits.LostAfter.Duration
+	if now.Sub(unit.LastUpdatedAt) < lostAfter {
+		return false
+	}
+
+	host := s.wmgr.GetCurrentHostRecord(unit.AssignedToHost)
+	if host == nil || now.Sub(host.LastCheckin) >= lostAfter {
+		return true
+	}
+
+	_, running := host.LastBeacon.Processes[unit.TaskID]
+	return !running
+}
+
+// requeueLostWorkUnit releases the unit to the other workers if it's still lost once unitMu is held as the worker may have
+// reported on it since the active units were listed. Returns true if the unit was requeued
+func (s *RPCServer) requeueLostWorkUnit(unitID string, now time.Time) (bool, error) {
+	s.unitMu.Lock()
+	defer s.unitMu.Unlock()
+
+	unit, err := s.stor.GetWorkUnit(unitID)
+	if err != nil {
+		return false, err
+	}
+
+	switch unit.Status {
+	case storage.TaskStatusDequeued, storage.TaskStatusRunning, storage.TaskStatusStopping:
+	default:
+		return false, nil
+	}
+
+	if !s.isWorkUnitLost(*unit, now) {
+		return false, nil
+	}
+
+	log.Warn().
+		Str("task_id", unit.TaskID).
+		Str("unit_id", unit.UnitID).
+		Str("host", unit.AssignedToHost).
+		Msg("Work unit has been lost by its worker")
+
+	lostErr := fmt.Sprintf("lost by %s", unit.AssignedToHost)
+	return true, s.setWorkUnitStatus(unit, storage.TaskStatusStopped, &lostErr)
+}
+
+// requeueLostWorkUnits finds units whose worker has gone away and releases them to the other workers. A unit that
+// fails to be requeued is logged so that it does not hold back the others
+func (s *RPCServer) requeueLostWorkUnits() error {
+	units, err := s.stor.GetActiveWorkUnits()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	for _, unit := range units {
+		if !s.isWorkUnitLost(unit, now) {
+			continue
+		}
+
+		requeued, err := s.requeueLostWorkUnit(unit.UnitID, now)
+		if err == nil && requeued {
+			err = s.aggregateWorkUnits(unit.TaskID, storage.TaskStatusChange{ActorType: storage.StatusActorServer})
+		}
+
+		if err != nil {
+			log.Error().
+				Err(err).
+				Str("task_id", unit.TaskID).
+				Str("unit_id", unit.UnitID).
+				Msg("Failed to requeue a lost work unit")
+		}
+	}
+	return nil
+}
+
+// monitorWorkUnits periodically requeues work units that have been lost until the server is stopped
+func (s *RPCServer) monitorWorkUnits() {
+	defer s.wg.Done()
+
+	tickEvery := time.NewTicker(s.cfg.WorkUnits.LostAfter.Duration / 2)
+	defer tickEvery.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-tickEvery.C:
+			if err := s.requeueLostWorkUnits(); err != nil {
+				log.Error().Err(err).Msg("Failed to requeue lost work units")
+			}
+		}
+	}
+}
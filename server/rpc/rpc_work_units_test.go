@@ -0,0 +1,163 @@
+package rpc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mandiant/gocrack/server/scheduler"
+	"github.com/mandiant/gocrack/server/servertest"
+	"github.com/mandiant/gocrack/server/storage"
+	"github.com/mandiant/gocrack/server/workmgr"
+	"github.com/mandiant/gocrack/shared"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestServer(t *testing.T) (*RPCServer, *servertest.Fixture, *workmgr.WorkerManager, func()) {
+	f, closer := servertest.New(t, "bdb")
+	cfg := Config{WorkUnits: WorkUnitConfig{
+		LostAfter:   shared.HumanDuration{Duration: time.Minute},
+		MaxAttempts: 3,
+	}}
+
+	wmgr := workmgr.NewWorkerManager()
+	return &RPCServer{stor: f.Stor, wmgr: wmgr, cfg: cfg}, f, wmgr, func() {
+		wmgr.Stop()
+		closer()
+	}
+}
+
+// createDistributedTask saves the task split into the number of units. The IDs & keyspace of the task are filled in
+func createDistributedTask(t *testing.T, f *servertest.Fixture, task storage.Task, units int) (storage.Task, []storage.WorkUnit) {
+	task.TaskID = uuid.NewString()
+	task.TaskName = "Distributed"
+	task.FileID = uuid.NewString()
+	task.CreatedByUUID = f.CreateUser(t, false).UserUUID
+	task.CreatedAt = time.Now().UTC()
+	task.Keyspace = 100
+	workUnits := storage.SplitKeyspace(task.TaskID, task.Keyspace, units)
+	task.WorkUnitCount = len(workUnits)
+
+	txn, err := f.Stor.NewTaskCreateTransaction()
+	if err != nil {
+		assert.FailNow(t, "failed to create task transaction", err.Error())
+	}
+	assert.Nil(t, txn.CreateTask(&task))
+	assert.Nil(t, txn.CreateWorkUnits(workUnits))
+	assert.Nil(t, txn.Commit())
+	return task, workUnits
+}
+
+func TestRequeueLostWorkUnits(t *testing.T) {
+	s, f, wmgr, cleanup := newTestServer(t)
+	defer cleanup()
+	stor := f.Stor
+
+	task, units := createDistributedTask(t, f, storage.Task{Status: storage.TaskStatusRunning}, 3)
+	lastUpdate := time.Now().UTC().Add(-time.Hour)
+
+	// the first two units were dispatched to a worker that has gone away while the last is still being reported
+	wmgr.HostCheckingIn(shared.Beacon{
+		Hostname:  "alive",
+		Processes: map[string]shared.TaskProcess{task.TaskID: {}},
+	})
+	for i, host := range []string{"gone", "gone", "alive"} {
+		units[i].Status = storage.TaskStatusRunning
+		units[i].AssignedToHost = host
+		units[i].Attempts = 1
+		units[i].LastUpdatedAt = lastUpdate
+		assert.Nil(t, stor.UpdateWorkUnit(units[i]))
+	}
+
+	assert.Nil(t, s.requeueLostWorkUnits())
+
+	found, err := stor.GetWorkUnits(task.TaskID)
+	if assert.Nil(t, err) && assert.Len(t, found, 3) {
+		for _, unit := range found[:2] {
+			assert.Equal(t, storage.TaskStatusQueued, unit.Status)
+			assert.Empty(t, unit.AssignedToHost)
+			if assert.NotNil(t, unit.Error) {
+				assert.Equal(t, "lost by gone", *unit.Error)
+			}
+		}
+		assert.Equal(t, storage.TaskStatusRunning, found[2].Status)
+	}
+
+	// a unit that has been reported on since it was listed is left alone
+	requeued, err := s.requeueLostWorkUnit(units[2].UnitID, time.Now().UTC())
+	assert.Nil(t, err)
+	assert.False(t, requeued)
+}
+
+func TestWorkUnitOutOfTime(t *testing.T) {
+	s, f, _, cleanup := newTestServer(t)
+	defer cleanup()
+	stor := f.Stor
+
+	task, units := createDistributedTask(t, f, storage.Task{
+		Status:       storage.TaskStatusRunning,
+		TaskDuration: 60,
+		Runtime:      60,
+	}, 2)
+
+	units[0].Status = storage.TaskStatusRunning
+	units[0].AssignedToHost = "worker"
+	units[0].Attempts = 1
+	assert.Nil(t, stor.UpdateWorkUnit(units[0]))
+
+	// the queued unit is not handed out once the worker reports the budget of the task has run out
+	assert.Nil(t, s.changeWorkUnitStatus(ChangeTaskStatusRequest{
+		TaskID:     task.TaskID,
+		WorkUnitID: units[0].UnitID,
+		NewStatus:  storage.TaskStatusOutOfTime,
+		Hostname:   "worker",
+	}))
+
+	found, err := stor.GetTaskByID(task.TaskID)
+	if assert.Nil(t, err) {
+		assert.Equal(t, storage.TaskStatusOutOfTime, found.Status)
+		assert.True(t, found.IsOutOfTime(time.Now().UTC()))
+	}
+
+	foundUnits, err := stor.GetWorkUnits(task.TaskID)
+	if assert.Nil(t, err) && assert.Len(t, foundUnits, 2) {
+		assert.Equal(t, storage.TaskStatusOutOfTime, foundUnits[0].Status)
+		assert.Equal(t, storage.TaskStatusQueued, foundUnits[1].Status)
+	}
+
+	// resuming the task once its budget is raised hands out every unit again
+	assert.Nil(t, stor.UpdateTask(task.TaskID, storage.ModifiableTaskRequest{TaskDuration: shared.GetIntPtr(120)}))
+	found, err = stor.GetTaskByID(task.TaskID)
+	if assert.Nil(t, err) {
+		assert.False(t, found.IsOutOfTime(time.Now().UTC()))
+	}
+
+	assert.Nil(t, stor.RequeueWorkUnits(task.TaskID))
+	_, err = stor.ChangeTaskStatus(task.TaskID, storage.TaskStatusChange{Status: storage.TaskStatusQueued, ActorType: storage.StatusActorUser})
+	assert.Nil(t, err)
+	assert.Nil(t, s.aggregateWorkUnits(task.TaskID, storage.TaskStatusChange{ActorType: storage.StatusActorServer}))
+
+	found, err = stor.GetTaskByID(task.TaskID)
+	if assert.Nil(t, err) {
+		assert.Equal(t, storage.TaskStatusQueued, found.Status)
+	}
+
+	foundUnits, err = stor.GetWorkUnits(task.TaskID)
+	if assert.Nil(t, err) && assert.Len(t, foundUnits, 2) {
+		for _, unit := range foundUnits {
+			assert.Equal(t, storage.TaskStatusQueued, unit.Status)
+			assert.Empty(t, unit.AssignedToHost)
+		}
+	}
+}
+
+func TestStopTwice(t *testing.T) {
+	s, err := NewRPCServer(Config{Listener: shared.ServerCfg{Address: "127.0.0.1:0"}}, nil, nil, nil, scheduler.PreemptionConfig{})
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	assert.Nil(t, s.Stop())
+	assert.Nil(t, s.Stop())
+}
@@ -0,0 +1,43 @@
+package scheduler
+
+import (
+	"errors"
+
+	"github.com/mandiant/gocrack/shared"
+)
+
+// Config describes how the server decides which queued task a worker is given next
+type Config struct {
+	// Type is the name of the scheduler. Defaults to fair_share
+	Type       string           `yaml:"type"`
+	FairShare  FairShareConfig  `yaml:"fair_share"`
+	Preemption PreemptionConfig `yaml:"preemption"`
+}
+
+// FairShareConfig limits how much of the workers a single user or case can take up. A limit of 0 is unlimited
+type FairShareConfig struct {
+	MaxTasksPerUser   int `yaml:"max_tasks_per_user"`
+	MaxDevicesPerUser int `yaml:"max_devices_per_user"`
+	MaxTasksPerCase   int `yaml:"max_tasks_per_case"`
+	MaxDevicesPerCase int `yaml:"max_devices_per_case"`
+	// AgingInterval raises the priority of a queued task by a level every time the interval passes since it was last queued.
+	// Aging is disabled if 0
+	AgingInterval shared.HumanDuration `yaml:"aging_interval"`
+}
+
+// Validate the config and set any default options
+func (s *Config) Validate() error {
+	if s.Type == "" {
+		s.Type = FairShareScheduler
+	}
+
+	fs := s.FairShare
+	if fs.MaxTasksPerUser < 0 || fs.MaxDevicesPerUser < 0 || fs.MaxTasksPerCase < 0 || fs.MaxDevicesPerCase < 0 {
+		return errors.New("scheduler.fair_share limits must not be negative")
+	}
+
+	if fs.AgingInterval.Duration < 0 {
+		return errors.New("scheduler.fair_share.aging_interval must not be negative")
+	}
+	return s.Preemption.Validate()
+}
@@ -0,0 +1,113 @@
+package scheduler
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/mandiant/gocrack/server/storage"
+)
+
+// FairShareScheduler shares the workers between users and cases. Each is limited to a number of concurrent tasks and
+// devices, and the tasks of users running the fewest tasks are handed out first within a priority
+const FairShareScheduler = "fair_share"
+
+func init() {
+	Register(FairShareScheduler, func(cfg Config) (Scheduler, error) {
+		return &fairShare{cfg: cfg.FairShare}, nil
+	})
+}
+
+type fairShare struct {
+	cfg FairShareConfig
+}
+
+// usage is the number of tasks & devices a user or case has in use
+type usage struct {
+	tasks   int
+	devices int
+}
+
+func caseCode(task storage.Task) string {
+	if task.CaseCode == nil {
+		return ""
+	}
+	return *task.CaseCode
+}
+
+// agedPriority raises the priority of the task by a level for every aging interval it has waited since it was last queued.
+// A task that is requeued after being stopped or preempted starts aging again so it does not immediately outrank the
+// tasks it was stopped for
+func (s *fairShare) agedPriority(task storage.Task, now time.Time) storage.WorkerPriority {
+	if s.cfg.AgingInterval.Duration <= 0 {
+		return task.Priority
+	}
+
+	aged := task.Priority - storage.WorkerPriority(now.Sub(task.QueuedAt)/s.cfg.AgingInterval.Duration)
+	if aged < storage.WorkerPriorityHigh {
+		return storage.WorkerPriorityHigh
+	}
+	return aged
+}
+
+// overLimit returns why the usage does not allow another task to be handed out. A task that is already being processed
+// does not count against the limit of tasks as handing out more of its work units does not add a task
+func overLimit(who string, use usage, active bool, maxTasks, maxDevices int) string {
+	if active {
+		use.tasks--
+	}
+
+	switch {
+	case maxTasks > 0 && use.tasks >= maxTasks:
+		return fmt.Sprintf("%s has reached the limit of %d concurrent tasks", who, maxTasks)
+	case maxDevices > 0 && use.devices >= maxDevices:
+		return fmt.Sprintf("%s has reached the limit of %d devices", who, maxDevices)
+	}
+	return ""
+}
+
+func (s *fairShare) Queue(queued []storage.Task, load Load, now time.Time) []Entry {
+	users := make(map[string]usage)
+	cases := make(map[string]usage)
+	active := make(map[string]bool, len(load.Tasks))
+
+	for _, task := range load.Tasks {
+		active[task.TaskID] = true
+		devices := load.Devices[task.TaskID]
+
+		u := users[task.CreatedByUUID]
+		users[task.CreatedByUUID] = usage{tasks: u.tasks + 1, devices: u.devices + devices}
+
+		if code := caseCode(task); code != "" {
+			c := cases[code]
+			cases[code] = usage{tasks: c.tasks + 1, devices: c.devices + devices}
+		}
+	}
+
+	entries := make([]Entry, len(queued))
+	for i, task := range queued {
+		entries[i] = Entry{
+			Task:     task,
+			Priority: s.agedPriority(task, now),
+			Reason:   overLimit("user", users[task.CreatedByUUID], active[task.TaskID], s.cfg.MaxTasksPerUser, s.cfg.MaxDevicesPerUser),
+		}
+
+		if code := caseCode(task); entries[i].Reason == "" && code != "" {
+			entries[i].Reason = overLimit("case "+code, cases[code], active[task.TaskID], s.cfg.MaxTasksPerCase, s.cfg.MaxDevicesPerCase)
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.Priority != b.Priority {
+			return a.Priority < b.Priority
+		}
+
+		// users running fewer tasks go first so a user with many queued tasks can not take every worker
+		if ua, ub := users[a.Task.CreatedByUUID].tasks, users[b.Task.CreatedByUUID].tasks; ua != ub {
+			return ua < ub
+		}
+		return a.Task.CreatedAt.Before(b.Task.CreatedAt)
+	})
+	return numberEntries(entries)
+}
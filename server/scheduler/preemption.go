@@ -0,0 +1,196 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mandiant/gocrack/server/storage"
+	"github.com/mandiant/gocrack/server/workmgr"
+	"github.com/mandiant/gocrack/shared"
+)
+
+var priorityNames = map[string]storage.WorkerPriority{
+	"high":   storage.WorkerPriorityHigh,
+	"normal": storage.WorkerPriorityNormal,
+	"low":    storage.WorkerPriorityLow,
+}
+
+// PreemptionConfig allows a queued task to stop a running task of a lower priority when there are no free devices for it.
+// The stopped task saves a checkpoint & is queued again so that it resumes once devices are free
+type PreemptionConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Priorities are the names of the priorities whose tasks can preempt others. Defaults to high
+	Priorities []string `yaml:"priorities"`
+	// MinRuntime is how long a task must have been running before it can be preempted
+	MinRuntime shared.HumanDuration `yaml:"min_runtime"`
+}
+
+// Preemption is a running task that is stopped so that its devices are handed to a queued task
+type Preemption struct {
+	Task storage.Task // Task is the running task being stopped
+	Host string       // Host is the worker running the task
+	For  storage.Task // For is the queued task the devices are freed up for
+}
+
+// Validate the config and set any default options
+func (s *PreemptionConfig) Validate() error {
+	if len(s.Priorities) == 0 {
+		s.Priorities = []string{"high"}
+	}
+
+	for _, name := range s.Priorities {
+		if _, ok := priorityNames[strings.ToLower(name)]; !ok {
+			return fmt.Errorf("scheduler.preemption.priorities: `%s` is not one of high, normal or low", name)
+		}
+	}
+
+	if s.MinRuntime.Duration < 0 {
+		return errors.New("scheduler.preemption.min_runtime must not be negative")
+	}
+	return nil
+}
+
+// canPreempt returns true if tasks of the priority can preempt others
+func (s PreemptionConfig) canPreempt(priority storage.WorkerPriority) bool {
+	for _, name := range s.Priorities {
+		if p, ok := priorityNames[strings.ToLower(name)]; ok && p == priority {
+			return true
+		}
+	}
+	return false
+}
+
+// runningProcess is a task a worker reported it's running
+type runningProcess struct {
+	host    string
+	devices []int
+}
+
+// hasFreeDevices returns true if a worker the task can be handed to has a device that is not busy
+func hasFreeDevices(task storage.Task, hosts map[string]workmgr.ConnectedHost) bool {
+	for hostname, host := range hosts {
+		if task.AssignedToHost != "" && task.AssignedToHost != hostname {
+			continue
+		}
+
+		if task.AssignedToDevices == nil || len(*task.AssignedToDevices) == 0 {
+			if host.LastBeacon.Devices.HasFreeDevices() {
+				return true
+			}
+			continue
+		}
+
+		for _, id := range *task.AssignedToDevices {
+			if dev, ok := host.LastBeacon.Devices[id]; ok && !dev.IsBusy {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// frees returns true if stopping the process would free up devices the task can use
+func frees(task storage.Task, proc runningProcess) bool {
+	if task.AssignedToHost != "" && task.AssignedToHost != proc.host {
+		return false
+	}
+
+	if task.AssignedToDevices == nil || len(*task.AssignedToDevices) == 0 {
+		return true
+	}
+
+	for _, want := range *task.AssignedToDevices {
+		for _, id := range proc.devices {
+			if id == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// freedBy returns the index of the first process that frees up devices the task can use or -1 if there is none
+func freedBy(task storage.Task, procs []runningProcess) int {
+	for i, proc := range procs {
+		if frees(task, proc) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Preempt picks the running tasks that should be stopped for the tasks in the queue. Only tasks that can be handed out,
+// are of a priority allowed to preempt & have no free devices to run on are considered. Each of them preempts the running
+// task of the lowest priority below its own that has run for at least the minimum runtime, preferring the one that started last.
+// Distributed tasks are never preempted as their work units are already shared between the workers
+func (s PreemptionConfig) Preempt(queue []Entry, active []storage.Task, hosts map[string]workmgr.ConnectedHost, now time.Time) []Preemption {
+	if !s.Enabled {
+		return nil
+	}
+
+	procs := make(map[string]runningProcess)
+	for hostname, host := range hosts {
+		for taskID, proc := range host.LastBeacon.Processes {
+			procs[taskID] = runningProcess{host: hostname, devices: proc.UsingDevices}
+		}
+	}
+
+	// tasks that are already stopping for a preemption will free up devices for the first of the waiting tasks that can use them
+	var stopping []runningProcess
+	for _, task := range active {
+		if proc, ok := procs[task.TaskID]; ok && task.Preempted && task.Status == storage.TaskStatusStopping {
+			stopping = append(stopping, proc)
+		}
+	}
+
+	taken := make(map[string]bool)
+	var out []Preemption
+
+	for _, entry := range queue {
+		task := entry.Task
+		if entry.Reason != "" || !s.canPreempt(task.Priority) || hasFreeDevices(task, hosts) {
+			continue
+		}
+
+		if i := freedBy(task, stopping); i >= 0 {
+			stopping = append(stopping[:i], stopping[i+1:]...)
+			continue
+		}
+
+		var victim *storage.Task
+		var victimHost string
+
+		for i := range active {
+			candidate := &active[i]
+			if candidate.Status != storage.TaskStatusRunning || candidate.WorkUnitCount > 0 || candidate.Preempted ||
+				taken[candidate.TaskID] || candidate.Priority <= task.Priority {
+				continue
+			}
+
+			if candidate.RunningSince == nil || now.Sub(*candidate.RunningSince) < s.MinRuntime.Duration {
+				continue
+			}
+
+			proc, ok := procs[candidate.TaskID]
+			if !ok || !frees(task, proc) {
+				continue
+			}
+
+			if victim == nil || candidate.Priority > victim.Priority ||
+				(candidate.Priority == victim.Priority && candidate.RunningSince.After(*victim.RunningSince)) {
+				victim = candidate
+				victimHost = proc.host
+			}
+		}
+
+		if victim == nil {
+			continue
+		}
+
+		taken[victim.TaskID] = true
+		out = append(out, Preemption{Task: *victim, Host: victimHost, For: task})
+	}
+	return out
+}
@@ -0,0 +1,120 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mandiant/gocrack/server/storage"
+	"github.com/mandiant/gocrack/server/workmgr"
+	"github.com/mandiant/gocrack/shared"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func preemptedIDs(preemptions []Preemption) map[string]string {
+	out := make(map[string]string, len(preemptions))
+	for _, p := range preemptions {
+		out[p.For.TaskID] = p.Task.TaskID
+	}
+	return out
+}
+
+// busyHost returns a worker whose devices are all in use by the tasks
+func busyHost(hostname string, tasks map[string][]int) workmgr.ConnectedHost {
+	beacon := shared.Beacon{
+		Hostname:  hostname,
+		Devices:   shared.DeviceMap{},
+		Processes: make(map[string]shared.TaskProcess),
+	}
+
+	for taskID, devices := range tasks {
+		beacon.Processes[taskID] = shared.TaskProcess{UsingDevices: devices}
+		for _, id := range devices {
+			beacon.Devices[id] = &shared.Device{ID: id, IsBusy: true}
+		}
+	}
+	return workmgr.ConnectedHost{LastCheckin: now, LastBeacon: beacon}
+}
+
+func runningTask(taskID string, priority storage.WorkerPriority, runningFor time.Duration) storage.Task {
+	since := now.Add(-runningFor)
+	return storage.Task{TaskID: taskID, Status: storage.TaskStatusRunning, Priority: priority, RunningSince: &since}
+}
+
+func TestPreemptionConfig(t *testing.T) {
+	cfg := PreemptionConfig{}
+	assert.Nil(t, cfg.Validate())
+	assert.Equal(t, []string{"high"}, cfg.Priorities)
+	assert.True(t, cfg.canPreempt(storage.WorkerPriorityHigh))
+	assert.False(t, cfg.canPreempt(storage.WorkerPriorityNormal))
+
+	cfg = PreemptionConfig{Priorities: []string{"High", "normal"}}
+	assert.Nil(t, cfg.Validate())
+	assert.True(t, cfg.canPreempt(storage.WorkerPriorityNormal))
+
+	assert.NotNil(t, (&PreemptionConfig{Priorities: []string{"urgent"}}).Validate())
+	assert.NotNil(t, (&PreemptionConfig{MinRuntime: shared.HumanDuration{Duration: -time.Minute}}).Validate())
+}
+
+func TestPreempt(t *testing.T) {
+	cfg := PreemptionConfig{Enabled: true, MinRuntime: shared.HumanDuration{Duration: 10 * time.Minute}}
+	assert.Nil(t, cfg.Validate())
+
+	active := []storage.Task{
+		runningTask("normal", storage.WorkerPriorityNormal, time.Hour),
+		runningTask("low-old", storage.WorkerPriorityLow, 2*time.Hour),
+		runningTask("low-new", storage.WorkerPriorityLow, time.Hour),
+		runningTask("low-just-started", storage.WorkerPriorityLow, time.Minute),
+		runningTask("high", storage.WorkerPriorityHigh, time.Hour),
+	}
+	hosts := map[string]workmgr.ConnectedHost{
+		"cracker-1": busyHost("cracker-1", map[string][]int{"normal": {0}, "low-old": {1}}),
+		"cracker-2": busyHost("cracker-2", map[string][]int{"low-new": {0}, "low-just-started": {1}, "high": {2}}),
+	}
+	queue := []Entry{
+		{Task: storage.Task{TaskID: "urgent-1", Priority: storage.WorkerPriorityHigh}},
+		{Task: storage.Task{TaskID: "urgent-2", Priority: storage.WorkerPriorityHigh}},
+		{Task: storage.Task{TaskID: "held-back", Priority: storage.WorkerPriorityHigh}, Reason: "outside of its execution windows"},
+		{Task: storage.Task{TaskID: "not-urgent", Priority: storage.WorkerPriorityNormal}},
+	}
+
+	// the lowest priority task that started last is preempted first & tasks that just started are left running
+	preemptions := cfg.Preempt(queue, active, hosts, now)
+	assert.Equal(t, map[string]string{"urgent-1": "low-new", "urgent-2": "low-old"}, preemptedIDs(preemptions))
+	if assert.Len(t, preemptions, 2) {
+		assert.Equal(t, "cracker-2", preemptions[0].Host)
+	}
+
+	// tasks that are already stopping for a preemption free up devices for the first waiting tasks
+	active[2].Status = storage.TaskStatusStopping
+	active[2].Preempted = true
+	assert.Equal(t, map[string]string{"urgent-2": "low-old"}, preemptedIDs(cfg.Preempt(queue, active, hosts, now)))
+
+	// tasks assigned to a host only preempt tasks on it
+	pinned := []Entry{{Task: storage.Task{TaskID: "pinned", Priority: storage.WorkerPriorityHigh, AssignedToHost: "cracker-1"}}}
+	assert.Equal(t, map[string]string{"pinned": "low-old"}, preemptedIDs(cfg.Preempt(pinned, active, hosts, now)))
+
+	// nothing is preempted while a worker has a free device
+	hosts["cracker-3"] = workmgr.ConnectedHost{LastBeacon: shared.Beacon{Devices: shared.DeviceMap{0: &shared.Device{ID: 0}}}}
+	assert.Len(t, cfg.Preempt(queue, active, hosts, now), 0)
+
+	cfg.Enabled = false
+	delete(hosts, "cracker-3")
+	assert.Len(t, cfg.Preempt(queue, active, hosts, now), 0)
+}
+
+func TestPreemptSkipsDistributedTasks(t *testing.T) {
+	cfg := PreemptionConfig{Enabled: true}
+	assert.Nil(t, cfg.Validate())
+
+	distributed := runningTask("distributed", storage.WorkerPriorityLow, time.Hour)
+	distributed.WorkUnitCount = 4
+
+	preemptions := cfg.Preempt(
+		[]Entry{{Task: storage.Task{TaskID: "urgent", Priority: storage.WorkerPriorityHigh}}},
+		[]storage.Task{distributed},
+		map[string]workmgr.ConnectedHost{"cracker-1": busyHost("cracker-1", map[string][]int{"distributed": {0}})},
+		now,
+	)
+	assert.Len(t, preemptions, 0)
+}
@@ -0,0 +1,35 @@
+package scheduler
+
+import (
+	"sort"
+	"time"
+
+	"github.com/mandiant/gocrack/server/storage"
+)
+
+// PriorityScheduler hands out tasks by priority and then by when they were created
+const PriorityScheduler = "priority"
+
+func init() {
+	Register(PriorityScheduler, func(Config) (Scheduler, error) {
+		return priority{}, nil
+	})
+}
+
+type priority struct{}
+
+func (priority) Queue(queued []storage.Task, _ Load, _ time.Time) []Entry {
+	entries := make([]Entry, len(queued))
+	for i, task := range queued {
+		entries[i] = Entry{Task: task, Priority: task.Priority}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i].Task, entries[j].Task
+		if a.Priority != b.Priority {
+			return a.Priority < b.Priority
+		}
+		return a.CreatedAt.Before(b.CreatedAt)
+	})
+	return numberEntries(entries)
+}
@@ -0,0 +1,142 @@
+package scheduler
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mandiant/gocrack/server/storage"
+	"github.com/mandiant/gocrack/server/workmgr"
+)
+
+var (
+	schedulersMu sync.RWMutex
+	schedulers   = make(map[string]Factory)
+)
+
+// Scheduler decides the order queued tasks are handed out to workers in
+type Scheduler interface {
+	// Queue orders the queued tasks by when they should be handed out. Tasks that can not be handed out yet stay in the
+	// queue with the reason they are held back
+	Queue(queued []storage.Task, load Load, now time.Time) []Entry
+}
+
+// Factory creates a scheduler from the server's config
+type Factory func(cfg Config) (Scheduler, error)
+
+// Load describes the tasks the workers are currently processing
+type Load struct {
+	Tasks []storage.Task
+	// Devices is the number of devices in use by each of the tasks
+	Devices map[string]int
+}
+
+// Entry is the position of a queued task
+type Entry struct {
+	Task     storage.Task
+	Position int                    // Position of the task in the queue starting at 1
+	Priority storage.WorkerPriority // Priority of the task after it has been aged by the scheduler
+	Reason   string                 // Reason the task is held back. Empty if it can be handed out
+}
+
+// Register makes a scheduler available by the name
+func Register(name string, factory Factory) {
+	schedulersMu.Lock()
+	defer schedulersMu.Unlock()
+
+	if factory == nil {
+		panic("cannot register a nil scheduler")
+	}
+
+	if _, exists := schedulers[name]; exists {
+		panic(fmt.Sprintf("scheduler %s already exists", name))
+	}
+
+	schedulers[name] = factory
+}
+
+// New creates the scheduler selected in the config
+func New(cfg Config) (Scheduler, error) {
+	schedulersMu.RLock()
+	factory, ok := schedulers[cfg.Type]
+	schedulersMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unregistered scheduler %s", cfg.Type)
+	}
+	return factory(cfg)
+}
+
+// CurrentLoad returns the tasks being processed along with the devices the workers report them using. Tasks that have
+// not been reported by a worker yet are counted as using their assigned devices or a single device
+func CurrentLoad(stor storage.Backend, wmgr *workmgr.WorkerManager) (Load, error) {
+	tasks, err := stor.GetTasksByStatus(storage.TaskStatusDequeued, storage.TaskStatusRunning, storage.TaskStatusStopping)
+	if err != nil {
+		return Load{}, err
+	}
+
+	load := Load{
+		Tasks:   tasks,
+		Devices: make(map[string]int, len(tasks)),
+	}
+
+	for _, host := range wmgr.GetCurrentWorkers() {
+		for taskID, proc := range host.LastBeacon.Processes {
+			load.Devices[taskID] += len(proc.UsingDevices)
+		}
+	}
+
+	for _, task := range tasks {
+		if load.Devices[task.TaskID] > 0 {
+			continue
+		}
+
+		if task.AssignedToDevices != nil && len(*task.AssignedToDevices) > 0 {
+			load.Devices[task.TaskID] = len(*task.AssignedToDevices)
+		} else {
+			load.Devices[task.TaskID] = 1
+		}
+	}
+	return load, nil
+}
+
+// BuildQueue orders the queued tasks with the scheduler. Tasks outside of their schedule or out of time are held back
+// whichever scheduler is used as they can not be handed out
+func BuildQueue(s Scheduler, queued []storage.Task, load Load, now time.Time) []Entry {
+	entries := s.Queue(queued, load, now)
+	for i := range entries {
+		if entries[i].Reason != "" {
+			continue
+		}
+
+		task := entries[i].Task
+		switch {
+		case task.Schedule.NotBefore != nil && now.Before(*task.Schedule.NotBefore):
+			entries[i].Reason = fmt.Sprintf("scheduled to start at %s", task.Schedule.NotBefore.UTC().Format(time.RFC3339))
+		case !task.Schedule.IsOpen(now):
+			entries[i].Reason = "outside of its execution windows"
+		case task.IsOutOfTime(now):
+			entries[i].Reason = "used up its time budget"
+		}
+	}
+	return entries
+}
+
+// Ready returns the tasks in the queue that can be handed out in the order they should be
+func Ready(entries []Entry) []storage.Task {
+	tasks := make([]storage.Task, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Reason == "" {
+			tasks = append(tasks, entry.Task)
+		}
+	}
+	return tasks
+}
+
+// numberEntries sets the position of the entries from their order
+func numberEntries(entries []Entry) []Entry {
+	for i := range entries {
+		entries[i].Position = i + 1
+	}
+	return entries
+}
@@ -0,0 +1,177 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mandiant/gocrack/server/storage"
+	"github.com/mandiant/gocrack/shared"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var now = time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+func taskIDs(tasks []storage.Task) []string {
+	out := make([]string, len(tasks))
+	for i, task := range tasks {
+		out[i] = task.TaskID
+	}
+	return out
+}
+
+func reasons(entries []Entry) map[string]string {
+	out := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		out[entry.Task.TaskID] = entry.Reason
+	}
+	return out
+}
+
+func newScheduler(t *testing.T, cfg Config) Scheduler {
+	if !assert.Nil(t, cfg.Validate()) {
+		t.FailNow()
+	}
+
+	s, err := New(cfg)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	return s
+}
+
+func TestNew(t *testing.T) {
+	cfg := Config{}
+	assert.Nil(t, cfg.Validate())
+	assert.Equal(t, FairShareScheduler, cfg.Type)
+
+	_, err := New(Config{Type: "lottery"})
+	assert.NotNil(t, err)
+
+	assert.NotNil(t, (&Config{FairShare: FairShareConfig{MaxTasksPerUser: -1}}).Validate())
+}
+
+func TestPriorityScheduler(t *testing.T) {
+	s := newScheduler(t, Config{Type: PriorityScheduler})
+
+	entries := s.Queue([]storage.Task{
+		{TaskID: "low", Priority: storage.WorkerPriorityLow, CreatedAt: now.Add(-time.Hour)},
+		{TaskID: "high-new", Priority: storage.WorkerPriorityHigh, CreatedAt: now},
+		{TaskID: "high-old", Priority: storage.WorkerPriorityHigh, CreatedAt: now.Add(-time.Minute)},
+	}, Load{}, now)
+
+	assert.Equal(t, []string{"high-old", "high-new", "low"}, taskIDs(Ready(entries)))
+	for i, entry := range entries {
+		assert.Equal(t, i+1, entry.Position)
+	}
+}
+
+func TestFairShareOrdering(t *testing.T) {
+	s := newScheduler(t, Config{})
+
+	// alice queued first but is already running a task so bob's task is handed out before hers
+	load := Load{
+		Tasks:   []storage.Task{{TaskID: "alice-running", CreatedByUUID: "alice"}},
+		Devices: map[string]int{"alice-running": 2},
+	}
+
+	entries := s.Queue([]storage.Task{
+		{TaskID: "alice-1", CreatedByUUID: "alice", Priority: storage.WorkerPriorityNormal, CreatedAt: now.Add(-time.Hour)},
+		{TaskID: "alice-2", CreatedByUUID: "alice", Priority: storage.WorkerPriorityNormal, CreatedAt: now.Add(-time.Hour)},
+		{TaskID: "bob-1", CreatedByUUID: "bob", Priority: storage.WorkerPriorityNormal, CreatedAt: now},
+		{TaskID: "carol-low", CreatedByUUID: "carol", Priority: storage.WorkerPriorityLow, CreatedAt: now.Add(-time.Hour)},
+	}, load, now)
+
+	assert.Equal(t, []string{"bob-1", "alice-1", "alice-2", "carol-low"}, taskIDs(Ready(entries)))
+}
+
+func TestFairShareLimits(t *testing.T) {
+	s := newScheduler(t, Config{FairShare: FairShareConfig{
+		MaxTasksPerUser:   2,
+		MaxDevicesPerUser: 4,
+		MaxTasksPerCase:   1,
+	}})
+
+	load := Load{
+		Tasks: []storage.Task{
+			{TaskID: "alice-1", CreatedByUUID: "alice"},
+			{TaskID: "alice-2", CreatedByUUID: "alice", WorkUnitCount: 4},
+			{TaskID: "bob-1", CreatedByUUID: "bob"},
+			{TaskID: "dave-1", CreatedByUUID: "dave", CaseCode: shared.GetStrPtr("CASE-1")},
+		},
+		Devices: map[string]int{"alice-1": 1, "alice-2": 1, "bob-1": 4, "dave-1": 1},
+	}
+
+	entries := s.Queue([]storage.Task{
+		{TaskID: "alice-3", CreatedByUUID: "alice"},
+		// more units of a distributed task that is already running do not add to the number of tasks
+		{TaskID: "alice-2", CreatedByUUID: "alice", WorkUnitCount: 4},
+		{TaskID: "bob-2", CreatedByUUID: "bob"},
+		{TaskID: "carol-1", CreatedByUUID: "carol", CaseCode: shared.GetStrPtr("CASE-1")},
+		{TaskID: "carol-2", CreatedByUUID: "carol", CaseCode: shared.GetStrPtr("CASE-2")},
+	}, load, now)
+
+	assert.Equal(t, map[string]string{
+		"alice-3": "user has reached the limit of 2 concurrent tasks",
+		"alice-2": "",
+		"bob-2":   "user has reached the limit of 4 devices",
+		"carol-1": "case CASE-1 has reached the limit of 1 concurrent tasks",
+		"carol-2": "",
+	}, reasons(entries))
+}
+
+func TestFairShareAging(t *testing.T) {
+	s := newScheduler(t, Config{FairShare: FairShareConfig{AgingInterval: shared.HumanDuration{Duration: time.Hour}}})
+
+	entries := s.Queue([]storage.Task{
+		{TaskID: "high", Priority: storage.WorkerPriorityHigh, CreatedAt: now, QueuedAt: now},
+		{TaskID: "normal", Priority: storage.WorkerPriorityNormal, CreatedAt: now.Add(-30 * time.Minute), QueuedAt: now.Add(-30 * time.Minute)},
+		{TaskID: "old-low", Priority: storage.WorkerPriorityLow, CreatedAt: now.Add(-5 * time.Hour), QueuedAt: now.Add(-5 * time.Hour)},
+	}, Load{}, now)
+
+	// the low priority task has waited long enough to be aged to high and was created first
+	assert.Equal(t, []string{"old-low", "high", "normal"}, taskIDs(Ready(entries)))
+	assert.Equal(t, storage.WorkerPriorityHigh, entries[0].Priority)
+	assert.Equal(t, storage.WorkerPriorityNormal, entries[2].Priority)
+}
+
+func TestFairShareAgingRequeued(t *testing.T) {
+	s := newScheduler(t, Config{FairShare: FairShareConfig{AgingInterval: shared.HumanDuration{Duration: time.Hour}}})
+	urgent := storage.Task{TaskID: "urgent", Priority: storage.WorkerPriorityHigh, CreatedAt: now.Add(-10 * time.Minute), QueuedAt: now.Add(-10 * time.Minute)}
+	preempted := storage.Task{TaskID: "preempted", Priority: storage.WorkerPriorityLow, CreatedAt: now.Add(-5 * time.Hour), QueuedAt: now.Add(-5 * time.Hour)}
+
+	// waiting since it was created would age the task above the urgent task that preempted it
+	entries := s.Queue([]storage.Task{urgent, preempted}, Load{}, now)
+	assert.Equal(t, []string{"preempted", "urgent"}, taskIDs(Ready(entries)))
+
+	// once it's requeued from its checkpoint, the task ages from when it was queued again
+	preempted.QueuedAt = now.Add(-time.Minute)
+	entries = s.Queue([]storage.Task{urgent, preempted}, Load{}, now)
+	assert.Equal(t, []string{"urgent", "preempted"}, taskIDs(Ready(entries)))
+	assert.Equal(t, storage.WorkerPriorityLow, entries[1].Priority)
+
+	entries = s.Queue([]storage.Task{urgent, preempted}, Load{}, now.Add(2*time.Hour))
+	assert.Equal(t, storage.WorkerPriorityHigh, entries[0].Priority)
+}
+
+func TestBuildQueue(t *testing.T) {
+	s := newScheduler(t, Config{Type: PriorityScheduler})
+	later := now.Add(time.Hour)
+
+	entries := BuildQueue(s, []storage.Task{
+		{TaskID: "ready", CreatedAt: now},
+		{TaskID: "not-before", CreatedAt: now, Schedule: storage.TaskSchedule{NotBefore: &later}},
+		{TaskID: "window", CreatedAt: now, Schedule: storage.TaskSchedule{
+			Windows: []storage.ExecutionWindow{{Start: "20:00", End: "06:00"}},
+		}},
+		{TaskID: "out-of-time", CreatedAt: now, TaskDuration: 60, Runtime: 60},
+	}, Load{}, now)
+
+	assert.Equal(t, map[string]string{
+		"ready":       "",
+		"not-before":  "scheduled to start at 2024-03-01T13:00:00Z",
+		"window":      "outside of its execution windows",
+		"out-of-time": "used up its time budget",
+	}, reasons(entries))
+	assert.Equal(t, []string{"ready"}, taskIDs(Ready(entries)))
+}
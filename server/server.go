@@ -10,6 +10,7 @@ import (
 	"github.com/mandiant/gocrack/server/filemanager"
 	"github.com/mandiant/gocrack/server/notifications"
 	"github.com/mandiant/gocrack/server/rpc"
+	"github.com/mandiant/gocrack/server/scheduler"
 	"github.com/mandiant/gocrack/server/storage"
 	"github.com/mandiant/gocrack/server/web"
 	"github.com/mandiant/gocrack/server/workmgr"
@@ -96,6 +97,11 @@ func (s *Server) registerNotifications() (func(), error) {
 			return
 		}
 
+		// users already know about the changes they made through the API
+		if taskStatus.History.ActorType == storage.StatusActorUser {
+			return
+		}
+
 		if err := emailer.TaskStatusChanged(taskStatus.TaskID, taskStatus.Status); err != nil {
 			log.Error().Err(err).Msg("Failed to send email regarding task status change")
 		}
@@ -131,12 +137,17 @@ func (s *Server) registerNotifications() (func(), error) {
 
 // Start spawns the API Server as well as the RPC Server and blocks until stop has been called
 func (s *Server) Start() error {
-	rs, err := rpc.NewRPCServer(s.cfg.RPC, s.stor, s.workers)
+	sched, err := scheduler.New(s.cfg.Scheduler)
+	if err != nil {
+		return err
+	}
+
+	rs, err := rpc.NewRPCServer(s.cfg.RPC, s.stor, s.workers, sched, s.cfg.Scheduler.Preemption)
 	if err != nil {
 		return err
 	}
 
-	web, err := web.NewServer(s.cfg.WebServer, s.stor, s.workers, s.auth, s.fm)
+	web, err := web.NewServer(s.cfg.WebServer, s.stor, s.workers, s.auth, s.fm, sched)
 	if err != nil {
 		return err
 	}
@@ -149,6 +160,14 @@ func (s *Server) Start() error {
 		defer closer()
 	}
 
+	if gc, ok := s.stor.(storage.GarbageCollector); ok {
+		s.wg.Add(1)
+		go s.collectGarbage(gc, s.cfg.Database.GarbageCollection)
+	}
+
+	s.wg.Add(1)
+	go s.updateWordlists()
+
 	// If any of the goroutines that are running a listener fail, we'll send the err on this channel
 	errch := make(chan error, 1)
 	defer close(errch)
@@ -0,0 +1,166 @@
+// Package servertest sets up the storage backend & file manager the server's packages are tested against along with
+// the users, task files and tasks the tests need
+package servertest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mandiant/gocrack/server/filemanager"
+	"github.com/mandiant/gocrack/server/storage"
+	_ "github.com/mandiant/gocrack/server/storage/bdb"
+	_ "github.com/mandiant/gocrack/server/storage/sqldb"
+	_ "github.com/mandiant/gocrack/shared/enginedef/cpu"
+	_ "github.com/mandiant/gocrack/shared/enginedef/hashcat"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// Backends are the names of the storage backends a fixture can be created with
+var Backends = []string{"bdb", "sqlite"}
+
+// Fixture is a storage backend & file manager in a temporary directory
+type Fixture struct {
+	Dir  string // Dir is the temporary directory the database & files are saved in
+	Stor storage.Backend
+	FM   *filemanager.Context
+}
+
+// New creates a fixture on the backend. The returned function closes the backend and removes the directory
+func New(t *testing.T, backend string) (*Fixture, func()) {
+	dir, err := ioutil.TempDir("", "server_tests")
+	if err != nil {
+		assert.FailNow(t, "failed to create temp directory", err.Error())
+	}
+
+	stor, err := storage.Open(storage.Config{Backend: backend, ConnectionString: filepath.Join(dir, "test.db")})
+	if err != nil {
+		os.RemoveAll(dir)
+		assert.FailNow(t, "failed to open database", err.Error())
+	}
+
+	cfg := filemanager.Config{
+		TaskUploadPath: filepath.Join(dir, "tasks"),
+		EngineFilePath: filepath.Join(dir, "engine"),
+		TempPath:       filepath.Join(dir, "temp"),
+	}
+	for _, path := range []string{cfg.TaskUploadPath, cfg.EngineFilePath, cfg.TempPath} {
+		if err := os.MkdirAll(path, 0700); err != nil {
+			assert.FailNow(t, "failed to create filemanager directory", err.Error())
+		}
+	}
+
+	return &Fixture{Dir: dir, Stor: stor, FM: filemanager.New(stor, cfg)}, func() {
+		stor.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+// CreateUser creates a user with a random username
+func (f *Fixture) CreateUser(t *testing.T, isAdmin bool) storage.User {
+	user := storage.User{
+		Username:    "user_" + uuid.NewString()[:8],
+		Password:    "hunter2",
+		IsSuperUser: isAdmin,
+	}
+	if err := f.Stor.CreateUser(&user); err != nil {
+		assert.FailNow(t, "failed to create user", err.Error())
+	}
+	return user
+}
+
+// CreateTaskFile saves a task file with a line per hash that the uploader is entitled to
+func (f *Fixture) CreateTaskFile(t *testing.T, uploader storage.User, hashes ...string) storage.TaskFile {
+	tf := storage.TaskFile{
+		FileID:         uuid.NewString(),
+		FileName:       "hashes.txt",
+		UploadedByUUID: uploader.UserUUID,
+		ForEngine:      storage.TaskFileEngineHashcat,
+	}
+
+	contents := strings.Join(hashes, "\n") + "\n"
+	fresp, err := f.FM.SaveFile(ioutil.NopCloser(strings.NewReader(contents)), tf.FileName, tf.FileID, tf.ForEngine)
+	if err != nil || fresp == nil {
+		assert.FailNow(t, "failed to save task file")
+	}
+	tf.SavedAt = fresp.SavedTo
+	tf.FileSize = fresp.Size
+	tf.SHA1Hash = fresp.SHA1
+	tf.UploadedAt = time.Now().UTC()
+	tf.NumberOfPasswords = len(hashes)
+
+	txn, err := f.Stor.NewTaskFileTransaction()
+	if err != nil {
+		assert.FailNow(t, "failed to create task file transaction", err.Error())
+	}
+	if err := txn.SaveTaskFile(tf); err != nil {
+		txn.Rollback()
+		assert.FailNow(t, "failed to save task file", err.Error())
+	}
+	assert.Nil(t, txn.AddEntitlement(tf, uploader.UserUUID))
+	assert.Nil(t, txn.Commit())
+	return tf
+}
+
+// CreateEngineFile saves an engine file with a line per entry that the uploader is entitled to
+func (f *Fixture) CreateEngineFile(t *testing.T, uploader storage.User, fileType storage.EngineFileType, shared bool, entries ...string) storage.EngineFile {
+	ef := storage.EngineFile{
+		FileID:         uuid.NewString(),
+		FileName:       "entries.txt",
+		UploadedByUUID: uploader.UserUUID,
+		FileType:       fileType,
+		IsShared:       shared,
+	}
+
+	contents := strings.Join(entries, "\n") + "\n"
+	fresp, err := f.FM.SaveFile(ioutil.NopCloser(strings.NewReader(contents)), ef.FileName, ef.FileID, fileType)
+	if err != nil || fresp == nil {
+		assert.FailNow(t, "failed to save engine file")
+	}
+	ef.SavedAt = fresp.SavedTo
+	ef.FileSize = fresp.Size
+	ef.SHA1Hash = fresp.SHA1
+	ef.UploadedAt = time.Now().UTC()
+	ef.LastUpdatedAt = ef.UploadedAt
+	ef.NumberOfEntries = int64(len(entries))
+
+	txn, err := f.Stor.NewEngineFileTransaction()
+	if err != nil {
+		assert.FailNow(t, "failed to create engine file transaction", err.Error())
+	}
+	if err := txn.SaveEngineFile(ef); err != nil {
+		txn.Rollback()
+		assert.FailNow(t, "failed to save engine file", err.Error())
+	}
+	assert.Nil(t, txn.AddEntitlement(ef, uploader.UserUUID))
+	assert.Nil(t, txn.Commit())
+	return ef
+}
+
+// CreateTask saves the task on behalf of the creator, who is entitled to it. The task is given an ID & name if it
+// does not have one
+func (f *Fixture) CreateTask(t *testing.T, creator storage.User, task *storage.Task) {
+	if task.TaskID == "" {
+		task.TaskID = uuid.NewString()
+	}
+
+	if task.TaskName == "" {
+		task.TaskName = "task " + task.TaskID[:8]
+	}
+	task.CreatedByUUID = creator.UserUUID
+
+	txn, err := f.Stor.NewTaskCreateTransaction()
+	if err != nil {
+		assert.FailNow(t, "failed to create task transaction", err.Error())
+	}
+	if err := txn.CreateTask(task); err != nil {
+		txn.Rollback()
+		assert.FailNow(t, "failed to create task", err.Error())
+	}
+	assert.Nil(t, txn.Commit())
+}
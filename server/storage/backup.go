@@ -0,0 +1,107 @@
+package storage
+
+import "io"
+
+// RecordType identifies the kind of document within a backend neutral export
+type RecordType string
+
+const (
+	RecordUser            RecordType = "user"
+	RecordTaskFile        RecordType = "task_file"
+	RecordEngineFile      RecordType = "engine_file"
+	RecordTask            RecordType = "task"
+	RecordWorkUnit        RecordType = "work_unit"
+	RecordCrackedHash     RecordType = "cracked_hash"
+	RecordCheckpoint      RecordType = "checkpoint"
+	RecordEntitlement     RecordType = "entitlement"
+	RecordAuditLog        RecordType = "audit_log"
+	RecordTaskStatus      RecordType = "task_status"
+	RecordKnownHash       RecordType = "known_hash"
+	RecordTaskFileAccount RecordType = "task_file_account"
+	RecordPasswordPolicy  RecordType = "password_policy"
+	RecordCrackedWordlist RecordType = "cracked_wordlist"
+	RecordPipeline        RecordType = "pipeline"
+	RecordTaskTemplate    RecordType = "task_template"
+)
+
+// RecordTypes contains every record type in the order they are exported.
+// Records reference the ones before them so they must be imported in the same order
+var RecordTypes = []RecordType{
+	RecordUser,
+	RecordTaskFile,
+	RecordEngineFile,
+	RecordTask,
+	RecordWorkUnit,
+	RecordCrackedHash,
+	RecordCheckpoint,
+	RecordTaskTemplate,
+	RecordEntitlement,
+	RecordAuditLog,
+	RecordTaskStatus,
+	RecordKnownHash,
+	RecordTaskFileAccount,
+	RecordPasswordPolicy,
+	RecordCrackedWordlist,
+	RecordPipeline,
+}
+
+// Record is a single document within an export. Value holds the storage type of the record:
+//
+//	RecordUser: User
+//	RecordTaskFile: TaskFile
+//	RecordEngineFile: EngineFile
+//	RecordTask: Task
+//	RecordWorkUnit: WorkUnit
+//	RecordCrackedHash: ExportedCrackedHash
+//	RecordCheckpoint: CheckpointFile
+//	RecordTaskTemplate: TaskTemplate
+//	RecordEntitlement: ExportedEntitlement
+//	RecordAuditLog: ActivityLogEntry
+//	RecordTaskStatus: TaskStatusHistoryEntry
+//	RecordKnownHash: KnownHash
+//	RecordTaskFileAccount: TaskFileAccount
+//	RecordPasswordPolicy: PasswordPolicy
+//	RecordCrackedWordlist: CrackedWordlist
+//	RecordPipeline: Pipeline
+type Record struct {
+	Type  RecordType
+	Value interface{}
+}
+
+// ExportedCrackedHash is a cracked password along with the task it was cracked by
+type ExportedCrackedHash struct {
+	TaskID string
+	CrackedHash
+}
+
+// ExportedEntitlement is an entitlement along with the type of document the user was entitled to
+type ExportedEntitlement struct {
+	Type EntitlementType
+	EntitlementEntry
+}
+
+// Exporter is implemented by backends that can enumerate every document they store
+type Exporter interface {
+	// Export calls fn with every document in the database ordered by RecordTypes.
+	// All documents are read within a single transaction so the export is consistent while the server is running
+	Export(fn func(Record) error) error
+}
+
+// Snapshotter is implemented by backends that can copy their database while it's in use
+type Snapshotter interface {
+	// Snapshot writes a consistent copy of the database in the backend's native format to w
+	Snapshot(w io.Writer) (int64, error)
+}
+
+// ImportTxn describes all the methods needed for the transaction used to restore an export
+type ImportTxn interface {
+	// Import saves the record as is. Records must be imported in the order of RecordTypes
+	Import(Record) error
+	Rollback() error
+	Commit() error
+}
+
+// Importer is implemented by backends that can restore an export from any backend
+type Importer interface {
+	NewImportTransaction() (ImportTxn, error)
+}
@@ -0,0 +1,240 @@
+package bdb
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/mandiant/gocrack/server/storage"
+
+	"github.com/asdine/storm"
+	bolt "go.etcd.io/bbolt"
+)
+
+// entitlementBuckets maps each entitlement type to the bucket its records are saved in
+var entitlementBuckets = map[storage.EntitlementType][]string{
+	storage.EntitlementTask:         bucketEntTasks,
+	storage.EntitlementTaskFile:     bucketEntTaskFiles,
+	storage.EntitlementEngineFile:   bucketEntEngineFiles,
+	storage.EntitlementTaskTemplate: bucketEntTemplates,
+}
+
+// Snapshot implements storage.Snapshotter by copying the database within a read transaction
+func (s *BoltBackend) Snapshot(w io.Writer) (n int64, err error) {
+	err = s.db.Bolt.View(func(tx *bolt.Tx) error {
+		n, err = tx.WriteTo(w)
+		return err
+	})
+	return n, err
+}
+
+// each calls fn with every document saved in the bucket. A bucket that does not exist yet is treated as empty
+func each(node storm.Node, kind interface{}, fn func(record interface{}) error) error {
+	if err := node.Select().Each(kind, fn); err != nil && err != storm.ErrNotFound {
+		return err
+	}
+	return nil
+}
+
+// Export implements storage.Exporter
+func (s *BoltBackend) Export(fn func(storage.Record) error) error {
+	var taskIDs []string
+
+	tx, err := s.db.Bolt.Begin(false)
+	if err != nil {
+		return convertErr(err)
+	}
+	defer tx.Rollback()
+	root := s.db.WithTransaction(tx)
+
+	emit := func(rt storage.RecordType, value interface{}) error {
+		return fn(storage.Record{Type: rt, Value: value})
+	}
+
+	if err := each(root.From("users"), new(boltUser), func(record interface{}) error {
+		return emit(storage.RecordUser, record.(*boltUser).User)
+	}); err != nil {
+		return err
+	}
+
+	if err := each(root.From(bucketTaskFiles...), new(boltTaskFile), func(record interface{}) error {
+		return emit(storage.RecordTaskFile, record.(*boltTaskFile).TaskFile)
+	}); err != nil {
+		return err
+	}
+
+	if err := each(root.From(bucketEngineFiles...), new(boltEngineFile), func(record interface{}) error {
+		return emit(storage.RecordEngineFile, record.(*boltEngineFile).EngineFile)
+	}); err != nil {
+		return err
+	}
+
+	if err := each(root.From(bucketTasks), new(boltCrackTask), func(record interface{}) error {
+		task := record.(*boltCrackTask).Task
+		taskIDs = append(taskIDs, task.TaskID)
+		return emit(storage.RecordTask, task)
+	}); err != nil {
+		return err
+	}
+
+	if err := each(root.From(bucketWorkUnits...), new(boltWorkUnit), func(record interface{}) error {
+		return emit(storage.RecordWorkUnit, record.(*boltWorkUnit).WorkUnit)
+	}); err != nil {
+		return err
+	}
+
+	for _, taskID := range taskIDs {
+		if err := each(root.From(bucketTasks, taskID, "results"), new(boltCrackedHash), func(record interface{}) error {
+			return emit(storage.RecordCrackedHash, storage.ExportedCrackedHash{
+				TaskID:      taskID,
+				CrackedHash: record.(*boltCrackedHash).CrackedHash,
+			})
+		}); err != nil {
+			return err
+		}
+	}
+
+	if err := each(root.From(bucketCheckpoints), new(boltCheckpointFile), func(record interface{}) error {
+		return emit(storage.RecordCheckpoint, record.(*boltCheckpointFile).CheckpointFile)
+	}); err != nil {
+		return err
+	}
+
+	if err := each(root.From(bucketTemplates), new(boltTaskTemplate), func(record interface{}) error {
+		return emit(storage.RecordTaskTemplate, record.(*boltTaskTemplate).TaskTemplate)
+	}); err != nil {
+		return err
+	}
+
+	for _, entType := range []storage.EntitlementType{
+		storage.EntitlementTask,
+		storage.EntitlementTaskFile,
+		storage.EntitlementEngineFile,
+		storage.EntitlementTaskTemplate,
+	} {
+		if err := each(root.From(entitlementBuckets[entType]...), new(boltEntitlement), func(record interface{}) error {
+			return emit(storage.RecordEntitlement, storage.ExportedEntitlement{
+				Type:             entType,
+				EntitlementEntry: record.(*boltEntitlement).EntitlementEntry,
+			})
+		}); err != nil {
+			return err
+		}
+	}
+
+	if err := each(root.From(bucketAuditLog), new(boltAuditLogEntry), func(record interface{}) error {
+		return emit(storage.RecordAuditLog, record.(*boltAuditLogEntry).ActivityLogEntry)
+	}); err != nil {
+		return err
+	}
+
+	if err := each(root.From(bucketTaskStatus...), new(boltTaskStatusEntry), func(record interface{}) error {
+		return emit(storage.RecordTaskStatus, record.(*boltTaskStatusEntry).TaskStatusHistoryEntry)
+	}); err != nil {
+		return err
+	}
+
+	if err := each(root.From(bucketKnownHashes), new(boltKnownHash), func(record interface{}) error {
+		return emit(storage.RecordKnownHash, record.(*boltKnownHash).KnownHash)
+	}); err != nil {
+		return err
+	}
+
+	if err := each(root.From(bucketAccounts...), new(boltTaskFileAccount), func(record interface{}) error {
+		return emit(storage.RecordTaskFileAccount, record.(*boltTaskFileAccount).TaskFileAccount)
+	}); err != nil {
+		return err
+	}
+
+	if err := each(root.From(bucketPolicies), new(boltPasswordPolicy), func(record interface{}) error {
+		return emit(storage.RecordPasswordPolicy, record.(*boltPasswordPolicy).PasswordPolicy)
+	}); err != nil {
+		return err
+	}
+
+	if err := each(root.From(bucketWordlists), new(boltCrackedWordlist), func(record interface{}) error {
+		return emit(storage.RecordCrackedWordlist, record.(*boltCrackedWordlist).CrackedWordlist)
+	}); err != nil {
+		return err
+	}
+
+	return each(root.From(bucketPipelines), new(boltPipeline), func(record interface{}) error {
+		return emit(storage.RecordPipeline, record.(*boltPipeline).Pipeline)
+	})
+}
+
+// ImportTransaction restores the records of an export within a single bolt transaction
+type ImportTransaction struct {
+	txn storm.Node
+}
+
+// NewImportTransaction implements storage.Importer
+func (s *BoltBackend) NewImportTransaction() (storage.ImportTxn, error) {
+	txn, err := s.db.Begin(true)
+	if err != nil {
+		return nil, convertErr(err)
+	}
+	return &ImportTransaction{txn}, nil
+}
+
+// Import saves the record as is within the transaction
+func (s *ImportTransaction) Import(rec storage.Record) error {
+	var node storm.Node
+	var doc interface{}
+
+	switch v := rec.Value.(type) {
+	case storage.User:
+		node, doc = s.txn.From("users"), &boltUser{User: v, DocVersion: curUserVer}
+	case storage.TaskFile:
+		node, doc = s.txn.From(bucketTaskFiles...), &boltTaskFile{TaskFile: v, DocVersion: curTaskFileVer}
+	case storage.EngineFile:
+		node, doc = s.txn.From(bucketEngineFiles...), &boltEngineFile{EngineFile: v, DocVersion: curEngineFileVer}
+	case storage.Task:
+		// backups taken before tasks recorded when they were queued age from when the task was created
+		if v.QueuedAt.IsZero() {
+			v.QueuedAt = v.CreatedAt
+		}
+		node, doc = s.txn.From(bucketTasks), &boltCrackTask{Task: v, DocVersion: curCrackTaskVer}
+	case storage.WorkUnit:
+		node, doc = s.txn.From(bucketWorkUnits...), &boltWorkUnit{WorkUnit: v, DocVersion: curWorkUnitVer}
+	case storage.ExportedCrackedHash:
+		node, doc = s.txn.From(bucketTasks, v.TaskID, "results"), &boltCrackedHash{CrackedHash: v.CrackedHash, DocVersion: curCrackedHashVer}
+	case storage.CheckpointFile:
+		node, doc = s.txn.From(bucketCheckpoints), &boltCheckpointFile{ID: v.TaskID, CheckpointFile: v, DocVersion: curCheckpointFileVer}
+	case storage.TaskTemplate:
+		node, doc = s.txn.From(bucketTemplates), &boltTaskTemplate{TaskTemplate: v, DocVersion: curTemplateVer}
+	case storage.ExportedEntitlement:
+		bucket, ok := entitlementBuckets[v.Type]
+		if !ok {
+			return fmt.Errorf("unknown entType of %d", v.Type)
+		}
+		return saveEntitlement(s.txn.From(bucket...), v.EntitlementEntry)
+	case storage.ActivityLogEntry:
+		node, doc = s.txn.From(bucketAuditLog), &boltAuditLogEntry{ActivityLogEntry: v, DocVersion: curAuditEntryVer}
+	case storage.TaskStatusHistoryEntry:
+		node, doc = s.txn.From(bucketTaskStatus...), &boltTaskStatusEntry{TaskStatusHistoryEntry: v, DocVersion: curStatusHistoryVer}
+	case storage.KnownHash:
+		v.Hash = storage.NormalizeHash(v.Hash)
+		node, doc = s.txn.From(bucketKnownHashes), &boltKnownHash{ID: knownHashID(v.HashType, v.Hash), KnownHash: v, DocVersion: curKnownHashVer}
+	case storage.TaskFileAccount:
+		node, doc = s.txn.From(bucketAccounts...), &boltTaskFileAccount{TaskFileAccount: v, DocVersion: curAccountVer}
+	case storage.PasswordPolicy:
+		node, doc = s.txn.From(bucketPolicies), &boltPasswordPolicy{PasswordPolicy: v, DocVersion: curPolicyVer}
+	case storage.CrackedWordlist:
+		node, doc = s.txn.From(bucketWordlists), &boltCrackedWordlist{CrackedWordlist: v, DocVersion: curWordlistVer}
+	case storage.Pipeline:
+		node, doc = s.txn.From(bucketPipelines), &boltPipeline{Pipeline: v, DocVersion: curPipelineVer}
+	default:
+		return fmt.Errorf("unknown %s record of type %T", rec.Type, rec.Value)
+	}
+	return convertErr(node.Save(doc))
+}
+
+// Rollback any writes to the database
+func (s *ImportTransaction) Rollback() error {
+	return s.txn.Rollback()
+}
+
+// Commit the transaction
+func (s *ImportTransaction) Commit() error {
+	return convertErr(s.txn.Commit())
+}
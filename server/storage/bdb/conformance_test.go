@@ -0,0 +1,22 @@
+package bdb
+
+import (
+	"testing"
+
+	"github.com/mandiant/gocrack/server/storage"
+	"github.com/mandiant/gocrack/server/storage/storagetest"
+)
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) (storage.Backend, func()) {
+		db := initTest(t)
+		return db, db.DestroyTest
+	})
+}
+
+func TestGarbageCollection(t *testing.T) {
+	storagetest.RunGarbageCollection(t, func(t *testing.T) (storage.Backend, func()) {
+		db := initTest(t)
+		return db, db.DestroyTest
+	})
+}
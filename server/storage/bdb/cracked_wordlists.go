@@ -0,0 +1,44 @@
+package bdb
+
+import (
+	"github.com/mandiant/gocrack/server/storage"
+
+	"github.com/asdine/storm"
+	"github.com/asdine/storm/q"
+)
+
+// SaveCrackedWordlist implements storage.SaveCrackedWordlist
+func (s *BoltBackend) SaveCrackedWordlist(wl storage.CrackedWordlist) error {
+	return convertErr(s.db.From(bucketWordlists).Save(&boltCrackedWordlist{
+		DocVersion:      curWordlistVer,
+		CrackedWordlist: wl,
+	}))
+}
+
+// GetCrackedWordlist implements storage.GetCrackedWordlist
+func (s *BoltBackend) GetCrackedWordlist(fileID string) (*storage.CrackedWordlist, error) {
+	var wl boltCrackedWordlist
+
+	if err := s.db.From(bucketWordlists).One("FileID", fileID, &wl); err != nil {
+		return nil, convertErr(err)
+	}
+	return &wl.CrackedWordlist, nil
+}
+
+// GetAutoUpdatedWordlists implements storage.GetAutoUpdatedWordlists
+func (s *BoltBackend) GetAutoUpdatedWordlists() ([]storage.CrackedWordlist, error) {
+	out := make([]storage.CrackedWordlist, 0)
+
+	if err := s.db.From(bucketWordlists).Select(q.Eq("AutoUpdate", true)).Each(new(boltCrackedWordlist), func(record interface{}) error {
+		out = append(out, record.(*boltCrackedWordlist).CrackedWordlist)
+		return nil
+	}); err != nil && err != storm.ErrNotFound {
+		return nil, convertErr(err)
+	}
+	return out, nil
+}
+
+// deleteCrackedWordlist removes the wordlist of the engine file if it was built from cracked passwords
+func deleteCrackedWordlist(root storm.Node, fileID string) error {
+	return ignoreNotFound(root.From(bucketWordlists).Select(q.Eq("FileID", fileID)).Delete(new(boltCrackedWordlist)))
+}
@@ -50,7 +50,24 @@ func (s *BoltBackend) GetEngineFilesForUser(user storage.User) (sfs []storage.En
 	return
 }
 
+// UpdateEngineFile implements storage.UpdateEngineFile
+func (s *BoltBackend) UpdateEngineFile(sf storage.EngineFile) error {
+	var bsf boltEngineFile
+
+	node := s.db.From(bucketEngineFiles...)
+	if err := node.One("FileID", sf.FileID, &bsf); err != nil {
+		return convertErr(err)
+	}
+
+	bsf.DocVersion = curEngineFileVer
+	bsf.EngineFile = sf
+	return convertErr(node.Save(&bsf))
+}
+
 // DeleteEngineFile file implements storage.DeleteEngineFile
 func (s *BoltBackend) DeleteEngineFile(fileID string) error {
-	return s.deleteFile(fileID, deleteTaskEngineFile)
+	if err := s.deleteFile(fileID, deleteTaskEngineFile); err != nil {
+		return convertErr(err)
+	}
+	return convertErr(deleteCrackedWordlist(s.db, fileID))
 }
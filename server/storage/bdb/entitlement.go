@@ -39,18 +39,25 @@ func grantEntitlement(node storm.Node, user storage.User, entitledTo interface{}
 		entitledID = rec.TaskID
 	case storage.EngineFile:
 		entitledID = rec.FileID
+	case storage.TaskTemplate:
+		entitledID = rec.TemplateID
 	default:
 		return fmt.Errorf("unknown object type passed into entitledTo")
 	}
 
+	return saveEntitlement(node, storage.EntitlementEntry{
+		UserUUID:        user.UserUUID,
+		EntitledID:      entitledID,
+		GrantedAccessAt: time.Now().UTC(),
+	})
+}
+
+// saveEntitlement inserts the entitlement into the database unless the user is already entitled to the document
+func saveEntitlement(node storm.Node, entry storage.EntitlementEntry) error {
 	if err := node.Save(&boltEntitlement{
-		EntitlementEntry: storage.EntitlementEntry{
-			UserUUID:        user.UserUUID,
-			EntitledID:      entitledID,
-			GrantedAccessAt: time.Now().UTC(),
-		},
-		UniqueID:   fmt.Sprintf("%x", md5.New().Sum([]byte(fmt.Sprintf("%s_%s", user.UserUUID, entitledID)))),
-		DocVersion: curEntVer,
+		EntitlementEntry: entry,
+		UniqueID:         fmt.Sprintf("%x", md5.New().Sum([]byte(fmt.Sprintf("%s_%s", entry.UserUUID, entry.EntitledID)))),
+		DocVersion:       curEntVer,
 	}); err != nil {
 		// If they are already entitled, do not unnecessarily create another record
 		if err == storm.ErrAlreadyExists {
@@ -71,6 +78,8 @@ func (s *BoltBackend) CheckEntitlement(userUUID, entityID string, entType storag
 		node = s.db.From(bucketEntTasks...)
 	case storage.EntitlementEngineFile:
 		node = s.db.From(bucketEntEngineFiles...)
+	case storage.EntitlementTaskTemplate:
+		node = s.db.From(bucketEntTemplates...)
 	default:
 		return false, fmt.Errorf("unknown entType of %d", entType)
 	}
@@ -97,6 +106,8 @@ func (s *BoltBackend) GrantEntitlement(user storage.User, entitledTo interface{}
 		node = s.db.From(bucketEntTasks...)
 	case storage.EngineFile:
 		node = s.db.From(bucketEntEngineFiles...)
+	case storage.TaskTemplate:
+		node = s.db.From(bucketEntTemplates...)
 	default:
 		err = fmt.Errorf("unknown object type passed into GrantEntitlement")
 	}
@@ -127,6 +138,9 @@ func (s *BoltBackend) RevokeEntitlement(user storage.User, document interface{})
 	case storage.EngineFile:
 		node = s.db.From(bucketEntEngineFiles...)
 		entitledID = rec.FileID
+	case storage.TaskTemplate:
+		node = s.db.From(bucketEntTemplates...)
+		entitledID = rec.TemplateID
 	default:
 		return fmt.Errorf("unknown object type passed into entitledTo")
 	}
@@ -174,6 +188,8 @@ func (s *BoltBackend) RemoveEntitlements(entityID string, entType storage.Entitl
 		node = s.db.From(bucketEntTasks...)
 	case storage.EntitlementEngineFile:
 		node = s.db.From(bucketEntEngineFiles...)
+	case storage.EntitlementTaskTemplate:
+		node = s.db.From(bucketEntTemplates...)
 	default:
 		return fmt.Errorf("unknown entType of %d", entType)
 	}
@@ -0,0 +1,214 @@
+package bdb
+
+import (
+	"strings"
+
+	"github.com/mandiant/gocrack/server/storage"
+
+	"github.com/asdine/storm"
+	bolt "go.etcd.io/bbolt"
+)
+
+// taskSubBuckets are the buckets nested under the tasks bucket that do not hold the cracked hashes of a task
+var taskSubBuckets = map[string]bool{
+	"boltCrackTask":     true,
+	bucketEntName:       true,
+	bucketWorkUnits[1]:  true,
+	bucketTaskStatus[1]: true,
+}
+
+// orphanCollector records the orphans found in a bolt transaction and removes them unless it's a dry run
+type orphanCollector struct {
+	root   storm.Node
+	dryRun bool
+	report *storage.OrphanReport
+}
+
+// collect walks every document in the bucket and removes the ones isOrphan returns true for
+func (c *orphanCollector) collect(node storm.Node, rt storage.RecordType, kind interface{}, isOrphan func(record interface{}) bool) error {
+	var orphans []interface{}
+
+	if err := each(node, kind, func(record interface{}) error {
+		if isOrphan(record) {
+			orphans = append(orphans, record)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	c.report.Orphans[rt] += len(orphans)
+	if c.dryRun {
+		return nil
+	}
+
+	for _, record := range orphans {
+		if err := node.DeleteStruct(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ids returns the set of IDs of every document within the bucket
+func ids(node storm.Node, kind interface{}, id func(record interface{}) string) (map[string]bool, error) {
+	out := make(map[string]bool)
+	err := each(node, kind, func(record interface{}) error {
+		out[id(record)] = true
+		return nil
+	})
+	return out, err
+}
+
+// CollectGarbage implements storage.GarbageCollector
+func (s *BoltBackend) CollectGarbage(dryRun bool) (*storage.OrphanReport, error) {
+	tx, err := s.db.Bolt.Begin(!dryRun)
+	if err != nil {
+		return nil, convertErr(err)
+	}
+	defer tx.Rollback()
+
+	c := &orphanCollector{
+		root:   s.db.WithTransaction(tx),
+		dryRun: dryRun,
+		report: &storage.OrphanReport{DryRun: dryRun, Orphans: make(map[storage.RecordType]int)},
+	}
+
+	if err = c.collectAll(tx); err != nil {
+		return nil, convertErr(err)
+	}
+
+	if !dryRun {
+		if err = tx.Commit(); err != nil {
+			return nil, convertErr(err)
+		}
+	}
+	return c.report, nil
+}
+
+func (c *orphanCollector) collectAll(tx *bolt.Tx) error {
+	taskIDs, err := ids(c.root.From(bucketTasks), new(boltCrackTask), func(record interface{}) string {
+		return record.(*boltCrackTask).TaskID
+	})
+	if err != nil {
+		return err
+	}
+
+	taskFileIDs, err := ids(c.root.From(bucketTaskFiles...), new(boltTaskFile), func(record interface{}) string {
+		return record.(*boltTaskFile).FileID
+	})
+	if err != nil {
+		return err
+	}
+
+	engineFileIDs, err := ids(c.root.From(bucketEngineFiles...), new(boltEngineFile), func(record interface{}) string {
+		return record.(*boltEngineFile).FileID
+	})
+	if err != nil {
+		return err
+	}
+
+	templateIDs, err := ids(c.root.From(bucketTemplates), new(boltTaskTemplate), func(record interface{}) string {
+		return record.(*boltTaskTemplate).TemplateID
+	})
+	if err != nil {
+		return err
+	}
+
+	if err = c.collect(c.root.From(bucketWorkUnits...), storage.RecordWorkUnit, new(boltWorkUnit), func(record interface{}) bool {
+		return !taskIDs[record.(*boltWorkUnit).TaskID]
+	}); err != nil {
+		return err
+	}
+
+	if err = c.collectCrackedHashes(tx, taskIDs); err != nil {
+		return err
+	}
+
+	if err = c.collect(c.root.From(bucketCheckpoints), storage.RecordCheckpoint, new(boltCheckpointFile), func(record interface{}) bool {
+		return !taskIDs[record.(*boltCheckpointFile).ID]
+	}); err != nil {
+		return err
+	}
+
+	if err = c.collect(c.root.From(bucketTaskStatus...), storage.RecordTaskStatus, new(boltTaskStatusEntry), func(record interface{}) bool {
+		return !taskIDs[record.(*boltTaskStatusEntry).TaskID]
+	}); err != nil {
+		return err
+	}
+
+	if err = c.collect(c.root.From(bucketKnownHashes), storage.RecordKnownHash, new(boltKnownHash), func(record interface{}) bool {
+		return !taskIDs[record.(*boltKnownHash).TaskID]
+	}); err != nil {
+		return err
+	}
+
+	if err = c.collect(c.root.From(bucketAccounts...), storage.RecordTaskFileAccount, new(boltTaskFileAccount), func(record interface{}) bool {
+		return !taskFileIDs[record.(*boltTaskFileAccount).FileID]
+	}); err != nil {
+		return err
+	}
+
+	if err = c.collect(c.root.From(bucketWordlists), storage.RecordCrackedWordlist, new(boltCrackedWordlist), func(record interface{}) bool {
+		return !engineFileIDs[record.(*boltCrackedWordlist).FileID]
+	}); err != nil {
+		return err
+	}
+
+	for entType, parents := range map[storage.EntitlementType]map[string]bool{
+		storage.EntitlementTask:         taskIDs,
+		storage.EntitlementTaskFile:     taskFileIDs,
+		storage.EntitlementEngineFile:   engineFileIDs,
+		storage.EntitlementTaskTemplate: templateIDs,
+	} {
+		parents := parents
+		if err = c.collect(c.root.From(entitlementBuckets[entType]...), storage.RecordEntitlement, new(boltEntitlement), func(record interface{}) bool {
+			return !parents[record.(*boltEntitlement).EntitledID]
+		}); err != nil {
+			return err
+		}
+	}
+
+	// every audited action other than logging in is taken against a task
+	return c.collect(c.root.From(bucketAuditLog), storage.RecordAuditLog, new(boltAuditLogEntry), func(record interface{}) bool {
+		entry := record.(*boltAuditLogEntry)
+		return entry.Type != storage.ActivtyLogin && entry.EntityID != "" && !taskIDs[entry.EntityID]
+	})
+}
+
+// collectCrackedHashes removes the buckets holding the cracked hashes of tasks that no longer exist
+func (c *orphanCollector) collectCrackedHashes(tx *bolt.Tx, taskIDs map[string]bool) error {
+	var orphaned []string
+
+	bucket := tx.Bucket([]byte(bucketTasks))
+	if bucket == nil {
+		return nil
+	}
+
+	if err := bucket.ForEach(func(k, v []byte) error {
+		name := string(k)
+		// only nested buckets have a nil value
+		if v != nil || taskSubBuckets[name] || strings.HasPrefix(name, "__storm") || taskIDs[name] {
+			return nil
+		}
+		orphaned = append(orphaned, name)
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	for _, taskID := range orphaned {
+		n, err := c.root.From(bucketTasks, taskID, "results").Count(new(boltCrackedHash))
+		if err != nil && err != storm.ErrNotFound {
+			return err
+		}
+		c.report.Orphans[storage.RecordCrackedHash] += n
+
+		if !c.dryRun {
+			if err = bucket.DeleteBucket([]byte(taskID)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -30,6 +30,14 @@ func convertErr(err error) error {
 	return storage.StorageError{DriverError: err}
 }
 
+// ignoreNotFound treats storm's not found error as a success. Deleting documents that do not exist returns it
+func ignoreNotFound(err error) error {
+	if err == storm.ErrNotFound {
+		return nil
+	}
+	return err
+}
+
 func (s *BoltBackend) deleteFile(fileid string, filetype deleteType) error {
 	switch filetype {
 	case deleteTaskFile:
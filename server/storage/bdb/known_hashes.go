@@ -0,0 +1,122 @@
+package bdb
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/mandiant/gocrack/server/storage"
+
+	"github.com/asdine/storm"
+	"github.com/asdine/storm/q"
+)
+
+func knownHashID(hashType int, hash string) string {
+	return strconv.Itoa(hashType) + ":" + storage.NormalizeHash(hash)
+}
+
+// SaveKnownHash implements storage.SaveKnownHash
+func (s *BoltBackend) SaveKnownHash(kh storage.KnownHash) error {
+	var existing boltKnownHash
+
+	txn, err := s.db.From(bucketKnownHashes).Begin(true)
+	if err != nil {
+		return convertErr(err)
+	}
+	defer txn.Rollback()
+
+	kh.Hash = storage.NormalizeHash(kh.Hash)
+	id := knownHashID(kh.HashType, kh.Hash)
+	if err = txn.One("ID", id, &existing); err == nil {
+		// the first task to crack the hash is kept as its provenance
+		return nil
+	} else if err != storm.ErrNotFound {
+		return convertErr(err)
+	}
+
+	if err = txn.Save(&boltKnownHash{ID: id, KnownHash: kh, DocVersion: curKnownHashVer}); err != nil {
+		return convertErr(err)
+	}
+	return convertErr(txn.Commit())
+}
+
+// LookupKnownHashes implements storage.LookupKnownHashes
+func (s *BoltBackend) LookupKnownHashes(user storage.User, hashType int, hashes []string) ([]storage.KnownHash, error) {
+	out := make([]storage.KnownHash, 0)
+	node := s.db.From(bucketKnownHashes)
+	entitled := make(map[string]bool)
+
+	for _, hash := range hashes {
+		var tmp boltKnownHash
+
+		if err := node.One("ID", knownHashID(hashType, hash), &tmp); err != nil {
+			if err == storm.ErrNotFound {
+				continue
+			}
+			return nil, convertErr(err)
+		}
+
+		if !user.IsSuperUser {
+			ok, checked := entitled[tmp.TaskID]
+			if !checked {
+				ent, err := getEntitlement(s.db.From(bucketEntTasks...), user.UserUUID, tmp.TaskID)
+				if err != nil {
+					return nil, err
+				}
+				ok = ent != nil
+				entitled[tmp.TaskID] = ok
+			}
+
+			if !ok {
+				continue
+			}
+		}
+		out = append(out, tmp.KnownHash)
+	}
+	return out, nil
+}
+
+// CreditKnownHashes implements storage.CreditKnownHashes
+func (s *BoltBackend) CreditKnownHashes(taskID string, hashes []storage.KnownHash) (int, error) {
+	var credited int
+
+	txn, err := s.db.Begin(true)
+	if err != nil {
+		return 0, convertErr(err)
+	}
+	defer txn.Rollback()
+
+	var task boltCrackTask
+	if err = txn.From(bucketTasks).One("TaskID", taskID, &task); err != nil {
+		return 0, convertErr(err)
+	}
+
+	node := txn.From(bucketTasks, taskID, "results")
+	creditedAt := time.Now().UTC()
+	for _, kh := range hashes {
+		if err = node.Save(&boltCrackedHash{
+			DocVersion: curCrackedHashVer,
+			CrackedHash: storage.CrackedHash{
+				Hash:             kh.Hash,
+				Value:            kh.Value,
+				CrackedAt:        creditedAt,
+				ReusedFromTaskID: kh.TaskID,
+			},
+		}); err != nil {
+			if err == storm.ErrAlreadyExists {
+				continue
+			}
+			return 0, convertErr(err)
+		}
+		credited++
+	}
+
+	if err = txn.Commit(); err != nil {
+		return 0, convertErr(err)
+	}
+	return credited, nil
+}
+
+// deleteKnownHashes removes the hashes cracked by the task from the index
+func deleteKnownHashes(root storm.Node, taskID string) error {
+	return ignoreNotFound(root.From(bucketKnownHashes).Select(q.Eq("TaskID", taskID)).Delete(new(boltKnownHash)))
+}
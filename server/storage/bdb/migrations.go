@@ -0,0 +1,411 @@
+package bdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/mandiant/gocrack/server/storage"
+
+	"github.com/asdine/storm"
+	"github.com/rs/zerolog/log"
+	bolt "go.etcd.io/bbolt"
+)
+
+// boltDocument is a document as it's stored by storm
+type boltDocument map[string]interface{}
+
+// nodeWildcard matches every nested bucket at its position in migrationStep.Node, such as the bucket of each task
+const nodeWildcard = "*"
+
+// migrationStep upgrades every document of a type that is older than ToDocVersion.
+// Upgrade receives the raw document so it can read fields that no longer exist in the bolt* structs and must be
+// safe to run against a document that has already been upgraded
+type migrationStep struct {
+	// Node is the path passed into storm's From when the documents are saved. It may contain nodeWildcard
+	Node []string
+	// Model is a pointer to the bolt* struct of the documents. Storm saves documents in a bucket named after the struct
+	Model        interface{}
+	ToDocVersion float32
+	Upgrade      func(doc boltDocument) error
+	// Reindex rebuilds the storm indexes of the documents and must be set if Upgrade modifies an indexed field
+	Reindex bool
+}
+
+// migration moves the database from Version-1 to Version
+type migration struct {
+	Version     int
+	Description string
+	Steps       []migrationStep
+}
+
+// migrations contains every migration in the order they are applied.
+// Migrations must never be modified once released; append a new one instead and bump CurrentStorageVersion.
+// Every field added to a document gets a migration that sets its default, even when that is the zero value, so that
+// the DocVersion of a document always describes the fields it has
+var migrations = []migration{
+	{
+		Version:     2,
+		Description: "Record the keyspace, work unit count and progress of distributed tasks",
+		Steps: []migrationStep{
+			{
+				Node:         []string{bucketTasks},
+				Model:        &boltCrackTask{},
+				ToDocVersion: 1.2,
+				Upgrade: func(doc boltDocument) error {
+					doc.setDefault("Keyspace", 0)
+					doc.setDefault("WorkUnitCount", 0)
+					doc.setDefault("Progress", 0)
+					return nil
+				},
+			},
+		},
+	},
+	{
+		Version:     3,
+		Description: "Record whether tasks share their results through the known hash index & which passwords were credited from it",
+		Steps: []migrationStep{
+			{
+				Node:         []string{bucketTasks},
+				Model:        &boltCrackTask{},
+				ToDocVersion: 1.3,
+				Upgrade: func(doc boltDocument) error {
+					doc.setDefault("DisableHashReuse", false)
+					return nil
+				},
+			},
+			{
+				Node:         []string{bucketTasks, nodeWildcard, "results"},
+				Model:        &boltCrackedHash{},
+				ToDocVersion: 1.1,
+				Upgrade: func(doc boltDocument) error {
+					doc.setDefault("ReusedFromTaskID", "")
+					return nil
+				},
+			},
+		},
+	},
+	{
+		Version:     4,
+		Description: "Record the format of task files",
+		Steps: []migrationStep{
+			{
+				Node:         bucketTaskFiles,
+				Model:        &boltTaskFile{},
+				ToDocVersion: 1.1,
+				Upgrade: func(doc boltDocument) error {
+					doc.setDefault("Format", storage.TaskFileFormatHashes)
+					return nil
+				},
+			},
+		},
+	},
+	{
+		Version:     5,
+		Description: "Restrict when tasks can run with a start time and execution windows",
+		Steps: []migrationStep{
+			{
+				Node:         []string{bucketTasks},
+				Model:        &boltCrackTask{},
+				ToDocVersion: 1.4,
+				Upgrade: func(doc boltDocument) error {
+					doc.setDefault("Schedule", map[string]interface{}{})
+					doc.setDefault("StoppedByWindow", false)
+					return nil
+				},
+			},
+		},
+	},
+	{
+		Version:     6,
+		Description: "Track the runtime of tasks against their time budget",
+		Steps: []migrationStep{
+			{
+				Node:         []string{bucketTasks},
+				Model:        &boltCrackTask{},
+				ToDocVersion: 1.5,
+				Upgrade: func(doc boltDocument) error {
+					doc.setDefault("Runtime", 0)
+					doc.setDefault("RunningSince", nil)
+					return nil
+				},
+			},
+		},
+	},
+	{
+		Version:     7,
+		Description: "Record the tasks being stopped for a task of a higher priority",
+		Steps: []migrationStep{
+			{
+				Node:         []string{bucketTasks},
+				Model:        &boltCrackTask{},
+				ToDocVersion: 1.6,
+				Upgrade: func(doc boltDocument) error {
+					doc.setDefault("Preempted", false)
+					return nil
+				},
+			},
+		},
+	},
+	{
+		Version:     8,
+		Description: "Age the priority of tasks from when they were last queued",
+		Steps: []migrationStep{
+			{
+				Node:         []string{bucketTasks},
+				Model:        &boltCrackTask{},
+				ToDocVersion: 1.7,
+				Upgrade: func(doc boltDocument) error {
+					doc.setDefault("QueuedAt", doc["CreatedAt"])
+					return nil
+				},
+			},
+		},
+	},
+}
+
+func (s boltDocument) setDefault(field string, value interface{}) {
+	if _, ok := s[field]; !ok {
+		s[field] = value
+	}
+}
+
+func (s boltDocument) docVersion() float32 {
+	switch v := s["DocVersion"].(type) {
+	case json.Number:
+		f, _ := v.Float64()
+		return float32(f)
+	case float64:
+		return float32(v)
+	}
+	return 0
+}
+
+func (s *migrationStep) bucketPath() []string {
+	return append(append([]string{}, s.Node...), reflect.TypeOf(s.Model).Elem().Name())
+}
+
+// matchedBucket is a bucket of documents along with the node it was found at
+type matchedBucket struct {
+	node   []string
+	bucket *bolt.Bucket
+}
+
+// buckets returns every bucket of documents the step applies to by expanding the wildcards of its path
+func (s *migrationStep) buckets(tx *bolt.Tx) []matchedBucket {
+	path := s.bucketPath()
+
+	var walk func(node []string, bucket *bolt.Bucket, rest []string) []matchedBucket
+	walk = func(node []string, bucket *bolt.Bucket, rest []string) []matchedBucket {
+		if len(rest) == 0 {
+			return []matchedBucket{{node: node[:len(node)-1], bucket: bucket}}
+		}
+
+		var names [][]byte
+		if rest[0] == nodeWildcard {
+			// nested buckets are the only keys with a nil value
+			bucket.ForEach(func(k, v []byte) error {
+				if v == nil {
+					names = append(names, append([]byte{}, k...))
+				}
+				return nil
+			})
+		} else {
+			names = [][]byte{[]byte(rest[0])}
+		}
+
+		var out []matchedBucket
+		for _, name := range names {
+			if child := bucket.Bucket(name); child != nil {
+				out = append(out, walk(append(append([]string{}, node...), string(name)), child, rest[1:])...)
+			}
+		}
+		return out
+	}
+
+	root := tx.Bucket([]byte(path[0]))
+	if root == nil {
+		return nil
+	}
+	return walk([]string{path[0]}, root, path[1:])
+}
+
+// apply upgrades the documents of the step within the transaction and returns the number of documents that were modified
+func (s *migrationStep) apply(tx *bolt.Tx, root storm.Node) (int, error) {
+	var total int
+
+	// nothing has been saved of this type yet if there are no buckets
+	for _, match := range s.buckets(tx) {
+		n, err := s.upgradeBucket(match.bucket, strings.Join(match.node, "/"))
+		if err != nil {
+			return 0, err
+		}
+
+		if s.Reindex && n > 0 {
+			if err := root.From(match.node...).ReIndex(reflect.New(reflect.TypeOf(s.Model).Elem()).Interface()); err != nil {
+				return 0, err
+			}
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// upgradeBucket upgrades the documents within the bucket & returns the number of documents that were modified
+func (s *migrationStep) upgradeBucket(bucket *bolt.Bucket, name string) (int, error) {
+	upgraded := make(map[string][]byte)
+	if err := bucket.ForEach(func(k, v []byte) error {
+		var doc boltDocument
+
+		// nested buckets such as the storm indexes have no value
+		if v == nil {
+			return nil
+		}
+
+		dec := json.NewDecoder(bytes.NewReader(v))
+		dec.UseNumber()
+		if err := dec.Decode(&doc); err != nil {
+			return fmt.Errorf("failed to decode document %x in %s: %s", k, name, err)
+		}
+
+		if doc.docVersion() >= s.ToDocVersion {
+			return nil
+		}
+
+		if err := s.Upgrade(doc); err != nil {
+			return err
+		}
+		doc["DocVersion"] = s.ToDocVersion
+
+		b, err := json.Marshal(doc)
+		if err != nil {
+			return err
+		}
+		upgraded[string(k)] = b
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+
+	// bolt does not allow modifying a bucket while iterating over it
+	for k, v := range upgraded {
+		if err := bucket.Put([]byte(k), v); err != nil {
+			return 0, err
+		}
+	}
+	return len(upgraded), nil
+}
+
+// storageVersion returns the schema version of the database. ok is false if the version has never been recorded
+func (s *BoltBackend) storageVersion() (version int, ok bool, err error) {
+	if err = s.db.Get("options", "version", &version); err != nil {
+		if err == storm.ErrNotFound {
+			return 0, false, nil
+		}
+		return 0, false, convertErr(err)
+	}
+	return version, true, nil
+}
+
+func errNewerSchema(version int) error {
+	return fmt.Errorf("%w: database is at version %d and this server supports up to version %d",
+		storage.ErrNewerSchema, version, CurrentStorageVersion)
+}
+
+// backup copies the database next to itself and returns the path of the copy
+func (s *BoltBackend) backup(version int) (string, error) {
+	path := fmt.Sprintf("%s.v%d-%s.bak", s.path, version, time.Now().UTC().Format("20060102T150405"))
+
+	if err := s.db.Bolt.View(func(tx *bolt.Tx) error {
+		return tx.CopyFile(path, 0600)
+	}); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// Migrate implements storage.Migrator. The database is backed up before the first migration is applied and every
+// migration is applied within a single transaction so a failure leaves the database untouched
+func (s *BoltBackend) Migrate(dryRun bool) (*storage.MigrationReport, error) {
+	version, ok, err := s.storageVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &storage.MigrationReport{
+		FromVersion: version,
+		ToVersion:   CurrentStorageVersion,
+		DryRun:      dryRun,
+	}
+
+	if !ok {
+		// a new database is created with the latest schema
+		report.FromVersion = CurrentStorageVersion
+		if dryRun {
+			return report, nil
+		}
+		return report, convertErr(s.db.Set("options", "version", CurrentStorageVersion))
+	}
+
+	if version > CurrentStorageVersion {
+		return nil, errNewerSchema(version)
+	}
+
+	if version == CurrentStorageVersion {
+		return report, nil
+	}
+
+	if !dryRun {
+		if report.BackupPath, err = s.backup(version); err != nil {
+			return nil, fmt.Errorf("failed to backup the database before migrating: %s", err)
+		}
+	}
+
+	tx, err := s.db.Bolt.Begin(true)
+	if err != nil {
+		return nil, convertErr(err)
+	}
+	defer tx.Rollback()
+
+	root := s.db.WithTransaction(tx)
+	for _, m := range migrations {
+		if m.Version <= version {
+			continue
+		}
+
+		applied := storage.AppliedMigration{
+			Version:     m.Version,
+			Description: m.Description,
+			Changes:     make(map[string]int),
+		}
+
+		for _, step := range m.Steps {
+			n, err := step.apply(tx, root)
+			if err != nil {
+				return nil, fmt.Errorf("migration %d failed: %s", m.Version, err)
+			}
+			applied.Changes[strings.Join(step.bucketPath(), "/")] += n
+		}
+
+		if err = root.Set("options", "version", m.Version); err != nil {
+			return nil, convertErr(err)
+		}
+		report.Applied = append(report.Applied, applied)
+
+		if !dryRun {
+			log.Info().
+				Int("version", m.Version).
+				Str("description", m.Description).
+				Interface("changes", applied.Changes).
+				Msg("Applied database migration")
+		}
+	}
+
+	if dryRun {
+		return report, nil
+	}
+	return report, convertErr(tx.Commit())
+}
@@ -0,0 +1,162 @@
+package bdb
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/mandiant/gocrack/server/storage"
+
+	"github.com/stretchr/testify/assert"
+	bolt "go.etcd.io/bbolt"
+)
+
+// putRawDoc saves the document under the key in the bucket at the path, bypassing storm
+func putRawDoc(t *testing.T, db *storageTester, path []string, key string, fields map[string]interface{}) {
+	doc, _ := json.Marshal(fields)
+
+	assert.Nil(t, db.db.Bolt.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(path[0]))
+		if err != nil {
+			return err
+		}
+
+		for _, name := range path[1:] {
+			if b, err = b.CreateBucketIfNotExists([]byte(name)); err != nil {
+				return err
+			}
+		}
+		return b.Put([]byte(key), doc)
+	}))
+}
+
+func getRawDoc(t *testing.T, db *storageTester, path []string, key string) map[string]interface{} {
+	var doc map[string]interface{}
+
+	assert.Nil(t, db.db.Bolt.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(path[0]))
+		for _, name := range path[1:] {
+			b = b.Bucket([]byte(name))
+		}
+		return json.Unmarshal(b.Get([]byte(key)), &doc)
+	}))
+	return doc
+}
+
+// putLegacyTask saves a task along with a cracked password & its task file the way they were stored at storage version 1
+func putLegacyTask(t *testing.T, db *storageTester, taskID string) {
+	putRawDoc(t, db, []string{bucketTasks, "boltCrackTask"}, taskID, map[string]interface{}{
+		"DocVersion": 1.1,
+		"TaskID":     taskID,
+		"TaskName":   "legacy task",
+		"Status":     storage.TaskStatusFinished,
+		"CreatedAt":  "2024-03-01T12:00:00Z",
+	})
+	putRawDoc(t, db, []string{bucketTasks, taskID, "results", "boltCrackedHash"}, "1", map[string]interface{}{
+		"DocVersion": 1.0,
+		"Hash":       "5f4dcc3b5aa765d61d8327deb882cf99",
+		"Value":      "password",
+	})
+	putRawDoc(t, db, append(append([]string{}, bucketTaskFiles...), "boltTaskFile"), "legacy-file", map[string]interface{}{
+		"DocVersion": 1.0,
+		"FileID":     "legacy-file",
+		"FileName":   "hashes.txt",
+	})
+	assert.Nil(t, db.db.Set("options", "version", 1))
+}
+
+func getRawTask(t *testing.T, db *storageTester, taskID string) map[string]interface{} {
+	return getRawDoc(t, db, []string{bucketTasks, "boltCrackTask"}, taskID)
+}
+
+func TestMigrateNewDatabase(t *testing.T) {
+	db := initTest(t)
+	defer db.DestroyTest()
+
+	version, ok, err := db.storageVersion()
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, CurrentStorageVersion, version)
+
+	report, err := db.Migrate(false)
+	assert.Nil(t, err)
+	assert.Empty(t, report.Applied)
+	assert.Empty(t, report.BackupPath)
+}
+
+func TestMigrate(t *testing.T) {
+	db := initTest(t)
+	defer db.DestroyTest()
+
+	putLegacyTask(t, db, "legacy")
+
+	report, err := db.Migrate(true)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.True(t, report.DryRun)
+	assert.Equal(t, 1, report.FromVersion)
+	assert.Equal(t, CurrentStorageVersion, report.ToVersion)
+	assert.Empty(t, report.BackupPath)
+	if assert.Len(t, report.Applied, len(migrations)) {
+		assert.Equal(t, 2, report.Applied[0].Version)
+		assert.Equal(t, 1, report.Applied[0].Changes["tasks/boltCrackTask"])
+		assert.Equal(t, 1, report.Applied[1].Changes["tasks/*/results/boltCrackedHash"])
+		assert.Equal(t, 1, report.Applied[2].Changes["files/task_files/boltTaskFile"])
+		assert.Equal(t, CurrentStorageVersion, report.Applied[len(report.Applied)-1].Version)
+	}
+
+	// a dry run must leave the database untouched
+	version, _, err := db.storageVersion()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, version)
+	assert.Equal(t, 1.1, getRawTask(t, db, "legacy")["DocVersion"])
+
+	report, err = db.Migrate(false)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Len(t, report.Applied, len(migrations))
+	assert.FileExists(t, report.BackupPath)
+
+	doc := getRawTask(t, db, "legacy")
+	assert.InDelta(t, curCrackTaskVer, doc["DocVersion"], 0.001)
+	assert.Equal(t, float64(0), doc["Keyspace"])
+	assert.Equal(t, false, doc["DisableHashReuse"])
+	assert.Equal(t, map[string]interface{}{}, doc["Schedule"])
+	assert.Equal(t, float64(0), doc["Runtime"])
+	assert.Equal(t, false, doc["Preempted"])
+	assert.Equal(t, "2024-03-01T12:00:00Z", doc["QueuedAt"])
+	assert.Equal(t, "legacy task", doc["TaskName"])
+
+	doc = getRawDoc(t, db, []string{bucketTasks, "legacy", "results", "boltCrackedHash"}, "1")
+	assert.InDelta(t, curCrackedHashVer, doc["DocVersion"], 0.001)
+	assert.Equal(t, "", doc["ReusedFromTaskID"])
+
+	doc = getRawDoc(t, db, []string{"files", "task_files", "boltTaskFile"}, "legacy-file")
+	assert.InDelta(t, curTaskFileVer, doc["DocVersion"], 0.001)
+	assert.Equal(t, float64(storage.TaskFileFormatHashes), doc["Format"])
+
+	task, err := db.GetTaskByID("legacy")
+	if assert.Nil(t, err) {
+		assert.Equal(t, "legacy task", task.TaskName)
+		assert.Equal(t, storage.TaskStatusFinished, task.Status)
+		assert.True(t, task.CreatedAt.Equal(task.QueuedAt))
+	}
+
+	// migrations are only applied once
+	report, err = db.Migrate(false)
+	assert.Nil(t, err)
+	assert.Empty(t, report.Applied)
+}
+
+func TestMigrateRefusesNewerDatabase(t *testing.T) {
+	db := initTest(t)
+	defer db.DestroyTest()
+
+	assert.Nil(t, db.db.Set("options", "version", CurrentStorageVersion+1))
+
+	_, err := db.Migrate(true)
+	assert.True(t, errors.Is(err, storage.ErrNewerSchema))
+	assert.True(t, errors.Is(db.checkSchema(true), storage.ErrNewerSchema))
+}
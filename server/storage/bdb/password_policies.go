@@ -0,0 +1,54 @@
+package bdb
+
+import (
+	"sort"
+
+	"github.com/mandiant/gocrack/server/storage"
+
+	"github.com/asdine/storm"
+)
+
+// SavePasswordPolicy implements storage.SavePasswordPolicy
+func (s *BoltBackend) SavePasswordPolicy(policy storage.PasswordPolicy) error {
+	return convertErr(s.db.From(bucketPolicies).Save(&boltPasswordPolicy{
+		DocVersion:     curPolicyVer,
+		PasswordPolicy: policy,
+	}))
+}
+
+// GetPasswordPolicy implements storage.GetPasswordPolicy
+func (s *BoltBackend) GetPasswordPolicy(policyID string) (*storage.PasswordPolicy, error) {
+	var policy boltPasswordPolicy
+
+	if err := s.db.From(bucketPolicies).One("PolicyID", policyID, &policy); err != nil {
+		return nil, convertErr(err)
+	}
+	return &policy.PasswordPolicy, nil
+}
+
+// GetPasswordPolicies implements storage.GetPasswordPolicies
+func (s *BoltBackend) GetPasswordPolicies() ([]storage.PasswordPolicy, error) {
+	var policies []boltPasswordPolicy
+
+	if err := s.db.From(bucketPolicies).All(&policies); err != nil && err != storm.ErrNotFound {
+		return nil, convertErr(err)
+	}
+
+	out := make([]storage.PasswordPolicy, len(policies))
+	for i, policy := range policies {
+		out[i] = policy.PasswordPolicy
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+// DeletePasswordPolicy implements storage.DeletePasswordPolicy
+func (s *BoltBackend) DeletePasswordPolicy(policyID string) error {
+	var policy boltPasswordPolicy
+
+	node := s.db.From(bucketPolicies)
+	if err := node.One("PolicyID", policyID, &policy); err != nil {
+		return convertErr(err)
+	}
+	return convertErr(node.DeleteStruct(&policy))
+}
@@ -0,0 +1,43 @@
+package bdb
+
+import (
+	"sort"
+
+	"github.com/mandiant/gocrack/server/storage"
+
+	"github.com/asdine/storm"
+)
+
+// SavePipeline implements storage.SavePipeline
+func (s *BoltBackend) SavePipeline(p storage.Pipeline) error {
+	return convertErr(s.db.From(bucketPipelines).Save(&boltPipeline{
+		DocVersion: curPipelineVer,
+		Pipeline:   p,
+	}))
+}
+
+// GetPipeline implements storage.GetPipeline
+func (s *BoltBackend) GetPipeline(pipelineID string) (*storage.Pipeline, error) {
+	var p boltPipeline
+
+	if err := s.db.From(bucketPipelines).One("PipelineID", pipelineID, &p); err != nil {
+		return nil, convertErr(err)
+	}
+	return &p.Pipeline, nil
+}
+
+// GetPipelines implements storage.GetPipelines
+func (s *BoltBackend) GetPipelines() ([]storage.Pipeline, error) {
+	var pipelines []boltPipeline
+
+	if err := s.db.From(bucketPipelines).All(&pipelines); err != nil && err != storm.ErrNotFound {
+		return nil, convertErr(err)
+	}
+
+	out := make([]storage.Pipeline, len(pipelines))
+	for i, p := range pipelines {
+		out[i] = p.Pipeline
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, nil
+}
@@ -3,14 +3,22 @@ package bdb
 import "github.com/mandiant/gocrack/server/storage"
 
 const (
-	curCrackTaskVer      float32 = 1.1
+	curCrackTaskVer      float32 = 1.7
 	curUserVer           float32 = 1.0
 	curEntVer            float32 = 1.0
-	curTaskFileVer       float32 = 1.0
-	curCrackedHashVer    float32 = 1.0
+	curTaskFileVer       float32 = 1.1
+	curCrackedHashVer    float32 = 1.1
 	curAuditEntryVer     float32 = 1.0
 	curEngineFileVer     float32 = 1.0
 	curCheckpointFileVer float32 = 1.0
+	curWorkUnitVer       float32 = 1.0
+	curStatusHistoryVer  float32 = 1.0
+	curKnownHashVer      float32 = 1.0
+	curAccountVer        float32 = 1.0
+	curPolicyVer         float32 = 1.0
+	curWordlistVer       float32 = 1.0
+	curPipelineVer       float32 = 1.0
+	curTemplateVer       float32 = 1.0
 )
 
 var (
@@ -18,14 +26,26 @@ var (
 	bucketTasks       = "tasks"
 	bucketEntName     = "entitlements"
 	bucketCheckpoints = "checkpoints"
+	bucketKnownHashes = "known_hashes"
+	bucketPolicies    = "password_policies"
+	bucketWordlists   = "cracked_wordlists"
+	bucketPipelines   = "pipelines"
+	bucketTemplates   = "task_templates"
 
 	bucketTaskFiles   = []string{"files", "task_files"}
 	bucketEngineFiles = []string{"files", "engine_files"}
+	bucketWorkUnits   = []string{bucketTasks, "work_units"}
+	bucketTaskStatus  = []string{bucketTasks, "status_history"}
 
 	// Buckets related to entitlements
 	bucketEntTaskFiles   = append(bucketTaskFiles, bucketEntName)
 	bucketEntTasks       = []string{bucketTasks, bucketEntName}
 	bucketEntEngineFiles = append(bucketEngineFiles, bucketEntName)
+	bucketEntTemplates   = []string{bucketTemplates, bucketEntName}
+
+	// accounts are nested under the task files so they are saved within a task file transaction
+	bucketAccountsName = "accounts"
+	bucketAccounts     = append(bucketTaskFiles, bucketAccountsName)
 )
 
 type boltUser struct {
@@ -77,3 +97,47 @@ type boltCheckpointFile struct {
 	DocVersion             float32
 	storage.CheckpointFile `storm:"inline"`
 }
+
+type boltWorkUnit struct {
+	DocVersion       float32
+	storage.WorkUnit `storm:"inline"`
+}
+
+type boltTaskStatusEntry struct {
+	ID                             int64 `storm:"id,increment"`
+	DocVersion                     float32
+	storage.TaskStatusHistoryEntry `storm:"inline"`
+}
+
+type boltKnownHash struct {
+	// ID is the hash type & hash joined by a colon as storm cannot enforce uniqueness across multiple fields
+	ID                string `storm:"id,unique"`
+	DocVersion        float32
+	storage.KnownHash `storm:"inline"`
+}
+
+type boltTaskFileAccount struct {
+	ID                      int64 `storm:"id,increment"`
+	DocVersion              float32
+	storage.TaskFileAccount `storm:"inline"`
+}
+
+type boltPasswordPolicy struct {
+	DocVersion             float32
+	storage.PasswordPolicy `storm:"inline"`
+}
+
+type boltCrackedWordlist struct {
+	DocVersion              float32
+	storage.CrackedWordlist `storm:"inline"`
+}
+
+type boltPipeline struct {
+	DocVersion       float32
+	storage.Pipeline `storm:"inline"`
+}
+
+type boltTaskTemplate struct {
+	DocVersion           float32
+	storage.TaskTemplate `storm:"inline"`
+}
@@ -5,11 +5,12 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/asdine/storm"
+	"github.com/rs/zerolog/log"
 )
 
 // CurrentStorageVersion describes what storage version we're on and can be used to determine
-// if the underlying database structure needs to change
-const CurrentStorageVersion = 1.0
+// if the underlying database structure needs to change. It must match the version of the last migration
+const CurrentStorageVersion = 8
 
 var bucketInternalConfig = []byte("config")
 
@@ -27,6 +28,7 @@ func (s *Driver) Open(cfg storage.Config) (storage.Backend, error) {
 type BoltBackend struct {
 	// contains filtered or unexported fields
 	db       *storm.DB
+	path     string
 	expstats *StatsExporter
 }
 
@@ -39,9 +41,11 @@ func Init(cfg storage.Config) (storage.Backend, error) {
 
 	bb := &BoltBackend{
 		db:       db,
+		path:     cfg.ConnectionString,
 		expstats: NewExporter(db),
 	}
-	if err = bb.checkSchema(); err != nil {
+	if err = bb.checkSchema(cfg.SkipMigrations); err != nil {
+		db.Close()
 		return nil, err
 	}
 
@@ -60,18 +64,32 @@ func (s *BoltBackend) Close() error {
 	return s.db.Close()
 }
 
-func (s *BoltBackend) checkSchema() error {
-	var opt interface{}
-	// check our internal version
-	if err := s.db.Get("options", "version", &opt); err != nil {
-		if err == storm.ErrNotFound {
-			if err := s.db.Set("options", "version", CurrentStorageVersion); err != nil {
-				return err
-			}
-			opt = CurrentStorageVersion
-			return nil
+// checkSchema brings the database up to the latest storage version. If skipMigrations is set, the database is only
+// checked to ensure it was not written by a newer version of GoCrack
+func (s *BoltBackend) checkSchema(skipMigrations bool) error {
+	if skipMigrations {
+		version, _, err := s.storageVersion()
+		if err != nil {
+			return err
 		}
+
+		if version > CurrentStorageVersion {
+			return errNewerSchema(version)
+		}
+		return nil
+	}
+
+	report, err := s.Migrate(false)
+	if err != nil {
+		return err
+	}
+
+	if len(report.Applied) > 0 {
+		log.Info().
+			Int("from_version", report.FromVersion).
+			Int("to_version", report.ToVersion).
+			Str("backup", report.BackupPath).
+			Msg("Database has been migrated")
 	}
-	// XXX(cschmitt): What do if version changes?
 	return nil
 }
@@ -6,7 +6,6 @@ import (
 
 	"github.com/mandiant/gocrack/server/storage"
 
-	"github.com/asdine/storm"
 	"github.com/asdine/storm/q"
 )
 
@@ -24,8 +23,9 @@ func (s *strContainsMatcher) MatchField(v interface{}) (bool, error) {
 	case string:
 		return strings.Contains(fieldValue, s.contains), nil
 	case *string:
+		// optional fields such as the case code are never a match when they are unset
 		if fieldValue == nil {
-			return false, storm.ErrNilParam
+			return false, nil
 		}
 		return strings.Contains(*fieldValue, s.contains), nil
 	case storage.TaskStatus:
@@ -34,3 +34,23 @@ func (s *strContainsMatcher) MatchField(v interface{}) (bool, error) {
 		return false, fmt.Errorf("Only string is supported for StringContains matcher, got %T", fieldValue)
 	}
 }
+
+// StringEquals checks if the given field value is exactly value. Unlike q.Eq, optional (*string) fields are supported
+func StringEquals(field, value string) q.Matcher {
+	return q.NewFieldMatcher(field, &strEqualsMatcher{value: value})
+}
+
+type strEqualsMatcher struct {
+	value string
+}
+
+func (s *strEqualsMatcher) MatchField(v interface{}) (bool, error) {
+	switch fieldValue := v.(type) {
+	case string:
+		return fieldValue == s.value, nil
+	case *string:
+		return fieldValue != nil && *fieldValue == s.value, nil
+	default:
+		return false, fmt.Errorf("Only string is supported for StringEquals matcher, got %T", fieldValue)
+	}
+}
@@ -25,6 +25,10 @@ func TestStringContains(t *testing.T) {
 			Field: "not a match",
 			Match: false,
 		},
+		{
+			Field: (*string)(nil), // unset optional field
+			Match: false,
+		},
 		{
 			Field:         []byte("hello"), // wrong type
 			Match:         false,
@@ -44,6 +44,10 @@ func (s *TaskCreateTransaction) CreateTask(t *storage.Task) error {
 		t.CreatedAt = time.Now().UTC()
 	}
 
+	if t.QueuedAt.IsZero() {
+		t.QueuedAt = t.CreatedAt
+	}
+
 	if t.LastUpdatedAt.IsZero() {
 		t.LastUpdatedAt = time.Now().UTC()
 	}
@@ -65,6 +69,17 @@ func (s *TaskCreateTransaction) GrantEntitlement(userUUID string, t storage.Task
 	}, t)
 }
 
+// CreateWorkUnits saves the work units of a distributed task within the context of a database transaction
+func (s *TaskCreateTransaction) CreateWorkUnits(units []storage.WorkUnit) error {
+	node := s.txn.From(bucketWorkUnits[1:]...)
+	for _, unit := range units {
+		if err := node.Save(&boltWorkUnit{WorkUnit: unit, DocVersion: curWorkUnitVer}); err != nil {
+			return convertErr(err)
+		}
+	}
+	return nil
+}
+
 // Rollback any writes to the database
 func (s *TaskCreateTransaction) Rollback() error {
 	return s.txn.Rollback()
@@ -41,6 +41,17 @@ func (s *TaskFileTransaction) SaveTaskFile(tf storage.TaskFile) error {
 	return nil
 }
 
+// SaveTaskFileAccounts saves the accounts parsed from the task file
+func (s *TaskFileTransaction) SaveTaskFileAccounts(accounts []storage.TaskFileAccount) error {
+	node := s.txn.From(bucketAccountsName)
+	for _, account := range accounts {
+		if err := node.Save(&boltTaskFileAccount{TaskFileAccount: account, DocVersion: curAccountVer}); err != nil {
+			return convertErr(err)
+		}
+	}
+	return nil
+}
+
 // AddEntitlement creates a record giving the user access to the task file
 func (s *TaskFileTransaction) AddEntitlement(tf storage.TaskFile, userid string) error {
 	node := s.txn.From(bucketEntName)
@@ -105,7 +116,30 @@ func (s *BoltBackend) ListTasksForUser(user storage.User) ([]storage.TaskFile, e
 	return tfs, nil
 }
 
+// GetTaskFileAccounts implements storage.GetTaskFileAccounts
+func (s *BoltBackend) GetTaskFileAccounts(fileID string) ([]storage.TaskFileAccount, error) {
+	var accounts []boltTaskFileAccount
+
+	if err := s.db.From(bucketAccounts...).Find("FileID", fileID, &accounts); err != nil && err != storm.ErrNotFound {
+		return nil, convertErr(err)
+	}
+
+	out := make([]storage.TaskFileAccount, len(accounts))
+	for i, account := range accounts {
+		out[i] = account.TaskFileAccount
+	}
+	return out, nil
+}
+
+// deleteTaskFileAccounts removes the accounts parsed from the task file
+func deleteTaskFileAccounts(root storm.Node, fileID string) error {
+	return ignoreNotFound(root.From(bucketAccounts...).Select(q.Eq("FileID", fileID)).Delete(new(boltTaskFileAccount)))
+}
+
 // DeleteTaskFile implements storage.DeleteTaskFile
 func (s *BoltBackend) DeleteTaskFile(fileID string) error {
-	return convertErr(s.deleteFile(fileID, deleteTaskFile))
+	if err := s.deleteFile(fileID, deleteTaskFile); err != nil {
+		return convertErr(err)
+	}
+	return convertErr(deleteTaskFileAccounts(s.db, fileID))
 }
@@ -8,10 +8,11 @@ import (
 	"time"
 
 	"github.com/mandiant/gocrack/server/storage"
-	"github.com/mandiant/gocrack/shared"
+	"github.com/mandiant/gocrack/shared/enginedef"
 
 	"github.com/asdine/storm"
 	"github.com/asdine/storm/q"
+	bolt "go.etcd.io/bbolt"
 )
 
 var errExpectedUser = errors.New("expected CreatedBy to be set")
@@ -79,55 +80,103 @@ func convertTaskFromMap(task *storage.Task) error {
 	if err != nil {
 		return err
 	}
-	switch task.Engine {
-	case storage.WorkerHashcatEngine:
-		var hcp shared.HashcatUserOptions
-		if err := json.Unmarshal(b, &hcp); err != nil {
-			return err
-		}
-		task.EnginePayload = hcp
-	default:
-		return errors.New("unexpected engine")
+	payload, err := enginedef.DecodePayload(task.Engine, b)
+	if err != nil {
+		return err
 	}
+	task.EnginePayload = payload
 	return nil
 }
 
-func (s *BoltBackend) getNextTaskForHost(workerHostname string, devicesInUse storage.CLDevices) (*storage.Task, error) {
-	var tmp boltCrackTask
+// getNextTaskForHost returns the next task the host should run. If the task is distributed, the next work unit of the task
+// is assigned to the host and returned with it
+func (s *BoltBackend) getNextTaskForHost(req storage.GetPendingTasksRequest) (*storage.Task, *storage.WorkUnit, error) {
+	var tmp []boltCrackTask
 
-	searchQuery := q.And(
+	matchers := []q.Matcher{
 		q.Or(
-			q.Eq("AssignedToHost", workerHostname),
+			q.Eq("AssignedToHost", req.Hostname),
 			q.Eq("AssignedToHost", ""),
 		),
 		q.Not(
-			DeviceMatch(devicesInUse),
+			DeviceMatch(req.DevicesInUse),
 		),
-		q.Eq("Status", storage.TaskStatusQueued),
-	)
+		q.Or(
+			q.Eq("Status", storage.TaskStatusQueued),
+			// distributed tasks keep handing out units while other workers are processing the rest of the keyspace
+			q.And(
+				q.Gt("WorkUnitCount", 0),
+				q.In("Status", []storage.TaskStatus{storage.TaskStatusDequeued, storage.TaskStatusRunning}),
+			),
+		),
+		// a worker only processes a single unit of a task at a time
+		q.Not(
+			q.In("TaskID", req.RunningTasks),
+		),
+	}
+
+	// skip tasks of engines the host can't run
+	if req.Engines != nil {
+		if len(req.Engines) == 0 {
+			return nil, nil, storage.ErrNotFound
+		}
+		matchers = append(matchers, q.In("Engine", req.Engines))
+	}
 
 	baseQuery := s.db.
 		From("tasks").
-		Select(searchQuery).
-		Limit(1).
+		Select(q.And(matchers...)).
 		OrderBy("Priority", "CreatedAt")
 
-	if err := baseQuery.First(&tmp); err != nil {
-		return nil, convertErr(err)
+	if err := baseQuery.Find(&tmp); err != nil {
+		return nil, nil, convertErr(err)
 	}
 
-	v := storage.Task(tmp.Task)
-	if err := convertTaskFromMap(&v); err != nil {
-		return nil, convertErr(err)
+	now := time.Now().UTC()
+	candidates := make([]storage.Task, 0, len(tmp))
+	for _, bt := range tmp {
+		// tasks wait in the queue until their schedule allows them to run & are not handed out once out of time
+		if !bt.Schedule.IsOpen(now) || bt.IsOutOfTime(now) {
+			continue
+		}
+
+		v := storage.Task(bt.Task)
+		if err := convertTaskFromMap(&v); err != nil {
+			return nil, nil, convertErr(err)
+		}
+		candidates = append(candidates, v)
+	}
+
+	if req.Schedule != nil {
+		var err error
+		if candidates, err = req.Schedule(candidates); err != nil {
+			return nil, nil, err
+		}
 	}
-	return &v, nil
+
+	for i := range candidates {
+		var unit *storage.WorkUnit
+
+		if candidates[i].WorkUnitCount > 0 {
+			var err error
+			if unit, err = s.claimWorkUnit(candidates[i].TaskID, req.Hostname); err != nil {
+				if err == storage.ErrNotFound {
+					// every unit is either running or done
+					continue
+				}
+				return nil, nil, err
+			}
+		}
+		return &candidates[i], unit, nil
+	}
+	return nil, nil, storage.ErrNotFound
 }
 
 func (s *BoltBackend) GetPendingTasks(req storage.GetPendingTasksRequest) ([]storage.GetPendingTasksResponseItem, error) {
 	var items []storage.GetPendingTasksResponseItem
 
 	if req.CheckForNewTask {
-		newTask, err := s.getNextTaskForHost(req.Hostname, req.DevicesInUse)
+		newTask, unit, err := s.getNextTaskForHost(req)
 		if err != nil {
 			if err == storage.ErrNotFound {
 				goto GetPaused
@@ -137,8 +186,9 @@ func (s *BoltBackend) GetPendingTasks(req storage.GetPendingTasksRequest) ([]sto
 
 		if newTask != nil {
 			items = append(items, storage.GetPendingTasksResponseItem{
-				Type:    storage.PendingTaskNewRequest,
-				Payload: newTask,
+				Type:     storage.PendingTaskNewRequest,
+				Payload:  newTask,
+				WorkUnit: unit,
 			})
 		}
 	}
@@ -146,7 +196,14 @@ func (s *BoltBackend) GetPendingTasks(req storage.GetPendingTasksRequest) ([]sto
 GetPaused:
 	searchQuery := q.And(
 		q.In("TaskID", req.RunningTasks),
-		q.Eq("Status", storage.TaskStatusStopping),
+		q.Or(
+			q.Eq("Status", storage.TaskStatusStopping),
+			// The remaining units of a distributed task are stopped once another unit has cracked every hash
+			q.And(
+				q.Gt("WorkUnitCount", 0),
+				q.In("Status", []storage.TaskStatus{storage.TaskStatusFinished, storage.TaskStatusError}),
+			),
+		),
 	)
 
 	if err := convertErr(s.db.From("tasks").Select(searchQuery).Each(new(boltCrackTask), func(record interface{}) error {
@@ -155,7 +212,7 @@ GetPaused:
 			Type: storage.PendingTaskStatusChange,
 			Payload: storage.PendingTaskStatusChangeItem{
 				TaskID:    taskToPause.TaskID,
-				NewStatus: taskToPause.Status,
+				NewStatus: storage.TaskStatusStopping,
 			},
 		})
 		return nil
@@ -169,29 +226,71 @@ GetPaused:
 	return items, nil
 }
 
-func (s *BoltBackend) ChangeTaskStatus(taskID string, status storage.TaskStatus, potentialError *string) error {
+// ChangeTaskStatus implements storage.ChangeTaskStatus
+func (s *BoltBackend) ChangeTaskStatus(taskID string, change storage.TaskStatusChange) (*storage.TaskStatusHistoryEntry, error) {
 	var tmp boltCrackTask
 
-	txn, err := s.db.From("tasks").Begin(true)
+	txn, err := s.db.Begin(true)
 	if err != nil {
-		return convertErr(err)
+		return nil, convertErr(err)
 	}
 	defer txn.Rollback()
 
-	if err = txn.One("TaskID", taskID, &tmp); err != nil {
-		return convertErr(err)
+	if err = txn.From(bucketTasks).One("TaskID", taskID, &tmp); err != nil {
+		return nil, convertErr(err)
 	}
 
-	if potentialError != nil {
-		tmp.Error = potentialError
+	entry := storage.TaskStatusHistoryEntry{
+		TaskID:           taskID,
+		ChangedAt:        time.Now().UTC(),
+		PreviousStatus:   tmp.Status,
+		TaskStatusChange: change,
 	}
 
-	tmp.Status = status
+	if change.Error != nil {
+		tmp.Error = change.Error
+	}
 
-	if err = txn.Update(&tmp); err != nil {
-		return convertErr(err)
+	tmp.Status = change.Status
+	tmp.TrackRuntime(change.Status, entry.ChangedAt)
+	if change.Status == storage.TaskStatusQueued {
+		tmp.QueuedAt = entry.ChangedAt
 	}
-	return txn.Commit()
+
+	if err = txn.From(bucketTasks).Update(&tmp); err != nil {
+		return nil, convertErr(err)
+	}
+	// storm's Update skips zero values so the end of a run has to be written explicitly
+	if tmp.RunningSince == nil {
+		if err = txn.From(bucketTasks).UpdateField(&tmp, "RunningSince", (*time.Time)(nil)); err != nil {
+			return nil, convertErr(err)
+		}
+	}
+
+	if err = txn.From(bucketTaskStatus...).Save(&boltTaskStatusEntry{
+		TaskStatusHistoryEntry: entry,
+		DocVersion:             curStatusHistoryVer,
+	}); err != nil {
+		return nil, convertErr(err)
+	}
+
+	if err = txn.Commit(); err != nil {
+		return nil, convertErr(err)
+	}
+	return &entry, nil
+}
+
+// GetTaskStatusHistory implements storage.GetTaskStatusHistory
+func (s *BoltBackend) GetTaskStatusHistory(taskID string) ([]storage.TaskStatusHistoryEntry, error) {
+	items := make([]storage.TaskStatusHistoryEntry, 0)
+
+	if err := convertErr(s.db.From(bucketTaskStatus...).Select(q.Eq("TaskID", taskID)).OrderBy("ChangedAt", "ID").Each(new(boltTaskStatusEntry), func(record interface{}) error {
+		items = append(items, record.(*boltTaskStatusEntry).TaskStatusHistoryEntry)
+		return nil
+	})); err != nil && err != storage.ErrNotFound {
+		return nil, err
+	}
+	return items, nil
 }
 
 func (s *BoltBackend) TasksSearch(page, limit int, orderby, searchQuery string, isAscending bool, user storage.User) (*storage.SearchResults, error) {
@@ -300,6 +399,38 @@ func (s *BoltBackend) TasksSearch(page, limit int, orderby, searchQuery string,
 	return sr, nil
 }
 
+// GetTasksByCaseCode implements storage.GetTasksByCaseCode
+func (s *BoltBackend) GetTasksByCaseCode(caseCode string, user storage.User) ([]storage.Task, error) {
+	matcher := StringEquals("CaseCode", caseCode)
+
+	if !user.IsSuperUser {
+		var taskids []string
+
+		if err := s.db.From("tasks", bucketEntName).Select(
+			q.Eq("UserUUID", user.UserUUID),
+		).Each(new(boltEntitlement), func(record interface{}) error {
+			taskids = append(taskids, record.(*boltEntitlement).EntitledID)
+			return nil
+		}); err != nil {
+			return nil, convertErr(err)
+		}
+		matcher = q.And(q.In("TaskID", taskids), matcher)
+	}
+
+	tasks := make([]storage.Task, 0)
+	if err := s.db.From("tasks").Select(matcher).OrderBy("CreatedAt").Each(new(boltCrackTask), func(record interface{}) error {
+		task := record.(*boltCrackTask).Task
+		if err := convertTaskFromMap(&task); err != nil {
+			return err
+		}
+		tasks = append(tasks, task)
+		return nil
+	}); err != nil {
+		return nil, convertErr(err)
+	}
+	return tasks, nil
+}
+
 func (s *BoltBackend) countCrackedPasswords(taskid string) (int, error) {
 	return s.db.From("tasks", taskid, "results").Count(&boltCrackedHash{})
 }
@@ -331,14 +462,14 @@ func (s *BoltBackend) GetCrackedPasswords(taskid string) (*[]storage.CrackedHash
 }
 
 func (s *BoltBackend) UpdateTask(taskid string, modifiedFields storage.ModifiableTaskRequest) error {
-	txn, err := s.db.From("tasks").Begin(true)
+	txn, err := s.db.Begin(true)
 	if err != nil {
 		return convertErr(err)
 	}
 	defer txn.Rollback()
 
 	var tmp boltCrackTask
-	if err = txn.One("TaskID", taskid, &tmp); err != nil {
+	if err = txn.From(bucketTasks).One("TaskID", taskid, &tmp); err != nil {
 		return convertErr(err)
 	}
 
@@ -366,20 +497,203 @@ func (s *BoltBackend) UpdateTask(taskid string, modifiedFields storage.Modifiabl
 		tmp.TaskDuration = *modifiedFields.TaskDuration
 	}
 
-	if err = txn.Update(&tmp); err != nil {
+	if modifiedFields.DisableHashReuse != nil {
+		tmp.DisableHashReuse = *modifiedFields.DisableHashReuse
+		if tmp.DisableHashReuse {
+			if err = deleteKnownHashes(txn, taskid); err != nil {
+				return convertErr(err)
+			}
+		}
+	}
+
+	if err = txn.From(bucketTasks).Update(&tmp); err != nil {
 		return convertErr(err)
 	}
+	// storm's Update skips zero values so re-enabling reuse has to be written explicitly
+	if modifiedFields.DisableHashReuse != nil && !tmp.DisableHashReuse {
+		if err = txn.From(bucketTasks).UpdateField(&tmp, "DisableHashReuse", false); err != nil {
+			return convertErr(err)
+		}
+	}
+
+	if modifiedFields.Schedule != nil {
+		if err = txn.From(bucketTasks).UpdateField(&tmp, "Schedule", *modifiedFields.Schedule); err != nil {
+			return convertErr(err)
+		}
+	}
 	txn.Commit()
 
 	return nil
 }
 
 // DeleteTask implements storage.DeleteTask
-func (s *BoltBackend) DeleteTask(taskid string) error {
+func (s *BoltBackend) DeleteTask(taskid string, opts storage.DeleteTaskOptions) (*storage.TaskFile, error) {
 	var bt boltCrackTask
-	if err := s.db.From(bucketTasks).One("TaskID", taskid, &bt); err != nil {
+	var removedFile *storage.TaskFile
+
+	txn, err := s.db.Begin(true)
+	if err != nil {
+		return nil, convertErr(err)
+	}
+	defer txn.Rollback()
+
+	if err = txn.From(bucketTasks).One("TaskID", taskid, &bt); err != nil {
+		return nil, convertErr(err)
+	}
+
+	if err = deleteTaskDocuments(txn, taskid); err != nil {
+		return nil, convertErr(err)
+	}
+
+	if err = txn.From(bucketTasks).DeleteStruct(&bt); err != nil {
+		return nil, convertErr(err)
+	}
+
+	if opts.RemoveUnusedTaskFile {
+		if removedFile, err = deleteUnusedTaskFile(txn, bt.FileID); err != nil {
+			return nil, convertErr(err)
+		}
+	}
+
+	if err = txn.Commit(); err != nil {
+		return nil, convertErr(err)
+	}
+	return removedFile, nil
+}
+
+// deleteTaskDocuments removes everything that belongs to the task except for the task itself
+func deleteTaskDocuments(root storm.Node, taskID string) error {
+	if err := ignoreNotFound(root.From(bucketWorkUnits...).Select(q.Eq("TaskID", taskID)).Delete(new(boltWorkUnit))); err != nil {
+		return err
+	}
+
+	// cracked hashes are saved in a bucket named after the task
+	if err := root.From(bucketTasks).Drop(taskID); err != nil && err != bolt.ErrBucketNotFound {
+		return err
+	}
+
+	if err := ignoreNotFound(root.From(bucketCheckpoints).DeleteStruct(&boltCheckpointFile{ID: taskID})); err != nil {
+		return err
+	}
+
+	if err := ignoreNotFound(root.From(bucketTaskStatus...).Select(q.Eq("TaskID", taskID)).Delete(new(boltTaskStatusEntry))); err != nil {
+		return err
+	}
+
+	if err := deleteKnownHashes(root, taskID); err != nil {
+		return err
+	}
+
+	if err := ignoreNotFound(root.From(bucketEntTasks...).Select(q.Eq("EntitledID", taskID)).Delete(new(boltEntitlement))); err != nil {
+		return err
+	}
+	return ignoreNotFound(root.From(bucketAuditLog).Select(q.Eq("EntityID", taskID)).Delete(new(boltAuditLogEntry)))
+}
+
+// deleteUnusedTaskFile removes the task file and its entitlements if no task references it
+func deleteUnusedTaskFile(root storm.Node, fileID string) (*storage.TaskFile, error) {
+	var btf boltTaskFile
+
+	used, err := root.From(bucketTasks).Select(q.Eq("FileID", fileID)).Count(new(boltCrackTask))
+	if err != nil || used > 0 {
+		return nil, err
+	}
+
+	if err = root.From(bucketTaskFiles...).One("FileID", fileID, &btf); err != nil {
+		// the file may have been deleted before the task
+		return nil, ignoreNotFound(err)
+	}
+
+	if err = root.From(bucketTaskFiles...).DeleteStruct(&btf); err != nil {
+		return nil, err
+	}
+
+	if err = ignoreNotFound(root.From(bucketEntTaskFiles...).Select(q.Eq("EntitledID", fileID)).Delete(new(boltEntitlement))); err != nil {
+		return nil, err
+	}
+
+	if err = deleteTaskFileAccounts(root, fileID); err != nil {
+		return nil, err
+	}
+
+	tf := storage.TaskFile(btf.TaskFile)
+	return &tf, nil
+}
+
+// SetTaskProgress records the overall progress of a distributed task
+func (s *BoltBackend) SetTaskProgress(taskID string, progress float64) error {
+	return s.updateTaskField(taskID, "Progress", progress)
+}
+
+// SetTaskStoppedByWindow implements storage.SetTaskStoppedByWindow
+func (s *BoltBackend) SetTaskStoppedByWindow(taskID string, stopped bool) error {
+	return s.updateTaskField(taskID, "StoppedByWindow", stopped)
+}
+
+// SetTaskPreempted implements storage.SetTaskPreempted
+func (s *BoltBackend) SetTaskPreempted(taskID string, preempted bool) error {
+	return s.updateTaskField(taskID, "Preempted", preempted)
+}
+
+// updateTaskField sets a single field of the task. Unlike Update, zero values are written as well
+func (s *BoltBackend) updateTaskField(taskID, field string, value interface{}) error {
+	var tmp boltCrackTask
+
+	txn, err := s.db.From(bucketTasks).Begin(true)
+	if err != nil {
 		return convertErr(err)
 	}
+	defer txn.Rollback()
 
-	return convertErr(s.db.From(bucketTasks).DeleteStruct(&bt))
+	if err = txn.One("TaskID", taskID, &tmp); err != nil {
+		return convertErr(err)
+	}
+
+	if err = txn.UpdateField(&tmp, field, value); err != nil {
+		return convertErr(err)
+	}
+	return txn.Commit()
+}
+
+// GetWindowedTasks implements storage.GetWindowedTasks
+func (s *BoltBackend) GetWindowedTasks() ([]storage.Task, error) {
+	tasks := make([]storage.Task, 0)
+
+	if err := s.db.From(bucketTasks).Select(q.In("Status", []storage.TaskStatus{
+		storage.TaskStatusDequeued,
+		storage.TaskStatusRunning,
+		storage.TaskStatusStopping,
+		storage.TaskStatusStopped,
+	})).OrderBy("CreatedAt").Each(new(boltCrackTask), func(record interface{}) error {
+		task := record.(*boltCrackTask).Task
+		if len(task.Schedule.Windows) == 0 {
+			return nil
+		}
+
+		if err := convertTaskFromMap(&task); err != nil {
+			return err
+		}
+		tasks = append(tasks, task)
+		return nil
+	}); err != nil && err != storm.ErrNotFound {
+		return nil, convertErr(err)
+	}
+	return tasks, nil
+}
+
+// GetTasksByStatus implements storage.GetTasksByStatus
+func (s *BoltBackend) GetTasksByStatus(statuses ...storage.TaskStatus) ([]storage.Task, error) {
+	tasks := make([]storage.Task, 0)
+
+	if err := s.db.From(bucketTasks).Select(q.In("Status", statuses)).OrderBy("CreatedAt").Each(new(boltCrackTask), func(record interface{}) error {
+		task := record.(*boltCrackTask).Task
+		if err := convertTaskFromMap(&task); err != nil {
+			return err
+		}
+		tasks = append(tasks, task)
+		return nil
+	}); err != nil && err != storm.ErrNotFound {
+		return nil, convertErr(err)
+	}
+	return tasks, nil
 }
@@ -53,7 +53,7 @@ func TestTaskManagementChangeStatus(t *testing.T) {
 	}
 	txn.Commit()
 
-	err = db.ChangeTaskStatus(doc.TaskID, storage.TaskStatusDequeued, nil)
+	_, err = db.ChangeTaskStatus(doc.TaskID, storage.TaskStatusChange{Status: storage.TaskStatusDequeued})
 	assert.NoErrorf(t, err, "unexpected error changing task status")
 
 	foundDoc, err := db.GetTaskByID(doc.TaskID)
@@ -273,7 +273,10 @@ func TestTaskManagementGetNextTaskForHost(t *testing.T) {
 			goto CleanupTestIteration
 		}
 
-		task, err = db.getNextTaskForHost(test.Search.Hostname, test.Search.Devices)
+		task, _, err = db.getNextTaskForHost(storage.GetPendingTasksRequest{
+			Hostname:     test.Search.Hostname,
+			DevicesInUse: test.Search.Devices,
+		})
 		if test.ExpectedErrorOnGetNextTask == nil && err != nil {
 			assert.Fail(t, fmt.Sprintf("unexpected error getting next task for host in test %d", i), err.Error())
 			goto CleanupTestIteration
@@ -347,7 +350,7 @@ func TestTaskManagementProperlyQueueingTasks(t *testing.T) {
 		}
 	}
 
-	task, err := db.getNextTaskForHost("my-hostname", nil)
+	task, _, err := db.getNextTaskForHost(storage.GetPendingTasksRequest{Hostname: "my-hostname"})
 	if err != nil {
 		assert.Nil(t, err, "an error should not be present here")
 		return
@@ -357,7 +360,10 @@ func TestTaskManagementProperlyQueueingTasks(t *testing.T) {
 	assert.Equal(t, firstTaskID, task.TaskID)
 
 	// This should return nothing as the devices for the 2nd task are "in-use"
-	task, err = db.getNextTaskForHost("my-hostname", storage.CLDevices{4, 5})
+	task, _, err = db.getNextTaskForHost(storage.GetPendingTasksRequest{
+		Hostname:     "my-hostname",
+		DevicesInUse: storage.CLDevices{4, 5},
+	})
 	assert.Equal(t, err, storage.ErrNotFound)
 	assert.Nil(t, task)
 }
@@ -394,7 +400,7 @@ func TestDeleteTask(t *testing.T) {
 		return
 	}
 
-	if err := db.DeleteTask(doc.TaskID); err != nil {
+	if _, err := db.DeleteTask(doc.TaskID, storage.DeleteTaskOptions{}); err != nil {
 		assert.Nil(t, err)
 		return
 	}
@@ -0,0 +1,96 @@
+package bdb
+
+import (
+	"sort"
+
+	"github.com/mandiant/gocrack/server/storage"
+
+	"github.com/asdine/storm"
+	"github.com/asdine/storm/q"
+)
+
+// SaveTaskTemplate implements storage.SaveTaskTemplate
+func (s *BoltBackend) SaveTaskTemplate(tmpl storage.TaskTemplate) error {
+	return convertErr(s.db.From(bucketTemplates).Save(&boltTaskTemplate{
+		DocVersion:   curTemplateVer,
+		TaskTemplate: tmpl,
+	}))
+}
+
+// GetTaskTemplate implements storage.GetTaskTemplate
+func (s *BoltBackend) GetTaskTemplate(templateID string) (*storage.TaskTemplate, error) {
+	var tmpl boltTaskTemplate
+
+	if err := s.db.From(bucketTemplates).One("TemplateID", templateID, &tmpl); err != nil {
+		return nil, convertErr(err)
+	}
+	return &tmpl.TaskTemplate, nil
+}
+
+// GetTaskTemplatesForUser implements storage.GetTaskTemplatesForUser
+func (s *BoltBackend) GetTaskTemplatesForUser(user storage.User) ([]storage.TaskTemplate, error) {
+	var templateIDs []string
+	var baseQuery storm.Query
+
+	node := s.db.From(bucketTemplates)
+	if !user.IsSuperUser {
+		if err := ignoreNotFound(node.From(bucketEntName).Select(
+			q.Eq("UserUUID", user.UserUUID),
+		).Each(new(boltEntitlement), func(record interface{}) error {
+			templateIDs = append(templateIDs, record.(*boltEntitlement).EntitledID)
+			return nil
+		})); err != nil {
+			return nil, convertErr(err)
+		}
+		baseQuery = node.Select(q.Or(
+			q.Eq("IsShared", true),
+			q.In("TemplateID", templateIDs),
+		))
+	} else {
+		baseQuery = node.Select()
+	}
+
+	out := make([]storage.TaskTemplate, 0)
+	if err := ignoreNotFound(baseQuery.Each(new(boltTaskTemplate), func(record interface{}) error {
+		out = append(out, record.(*boltTaskTemplate).TaskTemplate)
+		return nil
+	})); err != nil {
+		return nil, convertErr(err)
+	}
+
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+// DeleteTaskTemplate implements storage.DeleteTaskTemplate
+func (s *BoltBackend) DeleteTaskTemplate(templateID string) error {
+	var tmpl boltTaskTemplate
+
+	txn, err := s.db.Begin(true)
+	if err != nil {
+		return convertErr(err)
+	}
+	defer txn.Rollback()
+
+	node := txn.From(bucketTemplates)
+	if err = node.One("TemplateID", templateID, &tmpl); err != nil {
+		return convertErr(err)
+	}
+
+	if err = node.DeleteStruct(&tmpl); err != nil {
+		return convertErr(err)
+	}
+
+	var ents []boltEntitlement
+	entNode := txn.From(bucketEntTemplates...)
+	if err = ignoreNotFound(entNode.Find("EntitledID", templateID, &ents)); err != nil {
+		return convertErr(err)
+	}
+
+	for i := range ents {
+		if err = entNode.DeleteStruct(&ents[i]); err != nil {
+			return convertErr(err)
+		}
+	}
+	return convertErr(txn.Commit())
+}
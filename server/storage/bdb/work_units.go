@@ -0,0 +1,155 @@
+package bdb
+
+import (
+	"time"
+
+	"github.com/mandiant/gocrack/server/storage"
+
+	"github.com/asdine/storm/q"
+)
+
+var activeWorkUnitStatuses = []storage.TaskStatus{
+	storage.TaskStatusDequeued,
+	storage.TaskStatusRunning,
+	storage.TaskStatusStopping,
+}
+
+// GetWorkUnits returns every work unit of a distributed task ordered by their position in the keyspace
+func (s *BoltBackend) GetWorkUnits(taskID string) ([]storage.WorkUnit, error) {
+	var tmp []boltWorkUnit
+
+	if err := s.db.From(bucketWorkUnits...).Select(q.Eq("TaskID", taskID)).OrderBy("Index").Find(&tmp); err != nil {
+		if err := convertErr(err); err != storage.ErrNotFound {
+			return nil, err
+		}
+	}
+
+	out := make([]storage.WorkUnit, len(tmp))
+	for i, doc := range tmp {
+		out[i] = doc.WorkUnit
+	}
+	return out, nil
+}
+
+// GetWorkUnit returns a work unit by its ID
+func (s *BoltBackend) GetWorkUnit(unitID string) (*storage.WorkUnit, error) {
+	var tmp boltWorkUnit
+
+	if err := s.db.From(bucketWorkUnits...).One("UnitID", unitID, &tmp); err != nil {
+		return nil, convertErr(err)
+	}
+	return &tmp.WorkUnit, nil
+}
+
+// UpdateWorkUnit replaces the stored work unit with unit
+func (s *BoltBackend) UpdateWorkUnit(unit storage.WorkUnit) error {
+	var tmp boltWorkUnit
+
+	txn, err := s.db.From(bucketWorkUnits...).Begin(true)
+	if err != nil {
+		return convertErr(err)
+	}
+	defer txn.Rollback()
+
+	if err = txn.One("UnitID", unit.UnitID, &tmp); err != nil {
+		return convertErr(err)
+	}
+
+	if unit.LastUpdatedAt.IsZero() {
+		unit.LastUpdatedAt = time.Now().UTC()
+	}
+
+	// Update ignores zero values so the whole document is saved as fields like the error may be cleared
+	if err = txn.Save(&boltWorkUnit{WorkUnit: unit, DocVersion: curWorkUnitVer}); err != nil {
+		return convertErr(err)
+	}
+	return txn.Commit()
+}
+
+// GetActiveWorkUnits returns every work unit that has been dispatched to a worker and has not stopped
+func (s *BoltBackend) GetActiveWorkUnits() ([]storage.WorkUnit, error) {
+	var tmp []boltWorkUnit
+
+	if err := s.db.From(bucketWorkUnits...).Select(q.In("Status", activeWorkUnitStatuses)).Find(&tmp); err != nil {
+		if err := convertErr(err); err != storage.ErrNotFound {
+			return nil, err
+		}
+	}
+
+	out := make([]storage.WorkUnit, len(tmp))
+	for i, doc := range tmp {
+		out[i] = doc.WorkUnit
+	}
+	return out, nil
+}
+
+// RequeueWorkUnits queues every unit of a task that has not finished or exhausted its keyspace
+func (s *BoltBackend) RequeueWorkUnits(taskID string) error {
+	var tmp []boltWorkUnit
+
+	txn, err := s.db.From(bucketWorkUnits...).Begin(true)
+	if err != nil {
+		return convertErr(err)
+	}
+	defer txn.Rollback()
+
+	if err = txn.Select(
+		q.Eq("TaskID", taskID),
+		q.Not(q.In("Status", []storage.TaskStatus{storage.TaskStatusExhausted, storage.TaskStatusFinished})),
+	).Find(&tmp); err != nil {
+		if err = convertErr(err); err == storage.ErrNotFound {
+			return nil
+		}
+		return err
+	}
+
+	now := time.Now().UTC()
+	for _, doc := range tmp {
+		doc.Status = storage.TaskStatusQueued
+		doc.AssignedToHost = ""
+		doc.Attempts = 0
+		doc.Progress = 0
+		doc.Error = nil
+		doc.LastUpdatedAt = now
+
+		if err = txn.Save(&doc); err != nil {
+			return convertErr(err)
+		}
+	}
+	return txn.Commit()
+}
+
+// claimWorkUnit assigns the next queued work unit of a task to the host
+func (s *BoltBackend) claimWorkUnit(taskID, hostname string) (*storage.WorkUnit, error) {
+	var tmp boltWorkUnit
+
+	txn, err := s.db.From(bucketWorkUnits...).Begin(true)
+	if err != nil {
+		return nil, convertErr(err)
+	}
+	defer txn.Rollback()
+
+	if err = txn.Select(
+		q.Eq("TaskID", taskID),
+		q.Eq("Status", storage.TaskStatusQueued),
+	).OrderBy("Index").First(&tmp); err != nil {
+		return nil, convertErr(err)
+	}
+
+	now := time.Now().UTC()
+	tmp.Status = storage.TaskStatusDequeued
+	tmp.AssignedToHost = hostname
+	tmp.Attempts++
+	tmp.Progress = 0
+	tmp.DispatchedAt = now
+	tmp.LastUpdatedAt = now
+
+	if err = txn.Save(&tmp); err != nil {
+		return nil, convertErr(err)
+	}
+
+	if err = txn.Commit(); err != nil {
+		return nil, convertErr(err)
+	}
+	return &tmp.WorkUnit, nil
+}
@@ -1,6 +1,11 @@
 package storage
 
-import "errors"
+import (
+	"errors"
+	"time"
+
+	"github.com/mandiant/gocrack/shared"
+)
 
 var (
 	noBackend          = errors.New("database.backend must not be empty")
@@ -8,8 +13,19 @@ var (
 )
 
 type Config struct {
-	Backend          string `yaml:"backend"`
-	ConnectionString string `yaml:"connection_string"`
+	Backend           string                  `yaml:"backend"`
+	ConnectionString  string                  `yaml:"connection_string"`
+	GarbageCollection GarbageCollectionConfig `yaml:"garbage_collection"`
+	// SkipMigrations opens the database without upgrading its schema. It's set by the migrate command and is not configurable
+	SkipMigrations bool `yaml:"-"`
+}
+
+// GarbageCollectionConfig describes how the server looks for documents left behind by deleted tasks and files
+type GarbageCollectionConfig struct {
+	// Interval is how often the server looks for orphaned documents
+	Interval shared.HumanDuration `yaml:"interval"`
+	// RemoveOrphans deletes the orphaned documents that are found rather than only reporting them
+	RemoveOrphans bool `yaml:"remove_orphans"`
 }
 
 func (s *Config) Validate() error {
@@ -20,5 +36,9 @@ func (s *Config) Validate() error {
 	if s.ConnectionString == "" {
 		return noConnectionString
 	}
+
+	if s.GarbageCollection.Interval.Duration <= 0 {
+		s.GarbageCollection.Interval = shared.HumanDuration{Duration: 24 * time.Hour}
+	}
 	return nil
 }
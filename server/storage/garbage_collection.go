@@ -0,0 +1,25 @@
+package storage
+
+// OrphanReport describes the documents that were found belonging to a task or file that no longer exists
+type OrphanReport struct {
+	// DryRun is true if the orphans were only counted and left in the database
+	DryRun bool
+	// Orphans is the number of orphaned documents keyed by their type
+	Orphans map[RecordType]int
+}
+
+// Total returns the number of orphaned documents across every type
+func (s OrphanReport) Total() (total int) {
+	for _, n := range s.Orphans {
+		total += n
+	}
+	return
+}
+
+// GarbageCollector is implemented by backends that can find documents left behind by tasks and files that have been deleted.
+// Databases created before DeleteTask removed everything belonging to a task may contain them
+type GarbageCollector interface {
+	// CollectGarbage finds every orphaned document and removes them within a single transaction.
+	// If dryRun is true, the database is left untouched and the report describes what would be removed
+	CollectGarbage(dryRun bool) (*OrphanReport, error)
+}
@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"strings"
+	"time"
+)
+
+// KnownHash is an entry in the server wide index of cracked hashes. Entries are keyed by the hash type & hash so a hash
+// that shows up in several task files only has to be cracked once
+type KnownHash struct {
+	HashType  int // HashType is the hashcat mode of the hash
+	Hash      string
+	Value     string
+	CrackedAt time.Time
+	TaskID    string // TaskID is the task that cracked the hash
+}
+
+// HashTyper is implemented by engine payloads that crack a single type of hash. Only tasks with such a payload
+// take part in the known hash index
+type HashTyper interface {
+	// GetHashType returns the hashcat mode of the hashes being cracked
+	GetHashType() int
+}
+
+// TaskHashType returns the hash type of the task if its cracked hashes can be shared with other tasks
+func TaskHashType(task *Task) (int, bool) {
+	if task.DisableHashReuse {
+		return 0, false
+	}
+
+	ht, ok := task.EnginePayload.(HashTyper)
+	if !ok {
+		return 0, false
+	}
+	return ht.GetHashType(), true
+}
+
+// NormalizeHash returns the form a hash is indexed under. Hashcat reports hex hashes in lower case while task files
+// may contain them in either case so hex hashes are lower cased. Other hashes are case sensitive and left as is
+func NormalizeHash(hash string) string {
+	for _, r := range hash {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+			return hash
+		}
+	}
+	return strings.ToLower(hash)
+}
@@ -0,0 +1,21 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeHash(t *testing.T) {
+	for _, tc := range []struct {
+		hash     string
+		expected string
+	}{
+		{"8846F7EAEE8FB117AD06BDD830B7586C", "8846f7eaee8fb117ad06bdd830b7586c"},
+		{"8846f7eaee8fb117ad06bdd830b7586c", "8846f7eaee8fb117ad06bdd830b7586c"},
+		{"$2a$05$LhayLxezLhK1LhWvKxCyLOj0j1u.Kj0jZ0pEmm134uzrQlFvQJLF6", "$2a$05$LhayLxezLhK1LhWvKxCyLOj0j1u.Kj0jZ0pEmm134uzrQlFvQJLF6"},
+		{"", ""},
+	} {
+		assert.Equal(t, tc.expected, NormalizeHash(tc.hash))
+	}
+}
@@ -0,0 +1,32 @@
+package storage
+
+import "errors"
+
+// ErrNewerSchema is raised when the database was last written by a newer version of GoCrack than the one running
+var ErrNewerSchema = errors.New("database schema is newer than this server supports")
+
+// Migrator is implemented by backends that version their schema. Backends apply pending migrations when they are opened
+// unless Config.SkipMigrations is set
+type Migrator interface {
+	// Migrate upgrades the database to the latest schema. If dryRun is true, the database is left untouched
+	// and the report describes what would change
+	Migrate(dryRun bool) (*MigrationReport, error)
+}
+
+// MigrationReport describes the migrations that were (or would be) applied to a database
+type MigrationReport struct {
+	FromVersion int
+	ToVersion   int
+	DryRun      bool
+	// BackupPath is the copy of the database taken before any migration was applied
+	BackupPath string
+	Applied    []AppliedMigration
+}
+
+// AppliedMigration is a single migration within a MigrationReport
+type AppliedMigration struct {
+	Version     int
+	Description string
+	// Changes is the number of documents modified by the migration keyed by the bucket or table they belong to
+	Changes map[string]int
+}
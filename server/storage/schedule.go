@@ -0,0 +1,144 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+)
+
+// ExecutionWindow is a period of the week that a task is allowed to run in. The window opens at Start on each of Days
+// and closes at End, which is on the following day if it's not after Start (e.g. 20:00 to 06:00 for nights)
+type ExecutionWindow struct {
+	Days     []time.Weekday `json:"days,omitempty"` // Days the window opens on where 0 is Sunday. The window opens every day if empty
+	Start    string         `json:"start"`          // Start & End are in the 24 hour HH:MM format
+	End      string         `json:"end"`
+	Timezone string         `json:"timezone,omitempty"` // Timezone is the IANA name of the zone Start & End are in. Defaults to UTC
+}
+
+// TaskSchedule restricts when a task is handed out to workers. A task without a schedule can run at any time
+type TaskSchedule struct {
+	NotBefore *time.Time `json:"not_before,omitempty"` // NotBefore is the earliest time the task can start
+	// Windows are the periods the task can run in. A running task is stopped when they close and queued again
+	Windows []ExecutionWindow `json:"windows,omitempty"`
+}
+
+// clockMinutes parses the HH:MM time into the number of minutes since midnight
+func clockMinutes(clock string) (int, error) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, fmt.Errorf("`%s` is not a HH:MM time", clock)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func (w ExecutionWindow) location() (*time.Location, error) {
+	if w.Timezone == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(w.Timezone)
+}
+
+// Validate the window and return a list of user friendly errors
+func (w ExecutionWindow) Validate() []string {
+	errs := make([]string, 0)
+
+	if _, err := clockMinutes(w.Start); err != nil {
+		errs = append(errs, "start "+err.Error())
+	}
+
+	if _, err := clockMinutes(w.End); err != nil {
+		errs = append(errs, "end "+err.Error())
+	}
+
+	for _, day := range w.Days {
+		if day < time.Sunday || day > time.Saturday {
+			errs = append(errs, fmt.Sprintf("%d is not a day of the week", day))
+		}
+	}
+
+	if _, err := w.location(); err != nil {
+		errs = append(errs, fmt.Sprintf("`%s` is not a known timezone", w.Timezone))
+	}
+	return errs
+}
+
+func (w ExecutionWindow) opensOn(day time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+
+	for _, d := range w.Days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// Contains returns true if the window is open at the time
+func (w ExecutionWindow) Contains(t time.Time) bool {
+	start, err := clockMinutes(w.Start)
+	if err != nil {
+		return false
+	}
+
+	end, err := clockMinutes(w.End)
+	if err != nil {
+		return false
+	}
+
+	loc, err := w.location()
+	if err != nil {
+		return false
+	}
+
+	local := t.In(loc)
+	now := local.Hour()*60 + local.Minute()
+
+	if start < end {
+		return now >= start && now < end && w.opensOn(local.Weekday())
+	}
+
+	// the window runs past midnight so it may have opened the day before
+	switch {
+	case now >= start:
+		return w.opensOn(local.Weekday())
+	case now < end:
+		return w.opensOn((local.Weekday() + 6) % 7)
+	}
+	return false
+}
+
+// IsEmpty returns true if the schedule does not restrict when the task can run
+func (s TaskSchedule) IsEmpty() bool {
+	return s.NotBefore == nil && len(s.Windows) == 0
+}
+
+// IsOpen returns true if the task is allowed to run at the time
+func (s TaskSchedule) IsOpen(t time.Time) bool {
+	if s.NotBefore != nil && t.Before(*s.NotBefore) {
+		return false
+	}
+
+	if len(s.Windows) == 0 {
+		return true
+	}
+
+	for _, w := range s.Windows {
+		if w.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate the schedule and return a list of user friendly errors
+func (s TaskSchedule) Validate() []string {
+	errs := make([]string, 0)
+
+	for i, w := range s.Windows {
+		for _, err := range w.Validate() {
+			errs = append(errs, fmt.Sprintf("windows[%d]: %s", i, err))
+		}
+	}
+	return errs
+}
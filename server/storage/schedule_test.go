@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecutionWindowContains(t *testing.T) {
+	// Friday the 1st of March 2024
+	friday := func(hour, min int) time.Time {
+		return time.Date(2024, time.March, 1, hour, min, 0, 0, time.UTC)
+	}
+
+	for i, test := range []struct {
+		Window   ExecutionWindow
+		At       time.Time
+		Expected bool
+	}{
+		{Window: ExecutionWindow{Start: "09:00", End: "17:00"}, At: friday(9, 0), Expected: true},
+		{Window: ExecutionWindow{Start: "09:00", End: "17:00"}, At: friday(16, 59), Expected: true},
+		{Window: ExecutionWindow{Start: "09:00", End: "17:00"}, At: friday(17, 0), Expected: false},
+		{Window: ExecutionWindow{Start: "09:00", End: "17:00"}, At: friday(8, 59), Expected: false},
+		// nights run past midnight
+		{Window: ExecutionWindow{Start: "20:00", End: "06:00"}, At: friday(23, 0), Expected: true},
+		{Window: ExecutionWindow{Start: "20:00", End: "06:00"}, At: friday(5, 0), Expected: true},
+		{Window: ExecutionWindow{Start: "20:00", End: "06:00"}, At: friday(12, 0), Expected: false},
+		// weekends
+		{Window: ExecutionWindow{Days: []time.Weekday{time.Saturday, time.Sunday}, Start: "00:00", End: "00:00"}, At: friday(12, 0), Expected: false},
+		{Window: ExecutionWindow{Days: []time.Weekday{time.Saturday, time.Sunday}, Start: "00:00", End: "00:00"}, At: friday(12, 0).Add(24 * time.Hour), Expected: true},
+		// a window opening on friday night is still open on saturday morning but not one opening on thursday night
+		{Window: ExecutionWindow{Days: []time.Weekday{time.Friday}, Start: "20:00", End: "06:00"}, At: friday(2, 0).Add(24 * time.Hour), Expected: true},
+		{Window: ExecutionWindow{Days: []time.Weekday{time.Thursday}, Start: "20:00", End: "06:00"}, At: friday(21, 0), Expected: false},
+		// 14:00 UTC is 09:00 in New York
+		{Window: ExecutionWindow{Start: "09:00", End: "17:00", Timezone: "America/New_York"}, At: friday(14, 0), Expected: true},
+		{Window: ExecutionWindow{Start: "09:00", End: "17:00", Timezone: "America/New_York"}, At: friday(9, 0), Expected: false},
+		{Window: ExecutionWindow{Start: "9am", End: "17:00"}, At: friday(12, 0), Expected: false},
+	} {
+		assert.Equal(t, test.Expected, test.Window.Contains(test.At), "test %d", i)
+	}
+}
+
+func TestTaskScheduleIsOpen(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	later := now.Add(time.Hour)
+
+	assert.True(t, TaskSchedule{}.IsOpen(now))
+	assert.False(t, TaskSchedule{NotBefore: &later}.IsOpen(now))
+	assert.True(t, TaskSchedule{NotBefore: &later}.IsOpen(later))
+
+	schedule := TaskSchedule{Windows: []ExecutionWindow{
+		{Start: "00:00", End: "06:00"},
+		{Start: "11:00", End: "13:00"},
+	}}
+	assert.True(t, schedule.IsOpen(now))
+	assert.False(t, schedule.IsOpen(now.Add(2*time.Hour)))
+
+	schedule.NotBefore = &later
+	assert.False(t, schedule.IsOpen(now))
+}
+
+func TestTaskScheduleValidate(t *testing.T) {
+	assert.Empty(t, TaskSchedule{Windows: []ExecutionWindow{{Start: "20:00", End: "06:00", Timezone: "Europe/London"}}}.Validate())
+
+	errs := TaskSchedule{Windows: []ExecutionWindow{
+		{Start: "09:00", End: "17:00"},
+		{Days: []time.Weekday{7}, Start: "25:00", End: "17:00", Timezone: "Mars/Olympus_Mons"},
+	}}.Validate()
+	assert.Equal(t, []string{
+		"windows[1]: start `25:00` is not a HH:MM time",
+		"windows[1]: 7 is not a day of the week",
+		"windows[1]: `Mars/Olympus_Mons` is not a known timezone",
+	}, errs)
+}
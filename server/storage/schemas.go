@@ -13,6 +13,8 @@ type WorkerCrackEngine uint8
 const (
 	// WorkerHashcatEngine indicates the task should use the hashcat engine
 	WorkerHashcatEngine WorkerCrackEngine = 1 << iota
+	// WorkerCPUEngine indicates the task should use the pure Go CPU reference engine
+	WorkerCPUEngine
 )
 
 // PendingTaskPayloadType describes the payload structure & contents
@@ -35,6 +37,25 @@ const (
 	TaskFileEngineHashcat = TaskFileEngine(WorkerHashcatEngine)
 )
 
+// TaskFileFormat describes how the hashes are laid out within a task file
+type TaskFileFormat uint8
+
+const (
+	// TaskFileFormatHashes indicates the task file contains a hash per line
+	TaskFileFormatHashes TaskFileFormat = iota
+	// TaskFileFormatUserHash indicates each hash is prefixed with the username of its account (user:hash)
+	TaskFileFormatUserHash
+	// TaskFileFormatPwdump indicates the task file is a pwdump of windows accounts (user:rid:lmhash:nthash:::)
+	TaskFileFormatPwdump
+	// TaskFileFormatNetNTLM indicates the task file contains NetNTLM challenge responses which include the username
+	TaskFileFormatNetNTLM
+)
+
+// HasUsernamePrefix returns true if each line of the task file is prefixed with a username that hashcat must ignore
+func (s TaskFileFormat) HasUsernamePrefix() bool {
+	return s == TaskFileFormatUserHash || s == TaskFileFormatPwdump
+}
+
 // ActivityType describes an action taken within the system
 type ActivityType uint8
 
@@ -67,6 +88,8 @@ const (
 	EngineFileMasks
 	// EngineFileRules indicates the file is a mangling rule set and is used to modify dictionary words
 	EngineFileRules
+	// EngineFileCharset indicates the file is a hashcat custom charset (.hcchr) used by masks
+	EngineFileCharset
 )
 
 // TaskStatus indicates the processing status of a Task
@@ -81,6 +104,8 @@ const (
 	TaskStatusError     TaskStatus = "Error"
 	TaskStatusExhausted TaskStatus = "Exhausted"
 	TaskStatusFinished  TaskStatus = "Finished"
+	// TaskStatusOutOfTime indicates the task was stopped as it used up its time budget (TaskDuration)
+	TaskStatusOutOfTime TaskStatus = "OutOfTime"
 )
 
 func (s *TaskStatus) UnmarshalJSON(data []byte) error {
@@ -106,12 +131,26 @@ func (s *TaskStatus) UnmarshalJSON(data []byte) error {
 		*s = TaskStatusExhausted
 	case "finished":
 		*s = TaskStatusFinished
+	case "outoftime":
+		*s = TaskStatusOutOfTime
 	default:
 		return fmt.Errorf("`%s` is not a valid task status", tmp)
 	}
 	return nil
 }
 
+// StatusActorType indicates who changed the status of a task
+type StatusActorType string
+
+const (
+	// StatusActorServer indicates the server changed the status, e.g. after a work unit was lost by its worker
+	StatusActorServer StatusActorType = "server"
+	// StatusActorUser indicates a user changed the status through the API
+	StatusActorUser StatusActorType = "user"
+	// StatusActorWorker indicates the worker running the task changed the status
+	StatusActorWorker StatusActorType = "worker"
+)
+
 // WorkerPriority describes the priority of the task in relative to the position in queue
 type WorkerPriority int
 
@@ -128,6 +167,7 @@ const (
 	EntitlementTask EntitlementType = iota
 	EntitlementTaskFile
 	EntitlementEngineFile
+	EntitlementTaskTemplate
 )
 
 // TaskFile describes all the properties of a task file which is a file that contains one or more hashes
@@ -144,6 +184,14 @@ type TaskFile struct {
 	ForEngine         TaskFileEngine
 	NumberOfPasswords int
 	NumberOfSalts     int
+	Format            TaskFileFormat
+}
+
+// TaskFileAccount maps an account within a task file to the hash of its password. Accounts may share a hash
+type TaskFileAccount struct {
+	FileID   string
+	Username string
+	Hash     string
 }
 
 // User describes all the properties of a GoCrack user
@@ -163,13 +211,13 @@ type Task struct {
 	TaskName          string
 	Status            TaskStatus
 	Engine            WorkerCrackEngine
-	EnginePayload     interface{} // As of now, this can only be shared.HashcatUserOptions
+	EnginePayload     interface{} // Decoded by the payload decoder registered for Engine (see worker/engines)
 	Priority          WorkerPriority
 	FileID            string // FileID is a reference to TaskFile via TaskFile.FileID
 	CreatedBy         string
 	CreatedByUUID     string // CreatedBy is a reference to User via User.UserUUID
 	CreatedAt         time.Time
-	TaskDuration      int
+	TaskDuration      int // TaskDuration is the time budget of the task in seconds across all its runs. 0 means it's unlimited
 	LastUpdatedAt     time.Time
 	AssignedToHost    string
 	AssignedToDevices *CLDevices
@@ -178,6 +226,16 @@ type Task struct {
 	NumberCracked     int
 	NumberPasswords   int
 	Error             *string
+	Keyspace          uint64       // Keyspace is the number of dictionary words or masks a distributed task was split on
+	WorkUnitCount     int          // WorkUnitCount is the number of work units the task was split into. 0 indicates the task runs on a single worker
+	Progress          float64      // Progress is the percentage of a distributed task's keyspace that has been processed
+	DisableHashReuse  bool         // DisableHashReuse keeps the task out of the known hash index so its results are never shared with other tasks
+	Schedule          TaskSchedule // Schedule restricts when the task is handed out to workers
+	StoppedByWindow   bool         // StoppedByWindow is true while the task is stopped because its execution windows closed
+	Runtime           int          // Runtime is the number of seconds workers have spent processing the task in previous runs
+	RunningSince      *time.Time   // RunningSince is when the current run of the task started
+	Preempted         bool         // Preempted is true while the task is being stopped to free up its devices for a task of a higher priority
+	QueuedAt          time.Time    // QueuedAt is when the task was last queued. The scheduler ages the priority of the task from it
 }
 
 // EngineFile describes a file that is either a dictionary, list of masks, or a rule file for GoCrack
@@ -202,6 +260,8 @@ type CrackedHash struct {
 	Hash      string `storm:"unique"`
 	Value     string
 	CrackedAt time.Time
+	// ReusedFromTaskID is set when the password was credited from the known hash index instead of being cracked by the task
+	ReusedFromTaskID string
 }
 
 // EntitlementEntry is created when a user is granted access to a task, file, etc.
@@ -223,6 +283,119 @@ type ActivityLogEntry struct {
 	IPAddress  string
 }
 
+// TaskStatusChange describes a new status of a task along with who changed it
+type TaskStatusChange struct {
+	Status    TaskStatus      `json:"status"`
+	Error     *string         `json:"error,omitempty"`
+	ActorType StatusActorType `json:"actor_type"`
+	Actor     string          `json:"actor"`             // Actor is the UUID of the user, the hostname of the worker or why the server changed the status
+	Devices   CLDevices       `json:"devices,omitempty"` // Devices are the devices of the worker the task is running on
+}
+
+// TaskStatusHistoryEntry is an append-only record of a change in the status of a task
+type TaskStatusHistoryEntry struct {
+	TaskID         string     `json:"task_id"`
+	ChangedAt      time.Time  `json:"changed_at"`
+	PreviousStatus TaskStatus `json:"previous_status"`
+	TaskStatusChange
+}
+
+// WorkUnit is a slice of a distributed task's keyspace that is processed by a single worker.
+// Skip & Limit are offsets into the dictionary words or masks of the task
+type WorkUnit struct {
+	UnitID         string `storm:"id,unique"`
+	TaskID         string `storm:"index"`
+	Index          int
+	Skip           uint64
+	Limit          uint64
+	Status         TaskStatus
+	AssignedToHost string
+	Attempts       int     // Attempts is the number of times the unit has been dispatched to a worker
+	Progress       float64 // Progress is the percentage of the unit that has been processed
+	DispatchedAt   time.Time
+	LastUpdatedAt  time.Time
+	Error          *string
+}
+
+// PasswordPolicy is a named set of rules that the cracked passwords of a task can be evaluated against
+type PasswordPolicy struct {
+	PolicyID  string `storm:"id"`
+	Name      string `storm:"unique"`
+	MinLength int
+	// RequiredClasses are the character classes (lower, upper, digit & special) every password must contain
+	RequiredClasses []string
+	// MinClasses is the number of distinct character classes a password must contain
+	MinClasses int
+	// MaxRepeatedChars is the longest run of a single character a password may contain. 0 allows any
+	MaxRepeatedChars int
+	// BannedWordFiles are the IDs of dictionary engine files whose words may not be the base of a password
+	BannedWordFiles []string
+	CreatedByUUID   string
+	CreatedAt       time.Time
+	LastUpdatedAt   time.Time
+}
+
+// CrackedWordlist records the tasks a dictionary engine file was built from so it can be rebuilt as they crack more passwords
+type CrackedWordlist struct {
+	FileID string `storm:"id"` // FileID is the ID of the dictionary engine file
+	// TaskIDs are the tasks the wordlist is built from. Wordlists of a case use the tasks of CaseCode instead
+	TaskIDs  []string
+	CaseCode string
+	// AutoUpdate rebuilds the wordlist whenever one of its tasks cracks a password
+	AutoUpdate    bool
+	CreatedByUUID string
+	CreatedAt     time.Time
+	LastBuiltAt   time.Time
+}
+
+// PipelineStage is an attack of a pipeline. It's started as a task once every stage before it has finished or exhausted
+type PipelineStage struct {
+	Name          string
+	Engine        WorkerCrackEngine
+	EnginePayload json.RawMessage // EnginePayload is the payload of the stage's task as it was requested
+	TaskDuration  int
+	WorkUnits     int
+	TaskID        string // TaskID is the task the stage was started as. It's empty until the stage starts
+}
+
+// Pipeline runs an ordered list of attacks against a task file. Every stage after the first only attacks the hashes
+// of the file that the stages before it did not crack
+type Pipeline struct {
+	PipelineID      string `storm:"id"`
+	Name            string
+	FileID          string // FileID is the task file the pipeline was created for
+	CaseCode        *string
+	Priority        WorkerPriority
+	AdditionalUsers []string // AdditionalUsers are entitled to the task of every stage
+	Stages          []PipelineStage
+	CurrentStage    int
+	// Status is Running until the last stage is done or every hash is cracked. It's Error if a stage could not be started
+	Status        TaskStatus
+	Error         *string
+	CreatedByUUID string
+	CreatedAt     time.Time
+	LastUpdatedAt time.Time
+}
+
+// TaskTemplate is a saved task configuration that tasks can be created from. Templates are visible to the users
+// entitled to them or to everyone if shared
+type TaskTemplate struct {
+	TemplateID        string `storm:"id"`
+	Name              string
+	Description       *string
+	Engine            WorkerCrackEngine
+	EnginePayload     json.RawMessage // EnginePayload is the payload of the tasks created from the template as it was requested
+	Priority          WorkerPriority
+	TaskDuration      int
+	WorkUnits         int
+	AssignedToHost    string
+	AssignedToDevices *CLDevices
+	IsShared          bool
+	CreatedByUUID     string
+	CreatedAt         time.Time
+	LastUpdatedAt     time.Time
+}
+
 // CheckpointFile is a file used to restore a task's state within the engine.
 // Note: We may need to revisit this if the files grow in size but as of now, they are only a few hundred bytes.
 type CheckpointFile struct {
@@ -0,0 +1,69 @@
+package sqldb
+
+import (
+	"github.com/mandiant/gocrack/server/storage"
+)
+
+// LogActivity implements storage.LogActivity
+func (s *SQLBackend) LogActivity(entry storage.ActivityLogEntry) error {
+	if entry.Username == "" {
+		user, err := getUser(s.db, entry.UserUUID)
+		if err != nil {
+			return err
+		}
+		entry.Username = user.Username
+	}
+
+	return insertActivity(s.db, entry)
+}
+
+func insertActivity(db queryer, entry storage.ActivityLogEntry) error {
+	_, err := db.Exec(`INSERT INTO audit_log
+		(occured_at, user_uuid, username, entity_id, status_code, type, path, ip_address)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		entry.OccuredAt,
+		entry.UserUUID,
+		entry.Username,
+		entry.EntityID,
+		entry.StatusCode,
+		entry.Type,
+		entry.Path,
+		entry.IPAddress,
+	)
+	return convertErr(err)
+}
+
+// GetActivityLog implements storage.GetActivityLog
+func (s *SQLBackend) GetActivityLog(entityID string) ([]storage.ActivityLogEntry, error) {
+	rows, err := s.db.Query(`SELECT occured_at, user_uuid, username, entity_id, status_code, type, path, ip_address
+		FROM audit_log WHERE entity_id = ? ORDER BY occured_at, id`, entityID)
+	if err != nil {
+		return nil, convertErr(err)
+	}
+	defer rows.Close()
+
+	var items []storage.ActivityLogEntry
+	for rows.Next() {
+		var entry storage.ActivityLogEntry
+		if err := rows.Scan(
+			&entry.OccuredAt,
+			&entry.UserUUID,
+			&entry.Username,
+			&entry.EntityID,
+			&entry.StatusCode,
+			&entry.Type,
+			&entry.Path,
+			&entry.IPAddress,
+		); err != nil {
+			return nil, convertErr(err)
+		}
+		items = append(items, entry)
+	}
+	return items, convertErr(rows.Err())
+}
+
+// RemoveActivityEntries implements storage.RemoveActivityEntries
+func (s *SQLBackend) RemoveActivityEntries(entityID string) error {
+	_, err := s.db.Exec("DELETE FROM audit_log WHERE entity_id = ?", entityID)
+	return convertErr(err)
+}
@@ -0,0 +1,46 @@
+package sqldb
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/mandiant/gocrack/server/storage"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestActivityLog(t *testing.T) {
+	db := initTest(t)
+	defer db.DestroyTest()
+
+	user, err := createTestUser(false, t, db)
+	if err != nil {
+		assert.FailNow(t, "unexpected error in createTestUser", err.Error())
+	}
+
+	for i := 0; i < 3; i++ {
+		err = db.LogActivity(storage.ActivityLogEntry{
+			OccuredAt:  time.Now().UTC().Add(-time.Duration(i) * time.Minute),
+			UserUUID:   user.UserUUID,
+			EntityID:   "some-entity-id",
+			StatusCode: http.StatusOK,
+			Type:       storage.ActivityViewTask,
+			Path:       "/testing/path/1234/",
+			IPAddress:  "13.37.13.37",
+		})
+		assert.Nil(t, err)
+	}
+
+	entries, err := db.GetActivityLog("some-entity-id")
+	assert.Nil(t, err)
+	if assert.Len(t, entries, 3) {
+		assert.Equal(t, "testuser", entries[0].Username)
+		assert.True(t, entries[0].OccuredAt.Before(entries[2].OccuredAt))
+	}
+
+	assert.Nil(t, db.RemoveActivityEntries("some-entity-id"))
+	entries, err = db.GetActivityLog("some-entity-id")
+	assert.Nil(t, err)
+	assert.Empty(t, entries)
+}
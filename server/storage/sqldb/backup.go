@@ -0,0 +1,318 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/mandiant/gocrack/server/storage"
+)
+
+// Snapshot implements storage.Snapshotter by vacuuming the database into a temporary file and copying it into w
+func (s *SQLBackend) Snapshot(w io.Writer) (int64, error) {
+	dir, err := ioutil.TempDir("", "gocrack_snapshot")
+	if err != nil {
+		return 0, err
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "snapshot.db")
+	if _, err = s.db.Exec("VACUUM INTO ?", path); err != nil {
+		return 0, convertErr(err)
+	}
+
+	fd, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer fd.Close()
+	return io.Copy(w, fd)
+}
+
+// exportQuery calls scan with every row returned by the query
+func exportQuery(ctx context.Context, conn *sql.Conn, query string, scan func(rows *sql.Rows) error) error {
+	rows, err := conn.QueryContext(ctx, query)
+	if err != nil {
+		return convertErr(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+	return convertErr(rows.Err())
+}
+
+// Export implements storage.Exporter. The records are read within a deferred transaction which, unlike the
+// transactions started by Begin, does not block writers while the export is running
+func (s *SQLBackend) Export(fn func(storage.Record) error) error {
+	ctx := context.Background()
+
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return convertErr(err)
+	}
+	defer conn.Close()
+
+	if _, err = conn.ExecContext(ctx, "BEGIN DEFERRED"); err != nil {
+		return convertErr(err)
+	}
+	defer conn.ExecContext(ctx, "ROLLBACK")
+
+	emit := func(rt storage.RecordType, value interface{}) error {
+		return fn(storage.Record{Type: rt, Value: value})
+	}
+
+	if err = exportQuery(ctx, conn, "SELECT "+userColumns+" FROM users ORDER BY id", func(rows *sql.Rows) error {
+		user, err := scanUser(rows)
+		if err != nil {
+			return err
+		}
+		return emit(storage.RecordUser, *user)
+	}); err != nil {
+		return err
+	}
+
+	if err = exportQuery(ctx, conn, "SELECT "+taskFileColumns+" FROM task_files ORDER BY uploaded_at", func(rows *sql.Rows) error {
+		tf, err := scanTaskFile(rows)
+		if err != nil {
+			return err
+		}
+		return emit(storage.RecordTaskFile, *tf)
+	}); err != nil {
+		return err
+	}
+
+	if err = exportQuery(ctx, conn, "SELECT "+engineFileColumns+" FROM engine_files ORDER BY uploaded_at", func(rows *sql.Rows) error {
+		ef, err := scanEngineFile(rows)
+		if err != nil {
+			return err
+		}
+		return emit(storage.RecordEngineFile, *ef)
+	}); err != nil {
+		return err
+	}
+
+	if err = exportQuery(ctx, conn, "SELECT "+taskColumns+" FROM tasks t ORDER BY t.created_at", func(rows *sql.Rows) error {
+		task, err := scanTask(rows)
+		if err != nil {
+			return err
+		}
+		return emit(storage.RecordTask, *task)
+	}); err != nil {
+		return err
+	}
+
+	if err = exportQuery(ctx, conn, "SELECT "+workUnitColumns+" FROM work_units ORDER BY task_id, idx", func(rows *sql.Rows) error {
+		unit, err := scanWorkUnit(rows)
+		if err != nil {
+			return err
+		}
+		return emit(storage.RecordWorkUnit, *unit)
+	}); err != nil {
+		return err
+	}
+
+	if err = exportQuery(ctx, conn, "SELECT task_id, "+crackedHashColumns+" FROM cracked_hashes ORDER BY id", func(rows *sql.Rows) error {
+		var ch storage.ExportedCrackedHash
+		if err := rows.Scan(&ch.TaskID, &ch.Hash, &ch.Value, &ch.CrackedAt, &ch.ReusedFromTaskID); err != nil {
+			return convertErr(err)
+		}
+		return emit(storage.RecordCrackedHash, ch)
+	}); err != nil {
+		return err
+	}
+
+	if err = exportQuery(ctx, conn, "SELECT task_id, data FROM task_checkpoints ORDER BY task_id", func(rows *sql.Rows) error {
+		var cf storage.CheckpointFile
+		if err := rows.Scan(&cf.TaskID, &cf.Data); err != nil {
+			return convertErr(err)
+		}
+		return emit(storage.RecordCheckpoint, cf)
+	}); err != nil {
+		return err
+	}
+
+	if err = exportQuery(ctx, conn, "SELECT "+templateColumns+" FROM task_templates ORDER BY created_at", func(rows *sql.Rows) error {
+		tmpl, err := scanTaskTemplate(rows)
+		if err != nil {
+			return err
+		}
+		return emit(storage.RecordTaskTemplate, *tmpl)
+	}); err != nil {
+		return err
+	}
+
+	for _, entType := range []storage.EntitlementType{
+		storage.EntitlementTask,
+		storage.EntitlementTaskFile,
+		storage.EntitlementEngineFile,
+		storage.EntitlementTaskTemplate,
+	} {
+		table, _ := entitlementTable(entType)
+		if err = exportQuery(ctx, conn, "SELECT user_uuid, entitled_id, granted_access_at FROM "+table+" ORDER BY granted_access_at", func(rows *sql.Rows) error {
+			ent := storage.ExportedEntitlement{Type: entType}
+			if err := rows.Scan(&ent.UserUUID, &ent.EntitledID, &ent.GrantedAccessAt); err != nil {
+				return convertErr(err)
+			}
+			return emit(storage.RecordEntitlement, ent)
+		}); err != nil {
+			return err
+		}
+	}
+
+	if err = exportQuery(ctx, conn, `SELECT occured_at, user_uuid, username, entity_id, status_code, type, path, ip_address
+		FROM audit_log ORDER BY id`, func(rows *sql.Rows) error {
+		var entry storage.ActivityLogEntry
+		if err := rows.Scan(
+			&entry.OccuredAt,
+			&entry.UserUUID,
+			&entry.Username,
+			&entry.EntityID,
+			&entry.StatusCode,
+			&entry.Type,
+			&entry.Path,
+			&entry.IPAddress,
+		); err != nil {
+			return convertErr(err)
+		}
+		return emit(storage.RecordAuditLog, entry)
+	}); err != nil {
+		return err
+	}
+
+	if err = exportQuery(ctx, conn, "SELECT "+taskStatusColumns+" FROM task_status_history ORDER BY id", func(rows *sql.Rows) error {
+		entry, err := scanTaskStatus(rows)
+		if err != nil {
+			return err
+		}
+		return emit(storage.RecordTaskStatus, entry)
+	}); err != nil {
+		return err
+	}
+
+	if err = exportQuery(ctx, conn, "SELECT "+knownHashColumns+" FROM known_hashes ORDER BY hash_type, hash", func(rows *sql.Rows) error {
+		kh, err := scanKnownHash(rows)
+		if err != nil {
+			return err
+		}
+		return emit(storage.RecordKnownHash, kh)
+	}); err != nil {
+		return err
+	}
+
+	if err = exportQuery(ctx, conn, "SELECT file_id, username, hash FROM task_file_accounts ORDER BY id", func(rows *sql.Rows) error {
+		var account storage.TaskFileAccount
+		if err := rows.Scan(&account.FileID, &account.Username, &account.Hash); err != nil {
+			return convertErr(err)
+		}
+		return emit(storage.RecordTaskFileAccount, account)
+	}); err != nil {
+		return err
+	}
+
+	if err = exportQuery(ctx, conn, "SELECT "+policyColumns+" FROM password_policies ORDER BY name", func(rows *sql.Rows) error {
+		policy, err := scanPasswordPolicy(rows)
+		if err != nil {
+			return err
+		}
+		return emit(storage.RecordPasswordPolicy, *policy)
+	}); err != nil {
+		return err
+	}
+
+	if err = exportQuery(ctx, conn, "SELECT "+wordlistColumns+" FROM cracked_wordlists ORDER BY created_at", func(rows *sql.Rows) error {
+		wl, err := scanCrackedWordlist(rows)
+		if err != nil {
+			return err
+		}
+		return emit(storage.RecordCrackedWordlist, *wl)
+	}); err != nil {
+		return err
+	}
+
+	return exportQuery(ctx, conn, "SELECT "+pipelineColumns+" FROM pipelines ORDER BY created_at", func(rows *sql.Rows) error {
+		p, err := scanPipeline(rows)
+		if err != nil {
+			return err
+		}
+		return emit(storage.RecordPipeline, *p)
+	})
+}
+
+// ImportTransaction restores the records of an export within a single database transaction
+type ImportTransaction struct {
+	txn *sql.Tx
+}
+
+// NewImportTransaction implements storage.Importer
+func (s *SQLBackend) NewImportTransaction() (storage.ImportTxn, error) {
+	txn, err := s.db.Begin()
+	if err != nil {
+		return nil, convertErr(err)
+	}
+	return &ImportTransaction{txn}, nil
+}
+
+// Import saves the record as is within the transaction
+func (s *ImportTransaction) Import(rec storage.Record) error {
+	switch v := rec.Value.(type) {
+	case storage.User:
+		return insertUser(s.txn, &v)
+	case storage.TaskFile:
+		return insertTaskFile(s.txn, v)
+	case storage.EngineFile:
+		return insertEngineFile(s.txn, v)
+	case storage.Task:
+		// backups taken before tasks recorded when they were queued age from when the task was created
+		if v.QueuedAt.IsZero() {
+			v.QueuedAt = v.CreatedAt
+		}
+		return insertTask(s.txn, &v)
+	case storage.WorkUnit:
+		return insertWorkUnit(s.txn, v)
+	case storage.ExportedCrackedHash:
+		return insertCrackedHash(s.txn, v.TaskID, v.CrackedHash)
+	case storage.CheckpointFile:
+		return saveCheckpoint(s.txn, v)
+	case storage.TaskTemplate:
+		return saveTaskTemplate(s.txn, v)
+	case storage.ExportedEntitlement:
+		table, err := entitlementTable(v.Type)
+		if err != nil {
+			return err
+		}
+		return saveEntitlement(s.txn, table, v.EntitlementEntry)
+	case storage.ActivityLogEntry:
+		return insertActivity(s.txn, v)
+	case storage.TaskStatusHistoryEntry:
+		return insertTaskStatus(s.txn, v)
+	case storage.KnownHash:
+		return insertKnownHash(s.txn, v)
+	case storage.TaskFileAccount:
+		return insertTaskFileAccount(s.txn, v)
+	case storage.PasswordPolicy:
+		return savePasswordPolicy(s.txn, v)
+	case storage.CrackedWordlist:
+		return saveCrackedWordlist(s.txn, v)
+	case storage.Pipeline:
+		return savePipeline(s.txn, v)
+	}
+	return fmt.Errorf("unknown %s record of type %T", rec.Type, rec.Value)
+}
+
+// Rollback any writes to the database
+func (s *ImportTransaction) Rollback() error {
+	return s.txn.Rollback()
+}
+
+// Commit the transaction
+func (s *ImportTransaction) Commit() error {
+	return convertErr(s.txn.Commit())
+}
@@ -0,0 +1,38 @@
+package sqldb
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mandiant/gocrack/server/storage"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshot(t *testing.T) {
+	db := initTest(t)
+	defer db.DestroyTest()
+
+	assert.Nil(t, db.CreateUser(&storage.User{Username: "snapshot_user"}))
+
+	fd, err := os.Create(filepath.Join(db.dirpath, "snapshot.sqlite"))
+	if err != nil {
+		assert.FailNow(t, "failed to create snapshot file", err.Error())
+	}
+
+	n, err := db.Snapshot(fd)
+	assert.Nil(t, err)
+	assert.NotZero(t, n)
+	assert.Nil(t, fd.Close())
+
+	snap, err := Init(storage.Config{ConnectionString: fd.Name()})
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer snap.Close()
+
+	users, err := snap.GetUsers()
+	assert.Nil(t, err)
+	assert.Len(t, users, 1)
+}
@@ -0,0 +1,42 @@
+package sqldb
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mandiant/gocrack/server/storage"
+	"github.com/mandiant/gocrack/server/storage/storagetest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) (storage.Backend, func()) {
+		db := initTest(t)
+		return db, db.DestroyTest
+	})
+}
+
+func TestGarbageCollection(t *testing.T) {
+	storagetest.RunGarbageCollection(t, func(t *testing.T) (storage.Backend, func()) {
+		path, err := ioutil.TempDir("", "storage_tests")
+		if err != nil {
+			assert.FailNow(t, "Failed to create temp directory for database", err.Error())
+		}
+
+		// orphans can only exist in databases that were written without foreign keys
+		db, err := Init(storage.Config{
+			ConnectionString: filepath.Join(path, "test.db") + "?_foreign_keys=off",
+		})
+		if err != nil {
+			assert.FailNow(t, "Failed to initialize database", err.Error())
+		}
+
+		return db, func() {
+			db.Close()
+			os.RemoveAll(path)
+		}
+	})
+}
@@ -0,0 +1,85 @@
+package sqldb
+
+import (
+	"encoding/json"
+
+	"github.com/mandiant/gocrack/server/storage"
+)
+
+const wordlistColumns = `file_id, task_ids, case_code, auto_update, created_by_uuid, created_at, last_built_at`
+
+func scanCrackedWordlist(row rowScanner) (*storage.CrackedWordlist, error) {
+	var wl storage.CrackedWordlist
+	var taskIDs string
+
+	if err := row.Scan(
+		&wl.FileID,
+		&taskIDs,
+		&wl.CaseCode,
+		&wl.AutoUpdate,
+		&wl.CreatedByUUID,
+		&wl.CreatedAt,
+		&wl.LastBuiltAt,
+	); err != nil {
+		return nil, convertErr(err)
+	}
+
+	if err := json.Unmarshal([]byte(taskIDs), &wl.TaskIDs); err != nil {
+		return nil, err
+	}
+	return &wl, nil
+}
+
+// saveCrackedWordlist inserts the wordlist or replaces the one of the same engine file
+func saveCrackedWordlist(db queryer, wl storage.CrackedWordlist) error {
+	taskIDs, err := json.Marshal(emptyIfNil(wl.TaskIDs))
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(
+		`INSERT INTO cracked_wordlists (`+wordlistColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (file_id) DO UPDATE SET
+			task_ids = excluded.task_ids,
+			case_code = excluded.case_code,
+			auto_update = excluded.auto_update,
+			last_built_at = excluded.last_built_at`,
+		wl.FileID,
+		string(taskIDs),
+		wl.CaseCode,
+		wl.AutoUpdate,
+		wl.CreatedByUUID,
+		wl.CreatedAt,
+		wl.LastBuiltAt,
+	)
+	return convertErr(err)
+}
+
+// SaveCrackedWordlist implements storage.SaveCrackedWordlist
+func (s *SQLBackend) SaveCrackedWordlist(wl storage.CrackedWordlist) error {
+	return saveCrackedWordlist(s.db, wl)
+}
+
+// GetCrackedWordlist implements storage.GetCrackedWordlist
+func (s *SQLBackend) GetCrackedWordlist(fileID string) (*storage.CrackedWordlist, error) {
+	return scanCrackedWordlist(s.db.QueryRow("SELECT "+wordlistColumns+" FROM cracked_wordlists WHERE file_id = ?", fileID))
+}
+
+// GetAutoUpdatedWordlists implements storage.GetAutoUpdatedWordlists
+func (s *SQLBackend) GetAutoUpdatedWordlists() ([]storage.CrackedWordlist, error) {
+	rows, err := s.db.Query("SELECT " + wordlistColumns + " FROM cracked_wordlists WHERE auto_update = 1 ORDER BY created_at")
+	if err != nil {
+		return nil, convertErr(err)
+	}
+	defer rows.Close()
+
+	out := make([]storage.CrackedWordlist, 0)
+	for rows.Next() {
+		wl, err := scanCrackedWordlist(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *wl)
+	}
+	return out, convertErr(rows.Err())
+}
@@ -0,0 +1,166 @@
+package sqldb
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/mandiant/gocrack/server/storage"
+)
+
+const engineFileColumns = `file_id, file_name, file_size, description, uploaded_by, uploaded_by_uuid, uploaded_at, last_updated_at,
+	file_type, number_of_entries, is_shared, sha1_hash, saved_at`
+
+func scanEngineFile(row rowScanner) (*storage.EngineFile, error) {
+	var ef storage.EngineFile
+
+	if err := row.Scan(
+		&ef.FileID,
+		&ef.FileName,
+		&ef.FileSize,
+		&ef.Description,
+		&ef.UploadedBy,
+		&ef.UploadedByUUID,
+		&ef.UploadedAt,
+		&ef.LastUpdatedAt,
+		&ef.FileType,
+		&ef.NumberOfEntries,
+		&ef.IsShared,
+		&ef.SHA1Hash,
+		&ef.SavedAt,
+	); err != nil {
+		return nil, convertErr(err)
+	}
+	return &ef, nil
+}
+
+// EngineFileTransaction is used in the creation of an engine file and all the APIs it defines are executed under a database transaction
+type EngineFileTransaction struct {
+	txn *sql.Tx
+}
+
+// NewEngineFileTransaction creates a new transaction used by the engine file PUT APIs and the file importer
+func (s *SQLBackend) NewEngineFileTransaction() (storage.EngineFileTxn, error) {
+	txn, err := s.db.Begin()
+	if err != nil {
+		return nil, convertErr(err)
+	}
+	return &EngineFileTransaction{txn}, nil
+}
+
+// SaveEngineFile saves metadata regarding an engine file to the database
+func (s *EngineFileTransaction) SaveEngineFile(sf storage.EngineFile) error {
+	if sf.UploadedAt.IsZero() {
+		sf.UploadedAt = time.Now().UTC()
+	}
+
+	if sf.LastUpdatedAt.IsZero() {
+		sf.LastUpdatedAt = time.Now().UTC()
+	}
+
+	if sf.UploadedBy == "" {
+		user, err := getUser(s.txn, sf.UploadedByUUID)
+		if err != nil {
+			return err
+		}
+		sf.UploadedBy = user.Username
+	}
+
+	return insertEngineFile(s.txn, sf)
+}
+
+func insertEngineFile(db queryer, sf storage.EngineFile) error {
+	_, err := db.Exec(
+		"INSERT INTO engine_files ("+engineFileColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		sf.FileID,
+		sf.FileName,
+		sf.FileSize,
+		sf.Description,
+		sf.UploadedBy,
+		sf.UploadedByUUID,
+		sf.UploadedAt,
+		sf.LastUpdatedAt,
+		sf.FileType,
+		sf.NumberOfEntries,
+		sf.IsShared,
+		sf.SHA1Hash,
+		sf.SavedAt,
+	)
+	return convertErr(err)
+}
+
+// AddEntitlement creates a record giving the user access to the engine file
+func (s *EngineFileTransaction) AddEntitlement(sf storage.EngineFile, userid string) error {
+	return grantEntitlement(s.txn, userid, sf)
+}
+
+// Rollback any writes to the database
+func (s *EngineFileTransaction) Rollback() error {
+	return s.txn.Rollback()
+}
+
+// Commit the transaction
+func (s *EngineFileTransaction) Commit() error {
+	return convertErr(s.txn.Commit())
+}
+
+// GetEngineFileByID returns the engine file given it's unique ID
+func (s *SQLBackend) GetEngineFileByID(storageID string) (*storage.EngineFile, error) {
+	return scanEngineFile(s.db.QueryRow("SELECT "+engineFileColumns+" FROM engine_files WHERE file_id = ?", storageID))
+}
+
+// GetEngineFilesForUser returns a list of engine files available to the user
+func (s *SQLBackend) GetEngineFilesForUser(user storage.User) ([]storage.EngineFile, error) {
+	var rows *sql.Rows
+	var err error
+
+	if user.IsSuperUser {
+		rows, err = s.db.Query("SELECT " + engineFileColumns + " FROM engine_files ORDER BY uploaded_at")
+	} else {
+		rows, err = s.db.Query(`SELECT `+engineFileColumns+` FROM engine_files
+			WHERE is_shared = 1 OR file_id IN (SELECT entitled_id FROM engine_file_entitlements WHERE user_uuid = ?)
+			ORDER BY uploaded_at`, user.UserUUID)
+	}
+	if err != nil {
+		return nil, convertErr(err)
+	}
+	defer rows.Close()
+
+	var sfs []storage.EngineFile
+	for rows.Next() {
+		sf, err := scanEngineFile(rows)
+		if err != nil {
+			return nil, err
+		}
+		sfs = append(sfs, *sf)
+	}
+	return sfs, convertErr(rows.Err())
+}
+
+// UpdateEngineFile implements storage.UpdateEngineFile
+func (s *SQLBackend) UpdateEngineFile(sf storage.EngineFile) error {
+	res, err := s.db.Exec(`UPDATE engine_files SET file_name = ?, file_size = ?, description = ?, last_updated_at = ?,
+		number_of_entries = ?, is_shared = ?, sha1_hash = ?, saved_at = ? WHERE file_id = ?`,
+		sf.FileName,
+		sf.FileSize,
+		sf.Description,
+		sf.LastUpdatedAt,
+		sf.NumberOfEntries,
+		sf.IsShared,
+		sf.SHA1Hash,
+		sf.SavedAt,
+		sf.FileID,
+	)
+	if err != nil {
+		return convertErr(err)
+	}
+	return checkAffected(res)
+}
+
+// DeleteEngineFile file implements storage.DeleteEngineFile
+func (s *SQLBackend) DeleteEngineFile(fileID string) error {
+	res, err := s.db.Exec("DELETE FROM engine_files WHERE file_id = ?", fileID)
+	if err != nil {
+		return convertErr(err)
+	}
+	return checkAffected(res)
+}
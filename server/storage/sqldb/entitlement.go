@@ -0,0 +1,137 @@
+package sqldb
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mandiant/gocrack/server/storage"
+)
+
+// entitlementTable returns the table holding entitlements of the type
+func entitlementTable(entType storage.EntitlementType) (string, error) {
+	switch entType {
+	case storage.EntitlementTask:
+		return "task_entitlements", nil
+	case storage.EntitlementTaskFile:
+		return "task_file_entitlements", nil
+	case storage.EntitlementEngineFile:
+		return "engine_file_entitlements", nil
+	case storage.EntitlementTaskTemplate:
+		return "task_template_entitlements", nil
+	}
+	return "", fmt.Errorf("unknown entType of %d", entType)
+}
+
+// entitlementTarget returns the table & entitled ID of a document that can be entitled to a user
+func entitlementTarget(document interface{}) (table, entitledID string, err error) {
+	switch rec := document.(type) {
+	case storage.TaskFile:
+		return "task_file_entitlements", rec.FileID, nil
+	case storage.Task:
+		return "task_entitlements", rec.TaskID, nil
+	case storage.EngineFile:
+		return "engine_file_entitlements", rec.FileID, nil
+	case storage.TaskTemplate:
+		return "task_template_entitlements", rec.TemplateID, nil
+	}
+	return "", "", fmt.Errorf("unknown object type passed into entitledTo")
+}
+
+// grantEntitlement inserts the entitlement record into the database. Granting an existing entitlement is a no-op
+func grantEntitlement(db queryer, userUUID string, entitledTo interface{}) error {
+	table, entitledID, err := entitlementTarget(entitledTo)
+	if err != nil {
+		return err
+	}
+	return saveEntitlement(db, table, storage.EntitlementEntry{
+		UserUUID:        userUUID,
+		EntitledID:      entitledID,
+		GrantedAccessAt: time.Now().UTC(),
+	})
+}
+
+// saveEntitlement inserts the entitlement into the table unless the user is already entitled to the document
+func saveEntitlement(db queryer, table string, entry storage.EntitlementEntry) error {
+	_, err := db.Exec(
+		"INSERT INTO "+table+" (user_uuid, entitled_id, granted_access_at) VALUES (?, ?, ?) ON CONFLICT DO NOTHING",
+		entry.UserUUID,
+		entry.EntitledID,
+		entry.GrantedAccessAt,
+	)
+	return convertErr(err)
+}
+
+// CheckEntitlement implements storage.CheckEntitlement
+func (s *SQLBackend) CheckEntitlement(userUUID, entityID string, entType storage.EntitlementType) (bool, error) {
+	var found int
+
+	table, err := entitlementTable(entType)
+	if err != nil {
+		return false, err
+	}
+
+	if err = s.db.QueryRow(
+		"SELECT COUNT(*) FROM "+table+" WHERE user_uuid = ? AND entitled_id = ?",
+		userUUID,
+		entityID,
+	).Scan(&found); err != nil {
+		return false, convertErr(err)
+	}
+	return found > 0, nil
+}
+
+// GrantEntitlement implements storage.GrantEntitlement
+func (s *SQLBackend) GrantEntitlement(user storage.User, entitledTo interface{}) error {
+	if user.UserUUID == "" {
+		return fmt.Errorf("user record must have a UserUUID to check entitlement. is %s", user.UserUUID)
+	}
+	return grantEntitlement(s.db, user.UserUUID, entitledTo)
+}
+
+// RevokeEntitlement removes the users access to the document
+func (s *SQLBackend) RevokeEntitlement(user storage.User, document interface{}) error {
+	if user.UserUUID == "" {
+		return fmt.Errorf("user record must have a UserUUID to check entitlement. is %s", user.UserUUID)
+	}
+
+	table, entitledID, err := entitlementTarget(document)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec("DELETE FROM "+table+" WHERE user_uuid = ? AND entitled_id = ?", user.UserUUID, entitledID)
+	return convertErr(err)
+}
+
+// GetEntitlementsForTask implements storage.GetEntitlementsForTask
+func (s *SQLBackend) GetEntitlementsForTask(entityID string) ([]storage.EntitlementEntry, error) {
+	rows, err := s.db.Query(
+		"SELECT user_uuid, entitled_id, granted_access_at FROM task_entitlements WHERE entitled_id = ? ORDER BY granted_access_at",
+		entityID,
+	)
+	if err != nil {
+		return nil, convertErr(err)
+	}
+	defer rows.Close()
+
+	var ents []storage.EntitlementEntry
+	for rows.Next() {
+		var ent storage.EntitlementEntry
+		if err := rows.Scan(&ent.UserUUID, &ent.EntitledID, &ent.GrantedAccessAt); err != nil {
+			return nil, convertErr(err)
+		}
+		ents = append(ents, ent)
+	}
+	return ents, convertErr(rows.Err())
+}
+
+// RemoveEntitlements implements storage.RemoveEntitlements
+func (s *SQLBackend) RemoveEntitlements(entityID string, entType storage.EntitlementType) error {
+	table, err := entitlementTable(entType)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec("DELETE FROM "+table+" WHERE entitled_id = ?", entityID)
+	return convertErr(err)
+}
@@ -0,0 +1,92 @@
+package sqldb
+
+import (
+	"testing"
+
+	"github.com/mandiant/gocrack/server/storage"
+	"github.com/mandiant/gocrack/shared"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileEntitlements(t *testing.T) {
+	db := initTest(t)
+	defer db.DestroyTest()
+
+	user, err := createTestUser(false, t, db)
+	if err != nil {
+		assert.FailNow(t, "failed to create user document", err.Error())
+	}
+
+	other := &storage.User{Username: "other"}
+	if err := db.CreateUser(other); err != nil {
+		assert.FailNow(t, "failed to create user document", err.Error())
+	}
+
+	tftxn, err := db.NewTaskFileTransaction()
+	if err != nil {
+		assert.FailNow(t, "failed to create task file transaction", err.Error())
+	}
+	tf := storage.TaskFile{FileID: "task-file", UploadedByUUID: user.UserUUID, FileName: "hashes.txt"}
+	assert.Nil(t, tftxn.SaveTaskFile(tf))
+	assert.Nil(t, tftxn.AddEntitlement(tf, user.UserUUID))
+	assert.Nil(t, tftxn.AddEntitlement(tf, user.UserUUID))
+	assert.Nil(t, tftxn.Commit())
+
+	eftxn, err := db.NewEngineFileTransaction()
+	if err != nil {
+		assert.FailNow(t, "failed to create engine file transaction", err.Error())
+	}
+	private := storage.EngineFile{FileID: "private", UploadedByUUID: user.UserUUID, Description: shared.GetStrPtr("mine")}
+	assert.Nil(t, eftxn.SaveEngineFile(private))
+	assert.Nil(t, eftxn.AddEntitlement(private, user.UserUUID))
+	// files imported by the server are uploaded by a user that does not exist
+	assert.Nil(t, eftxn.SaveEngineFile(storage.EngineFile{FileID: "shared", UploadedBy: "System", UploadedByUUID: "system", IsShared: true}))
+	assert.Nil(t, eftxn.Commit())
+
+	tfs, err := db.ListTasksForUser(*user)
+	assert.Nil(t, err)
+	if assert.Len(t, tfs, 1) {
+		assert.Equal(t, "testuser", tfs[0].UploadedBy)
+	}
+
+	tfs, err = db.ListTasksForUser(*other)
+	assert.Nil(t, err)
+	assert.Empty(t, tfs)
+
+	efs, err := db.GetEngineFilesForUser(*user)
+	assert.Nil(t, err)
+	assert.Len(t, efs, 2)
+
+	efs, err = db.GetEngineFilesForUser(*other)
+	assert.Nil(t, err)
+	if assert.Len(t, efs, 1) {
+		assert.Equal(t, "shared", efs[0].FileID)
+	}
+
+	assert.Nil(t, db.GrantEntitlement(*other, private))
+	ok, err := db.CheckEntitlement(other.UserUUID, "private", storage.EntitlementEngineFile)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	assert.Nil(t, db.RevokeEntitlement(*other, private))
+	ok, err = db.CheckEntitlement(other.UserUUID, "private", storage.EntitlementEngineFile)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	ef, err := db.GetEngineFileByID("private")
+	assert.Nil(t, err)
+	assert.Equal(t, "mine", *ef.Description)
+	assert.False(t, ef.UploadedAt.IsZero())
+
+	// removing a file removes the entitlements to it
+	assert.Nil(t, db.DeleteTaskFile("task-file"))
+	assert.Equal(t, storage.ErrNotFound, db.DeleteTaskFile("task-file"))
+	ok, err = db.CheckEntitlement(user.UserUUID, "task-file", storage.EntitlementTaskFile)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	assert.Nil(t, db.DeleteEngineFile("private"))
+	_, err = db.GetEngineFileByID("private")
+	assert.Equal(t, storage.ErrNotFound, err)
+}
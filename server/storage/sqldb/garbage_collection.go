@@ -0,0 +1,67 @@
+package sqldb
+
+import (
+	"github.com/mandiant/gocrack/server/storage"
+)
+
+// orphanQueries are the conditions matching the rows of each table that belong to a task or file that no longer exists.
+// The foreign keys prevent most of them but they are not enforced on connections that disable them
+var orphanQueries = []struct {
+	recordType storage.RecordType
+	table      string
+	where      string
+}{
+	{storage.RecordWorkUnit, "work_units", "task_id NOT IN (SELECT task_id FROM tasks)"},
+	{storage.RecordCrackedHash, "cracked_hashes", "task_id NOT IN (SELECT task_id FROM tasks)"},
+	{storage.RecordCheckpoint, "task_checkpoints", "task_id NOT IN (SELECT task_id FROM tasks)"},
+	{storage.RecordTaskStatus, "task_status_history", "task_id NOT IN (SELECT task_id FROM tasks)"},
+	{storage.RecordKnownHash, "known_hashes", "task_id NOT IN (SELECT task_id FROM tasks)"},
+	{storage.RecordTaskFileAccount, "task_file_accounts", "file_id NOT IN (SELECT file_id FROM task_files)"},
+	{storage.RecordCrackedWordlist, "cracked_wordlists", "file_id NOT IN (SELECT file_id FROM engine_files)"},
+	{storage.RecordEntitlement, "task_entitlements", "entitled_id NOT IN (SELECT task_id FROM tasks)"},
+	{storage.RecordEntitlement, "task_file_entitlements", "entitled_id NOT IN (SELECT file_id FROM task_files)"},
+	{storage.RecordEntitlement, "engine_file_entitlements", "entitled_id NOT IN (SELECT file_id FROM engine_files)"},
+	{storage.RecordEntitlement, "task_template_entitlements", "entitled_id NOT IN (SELECT template_id FROM task_templates)"},
+	// every audited action other than logging in is taken against a task
+	{storage.RecordAuditLog, "audit_log", "type != 0 AND entity_id != '' AND entity_id NOT IN (SELECT task_id FROM tasks)"},
+}
+
+// CollectGarbage implements storage.GarbageCollector
+func (s *SQLBackend) CollectGarbage(dryRun bool) (*storage.OrphanReport, error) {
+	report := &storage.OrphanReport{DryRun: dryRun, Orphans: make(map[storage.RecordType]int)}
+
+	txn, err := s.db.Begin()
+	if err != nil {
+		return nil, convertErr(err)
+	}
+	defer txn.Rollback()
+
+	for _, oq := range orphanQueries {
+		if dryRun {
+			var n int
+			if err = txn.QueryRow("SELECT COUNT(*) FROM " + oq.table + " WHERE " + oq.where).Scan(&n); err != nil {
+				return nil, convertErr(err)
+			}
+			report.Orphans[oq.recordType] += n
+			continue
+		}
+
+		res, err := txn.Exec("DELETE FROM " + oq.table + " WHERE " + oq.where)
+		if err != nil {
+			return nil, convertErr(err)
+		}
+
+		n, err := res.RowsAffected()
+		if err != nil {
+			return nil, convertErr(err)
+		}
+		report.Orphans[oq.recordType] += int(n)
+	}
+
+	if !dryRun {
+		if err = txn.Commit(); err != nil {
+			return nil, convertErr(err)
+		}
+	}
+	return report, nil
+}
@@ -0,0 +1,80 @@
+package sqldb
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/mandiant/gocrack/server/storage"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// queryer is implemented by both *sql.DB and *sql.Tx so helpers can run inside or outside of a transaction
+type queryer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func convertErr(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	switch err {
+	case sql.ErrNoRows:
+		return storage.ErrNotFound
+	case storage.ErrNotFound, storage.ErrAlreadyExists, storage.ErrViolateConstraint:
+		return err
+	}
+
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.Code == sqlite3.ErrConstraint {
+		switch sqliteErr.ExtendedCode {
+		case sqlite3.ErrConstraintUnique, sqlite3.ErrConstraintPrimaryKey:
+			return storage.ErrAlreadyExists
+		}
+		return storage.ErrViolateConstraint
+	}
+
+	if _, ok := err.(storage.StorageError); ok {
+		return err
+	}
+	return storage.StorageError{DriverError: err}
+}
+
+// placeholders returns n comma separated bind parameters for use in an IN clause
+func placeholders(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return strings.Repeat("?, ", n-1) + "?"
+}
+
+// stringArgs converts a list of strings into bind parameters
+func stringArgs(in []string) []interface{} {
+	out := make([]interface{}, len(in))
+	for i, v := range in {
+		out[i] = v
+	}
+	return out
+}
+
+// checkAffected returns storage.ErrNotFound if the statement did not modify any rows
+func checkAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return convertErr(err)
+	}
+
+	if n == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package sqldb
+
+import (
+	"strings"
+	"time"
+
+	"github.com/mandiant/gocrack/server/storage"
+)
+
+// lookupBatchSize keeps the number of parameters of a lookup below SQLite's limit
+const lookupBatchSize = 500
+
+const knownHashColumns = "hash_type, hash, value, cracked_at, task_id"
+
+func scanKnownHash(row rowScanner) (storage.KnownHash, error) {
+	var kh storage.KnownHash
+	err := row.Scan(&kh.HashType, &kh.Hash, &kh.Value, &kh.CrackedAt, &kh.TaskID)
+	return kh, convertErr(err)
+}
+
+// insertKnownHash adds the hash to the index unless it's already known
+func insertKnownHash(db queryer, kh storage.KnownHash) error {
+	_, err := db.Exec(
+		"INSERT INTO known_hashes ("+knownHashColumns+") VALUES (?, ?, ?, ?, ?) ON CONFLICT (hash_type, hash) DO NOTHING",
+		kh.HashType,
+		storage.NormalizeHash(kh.Hash),
+		kh.Value,
+		kh.CrackedAt,
+		kh.TaskID,
+	)
+	return convertErr(err)
+}
+
+// SaveKnownHash implements storage.SaveKnownHash
+func (s *SQLBackend) SaveKnownHash(kh storage.KnownHash) error {
+	return insertKnownHash(s.db, kh)
+}
+
+// LookupKnownHashes implements storage.LookupKnownHashes
+func (s *SQLBackend) LookupKnownHashes(user storage.User, hashType int, hashes []string) ([]storage.KnownHash, error) {
+	out := make([]storage.KnownHash, 0)
+
+	for len(hashes) > 0 {
+		batch := hashes
+		if len(batch) > lookupBatchSize {
+			batch = batch[:lookupBatchSize]
+		}
+		hashes = hashes[len(batch):]
+
+		args := []interface{}{user.IsSuperUser, user.UserUUID, hashType}
+		for _, hash := range batch {
+			args = append(args, storage.NormalizeHash(hash))
+		}
+
+		rows, err := s.db.Query(
+			"SELECT "+knownHashColumns+` FROM known_hashes
+			WHERE (? OR task_id IN (SELECT entitled_id FROM task_entitlements WHERE user_uuid = ?))
+			AND hash_type = ? AND hash IN (?`+strings.Repeat(", ?", len(batch)-1)+")",
+			args...,
+		)
+		if err != nil {
+			return nil, convertErr(err)
+		}
+
+		for rows.Next() {
+			kh, err := scanKnownHash(rows)
+			if err != nil {
+				rows.Close()
+				return nil, err
+			}
+			out = append(out, kh)
+		}
+
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, convertErr(err)
+		}
+	}
+	return out, nil
+}
+
+// CreditKnownHashes implements storage.CreditKnownHashes
+func (s *SQLBackend) CreditKnownHashes(taskID string, hashes []storage.KnownHash) (int, error) {
+	var credited int
+	var exists int
+
+	txn, err := s.db.Begin()
+	if err != nil {
+		return 0, convertErr(err)
+	}
+	defer txn.Rollback()
+
+	if err = txn.QueryRow("SELECT 1 FROM tasks WHERE task_id = ?", taskID).Scan(&exists); err != nil {
+		return 0, convertErr(err)
+	}
+
+	creditedAt := time.Now().UTC()
+	for _, kh := range hashes {
+		res, err := txn.Exec(
+			"INSERT INTO cracked_hashes (task_id, "+crackedHashColumns+") VALUES (?, ?, ?, ?, ?) ON CONFLICT (task_id, hash) DO NOTHING",
+			taskID,
+			kh.Hash,
+			kh.Value,
+			creditedAt,
+			kh.TaskID,
+		)
+		if err != nil {
+			return 0, convertErr(err)
+		}
+
+		n, err := res.RowsAffected()
+		if err != nil {
+			return 0, convertErr(err)
+		}
+		credited += int(n)
+	}
+
+	if err = txn.Commit(); err != nil {
+		return 0, convertErr(err)
+	}
+	return credited, nil
+}
@@ -0,0 +1,121 @@
+package sqldb
+
+import (
+	"encoding/json"
+
+	"github.com/mandiant/gocrack/server/storage"
+)
+
+const policyColumns = `policy_id, name, min_length, required_classes, min_classes, max_repeated_chars, banned_word_files,
+	created_by_uuid, created_at, last_updated_at`
+
+func scanPasswordPolicy(row rowScanner) (*storage.PasswordPolicy, error) {
+	var policy storage.PasswordPolicy
+	var classes, files string
+
+	if err := row.Scan(
+		&policy.PolicyID,
+		&policy.Name,
+		&policy.MinLength,
+		&classes,
+		&policy.MinClasses,
+		&policy.MaxRepeatedChars,
+		&files,
+		&policy.CreatedByUUID,
+		&policy.CreatedAt,
+		&policy.LastUpdatedAt,
+	); err != nil {
+		return nil, convertErr(err)
+	}
+
+	if err := json.Unmarshal([]byte(classes), &policy.RequiredClasses); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(files), &policy.BannedWordFiles); err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// savePasswordPolicy inserts the policy or replaces the one with the same ID
+func savePasswordPolicy(db queryer, policy storage.PasswordPolicy) error {
+	classes, err := json.Marshal(emptyIfNil(policy.RequiredClasses))
+	if err != nil {
+		return err
+	}
+
+	files, err := json.Marshal(emptyIfNil(policy.BannedWordFiles))
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(
+		`INSERT INTO password_policies (`+policyColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (policy_id) DO UPDATE SET
+			name = excluded.name,
+			min_length = excluded.min_length,
+			required_classes = excluded.required_classes,
+			min_classes = excluded.min_classes,
+			max_repeated_chars = excluded.max_repeated_chars,
+			banned_word_files = excluded.banned_word_files,
+			last_updated_at = excluded.last_updated_at`,
+		policy.PolicyID,
+		policy.Name,
+		policy.MinLength,
+		string(classes),
+		policy.MinClasses,
+		policy.MaxRepeatedChars,
+		string(files),
+		policy.CreatedByUUID,
+		policy.CreatedAt,
+		policy.LastUpdatedAt,
+	)
+	return convertErr(err)
+}
+
+// emptyIfNil keeps nil lists from being stored as null
+func emptyIfNil(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+	return list
+}
+
+// SavePasswordPolicy implements storage.SavePasswordPolicy
+func (s *SQLBackend) SavePasswordPolicy(policy storage.PasswordPolicy) error {
+	return savePasswordPolicy(s.db, policy)
+}
+
+// GetPasswordPolicy implements storage.GetPasswordPolicy
+func (s *SQLBackend) GetPasswordPolicy(policyID string) (*storage.PasswordPolicy, error) {
+	return scanPasswordPolicy(s.db.QueryRow("SELECT "+policyColumns+" FROM password_policies WHERE policy_id = ?", policyID))
+}
+
+// GetPasswordPolicies implements storage.GetPasswordPolicies
+func (s *SQLBackend) GetPasswordPolicies() ([]storage.PasswordPolicy, error) {
+	rows, err := s.db.Query("SELECT " + policyColumns + " FROM password_policies ORDER BY name")
+	if err != nil {
+		return nil, convertErr(err)
+	}
+	defer rows.Close()
+
+	out := make([]storage.PasswordPolicy, 0)
+	for rows.Next() {
+		policy, err := scanPasswordPolicy(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *policy)
+	}
+	return out, convertErr(rows.Err())
+}
+
+// DeletePasswordPolicy implements storage.DeletePasswordPolicy
+func (s *SQLBackend) DeletePasswordPolicy(policyID string) error {
+	res, err := s.db.Exec("DELETE FROM password_policies WHERE policy_id = ?", policyID)
+	if err != nil {
+		return convertErr(err)
+	}
+	return checkAffected(res)
+}
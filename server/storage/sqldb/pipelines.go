@@ -0,0 +1,116 @@
+package sqldb
+
+import (
+	"encoding/json"
+
+	"github.com/mandiant/gocrack/server/storage"
+)
+
+const pipelineColumns = `pipeline_id, name, file_id, case_code, priority, additional_users, stages, current_stage, status, error,
+	created_by_uuid, created_at, last_updated_at`
+
+func scanPipeline(row rowScanner) (*storage.Pipeline, error) {
+	var p storage.Pipeline
+	var users, stages string
+
+	if err := row.Scan(
+		&p.PipelineID,
+		&p.Name,
+		&p.FileID,
+		&p.CaseCode,
+		&p.Priority,
+		&users,
+		&stages,
+		&p.CurrentStage,
+		&p.Status,
+		&p.Error,
+		&p.CreatedByUUID,
+		&p.CreatedAt,
+		&p.LastUpdatedAt,
+	); err != nil {
+		return nil, convertErr(err)
+	}
+
+	if err := json.Unmarshal([]byte(users), &p.AdditionalUsers); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(stages), &p.Stages); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// savePipeline inserts the pipeline or replaces the one with the same ID
+func savePipeline(db queryer, p storage.Pipeline) error {
+	users, err := json.Marshal(emptyIfNil(p.AdditionalUsers))
+	if err != nil {
+		return err
+	}
+
+	if p.Stages == nil {
+		p.Stages = []storage.PipelineStage{}
+	}
+
+	stages, err := json.Marshal(p.Stages)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(
+		`INSERT INTO pipelines (`+pipelineColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (pipeline_id) DO UPDATE SET
+			name = excluded.name,
+			case_code = excluded.case_code,
+			priority = excluded.priority,
+			additional_users = excluded.additional_users,
+			stages = excluded.stages,
+			current_stage = excluded.current_stage,
+			status = excluded.status,
+			error = excluded.error,
+			last_updated_at = excluded.last_updated_at`,
+		p.PipelineID,
+		p.Name,
+		p.FileID,
+		p.CaseCode,
+		p.Priority,
+		string(users),
+		string(stages),
+		p.CurrentStage,
+		p.Status,
+		p.Error,
+		p.CreatedByUUID,
+		p.CreatedAt,
+		p.LastUpdatedAt,
+	)
+	return convertErr(err)
+}
+
+// SavePipeline implements storage.SavePipeline
+func (s *SQLBackend) SavePipeline(p storage.Pipeline) error {
+	return savePipeline(s.db, p)
+}
+
+// GetPipeline implements storage.GetPipeline
+func (s *SQLBackend) GetPipeline(pipelineID string) (*storage.Pipeline, error) {
+	return scanPipeline(s.db.QueryRow("SELECT "+pipelineColumns+" FROM pipelines WHERE pipeline_id = ?", pipelineID))
+}
+
+// GetPipelines implements storage.GetPipelines
+func (s *SQLBackend) GetPipelines() ([]storage.Pipeline, error) {
+	rows, err := s.db.Query("SELECT " + pipelineColumns + " FROM pipelines ORDER BY created_at")
+	if err != nil {
+		return nil, convertErr(err)
+	}
+	defer rows.Close()
+
+	out := make([]storage.Pipeline, 0)
+	for rows.Next() {
+		p, err := scanPipeline(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *p)
+	}
+	return out, convertErr(rows.Err())
+}
@@ -0,0 +1,439 @@
+package sqldb
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mandiant/gocrack/server/storage"
+
+	"github.com/rs/zerolog/log"
+)
+
+// schemaMigration contains the statements that bring the database from one version to the next
+type schemaMigration struct {
+	Description string
+	Statements  string
+}
+
+// schemaMigrations contains every migration in the order they are applied.
+// The version of a database is the number of migrations that have been applied to it and is tracked by PRAGMA user_version.
+// Migrations must never be modified once released; append a new one instead
+var schemaMigrations = []schemaMigration{
+	{
+		Description: "Initial schema",
+		Statements: `
+	CREATE TABLE users (
+		id            INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_uuid     TEXT NOT NULL UNIQUE,
+		username      TEXT NOT NULL UNIQUE,
+		password      TEXT NOT NULL DEFAULT '',
+		enabled       INTEGER,
+		email_address TEXT NOT NULL DEFAULT '',
+		is_super_user INTEGER NOT NULL DEFAULT 0,
+		created_at    TIMESTAMP NOT NULL
+	);
+
+	-- uploaded_by_uuid is not a foreign key as files imported by the server are uploaded by the system user
+	CREATE TABLE task_files (
+		file_id             TEXT PRIMARY KEY,
+		saved_at            TEXT NOT NULL DEFAULT '',
+		uploaded_at         TIMESTAMP NOT NULL,
+		uploaded_by         TEXT NOT NULL DEFAULT '',
+		uploaded_by_uuid    TEXT NOT NULL DEFAULT '',
+		file_size           INTEGER NOT NULL DEFAULT 0,
+		file_name           TEXT NOT NULL DEFAULT '',
+		sha1_hash           TEXT NOT NULL DEFAULT '',
+		for_engine          INTEGER NOT NULL DEFAULT 0,
+		number_of_passwords INTEGER NOT NULL DEFAULT 0,
+		number_of_salts     INTEGER NOT NULL DEFAULT 0
+	);
+	CREATE INDEX task_files_uploaded_at_idx ON task_files (uploaded_at);
+
+	CREATE TABLE engine_files (
+		file_id           TEXT PRIMARY KEY,
+		file_name         TEXT NOT NULL DEFAULT '',
+		file_size         INTEGER NOT NULL DEFAULT 0,
+		description       TEXT,
+		uploaded_by       TEXT NOT NULL DEFAULT '',
+		uploaded_by_uuid  TEXT NOT NULL DEFAULT '',
+		uploaded_at       TIMESTAMP NOT NULL,
+		last_updated_at   TIMESTAMP NOT NULL,
+		file_type         INTEGER NOT NULL DEFAULT 0,
+		number_of_entries INTEGER NOT NULL DEFAULT 0,
+		is_shared         INTEGER NOT NULL DEFAULT 0,
+		sha1_hash         TEXT NOT NULL DEFAULT '',
+		saved_at          TEXT NOT NULL DEFAULT ''
+	);
+	CREATE INDEX engine_files_is_shared_idx ON engine_files (is_shared);
+
+	-- file_id is not a foreign key as task files may be removed while the tasks that cracked them are kept
+	CREATE TABLE tasks (
+		task_id             TEXT PRIMARY KEY,
+		task_name           TEXT NOT NULL DEFAULT '',
+		status              TEXT NOT NULL,
+		engine              INTEGER NOT NULL DEFAULT 0,
+		engine_payload      TEXT,
+		priority            INTEGER NOT NULL DEFAULT 0,
+		file_id             TEXT NOT NULL DEFAULT '',
+		created_by          TEXT NOT NULL DEFAULT '',
+		created_by_uuid     TEXT NOT NULL REFERENCES users (user_uuid),
+		created_at          TIMESTAMP NOT NULL,
+		task_duration       INTEGER NOT NULL DEFAULT 0,
+		last_updated_at     TIMESTAMP NOT NULL,
+		assigned_to_host    TEXT NOT NULL DEFAULT '',
+		assigned_to_devices TEXT,
+		comment             TEXT,
+		case_code           TEXT,
+		error               TEXT,
+		keyspace            INTEGER NOT NULL DEFAULT 0,
+		work_unit_count     INTEGER NOT NULL DEFAULT 0,
+		progress            REAL NOT NULL DEFAULT 0
+	);
+	CREATE INDEX tasks_queue_idx ON tasks (status, priority, created_at);
+	CREATE INDEX tasks_created_at_idx ON tasks (created_at);
+	CREATE INDEX tasks_file_id_idx ON tasks (file_id);
+
+	CREATE TABLE work_units (
+		unit_id          TEXT PRIMARY KEY,
+		task_id          TEXT NOT NULL REFERENCES tasks (task_id) ON DELETE CASCADE,
+		idx              INTEGER NOT NULL,
+		skip             INTEGER NOT NULL DEFAULT 0,
+		"limit"          INTEGER NOT NULL DEFAULT 0,
+		status           TEXT NOT NULL,
+		assigned_to_host TEXT NOT NULL DEFAULT '',
+		attempts         INTEGER NOT NULL DEFAULT 0,
+		progress         REAL NOT NULL DEFAULT 0,
+		dispatched_at    TIMESTAMP NOT NULL,
+		last_updated_at  TIMESTAMP NOT NULL,
+		error            TEXT
+	);
+	CREATE INDEX work_units_task_idx ON work_units (task_id, status, idx);
+	CREATE INDEX work_units_status_idx ON work_units (status);
+
+	CREATE TABLE cracked_hashes (
+		id         INTEGER PRIMARY KEY AUTOINCREMENT,
+		task_id    TEXT NOT NULL REFERENCES tasks (task_id) ON DELETE CASCADE,
+		hash       TEXT NOT NULL,
+		value      TEXT NOT NULL,
+		cracked_at TIMESTAMP NOT NULL,
+		UNIQUE (task_id, hash)
+	);
+
+	CREATE TABLE task_checkpoints (
+		task_id TEXT PRIMARY KEY REFERENCES tasks (task_id) ON DELETE CASCADE,
+		data    BLOB
+	);
+
+	CREATE TABLE audit_log (
+		id          INTEGER PRIMARY KEY AUTOINCREMENT,
+		occured_at  TIMESTAMP NOT NULL,
+		user_uuid   TEXT NOT NULL DEFAULT '',
+		username    TEXT NOT NULL DEFAULT '',
+		entity_id   TEXT NOT NULL DEFAULT '',
+		status_code INTEGER NOT NULL DEFAULT 0,
+		type        INTEGER NOT NULL DEFAULT 0,
+		path        TEXT NOT NULL DEFAULT '',
+		ip_address  TEXT NOT NULL DEFAULT ''
+	);
+	CREATE INDEX audit_log_entity_idx ON audit_log (entity_id, occured_at);
+
+	CREATE TABLE task_entitlements (
+		user_uuid         TEXT NOT NULL REFERENCES users (user_uuid) ON DELETE CASCADE,
+		entitled_id       TEXT NOT NULL REFERENCES tasks (task_id) ON DELETE CASCADE,
+		granted_access_at TIMESTAMP NOT NULL,
+		PRIMARY KEY (user_uuid, entitled_id)
+	);
+	CREATE INDEX task_entitlements_entitled_idx ON task_entitlements (entitled_id);
+
+	CREATE TABLE task_file_entitlements (
+		user_uuid         TEXT NOT NULL REFERENCES users (user_uuid) ON DELETE CASCADE,
+		entitled_id       TEXT NOT NULL REFERENCES task_files (file_id) ON DELETE CASCADE,
+		granted_access_at TIMESTAMP NOT NULL,
+		PRIMARY KEY (user_uuid, entitled_id)
+	);
+	CREATE INDEX task_file_entitlements_entitled_idx ON task_file_entitlements (entitled_id);
+
+	CREATE TABLE engine_file_entitlements (
+		user_uuid         TEXT NOT NULL REFERENCES users (user_uuid) ON DELETE CASCADE,
+		entitled_id       TEXT NOT NULL REFERENCES engine_files (file_id) ON DELETE CASCADE,
+		granted_access_at TIMESTAMP NOT NULL,
+		PRIMARY KEY (user_uuid, entitled_id)
+	);
+	CREATE INDEX engine_file_entitlements_entitled_idx ON engine_file_entitlements (entitled_id);
+	`,
+	},
+	{
+		Description: "Record the status history of tasks",
+		Statements: `
+	CREATE TABLE task_status_history (
+		id              INTEGER PRIMARY KEY AUTOINCREMENT,
+		task_id         TEXT NOT NULL REFERENCES tasks (task_id) ON DELETE CASCADE,
+		changed_at      TIMESTAMP NOT NULL,
+		previous_status TEXT NOT NULL DEFAULT '',
+		status          TEXT NOT NULL,
+		error           TEXT,
+		actor_type      TEXT NOT NULL DEFAULT '',
+		actor           TEXT NOT NULL DEFAULT '',
+		devices         TEXT
+	);
+	CREATE INDEX task_status_history_task_idx ON task_status_history (task_id, changed_at);
+	`,
+	},
+	{
+		Description: "Share cracked hashes between tasks through the known hash index",
+		Statements: `
+	ALTER TABLE tasks ADD disable_hash_reuse INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE cracked_hashes ADD reused_from_task_id TEXT NOT NULL DEFAULT '';
+
+	CREATE TABLE known_hashes (
+		hash_type  INTEGER NOT NULL,
+		hash       TEXT NOT NULL,
+		value      TEXT NOT NULL,
+		cracked_at TIMESTAMP NOT NULL,
+		task_id    TEXT NOT NULL REFERENCES tasks (task_id) ON DELETE CASCADE,
+		PRIMARY KEY (hash_type, hash)
+	);
+	CREATE INDEX known_hashes_task_idx ON known_hashes (task_id);
+	`,
+	},
+	{
+		Description: "Record the accounts of task files whose hashes are prefixed by a username",
+		Statements: `
+	ALTER TABLE task_files ADD format INTEGER NOT NULL DEFAULT 0;
+
+	CREATE TABLE task_file_accounts (
+		id       INTEGER PRIMARY KEY AUTOINCREMENT,
+		file_id  TEXT NOT NULL REFERENCES task_files (file_id) ON DELETE CASCADE,
+		username TEXT NOT NULL,
+		hash     TEXT NOT NULL
+	);
+	CREATE INDEX task_file_accounts_file_idx ON task_file_accounts (file_id);
+	`,
+	},
+	{
+		Description: "Store the password policies cracked passwords are evaluated against",
+		Statements: `
+	CREATE TABLE password_policies (
+		policy_id          TEXT PRIMARY KEY,
+		name               TEXT NOT NULL UNIQUE,
+		min_length         INTEGER NOT NULL DEFAULT 0,
+		required_classes   TEXT NOT NULL DEFAULT '[]',
+		min_classes        INTEGER NOT NULL DEFAULT 0,
+		max_repeated_chars INTEGER NOT NULL DEFAULT 0,
+		banned_word_files  TEXT NOT NULL DEFAULT '[]',
+		created_by_uuid    TEXT NOT NULL,
+		created_at         TIMESTAMP NOT NULL,
+		last_updated_at    TIMESTAMP NOT NULL
+	);
+	`,
+	},
+	{
+		Description: "Record the tasks dictionaries of cracked passwords are built from",
+		Statements: `
+	CREATE TABLE cracked_wordlists (
+		file_id         TEXT PRIMARY KEY REFERENCES engine_files (file_id) ON DELETE CASCADE,
+		task_ids        TEXT NOT NULL DEFAULT '[]',
+		case_code       TEXT NOT NULL DEFAULT '',
+		auto_update     INTEGER NOT NULL DEFAULT 0,
+		created_by_uuid TEXT NOT NULL,
+		created_at      TIMESTAMP NOT NULL,
+		last_built_at   TIMESTAMP NOT NULL
+	);
+	CREATE INDEX cracked_wordlists_auto_update_idx ON cracked_wordlists (auto_update);
+	`,
+	},
+	{
+		Description: "Add pipelines of attacks that are run one after another against a task file",
+		Statements: `
+	CREATE TABLE pipelines (
+		pipeline_id      TEXT PRIMARY KEY,
+		name             TEXT NOT NULL,
+		file_id          TEXT NOT NULL,
+		case_code        TEXT,
+		priority         INTEGER NOT NULL DEFAULT 1,
+		additional_users TEXT NOT NULL DEFAULT '[]',
+		stages           TEXT NOT NULL DEFAULT '[]',
+		current_stage    INTEGER NOT NULL DEFAULT 0,
+		status           TEXT NOT NULL,
+		error            TEXT,
+		created_by_uuid  TEXT NOT NULL,
+		created_at       TIMESTAMP NOT NULL,
+		last_updated_at  TIMESTAMP NOT NULL
+	);
+	`,
+	},
+	{
+		Description: "Add task templates that tasks can be created from",
+		Statements: `
+	CREATE TABLE task_templates (
+		template_id         TEXT PRIMARY KEY,
+		name                TEXT NOT NULL,
+		description         TEXT,
+		engine              INTEGER NOT NULL DEFAULT 0,
+		engine_payload      TEXT,
+		priority            INTEGER NOT NULL DEFAULT 1,
+		task_duration       INTEGER NOT NULL DEFAULT 0,
+		work_units          INTEGER NOT NULL DEFAULT 0,
+		assigned_to_host    TEXT NOT NULL DEFAULT '',
+		assigned_to_devices TEXT,
+		is_shared           INTEGER NOT NULL DEFAULT 0,
+		created_by_uuid     TEXT NOT NULL,
+		created_at          TIMESTAMP NOT NULL,
+		last_updated_at     TIMESTAMP NOT NULL
+	);
+	CREATE INDEX task_templates_name_idx ON task_templates (name);
+
+	CREATE TABLE task_template_entitlements (
+		user_uuid         TEXT NOT NULL REFERENCES users (user_uuid) ON DELETE CASCADE,
+		entitled_id       TEXT NOT NULL REFERENCES task_templates (template_id) ON DELETE CASCADE,
+		granted_access_at TIMESTAMP NOT NULL,
+		PRIMARY KEY (user_uuid, entitled_id)
+	);
+	CREATE INDEX task_template_entitlements_entitled_idx ON task_template_entitlements (entitled_id);
+	`,
+	},
+	{
+		Description: "Restrict when tasks can run with a start time and execution windows",
+		Statements: `
+	ALTER TABLE tasks ADD not_before TIMESTAMP;
+	ALTER TABLE tasks ADD execution_windows TEXT NOT NULL DEFAULT '[]';
+	ALTER TABLE tasks ADD stopped_by_window INTEGER NOT NULL DEFAULT 0;
+	`,
+	},
+	{
+		Description: "Track the runtime of tasks against their time budget",
+		Statements: `
+	ALTER TABLE tasks ADD runtime INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE tasks ADD running_since TIMESTAMP;
+	`,
+	},
+	{
+		Description: "Record the tasks being stopped for a task of a higher priority",
+		Statements: `
+	ALTER TABLE tasks ADD preempted INTEGER NOT NULL DEFAULT 0;
+	`,
+	},
+	{
+		Description: "Age the priority of tasks from when they were last queued",
+		Statements: `
+	ALTER TABLE tasks ADD queued_at TIMESTAMP;
+	UPDATE tasks SET queued_at = created_at;
+	`,
+	},
+}
+
+// checkSchema brings the database up to the latest schema. If skipMigrations is set, the database is only
+// checked to ensure it was not written by a newer version of GoCrack
+func (s *SQLBackend) checkSchema(skipMigrations bool) error {
+	if skipMigrations {
+		version, err := schemaVersion(s.db)
+		if err != nil {
+			return err
+		}
+
+		if version > len(schemaMigrations) {
+			return errNewerSchema(version)
+		}
+		return nil
+	}
+
+	report, err := s.Migrate(false)
+	if err != nil {
+		return err
+	}
+
+	if report.FromVersion > 0 && len(report.Applied) > 0 {
+		log.Info().
+			Int("from_version", report.FromVersion).
+			Int("to_version", report.ToVersion).
+			Str("backup", report.BackupPath).
+			Msg("Database has been migrated")
+	}
+	return nil
+}
+
+func schemaVersion(db queryer) (int, error) {
+	var version int
+
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return 0, convertErr(err)
+	}
+	return version, nil
+}
+
+func errNewerSchema(version int) error {
+	return fmt.Errorf("%w: database is at version %d and this server supports up to version %d",
+		storage.ErrNewerSchema, version, len(schemaMigrations))
+}
+
+// backup copies the database next to itself and returns the path of the copy.
+// In-memory databases can't be backed up and return an empty path
+func (s *SQLBackend) backup(version int) (string, error) {
+	if s.path == "" {
+		return "", nil
+	}
+
+	path := fmt.Sprintf("%s.v%d-%s.bak", s.path, version, time.Now().UTC().Format("20060102T150405"))
+	if _, err := s.db.Exec("VACUUM INTO ?", path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// Migrate implements storage.Migrator. Existing databases are backed up before the first migration is applied and every
+// migration is applied within a single transaction so a failure leaves the database untouched
+func (s *SQLBackend) Migrate(dryRun bool) (*storage.MigrationReport, error) {
+	version, err := schemaVersion(s.db)
+	if err != nil {
+		return nil, err
+	}
+
+	if version > len(schemaMigrations) {
+		return nil, errNewerSchema(version)
+	}
+
+	report := &storage.MigrationReport{
+		FromVersion: version,
+		ToVersion:   len(schemaMigrations),
+		DryRun:      dryRun,
+	}
+
+	if version == len(schemaMigrations) {
+		return report, nil
+	}
+
+	// a new database has nothing worth backing up
+	if !dryRun && version > 0 {
+		if report.BackupPath, err = s.backup(version); err != nil {
+			return nil, fmt.Errorf("failed to backup the database before migrating: %s", err)
+		}
+	}
+
+	txn, err := s.db.Begin()
+	if err != nil {
+		return nil, convertErr(err)
+	}
+	defer txn.Rollback()
+
+	for ; version < len(schemaMigrations); version++ {
+		m := schemaMigrations[version]
+		if _, err = txn.Exec(m.Statements); err != nil {
+			return nil, fmt.Errorf("failed to migrate database schema to version %d: %w", version+1, err)
+		}
+
+		report.Applied = append(report.Applied, storage.AppliedMigration{
+			Version:     version + 1,
+			Description: m.Description,
+		})
+	}
+
+	// PRAGMA statements do not support bound parameters
+	if _, err = txn.Exec(fmt.Sprintf("PRAGMA user_version = %d", version)); err != nil {
+		return nil, convertErr(err)
+	}
+
+	if dryRun {
+		return report, nil
+	}
+	return report, convertErr(txn.Commit())
+}
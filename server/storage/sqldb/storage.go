@@ -0,0 +1,94 @@
+package sqldb
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/mandiant/gocrack/server/storage"
+
+	// registers the sqlite3 database/sql driver
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// dsnDefaults are appended to the connection string unless it already sets them.
+// Write transactions take the database lock upfront so two writers can't deadlock upgrading their locks
+var dsnDefaults = []string{
+	"_foreign_keys=on",
+	"_busy_timeout=10000",
+	"_journal_mode=WAL",
+	"_txlock=immediate",
+}
+
+func init() {
+	storage.Register("sqlite", &Driver{})
+}
+
+type Driver struct{}
+
+func (s *Driver) Open(cfg storage.Config) (storage.Backend, error) {
+	return Init(cfg)
+}
+
+// SQLBackend is a storage backend for GoCrack built ontop of database/sql and an embedded SQLite database
+type SQLBackend struct {
+	// contains filtered or unexported fields
+	db *sql.DB
+	// path is the location of the database file or empty if the database is in memory
+	path string
+}
+
+// Init opens the SQLite database located at the connection string and brings its schema up to date
+func Init(cfg storage.Config) (storage.Backend, error) {
+	db, err := sql.Open("sqlite3", buildDSN(cfg.ConnectionString))
+	if err != nil {
+		return nil, err
+	}
+
+	sb := &SQLBackend{db: db, path: databasePath(cfg.ConnectionString)}
+	if err = sb.checkSchema(cfg.SkipMigrations); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return sb, nil
+}
+
+// Close the database
+func (s *SQLBackend) Close() error {
+	return s.db.Close()
+}
+
+func buildDSN(connStr string) string {
+	var opts []string
+	for _, opt := range dsnDefaults {
+		key := opt[:strings.Index(opt, "=")+1]
+		if !strings.Contains(connStr, key) {
+			opts = append(opts, opt)
+		}
+	}
+
+	if len(opts) == 0 {
+		return connStr
+	}
+
+	sep := "?"
+	if strings.Contains(connStr, "?") {
+		sep = "&"
+	}
+	return connStr + sep + strings.Join(opts, "&")
+}
+
+// databasePath returns the file the connection string refers to or an empty string if the database is in memory
+func databasePath(connStr string) string {
+	if i := strings.Index(connStr, "?"); i != -1 {
+		if strings.Contains(connStr[i:], "mode=memory") {
+			return ""
+		}
+		connStr = connStr[:i]
+	}
+
+	connStr = strings.TrimPrefix(connStr, "file:")
+	if connStr == ":memory:" {
+		return ""
+	}
+	return connStr
+}
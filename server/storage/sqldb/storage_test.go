@@ -0,0 +1,127 @@
+package sqldb
+
+import (
+	"errors"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mandiant/gocrack/server/storage"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type storageTester struct {
+	dirpath string
+	*SQLBackend
+}
+
+func initTest(t assert.TestingT) *storageTester {
+	path, err := ioutil.TempDir("", "storage_tests")
+	if err != nil {
+		assert.FailNow(t, "Failed to create temp directory for database", err.Error())
+	}
+
+	db, err := Init(storage.Config{
+		ConnectionString: filepath.Join(path, "test.db"),
+	})
+	if err != nil {
+		assert.FailNow(t, "Failed to initialize database", err.Error())
+	}
+
+	return &storageTester{
+		dirpath:    path,
+		SQLBackend: db.(*SQLBackend),
+	}
+}
+
+func (st *storageTester) DestroyTest() {
+	if err := st.Close(); err != nil {
+		log.Printf("Failed to close database: %s\n", err)
+	}
+	if err := os.RemoveAll(st.dirpath); err != nil {
+		log.Printf("Failed to destroy directory %s: %s\n", st.dirpath, err)
+	}
+}
+
+func TestBuildDSN(t *testing.T) {
+	assert.Equal(t, "/tmp/gocrack.db?_foreign_keys=on&_busy_timeout=10000&_journal_mode=WAL&_txlock=immediate", buildDSN("/tmp/gocrack.db"))
+	assert.Equal(t, "file:gocrack.db?_journal_mode=DELETE&_foreign_keys=on&_busy_timeout=10000&_txlock=immediate", buildDSN("file:gocrack.db?_journal_mode=DELETE"))
+}
+
+func TestSchemaIsVersioned(t *testing.T) {
+	db := initTest(t)
+	defer db.DestroyTest()
+
+	var version int
+	assert.Nil(t, db.db.QueryRow("PRAGMA user_version").Scan(&version))
+	assert.Equal(t, len(schemaMigrations), version)
+
+	// reopening the database must not reapply the migrations
+	assert.Nil(t, db.Close())
+	reopened, err := Init(storage.Config{ConnectionString: filepath.Join(db.dirpath, "test.db")})
+	if !assert.Nil(t, err) {
+		return
+	}
+	db.SQLBackend = reopened.(*SQLBackend)
+
+	// a database from a newer server must not be opened
+	_, err = db.db.Exec("PRAGMA user_version = 1000")
+	assert.Nil(t, err)
+	_, err = Init(storage.Config{ConnectionString: filepath.Join(db.dirpath, "test.db")})
+	assert.True(t, errors.Is(err, storage.ErrNewerSchema))
+}
+
+func TestDatabasePath(t *testing.T) {
+	assert.Equal(t, "/tmp/gocrack.db", databasePath("/tmp/gocrack.db"))
+	assert.Equal(t, "gocrack.db", databasePath("file:gocrack.db?_journal_mode=DELETE"))
+	assert.Equal(t, "", databasePath(":memory:"))
+	assert.Equal(t, "", databasePath("file:test.db?mode=memory&cache=shared"))
+}
+
+func TestMigrate(t *testing.T) {
+	db := initTest(t)
+	defer db.DestroyTest()
+
+	// add a migration the database has not seen yet
+	schemaMigrations = append(schemaMigrations, schemaMigration{
+		Description: "Test migration",
+		Statements:  "CREATE TABLE migration_test (id INTEGER PRIMARY KEY)",
+	})
+	defer func() { schemaMigrations = schemaMigrations[:len(schemaMigrations)-1] }()
+
+	report, err := db.Migrate(true)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.True(t, report.DryRun)
+	assert.Equal(t, len(schemaMigrations)-1, report.FromVersion)
+	assert.Equal(t, len(schemaMigrations), report.ToVersion)
+	assert.Empty(t, report.BackupPath)
+	if assert.Len(t, report.Applied, 1) {
+		assert.Equal(t, "Test migration", report.Applied[0].Description)
+	}
+
+	// a dry run must leave the database untouched
+	version, err := schemaVersion(db.db)
+	assert.Nil(t, err)
+	assert.Equal(t, len(schemaMigrations)-1, version)
+
+	report, err = db.Migrate(false)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Len(t, report.Applied, 1)
+	assert.FileExists(t, report.BackupPath)
+
+	version, err = schemaVersion(db.db)
+	assert.Nil(t, err)
+	assert.Equal(t, len(schemaMigrations), version)
+
+	// migrations are only applied once
+	report, err = db.Migrate(false)
+	assert.Nil(t, err)
+	assert.Empty(t, report.Applied)
+}
@@ -0,0 +1,26 @@
+package sqldb
+
+import "github.com/mandiant/gocrack/server/storage"
+
+// SaveTaskCheckpoint implements storage.SaveTaskCheckpoint
+func (s *SQLBackend) SaveTaskCheckpoint(checkpoint storage.CheckpointFile) error {
+	return saveCheckpoint(s.db, checkpoint)
+}
+
+func saveCheckpoint(db queryer, checkpoint storage.CheckpointFile) error {
+	_, err := db.Exec(
+		"INSERT INTO task_checkpoints (task_id, data) VALUES (?, ?) ON CONFLICT (task_id) DO UPDATE SET data = excluded.data",
+		checkpoint.TaskID,
+		checkpoint.Data,
+	)
+	return convertErr(err)
+}
+
+// GetTaskCheckpoint implements storage.GetTaskCheckpoint
+func (s *SQLBackend) GetTaskCheckpoint(taskid string) ([]byte, error) {
+	var data []byte
+	if err := s.db.QueryRow("SELECT data FROM task_checkpoints WHERE task_id = ?", taskid).Scan(&data); err != nil {
+		return nil, convertErr(err)
+	}
+	return data, nil
+}
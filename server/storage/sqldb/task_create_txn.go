@@ -0,0 +1,85 @@
+package sqldb
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/mandiant/gocrack/server/storage"
+)
+
+var errExpectedUser = errors.New("expected CreatedBy to be set")
+
+type TaskCreateTransaction struct {
+	txn *sql.Tx
+}
+
+// NewTaskCreateTransaction creates a new transaction used by the task creation PUT API
+func (s *SQLBackend) NewTaskCreateTransaction() (storage.CreateTaskTxn, error) {
+	txn, err := s.db.Begin()
+	if err != nil {
+		return nil, convertErr(err)
+	}
+	return &TaskCreateTransaction{txn}, nil
+}
+
+// CreateTask creates the task within the context of a database transaction
+func (s *TaskCreateTransaction) CreateTask(t *storage.Task) error {
+	if t.CreatedByUUID == "" {
+		return errExpectedUser
+	}
+
+	if t.CreatedBy == "" {
+		user, err := getUser(s.txn, t.CreatedByUUID)
+		if err != nil {
+			return err
+		}
+		t.CreatedBy = user.Username
+	}
+
+	if t.Status == "" {
+		t.Status = storage.TaskStatusQueued
+	}
+
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = time.Now().UTC()
+	}
+
+	if t.QueuedAt.IsZero() {
+		t.QueuedAt = t.CreatedAt
+	}
+
+	if t.LastUpdatedAt.IsZero() {
+		t.LastUpdatedAt = time.Now().UTC()
+	}
+
+	if err := insertTask(s.txn, t); err != nil {
+		return err
+	}
+	return s.GrantEntitlement(t.CreatedByUUID, *t)
+}
+
+// GrantEntitlement grants additional users to the task within the context of a database transaction
+func (s *TaskCreateTransaction) GrantEntitlement(userUUID string, t storage.Task) error {
+	return grantEntitlement(s.txn, userUUID, t)
+}
+
+// CreateWorkUnits saves the work units of a distributed task within the context of a database transaction
+func (s *TaskCreateTransaction) CreateWorkUnits(units []storage.WorkUnit) error {
+	for _, unit := range units {
+		if err := insertWorkUnit(s.txn, unit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rollback any writes to the database
+func (s *TaskCreateTransaction) Rollback() error {
+	return s.txn.Rollback()
+}
+
+// Commit the transaction
+func (s *TaskCreateTransaction) Commit() error {
+	return convertErr(s.txn.Commit())
+}
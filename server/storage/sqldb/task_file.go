@@ -0,0 +1,169 @@
+package sqldb
+
+import (
+	"database/sql"
+
+	"github.com/mandiant/gocrack/server/storage"
+)
+
+const taskFileColumns = `file_id, saved_at, uploaded_at, uploaded_by, uploaded_by_uuid, file_size, file_name, sha1_hash,
+	for_engine, number_of_passwords, number_of_salts, format`
+
+func scanTaskFile(row rowScanner) (*storage.TaskFile, error) {
+	var tf storage.TaskFile
+
+	if err := row.Scan(
+		&tf.FileID,
+		&tf.SavedAt,
+		&tf.UploadedAt,
+		&tf.UploadedBy,
+		&tf.UploadedByUUID,
+		&tf.FileSize,
+		&tf.FileName,
+		&tf.SHA1Hash,
+		&tf.ForEngine,
+		&tf.NumberOfPasswords,
+		&tf.NumberOfSalts,
+		&tf.Format,
+	); err != nil {
+		return nil, convertErr(err)
+	}
+	return &tf, nil
+}
+
+// TaskFileTransaction is used in the creation of a task file and all the APIs it defines are executed under a database transaction
+type TaskFileTransaction struct {
+	txn *sql.Tx
+}
+
+// NewTaskFileTransaction creates a new transaction used by the task file PUT APIs
+func (s *SQLBackend) NewTaskFileTransaction() (storage.TaskFileTxn, error) {
+	txn, err := s.db.Begin()
+	if err != nil {
+		return nil, convertErr(err)
+	}
+	return &TaskFileTransaction{txn}, nil
+}
+
+// SaveTaskFile saves metadata regarding a task file to the database
+func (s *TaskFileTransaction) SaveTaskFile(tf storage.TaskFile) error {
+	if tf.UploadedBy == "" {
+		user, err := getUser(s.txn, tf.UploadedByUUID)
+		if err != nil {
+			return err
+		}
+		tf.UploadedBy = user.Username
+	}
+
+	return insertTaskFile(s.txn, tf)
+}
+
+func insertTaskFile(db queryer, tf storage.TaskFile) error {
+	_, err := db.Exec(
+		"INSERT INTO task_files ("+taskFileColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		tf.FileID,
+		tf.SavedAt,
+		tf.UploadedAt,
+		tf.UploadedBy,
+		tf.UploadedByUUID,
+		tf.FileSize,
+		tf.FileName,
+		tf.SHA1Hash,
+		tf.ForEngine,
+		tf.NumberOfPasswords,
+		tf.NumberOfSalts,
+		tf.Format,
+	)
+	return convertErr(err)
+}
+
+// SaveTaskFileAccounts saves the accounts parsed from the task file
+func (s *TaskFileTransaction) SaveTaskFileAccounts(accounts []storage.TaskFileAccount) error {
+	for _, account := range accounts {
+		if err := insertTaskFileAccount(s.txn, account); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func insertTaskFileAccount(db queryer, account storage.TaskFileAccount) error {
+	_, err := db.Exec("INSERT INTO task_file_accounts (file_id, username, hash) VALUES (?, ?, ?)", account.FileID, account.Username, account.Hash)
+	return convertErr(err)
+}
+
+// AddEntitlement creates a record giving the user access to the task file
+func (s *TaskFileTransaction) AddEntitlement(tf storage.TaskFile, userid string) error {
+	return grantEntitlement(s.txn, userid, tf)
+}
+
+// Rollback any writes to the database
+func (s *TaskFileTransaction) Rollback() error {
+	return s.txn.Rollback()
+}
+
+// Commit the transaction
+func (s *TaskFileTransaction) Commit() error {
+	return convertErr(s.txn.Commit())
+}
+
+// GetTaskFileByID implements the storage.GetTaskFileByID API
+func (s *SQLBackend) GetTaskFileByID(storageID string) (*storage.TaskFile, error) {
+	return scanTaskFile(s.db.QueryRow("SELECT "+taskFileColumns+" FROM task_files WHERE file_id = ?", storageID))
+}
+
+// ListTasksForUser implements the storage.ListTasksForUser API
+func (s *SQLBackend) ListTasksForUser(user storage.User) ([]storage.TaskFile, error) {
+	var rows *sql.Rows
+	var err error
+
+	if user.IsSuperUser {
+		rows, err = s.db.Query("SELECT " + taskFileColumns + " FROM task_files ORDER BY uploaded_at DESC")
+	} else {
+		rows, err = s.db.Query(`SELECT `+taskFileColumns+` FROM task_files
+			WHERE file_id IN (SELECT entitled_id FROM task_file_entitlements WHERE user_uuid = ?)
+			ORDER BY uploaded_at DESC`, user.UserUUID)
+	}
+	if err != nil {
+		return nil, convertErr(err)
+	}
+	defer rows.Close()
+
+	tfs := make([]storage.TaskFile, 0)
+	for rows.Next() {
+		tf, err := scanTaskFile(rows)
+		if err != nil {
+			return nil, err
+		}
+		tfs = append(tfs, *tf)
+	}
+	return tfs, convertErr(rows.Err())
+}
+
+// GetTaskFileAccounts implements storage.GetTaskFileAccounts
+func (s *SQLBackend) GetTaskFileAccounts(fileID string) ([]storage.TaskFileAccount, error) {
+	rows, err := s.db.Query("SELECT file_id, username, hash FROM task_file_accounts WHERE file_id = ? ORDER BY id", fileID)
+	if err != nil {
+		return nil, convertErr(err)
+	}
+	defer rows.Close()
+
+	out := make([]storage.TaskFileAccount, 0)
+	for rows.Next() {
+		var account storage.TaskFileAccount
+		if err := rows.Scan(&account.FileID, &account.Username, &account.Hash); err != nil {
+			return nil, convertErr(err)
+		}
+		out = append(out, account)
+	}
+	return out, convertErr(rows.Err())
+}
+
+// DeleteTaskFile implements storage.DeleteTaskFile
+func (s *SQLBackend) DeleteTaskFile(fileID string) error {
+	res, err := s.db.Exec("DELETE FROM task_files WHERE file_id = ?", fileID)
+	if err != nil {
+		return convertErr(err)
+	}
+	return checkAffected(res)
+}
@@ -0,0 +1,752 @@
+package sqldb
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/mandiant/gocrack/server/storage"
+	"github.com/mandiant/gocrack/shared/enginedef"
+)
+
+const taskInsertColumns = `task_id, task_name, status, engine, engine_payload, priority, file_id, created_by, created_by_uuid,
+	created_at, task_duration, last_updated_at, assigned_to_host, assigned_to_devices, comment, case_code, error, keyspace,
+	work_unit_count, progress, disable_hash_reuse, not_before, execution_windows, stopped_by_window, runtime, running_since,
+	preempted, queued_at`
+
+// taskColumns are the columns of a task record in the same order as taskInsertColumns. Queries must alias the tasks table as "t".
+// The devices are read back as a blob as that's what storage.CLDevices scans from
+const taskColumns = `t.task_id, t.task_name, t.status, t.engine, t.engine_payload, t.priority, t.file_id, t.created_by,
+	t.created_by_uuid, t.created_at, t.task_duration, t.last_updated_at, t.assigned_to_host, CAST(t.assigned_to_devices AS BLOB),
+	t.comment, t.case_code, t.error, t.keyspace, t.work_unit_count, t.progress, t.disable_hash_reuse, t.not_before,
+	t.execution_windows, t.stopped_by_window, t.runtime, t.running_since, t.preempted, t.queued_at`
+
+// scanTask reads a task record from the row. Any columns selected after taskColumns are scanned into extra
+func scanTask(row rowScanner, extra ...interface{}) (*storage.Task, error) {
+	var task storage.Task
+	var payload sql.NullString
+	var windows string
+
+	dest := []interface{}{
+		&task.TaskID,
+		&task.TaskName,
+		&task.Status,
+		&task.Engine,
+		&payload,
+		&task.Priority,
+		&task.FileID,
+		&task.CreatedBy,
+		&task.CreatedByUUID,
+		&task.CreatedAt,
+		&task.TaskDuration,
+		&task.LastUpdatedAt,
+		&task.AssignedToHost,
+		&task.AssignedToDevices,
+		&task.Comment,
+		&task.CaseCode,
+		&task.Error,
+		&task.Keyspace,
+		&task.WorkUnitCount,
+		&task.Progress,
+		&task.DisableHashReuse,
+		&task.Schedule.NotBefore,
+		&windows,
+		&task.StoppedByWindow,
+		&task.Runtime,
+		&task.RunningSince,
+		&task.Preempted,
+		&task.QueuedAt,
+	}
+
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, convertErr(err)
+	}
+
+	if err := json.Unmarshal([]byte(windows), &task.Schedule.Windows); err != nil {
+		return nil, err
+	}
+
+	if payload.Valid {
+		pl, err := enginedef.DecodePayload(task.Engine, json.RawMessage(payload.String))
+		if err != nil {
+			return nil, err
+		}
+		task.EnginePayload = pl
+	}
+	return &task, nil
+}
+
+// marshalWindows encodes the execution windows of a task. Tasks without windows are stored with an empty list so they can be
+// filtered on
+func marshalWindows(windows []storage.ExecutionWindow) ([]byte, error) {
+	if windows == nil {
+		windows = []storage.ExecutionWindow{}
+	}
+	return json.Marshal(windows)
+}
+
+func insertTask(db queryer, t *storage.Task) error {
+	var payload *string
+
+	if t.EnginePayload != nil {
+		b, err := json.Marshal(t.EnginePayload)
+		if err != nil {
+			return err
+		}
+		tmp := string(b)
+		payload = &tmp
+	}
+
+	windows, err := marshalWindows(t.Schedule.Windows)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(
+		"INSERT INTO tasks ("+taskInsertColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		t.TaskID,
+		t.TaskName,
+		t.Status,
+		t.Engine,
+		payload,
+		t.Priority,
+		t.FileID,
+		t.CreatedBy,
+		t.CreatedByUUID,
+		t.CreatedAt,
+		t.TaskDuration,
+		t.LastUpdatedAt,
+		t.AssignedToHost,
+		t.AssignedToDevices,
+		t.Comment,
+		t.CaseCode,
+		t.Error,
+		t.Keyspace,
+		t.WorkUnitCount,
+		t.Progress,
+		t.DisableHashReuse,
+		t.Schedule.NotBefore,
+		string(windows),
+		t.StoppedByWindow,
+		t.Runtime,
+		t.RunningSince,
+		t.Preempted,
+		t.QueuedAt,
+	)
+	return convertErr(err)
+}
+
+// GetTaskByID returns a task record based on it's ID
+func (s *SQLBackend) GetTaskByID(taskID string) (*storage.Task, error) {
+	return scanTask(s.db.QueryRow("SELECT "+taskColumns+" FROM tasks t WHERE t.task_id = ?", taskID))
+}
+
+// getNextTaskForHost returns the next task the host should run. If the task is distributed, the next work unit of the task
+// is assigned to the host and returned with it
+func (s *SQLBackend) getNextTaskForHost(req storage.GetPendingTasksRequest) (*storage.Task, *storage.WorkUnit, error) {
+	where := []string{
+		"t.assigned_to_host IN (?, '')",
+		`(t.status = ? OR (t.work_unit_count > 0 AND t.status IN (?, ?) AND EXISTS (
+			SELECT 1 FROM work_units w WHERE w.task_id = t.task_id AND w.status = ?
+		)))`,
+	}
+	args := []interface{}{
+		req.Hostname,
+		storage.TaskStatusQueued,
+		storage.TaskStatusDequeued,
+		storage.TaskStatusRunning,
+		storage.TaskStatusQueued,
+	}
+
+	// skip tasks that want a device the host is already using
+	if len(req.DevicesInUse) > 0 {
+		where = append(where, "NOT EXISTS (SELECT 1 FROM json_each(t.assigned_to_devices) d WHERE d.value IN ("+placeholders(len(req.DevicesInUse))+"))")
+		for _, dev := range req.DevicesInUse {
+			args = append(args, dev)
+		}
+	}
+
+	// skip tasks of engines the host can't run
+	if req.Engines != nil {
+		if len(req.Engines) == 0 {
+			return nil, nil, storage.ErrNotFound
+		}
+
+		where = append(where, "t.engine IN ("+placeholders(len(req.Engines))+")")
+		for _, engine := range req.Engines {
+			args = append(args, engine)
+		}
+	}
+
+	// a worker only processes a single unit of a task at a time
+	if len(req.RunningTasks) > 0 {
+		where = append(where, "t.task_id NOT IN ("+placeholders(len(req.RunningTasks))+")")
+		args = append(args, stringArgs(req.RunningTasks)...)
+	}
+
+	rows, err := s.db.Query(
+		"SELECT "+taskColumns+" FROM tasks t WHERE "+strings.Join(where, " AND ")+" ORDER BY t.priority, t.created_at",
+		args...,
+	)
+	if err != nil {
+		return nil, nil, convertErr(err)
+	}
+
+	var candidates []*storage.Task
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			rows.Close()
+			return nil, nil, err
+		}
+		candidates = append(candidates, task)
+	}
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		return nil, nil, convertErr(err)
+	}
+
+	now := time.Now().UTC()
+	ready := make([]storage.Task, 0, len(candidates))
+	for _, task := range candidates {
+		// tasks wait in the queue until their schedule allows them to run & are not handed out once out of time
+		if !task.Schedule.IsOpen(now) || task.IsOutOfTime(now) {
+			continue
+		}
+		ready = append(ready, *task)
+	}
+
+	if req.Schedule != nil {
+		if ready, err = req.Schedule(ready); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for i := range ready {
+		if ready[i].WorkUnitCount == 0 {
+			return &ready[i], nil, nil
+		}
+
+		unit, err := s.claimWorkUnit(ready[i].TaskID, req.Hostname)
+		if err != nil {
+			if err == storage.ErrNotFound {
+				// another host claimed the last unit after the search
+				continue
+			}
+			return nil, nil, err
+		}
+		return &ready[i], unit, nil
+	}
+	return nil, nil, storage.ErrNotFound
+}
+
+// GetPendingTasks implements storage.GetPendingTasks
+func (s *SQLBackend) GetPendingTasks(req storage.GetPendingTasksRequest) ([]storage.GetPendingTasksResponseItem, error) {
+	var items []storage.GetPendingTasksResponseItem
+
+	if req.CheckForNewTask {
+		newTask, unit, err := s.getNextTaskForHost(req)
+		if err != nil && err != storage.ErrNotFound {
+			return nil, err
+		}
+
+		if newTask != nil {
+			items = append(items, storage.GetPendingTasksResponseItem{
+				Type:     storage.PendingTaskNewRequest,
+				Payload:  newTask,
+				WorkUnit: unit,
+			})
+		}
+	}
+
+	if len(req.RunningTasks) == 0 {
+		return items, nil
+	}
+
+	// The remaining units of a distributed task are stopped once another unit has cracked every hash
+	args := append(stringArgs(req.RunningTasks),
+		storage.TaskStatusStopping,
+		storage.TaskStatusFinished,
+		storage.TaskStatusError,
+	)
+	rows, err := s.db.Query(`SELECT task_id FROM tasks
+		WHERE task_id IN (`+placeholders(len(req.RunningTasks))+`)
+		AND (status = ? OR (work_unit_count > 0 AND status IN (?, ?)))`,
+		args...,
+	)
+	if err != nil {
+		return nil, convertErr(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var taskID string
+		if err := rows.Scan(&taskID); err != nil {
+			return nil, convertErr(err)
+		}
+
+		items = append(items, storage.GetPendingTasksResponseItem{
+			Type: storage.PendingTaskStatusChange,
+			Payload: storage.PendingTaskStatusChangeItem{
+				TaskID:    taskID,
+				NewStatus: storage.TaskStatusStopping,
+			},
+		})
+	}
+	return items, convertErr(rows.Err())
+}
+
+// ChangeTaskStatus implements storage.ChangeTaskStatus
+func (s *SQLBackend) ChangeTaskStatus(taskID string, change storage.TaskStatusChange) (*storage.TaskStatusHistoryEntry, error) {
+	entry := storage.TaskStatusHistoryEntry{
+		TaskID:           taskID,
+		ChangedAt:        time.Now().UTC(),
+		TaskStatusChange: change,
+	}
+
+	txn, err := s.db.Begin()
+	if err != nil {
+		return nil, convertErr(err)
+	}
+	defer txn.Rollback()
+
+	var task storage.Task
+	if err = txn.QueryRow("SELECT status, runtime, running_since, queued_at FROM tasks WHERE task_id = ?", taskID).Scan(
+		&entry.PreviousStatus,
+		&task.Runtime,
+		&task.RunningSince,
+		&task.QueuedAt,
+	); err != nil {
+		return nil, convertErr(err)
+	}
+	task.TrackRuntime(change.Status, entry.ChangedAt)
+	if change.Status == storage.TaskStatusQueued {
+		task.QueuedAt = entry.ChangedAt
+	}
+
+	if _, err = txn.Exec(
+		"UPDATE tasks SET status = ?, error = COALESCE(?, error), runtime = ?, running_since = ?, queued_at = ? WHERE task_id = ?",
+		change.Status,
+		change.Error,
+		task.Runtime,
+		task.RunningSince,
+		task.QueuedAt,
+		taskID,
+	); err != nil {
+		return nil, convertErr(err)
+	}
+
+	if err = insertTaskStatus(txn, entry); err != nil {
+		return nil, err
+	}
+
+	if err = txn.Commit(); err != nil {
+		return nil, convertErr(err)
+	}
+	return &entry, nil
+}
+
+func insertTaskStatus(db queryer, entry storage.TaskStatusHistoryEntry) error {
+	_, err := db.Exec(`INSERT INTO task_status_history
+		(task_id, changed_at, previous_status, status, error, actor_type, actor, devices)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		entry.TaskID,
+		entry.ChangedAt,
+		entry.PreviousStatus,
+		entry.Status,
+		entry.Error,
+		entry.ActorType,
+		entry.Actor,
+		entry.Devices,
+	)
+	return convertErr(err)
+}
+
+// taskStatusColumns are the columns of a status history entry. The devices are read back as a blob like the devices of a task
+const taskStatusColumns = `task_id, changed_at, previous_status, status, error, actor_type, actor, CAST(devices AS BLOB)`
+
+func scanTaskStatus(row rowScanner) (storage.TaskStatusHistoryEntry, error) {
+	var entry storage.TaskStatusHistoryEntry
+
+	err := row.Scan(
+		&entry.TaskID,
+		&entry.ChangedAt,
+		&entry.PreviousStatus,
+		&entry.Status,
+		&entry.Error,
+		&entry.ActorType,
+		&entry.Actor,
+		&entry.Devices,
+	)
+	return entry, convertErr(err)
+}
+
+// GetTaskStatusHistory implements storage.GetTaskStatusHistory
+func (s *SQLBackend) GetTaskStatusHistory(taskID string) ([]storage.TaskStatusHistoryEntry, error) {
+	rows, err := s.db.Query("SELECT "+taskStatusColumns+" FROM task_status_history WHERE task_id = ? ORDER BY changed_at, id", taskID)
+	if err != nil {
+		return nil, convertErr(err)
+	}
+	defer rows.Close()
+
+	items := make([]storage.TaskStatusHistoryEntry, 0)
+	for rows.Next() {
+		entry, err := scanTaskStatus(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, entry)
+	}
+	return items, convertErr(rows.Err())
+}
+
+// TasksSearch implements storage.TasksSearch
+func (s *SQLBackend) TasksSearch(page, limit int, orderby, searchQuery string, isAscending bool, user storage.User) (*storage.SearchResults, error) {
+	var where []string
+	var args []interface{}
+
+	if !user.IsSuperUser {
+		where = append(where, "t.task_id IN (SELECT entitled_id FROM task_entitlements WHERE user_uuid = ?)")
+		args = append(args, user.UserUUID)
+	}
+
+	if searchQuery != "" {
+		where = append(where, `(instr(t.task_name, ?) > 0 OR instr(COALESCE(t.case_code, ''), ?) > 0 OR instr(t.task_id, ?) > 0
+			OR instr(t.created_by, ?) > 0 OR instr(t.status, ?) > 0)`)
+		for i := 0; i < 5; i++ {
+			args = append(args, searchQuery)
+		}
+	}
+
+	whereClause := ""
+	if len(where) > 0 {
+		whereClause = " WHERE " + strings.Join(where, " AND ")
+	}
+
+	sr := &storage.SearchResults{}
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM tasks t"+whereClause, args...).Scan(&sr.Total); err != nil {
+		return nil, convertErr(err)
+	}
+
+	switch orderby {
+	case "status":
+		orderby = "t.status"
+	case "task_id":
+		orderby = "t.task_id"
+	default:
+		orderby = "t.created_at"
+	}
+
+	direction := "DESC"
+	if isAscending {
+		direction = "ASC"
+	}
+
+	if limit <= 0 {
+		// SQLite treats a negative limit as no limit
+		limit = -1
+	}
+
+	offset := 0
+	if page > 1 && limit > 0 {
+		offset = (page - 1) * limit
+	}
+
+	rows, err := s.db.Query(`SELECT `+taskColumns+`,
+			(SELECT COUNT(*) FROM cracked_hashes c WHERE c.task_id = t.task_id),
+			COALESCE(tf.number_of_passwords, 0)
+		FROM tasks t
+		LEFT JOIN task_files tf ON tf.file_id = t.file_id`+whereClause+`
+		ORDER BY `+orderby+` `+direction+`
+		LIMIT ? OFFSET ?`,
+		append(args, limit, offset)...,
+	)
+	if err != nil {
+		return nil, convertErr(err)
+	}
+	defer rows.Close()
+
+	tasks := make([]storage.Task, 0)
+	for rows.Next() {
+		var numCracked, numPasswords int
+
+		task, err := scanTask(rows, &numCracked, &numPasswords)
+		if err != nil {
+			return nil, err
+		}
+		task.NumberCracked = numCracked
+		task.NumberPasswords = numPasswords
+		tasks = append(tasks, *task)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, convertErr(err)
+	}
+
+	sr.Results = tasks
+	return sr, nil
+}
+
+// GetTasksByCaseCode implements storage.GetTasksByCaseCode
+func (s *SQLBackend) GetTasksByCaseCode(caseCode string, user storage.User) ([]storage.Task, error) {
+	query := "SELECT " + taskColumns + " FROM tasks t WHERE t.case_code = ?"
+	args := []interface{}{caseCode}
+
+	if !user.IsSuperUser {
+		query += " AND t.task_id IN (SELECT entitled_id FROM task_entitlements WHERE user_uuid = ?)"
+		args = append(args, user.UserUUID)
+	}
+
+	rows, err := s.db.Query(query+" ORDER BY t.created_at", args...)
+	if err != nil {
+		return nil, convertErr(err)
+	}
+	defer rows.Close()
+
+	tasks := make([]storage.Task, 0)
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, *task)
+	}
+	return tasks, convertErr(rows.Err())
+}
+
+// SaveCrackedHash implements storage.SaveCrackedHash
+func (s *SQLBackend) SaveCrackedHash(taskid, hash, value string, crackedAt time.Time) error {
+	return insertCrackedHash(s.db, taskid, storage.CrackedHash{Hash: hash, Value: value, CrackedAt: crackedAt})
+}
+
+const crackedHashColumns = "hash, value, cracked_at, reused_from_task_id"
+
+func insertCrackedHash(db queryer, taskid string, ch storage.CrackedHash) error {
+	_, err := db.Exec(
+		"INSERT INTO cracked_hashes (task_id, "+crackedHashColumns+") VALUES (?, ?, ?, ?, ?)",
+		taskid,
+		ch.Hash,
+		ch.Value,
+		ch.CrackedAt,
+		ch.ReusedFromTaskID,
+	)
+	return convertErr(err)
+}
+
+// GetCrackedPasswords implements storage.GetCrackedPasswords
+func (s *SQLBackend) GetCrackedPasswords(taskid string) (*[]storage.CrackedHash, error) {
+	rows, err := s.db.Query("SELECT "+crackedHashColumns+" FROM cracked_hashes WHERE task_id = ? ORDER BY id", taskid)
+	if err != nil {
+		return nil, convertErr(err)
+	}
+	defer rows.Close()
+
+	out := make([]storage.CrackedHash, 0)
+	for rows.Next() {
+		var ch storage.CrackedHash
+		if err := rows.Scan(&ch.Hash, &ch.Value, &ch.CrackedAt, &ch.ReusedFromTaskID); err != nil {
+			return nil, convertErr(err)
+		}
+		out = append(out, ch)
+	}
+	return &out, convertErr(rows.Err())
+}
+
+// UpdateTask implements storage.UpdateTask. The host & devices the task is assigned to are cleared if they are not set in modifiedFields
+func (s *SQLBackend) UpdateTask(taskid string, modifiedFields storage.ModifiableTaskRequest) error {
+	var assignedToHost string
+	if modifiedFields.AssignedToHost != nil {
+		assignedToHost = *modifiedFields.AssignedToHost
+	}
+
+	sets := []string{"assigned_to_host = ?", "assigned_to_devices = ?"}
+	args := []interface{}{assignedToHost, modifiedFields.AssignedToDevices}
+
+	if modifiedFields.Status != nil {
+		sets = append(sets, "status = ?")
+		args = append(args, *modifiedFields.Status)
+	}
+
+	if modifiedFields.TaskDuration != nil {
+		sets = append(sets, "task_duration = ?")
+		args = append(args, *modifiedFields.TaskDuration)
+	}
+
+	if modifiedFields.DisableHashReuse != nil {
+		sets = append(sets, "disable_hash_reuse = ?")
+		args = append(args, *modifiedFields.DisableHashReuse)
+	}
+
+	if modifiedFields.Schedule != nil {
+		windows, err := marshalWindows(modifiedFields.Schedule.Windows)
+		if err != nil {
+			return err
+		}
+		sets = append(sets, "not_before = ?", "execution_windows = ?")
+		args = append(args, modifiedFields.Schedule.NotBefore, string(windows))
+	}
+
+	txn, err := s.db.Begin()
+	if err != nil {
+		return convertErr(err)
+	}
+	defer txn.Rollback()
+
+	res, err := txn.Exec("UPDATE tasks SET "+strings.Join(sets, ", ")+" WHERE task_id = ?", append(args, taskid)...)
+	if err != nil {
+		return convertErr(err)
+	}
+
+	if err = checkAffected(res); err != nil {
+		return err
+	}
+
+	if modifiedFields.DisableHashReuse != nil && *modifiedFields.DisableHashReuse {
+		if _, err = txn.Exec("DELETE FROM known_hashes WHERE task_id = ?", taskid); err != nil {
+			return convertErr(err)
+		}
+	}
+	return convertErr(txn.Commit())
+}
+
+// DeleteTask implements storage.DeleteTask. The work units, cracked & known hashes, checkpoint & entitlements of the task are
+// removed by the foreign keys referencing it
+func (s *SQLBackend) DeleteTask(taskid string, opts storage.DeleteTaskOptions) (*storage.TaskFile, error) {
+	var fileID string
+	var removedFile *storage.TaskFile
+
+	txn, err := s.db.Begin()
+	if err != nil {
+		return nil, convertErr(err)
+	}
+	defer txn.Rollback()
+
+	if err = txn.QueryRow("SELECT file_id FROM tasks WHERE task_id = ?", taskid).Scan(&fileID); err != nil {
+		return nil, convertErr(err)
+	}
+
+	if _, err = txn.Exec("DELETE FROM audit_log WHERE entity_id = ?", taskid); err != nil {
+		return nil, convertErr(err)
+	}
+
+	if _, err = txn.Exec("DELETE FROM tasks WHERE task_id = ?", taskid); err != nil {
+		return nil, convertErr(err)
+	}
+
+	if opts.RemoveUnusedTaskFile {
+		if removedFile, err = deleteUnusedTaskFile(txn, fileID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = txn.Commit(); err != nil {
+		return nil, convertErr(err)
+	}
+	return removedFile, nil
+}
+
+// deleteUnusedTaskFile removes the task file if no task references it. Its entitlements are removed by their foreign key
+func deleteUnusedTaskFile(db queryer, fileID string) (*storage.TaskFile, error) {
+	var used int
+
+	if err := db.QueryRow("SELECT COUNT(*) FROM tasks WHERE file_id = ?", fileID).Scan(&used); err != nil || used > 0 {
+		return nil, convertErr(err)
+	}
+
+	tf, err := scanTaskFile(db.QueryRow("SELECT "+taskFileColumns+" FROM task_files WHERE file_id = ?", fileID))
+	if err != nil {
+		// the file may have been deleted before the task
+		if err == storage.ErrNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if _, err = db.Exec("DELETE FROM task_files WHERE file_id = ?", fileID); err != nil {
+		return nil, convertErr(err)
+	}
+	return tf, nil
+}
+
+// SetTaskProgress records the overall progress of a distributed task
+func (s *SQLBackend) SetTaskProgress(taskID string, progress float64) error {
+	res, err := s.db.Exec("UPDATE tasks SET progress = ? WHERE task_id = ?", progress, taskID)
+	if err != nil {
+		return convertErr(err)
+	}
+	return checkAffected(res)
+}
+
+// SetTaskStoppedByWindow implements storage.SetTaskStoppedByWindow
+func (s *SQLBackend) SetTaskStoppedByWindow(taskID string, stopped bool) error {
+	res, err := s.db.Exec("UPDATE tasks SET stopped_by_window = ? WHERE task_id = ?", stopped, taskID)
+	if err != nil {
+		return convertErr(err)
+	}
+	return checkAffected(res)
+}
+
+// SetTaskPreempted implements storage.SetTaskPreempted
+func (s *SQLBackend) SetTaskPreempted(taskID string, preempted bool) error {
+	res, err := s.db.Exec("UPDATE tasks SET preempted = ? WHERE task_id = ?", preempted, taskID)
+	if err != nil {
+		return convertErr(err)
+	}
+	return checkAffected(res)
+}
+
+// GetWindowedTasks implements storage.GetWindowedTasks
+func (s *SQLBackend) GetWindowedTasks() ([]storage.Task, error) {
+	rows, err := s.db.Query("SELECT "+taskColumns+` FROM tasks t
+		WHERE t.execution_windows != '[]' AND t.status IN (?, ?, ?, ?)
+		ORDER BY t.created_at`,
+		storage.TaskStatusDequeued,
+		storage.TaskStatusRunning,
+		storage.TaskStatusStopping,
+		storage.TaskStatusStopped,
+	)
+	if err != nil {
+		return nil, convertErr(err)
+	}
+	defer rows.Close()
+
+	tasks := make([]storage.Task, 0)
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, *task)
+	}
+	return tasks, convertErr(rows.Err())
+}
+
+// GetTasksByStatus implements storage.GetTasksByStatus
+func (s *SQLBackend) GetTasksByStatus(statuses ...storage.TaskStatus) ([]storage.Task, error) {
+	tasks := make([]storage.Task, 0)
+	if len(statuses) == 0 {
+		return tasks, nil
+	}
+
+	args := make([]interface{}, len(statuses))
+	for i, status := range statuses {
+		args[i] = status
+	}
+
+	rows, err := s.db.Query("SELECT "+taskColumns+" FROM tasks t WHERE t.status IN ("+placeholders(len(statuses))+") ORDER BY t.created_at", args...)
+	if err != nil {
+		return nil, convertErr(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, *task)
+	}
+	return tasks, convertErr(rows.Err())
+}
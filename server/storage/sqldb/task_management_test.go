@@ -0,0 +1,311 @@
+package sqldb
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mandiant/gocrack/server/storage"
+	"github.com/mandiant/gocrack/shared"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func createTestTasks(t *testing.T, db *storageTester, tasks ...*storage.Task) {
+	txn, err := db.NewTaskCreateTransaction()
+	if err != nil {
+		assert.FailNow(t, "failed to create task transaction", err.Error())
+	}
+	defer txn.Rollback()
+
+	for _, task := range tasks {
+		if err := txn.CreateTask(task); err != nil {
+			assert.FailNow(t, "failed to create task", err.Error())
+		}
+	}
+
+	if err := txn.Commit(); err != nil {
+		assert.FailNow(t, "failed to commit tasks", err.Error())
+	}
+}
+
+func TestTaskManagementCreateAndGet(t *testing.T) {
+	db := initTest(t)
+	defer db.DestroyTest()
+
+	user, err := createTestUser(false, t, db)
+	if err != nil {
+		assert.FailNow(t, "failed to create user document", err.Error())
+	}
+
+	doc := &storage.Task{
+		TaskID:        uuid.NewString(),
+		TaskName:      "My Awesome Task!",
+		FileID:        uuid.NewString(),
+		CreatedByUUID: user.UserUUID,
+		Engine:        storage.WorkerHashcatEngine,
+		EnginePayload: shared.HashcatUserOptions{
+			AttackMode: shared.AttackModeBruteForce,
+			HashType:   1000,
+			Masks:      shared.GetStrPtr("masks"),
+		},
+		AssignedToDevices: &storage.CLDevices{1, 2},
+		CaseCode:          shared.GetStrPtr("CC-1337"),
+	}
+	createTestTasks(t, db, doc)
+
+	found, err := db.GetTaskByID(doc.TaskID)
+	if err != nil {
+		assert.FailNow(t, "expected a document but got an error", err.Error())
+	}
+
+	assert.Equal(t, "My Awesome Task!", found.TaskName)
+	assert.Equal(t, user.Username, found.CreatedBy)
+	assert.Equal(t, storage.TaskStatusQueued, found.Status)
+	assert.Equal(t, doc.EnginePayload, found.EnginePayload)
+	assert.Equal(t, &storage.CLDevices{1, 2}, found.AssignedToDevices)
+	assert.Equal(t, "CC-1337", *found.CaseCode)
+	assert.Nil(t, found.Comment)
+	assert.WithinDuration(t, time.Now().UTC(), found.CreatedAt, 5*time.Second)
+
+	// the creator is entitled to the task
+	ok, err := db.CheckEntitlement(user.UserUUID, doc.TaskID, storage.EntitlementTask)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	// task IDs are unique
+	err = func() error {
+		txn, _ := db.NewTaskCreateTransaction()
+		defer txn.Rollback()
+		return txn.CreateTask(doc)
+	}()
+	assert.Equal(t, storage.ErrAlreadyExists, err)
+
+	errStr := "something broke"
+	_, err = db.ChangeTaskStatus(doc.TaskID, storage.TaskStatusChange{Status: storage.TaskStatusError, Error: &errStr})
+	assert.Nil(t, err)
+	_, err = db.ChangeTaskStatus(doc.TaskID, storage.TaskStatusChange{Status: storage.TaskStatusQueued})
+	assert.Nil(t, err)
+	found, err = db.GetTaskByID(doc.TaskID)
+	assert.Nil(t, err)
+	assert.Equal(t, storage.TaskStatusQueued, found.Status)
+	assert.Equal(t, errStr, *found.Error)
+
+	_, err = db.ChangeTaskStatus("missing", storage.TaskStatusChange{Status: storage.TaskStatusQueued})
+	assert.Equal(t, storage.ErrNotFound, err)
+	_, err = db.GetTaskByID("missing")
+	assert.Equal(t, storage.ErrNotFound, err)
+}
+
+func TestTaskManagementGetNextTaskForHost(t *testing.T) {
+	db := initTest(t)
+	defer db.DestroyTest()
+
+	user, err := createTestUser(false, t, db)
+	if err != nil {
+		assert.FailNow(t, "failed to create user document", err.Error())
+	}
+
+	tasks := []*storage.Task{
+		{
+			TaskID:            "pinned",
+			CreatedByUUID:     user.UserUUID,
+			CreatedAt:         time.Now().UTC().Add(-3 * time.Hour),
+			AssignedToHost:    "my-hostname",
+			AssignedToDevices: &storage.CLDevices{4, 5},
+		},
+		{
+			TaskID:        "finished",
+			CreatedByUUID: user.UserUUID,
+			CreatedAt:     time.Now().UTC().Add(-2 * time.Hour),
+			Status:        storage.TaskStatusFinished,
+		},
+		{
+			TaskID:            "any-host",
+			CreatedByUUID:     user.UserUUID,
+			CreatedAt:         time.Now().UTC().Add(-1 * time.Hour),
+			AssignedToDevices: &storage.CLDevices{5},
+		},
+		{
+			TaskID:        "low-priority",
+			CreatedByUUID: user.UserUUID,
+			CreatedAt:     time.Now().UTC().Add(-4 * time.Hour),
+			Priority:      storage.WorkerPriorityLow,
+		},
+	}
+	createTestTasks(t, db, tasks...)
+
+	for i, test := range []struct {
+		Request  storage.GetPendingTasksRequest
+		Expected string
+	}{
+		{Request: storage.GetPendingTasksRequest{Hostname: "my-hostname"}, Expected: "pinned"},
+		{Request: storage.GetPendingTasksRequest{Hostname: "my-hostname", DevicesInUse: storage.CLDevices{1, 2}}, Expected: "pinned"},
+		{Request: storage.GetPendingTasksRequest{Hostname: "other-host"}, Expected: "any-host"},
+		{Request: storage.GetPendingTasksRequest{Hostname: "my-hostname", DevicesInUse: storage.CLDevices{4, 5}}, Expected: "low-priority"},
+		{Request: storage.GetPendingTasksRequest{Hostname: "my-hostname", RunningTasks: []string{"pinned"}}, Expected: "any-host"},
+		{Request: storage.GetPendingTasksRequest{Hostname: "other-host", RunningTasks: []string{"any-host", "low-priority"}}, Expected: ""},
+	} {
+		task, _, err := db.getNextTaskForHost(test.Request)
+		if test.Expected == "" {
+			assert.Equal(t, storage.ErrNotFound, err, fmt.Sprintf("test %d", i))
+			continue
+		}
+
+		if assert.Nil(t, err, fmt.Sprintf("test %d", i)) {
+			assert.Equal(t, test.Expected, task.TaskID, fmt.Sprintf("test %d", i))
+		}
+	}
+
+	_, err = db.ChangeTaskStatus("pinned", storage.TaskStatusChange{Status: storage.TaskStatusStopping})
+	assert.Nil(t, err)
+	items, err := db.GetPendingTasks(storage.GetPendingTasksRequest{
+		Hostname:        "my-hostname",
+		RunningTasks:    []string{"pinned"},
+		CheckForNewTask: true,
+	})
+	assert.Nil(t, err)
+	if assert.Len(t, items, 2) {
+		assert.Equal(t, storage.PendingTaskNewRequest, items[0].Type)
+		assert.Equal(t, "any-host", items[0].Payload.(*storage.Task).TaskID)
+		assert.Equal(t, storage.GetPendingTasksResponseItem{
+			Type: storage.PendingTaskStatusChange,
+			Payload: storage.PendingTaskStatusChangeItem{
+				TaskID:    "pinned",
+				NewStatus: storage.TaskStatusStopping,
+			},
+		}, items[1])
+	}
+}
+
+func TestTaskManagementSearch(t *testing.T) {
+	db := initTest(t)
+	defer db.DestroyTest()
+
+	user, err := createTestUser(false, t, db)
+	if err != nil {
+		assert.FailNow(t, "failed to create user document", err.Error())
+	}
+
+	admin := &storage.User{Username: "admin", IsSuperUser: true}
+	if err := db.CreateUser(admin); err != nil {
+		assert.FailNow(t, "failed to create admin document", err.Error())
+	}
+
+	txn, err := db.NewTaskFileTransaction()
+	if err != nil {
+		assert.FailNow(t, "failed to create task file transaction", err.Error())
+	}
+	assert.Nil(t, txn.SaveTaskFile(storage.TaskFile{FileID: "file", UploadedByUUID: user.UserUUID, NumberOfPasswords: 10}))
+	assert.Nil(t, txn.Commit())
+
+	var tasks []*storage.Task
+	for i := 0; i < 5; i++ {
+		createdBy := user.UserUUID
+		if i%2 == 1 {
+			createdBy = admin.UserUUID
+		}
+
+		tasks = append(tasks, &storage.Task{
+			TaskID:        fmt.Sprintf("task-%d", i),
+			TaskName:      fmt.Sprintf("Task %d", i),
+			FileID:        "file",
+			CreatedByUUID: createdBy,
+			CreatedAt:     time.Now().UTC().Add(time.Duration(i) * time.Minute),
+		})
+	}
+	createTestTasks(t, db, tasks...)
+
+	assert.Nil(t, db.SaveCrackedHash("task-0", "hash1", "password1", time.Now().UTC()))
+	assert.Nil(t, db.SaveCrackedHash("task-0", "hash2", "password2", time.Now().UTC()))
+	assert.Equal(t, storage.ErrAlreadyExists, db.SaveCrackedHash("task-0", "hash2", "password2", time.Now().UTC()))
+
+	// the user can only see the tasks they are entitled to
+	sr, err := db.TasksSearch(1, 10, "created_at", "", true, *user)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, sr.Total)
+	results := sr.Results.([]storage.Task)
+	if assert.Len(t, results, 3) {
+		assert.Equal(t, "task-0", results[0].TaskID)
+		assert.Equal(t, 2, results[0].NumberCracked)
+		assert.Equal(t, 10, results[0].NumberPasswords)
+		assert.Equal(t, "task-4", results[2].TaskID)
+	}
+
+	// the admin sees everything
+	sr, err = db.TasksSearch(2, 2, "created_at", "", false, *admin)
+	assert.Nil(t, err)
+	assert.Equal(t, 5, sr.Total)
+	results = sr.Results.([]storage.Task)
+	if assert.Len(t, results, 2) {
+		assert.Equal(t, "task-2", results[0].TaskID)
+		assert.Equal(t, "task-1", results[1].TaskID)
+	}
+
+	// searches are case sensitive like the bdb backend
+	sr, err = db.TasksSearch(1, 10, "task_id", "Task 3", true, *admin)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, sr.Total)
+	sr, err = db.TasksSearch(1, 10, "task_id", "task 3", true, *admin)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, sr.Total)
+	assert.Empty(t, sr.Results)
+}
+
+func TestTaskManagementUpdateAndDelete(t *testing.T) {
+	db := initTest(t)
+	defer db.DestroyTest()
+
+	user, err := createTestUser(false, t, db)
+	if err != nil {
+		assert.FailNow(t, "failed to create user document", err.Error())
+	}
+
+	doc := &storage.Task{
+		TaskID:            uuid.NewString(),
+		CreatedByUUID:     user.UserUUID,
+		AssignedToHost:    "my-hostname",
+		AssignedToDevices: &storage.CLDevices{4, 5},
+	}
+	createTestTasks(t, db, doc)
+
+	status := storage.TaskStatusStopped
+	duration := 3600
+	assert.Nil(t, db.UpdateTask(doc.TaskID, storage.ModifiableTaskRequest{
+		Status:       &status,
+		TaskDuration: &duration,
+	}))
+
+	found, err := db.GetTaskByID(doc.TaskID)
+	assert.Nil(t, err)
+	assert.Equal(t, storage.TaskStatusStopped, found.Status)
+	assert.Equal(t, 3600, found.TaskDuration)
+	assert.Equal(t, "", found.AssignedToHost)
+	assert.Nil(t, found.AssignedToDevices)
+
+	assert.Nil(t, db.SaveCrackedHash(doc.TaskID, "hash", "password", time.Now().UTC()))
+	assert.Nil(t, db.SaveTaskCheckpoint(storage.CheckpointFile{TaskID: doc.TaskID, Data: []byte("first")}))
+	assert.Nil(t, db.SaveTaskCheckpoint(storage.CheckpointFile{TaskID: doc.TaskID, Data: []byte("second")}))
+
+	checkpoint, err := db.GetTaskCheckpoint(doc.TaskID)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("second"), checkpoint)
+
+	// everything belonging to the task is removed with it
+	_, err = db.DeleteTask(doc.TaskID, storage.DeleteTaskOptions{})
+	assert.Nil(t, err)
+	_, err = db.DeleteTask(doc.TaskID, storage.DeleteTaskOptions{})
+	assert.Equal(t, storage.ErrNotFound, err)
+
+	_, err = db.GetTaskCheckpoint(doc.TaskID)
+	assert.Equal(t, storage.ErrNotFound, err)
+
+	cracked, err := db.GetCrackedPasswords(doc.TaskID)
+	assert.Nil(t, err)
+	assert.Empty(t, *cracked)
+
+	ents, err := db.GetEntitlementsForTask(doc.TaskID)
+	assert.Nil(t, err)
+	assert.Empty(t, ents)
+}
@@ -0,0 +1,129 @@
+package sqldb
+
+import (
+	"database/sql"
+
+	"github.com/mandiant/gocrack/server/storage"
+)
+
+const templateColumns = `template_id, name, description, engine, engine_payload, priority, task_duration, work_units,
+	assigned_to_host, CAST(assigned_to_devices AS BLOB), is_shared, created_by_uuid, created_at, last_updated_at`
+
+const templateInsertColumns = `template_id, name, description, engine, engine_payload, priority, task_duration, work_units,
+	assigned_to_host, assigned_to_devices, is_shared, created_by_uuid, created_at, last_updated_at`
+
+func scanTaskTemplate(row rowScanner) (*storage.TaskTemplate, error) {
+	var tmpl storage.TaskTemplate
+	var payload []byte
+
+	if err := row.Scan(
+		&tmpl.TemplateID,
+		&tmpl.Name,
+		&tmpl.Description,
+		&tmpl.Engine,
+		&payload,
+		&tmpl.Priority,
+		&tmpl.TaskDuration,
+		&tmpl.WorkUnits,
+		&tmpl.AssignedToHost,
+		&tmpl.AssignedToDevices,
+		&tmpl.IsShared,
+		&tmpl.CreatedByUUID,
+		&tmpl.CreatedAt,
+		&tmpl.LastUpdatedAt,
+	); err != nil {
+		return nil, convertErr(err)
+	}
+
+	if len(payload) > 0 {
+		tmpl.EnginePayload = payload
+	}
+	return &tmpl, nil
+}
+
+// saveTaskTemplate inserts the template or replaces the one with the same ID
+func saveTaskTemplate(db queryer, tmpl storage.TaskTemplate) error {
+	var payload *string
+	if len(tmpl.EnginePayload) > 0 {
+		s := string(tmpl.EnginePayload)
+		payload = &s
+	}
+
+	_, err := db.Exec(
+		`INSERT INTO task_templates (`+templateInsertColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (template_id) DO UPDATE SET
+			name = excluded.name,
+			description = excluded.description,
+			engine = excluded.engine,
+			engine_payload = excluded.engine_payload,
+			priority = excluded.priority,
+			task_duration = excluded.task_duration,
+			work_units = excluded.work_units,
+			assigned_to_host = excluded.assigned_to_host,
+			assigned_to_devices = excluded.assigned_to_devices,
+			is_shared = excluded.is_shared,
+			last_updated_at = excluded.last_updated_at`,
+		tmpl.TemplateID,
+		tmpl.Name,
+		tmpl.Description,
+		tmpl.Engine,
+		payload,
+		tmpl.Priority,
+		tmpl.TaskDuration,
+		tmpl.WorkUnits,
+		tmpl.AssignedToHost,
+		tmpl.AssignedToDevices,
+		tmpl.IsShared,
+		tmpl.CreatedByUUID,
+		tmpl.CreatedAt,
+		tmpl.LastUpdatedAt,
+	)
+	return convertErr(err)
+}
+
+// SaveTaskTemplate implements storage.SaveTaskTemplate
+func (s *SQLBackend) SaveTaskTemplate(tmpl storage.TaskTemplate) error {
+	return saveTaskTemplate(s.db, tmpl)
+}
+
+// GetTaskTemplate implements storage.GetTaskTemplate
+func (s *SQLBackend) GetTaskTemplate(templateID string) (*storage.TaskTemplate, error) {
+	return scanTaskTemplate(s.db.QueryRow("SELECT "+templateColumns+" FROM task_templates WHERE template_id = ?", templateID))
+}
+
+// GetTaskTemplatesForUser implements storage.GetTaskTemplatesForUser
+func (s *SQLBackend) GetTaskTemplatesForUser(user storage.User) ([]storage.TaskTemplate, error) {
+	var rows *sql.Rows
+	var err error
+
+	if user.IsSuperUser {
+		rows, err = s.db.Query("SELECT " + templateColumns + " FROM task_templates ORDER BY name")
+	} else {
+		rows, err = s.db.Query(`SELECT `+templateColumns+` FROM task_templates
+			WHERE is_shared = 1 OR template_id IN (SELECT entitled_id FROM task_template_entitlements WHERE user_uuid = ?)
+			ORDER BY name`, user.UserUUID)
+	}
+	if err != nil {
+		return nil, convertErr(err)
+	}
+	defer rows.Close()
+
+	out := make([]storage.TaskTemplate, 0)
+	for rows.Next() {
+		tmpl, err := scanTaskTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *tmpl)
+	}
+	return out, convertErr(rows.Err())
+}
+
+// DeleteTaskTemplate implements storage.DeleteTaskTemplate. Its entitlements are removed by the foreign key
+func (s *SQLBackend) DeleteTaskTemplate(templateID string) error {
+	res, err := s.db.Exec("DELETE FROM task_templates WHERE template_id = ?", templateID)
+	if err != nil {
+		return convertErr(err)
+	}
+	return checkAffected(res)
+}
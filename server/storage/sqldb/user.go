@@ -0,0 +1,126 @@
+package sqldb
+
+import (
+	"strings"
+	"time"
+
+	"github.com/mandiant/gocrack/server/storage"
+
+	"github.com/google/uuid"
+)
+
+const userColumns = "user_uuid, username, password, enabled, email_address, is_super_user, created_at"
+
+func scanUser(row rowScanner) (*storage.User, error) {
+	var user storage.User
+
+	if err := row.Scan(
+		&user.UserUUID,
+		&user.Username,
+		&user.Password,
+		&user.Enabled,
+		&user.EmailAddress,
+		&user.IsSuperUser,
+		&user.CreatedAt,
+	); err != nil {
+		return nil, convertErr(err)
+	}
+	return &user, nil
+}
+
+func getUser(db queryer, userUUID string) (*storage.User, error) {
+	return scanUser(db.QueryRow("SELECT "+userColumns+" FROM users WHERE user_uuid = ?", userUUID))
+}
+
+// SearchForUserByPassword locates the user record by username. If a record is found, the checker function will be called to validate the password.
+func (s *SQLBackend) SearchForUserByPassword(username string, checker storage.PasswordCheckFunc) (*storage.User, error) {
+	user, err := scanUser(s.db.QueryRow("SELECT "+userColumns+" FROM users WHERE username = ?", username))
+	if err != nil {
+		return nil, err
+	}
+
+	if !checker(user.Password) {
+		return nil, storage.ErrNotFound
+	}
+	return user, nil
+}
+
+// CreateUser saves the record into the database
+func (s *SQLBackend) CreateUser(user *storage.User) error {
+	user.CreatedAt = time.Now().UTC()
+	if user.UserUUID == "" {
+		user.UserUUID = uuid.NewString()
+	}
+
+	return insertUser(s.db, user)
+}
+
+func insertUser(db queryer, user *storage.User) error {
+	_, err := db.Exec(
+		"INSERT INTO users ("+userColumns+") VALUES (?, ?, ?, ?, ?, ?, ?)",
+		user.UserUUID,
+		user.Username,
+		user.Password,
+		user.Enabled,
+		user.EmailAddress,
+		user.IsSuperUser,
+		user.CreatedAt,
+	)
+	return convertErr(err)
+}
+
+// GetUserByID returns a user record given the users unique uuid.
+func (s *SQLBackend) GetUserByID(userUUID string) (*storage.User, error) {
+	return getUser(s.db, userUUID)
+}
+
+// GetUsers returns a list of all users within the GoCrack system
+func (s *SQLBackend) GetUsers() ([]storage.User, error) {
+	rows, err := s.db.Query("SELECT " + userColumns + " FROM users ORDER BY id")
+	if err != nil {
+		return nil, convertErr(err)
+	}
+	defer rows.Close()
+
+	users := make([]storage.User, 0)
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *user)
+	}
+	return users, convertErr(rows.Err())
+}
+
+// EditUser implements storage.EditUser
+func (s *SQLBackend) EditUser(userUUID string, req storage.UserModifyRequest) error {
+	var sets []string
+	var args []interface{}
+
+	if req.UserIsAdmin != nil {
+		sets = append(sets, "is_super_user = ?")
+		args = append(args, *req.UserIsAdmin)
+	}
+
+	if req.Email != nil {
+		sets = append(sets, "email_address = ?")
+		args = append(args, *req.Email)
+	}
+
+	if req.Password != nil {
+		sets = append(sets, "password = ?")
+		args = append(args, *req.Password)
+	}
+
+	if len(sets) == 0 {
+		_, err := getUser(s.db, userUUID)
+		return err
+	}
+
+	res, err := s.db.Exec("UPDATE users SET "+strings.Join(sets, ", ")+" WHERE user_uuid = ?", append(args, userUUID)...)
+	if err != nil {
+		return convertErr(err)
+	}
+	return checkAffected(res)
+}
@@ -0,0 +1,69 @@
+package sqldb
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mandiant/gocrack/server/storage"
+	"github.com/mandiant/gocrack/shared"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func createTestUser(isSuperUser bool, t *testing.T, db *storageTester) (*storage.User, error) {
+	doc := &storage.User{
+		Username:     "testuser",
+		Password:     "secret_password!",
+		EmailAddress: "dummyuser@fireeye.com",
+		CreatedAt:    time.Now(),
+		IsSuperUser:  isSuperUser,
+	}
+	return doc, db.CreateUser(doc)
+}
+
+func TestUserAPIs(t *testing.T) {
+	db := initTest(t)
+	defer db.DestroyTest()
+
+	testPass := "my_super_secret_password!1"
+	err := db.CreateUser(&storage.User{
+		Username: "testuser",
+		Password: testPass,
+	})
+	assert.Nil(t, err)
+
+	// usernames are unique
+	err = db.CreateUser(&storage.User{Username: "testuser"})
+	assert.Equal(t, storage.ErrAlreadyExists, err)
+
+	rec, err := db.SearchForUserByPassword("testuser", func(p string) bool {
+		return p == testPass
+	})
+	if err != nil {
+		assert.FailNow(t, "expected to find a user record", err)
+	}
+	assert.Equal(t, "testuser", rec.Username)
+	assert.Equal(t, testPass, rec.Password)
+
+	_, err = db.SearchForUserByPassword("testuser", func(string) bool { return false })
+	assert.Equal(t, storage.ErrNotFound, err)
+
+	isAdmin := true
+	err = db.EditUser(rec.UserUUID, storage.UserModifyRequest{
+		UserIsAdmin: &isAdmin,
+		Email:       shared.GetStrPtr("admin@gocrack.local"),
+	})
+	assert.Nil(t, err)
+
+	rec, err = db.GetUserByID(rec.UserUUID)
+	assert.Nil(t, err)
+	assert.True(t, rec.IsSuperUser)
+	assert.Equal(t, "admin@gocrack.local", rec.EmailAddress)
+	assert.Equal(t, testPass, rec.Password)
+
+	assert.Equal(t, storage.ErrNotFound, db.EditUser("missing", storage.UserModifyRequest{Email: shared.GetStrPtr("a")}))
+
+	users, err := db.GetUsers()
+	assert.Nil(t, err)
+	assert.Len(t, users, 1)
+}
@@ -0,0 +1,171 @@
+package sqldb
+
+import (
+	"time"
+
+	"github.com/mandiant/gocrack/server/storage"
+)
+
+const workUnitColumns = `unit_id, task_id, idx, skip, "limit", status, assigned_to_host, attempts, progress, dispatched_at,
+	last_updated_at, error`
+
+func scanWorkUnit(row rowScanner) (*storage.WorkUnit, error) {
+	var unit storage.WorkUnit
+
+	if err := row.Scan(
+		&unit.UnitID,
+		&unit.TaskID,
+		&unit.Index,
+		&unit.Skip,
+		&unit.Limit,
+		&unit.Status,
+		&unit.AssignedToHost,
+		&unit.Attempts,
+		&unit.Progress,
+		&unit.DispatchedAt,
+		&unit.LastUpdatedAt,
+		&unit.Error,
+	); err != nil {
+		return nil, convertErr(err)
+	}
+	return &unit, nil
+}
+
+func insertWorkUnit(db queryer, unit storage.WorkUnit) error {
+	_, err := db.Exec(
+		"INSERT INTO work_units ("+workUnitColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		unit.UnitID,
+		unit.TaskID,
+		unit.Index,
+		unit.Skip,
+		unit.Limit,
+		unit.Status,
+		unit.AssignedToHost,
+		unit.Attempts,
+		unit.Progress,
+		unit.DispatchedAt,
+		unit.LastUpdatedAt,
+		unit.Error,
+	)
+	return convertErr(err)
+}
+
+func (s *SQLBackend) queryWorkUnits(query string, args ...interface{}) ([]storage.WorkUnit, error) {
+	rows, err := s.db.Query("SELECT "+workUnitColumns+" FROM work_units WHERE "+query, args...)
+	if err != nil {
+		return nil, convertErr(err)
+	}
+	defer rows.Close()
+
+	out := make([]storage.WorkUnit, 0)
+	for rows.Next() {
+		unit, err := scanWorkUnit(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *unit)
+	}
+	return out, convertErr(rows.Err())
+}
+
+// GetWorkUnits returns every work unit of a distributed task ordered by their position in the keyspace
+func (s *SQLBackend) GetWorkUnits(taskID string) ([]storage.WorkUnit, error) {
+	return s.queryWorkUnits("task_id = ? ORDER BY idx", taskID)
+}
+
+// GetWorkUnit returns a work unit by its ID
+func (s *SQLBackend) GetWorkUnit(unitID string) (*storage.WorkUnit, error) {
+	return scanWorkUnit(s.db.QueryRow("SELECT "+workUnitColumns+" FROM work_units WHERE unit_id = ?", unitID))
+}
+
+// UpdateWorkUnit replaces the stored work unit with unit
+func (s *SQLBackend) UpdateWorkUnit(unit storage.WorkUnit) error {
+	if unit.LastUpdatedAt.IsZero() {
+		unit.LastUpdatedAt = time.Now().UTC()
+	}
+
+	res, err := s.db.Exec(`UPDATE work_units SET
+		status = ?, assigned_to_host = ?, attempts = ?, progress = ?, dispatched_at = ?, last_updated_at = ?, error = ?
+		WHERE unit_id = ?`,
+		unit.Status,
+		unit.AssignedToHost,
+		unit.Attempts,
+		unit.Progress,
+		unit.DispatchedAt,
+		unit.LastUpdatedAt,
+		unit.Error,
+		unit.UnitID,
+	)
+	if err != nil {
+		return convertErr(err)
+	}
+	return checkAffected(res)
+}
+
+// GetActiveWorkUnits returns every work unit that has been dispatched to a worker and has not stopped
+func (s *SQLBackend) GetActiveWorkUnits() ([]storage.WorkUnit, error) {
+	return s.queryWorkUnits(
+		"status IN (?, ?, ?) ORDER BY task_id, idx",
+		storage.TaskStatusDequeued,
+		storage.TaskStatusRunning,
+		storage.TaskStatusStopping,
+	)
+}
+
+// RequeueWorkUnits queues every unit of a task that has not finished or exhausted its keyspace
+func (s *SQLBackend) RequeueWorkUnits(taskID string) error {
+	_, err := s.db.Exec(`UPDATE work_units SET
+		status = ?, assigned_to_host = '', attempts = 0, progress = 0, error = NULL, last_updated_at = ?
+		WHERE task_id = ? AND status NOT IN (?, ?)`,
+		storage.TaskStatusQueued,
+		time.Now().UTC(),
+		taskID,
+		storage.TaskStatusExhausted,
+		storage.TaskStatusFinished,
+	)
+	return convertErr(err)
+}
+
+// claimWorkUnit assigns the next queued work unit of a task to the host
+func (s *SQLBackend) claimWorkUnit(taskID, hostname string) (*storage.WorkUnit, error) {
+	txn, err := s.db.Begin()
+	if err != nil {
+		return nil, convertErr(err)
+	}
+	defer txn.Rollback()
+
+	unit, err := scanWorkUnit(txn.QueryRow(
+		"SELECT "+workUnitColumns+" FROM work_units WHERE task_id = ? AND status = ? ORDER BY idx LIMIT 1",
+		taskID,
+		storage.TaskStatusQueued,
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	unit.Status = storage.TaskStatusDequeued
+	unit.AssignedToHost = hostname
+	unit.Attempts++
+	unit.Progress = 0
+	unit.DispatchedAt = now
+	unit.LastUpdatedAt = now
+
+	if _, err = txn.Exec(
+		"UPDATE work_units SET status = ?, assigned_to_host = ?, attempts = ?, progress = ?, dispatched_at = ?, last_updated_at = ? WHERE unit_id = ?",
+		unit.Status,
+		unit.AssignedToHost,
+		unit.Attempts,
+		unit.Progress,
+		unit.DispatchedAt,
+		unit.LastUpdatedAt,
+		unit.UnitID,
+	); err != nil {
+		return nil, convertErr(err)
+	}
+
+	if err = txn.Commit(); err != nil {
+		return nil, convertErr(err)
+	}
+	return unit, nil
+}
@@ -0,0 +1,156 @@
+package sqldb
+
+import (
+	"testing"
+
+	"github.com/mandiant/gocrack/server/storage"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func createDistributedTask(t *testing.T, db *storageTester, keyspace uint64, numUnits int) *storage.Task {
+	user, err := createTestUser(false, t, db)
+	if err != nil {
+		assert.FailNow(t, "failed to create user document", err.Error())
+	}
+
+	txn, err := db.NewTaskCreateTransaction()
+	if err != nil {
+		assert.FailNow(t, "failed to create task transaction", err.Error())
+	}
+	defer txn.Rollback()
+
+	doc := &storage.Task{
+		TaskID:        uuid.NewString(),
+		TaskName:      "Distributed",
+		CreatedByUUID: user.UserUUID,
+	}
+	units := storage.SplitKeyspace(doc.TaskID, keyspace, numUnits)
+	doc.Keyspace = keyspace
+	doc.WorkUnitCount = len(units)
+
+	if err := txn.CreateTask(doc); err != nil {
+		assert.FailNow(t, "failed to create task", err.Error())
+	}
+
+	if err := txn.CreateWorkUnits(units); err != nil {
+		assert.FailNow(t, "failed to create work units", err.Error())
+	}
+
+	if err := txn.Commit(); err != nil {
+		assert.FailNow(t, "failed to commit task", err.Error())
+	}
+	return doc
+}
+
+func TestWorkUnitsDispatchedToHosts(t *testing.T) {
+	db := initTest(t)
+	defer db.DestroyTest()
+
+	task := createDistributedTask(t, db, 100, 3)
+
+	units, err := db.GetWorkUnits(task.TaskID)
+	assert.Nil(t, err)
+	assert.Len(t, units, 3)
+
+	// every host should get a different unit of the same task
+	for i, hostname := range []string{"host-a", "host-b", "host-c"} {
+		items, err := db.GetPendingTasks(storage.GetPendingTasksRequest{
+			Hostname:        hostname,
+			CheckForNewTask: true,
+		})
+		assert.Nil(t, err)
+		if !assert.Len(t, items, 1) {
+			return
+		}
+
+		assert.Equal(t, task.TaskID, items[0].Payload.(*storage.Task).TaskID)
+		assert.Equal(t, units[i].UnitID, items[0].WorkUnit.UnitID)
+		assert.Equal(t, hostname, items[0].WorkUnit.AssignedToHost)
+		assert.Equal(t, 1, items[0].WorkUnit.Attempts)
+		assert.Equal(t, storage.TaskStatusDequeued, items[0].WorkUnit.Status)
+		_, err = db.ChangeTaskStatus(task.TaskID, storage.TaskStatusChange{Status: storage.TaskStatusRunning})
+		assert.Nil(t, err)
+	}
+
+	// all units have been handed out
+	items, err := db.GetPendingTasks(storage.GetPendingTasksRequest{
+		Hostname:        "host-d",
+		CheckForNewTask: true,
+	})
+	assert.Nil(t, err)
+	assert.Empty(t, items)
+
+	active, err := db.GetActiveWorkUnits()
+	assert.Nil(t, err)
+	assert.Len(t, active, 3)
+
+	// the remaining units should be stopped once the task is finished
+	_, err = db.ChangeTaskStatus(task.TaskID, storage.TaskStatusChange{Status: storage.TaskStatusFinished})
+	assert.Nil(t, err)
+	items, err = db.GetPendingTasks(storage.GetPendingTasksRequest{
+		Hostname:     "host-b",
+		RunningTasks: []string{task.TaskID},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []storage.GetPendingTasksResponseItem{
+		{
+			Type: storage.PendingTaskStatusChange,
+			Payload: storage.PendingTaskStatusChangeItem{
+				TaskID:    task.TaskID,
+				NewStatus: storage.TaskStatusStopping,
+			},
+		},
+	}, items)
+}
+
+func TestWorkUnitsRequeue(t *testing.T) {
+	db := initTest(t)
+	defer db.DestroyTest()
+
+	task := createDistributedTask(t, db, 10, 2)
+	units, err := db.GetWorkUnits(task.TaskID)
+	assert.Nil(t, err)
+
+	errStr := "host went away"
+	units[0].Status = storage.TaskStatusExhausted
+	units[1].Status = storage.TaskStatusError
+	units[1].AssignedToHost = "host-a"
+	units[1].Error = &errStr
+	for _, unit := range units {
+		assert.Nil(t, db.UpdateWorkUnit(unit))
+	}
+
+	unit, err := db.GetWorkUnit(units[1].UnitID)
+	assert.Nil(t, err)
+	assert.Equal(t, errStr, *unit.Error)
+
+	assert.Nil(t, db.RequeueWorkUnits(task.TaskID))
+
+	units, err = db.GetWorkUnits(task.TaskID)
+	assert.Nil(t, err)
+	assert.Equal(t, storage.TaskStatusExhausted, units[0].Status)
+	assert.Equal(t, storage.TaskStatusQueued, units[1].Status)
+	assert.Equal(t, "", units[1].AssignedToHost)
+	assert.Nil(t, units[1].Error)
+
+	assert.Nil(t, db.SetTaskProgress(task.TaskID, 50))
+	found, err := db.GetTaskByID(task.TaskID)
+	assert.Nil(t, err)
+	assert.Equal(t, float64(50), found.Progress)
+
+	_, err = db.DeleteTask(task.TaskID, storage.DeleteTaskOptions{})
+	assert.Nil(t, err)
+	units, err = db.GetWorkUnits(task.TaskID)
+	assert.Nil(t, err)
+	assert.Empty(t, units)
+
+	// units cannot exist without their task
+	err = func() error {
+		txn, _ := db.NewTaskCreateTransaction()
+		defer txn.Rollback()
+		return txn.CreateWorkUnits(storage.SplitKeyspace("missing", 10, 1))
+	}()
+	assert.Equal(t, storage.ErrViolateConstraint, err)
+}
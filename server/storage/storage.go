@@ -30,6 +30,8 @@ func (e StorageError) Error() string {
 // TaskFileTxn describes all the methods needed for a task file transaction
 type TaskFileTxn interface {
 	SaveTaskFile(tf TaskFile) error
+	// SaveTaskFileAccounts saves the accounts parsed from a task file whose hashes are prefixed by a username
+	SaveTaskFileAccounts(accounts []TaskFileAccount) error
 	AddEntitlement(tf TaskFile, userid string) error
 	Rollback() error
 	Commit() error
@@ -46,6 +48,7 @@ type EngineFileTxn interface {
 type CreateTaskTxn interface {
 	CreateTask(t *Task) error
 	GrantEntitlement(userUUID string, t Task) (err error)
+	CreateWorkUnits(units []WorkUnit) error
 	Rollback() error
 	Commit() error
 }
@@ -63,6 +66,12 @@ type GetPendingTasksRequest struct {
 	DevicesInUse    CLDevices
 	RunningTasks    []string
 	CheckForNewTask bool
+	// Engines are the engines the host can run. New tasks of any other engine are not handed to the host.
+	// If nil, the engine of the task is not checked
+	Engines []WorkerCrackEngine
+	// Schedule orders the queued tasks the host can run by when they should be handed out. Tasks left out of the result
+	// are not handed out. If nil, the tasks are handed out by priority and then by when they were created
+	Schedule func(candidates []Task) ([]Task, error)
 }
 
 type PendingTaskStatusChangeItem struct {
@@ -73,6 +82,14 @@ type PendingTaskStatusChangeItem struct {
 type GetPendingTasksResponseItem struct {
 	Type    PendingTaskPayloadType
 	Payload interface{}
+	// WorkUnit is set on a PendingTaskNewRequest when the task is distributed and contains the unit assigned to the host
+	WorkUnit *WorkUnit
+}
+
+// DeleteTaskOptions controls what DeleteTask removes in addition to the task and the documents belonging to it
+type DeleteTaskOptions struct {
+	// RemoveUnusedTaskFile deletes the task file and its entitlements as well if no other task references it
+	RemoveUnusedTaskFile bool
 }
 
 // Backend describes all APIs that a backend storage driver should implement
@@ -88,24 +105,63 @@ type Backend interface {
 	NewTaskFileTransaction() (TaskFileTxn, error)
 	GetTaskFileByID(string) (*TaskFile, error)
 	ListTasksForUser(User) ([]TaskFile, error)
+	// GetTaskFileAccounts returns the accounts of the task file. A file without usernames has no accounts
+	GetTaskFileAccounts(fileID string) ([]TaskFileAccount, error)
 	NewEngineFileTransaction() (EngineFileTxn, error)
 	GetEngineFileByID(storageID string) (*EngineFile, error)
 	GetEngineFilesForUser(User) ([]EngineFile, error)
+	// UpdateEngineFile replaces the metadata of an existing engine file
+	UpdateEngineFile(EngineFile) error
 	DeleteEngineFile(string) error
 	DeleteTaskFile(string) error
 
 	// Task Management APIs
 	NewTaskCreateTransaction() (CreateTaskTxn, error)
 	GetTaskByID(string) (*Task, error)
-	ChangeTaskStatus(string, TaskStatus, *string) error
+	// ChangeTaskStatus sets the status of the task and appends the change to the task's status history
+	ChangeTaskStatus(taskID string, change TaskStatusChange) (*TaskStatusHistoryEntry, error)
+	// GetTaskStatusHistory returns every status change of the task from oldest to newest
+	GetTaskStatusHistory(taskID string) ([]TaskStatusHistoryEntry, error)
 	TasksSearch(page, limit int, orderby, searchQuery string, isAscending bool, user User) (*SearchResults, error)
+	// GetTasksByCaseCode returns the tasks with the case code that the user is entitled to, oldest first
+	GetTasksByCaseCode(caseCode string, user User) ([]Task, error)
 	SaveCrackedHash(taskid, hash, value string, crackedAt time.Time) error
 	GetCrackedPasswords(string) (*[]CrackedHash, error)
 	GetPendingTasks(GetPendingTasksRequest) ([]GetPendingTasksResponseItem, error)
 	UpdateTask(string, ModifiableTaskRequest) error
 	SaveTaskCheckpoint(CheckpointFile) error
 	GetTaskCheckpoint(string) ([]byte, error)
-	DeleteTask(string) error
+	// DeleteTask removes the task along with its work units, cracked & known hashes, checkpoint, status history, entitlements and audit log entries
+	// within a single transaction. If the task file was removed as well, it's returned so the caller can delete it from disk
+	DeleteTask(taskID string, opts DeleteTaskOptions) (removedFile *TaskFile, err error)
+	SetTaskProgress(taskID string, progress float64) error
+	// SetTaskStoppedByWindow records whether the task was stopped because its execution windows closed
+	SetTaskStoppedByWindow(taskID string, stopped bool) error
+	// SetTaskPreempted records whether the task is being stopped so that a task of a higher priority can use its devices
+	SetTaskPreempted(taskID string, preempted bool) error
+	// GetTasksByStatus returns every task with one of the statuses ordered by when they were created
+	GetTasksByStatus(statuses ...TaskStatus) ([]Task, error)
+	// GetWindowedTasks returns the tasks with execution windows that are being processed or were stopped by them
+	GetWindowedTasks() ([]Task, error)
+
+	// Known Hash Index APIs
+	// SaveKnownHash adds the hash to the index. A hash that is already known keeps the task that cracked it first
+	SaveKnownHash(KnownHash) error
+	// LookupKnownHashes returns the entries of the index that match any of the hashes of the type. Unless the user is an
+	// admin, only the hashes cracked by tasks the user is entitled to are returned so the index never reveals a password
+	// the user could not already see
+	LookupKnownHashes(user User, hashType int, hashes []string) ([]KnownHash, error)
+	// CreditKnownHashes saves the known hashes as cracked passwords of the task. Hashes the task has already cracked are skipped
+	CreditKnownHashes(taskID string, hashes []KnownHash) (credited int, err error)
+
+	// Distributed Task APIs
+	GetWorkUnits(taskID string) ([]WorkUnit, error)
+	GetWorkUnit(unitID string) (*WorkUnit, error)
+	UpdateWorkUnit(WorkUnit) error
+	// GetActiveWorkUnits returns every work unit that has been dispatched to a worker and has not stopped
+	GetActiveWorkUnits() ([]WorkUnit, error)
+	// RequeueWorkUnits queues every unit of a task that has not finished or exhausted its keyspace
+	RequeueWorkUnits(taskID string) error
 
 	// Rights Management APIs
 	CheckEntitlement(userUUID, entityID string, entType EntitlementType) (bool, error)
@@ -122,4 +178,36 @@ type Backend interface {
 	GetUserByID(userUUID string) (user *User, err error)
 	GetUsers() ([]User, error)
 	EditUser(string, UserModifyRequest) error
+
+	// Password Policy APIs
+	// SavePasswordPolicy creates the policy or replaces the one with the same PolicyID. Names must be unique
+	SavePasswordPolicy(PasswordPolicy) error
+	GetPasswordPolicy(policyID string) (*PasswordPolicy, error)
+	// GetPasswordPolicies returns every policy ordered by name
+	GetPasswordPolicies() ([]PasswordPolicy, error)
+	DeletePasswordPolicy(policyID string) error
+
+	// Cracked Wordlist APIs
+	// SaveCrackedWordlist creates the wordlist or replaces the one of the same engine file. It is removed along with the engine file
+	SaveCrackedWordlist(CrackedWordlist) error
+	GetCrackedWordlist(fileID string) (*CrackedWordlist, error)
+	// GetAutoUpdatedWordlists returns the wordlists that are rebuilt when their tasks crack a password
+	GetAutoUpdatedWordlists() ([]CrackedWordlist, error)
+
+	// Pipeline APIs
+	// SavePipeline creates the pipeline or replaces the one with the same ID
+	SavePipeline(Pipeline) error
+	GetPipeline(pipelineID string) (*Pipeline, error)
+	// GetPipelines returns every pipeline ordered by the time it was created
+	GetPipelines() ([]Pipeline, error)
+
+	// Task Template APIs
+	// SaveTaskTemplate creates the template or replaces the one with the same ID
+	SaveTaskTemplate(TaskTemplate) error
+	GetTaskTemplate(templateID string) (*TaskTemplate, error)
+	// GetTaskTemplatesForUser returns the templates that are shared or that the user is entitled to ordered by name.
+	// Super users get every template
+	GetTaskTemplatesForUser(user User) ([]TaskTemplate, error)
+	// DeleteTaskTemplate removes the template along with its entitlements
+	DeleteTaskTemplate(templateID string) error
 }
@@ -2,6 +2,7 @@ package storage
 
 import (
 	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -13,3 +14,61 @@ func TestStorageError(t *testing.T) {
 	}
 	assert.Equal(t, "get to the chopper!", customError.Error())
 }
+
+func TestSplitKeyspace(t *testing.T) {
+	for i, test := range []struct {
+		Keyspace uint64
+		N        int
+		Expected [][2]uint64
+	}{
+		{Keyspace: 10, N: 3, Expected: [][2]uint64{{0, 4}, {4, 4}, {8, 2}}},
+		{Keyspace: 9, N: 3, Expected: [][2]uint64{{0, 3}, {3, 3}, {6, 3}}},
+		{Keyspace: 2, N: 5, Expected: [][2]uint64{{0, 1}, {1, 1}}},
+		{Keyspace: 0, N: 5, Expected: [][2]uint64{}},
+		{Keyspace: 10, N: 0, Expected: [][2]uint64{}},
+	} {
+		units := SplitKeyspace("task", test.Keyspace, test.N)
+		ranges := make([][2]uint64, 0)
+		for idx, unit := range units {
+			assert.Equal(t, idx, unit.Index, "test %d", i)
+			assert.Equal(t, fmt.Sprintf("task-%d", idx), unit.UnitID, "test %d", i)
+			assert.Equal(t, TaskStatusQueued, unit.Status, "test %d", i)
+			ranges = append(ranges, [2]uint64{unit.Skip, unit.Limit})
+		}
+		assert.Equal(t, test.Expected, ranges, "test %d", i)
+	}
+}
+
+func TestWorkUnitSummary(t *testing.T) {
+	units := []WorkUnit{
+		{Limit: 50, Status: TaskStatusExhausted},
+		{Limit: 30, Status: TaskStatusRunning, Progress: 50},
+		{Limit: 20, Status: TaskStatusQueued},
+	}
+
+	summary := SummarizeWorkUnits(100, units)
+	assert.Equal(t, WorkUnitSummary{Total: 3, Queued: 1, Active: 1, Exhausted: 1, Progress: 65}, summary)
+
+	for i, test := range []struct {
+		Statuses []TaskStatus
+		Current  TaskStatus
+		Expected TaskStatus
+	}{
+		{[]TaskStatus{TaskStatusQueued, TaskStatusQueued}, TaskStatusQueued, TaskStatusQueued},
+		{[]TaskStatus{TaskStatusRunning, TaskStatusQueued}, TaskStatusQueued, TaskStatusRunning},
+		{[]TaskStatus{TaskStatusExhausted, TaskStatusQueued}, TaskStatusRunning, TaskStatusRunning},
+		{[]TaskStatus{TaskStatusExhausted, TaskStatusExhausted}, TaskStatusRunning, TaskStatusExhausted},
+		{[]TaskStatus{TaskStatusFinished, TaskStatusRunning}, TaskStatusRunning, TaskStatusFinished},
+		{[]TaskStatus{TaskStatusStopping, TaskStatusStopped}, TaskStatusStopping, TaskStatusStopping},
+		{[]TaskStatus{TaskStatusStopped, TaskStatusQueued}, TaskStatusStopping, TaskStatusStopped},
+		{[]TaskStatus{TaskStatusError, TaskStatusExhausted}, TaskStatusRunning, TaskStatusError},
+		{[]TaskStatus{TaskStatusOutOfTime, TaskStatusRunning}, TaskStatusRunning, TaskStatusRunning},
+		{[]TaskStatus{TaskStatusOutOfTime, TaskStatusQueued}, TaskStatusRunning, TaskStatusOutOfTime},
+	} {
+		units := make([]WorkUnit, len(test.Statuses))
+		for idx, status := range test.Statuses {
+			units[idx] = WorkUnit{Limit: 1, Status: status}
+		}
+		assert.Equal(t, test.Expected, SummarizeWorkUnits(uint64(len(units)), units).TaskStatus(test.Current), "test %d", i)
+	}
+}
@@ -0,0 +1,85 @@
+package storagetest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mandiant/gocrack/server/storage"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func testUpdateEngineFile(t *testing.T, stor storage.Backend) {
+	user := createUser(t, stor, false)
+	ef := createEngineFile(t, stor, user, false)
+
+	ef.FileSize = 4096
+	ef.NumberOfEntries = 512
+	ef.SHA1Hash = "da39a3ee5e6b4b0d3255bfef95601890afd80709"
+	ef.LastUpdatedAt = time.Now().UTC().Truncate(time.Second)
+	assert.Nil(t, stor.UpdateEngineFile(ef))
+
+	found, err := stor.GetEngineFileByID(ef.FileID)
+	if assert.Nil(t, err) {
+		assert.Equal(t, ef.FileSize, found.FileSize)
+		assert.Equal(t, ef.NumberOfEntries, found.NumberOfEntries)
+		assert.Equal(t, ef.SHA1Hash, found.SHA1Hash)
+		assert.True(t, ef.LastUpdatedAt.Equal(found.LastUpdatedAt))
+		assert.Equal(t, ef.UploadedByUUID, found.UploadedByUUID)
+	}
+
+	assert.Equal(t, storage.ErrNotFound, stor.UpdateEngineFile(storage.EngineFile{FileID: uuid.NewString()}))
+}
+
+func testCrackedWordlists(t *testing.T, stor storage.Backend) {
+	user := createUser(t, stor, false)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	byTask := storage.CrackedWordlist{
+		FileID:        createEngineFile(t, stor, user, false).FileID,
+		TaskIDs:       []string{uuid.NewString(), uuid.NewString()},
+		CreatedByUUID: user.UserUUID,
+		CreatedAt:     now,
+		LastBuiltAt:   now,
+	}
+	assert.Nil(t, stor.SaveCrackedWordlist(byTask))
+
+	byCase := storage.CrackedWordlist{
+		FileID:        createEngineFile(t, stor, user, false).FileID,
+		CaseCode:      "CASE-1",
+		AutoUpdate:    true,
+		CreatedByUUID: user.UserUUID,
+		CreatedAt:     now,
+		LastBuiltAt:   now,
+	}
+	assert.Nil(t, stor.SaveCrackedWordlist(byCase))
+
+	found, err := stor.GetCrackedWordlist(byTask.FileID)
+	if assert.Nil(t, err) {
+		assert.Equal(t, byTask.TaskIDs, found.TaskIDs)
+		assert.False(t, found.AutoUpdate)
+		assert.True(t, now.Equal(found.LastBuiltAt))
+	}
+
+	wls, err := stor.GetAutoUpdatedWordlists()
+	if assert.Nil(t, err) && assert.Len(t, wls, 1) {
+		assert.Equal(t, byCase.FileID, wls[0].FileID)
+		assert.Equal(t, "CASE-1", wls[0].CaseCode)
+	}
+
+	// saving the wordlist of the same file replaces it
+	byTask.AutoUpdate = true
+	byTask.LastBuiltAt = now.Add(time.Minute)
+	assert.Nil(t, stor.SaveCrackedWordlist(byTask))
+
+	wls, err = stor.GetAutoUpdatedWordlists()
+	if assert.Nil(t, err) {
+		assert.Len(t, wls, 2)
+	}
+
+	// the wordlist is removed along with its engine file
+	assert.Nil(t, stor.DeleteEngineFile(byTask.FileID))
+	_, err = stor.GetCrackedWordlist(byTask.FileID)
+	assert.Equal(t, storage.ErrNotFound, err)
+}
@@ -0,0 +1,105 @@
+package storagetest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mandiant/gocrack/server/storage"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testEntitlements(t *testing.T, stor storage.Backend) {
+	owner := createUser(t, stor, false)
+	user := createUser(t, stor, false)
+	task := &storage.Task{TaskName: "Entitled"}
+	createTasks(t, stor, owner, task)
+
+	entitled, err := stor.CheckEntitlement(user.UserUUID, task.TaskID, storage.EntitlementTask)
+	assert.Nil(t, err)
+	assert.False(t, entitled)
+
+	// granting an entitlement twice is a no-op
+	assert.Nil(t, stor.GrantEntitlement(*user, *task))
+	assert.Nil(t, stor.GrantEntitlement(*user, *task))
+
+	entitled, err = stor.CheckEntitlement(user.UserUUID, task.TaskID, storage.EntitlementTask)
+	assert.Nil(t, err)
+	assert.True(t, entitled)
+
+	// entitlements are scoped to the type of document
+	entitled, err = stor.CheckEntitlement(user.UserUUID, task.TaskID, storage.EntitlementTaskFile)
+	assert.Nil(t, err)
+	assert.False(t, entitled)
+
+	ents, err := stor.GetEntitlementsForTask(task.TaskID)
+	if assert.Nil(t, err) && assert.Len(t, ents, 2) {
+		users := make([]string, len(ents))
+		for i, ent := range ents {
+			users[i] = ent.UserUUID
+			assert.Equal(t, task.TaskID, ent.EntitledID)
+			assert.WithinDuration(t, time.Now().UTC(), ent.GrantedAccessAt, 5*time.Second)
+		}
+		assert.ElementsMatch(t, []string{owner.UserUUID, user.UserUUID}, users)
+	}
+
+	assert.NotNil(t, stor.GrantEntitlement(storage.User{}, *task), "granting an entitlement requires a user")
+	assert.NotNil(t, stor.GrantEntitlement(*user, "not a document"))
+
+	assert.Nil(t, stor.RevokeEntitlement(*user, *task))
+	entitled, err = stor.CheckEntitlement(user.UserUUID, task.TaskID, storage.EntitlementTask)
+	assert.Nil(t, err)
+	assert.False(t, entitled)
+
+	// revoking an entitlement the user does not have is not an error
+	assert.Nil(t, stor.RevokeEntitlement(*user, *task))
+
+	assert.Nil(t, stor.GrantEntitlement(*user, *task))
+	assert.Nil(t, stor.RemoveEntitlements(task.TaskID, storage.EntitlementTask))
+	ents, err = stor.GetEntitlementsForTask(task.TaskID)
+	assert.Nil(t, err)
+	assert.Empty(t, ents)
+
+	_, err = stor.CheckEntitlement(user.UserUUID, task.TaskID, storage.EntitlementType(255))
+	assert.NotNil(t, err)
+}
+
+func testActivityLog(t *testing.T, stor storage.Backend) {
+	user := createUser(t, stor, false)
+	task := &storage.Task{TaskName: "Audited"}
+	other := &storage.Task{TaskName: "Other"}
+	createTasks(t, stor, user, task, other)
+
+	entries, err := stor.GetActivityLog(task.TaskID)
+	assert.Nil(t, err)
+	assert.Empty(t, entries)
+
+	now := time.Now().UTC()
+	for _, entry := range []storage.ActivityLogEntry{
+		{OccuredAt: now, UserUUID: user.UserUUID, EntityID: task.TaskID, Type: storage.ActivityViewTask, StatusCode: 200},
+		{OccuredAt: now.Add(-time.Minute), UserUUID: user.UserUUID, EntityID: task.TaskID, Type: storage.ActivityCreatedTask, StatusCode: 201},
+		{OccuredAt: now, UserUUID: user.UserUUID, EntityID: other.TaskID, Type: storage.ActivityViewTask},
+	} {
+		assert.Nil(t, stor.LogActivity(entry))
+	}
+
+	// entries are returned oldest first with the username filled in
+	entries, err = stor.GetActivityLog(task.TaskID)
+	if assert.Nil(t, err) && assert.Len(t, entries, 2) {
+		assert.Equal(t, storage.ActivityCreatedTask, entries[0].Type)
+		assert.Equal(t, 201, entries[0].StatusCode)
+		assert.Equal(t, storage.ActivityViewTask, entries[1].Type)
+		for _, entry := range entries {
+			assert.Equal(t, user.Username, entry.Username)
+		}
+	}
+
+	assert.Nil(t, stor.RemoveActivityEntries(task.TaskID))
+	entries, err = stor.GetActivityLog(task.TaskID)
+	assert.Nil(t, err)
+	assert.Empty(t, entries)
+
+	entries, err = stor.GetActivityLog(other.TaskID)
+	assert.Nil(t, err)
+	assert.Len(t, entries, 1)
+}
@@ -0,0 +1,244 @@
+package storagetest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mandiant/gocrack/server/storage"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func testTaskFileTransaction(t *testing.T, stor storage.Backend) {
+	user := createUser(t, stor, false)
+
+	tf := createTaskFile(t, stor, user, time.Now().UTC())
+	found, err := stor.GetTaskFileByID(tf.FileID)
+	if assert.Nil(t, err) {
+		assert.Equal(t, tf.FileName, found.FileName)
+		assert.Equal(t, tf.SavedAt, found.SavedAt)
+		assert.Equal(t, tf.NumberOfPasswords, found.NumberOfPasswords)
+		assert.Equal(t, user.Username, found.UploadedBy, "the uploader's username must be filled in from UploadedByUUID")
+	}
+
+	entitled, err := stor.CheckEntitlement(user.UserUUID, tf.FileID, storage.EntitlementTaskFile)
+	assert.Nil(t, err)
+	assert.True(t, entitled)
+
+	// nothing is saved when the transaction is rolled back
+	txn, err := stor.NewTaskFileTransaction()
+	if err != nil {
+		assert.FailNow(t, "failed to create task file transaction", err.Error())
+	}
+
+	rolledBack := storage.TaskFile{FileID: uuid.NewString(), UploadedByUUID: user.UserUUID, UploadedAt: time.Now().UTC()}
+	assert.Nil(t, txn.SaveTaskFile(rolledBack))
+	assert.Nil(t, txn.AddEntitlement(rolledBack, user.UserUUID))
+	assert.Nil(t, txn.Rollback())
+
+	_, err = stor.GetTaskFileByID(rolledBack.FileID)
+	assert.Equal(t, storage.ErrNotFound, err)
+
+	entitled, err = stor.CheckEntitlement(user.UserUUID, rolledBack.FileID, storage.EntitlementTaskFile)
+	assert.Nil(t, err)
+	assert.False(t, entitled)
+
+	assert.Nil(t, stor.DeleteTaskFile(tf.FileID))
+	_, err = stor.GetTaskFileByID(tf.FileID)
+	assert.Equal(t, storage.ErrNotFound, err)
+	assert.Equal(t, storage.ErrNotFound, stor.DeleteTaskFile(tf.FileID))
+}
+
+func testTaskFileAccounts(t *testing.T, stor storage.Backend) {
+	user := createUser(t, stor, false)
+
+	tf := storage.TaskFile{
+		FileID:         uuid.NewString(),
+		UploadedByUUID: user.UserUUID,
+		UploadedAt:     time.Now().UTC(),
+		Format:         storage.TaskFileFormatPwdump,
+	}
+	accounts := []storage.TaskFileAccount{
+		{FileID: tf.FileID, Username: "Administrator", Hash: "8846f7eaee8fb117ad06bdd830b7586c"},
+		{FileID: tf.FileID, Username: "Guest", Hash: "31d6cfe0d16ae931b73c59d7e0c089c0"},
+		{FileID: tf.FileID, Username: "backup", Hash: "8846f7eaee8fb117ad06bdd830b7586c"},
+	}
+
+	txn, err := stor.NewTaskFileTransaction()
+	if err != nil {
+		assert.FailNow(t, "failed to create task file transaction", err.Error())
+	}
+	assert.Nil(t, txn.SaveTaskFile(tf))
+	assert.Nil(t, txn.SaveTaskFileAccounts(accounts))
+	assert.Nil(t, txn.Commit())
+
+	found, err := stor.GetTaskFileByID(tf.FileID)
+	if assert.Nil(t, err) {
+		assert.Equal(t, storage.TaskFileFormatPwdump, found.Format)
+	}
+
+	saved, err := stor.GetTaskFileAccounts(tf.FileID)
+	if assert.Nil(t, err) {
+		assert.ElementsMatch(t, accounts, saved)
+	}
+
+	saved, err = stor.GetTaskFileAccounts(uuid.NewString())
+	assert.Nil(t, err)
+	assert.Empty(t, saved)
+
+	// the accounts are removed along with their file
+	assert.Nil(t, stor.DeleteTaskFile(tf.FileID))
+	saved, err = stor.GetTaskFileAccounts(tf.FileID)
+	assert.Nil(t, err)
+	assert.Empty(t, saved)
+}
+
+func testListTasksForUser(t *testing.T, stor storage.Backend) {
+	admin := createUser(t, stor, true)
+	user := createUser(t, stor, false)
+	other := createUser(t, stor, false)
+
+	now := time.Now().UTC()
+	older := createTaskFile(t, stor, user, now.Add(-time.Hour))
+	newer := createTaskFile(t, stor, user, now)
+	otherFile := createTaskFile(t, stor, other, now.Add(-time.Minute))
+
+	fileIDs := func(files []storage.TaskFile) []string {
+		out := make([]string, len(files))
+		for i, file := range files {
+			out[i] = file.FileID
+		}
+		return out
+	}
+
+	// files are ordered by when they were uploaded, newest first
+	files, err := stor.ListTasksForUser(*user)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{newer.FileID, older.FileID}, fileIDs(files))
+
+	files, err = stor.ListTasksForUser(*admin)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{newer.FileID, otherFile.FileID, older.FileID}, fileIDs(files))
+
+	assert.Nil(t, stor.GrantEntitlement(*user, otherFile))
+	files, err = stor.ListTasksForUser(*user)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{newer.FileID, otherFile.FileID, older.FileID}, fileIDs(files))
+
+	files, err = stor.ListTasksForUser(*createUser(t, stor, false))
+	assert.Nil(t, err)
+	assert.Empty(t, files)
+}
+
+// createEngineFile saves an engine file uploaded by the user and fails the test if it could not be created
+func createEngineFile(t *testing.T, stor storage.Backend, uploader *storage.User, shared bool) storage.EngineFile {
+	ef := storage.EngineFile{
+		FileID:          uuid.NewString(),
+		FileName:        "words.txt",
+		FileSize:        2048,
+		UploadedByUUID:  uploader.UserUUID,
+		FileType:        storage.EngineFileDictionary,
+		NumberOfEntries: 100,
+		IsShared:        shared,
+		SHA1Hash:        "da39a3ee5e6b4b0d3255bfef95601890afd80709",
+		SavedAt:         "/tmp/" + uuid.NewString(),
+	}
+
+	txn, err := stor.NewEngineFileTransaction()
+	if err != nil {
+		assert.FailNow(t, "failed to create engine file transaction", err.Error())
+	}
+
+	if err := txn.SaveEngineFile(ef); err != nil {
+		txn.Rollback()
+		assert.FailNow(t, "failed to save engine file", err.Error())
+	}
+
+	if err := txn.AddEntitlement(ef, uploader.UserUUID); err != nil {
+		txn.Rollback()
+		assert.FailNow(t, "failed to entitle engine file", err.Error())
+	}
+
+	if err := txn.Commit(); err != nil {
+		assert.FailNow(t, "failed to commit engine file transaction", err.Error())
+	}
+	return ef
+}
+
+func testEngineFileTransaction(t *testing.T, stor storage.Backend) {
+	user := createUser(t, stor, false)
+
+	ef := createEngineFile(t, stor, user, false)
+	found, err := stor.GetEngineFileByID(ef.FileID)
+	if assert.Nil(t, err) {
+		assert.Equal(t, ef.FileName, found.FileName)
+		assert.Equal(t, ef.FileType, found.FileType)
+		assert.Equal(t, ef.NumberOfEntries, found.NumberOfEntries)
+		assert.Equal(t, user.Username, found.UploadedBy, "the uploader's username must be filled in from UploadedByUUID")
+		assert.False(t, found.UploadedAt.IsZero(), "UploadedAt must default to the time the file was saved")
+		assert.False(t, found.LastUpdatedAt.IsZero(), "LastUpdatedAt must default to the time the file was saved")
+	}
+
+	entitled, err := stor.CheckEntitlement(user.UserUUID, ef.FileID, storage.EntitlementEngineFile)
+	assert.Nil(t, err)
+	assert.True(t, entitled)
+
+	txn, err := stor.NewEngineFileTransaction()
+	if err != nil {
+		assert.FailNow(t, "failed to create engine file transaction", err.Error())
+	}
+
+	rolledBack := storage.EngineFile{FileID: uuid.NewString(), UploadedByUUID: user.UserUUID}
+	assert.Nil(t, txn.SaveEngineFile(rolledBack))
+	assert.Nil(t, txn.Rollback())
+
+	_, err = stor.GetEngineFileByID(rolledBack.FileID)
+	assert.Equal(t, storage.ErrNotFound, err)
+
+	assert.Nil(t, stor.DeleteEngineFile(ef.FileID))
+	_, err = stor.GetEngineFileByID(ef.FileID)
+	assert.Equal(t, storage.ErrNotFound, err)
+	assert.Equal(t, storage.ErrNotFound, stor.DeleteEngineFile(ef.FileID))
+}
+
+func testGetEngineFilesForUser(t *testing.T, stor storage.Backend) {
+	admin := createUser(t, stor, true)
+	user := createUser(t, stor, false)
+	other := createUser(t, stor, false)
+
+	files, err := stor.GetEngineFilesForUser(*user)
+	assert.Nil(t, err)
+	assert.Empty(t, files)
+
+	own := createEngineFile(t, stor, user, false)
+	shared := createEngineFile(t, stor, other, true)
+	private := createEngineFile(t, stor, other, false)
+
+	fileIDs := func(files []storage.EngineFile) []string {
+		out := make([]string, len(files))
+		for i, file := range files {
+			out[i] = file.FileID
+		}
+		return out
+	}
+
+	// non admins see shared files and the files they are entitled to
+	files, err = stor.GetEngineFilesForUser(*user)
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{own.FileID, shared.FileID}, fileIDs(files))
+
+	files, err = stor.GetEngineFilesForUser(*admin)
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{own.FileID, shared.FileID, private.FileID}, fileIDs(files))
+
+	assert.Nil(t, stor.GrantEntitlement(*user, private))
+	files, err = stor.GetEngineFilesForUser(*user)
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{own.FileID, shared.FileID, private.FileID}, fileIDs(files))
+
+	assert.Nil(t, stor.RevokeEntitlement(*user, private))
+	files, err = stor.GetEngineFilesForUser(*user)
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{own.FileID, shared.FileID}, fileIDs(files))
+}
@@ -0,0 +1,179 @@
+package storagetest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mandiant/gocrack/server/storage"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// RunGarbageCollection tests the storage.GarbageCollector of a backend. The database opened by open must allow documents
+// that reference tasks & files that do not exist (e.g. by disabling foreign keys) so the orphans can be created
+func RunGarbageCollection(t *testing.T, open OpenFunc) {
+	stor, cleanup := open(t)
+	defer cleanup()
+
+	gc, ok := stor.(storage.GarbageCollector)
+	if !ok {
+		assert.FailNow(t, "backend does not implement storage.GarbageCollector")
+	}
+
+	user := createUser(t, stor, false)
+	tf := createTaskFile(t, stor, user, time.Now().UTC())
+	ef := createEngineFile(t, stor, user, false)
+	task := &storage.Task{TaskName: "Kept", FileID: tf.FileID, WorkUnitCount: 2, Keyspace: 10}
+	createTasks(t, stor, user, task)
+
+	missingTask := storage.Task{TaskID: uuid.NewString()}
+	saveTaskDocuments := func(taskID string) {
+		txn, err := stor.NewTaskCreateTransaction()
+		if err != nil {
+			assert.FailNow(t, "failed to create task transaction", err.Error())
+		}
+		assert.Nil(t, txn.CreateWorkUnits(storage.SplitKeyspace(taskID, 10, 2)))
+		assert.Nil(t, txn.Commit())
+
+		assert.Nil(t, stor.SaveCrackedHash(taskID, "5f4dcc3b5aa765d61d8327deb882cf99", "password", time.Now().UTC()))
+		assert.Nil(t, stor.SaveCrackedHash(taskID, "e10adc3949ba59abbe56e057f20f883e", "123456", time.Now().UTC()))
+		assert.Nil(t, stor.SaveTaskCheckpoint(storage.CheckpointFile{TaskID: taskID, Data: []byte("checkpoint")}))
+		assert.Nil(t, stor.LogActivity(storage.ActivityLogEntry{OccuredAt: time.Now().UTC(), UserUUID: user.UserUUID, EntityID: taskID, Type: storage.ActivityViewTask}))
+		assert.Nil(t, stor.SaveKnownHash(storage.KnownHash{HashType: 0, Hash: taskID, Value: "password", CrackedAt: time.Now().UTC(), TaskID: taskID}))
+	}
+	saveTaskDocuments(task.TaskID)
+	saveTaskDocuments(missingTask.TaskID)
+	changeStatus(t, stor, task.TaskID, storage.TaskStatusRunning)
+
+	assert.Nil(t, stor.GrantEntitlement(*user, missingTask))
+	assert.Nil(t, stor.GrantEntitlement(*user, storage.TaskFile{FileID: uuid.NewString()}))
+	assert.Nil(t, stor.GrantEntitlement(*user, storage.EngineFile{FileID: uuid.NewString()}))
+	// the status of a task that does not exist can't be changed and accounts are only saved with their file so the
+	// orphans are restored from an export
+	if imp, ok := stor.(storage.Importer); assert.True(t, ok, "backend does not implement storage.Importer") {
+		txn, err := imp.NewImportTransaction()
+		if err != nil {
+			assert.FailNow(t, "failed to create import transaction", err.Error())
+		}
+		assert.Nil(t, txn.Import(storage.Record{Type: storage.RecordTaskStatus, Value: storage.TaskStatusHistoryEntry{
+			TaskID:           missingTask.TaskID,
+			ChangedAt:        time.Now().UTC(),
+			PreviousStatus:   storage.TaskStatusQueued,
+			TaskStatusChange: storage.TaskStatusChange{Status: storage.TaskStatusRunning, ActorType: storage.StatusActorWorker, Actor: "host-a"},
+		}}))
+		assert.Nil(t, txn.Import(storage.Record{Type: storage.RecordTaskFileAccount, Value: storage.TaskFileAccount{
+			FileID:   uuid.NewString(),
+			Username: "Administrator",
+			Hash:     "8846f7eaee8fb117ad06bdd830b7586c",
+		}}))
+		assert.Nil(t, txn.Commit())
+	}
+	// logins are not taken against a task
+	assert.Nil(t, stor.LogActivity(storage.ActivityLogEntry{OccuredAt: time.Now().UTC(), UserUUID: user.UserUUID, EntityID: user.UserUUID, Type: storage.ActivtyLogin}))
+
+	expected := map[storage.RecordType]int{
+		storage.RecordWorkUnit:        2,
+		storage.RecordCrackedHash:     2,
+		storage.RecordCheckpoint:      1,
+		storage.RecordEntitlement:     3,
+		storage.RecordAuditLog:        1,
+		storage.RecordTaskStatus:      1,
+		storage.RecordKnownHash:       1,
+		storage.RecordTaskFileAccount: 1,
+	}
+
+	report, err := gc.CollectGarbage(true)
+	if assert.Nil(t, err) {
+		assert.True(t, report.DryRun)
+		assert.Equal(t, expected, withoutZeros(report.Orphans))
+		assert.Equal(t, 12, report.Total())
+	}
+
+	// a dry run leaves the orphans alone
+	cracked, err := stor.GetCrackedPasswords(missingTask.TaskID)
+	if assert.Nil(t, err) {
+		assert.Len(t, *cracked, 2)
+	}
+
+	report, err = gc.CollectGarbage(false)
+	if assert.Nil(t, err) {
+		assert.False(t, report.DryRun)
+		assert.Equal(t, expected, withoutZeros(report.Orphans))
+	}
+
+	report, err = gc.CollectGarbage(true)
+	if assert.Nil(t, err) {
+		assert.Equal(t, 0, report.Total())
+	}
+
+	cracked, err = stor.GetCrackedPasswords(missingTask.TaskID)
+	if assert.Nil(t, err) {
+		assert.Empty(t, *cracked)
+	}
+
+	units, err := stor.GetWorkUnits(missingTask.TaskID)
+	assert.Nil(t, err)
+	assert.Empty(t, units)
+
+	_, err = stor.GetTaskCheckpoint(missingTask.TaskID)
+	assert.Equal(t, storage.ErrNotFound, err)
+
+	history, err := stor.GetTaskStatusHistory(missingTask.TaskID)
+	assert.Nil(t, err)
+	assert.Empty(t, history)
+
+	// documents belonging to existing tasks & files are kept
+	cracked, err = stor.GetCrackedPasswords(task.TaskID)
+	if assert.Nil(t, err) {
+		assert.Len(t, *cracked, 2)
+	}
+
+	units, err = stor.GetWorkUnits(task.TaskID)
+	assert.Nil(t, err)
+	assert.Len(t, units, 2)
+
+	_, err = stor.GetTaskCheckpoint(task.TaskID)
+	assert.Nil(t, err)
+
+	history, err = stor.GetTaskStatusHistory(task.TaskID)
+	assert.Nil(t, err)
+	assert.Len(t, history, 1)
+
+	known, err := stor.LookupKnownHashes(*user, 0, []string{task.TaskID, missingTask.TaskID})
+	if assert.Nil(t, err) && assert.Len(t, known, 1) {
+		assert.Equal(t, task.TaskID, known[0].TaskID)
+	}
+
+	entries, err := stor.GetActivityLog(task.TaskID)
+	assert.Nil(t, err)
+	assert.Len(t, entries, 1)
+
+	entries, err = stor.GetActivityLog(user.UserUUID)
+	assert.Nil(t, err)
+	assert.Len(t, entries, 1)
+
+	for _, check := range []struct {
+		id      string
+		entType storage.EntitlementType
+	}{
+		{task.TaskID, storage.EntitlementTask},
+		{tf.FileID, storage.EntitlementTaskFile},
+		{ef.FileID, storage.EntitlementEngineFile},
+	} {
+		entitled, err := stor.CheckEntitlement(user.UserUUID, check.id, check.entType)
+		assert.Nil(t, err)
+		assert.True(t, entitled)
+	}
+}
+
+// withoutZeros removes the record types without any orphans so reports from different backends can be compared
+func withoutZeros(orphans map[storage.RecordType]int) map[storage.RecordType]int {
+	out := make(map[storage.RecordType]int)
+	for rt, n := range orphans {
+		if n > 0 {
+			out[rt] = n
+		}
+	}
+	return out
+}
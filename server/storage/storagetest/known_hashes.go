@@ -0,0 +1,154 @@
+package storagetest
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mandiant/gocrack/server/storage"
+	"github.com/mandiant/gocrack/shared"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func testKnownHashes(t *testing.T, stor storage.Backend) {
+	user := createUser(t, stor, false)
+	first := &storage.Task{TaskName: "First"}
+	second := &storage.Task{TaskName: "Second"}
+	reusing := &storage.Task{TaskName: "Reusing"}
+	createTasks(t, stor, user, first, second, reusing)
+
+	crackedAt := time.Now().UTC()
+	assert.Nil(t, stor.SaveKnownHash(storage.KnownHash{HashType: 1000, Hash: "8846f7eaee8fb117ad06bdd830b7586c", Value: "password", CrackedAt: crackedAt, TaskID: first.TaskID}))
+	// the task that cracked the hash first is kept
+	assert.Nil(t, stor.SaveKnownHash(storage.KnownHash{HashType: 1000, Hash: "8846f7eaee8fb117ad06bdd830b7586c", Value: "password", CrackedAt: crackedAt, TaskID: second.TaskID}))
+	// the same hash of a different type is a different entry
+	assert.Nil(t, stor.SaveKnownHash(storage.KnownHash{HashType: 0, Hash: "8846f7eaee8fb117ad06bdd830b7586c", Value: "other", CrackedAt: crackedAt, TaskID: second.TaskID}))
+	assert.Nil(t, stor.SaveKnownHash(storage.KnownHash{HashType: 1000, Hash: "32ed87bdb5fdc5e9cba88547376818d4", Value: "123456", CrackedAt: crackedAt, TaskID: second.TaskID}))
+
+	known, err := stor.LookupKnownHashes(*user, 1000, []string{"8846f7eaee8fb117ad06bdd830b7586c", "32ed87bdb5fdc5e9cba88547376818d4", "31d6cfe0d16ae931b73c59d7e0c089c0"})
+	if assert.Nil(t, err) && assert.Len(t, known, 2) {
+		byHash := map[string]storage.KnownHash{}
+		for _, kh := range known {
+			byHash[kh.Hash] = kh
+		}
+		assert.Equal(t, first.TaskID, byHash["8846f7eaee8fb117ad06bdd830b7586c"].TaskID)
+		assert.Equal(t, "password", byHash["8846f7eaee8fb117ad06bdd830b7586c"].Value)
+		assert.Equal(t, 1000, byHash["8846f7eaee8fb117ad06bdd830b7586c"].HashType)
+		assert.WithinDuration(t, crackedAt, byHash["8846f7eaee8fb117ad06bdd830b7586c"].CrackedAt, time.Second)
+		assert.Equal(t, second.TaskID, byHash["32ed87bdb5fdc5e9cba88547376818d4"].TaskID)
+	}
+
+	known, err = stor.LookupKnownHashes(*user, 1000, []string{"31d6cfe0d16ae931b73c59d7e0c089c0"})
+	assert.Nil(t, err)
+	assert.Empty(t, known)
+
+	// hex hashes match regardless of their case while other hashes are case sensitive
+	assert.Nil(t, stor.SaveKnownHash(storage.KnownHash{HashType: 1000, Hash: "E10ADC3949BA59ABBE56E057F20F883E", Value: "123456", CrackedAt: crackedAt, TaskID: first.TaskID}))
+	assert.Nil(t, stor.SaveKnownHash(storage.KnownHash{HashType: 3200, Hash: "$2a$05$LhayLxezLhK1LhWvKxCyLOj0j1u.Kj0jZ0pEmm134uzrQlFvQJLF6", Value: "hashcat", CrackedAt: crackedAt, TaskID: first.TaskID}))
+
+	known, err = stor.LookupKnownHashes(*user, 1000, []string{"e10adc3949ba59abbe56e057f20f883e", "8846F7EAEE8FB117AD06BDD830B7586C"})
+	if assert.Nil(t, err) && assert.Len(t, known, 2) {
+		values := map[string]string{}
+		for _, kh := range known {
+			values[kh.Hash] = kh.Value
+		}
+		assert.Equal(t, map[string]string{
+			"e10adc3949ba59abbe56e057f20f883e": "123456",
+			"8846f7eaee8fb117ad06bdd830b7586c": "password",
+		}, values)
+	}
+
+	known, err = stor.LookupKnownHashes(*user, 3200, []string{"$2a$05$lhaylxezlhk1lhwvkxcylOj0j1u.kj0jz0pemm134uzrqlfvqjlf6"})
+	assert.Nil(t, err)
+	assert.Empty(t, known)
+
+	known, err = stor.LookupKnownHashes(*user, 3200, []string{"$2a$05$LhayLxezLhK1LhWvKxCyLOj0j1u.Kj0jZ0pEmm134uzrQlFvQJLF6"})
+	assert.Nil(t, err)
+	assert.Len(t, known, 1)
+
+	// task files may contain more hashes than a backend can look up at once
+	many := make([]string, 0, 1200)
+	for i := 0; i < 1200; i++ {
+		many = append(many, fmt.Sprintf("%032x", i))
+	}
+	known, err = stor.LookupKnownHashes(*user, 1000, append(many, "32ed87bdb5fdc5e9cba88547376818d4"))
+	if assert.Nil(t, err) && assert.Len(t, known, 1) {
+		assert.Equal(t, "123456", known[0].Value)
+	}
+
+	// the task has already cracked one of the hashes so it's skipped
+	assert.Nil(t, stor.SaveCrackedHash(reusing.TaskID, "32ed87bdb5fdc5e9cba88547376818d4", "123456", crackedAt))
+	known, err = stor.LookupKnownHashes(*user, 1000, []string{"8846f7eaee8fb117ad06bdd830b7586c", "32ed87bdb5fdc5e9cba88547376818d4"})
+	if assert.Nil(t, err) {
+		credited, err := stor.CreditKnownHashes(reusing.TaskID, known)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, credited)
+	}
+
+	cracked, err := stor.GetCrackedPasswords(reusing.TaskID)
+	if assert.Nil(t, err) && assert.Len(t, *cracked, 2) {
+		reusedFrom := map[string]string{}
+		for _, ch := range *cracked {
+			reusedFrom[ch.Hash] = ch.ReusedFromTaskID
+		}
+		assert.Equal(t, map[string]string{
+			"8846f7eaee8fb117ad06bdd830b7586c": first.TaskID,
+			"32ed87bdb5fdc5e9cba88547376818d4": "",
+		}, reusedFrom)
+	}
+
+	_, err = stor.CreditKnownHashes(uuid.NewString(), known)
+	assert.Equal(t, storage.ErrNotFound, err)
+
+	// users only see the passwords cracked by tasks they are entitled to
+	outsider := createUser(t, stor, false)
+	outsiderTask := &storage.Task{TaskName: "Outsider"}
+	createTasks(t, stor, outsider, outsiderTask)
+
+	known, err = stor.LookupKnownHashes(*outsider, 1000, []string{"8846f7eaee8fb117ad06bdd830b7586c", "32ed87bdb5fdc5e9cba88547376818d4"})
+	if assert.Nil(t, err) && assert.Empty(t, known) {
+		credited, err := stor.CreditKnownHashes(outsiderTask.TaskID, known)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, credited)
+	}
+
+	cracked, err = stor.GetCrackedPasswords(outsiderTask.TaskID)
+	if assert.Nil(t, err) {
+		assert.Empty(t, *cracked)
+	}
+
+	assert.Nil(t, stor.GrantEntitlement(*outsider, *first))
+	known, err = stor.LookupKnownHashes(*outsider, 1000, []string{"8846f7eaee8fb117ad06bdd830b7586c", "32ed87bdb5fdc5e9cba88547376818d4"})
+	if assert.Nil(t, err) && assert.Len(t, known, 1) {
+		assert.Equal(t, first.TaskID, known[0].TaskID)
+	}
+
+	admin := createUser(t, stor, true)
+	known, err = stor.LookupKnownHashes(*admin, 1000, []string{"8846f7eaee8fb117ad06bdd830b7586c", "32ed87bdb5fdc5e9cba88547376818d4"})
+	assert.Nil(t, err)
+	assert.Len(t, known, 2)
+
+	// disabling reuse removes the hashes cracked by the task from the index
+	assert.Nil(t, stor.UpdateTask(second.TaskID, storage.ModifiableTaskRequest{DisableHashReuse: shared.GetBoolPtr(true)}))
+	found, err := stor.GetTaskByID(second.TaskID)
+	if assert.Nil(t, err) {
+		assert.True(t, found.DisableHashReuse)
+	}
+
+	known, err = stor.LookupKnownHashes(*user, 1000, []string{"8846f7eaee8fb117ad06bdd830b7586c", "32ed87bdb5fdc5e9cba88547376818d4"})
+	if assert.Nil(t, err) && assert.Len(t, known, 1) {
+		assert.Equal(t, first.TaskID, known[0].TaskID)
+	}
+
+	known, err = stor.LookupKnownHashes(*user, 0, []string{"8846f7eaee8fb117ad06bdd830b7586c"})
+	assert.Nil(t, err)
+	assert.Empty(t, known)
+
+	assert.Nil(t, stor.UpdateTask(second.TaskID, storage.ModifiableTaskRequest{DisableHashReuse: shared.GetBoolPtr(false)}))
+	found, err = stor.GetTaskByID(second.TaskID)
+	if assert.Nil(t, err) {
+		assert.False(t, found.DisableHashReuse)
+	}
+}
@@ -0,0 +1,68 @@
+package storagetest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mandiant/gocrack/server/storage"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func testPasswordPolicies(t *testing.T, stor storage.Backend) {
+	user := createUser(t, stor, false)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	policy := storage.PasswordPolicy{
+		PolicyID:         uuid.NewString(),
+		Name:             "Windows Complexity",
+		MinLength:        8,
+		MinClasses:       3,
+		MaxRepeatedChars: 2,
+		RequiredClasses:  []string{"digit"},
+		BannedWordFiles:  []string{uuid.NewString()},
+		CreatedByUUID:    user.UserUUID,
+		CreatedAt:        now,
+		LastUpdatedAt:    now,
+	}
+	assert.Nil(t, stor.SavePasswordPolicy(policy))
+
+	other := storage.PasswordPolicy{PolicyID: uuid.NewString(), Name: "Banking", MinLength: 14, CreatedByUUID: user.UserUUID, CreatedAt: now, LastUpdatedAt: now}
+	assert.Nil(t, stor.SavePasswordPolicy(other))
+
+	found, err := stor.GetPasswordPolicy(policy.PolicyID)
+	if assert.Nil(t, err) {
+		assert.Equal(t, policy.Name, found.Name)
+		assert.Equal(t, policy.RequiredClasses, found.RequiredClasses)
+		assert.Equal(t, policy.BannedWordFiles, found.BannedWordFiles)
+		assert.Equal(t, policy.MaxRepeatedChars, found.MaxRepeatedChars)
+		assert.True(t, policy.CreatedAt.Equal(found.CreatedAt))
+	}
+
+	// names are unique
+	assert.Equal(t, storage.ErrAlreadyExists, stor.SavePasswordPolicy(storage.PasswordPolicy{
+		PolicyID:      uuid.NewString(),
+		Name:          policy.Name,
+		CreatedByUUID: user.UserUUID,
+		CreatedAt:     now,
+		LastUpdatedAt: now,
+	}))
+
+	// saving a policy with the same ID replaces it
+	policy.Name = "Windows Complexity v2"
+	policy.MinLength = 10
+	assert.Nil(t, stor.SavePasswordPolicy(policy))
+
+	policies, err := stor.GetPasswordPolicies()
+	if assert.Nil(t, err) && assert.Len(t, policies, 2) {
+		assert.Equal(t, "Banking", policies[0].Name)
+		assert.Equal(t, "Windows Complexity v2", policies[1].Name)
+		assert.Equal(t, 10, policies[1].MinLength)
+	}
+
+	assert.Nil(t, stor.DeletePasswordPolicy(policy.PolicyID))
+	_, err = stor.GetPasswordPolicy(policy.PolicyID)
+	assert.Equal(t, storage.ErrNotFound, err)
+	assert.Equal(t, storage.ErrNotFound, stor.DeletePasswordPolicy(policy.PolicyID))
+}
@@ -0,0 +1,271 @@
+package storagetest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mandiant/gocrack/server/storage"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type pendingTaskTest struct {
+	name  string
+	tasks []storage.Task
+	req   storage.GetPendingTasksRequest
+	// index inside tasks of the task that should be handed out. -1 means nothing should be handed out
+	expected int
+}
+
+func testGetPendingTasksHostMatching(t *testing.T, stor storage.Backend) {
+	for _, test := range []pendingTaskTest{
+		{
+			name: "matching host and free devices",
+			tasks: []storage.Task{
+				{AssignedToHost: "my-hostname", AssignedToDevices: &storage.CLDevices{4, 5}},
+			},
+			req:      storage.GetPendingTasksRequest{Hostname: "my-hostname", DevicesInUse: storage.CLDevices{1, 2}},
+			expected: 0,
+		},
+		{
+			name: "devices in use",
+			tasks: []storage.Task{
+				{AssignedToHost: "my-hostname", AssignedToDevices: &storage.CLDevices{4, 5}},
+			},
+			req:      storage.GetPendingTasksRequest{Hostname: "my-hostname", DevicesInUse: storage.CLDevices{4}},
+			expected: -1,
+		},
+		{
+			name: "assigned to another host",
+			tasks: []storage.Task{
+				{AssignedToHost: "other-hostname"},
+			},
+			req:      storage.GetPendingTasksRequest{Hostname: "my-hostname"},
+			expected: -1,
+		},
+		{
+			name: "unassigned host",
+			tasks: []storage.Task{
+				{AssignedToHost: "other-hostname"},
+				{},
+			},
+			req:      storage.GetPendingTasksRequest{Hostname: "my-hostname", DevicesInUse: storage.CLDevices{1}},
+			expected: 1,
+		},
+		{
+			name: "outside of its schedule",
+			tasks: []storage.Task{
+				{Schedule: storage.TaskSchedule{NotBefore: timePtr(time.Now().UTC().Add(time.Hour))}},
+				{Schedule: storage.TaskSchedule{Windows: []storage.ExecutionWindow{closedWindow()}}},
+				{Schedule: storage.TaskSchedule{NotBefore: timePtr(time.Now().UTC().Add(-time.Hour))}},
+			},
+			req:      storage.GetPendingTasksRequest{Hostname: "my-hostname"},
+			expected: 2,
+		},
+		{
+			name: "out of time",
+			tasks: []storage.Task{
+				{TaskDuration: 60, Runtime: 60},
+				{TaskDuration: 60, Runtime: 30},
+			},
+			req:      storage.GetPendingTasksRequest{Hostname: "my-hostname"},
+			expected: 1,
+		},
+		{
+			name: "not queued",
+			tasks: []storage.Task{
+				{Status: storage.TaskStatusRunning},
+				{Status: storage.TaskStatusFinished},
+				{Status: storage.TaskStatusStopped},
+			},
+			req:      storage.GetPendingTasksRequest{Hostname: "my-hostname"},
+			expected: -1,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			user := createUser(t, stor, false)
+
+			tasks := make([]*storage.Task, len(test.tasks))
+			for i := range test.tasks {
+				tasks[i] = &test.tasks[i]
+			}
+			createTasks(t, stor, user, tasks...)
+
+			test.req.CheckForNewTask = true
+			items, err := stor.GetPendingTasks(test.req)
+			assert.Nil(t, err)
+
+			if test.expected == -1 {
+				assert.Empty(t, items)
+			} else if assert.Len(t, items, 1) {
+				assert.Equal(t, storage.PendingTaskNewRequest, items[0].Type)
+				assert.Equal(t, tasks[test.expected].TaskID, items[0].Payload.(*storage.Task).TaskID)
+				assert.Nil(t, items[0].WorkUnit)
+			}
+
+			// clear out the tasks so they are not handed out in the next test
+			for _, task := range tasks {
+				changeStatus(t, stor, task.TaskID, storage.TaskStatusFinished)
+			}
+		})
+	}
+}
+
+func testGetPendingTasksOrdering(t *testing.T, stor storage.Backend) {
+	user := createUser(t, stor, false)
+
+	now := time.Now().UTC()
+	lowOld := &storage.Task{Priority: storage.WorkerPriorityLow, CreatedAt: now.Add(-time.Hour)}
+	normal := &storage.Task{Priority: storage.WorkerPriorityNormal, CreatedAt: now}
+	highNew := &storage.Task{Priority: storage.WorkerPriorityHigh, CreatedAt: now}
+	highOld := &storage.Task{Priority: storage.WorkerPriorityHigh, CreatedAt: now.Add(-time.Minute)}
+	createTasks(t, stor, user, lowOld, normal, highNew, highOld)
+
+	// tasks are handed out by priority and then by the time they were created
+	for _, expected := range []*storage.Task{highOld, highNew, normal, lowOld} {
+		items, err := stor.GetPendingTasks(storage.GetPendingTasksRequest{Hostname: "my-hostname", CheckForNewTask: true})
+		assert.Nil(t, err)
+		if !assert.Len(t, items, 1) {
+			return
+		}
+
+		assert.Equal(t, expected.TaskID, items[0].Payload.(*storage.Task).TaskID)
+		changeStatus(t, stor, expected.TaskID, storage.TaskStatusDequeued)
+	}
+
+	items, err := stor.GetPendingTasks(storage.GetPendingTasksRequest{Hostname: "my-hostname", CheckForNewTask: true})
+	assert.Nil(t, err)
+	assert.Empty(t, items)
+}
+
+func testGetPendingTasksEngines(t *testing.T, stor storage.Backend) {
+	user := createUser(t, stor, false)
+
+	now := time.Now().UTC()
+	hashcat := &storage.Task{Engine: storage.WorkerHashcatEngine, Priority: storage.WorkerPriorityHigh, CreatedAt: now.Add(-time.Hour)}
+	cpu := &storage.Task{Engine: storage.WorkerCPUEngine, Priority: storage.WorkerPriorityLow, CreatedAt: now}
+	createTasks(t, stor, user, hashcat, cpu)
+
+	// a host that can't run any engine is not given a task
+	items, err := stor.GetPendingTasks(storage.GetPendingTasksRequest{Hostname: "cpu-host", CheckForNewTask: true, Engines: []storage.WorkerCrackEngine{}})
+	assert.Nil(t, err)
+	assert.Empty(t, items)
+
+	// the hashcat task comes first but is never handed to a CPU only host
+	cpuOnly := storage.GetPendingTasksRequest{Hostname: "cpu-host", CheckForNewTask: true, Engines: []storage.WorkerCrackEngine{storage.WorkerCPUEngine}}
+	items, err = stor.GetPendingTasks(cpuOnly)
+	assert.Nil(t, err)
+	if assert.Len(t, items, 1) {
+		assert.Equal(t, cpu.TaskID, items[0].Payload.(*storage.Task).TaskID)
+	}
+	changeStatus(t, stor, cpu.TaskID, storage.TaskStatusDequeued)
+
+	cpuOnly.RunningTasks = []string{cpu.TaskID}
+	items, err = stor.GetPendingTasks(cpuOnly)
+	assert.Nil(t, err)
+	assert.Empty(t, items)
+
+	items, err = stor.GetPendingTasks(storage.GetPendingTasksRequest{
+		Hostname:        "gpu-host",
+		CheckForNewTask: true,
+		Engines:         []storage.WorkerCrackEngine{storage.WorkerHashcatEngine, storage.WorkerCPUEngine},
+	})
+	assert.Nil(t, err)
+	if assert.Len(t, items, 1) {
+		assert.Equal(t, hashcat.TaskID, items[0].Payload.(*storage.Task).TaskID)
+	}
+}
+
+func testGetPendingTasksScheduler(t *testing.T, stor storage.Backend) {
+	user := createUser(t, stor, false)
+
+	now := time.Now().UTC()
+	high := &storage.Task{Priority: storage.WorkerPriorityHigh, CreatedAt: now}
+	low := &storage.Task{Priority: storage.WorkerPriorityLow, CreatedAt: now}
+	held := &storage.Task{Priority: storage.WorkerPriorityHigh, CreatedAt: now.Add(-time.Hour)}
+	createTasks(t, stor, user, high, low, held)
+
+	// the scheduler is given the tasks in priority order and can reorder them or hold them back
+	var given []string
+	schedule := func(candidates []storage.Task) ([]storage.Task, error) {
+		given = taskIDs(candidates)
+
+		out := make([]storage.Task, 0)
+		for i := len(candidates) - 1; i >= 0; i-- {
+			if candidates[i].TaskID != held.TaskID {
+				out = append(out, candidates[i])
+			}
+		}
+		return out, nil
+	}
+
+	items, err := stor.GetPendingTasks(storage.GetPendingTasksRequest{Hostname: "my-hostname", CheckForNewTask: true, Schedule: schedule})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{held.TaskID, high.TaskID, low.TaskID}, given)
+	if assert.Len(t, items, 1) {
+		assert.Equal(t, low.TaskID, items[0].Payload.(*storage.Task).TaskID)
+	}
+
+	changeStatus(t, stor, low.TaskID, storage.TaskStatusRunning)
+	changeStatus(t, stor, high.TaskID, storage.TaskStatusRunning)
+
+	items, err = stor.GetPendingTasks(storage.GetPendingTasksRequest{Hostname: "my-hostname", CheckForNewTask: true, Schedule: schedule})
+	assert.Nil(t, err)
+	assert.Empty(t, items)
+
+	tasks, err := stor.GetTasksByStatus(storage.TaskStatusRunning, storage.TaskStatusQueued)
+	if assert.Nil(t, err) && assert.Len(t, tasks, 3) {
+		// the oldest task comes first
+		assert.Equal(t, held.TaskID, tasks[0].TaskID)
+		assert.ElementsMatch(t, []string{held.TaskID, high.TaskID, low.TaskID}, taskIDs(tasks))
+	}
+
+	tasks, err = stor.GetTasksByStatus(storage.TaskStatusError)
+	if assert.Nil(t, err) {
+		assert.Empty(t, tasks)
+	}
+}
+
+func testGetPendingTasksStatusChanges(t *testing.T, stor storage.Backend) {
+	user := createUser(t, stor, false)
+
+	running := &storage.Task{Status: storage.TaskStatusRunning}
+	stopping := &storage.Task{Status: storage.TaskStatusStopping}
+	notOnHost := &storage.Task{Status: storage.TaskStatusStopping}
+	// a finished distributed task stops the units still running on other hosts
+	distributed := &storage.Task{Status: storage.TaskStatusFinished, WorkUnitCount: 2, Keyspace: 10}
+	queued := &storage.Task{}
+	createTasks(t, stor, user, running, stopping, notOnHost, distributed, queued)
+
+	items, err := stor.GetPendingTasks(storage.GetPendingTasksRequest{
+		Hostname:     "my-hostname",
+		RunningTasks: []string{running.TaskID, stopping.TaskID, distributed.TaskID},
+	})
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []storage.GetPendingTasksResponseItem{
+		{
+			Type:    storage.PendingTaskStatusChange,
+			Payload: storage.PendingTaskStatusChangeItem{TaskID: stopping.TaskID, NewStatus: storage.TaskStatusStopping},
+		},
+		{
+			Type:    storage.PendingTaskStatusChange,
+			Payload: storage.PendingTaskStatusChangeItem{TaskID: distributed.TaskID, NewStatus: storage.TaskStatusStopping},
+		},
+	}, items, "only tasks running on the host are stopped and new tasks are not handed out unless requested")
+
+	items, err = stor.GetPendingTasks(storage.GetPendingTasksRequest{
+		Hostname:        "my-hostname",
+		RunningTasks:    []string{stopping.TaskID},
+		CheckForNewTask: true,
+	})
+	assert.Nil(t, err)
+	if assert.Len(t, items, 2) {
+		assert.Equal(t, storage.PendingTaskNewRequest, items[0].Type)
+		assert.Equal(t, queued.TaskID, items[0].Payload.(*storage.Task).TaskID)
+		assert.Equal(t, storage.PendingTaskStatusChange, items[1].Type)
+	}
+
+	items, err = stor.GetPendingTasks(storage.GetPendingTasksRequest{Hostname: "my-hostname"})
+	assert.Nil(t, err)
+	assert.Empty(t, items)
+}
@@ -0,0 +1,96 @@
+package storagetest
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/mandiant/gocrack/server/storage"
+	"github.com/mandiant/gocrack/shared"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func testPipelines(t *testing.T, stor storage.Backend) {
+	user := createUser(t, stor, false)
+	now := time.Now().UTC().Truncate(time.Second)
+	tf := createTaskFile(t, stor, user, now)
+
+	pipelines, err := stor.GetPipelines()
+	if assert.Nil(t, err) {
+		assert.Len(t, pipelines, 0)
+	}
+
+	second := storage.Pipeline{
+		PipelineID: uuid.NewString(),
+		Name:       "second",
+		FileID:     tf.FileID,
+		Priority:   storage.WorkerPriorityNormal,
+		Stages: []storage.PipelineStage{
+			{Name: "dictionary", Engine: storage.WorkerHashcatEngine, EnginePayload: json.RawMessage(`{"hash_type":"MD5"}`)},
+		},
+		Status:        storage.TaskStatusRunning,
+		CreatedByUUID: user.UserUUID,
+		CreatedAt:     now.Add(time.Minute),
+		LastUpdatedAt: now.Add(time.Minute),
+	}
+	assert.Nil(t, stor.SavePipeline(second))
+
+	first := storage.Pipeline{
+		PipelineID:      uuid.NewString(),
+		Name:            "first",
+		FileID:          tf.FileID,
+		CaseCode:        shared.GetStrPtr("CASE-1"),
+		Priority:        storage.WorkerPriorityHigh,
+		AdditionalUsers: []string{uuid.NewString()},
+		Stages: []storage.PipelineStage{
+			{Name: "quick", Engine: storage.WorkerHashcatEngine, EnginePayload: json.RawMessage(`{"hash_type":"MD5"}`), TaskDuration: 60, TaskID: uuid.NewString()},
+			{Name: "masks", Engine: storage.WorkerHashcatEngine, EnginePayload: json.RawMessage(`{"hash_type":"MD5"}`), WorkUnits: 4},
+		},
+		Status:        storage.TaskStatusRunning,
+		CreatedByUUID: user.UserUUID,
+		CreatedAt:     now,
+		LastUpdatedAt: now,
+	}
+	assert.Nil(t, stor.SavePipeline(first))
+
+	found, err := stor.GetPipeline(first.PipelineID)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "first", found.Name)
+		assert.Equal(t, "CASE-1", *found.CaseCode)
+		assert.Equal(t, storage.WorkerPriorityHigh, found.Priority)
+		assert.Equal(t, first.AdditionalUsers, found.AdditionalUsers)
+		if assert.Len(t, found.Stages, 2) {
+			assert.Equal(t, first.Stages[0].TaskID, found.Stages[0].TaskID)
+			assert.Equal(t, 60, found.Stages[0].TaskDuration)
+			assert.Equal(t, 4, found.Stages[1].WorkUnits)
+			assert.JSONEq(t, `{"hash_type":"MD5"}`, string(found.Stages[1].EnginePayload))
+		}
+		assert.True(t, now.Equal(found.CreatedAt))
+	}
+
+	// saving a pipeline with the same ID replaces it
+	first.CurrentStage = 1
+	first.Stages[1].TaskID = uuid.NewString()
+	first.Status = storage.TaskStatusError
+	first.Error = shared.GetStrPtr("the task file no longer exists")
+	first.LastUpdatedAt = now.Add(time.Hour)
+	assert.Nil(t, stor.SavePipeline(first))
+
+	pipelines, err = stor.GetPipelines()
+	if assert.Nil(t, err) && assert.Len(t, pipelines, 2) {
+		assert.Equal(t, first.PipelineID, pipelines[0].PipelineID)
+		assert.Equal(t, second.PipelineID, pipelines[1].PipelineID)
+		assert.Equal(t, 1, pipelines[0].CurrentStage)
+		assert.Equal(t, first.Stages[1].TaskID, pipelines[0].Stages[1].TaskID)
+		assert.Equal(t, storage.TaskStatusError, pipelines[0].Status)
+		if assert.NotNil(t, pipelines[0].Error) {
+			assert.Equal(t, *first.Error, *pipelines[0].Error)
+		}
+		assert.Nil(t, pipelines[1].CaseCode)
+	}
+
+	_, err = stor.GetPipeline(uuid.NewString())
+	assert.Equal(t, storage.ErrNotFound, err)
+}
@@ -0,0 +1,44 @@
+package storagetest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mandiant/gocrack/server/storage"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func testTaskPreempted(t *testing.T, stor storage.Backend) {
+	user := createUser(t, stor, false)
+	running := &storage.Task{Status: storage.TaskStatusRunning, CreatedAt: time.Now().UTC()}
+	createTasks(t, stor, user, running)
+
+	found, err := stor.GetTaskByID(running.TaskID)
+	if assert.Nil(t, err) {
+		assert.False(t, found.Preempted)
+	}
+
+	assert.Nil(t, stor.SetTaskPreempted(running.TaskID, true))
+	found, err = stor.GetTaskByID(running.TaskID)
+	if assert.Nil(t, err) {
+		assert.True(t, found.Preempted)
+	}
+
+	// the flag is kept when the worker reports the task has stopped
+	_, err = stor.ChangeTaskStatus(running.TaskID, storage.TaskStatusChange{Status: storage.TaskStatusStopped})
+	assert.Nil(t, err)
+	found, err = stor.GetTaskByID(running.TaskID)
+	if assert.Nil(t, err) {
+		assert.True(t, found.Preempted)
+		assert.Equal(t, storage.TaskStatusStopped, found.Status)
+	}
+
+	assert.Nil(t, stor.SetTaskPreempted(running.TaskID, false))
+	found, err = stor.GetTaskByID(running.TaskID)
+	if assert.Nil(t, err) {
+		assert.False(t, found.Preempted)
+	}
+	assert.Equal(t, storage.ErrNotFound, stor.SetTaskPreempted(uuid.NewString(), true))
+}
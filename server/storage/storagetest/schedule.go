@@ -0,0 +1,88 @@
+package storagetest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mandiant/gocrack/server/storage"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+// closedWindow returns an execution window that opens in two hours so it's closed for the duration of the test
+func closedWindow() storage.ExecutionWindow {
+	now := time.Now().UTC()
+	return storage.ExecutionWindow{
+		Start: now.Add(2 * time.Hour).Format("15:04"),
+		End:   now.Add(3 * time.Hour).Format("15:04"),
+	}
+}
+
+func testTaskSchedules(t *testing.T, stor storage.Backend) {
+	user := createUser(t, stor, false)
+	now := time.Now().UTC().Truncate(time.Second)
+	schedule := storage.TaskSchedule{
+		NotBefore: timePtr(now.Add(time.Hour)),
+		Windows: []storage.ExecutionWindow{
+			{Days: []time.Weekday{time.Saturday, time.Sunday}, Start: "00:00", End: "23:59"},
+			{Start: "20:00", End: "06:00", Timezone: "America/New_York"},
+		},
+	}
+
+	running := &storage.Task{Status: storage.TaskStatusRunning, CreatedAt: now, Schedule: schedule}
+	stopped := &storage.Task{Status: storage.TaskStatusStopped, CreatedAt: now.Add(time.Minute), Schedule: schedule}
+	queued := &storage.Task{Status: storage.TaskStatusQueued, CreatedAt: now, Schedule: schedule}
+	unscheduled := &storage.Task{Status: storage.TaskStatusRunning, CreatedAt: now}
+	createTasks(t, stor, user, running, stopped, queued, unscheduled)
+
+	found, err := stor.GetTaskByID(running.TaskID)
+	if assert.Nil(t, err) {
+		if assert.NotNil(t, found.Schedule.NotBefore) {
+			assert.True(t, schedule.NotBefore.Equal(*found.Schedule.NotBefore))
+		}
+		assert.Equal(t, schedule.Windows, found.Schedule.Windows)
+		assert.False(t, found.StoppedByWindow)
+	}
+
+	found, err = stor.GetTaskByID(unscheduled.TaskID)
+	if assert.Nil(t, err) {
+		assert.True(t, found.Schedule.IsEmpty())
+	}
+
+	// only tasks with windows that are processing or stopped are checked by the server
+	windowed, err := stor.GetWindowedTasks()
+	if assert.Nil(t, err) && assert.Len(t, windowed, 2) {
+		assert.Equal(t, running.TaskID, windowed[0].TaskID)
+		assert.Equal(t, stopped.TaskID, windowed[1].TaskID)
+	}
+
+	assert.Nil(t, stor.SetTaskStoppedByWindow(stopped.TaskID, true))
+	found, err = stor.GetTaskByID(stopped.TaskID)
+	if assert.Nil(t, err) {
+		assert.True(t, found.StoppedByWindow)
+	}
+
+	assert.Nil(t, stor.SetTaskStoppedByWindow(stopped.TaskID, false))
+	found, err = stor.GetTaskByID(stopped.TaskID)
+	if assert.Nil(t, err) {
+		assert.False(t, found.StoppedByWindow)
+	}
+	assert.Equal(t, storage.ErrNotFound, stor.SetTaskStoppedByWindow(uuid.NewString(), true))
+
+	// an empty schedule removes the restrictions of the task
+	assert.Nil(t, stor.UpdateTask(running.TaskID, storage.ModifiableTaskRequest{Schedule: &storage.TaskSchedule{}}))
+	found, err = stor.GetTaskByID(running.TaskID)
+	if assert.Nil(t, err) {
+		assert.True(t, found.Schedule.IsEmpty())
+	}
+
+	windowed, err = stor.GetWindowedTasks()
+	if assert.Nil(t, err) && assert.Len(t, windowed, 1) {
+		assert.Equal(t, stopped.TaskID, windowed[0].TaskID)
+	}
+}
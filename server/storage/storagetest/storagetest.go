@@ -0,0 +1,174 @@
+/* package storagetest contains the conformance tests that every storage.Backend must pass.
+The web & RPC servers rely on the behavior tested here so new drivers should run Run from their own tests */
+
+package storagetest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mandiant/gocrack/server/storage"
+	// tasks are saved with the payloads of the built-in engines
+	_ "github.com/mandiant/gocrack/shared/enginedef/cpu"
+	_ "github.com/mandiant/gocrack/shared/enginedef/hashcat"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// OpenFunc opens an empty database for a single test. The returned function must close the database and remove any files it created
+type OpenFunc func(t *testing.T) (stor storage.Backend, cleanup func())
+
+type conformanceTest struct {
+	name string
+	fn   func(t *testing.T, stor storage.Backend)
+}
+
+var conformanceTests = []conformanceTest{
+	{"Users", testUsers},
+	{"EditUser", testEditUser},
+	{"TaskFileTransaction", testTaskFileTransaction},
+	{"TaskFileAccounts", testTaskFileAccounts},
+	{"ListTasksForUser", testListTasksForUser},
+	{"EngineFileTransaction", testEngineFileTransaction},
+	{"GetEngineFilesForUser", testGetEngineFilesForUser},
+	{"TaskCreateTransaction", testTaskCreateTransaction},
+	{"ChangeTaskStatus", testChangeTaskStatus},
+	{"TaskRuntime", testTaskRuntime},
+	{"TaskQueuedAt", testTaskQueuedAt},
+	{"TaskStatusHistory", testTaskStatusHistory},
+	{"UpdateTask", testUpdateTask},
+	{"TasksSearch", testTasksSearch},
+	{"TasksSearchEntitled", testTasksSearchEntitled},
+	{"GetTasksByCaseCode", testGetTasksByCaseCode},
+	{"GetPendingTasksHostMatching", testGetPendingTasksHostMatching},
+	{"GetPendingTasksOrdering", testGetPendingTasksOrdering},
+	{"GetPendingTasksStatusChanges", testGetPendingTasksStatusChanges},
+	{"GetPendingTasksScheduler", testGetPendingTasksScheduler},
+	{"GetPendingTasksEngines", testGetPendingTasksEngines},
+	{"CrackedHashes", testCrackedHashes},
+	{"KnownHashes", testKnownHashes},
+	{"Checkpoints", testCheckpoints},
+	{"Entitlements", testEntitlements},
+	{"ActivityLog", testActivityLog},
+	{"WorkUnits", testWorkUnits},
+	{"DeleteTask", testDeleteTask},
+	{"DeleteTaskKeepsFile", testDeleteTaskKeepsFile},
+	{"PasswordPolicies", testPasswordPolicies},
+	{"UpdateEngineFile", testUpdateEngineFile},
+	{"CrackedWordlists", testCrackedWordlists},
+	{"Pipelines", testPipelines},
+	{"TaskTemplates", testTaskTemplates},
+	{"TaskSchedules", testTaskSchedules},
+	{"TaskPreempted", testTaskPreempted},
+}
+
+// Run runs every conformance test against a fresh database opened by open.
+// The tests are not run in parallel as some backends may only be opened once per process
+func Run(t *testing.T, open OpenFunc) {
+	for _, test := range conformanceTests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			stor, cleanup := open(t)
+			defer cleanup()
+
+			test.fn(t, stor)
+		})
+	}
+}
+
+// createUser saves a new user into the database and fails the test if it could not be created
+func createUser(t *testing.T, stor storage.Backend, isAdmin bool) *storage.User {
+	user := &storage.User{
+		Username:     "user_" + uuid.NewString()[:8],
+		Password:     "password_hash",
+		EmailAddress: "user@example.com",
+		IsSuperUser:  isAdmin,
+	}
+
+	if err := stor.CreateUser(user); err != nil {
+		assert.FailNow(t, "failed to create user", err.Error())
+	}
+	return user
+}
+
+// createTasks saves the tasks within a single transaction and fails the test if any could not be created.
+// Tasks without a TaskID or CreatedByUUID are given one
+func createTasks(t *testing.T, stor storage.Backend, creator *storage.User, tasks ...*storage.Task) {
+	txn, err := stor.NewTaskCreateTransaction()
+	if err != nil {
+		assert.FailNow(t, "failed to create task transaction", err.Error())
+	}
+
+	for _, task := range tasks {
+		if task.TaskID == "" {
+			task.TaskID = uuid.NewString()
+		}
+
+		if task.CreatedByUUID == "" {
+			task.CreatedByUUID = creator.UserUUID
+		}
+
+		if task.FileID == "" {
+			task.FileID = uuid.NewString()
+		}
+
+		if err := txn.CreateTask(task); err != nil {
+			txn.Rollback()
+			assert.FailNow(t, "failed to create task", err.Error())
+		}
+	}
+
+	if err := txn.Commit(); err != nil {
+		assert.FailNow(t, "failed to commit task transaction", err.Error())
+	}
+}
+
+// createTaskFile saves a task file uploaded by the user and fails the test if it could not be created
+func createTaskFile(t *testing.T, stor storage.Backend, uploader *storage.User, uploadedAt time.Time) storage.TaskFile {
+	tf := storage.TaskFile{
+		FileID:            uuid.NewString(),
+		SavedAt:           "/tmp/" + uuid.NewString(),
+		UploadedAt:        uploadedAt,
+		UploadedByUUID:    uploader.UserUUID,
+		FileSize:          1024,
+		FileName:          "hashes.txt",
+		SHA1Hash:          "da39a3ee5e6b4b0d3255bfef95601890afd80709",
+		NumberOfPasswords: 10,
+	}
+
+	txn, err := stor.NewTaskFileTransaction()
+	if err != nil {
+		assert.FailNow(t, "failed to create task file transaction", err.Error())
+	}
+
+	if err := txn.SaveTaskFile(tf); err != nil {
+		txn.Rollback()
+		assert.FailNow(t, "failed to save task file", err.Error())
+	}
+
+	if err := txn.AddEntitlement(tf, uploader.UserUUID); err != nil {
+		txn.Rollback()
+		assert.FailNow(t, "failed to entitle task file", err.Error())
+	}
+
+	if err := txn.Commit(); err != nil {
+		assert.FailNow(t, "failed to commit task file transaction", err.Error())
+	}
+	return tf
+}
+
+// changeStatus changes the status of the task on behalf of the server
+func changeStatus(t *testing.T, stor storage.Backend, taskID string, status storage.TaskStatus) {
+	_, err := stor.ChangeTaskStatus(taskID, storage.TaskStatusChange{Status: status, ActorType: storage.StatusActorServer})
+	assert.Nil(t, err)
+}
+
+// taskIDs returns the ID's of the tasks in the order they are in
+func taskIDs(tasks []storage.Task) []string {
+	out := make([]string, len(tasks))
+	for i, task := range tasks {
+		out[i] = task.TaskID
+	}
+	return out
+}
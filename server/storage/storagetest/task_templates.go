@@ -0,0 +1,123 @@
+package storagetest
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/mandiant/gocrack/server/storage"
+	"github.com/mandiant/gocrack/shared"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func templateIDs(templates []storage.TaskTemplate) []string {
+	out := make([]string, len(templates))
+	for i, tmpl := range templates {
+		out[i] = tmpl.TemplateID
+	}
+	return out
+}
+
+func testTaskTemplates(t *testing.T, stor storage.Backend) {
+	creator := createUser(t, stor, false)
+	other := createUser(t, stor, false)
+	admin := createUser(t, stor, true)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	templates, err := stor.GetTaskTemplatesForUser(*creator)
+	if assert.Nil(t, err) {
+		assert.Len(t, templates, 0)
+	}
+
+	private := storage.TaskTemplate{
+		TemplateID:        uuid.NewString(),
+		Name:              "rockyou + best64",
+		Description:       shared.GetStrPtr("dictionary attack with the best64 rules"),
+		Engine:            storage.WorkerHashcatEngine,
+		EnginePayload:     json.RawMessage(`{"hash_type":"MD5"}`),
+		Priority:          storage.WorkerPriorityHigh,
+		TaskDuration:      3600,
+		WorkUnits:         4,
+		AssignedToHost:    "cracker-1",
+		AssignedToDevices: &storage.CLDevices{0, 2},
+		CreatedByUUID:     creator.UserUUID,
+		CreatedAt:         now,
+		LastUpdatedAt:     now,
+	}
+	assert.Nil(t, stor.SaveTaskTemplate(private))
+	assert.Nil(t, stor.GrantEntitlement(*creator, private))
+
+	public := storage.TaskTemplate{
+		TemplateID:    uuid.NewString(),
+		Name:          "brute force",
+		Engine:        storage.WorkerHashcatEngine,
+		EnginePayload: json.RawMessage(`{"hash_type":"NTLM"}`),
+		Priority:      storage.WorkerPriorityNormal,
+		IsShared:      true,
+		CreatedByUUID: other.UserUUID,
+		CreatedAt:     now,
+		LastUpdatedAt: now,
+	}
+	assert.Nil(t, stor.SaveTaskTemplate(public))
+
+	found, err := stor.GetTaskTemplate(private.TemplateID)
+	if assert.Nil(t, err) {
+		assert.Equal(t, private.Name, found.Name)
+		assert.Equal(t, *private.Description, *found.Description)
+		assert.Equal(t, storage.WorkerPriorityHigh, found.Priority)
+		assert.JSONEq(t, `{"hash_type":"MD5"}`, string(found.EnginePayload))
+		assert.Equal(t, 3600, found.TaskDuration)
+		assert.Equal(t, 4, found.WorkUnits)
+		assert.Equal(t, "cracker-1", found.AssignedToHost)
+		assert.Equal(t, &storage.CLDevices{0, 2}, found.AssignedToDevices)
+		assert.False(t, found.IsShared)
+		assert.True(t, now.Equal(found.CreatedAt))
+	}
+
+	// templates are listed by name when they're shared or the user is entitled to them
+	templates, err = stor.GetTaskTemplatesForUser(*creator)
+	if assert.Nil(t, err) {
+		assert.Equal(t, []string{public.TemplateID, private.TemplateID}, templateIDs(templates))
+	}
+
+	templates, err = stor.GetTaskTemplatesForUser(*other)
+	if assert.Nil(t, err) {
+		assert.Equal(t, []string{public.TemplateID}, templateIDs(templates))
+	}
+
+	templates, err = stor.GetTaskTemplatesForUser(*admin)
+	if assert.Nil(t, err) {
+		assert.Equal(t, []string{public.TemplateID, private.TemplateID}, templateIDs(templates))
+	}
+
+	// saving a template with the same ID replaces it
+	private.Name = "dictionary"
+	private.AssignedToDevices = nil
+	private.IsShared = true
+	private.LastUpdatedAt = now.Add(time.Hour)
+	assert.Nil(t, stor.SaveTaskTemplate(private))
+
+	found, err = stor.GetTaskTemplate(private.TemplateID)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "dictionary", found.Name)
+		assert.Nil(t, found.AssignedToDevices)
+		assert.True(t, found.IsShared)
+	}
+
+	entitled, err := stor.CheckEntitlement(creator.UserUUID, private.TemplateID, storage.EntitlementTaskTemplate)
+	assert.Nil(t, err)
+	assert.True(t, entitled)
+
+	// deleting a template removes its entitlements
+	assert.Nil(t, stor.DeleteTaskTemplate(private.TemplateID))
+	_, err = stor.GetTaskTemplate(private.TemplateID)
+	assert.Equal(t, storage.ErrNotFound, err)
+
+	entitled, err = stor.CheckEntitlement(creator.UserUUID, private.TemplateID, storage.EntitlementTaskTemplate)
+	assert.Nil(t, err)
+	assert.False(t, entitled)
+
+	assert.Equal(t, storage.ErrNotFound, stor.DeleteTaskTemplate(private.TemplateID))
+}
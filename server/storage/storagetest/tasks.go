@@ -0,0 +1,619 @@
+package storagetest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mandiant/gocrack/server/storage"
+	"github.com/mandiant/gocrack/shared"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func testTaskCreateTransaction(t *testing.T, stor storage.Backend) {
+	user := createUser(t, stor, false)
+	other := createUser(t, stor, false)
+
+	task := &storage.Task{TaskName: "Conformance", CaseCode: shared.GetStrPtr("CC-1337")}
+	createTasks(t, stor, user, task)
+
+	found, err := stor.GetTaskByID(task.TaskID)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "Conformance", found.TaskName)
+		assert.Equal(t, storage.TaskStatusQueued, found.Status, "tasks without a status must be queued")
+		assert.Equal(t, user.Username, found.CreatedBy, "the creator's username must be filled in from CreatedByUUID")
+		assert.WithinDuration(t, time.Now().UTC(), found.CreatedAt, 5*time.Second)
+		assert.False(t, found.LastUpdatedAt.IsZero())
+		if assert.NotNil(t, found.CaseCode) {
+			assert.Equal(t, "CC-1337", *found.CaseCode)
+		}
+	}
+
+	// the creator is always entitled to their task
+	entitled, err := stor.CheckEntitlement(user.UserUUID, task.TaskID, storage.EntitlementTask)
+	assert.Nil(t, err)
+	assert.True(t, entitled)
+
+	entitled, err = stor.CheckEntitlement(other.UserUUID, task.TaskID, storage.EntitlementTask)
+	assert.Nil(t, err)
+	assert.False(t, entitled)
+
+	_, err = stor.GetTaskByID(uuid.NewString())
+	assert.Equal(t, storage.ErrNotFound, err)
+
+	txn, err := stor.NewTaskCreateTransaction()
+	if err != nil {
+		assert.FailNow(t, "failed to create task transaction", err.Error())
+	}
+
+	// a task must have a creator
+	assert.NotNil(t, txn.CreateTask(&storage.Task{TaskID: uuid.NewString(), FileID: uuid.NewString()}))
+
+	rolledBack := &storage.Task{TaskID: uuid.NewString(), FileID: uuid.NewString(), CreatedByUUID: user.UserUUID, WorkUnitCount: 2, Keyspace: 10}
+	assert.Nil(t, txn.CreateTask(rolledBack))
+	assert.Nil(t, txn.GrantEntitlement(other.UserUUID, *rolledBack))
+	assert.Nil(t, txn.CreateWorkUnits(storage.SplitKeyspace(rolledBack.TaskID, rolledBack.Keyspace, rolledBack.WorkUnitCount)))
+	assert.Nil(t, txn.Rollback())
+
+	_, err = stor.GetTaskByID(rolledBack.TaskID)
+	assert.Equal(t, storage.ErrNotFound, err)
+
+	entitled, err = stor.CheckEntitlement(other.UserUUID, rolledBack.TaskID, storage.EntitlementTask)
+	assert.Nil(t, err)
+	assert.False(t, entitled)
+
+	units, err := stor.GetWorkUnits(rolledBack.TaskID)
+	assert.Nil(t, err)
+	assert.Empty(t, units)
+}
+
+func testChangeTaskStatus(t *testing.T, stor storage.Backend) {
+	user := createUser(t, stor, false)
+	task := &storage.Task{TaskName: "Status"}
+	createTasks(t, stor, user, task)
+
+	changeStatus(t, stor, task.TaskID, storage.TaskStatusRunning)
+	found, err := stor.GetTaskByID(task.TaskID)
+	if assert.Nil(t, err) {
+		assert.Equal(t, storage.TaskStatusRunning, found.Status)
+		assert.Nil(t, found.Error)
+	}
+
+	_, err = stor.ChangeTaskStatus(task.TaskID, storage.TaskStatusChange{Status: storage.TaskStatusError, Error: shared.GetStrPtr("engine crashed")})
+	assert.Nil(t, err)
+	found, err = stor.GetTaskByID(task.TaskID)
+	if assert.Nil(t, err) {
+		assert.Equal(t, storage.TaskStatusError, found.Status)
+		if assert.NotNil(t, found.Error) {
+			assert.Equal(t, "engine crashed", *found.Error)
+		}
+	}
+
+	// a nil error does not clear the previous one
+	changeStatus(t, stor, task.TaskID, storage.TaskStatusQueued)
+	found, err = stor.GetTaskByID(task.TaskID)
+	if assert.Nil(t, err) {
+		assert.Equal(t, storage.TaskStatusQueued, found.Status)
+		assert.NotNil(t, found.Error)
+	}
+
+	_, err = stor.ChangeTaskStatus(uuid.NewString(), storage.TaskStatusChange{Status: storage.TaskStatusRunning})
+	assert.Equal(t, storage.ErrNotFound, err)
+}
+
+func testTaskRuntime(t *testing.T, stor storage.Backend) {
+	user := createUser(t, stor, false)
+	runningSince := time.Now().UTC().Add(-10 * time.Minute).Truncate(time.Second)
+	task := &storage.Task{
+		TaskName:     "Runtime",
+		Status:       storage.TaskStatusRunning,
+		TaskDuration: 3600,
+		Runtime:      60,
+		RunningSince: &runningSince,
+	}
+	createTasks(t, stor, user, task)
+
+	found, err := stor.GetTaskByID(task.TaskID)
+	if assert.Nil(t, err) && assert.NotNil(t, found.RunningSince) {
+		assert.True(t, runningSince.Equal(*found.RunningSince))
+		assert.Equal(t, 60, found.Runtime)
+	}
+
+	// the run continues until the task has stopped
+	changeStatus(t, stor, task.TaskID, storage.TaskStatusStopping)
+	found, err = stor.GetTaskByID(task.TaskID)
+	if assert.Nil(t, err) {
+		assert.NotNil(t, found.RunningSince)
+		assert.Equal(t, 60, found.Runtime)
+	}
+
+	changeStatus(t, stor, task.TaskID, storage.TaskStatusStopped)
+	found, err = stor.GetTaskByID(task.TaskID)
+	if assert.Nil(t, err) {
+		assert.Nil(t, found.RunningSince)
+		assert.InDelta(t, 660, found.Runtime, 5)
+	}
+
+	changeStatus(t, stor, task.TaskID, storage.TaskStatusRunning)
+	found, err = stor.GetTaskByID(task.TaskID)
+	if assert.Nil(t, err) {
+		assert.NotNil(t, found.RunningSince)
+		assert.InDelta(t, 660, found.Runtime, 5)
+	}
+}
+
+func testTaskQueuedAt(t *testing.T, stor storage.Backend) {
+	user := createUser(t, stor, false)
+	createdAt := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+	task := &storage.Task{TaskName: "QueuedAt", CreatedAt: createdAt}
+	createTasks(t, stor, user, task)
+
+	found, err := stor.GetTaskByID(task.TaskID)
+	if assert.Nil(t, err) {
+		assert.True(t, createdAt.Equal(found.QueuedAt))
+	}
+
+	// only queueing the task again moves the time it was queued
+	changeStatus(t, stor, task.TaskID, storage.TaskStatusRunning)
+	changeStatus(t, stor, task.TaskID, storage.TaskStatusStopped)
+	found, err = stor.GetTaskByID(task.TaskID)
+	if assert.Nil(t, err) {
+		assert.True(t, createdAt.Equal(found.QueuedAt))
+	}
+
+	changeStatus(t, stor, task.TaskID, storage.TaskStatusQueued)
+	found, err = stor.GetTaskByID(task.TaskID)
+	if assert.Nil(t, err) {
+		assert.WithinDuration(t, time.Now().UTC(), found.QueuedAt, 5*time.Second)
+	}
+}
+
+func testTaskStatusHistory(t *testing.T, stor storage.Backend) {
+	user := createUser(t, stor, false)
+	task := &storage.Task{TaskName: "History"}
+	other := &storage.Task{TaskName: "Other"}
+	createTasks(t, stor, user, task, other)
+
+	history, err := stor.GetTaskStatusHistory(task.TaskID)
+	assert.Nil(t, err)
+	assert.Empty(t, history)
+
+	changes := []storage.TaskStatusChange{
+		{Status: storage.TaskStatusDequeued, ActorType: storage.StatusActorWorker, Actor: "host-a", Devices: storage.CLDevices{1, 2}},
+		{Status: storage.TaskStatusRunning, ActorType: storage.StatusActorWorker, Actor: "host-a", Devices: storage.CLDevices{1, 2}},
+		{Status: storage.TaskStatusError, ActorType: storage.StatusActorWorker, Actor: "host-a", Error: shared.GetStrPtr("engine crashed")},
+		{Status: storage.TaskStatusQueued, ActorType: storage.StatusActorUser, Actor: user.UserUUID},
+	}
+
+	previous := storage.TaskStatusQueued
+	for _, change := range changes {
+		entry, err := stor.ChangeTaskStatus(task.TaskID, change)
+		if assert.Nil(t, err) {
+			assert.Equal(t, task.TaskID, entry.TaskID)
+			assert.Equal(t, previous, entry.PreviousStatus)
+			assert.Equal(t, change, entry.TaskStatusChange)
+			assert.WithinDuration(t, time.Now().UTC(), entry.ChangedAt, 5*time.Second)
+		}
+		previous = change.Status
+	}
+	changeStatus(t, stor, other.TaskID, storage.TaskStatusRunning)
+
+	// entries are returned oldest first and only for the task requested
+	history, err = stor.GetTaskStatusHistory(task.TaskID)
+	if assert.Nil(t, err) && assert.Len(t, history, len(changes)) {
+		previous = storage.TaskStatusQueued
+		for i, entry := range history {
+			assert.Equal(t, task.TaskID, entry.TaskID)
+			assert.Equal(t, previous, entry.PreviousStatus)
+			assert.Equal(t, changes[i], entry.TaskStatusChange)
+			previous = entry.Status
+		}
+	}
+
+	history, err = stor.GetTaskStatusHistory(other.TaskID)
+	if assert.Nil(t, err) && assert.Len(t, history, 1) {
+		assert.Equal(t, storage.StatusActorServer, history[0].ActorType)
+	}
+
+	// a task that does not exist is not recorded
+	_, err = stor.ChangeTaskStatus(uuid.NewString(), storage.TaskStatusChange{Status: storage.TaskStatusRunning})
+	assert.Equal(t, storage.ErrNotFound, err)
+}
+
+func testUpdateTask(t *testing.T, stor storage.Backend) {
+	user := createUser(t, stor, false)
+	task := &storage.Task{
+		TaskName:          "Update",
+		AssignedToHost:    "host-a",
+		AssignedToDevices: &storage.CLDevices{1, 2},
+		TaskDuration:      60,
+	}
+	createTasks(t, stor, user, task)
+
+	status := storage.TaskStatusStopping
+	assert.Nil(t, stor.UpdateTask(task.TaskID, storage.ModifiableTaskRequest{
+		AssignedToHost:    shared.GetStrPtr("host-b"),
+		AssignedToDevices: &storage.CLDevices{3},
+		Status:            &status,
+		TaskDuration:      shared.GetIntPtr(120),
+	}))
+
+	found, err := stor.GetTaskByID(task.TaskID)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "host-b", found.AssignedToHost)
+		if assert.NotNil(t, found.AssignedToDevices) {
+			assert.Equal(t, storage.CLDevices{3}, *found.AssignedToDevices)
+		}
+		assert.Equal(t, storage.TaskStatusStopping, found.Status)
+		assert.Equal(t, 120, found.TaskDuration)
+	}
+
+	// the host & devices are unassigned when they are not in the request. Everything else is left alone
+	assert.Nil(t, stor.UpdateTask(task.TaskID, storage.ModifiableTaskRequest{}))
+	found, err = stor.GetTaskByID(task.TaskID)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "", found.AssignedToHost)
+		assert.Nil(t, found.AssignedToDevices)
+		assert.Equal(t, storage.TaskStatusStopping, found.Status)
+		assert.Equal(t, 120, found.TaskDuration)
+	}
+
+	assert.Equal(t, storage.ErrNotFound, stor.UpdateTask(uuid.NewString(), storage.ModifiableTaskRequest{}))
+
+	assert.Nil(t, stor.SetTaskProgress(task.TaskID, 42.5))
+	found, err = stor.GetTaskByID(task.TaskID)
+	if assert.Nil(t, err) {
+		assert.Equal(t, 42.5, found.Progress)
+	}
+	assert.Equal(t, storage.ErrNotFound, stor.SetTaskProgress(uuid.NewString(), 1))
+}
+
+func testTasksSearch(t *testing.T, stor storage.Backend) {
+	admin := createUser(t, stor, true)
+	tf := createTaskFile(t, stor, admin, time.Now().UTC())
+
+	// created in reverse order so the results can not match the order the tasks were saved in
+	now := time.Now().UTC()
+	tasks := make([]*storage.Task, 5)
+	for i := range tasks {
+		tasks[i] = &storage.Task{
+			TaskName:  "Search Task",
+			FileID:    tf.FileID,
+			CreatedAt: now.Add(-time.Duration(i) * time.Minute),
+		}
+	}
+	tasks[2].TaskName = "Needle"
+	tasks[3].Status = storage.TaskStatusFinished
+	createTasks(t, stor, admin, tasks...)
+
+	assert.Nil(t, stor.SaveCrackedHash(tasks[0].TaskID, "5f4dcc3b5aa765d61d8327deb882cf99", "password", now))
+	assert.Nil(t, stor.SaveCrackedHash(tasks[0].TaskID, "e10adc3949ba59abbe56e057f20f883e", "123456", now))
+
+	newestFirst := []string{tasks[0].TaskID, tasks[1].TaskID, tasks[2].TaskID, tasks[3].TaskID, tasks[4].TaskID}
+	oldestFirst := []string{tasks[4].TaskID, tasks[3].TaskID, tasks[2].TaskID, tasks[1].TaskID, tasks[0].TaskID}
+
+	search := func(page, limit int, orderby, query string, isAscending bool) ([]storage.Task, int) {
+		sr, err := stor.TasksSearch(page, limit, orderby, query, isAscending, *admin)
+		if err != nil {
+			assert.FailNow(t, "failed to search tasks", err.Error())
+		}
+		return sr.Results.([]storage.Task), sr.Total
+	}
+
+	results, total := search(1, 10, "created_at", "", false)
+	assert.Equal(t, 5, total)
+	assert.Equal(t, newestFirst, taskIDs(results))
+
+	results, total = search(1, 10, "created_at", "", true)
+	assert.Equal(t, 5, total)
+	assert.Equal(t, oldestFirst, taskIDs(results))
+
+	// pages are 1 based and the total is counted before the limit is applied
+	results, total = search(1, 2, "created_at", "", false)
+	assert.Equal(t, 5, total)
+	assert.Equal(t, newestFirst[:2], taskIDs(results))
+
+	results, total = search(2, 2, "created_at", "", false)
+	assert.Equal(t, 5, total)
+	assert.Equal(t, newestFirst[2:4], taskIDs(results))
+
+	results, total = search(3, 2, "created_at", "", false)
+	assert.Equal(t, 5, total)
+	assert.Equal(t, newestFirst[4:], taskIDs(results))
+
+	results, _ = search(4, 2, "created_at", "", false)
+	assert.Empty(t, results)
+
+	results, _ = search(1, 10, "task_id", "", true)
+	for i := 1; i < len(results); i++ {
+		assert.True(t, results[i-1].TaskID < results[i].TaskID, "results must be ordered by task_id")
+	}
+
+	results, total = search(1, 10, "created_at", "Needle", false)
+	assert.Equal(t, 1, total)
+	assert.Equal(t, []string{tasks[2].TaskID}, taskIDs(results))
+
+	results, total = search(1, 10, "created_at", string(storage.TaskStatusFinished), false)
+	assert.Equal(t, 1, total)
+	assert.Equal(t, []string{tasks[3].TaskID}, taskIDs(results))
+
+	results, total = search(1, 10, "created_at", "does not exist", false)
+	assert.Equal(t, 0, total)
+	assert.Empty(t, results)
+
+	// the results are filled in with the number of cracked & total passwords
+	results, _ = search(1, 1, "created_at", "", false)
+	if assert.Len(t, results, 1) {
+		assert.Equal(t, 2, results[0].NumberCracked)
+		assert.Equal(t, tf.NumberOfPasswords, results[0].NumberPasswords)
+	}
+}
+
+func testTasksSearchEntitled(t *testing.T, stor storage.Backend) {
+	admin := createUser(t, stor, true)
+	user := createUser(t, stor, false)
+	other := createUser(t, stor, false)
+
+	own := &storage.Task{TaskName: "Mine"}
+	createTasks(t, stor, user, own)
+
+	shared := &storage.Task{TaskName: "Shared"}
+	private := &storage.Task{TaskName: "Private"}
+	createTasks(t, stor, other, shared, private)
+	assert.Nil(t, stor.GrantEntitlement(*user, *shared))
+
+	sr, err := stor.TasksSearch(1, 10, "task_id", "", true, *user)
+	if assert.Nil(t, err) {
+		assert.Equal(t, 2, sr.Total)
+		assert.ElementsMatch(t, []string{own.TaskID, shared.TaskID}, taskIDs(sr.Results.([]storage.Task)))
+	}
+
+	sr, err = stor.TasksSearch(1, 10, "task_id", "Private", true, *user)
+	if assert.Nil(t, err) {
+		assert.Equal(t, 0, sr.Total)
+		assert.Empty(t, sr.Results)
+	}
+
+	// admins see everything
+	sr, err = stor.TasksSearch(1, 10, "task_id", "", true, *admin)
+	if assert.Nil(t, err) {
+		assert.Equal(t, 3, sr.Total)
+	}
+
+	sr, err = stor.TasksSearch(1, 10, "task_id", "", true, *createUser(t, stor, false))
+	if assert.Nil(t, err) {
+		assert.Equal(t, 0, sr.Total)
+		assert.Empty(t, sr.Results)
+	}
+}
+
+func testGetTasksByCaseCode(t *testing.T, stor storage.Backend) {
+	admin := createUser(t, stor, true)
+	user := createUser(t, stor, false)
+	other := createUser(t, stor, false)
+
+	caseCode, otherCaseCode := "IR-1234", "IR-12345"
+	now := time.Now().UTC()
+	first := &storage.Task{
+		TaskName:  "First",
+		CaseCode:  &caseCode,
+		CreatedAt: now.Add(-time.Hour),
+		Engine:    storage.WorkerHashcatEngine,
+		EnginePayload: shared.HashcatUserOptions{
+			HashType:   1000,
+			AttackMode: shared.AttackModeBruteForce,
+			Masks:      shared.GetStrPtr("?a?a?a?a"),
+		},
+	}
+	second := &storage.Task{TaskName: "Second", CaseCode: &caseCode, CreatedAt: now}
+	createTasks(t, stor, user, second, first, &storage.Task{TaskName: "No Case"}, &storage.Task{TaskName: "Other Case", CaseCode: &otherCaseCode})
+
+	private := &storage.Task{TaskName: "Private", CaseCode: &caseCode, CreatedAt: now}
+	createTasks(t, stor, other, private)
+
+	tasks, err := stor.GetTasksByCaseCode(caseCode, *user)
+	if assert.Nil(t, err) && assert.Equal(t, []string{first.TaskID, second.TaskID}, taskIDs(tasks)) {
+		// the payload is decoded the same way as a task read by its ID
+		hashType, ok := storage.TaskHashType(&tasks[0])
+		assert.True(t, ok)
+		assert.Equal(t, 1000, hashType)
+	}
+
+	// admins see every task with the case code
+	tasks, err = stor.GetTasksByCaseCode(caseCode, *admin)
+	if assert.Nil(t, err) {
+		assert.ElementsMatch(t, []string{first.TaskID, second.TaskID, private.TaskID}, taskIDs(tasks))
+	}
+
+	tasks, err = stor.GetTasksByCaseCode("IR-0000", *admin)
+	assert.Nil(t, err)
+	assert.Empty(t, tasks)
+}
+
+func testCrackedHashes(t *testing.T, stor storage.Backend) {
+	user := createUser(t, stor, false)
+	task := &storage.Task{TaskName: "Cracked"}
+	other := &storage.Task{TaskName: "Other"}
+	createTasks(t, stor, user, task, other)
+
+	cracked, err := stor.GetCrackedPasswords(task.TaskID)
+	if assert.Nil(t, err) {
+		assert.Empty(t, *cracked)
+	}
+
+	crackedAt := time.Now().UTC()
+	assert.Nil(t, stor.SaveCrackedHash(task.TaskID, "5f4dcc3b5aa765d61d8327deb882cf99", "password", crackedAt))
+	assert.Nil(t, stor.SaveCrackedHash(task.TaskID, "e10adc3949ba59abbe56e057f20f883e", "123456", crackedAt))
+
+	// a hash is only cracked once per task but may be cracked by many tasks
+	assert.Equal(t, storage.ErrAlreadyExists, stor.SaveCrackedHash(task.TaskID, "5f4dcc3b5aa765d61d8327deb882cf99", "password", crackedAt))
+	assert.Nil(t, stor.SaveCrackedHash(other.TaskID, "5f4dcc3b5aa765d61d8327deb882cf99", "password", crackedAt))
+
+	cracked, err = stor.GetCrackedPasswords(task.TaskID)
+	if assert.Nil(t, err) && assert.Len(t, *cracked, 2) {
+		values := map[string]string{}
+		for _, ch := range *cracked {
+			values[ch.Hash] = ch.Value
+			assert.WithinDuration(t, crackedAt, ch.CrackedAt, time.Second)
+		}
+		assert.Equal(t, map[string]string{
+			"5f4dcc3b5aa765d61d8327deb882cf99": "password",
+			"e10adc3949ba59abbe56e057f20f883e": "123456",
+		}, values)
+	}
+
+	cracked, err = stor.GetCrackedPasswords(other.TaskID)
+	if assert.Nil(t, err) {
+		assert.Len(t, *cracked, 1)
+	}
+}
+
+func testCheckpoints(t *testing.T, stor storage.Backend) {
+	user := createUser(t, stor, false)
+	task := &storage.Task{TaskName: "Checkpoint"}
+	createTasks(t, stor, user, task)
+
+	_, err := stor.GetTaskCheckpoint(task.TaskID)
+	assert.Equal(t, storage.ErrNotFound, err)
+
+	assert.Nil(t, stor.SaveTaskCheckpoint(storage.CheckpointFile{TaskID: task.TaskID, Data: []byte{0x00, 0x01, 0xff}}))
+	data, err := stor.GetTaskCheckpoint(task.TaskID)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0x00, 0x01, 0xff}, data)
+
+	// saving a checkpoint replaces the previous one
+	assert.Nil(t, stor.SaveTaskCheckpoint(storage.CheckpointFile{TaskID: task.TaskID, Data: []byte("restore")}))
+	data, err = stor.GetTaskCheckpoint(task.TaskID)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("restore"), data)
+}
+
+func testDeleteTask(t *testing.T, stor storage.Backend) {
+	user := createUser(t, stor, false)
+	other := createUser(t, stor, false)
+	tf := createTaskFile(t, stor, user, time.Now().UTC())
+
+	task := &storage.Task{TaskName: "Delete", FileID: tf.FileID, WorkUnitCount: 2, Keyspace: 10}
+	remaining := &storage.Task{TaskName: "Remaining", FileID: tf.FileID, WorkUnitCount: 2, Keyspace: 10}
+	createTasks(t, stor, user, task, remaining)
+
+	txn, err := stor.NewTaskCreateTransaction()
+	if err != nil {
+		assert.FailNow(t, "failed to create task transaction", err.Error())
+	}
+	assert.Nil(t, txn.CreateWorkUnits(storage.SplitKeyspace(task.TaskID, task.Keyspace, task.WorkUnitCount)))
+	assert.Nil(t, txn.CreateWorkUnits(storage.SplitKeyspace(remaining.TaskID, remaining.Keyspace, remaining.WorkUnitCount)))
+	assert.Nil(t, txn.Commit())
+
+	for _, taskID := range []string{task.TaskID, remaining.TaskID} {
+		assert.Nil(t, stor.SaveCrackedHash(taskID, "5f4dcc3b5aa765d61d8327deb882cf99", "password", time.Now().UTC()))
+		assert.Nil(t, stor.SaveTaskCheckpoint(storage.CheckpointFile{TaskID: taskID, Data: []byte("checkpoint")}))
+		assert.Nil(t, stor.LogActivity(storage.ActivityLogEntry{OccuredAt: time.Now().UTC(), UserUUID: user.UserUUID, EntityID: taskID, Type: storage.ActivityViewTask}))
+		changeStatus(t, stor, taskID, storage.TaskStatusRunning)
+	}
+	assert.Nil(t, stor.SaveKnownHash(storage.KnownHash{HashType: 0, Hash: "5f4dcc3b5aa765d61d8327deb882cf99", Value: "password", CrackedAt: time.Now().UTC(), TaskID: task.TaskID}))
+	assert.Nil(t, stor.SaveKnownHash(storage.KnownHash{HashType: 0, Hash: "e10adc3949ba59abbe56e057f20f883e", Value: "123456", CrackedAt: time.Now().UTC(), TaskID: remaining.TaskID}))
+	assert.Nil(t, stor.GrantEntitlement(*other, *task))
+
+	// the task file is still used by the remaining task so it's kept
+	removedFile, err := stor.DeleteTask(task.TaskID, storage.DeleteTaskOptions{RemoveUnusedTaskFile: true})
+	assert.Nil(t, err)
+	assert.Nil(t, removedFile)
+
+	_, err = stor.GetTaskByID(task.TaskID)
+	assert.Equal(t, storage.ErrNotFound, err)
+
+	units, err := stor.GetWorkUnits(task.TaskID)
+	assert.Nil(t, err)
+	assert.Empty(t, units, "the work units of a task must be removed with it")
+
+	cracked, err := stor.GetCrackedPasswords(task.TaskID)
+	if assert.Nil(t, err) {
+		assert.Empty(t, *cracked, "the cracked passwords of a task must be removed with it")
+	}
+
+	_, err = stor.GetTaskCheckpoint(task.TaskID)
+	assert.Equal(t, storage.ErrNotFound, err, "the checkpoint of a task must be removed with it")
+
+	ents, err := stor.GetEntitlementsForTask(task.TaskID)
+	assert.Nil(t, err)
+	assert.Empty(t, ents, "the entitlements of a task must be removed with it")
+
+	entries, err := stor.GetActivityLog(task.TaskID)
+	assert.Nil(t, err)
+	assert.Empty(t, entries, "the audit log of a task must be removed with it")
+
+	history, err := stor.GetTaskStatusHistory(task.TaskID)
+	assert.Nil(t, err)
+	assert.Empty(t, history, "the status history of a task must be removed with it")
+
+	known, err := stor.LookupKnownHashes(storage.User{IsSuperUser: true}, 0, []string{"5f4dcc3b5aa765d61d8327deb882cf99", "e10adc3949ba59abbe56e057f20f883e"})
+	if assert.Nil(t, err) && assert.Len(t, known, 1, "the hashes cracked by a task must be removed from the index with it") {
+		assert.Equal(t, remaining.TaskID, known[0].TaskID)
+	}
+
+	// other tasks are left alone
+	_, err = stor.GetTaskByID(remaining.TaskID)
+	assert.Nil(t, err)
+
+	units, err = stor.GetWorkUnits(remaining.TaskID)
+	assert.Nil(t, err)
+	assert.Len(t, units, 2)
+
+	cracked, err = stor.GetCrackedPasswords(remaining.TaskID)
+	if assert.Nil(t, err) {
+		assert.Len(t, *cracked, 1)
+	}
+
+	_, err = stor.GetTaskCheckpoint(remaining.TaskID)
+	assert.Nil(t, err)
+
+	entries, err = stor.GetActivityLog(remaining.TaskID)
+	assert.Nil(t, err)
+	assert.Len(t, entries, 1)
+
+	history, err = stor.GetTaskStatusHistory(remaining.TaskID)
+	assert.Nil(t, err)
+	assert.Len(t, history, 1)
+
+	_, err = stor.DeleteTask(task.TaskID, storage.DeleteTaskOptions{})
+	assert.Equal(t, storage.ErrNotFound, err)
+
+	// the last task using the file removes it when requested
+	removedFile, err = stor.DeleteTask(remaining.TaskID, storage.DeleteTaskOptions{RemoveUnusedTaskFile: true})
+	assert.Nil(t, err)
+	if assert.NotNil(t, removedFile) {
+		assert.Equal(t, tf.FileID, removedFile.FileID)
+		assert.Equal(t, tf.SavedAt, removedFile.SavedAt)
+	}
+
+	_, err = stor.GetTaskFileByID(tf.FileID)
+	assert.Equal(t, storage.ErrNotFound, err)
+
+	entitled, err := stor.CheckEntitlement(user.UserUUID, tf.FileID, storage.EntitlementTaskFile)
+	assert.Nil(t, err)
+	assert.False(t, entitled)
+
+	// nothing should be left behind for the garbage collector
+	if gc, ok := stor.(storage.GarbageCollector); ok {
+		report, err := gc.CollectGarbage(true)
+		if assert.Nil(t, err) {
+			assert.Equal(t, 0, report.Total(), "orphans were left behind: %v", report.Orphans)
+		}
+	}
+}
+
+func testDeleteTaskKeepsFile(t *testing.T, stor storage.Backend) {
+	user := createUser(t, stor, false)
+	tf := createTaskFile(t, stor, user, time.Now().UTC())
+	task := &storage.Task{TaskName: "Delete", FileID: tf.FileID}
+	createTasks(t, stor, user, task)
+
+	removedFile, err := stor.DeleteTask(task.TaskID, storage.DeleteTaskOptions{})
+	assert.Nil(t, err)
+	assert.Nil(t, removedFile)
+
+	_, err = stor.GetTaskFileByID(tf.FileID)
+	assert.Nil(t, err, "the task file must be kept unless it's requested to be removed")
+}
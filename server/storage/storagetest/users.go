@@ -0,0 +1,75 @@
+package storagetest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mandiant/gocrack/server/storage"
+	"github.com/mandiant/gocrack/shared"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testUsers(t *testing.T, stor storage.Backend) {
+	user := createUser(t, stor, false)
+	assert.NotEmpty(t, user.UserUUID, "CreateUser must assign a UUID to the user")
+	assert.WithinDuration(t, time.Now().UTC(), user.CreatedAt, 5*time.Second)
+
+	found, err := stor.GetUserByID(user.UserUUID)
+	if assert.Nil(t, err) {
+		assert.Equal(t, user.Username, found.Username)
+		assert.Equal(t, user.EmailAddress, found.EmailAddress)
+		assert.False(t, found.IsSuperUser)
+	}
+
+	_, err = stor.GetUserByID("00000000-0000-0000-0000-000000000000")
+	assert.Equal(t, storage.ErrNotFound, err)
+
+	// usernames are unique
+	err = stor.CreateUser(&storage.User{Username: user.Username})
+	assert.Equal(t, storage.ErrAlreadyExists, err)
+
+	found, err = stor.SearchForUserByPassword(user.Username, func(password string) bool {
+		return password == "password_hash"
+	})
+	if assert.Nil(t, err) {
+		assert.Equal(t, user.UserUUID, found.UserUUID)
+	}
+
+	_, err = stor.SearchForUserByPassword(user.Username, func(password string) bool { return false })
+	assert.Equal(t, storage.ErrNotFound, err, "a failed password check must look like the user does not exist")
+
+	_, err = stor.SearchForUserByPassword("does_not_exist", func(password string) bool { return true })
+	assert.Equal(t, storage.ErrNotFound, err)
+
+	createUser(t, stor, true)
+	users, err := stor.GetUsers()
+	assert.Nil(t, err)
+	assert.Len(t, users, 2)
+}
+
+func testEditUser(t *testing.T, stor storage.Backend) {
+	user := createUser(t, stor, false)
+
+	assert.Nil(t, stor.EditUser(user.UserUUID, storage.UserModifyRequest{
+		Email:       shared.GetStrPtr("new@example.com"),
+		UserIsAdmin: shared.GetBoolPtr(true),
+	}))
+
+	found, err := stor.GetUserByID(user.UserUUID)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "new@example.com", found.EmailAddress)
+		assert.True(t, found.IsSuperUser)
+		assert.Equal(t, user.Password, found.Password, "fields not in the request must not be modified")
+	}
+
+	assert.Nil(t, stor.EditUser(user.UserUUID, storage.UserModifyRequest{Password: shared.GetStrPtr("new_hash")}))
+	found, err = stor.GetUserByID(user.UserUUID)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "new_hash", found.Password)
+		assert.Equal(t, "new@example.com", found.EmailAddress)
+	}
+
+	err = stor.EditUser("00000000-0000-0000-0000-000000000000", storage.UserModifyRequest{Email: shared.GetStrPtr("x@example.com")})
+	assert.Equal(t, storage.ErrNotFound, err)
+}
@@ -0,0 +1,126 @@
+package storagetest
+
+import (
+	"testing"
+
+	"github.com/mandiant/gocrack/server/storage"
+	"github.com/mandiant/gocrack/shared"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func testWorkUnits(t *testing.T, stor storage.Backend) {
+	user := createUser(t, stor, false)
+	task := &storage.Task{TaskID: uuid.NewString(), TaskName: "Distributed", Keyspace: 100}
+	units := storage.SplitKeyspace(task.TaskID, task.Keyspace, 3)
+	task.WorkUnitCount = len(units)
+
+	txn, err := stor.NewTaskCreateTransaction()
+	if err != nil {
+		assert.FailNow(t, "failed to create task transaction", err.Error())
+	}
+	task.CreatedByUUID = user.UserUUID
+	task.FileID = uuid.NewString()
+	assert.Nil(t, txn.CreateTask(task))
+	assert.Nil(t, txn.CreateWorkUnits(units))
+	assert.Nil(t, txn.Commit())
+
+	found, err := stor.GetWorkUnits(task.TaskID)
+	if assert.Nil(t, err) {
+		assert.Equal(t, units, found, "work units must be returned in keyspace order")
+	}
+
+	_, err = stor.GetWorkUnit(uuid.NewString())
+	assert.Equal(t, storage.ErrNotFound, err)
+	assert.Equal(t, storage.ErrNotFound, stor.UpdateWorkUnit(storage.WorkUnit{UnitID: uuid.NewString()}))
+
+	// every host is given the next queued unit of the task
+	for i, hostname := range []string{"host-a", "host-b", "host-c"} {
+		items, err := stor.GetPendingTasks(storage.GetPendingTasksRequest{Hostname: hostname, CheckForNewTask: true})
+		assert.Nil(t, err)
+		if !assert.Len(t, items, 1) || !assert.NotNil(t, items[0].WorkUnit) {
+			return
+		}
+
+		unit := items[0].WorkUnit
+		assert.Equal(t, task.TaskID, items[0].Payload.(*storage.Task).TaskID)
+		assert.Equal(t, units[i].UnitID, unit.UnitID)
+		assert.Equal(t, hostname, unit.AssignedToHost)
+		assert.Equal(t, storage.TaskStatusDequeued, unit.Status)
+		assert.Equal(t, 1, unit.Attempts)
+		assert.False(t, unit.DispatchedAt.IsZero())
+	}
+
+	items, err := stor.GetPendingTasks(storage.GetPendingTasksRequest{Hostname: "host-d", CheckForNewTask: true})
+	assert.Nil(t, err)
+	assert.Empty(t, items, "a task must not be handed out once every unit is dispatched")
+
+	active, err := stor.GetActiveWorkUnits()
+	assert.Nil(t, err)
+	assert.Len(t, active, 3)
+
+	unit, err := stor.GetWorkUnit(units[0].UnitID)
+	if !assert.Nil(t, err) {
+		return
+	}
+	unit.Status = storage.TaskStatusExhausted
+	unit.Progress = 100
+	assert.Nil(t, stor.UpdateWorkUnit(*unit))
+
+	unit, err = stor.GetWorkUnit(units[1].UnitID)
+	if !assert.Nil(t, err) {
+		return
+	}
+	unit.Status = storage.TaskStatusError
+	unit.Error = shared.GetStrPtr("host went away")
+	assert.Nil(t, stor.UpdateWorkUnit(*unit))
+
+	found, err = stor.GetWorkUnits(task.TaskID)
+	if assert.Nil(t, err) && assert.Len(t, found, 3) {
+		assert.Equal(t, storage.TaskStatusExhausted, found[0].Status)
+		assert.Equal(t, float64(100), found[0].Progress)
+		assert.False(t, found[0].LastUpdatedAt.IsZero())
+		if assert.NotNil(t, found[1].Error) {
+			assert.Equal(t, "host went away", *found[1].Error)
+		}
+	}
+
+	active, err = stor.GetActiveWorkUnits()
+	assert.Nil(t, err)
+	assert.Len(t, active, 1)
+
+	// requeueing leaves units that are done alone and resets the rest
+	assert.Nil(t, stor.RequeueWorkUnits(task.TaskID))
+	found, err = stor.GetWorkUnits(task.TaskID)
+	if assert.Nil(t, err) && assert.Len(t, found, 3) {
+		assert.Equal(t, storage.TaskStatusExhausted, found[0].Status)
+		assert.Equal(t, "host-a", found[0].AssignedToHost)
+		for _, unit := range found[1:] {
+			assert.Equal(t, storage.TaskStatusQueued, unit.Status)
+			assert.Equal(t, "", unit.AssignedToHost)
+			assert.Equal(t, 0, unit.Attempts)
+			assert.Nil(t, unit.Error)
+		}
+	}
+
+	active, err = stor.GetActiveWorkUnits()
+	assert.Nil(t, err)
+	assert.Empty(t, active)
+
+	// a host already running a unit of the task is not given another one
+	changeStatus(t, stor, task.TaskID, storage.TaskStatusRunning)
+	items, err = stor.GetPendingTasks(storage.GetPendingTasksRequest{
+		Hostname:        "host-a",
+		RunningTasks:    []string{task.TaskID},
+		CheckForNewTask: true,
+	})
+	assert.Nil(t, err)
+	assert.Empty(t, items)
+
+	items, err = stor.GetPendingTasks(storage.GetPendingTasksRequest{Hostname: "host-d", CheckForNewTask: true})
+	assert.Nil(t, err)
+	if assert.Len(t, items, 1) && assert.NotNil(t, items[0].WorkUnit) {
+		assert.Equal(t, units[1].UnitID, items[0].WorkUnit.UnitID)
+	}
+}
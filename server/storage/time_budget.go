@@ -0,0 +1,48 @@
+package storage
+
+import "time"
+
+// isProcessing returns true if a worker is processing a task with the status
+func isProcessing(status TaskStatus) bool {
+	return status == TaskStatusRunning || status == TaskStatusStopping
+}
+
+// TrackRuntime updates the runtime of the task as its status changes at the time. A run starts when the task starts running
+// and ends once it's no longer being processed
+func (t *Task) TrackRuntime(status TaskStatus, at time.Time) {
+	switch {
+	case isProcessing(status) && t.RunningSince == nil:
+		t.RunningSince = &at
+	case !isProcessing(status) && t.RunningSince != nil:
+		t.Runtime += int(at.Sub(*t.RunningSince).Round(time.Second) / time.Second)
+		t.RunningSince = nil
+	}
+}
+
+// RuntimeAt returns the number of seconds workers have spent processing the task up until the time
+func (t Task) RuntimeAt(at time.Time) int {
+	runtime := t.Runtime
+	if t.RunningSince != nil && at.After(*t.RunningSince) {
+		runtime += int(at.Sub(*t.RunningSince) / time.Second)
+	}
+	return runtime
+}
+
+// TimeRemaining returns the number of seconds left of the task's time budget at the time. ok is false if the task
+// does not have a budget
+func (t Task) TimeRemaining(at time.Time) (remaining int, ok bool) {
+	if t.TaskDuration <= 0 {
+		return 0, false
+	}
+
+	if remaining = t.TaskDuration - t.RuntimeAt(at); remaining < 0 {
+		remaining = 0
+	}
+	return remaining, true
+}
+
+// IsOutOfTime returns true if the task has used up its time budget at the time
+func (t Task) IsOutOfTime(at time.Time) bool {
+	remaining, ok := t.TimeRemaining(at)
+	return ok && remaining == 0
+}
@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrackRuntime(t *testing.T) {
+	start := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	task := Task{TaskDuration: 3600}
+
+	remaining, ok := task.TimeRemaining(start)
+	assert.True(t, ok)
+	assert.Equal(t, 3600, remaining)
+
+	// the run continues while the task is stopping and ends once it's stopped
+	task.TrackRuntime(TaskStatusRunning, start)
+	task.TrackRuntime(TaskStatusStopping, start.Add(10*time.Minute))
+	assert.Equal(t, 900, task.RuntimeAt(start.Add(15*time.Minute)))
+
+	task.TrackRuntime(TaskStatusStopped, start.Add(20*time.Minute))
+	assert.Nil(t, task.RunningSince)
+	assert.Equal(t, 1200, task.Runtime)
+	assert.Equal(t, 1200, task.RuntimeAt(start.Add(time.Hour)))
+
+	// time spent waiting in the queue does not count against the budget
+	task.TrackRuntime(TaskStatusQueued, start.Add(2*time.Hour))
+	task.TrackRuntime(TaskStatusRunning, start.Add(3*time.Hour))
+	remaining, _ = task.TimeRemaining(start.Add(3*time.Hour + 30*time.Minute))
+	assert.Equal(t, 600, remaining)
+	assert.False(t, task.IsOutOfTime(start.Add(3*time.Hour+30*time.Minute)))
+	assert.True(t, task.IsOutOfTime(start.Add(4*time.Hour)))
+
+	task.TrackRuntime(TaskStatusOutOfTime, start.Add(4*time.Hour))
+	assert.Equal(t, 4800, task.Runtime)
+	remaining, _ = task.TimeRemaining(start.Add(5 * time.Hour))
+	assert.Equal(t, 0, remaining)
+
+	// tasks without a budget can run forever
+	task.TaskDuration = 0
+	_, ok = task.TimeRemaining(start)
+	assert.False(t, ok)
+	assert.False(t, task.IsOutOfTime(start.Add(24*time.Hour)))
+}
@@ -6,6 +6,10 @@ type ModifiableTaskRequest struct {
 	AssignedToDevices *CLDevices
 	Status            *TaskStatus
 	TaskDuration      *int
+	// DisableHashReuse removes the task's hashes from the known hash index when set to true
+	DisableHashReuse *bool
+	// Schedule replaces when the task is allowed to run
+	Schedule *TaskSchedule
 }
 
 // UserModifyRequest contains the fields in `User` that are allowed to be modified
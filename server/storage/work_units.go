@@ -0,0 +1,118 @@
+package storage
+
+import "fmt"
+
+// WorkUnitSummary is the combined state of every work unit belonging to a distributed task
+type WorkUnitSummary struct {
+	Total     int     `json:"total"`
+	Queued    int     `json:"queued"`
+	Active    int     `json:"active"`
+	Stopped   int     `json:"stopped"`
+	Exhausted int     `json:"exhausted"`
+	Finished  int     `json:"finished"`
+	Failed    int     `json:"failed"`
+	OutOfTime int     `json:"out_of_time"`
+	Progress  float64 `json:"progress"`
+}
+
+// SplitKeyspace carves the keyspace of a task into at most n evenly sized work units
+func SplitKeyspace(taskID string, keyspace uint64, n int) []WorkUnit {
+	if n < 1 || keyspace == 0 {
+		return nil
+	}
+
+	if uint64(n) > keyspace {
+		n = int(keyspace)
+	}
+
+	size := keyspace / uint64(n)
+	if keyspace%uint64(n) != 0 {
+		size++
+	}
+
+	units := make([]WorkUnit, 0, n)
+	for skip := uint64(0); skip < keyspace; skip += size {
+		limit := size
+		if keyspace-skip < size {
+			limit = keyspace - skip
+		}
+
+		units = append(units, WorkUnit{
+			UnitID: fmt.Sprintf("%s-%d", taskID, len(units)),
+			TaskID: taskID,
+			Index:  len(units),
+			Skip:   skip,
+			Limit:  limit,
+			Status: TaskStatusQueued,
+		})
+	}
+	return units
+}
+
+// SummarizeWorkUnits counts the units in each state and calculates the progress of the task across its keyspace
+func SummarizeWorkUnits(keyspace uint64, units []WorkUnit) WorkUnitSummary {
+	var processed float64
+	summary := WorkUnitSummary{Total: len(units)}
+
+	for _, unit := range units {
+		switch unit.Status {
+		case TaskStatusQueued:
+			summary.Queued++
+		case TaskStatusDequeued, TaskStatusRunning, TaskStatusStopping:
+			summary.Active++
+		case TaskStatusStopped:
+			summary.Stopped++
+		case TaskStatusExhausted:
+			summary.Exhausted++
+		case TaskStatusFinished:
+			summary.Finished++
+		case TaskStatusError:
+			summary.Failed++
+		case TaskStatusOutOfTime:
+			summary.OutOfTime++
+		}
+
+		switch unit.Status {
+		case TaskStatusExhausted, TaskStatusFinished:
+			processed += float64(unit.Limit)
+		default:
+			processed += float64(unit.Limit) * unit.Progress / 100
+		}
+	}
+
+	if keyspace > 0 {
+		summary.Progress = processed / float64(keyspace) * 100
+	}
+	return summary
+}
+
+// TaskStatus determines the status of the parent task from the state of its work units
+func (s WorkUnitSummary) TaskStatus(current TaskStatus) TaskStatus {
+	switch {
+	case s.Finished > 0:
+		// every hash has been cracked so the remaining units do not need to run
+		return TaskStatusFinished
+	case current == TaskStatusStopping:
+		if s.Active > 0 {
+			return TaskStatusStopping
+		}
+		return TaskStatusStopped
+	case s.Active > 0:
+		return TaskStatusRunning
+	case s.OutOfTime > 0:
+		// the queued units are not handed out once the time budget of the task is used up
+		return TaskStatusOutOfTime
+	case s.Queued > 0:
+		if current == TaskStatusQueued {
+			return TaskStatusQueued
+		}
+		return TaskStatusRunning
+	case s.Failed > 0:
+		return TaskStatusError
+	case s.Stopped > 0:
+		return TaskStatusStopped
+	case s.Exhausted == s.Total && s.Total > 0:
+		return TaskStatusExhausted
+	}
+	return current
+}
@@ -3,4 +3,7 @@
 
 package server
 
-import _ "github.com/mandiant/gocrack/server/storage/bdb"
+import (
+	_ "github.com/mandiant/gocrack/server/storage/bdb"
+	_ "github.com/mandiant/gocrack/server/storage/sqldb"
+)
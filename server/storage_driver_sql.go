@@ -0,0 +1,6 @@
+//go:build stor_sql
+// +build stor_sql
+
+package server
+
+import _ "github.com/mandiant/gocrack/server/storage/sqldb"
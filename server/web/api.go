@@ -6,9 +6,11 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"sync"
 
 	"github.com/mandiant/gocrack/server/authentication"
 	"github.com/mandiant/gocrack/server/filemanager"
+	"github.com/mandiant/gocrack/server/scheduler"
 	"github.com/mandiant/gocrack/server/storage"
 	"github.com/mandiant/gocrack/server/workmgr"
 	"github.com/mandiant/gocrack/shared"
@@ -20,18 +22,23 @@ var (
 )
 
 type Server struct {
-	stor storage.Backend
-	wmgr *workmgr.WorkerManager
-	auth *authentication.AuthWrapper
-	netl net.Listener
-	rt   *RealtimeServer
-	fm   *filemanager.Context
+	stor  storage.Backend
+	wmgr  *workmgr.WorkerManager
+	auth  *authentication.AuthWrapper
+	netl  net.Listener
+	rt    *RealtimeServer
+	fm    *filemanager.Context
+	sched scheduler.Scheduler
+
+	// pipelineMu serializes the starting of pipeline stages so a stage is never started twice
+	pipelineMu   sync.Mutex
+	pipelineHndl uint
 
 	*http.Server
 }
 
-// NewServer creates an HTTP API server
-func NewServer(cfg Config, stor storage.Backend, wmgr *workmgr.WorkerManager, auth *authentication.AuthWrapper, fm *filemanager.Context) (*Server, error) {
+// NewServer creates an HTTP API server. The scheduler is used to show admins the order queued tasks will be handed out in
+func NewServer(cfg Config, stor storage.Backend, wmgr *workmgr.WorkerManager, auth *authentication.AuthWrapper, fm *filemanager.Context, sched scheduler.Scheduler) (*Server, error) {
 	var l net.Listener
 	var err error
 
@@ -51,12 +58,17 @@ func NewServer(cfg Config, stor storage.Backend, wmgr *workmgr.WorkerManager, au
 	}
 
 	svr := &Server{
-		stor: stor,
-		wmgr: wmgr,
-		netl: l,
-		auth: auth,
-		rt:   NewRealtimeServer(wmgr, stor),
-		fm:   fm,
+		stor:  stor,
+		wmgr:  wmgr,
+		netl:  l,
+		auth:  auth,
+		rt:    NewRealtimeServer(wmgr, stor),
+		fm:    fm,
+		sched: sched,
+	}
+
+	if svr.pipelineHndl, err = wmgr.Subscribe(workmgr.TaskStatusTopic, svr.onPipelineTaskStatus); err != nil {
+		return nil, err
 	}
 
 	svr.Server = newHTTPServer(cfg, svr)
@@ -65,6 +77,7 @@ func NewServer(cfg Config, stor storage.Backend, wmgr *workmgr.WorkerManager, au
 
 // Start the API server and block
 func (s *Server) Start() error {
+	s.resumePipelines()
 	return s.Serve(s.netl)
 }
 
@@ -78,6 +91,7 @@ func SetVersionInfo(revision, ctime string) {
 func (s *Server) Stop() error {
 	// Stop the Realtime Streaming Server
 	s.rt.Stop()
+	s.wmgr.Unsubscribe(s.pipelineHndl)
 	if err := s.Shutdown(context.Background()); err != nil && err != http.ErrServerClosed {
 		return err
 	}
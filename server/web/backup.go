@@ -0,0 +1,100 @@
+package web
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/mandiant/gocrack/server/backup"
+	"github.com/mandiant/gocrack/server/storage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// sendBackup writes the backup produced by fn into a temporary file and sends it to the user once it's complete
+// so a failure halfway through is returned as an error rather than a truncated download
+func sendBackup(c *gin.Context, filename string, fn func(w io.Writer) error) *WebAPIError {
+	fd, err := ioutil.TempFile("", "gocrack_backup")
+	if err != nil {
+		return &WebAPIError{
+			StatusCode: http.StatusInternalServerError,
+			Err:        err,
+			UserError:  "The server was unable to process your request. Please try again later",
+		}
+	}
+	defer os.Remove(fd.Name())
+	defer fd.Close()
+
+	if err = fn(fd); err != nil {
+		return &WebAPIError{
+			StatusCode: http.StatusInternalServerError,
+			Err:        err,
+			UserError:  "The server was unable to create the backup",
+		}
+	}
+
+	if _, err = fd.Seek(0, io.SeekStart); err != nil {
+		return &WebAPIError{
+			StatusCode: http.StatusInternalServerError,
+			Err:        err,
+			UserError:  "The server was unable to process your request. Please try again later",
+		}
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+	http.ServeContent(c.Writer, c.Request, filename, time.Now(), fd)
+	return nil
+}
+
+func (s *Server) webDownloadSnapshot(c *gin.Context) *WebAPIError {
+	snapshotter, ok := s.stor.(storage.Snapshotter)
+	if !ok {
+		return &WebAPIError{
+			StatusCode: http.StatusNotImplemented,
+			UserError:  "The storage backend does not support snapshots",
+		}
+	}
+
+	claim := getClaimInformation(c)
+	log.Info().Str("user", claim.Username).Msg("Creating database snapshot")
+
+	return sendBackup(c, fmt.Sprintf("gocrack-%s.db", time.Now().UTC().Format("20060102T150405")), func(w io.Writer) error {
+		_, err := snapshotter.Snapshot(w)
+		return err
+	})
+}
+
+func (s *Server) webDownloadExport(c *gin.Context) *WebAPIError {
+	var includeFiles bool
+
+	if val := c.Query("include_files"); val != "" {
+		var err error
+		if includeFiles, err = strconv.ParseBool(val); err != nil {
+			return &WebAPIError{
+				StatusCode: http.StatusBadRequest,
+				Err:        err,
+				UserError:  "include_files must be a boolean",
+			}
+		}
+	}
+
+	if _, ok := s.stor.(storage.Exporter); !ok {
+		return &WebAPIError{
+			StatusCode: http.StatusNotImplemented,
+			UserError:  "The storage backend does not support exports",
+		}
+	}
+
+	claim := getClaimInformation(c)
+	log.Info().Str("user", claim.Username).Bool("include_files", includeFiles).Msg("Exporting database")
+
+	return sendBackup(c, fmt.Sprintf("gocrack-%s.tar.gz", time.Now().UTC().Format("20060102T150405")), func(w io.Writer) error {
+		_, err := backup.Export(w, s.stor, backup.ExportOptions{IncludeFiles: includeFiles})
+		return err
+	})
+}
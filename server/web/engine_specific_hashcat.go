@@ -4,7 +4,9 @@ import (
 	"net/http"
 
 	"github.com/mandiant/gocat/v6/types"
+	"github.com/mandiant/gocrack/server/storage"
 	"github.com/mandiant/gocrack/shared"
+	"github.com/mandiant/gocrack/shared/enginedef"
 
 	"github.com/gin-gonic/gin"
 )
@@ -23,3 +25,20 @@ func (s *Server) apiHashcatGetTaskModes(c *gin.Context) {
 
 	c.JSON(http.StatusOK, out)
 }
+
+// hashcatUsernameOption enables --username on a hashcat task whose task file prefixes each hash with the username of its
+// account. Any other payload is returned as is
+func hashcatUsernameOption(payload enginedef.Payload, tf *storage.TaskFile) enginedef.Payload {
+	opts, ok := payload.(shared.HashcatUserOptions)
+	if !ok || !tf.Format.HasUsernamePrefix() {
+		return payload
+	}
+
+	var tuning shared.HashcatTuningOptions
+	if opts.Tuning != nil {
+		tuning = *opts.Tuning
+	}
+	tuning.Username = true
+	opts.Tuning = &tuning
+	return opts
+}
@@ -5,7 +5,7 @@ import (
 	"net/http"
 
 	"github.com/mandiant/gocrack/server/storage"
-	"github.com/mandiant/gocrack/shared"
+	"github.com/mandiant/gocrack/shared/enginedef"
 
 	"github.com/gin-gonic/gin"
 )
@@ -118,26 +118,13 @@ func (s *Server) checkIfTaskFilesArePresent(task *storage.Task) (bool, error) {
 		goto CheckError
 	}
 
-	switch ep := task.EnginePayload.(type) {
-	case shared.HashcatUserOptions:
-		if ep.DictionaryFile != nil {
-			if _, err = s.stor.GetEngineFileByID(*ep.DictionaryFile); err != nil {
+	if ep, ok := task.EnginePayload.(enginedef.Payload); ok {
+		for _, engineFileID := range ep.EngineFiles() {
+			if _, err = s.stor.GetEngineFileByID(engineFileID); err != nil {
 				goto CheckError
 			}
 		}
-
-		if ep.ManglingRuleFile != nil {
-			if _, err = s.stor.GetEngineFileByID(*ep.ManglingRuleFile); err != nil {
-				goto CheckError
-			}
-		}
-
-		if ep.Masks != nil {
-			if _, err = s.stor.GetEngineFileByID(*ep.Masks); err != nil {
-				goto CheckError
-			}
-		}
-	default:
+	} else {
 		return false, errors.New("unknown task.EnginePayload")
 	}
 	return true, nil
@@ -1,6 +1,7 @@
 package web
 
 import (
+	"bufio"
 	"fmt"
 	"io"
 	"net/http"
@@ -11,6 +12,7 @@ import (
 
 	"github.com/mandiant/gocrack/server/filemanager"
 	"github.com/mandiant/gocrack/server/storage"
+	"github.com/mandiant/gocrack/shared"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -26,6 +28,8 @@ func (s FileType) MarshalJSON() ([]byte, error) {
 		return []byte("\"Mask(s)\""), nil
 	case storage.EngineFileRules:
 		return []byte("\"Rule(s)\""), nil
+	case storage.EngineFileCharset:
+		return []byte("\"Charset\""), nil
 	}
 	return []byte("\"Unknown\""), nil
 }
@@ -99,10 +103,12 @@ func (s *Server) webUploadEngineFile(c *gin.Context) *WebAPIError {
 		sf.FileType = storage.EngineFileMasks
 	case "2", "rules", "rule":
 		sf.FileType = storage.EngineFileRules
+	case "3", "charset", "hcchr":
+		sf.FileType = storage.EngineFileCharset
 	default:
 		return &WebAPIError{
 			StatusCode: http.StatusBadRequest,
-			UserError:  "type must be either dictionary (0), masks (1), or rules (2)",
+			UserError:  "type must be either dictionary (0), masks (1), rules (2), or charset (3)",
 		}
 	}
 
@@ -124,6 +130,17 @@ func (s *Server) webUploadEngineFile(c *gin.Context) *WebAPIError {
 		goto ServerError
 	}
 
+	if sf.FileType == storage.EngineFileMasks {
+		if err = validateMaskFile(fresp.SavedTo); err != nil {
+			os.Remove(fresp.SavedTo)
+			return &WebAPIError{
+				StatusCode: http.StatusBadRequest,
+				Err:        err,
+				UserError:  err.Error(),
+			}
+		}
+	}
+
 	if txn, err = s.stor.NewEngineFileTransaction(); err != nil {
 		goto ServerError
 	}
@@ -166,6 +183,25 @@ ServerError:
 	}
 }
 
+// validateMaskFile checks the syntax of every mask in an uploaded mask file so a bad mask is caught before a task uses it
+func validateMaskFile(path string) error {
+	fd, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+
+	lineno := 0
+	scanner := bufio.NewScanner(fd)
+	for scanner.Scan() {
+		lineno++
+		if err = shared.ValidateHcmaskLine(strings.TrimRight(scanner.Text(), "\r")); err != nil {
+			return fmt.Errorf("line %d: %w", lineno, err)
+		}
+	}
+	return scanner.Err()
+}
+
 func (s *Server) webDownloadEngineFile(c *gin.Context) *WebAPIError {
 	var (
 		fileid    = c.Param("fileid")
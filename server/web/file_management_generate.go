@@ -0,0 +1,201 @@
+package web
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mandiant/gocrack/server/analytics"
+	"github.com/mandiant/gocrack/server/authentication"
+	"github.com/mandiant/gocrack/server/storage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// GenerateEngineFileRequest asks for a mask or rule file derived from the cracked passwords of one or more tasks
+type GenerateEngineFileRequest struct {
+	TaskIDs []string `json:"task_ids"`
+	// Type is either masks or rules
+	Type     string `json:"type"`
+	FileName string `json:"file_name"`
+	// Limit caps the number of masks or rules in the file. 0 keeps all of them
+	Limit int `json:"limit"`
+	// Order is either efficiency (the default) or frequency and only applies to masks
+	Order  string `json:"order"`
+	Shared bool   `json:"shared"`
+}
+
+// webGenerateEngineFile saves a mask or rule file learned from the cracked passwords of the tasks as an engine file
+// owned by the user. The passwords are read so a view of them is logged against every task
+func (s *Server) webGenerateEngineFile(c *gin.Context) *WebAPIError {
+	var req GenerateEngineFileRequest
+
+	if err := c.BindJSON(&req); err != nil {
+		return &WebAPIError{
+			StatusCode: http.StatusBadRequest,
+			Err:        err,
+			UserError:  "The request was not valid",
+		}
+	}
+
+	claim := getClaimInformation(c)
+	sf := storage.EngineFile{
+		FileID:         uuid.NewString(),
+		FileName:       strings.TrimSpace(req.FileName),
+		UploadedByUUID: claim.UserUUID,
+		UploadedAt:     time.Now().UTC(),
+		IsShared:       req.Shared,
+	}
+
+	errs := make([]string, 0)
+	switch strings.ToLower(req.Type) {
+	case "masks", "mask":
+		sf.FileType = storage.EngineFileMasks
+		if sf.FileName == "" {
+			sf.FileName = "generated.hcmask"
+		}
+	case "rules", "rule":
+		sf.FileType = storage.EngineFileRules
+		if sf.FileName == "" {
+			sf.FileName = "generated.rule"
+		}
+	default:
+		errs = append(errs, "type must be either masks or rules")
+	}
+
+	if req.Order == "" {
+		req.Order = analytics.MaskOrderEfficiency
+	}
+	if req.Order != analytics.MaskOrderEfficiency && req.Order != analytics.MaskOrderFrequency {
+		errs = append(errs, "order must be either efficiency or frequency")
+	}
+
+	if req.Limit < 0 {
+		errs = append(errs, "limit cannot be negative")
+	}
+
+	if len(req.TaskIDs) == 0 {
+		errs = append(errs, "task_ids must contain at least one task")
+	}
+
+	tasks, taskErrs, err := s.entitledTasks(claim, req.TaskIDs)
+	if err != nil {
+		return internalServerError(err)
+	}
+	errs = append(errs, taskErrs...)
+
+	if len(errs) > 0 {
+		c.JSON(http.StatusBadRequest, APIValidationErrors{
+			Valid:  false,
+			Errors: errs,
+		})
+		return nil
+	}
+
+	entries, err := s.crackedEntries(tasks)
+	if err != nil {
+		return internalServerError(err)
+	}
+	s.logPasswordViews(c, tasks)
+
+	var generated []analytics.Count
+	if sf.FileType == storage.EngineFileMasks {
+		generated = analytics.GenerateMasks(entries, req.Limit, req.Order)
+	} else {
+		generated = analytics.GenerateRules(entries, req.Limit)
+	}
+
+	if len(generated) == 0 {
+		return &WebAPIError{
+			StatusCode:            http.StatusBadRequest,
+			Err:                   errors.New("the tasks have not cracked any passwords that a file can be generated from"),
+			CanErrorBeShownToUser: true,
+		}
+	}
+
+	var buf bytes.Buffer
+	for _, line := range generated {
+		buf.WriteString(line.Value + "\n")
+	}
+
+	description := fmt.Sprintf("Generated from the cracked passwords of %d task(s)", len(tasks))
+	sf.Description = &description
+	return s.saveGeneratedEngineFile(c, sf, &buf)
+}
+
+// saveGeneratedEngineFile saves the contents of a generated engine file & entitles the user that generated it
+func (s *Server) saveGeneratedEngineFile(c *gin.Context, sf storage.EngineFile, contents io.Reader) *WebAPIError {
+	fresp, err := s.fm.SaveFile(io.NopCloser(contents), sf.FileName, sf.FileID, sf.FileType)
+	if err != nil || fresp == nil {
+		if err == nil {
+			err = errors.New("failed to write the generated file")
+		}
+		return internalServerError(err)
+	}
+
+	sf.FileSize = fresp.Size
+	sf.NumberOfEntries = fresp.NumberOfLines
+	sf.SavedAt = fresp.SavedTo
+	sf.SHA1Hash = fresp.SHA1
+	sf.LastUpdatedAt = sf.UploadedAt
+
+	txn, err := s.stor.NewEngineFileTransaction()
+	if err != nil {
+		os.Remove(sf.SavedAt)
+		return internalServerError(err)
+	}
+	defer txn.Rollback() // wont be rolled back if the commit occurs
+
+	if err = txn.SaveEngineFile(sf); err == nil {
+		if err = txn.AddEntitlement(sf, sf.UploadedByUUID); err == nil {
+			err = txn.Commit()
+		}
+	}
+
+	if err != nil {
+		os.Remove(sf.SavedAt)
+		return internalServerError(err)
+	}
+
+	c.JSON(http.StatusCreated, &UploadEngineFileResponse{
+		FileID:          sf.FileID,
+		FileSize:        sf.FileSize,
+		NumberOfEntries: sf.NumberOfEntries,
+		SHA1:            sf.SHA1Hash,
+	})
+	return nil
+}
+
+// entitledTasks returns the requested tasks along with a validation error for every task that does not exist or the user
+// is not entitled to
+func (s *Server) entitledTasks(claim *authentication.AuthClaim, taskIDs []string) ([]storage.Task, []string, error) {
+	var errs []string
+
+	tasks := make([]storage.Task, 0, len(taskIDs))
+	for _, taskID := range taskIDs {
+		task, err := s.stor.GetTaskByID(taskID)
+		if err != nil && err != storage.ErrNotFound {
+			return nil, nil, err
+		}
+
+		entitled := claim.IsAdmin
+		if task != nil && !entitled {
+			if entitled, err = s.stor.CheckEntitlement(claim.UserUUID, task.TaskID, storage.EntitlementTask); err != nil && err != storage.ErrNotFound {
+				return nil, nil, err
+			}
+		}
+
+		if task == nil || !entitled {
+			errs = append(errs, fmt.Sprintf("task_ids: `%s` does not exist", taskID))
+			continue
+		}
+		tasks = append(tasks, *task)
+	}
+	return tasks, errs, nil
+}
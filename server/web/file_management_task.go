@@ -11,6 +11,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/mandiant/gocrack/server/hashfile"
 	"github.com/mandiant/gocrack/server/storage"
 
 	"github.com/gin-gonic/gin"
@@ -30,12 +31,33 @@ func (s APIEngine) MarshalJSON() ([]byte, error) {
 	return []byte("\"Unknown\""), nil
 }
 
+type APIFormat storage.TaskFileFormat
+
+func (s APIFormat) MarshalJSON() ([]byte, error) {
+	switch storage.TaskFileFormat(s) {
+	case storage.TaskFileFormatHashes:
+		return []byte("\"Hashes\""), nil
+	case storage.TaskFileFormatUserHash:
+		return []byte("\"Username:Hash\""), nil
+	case storage.TaskFileFormatPwdump:
+		return []byte("\"pwdump\""), nil
+	case storage.TaskFileFormatNetNTLM:
+		return []byte("\"NetNTLM\""), nil
+	}
+	return []byte("\"Unknown\""), nil
+}
+
 // UploadedFileResponse is returned on a successful upload
 type UploadedFileResponse struct {
 	SHA1       string    `json:"sha1"`
 	FileUUID   string    `json:"file_uuid"`
 	FileSize   int64     `json:"file_size"`
 	UploadedAt time.Time `json:"uploaded_at"`
+	// KnownHashes is the number of hashes in the file that have already been cracked by another task
+	KnownHashes int `json:"known_hashes,omitempty"`
+	// Format is the detected layout of the file and Accounts the number of usernames found in it
+	Format   APIFormat `json:"format"`
+	Accounts int       `json:"accounts,omitempty"`
 }
 
 // TaskFileItem describes a file that is used for tasks
@@ -50,6 +72,7 @@ type TaskFileItem struct {
 	ForEngine         APIEngine `json:"use_in_engine"`
 	NumberOfPasswords int       `json:"num_passwords"`
 	NumberOfSalts     int       `json:"num_salts"`
+	Format            APIFormat `json:"format"`
 }
 
 type TaskFileLintError struct {
@@ -69,6 +92,7 @@ func convStorTaskFileItem(i storage.TaskFile) *TaskFileItem {
 		ForEngine:         APIEngine(i.ForEngine),
 		NumberOfPasswords: i.NumberOfPasswords,
 		NumberOfSalts:     i.NumberOfSalts,
+		Format:            APIFormat(i.Format),
 	}
 }
 
@@ -104,6 +128,8 @@ func getFileFromContext(c *gin.Context) (f io.ReadCloser, filename string, e *We
 func (s *Server) webUploadTaskFile(c *gin.Context) *WebAPIError {
 	var err error
 	var txn storage.TaskFileTxn
+	var knownHashes int
+	var accounts []storage.TaskFileAccount
 
 	claim := getClaimInformation(c)
 
@@ -165,11 +191,32 @@ func (s *Server) webUploadTaskFile(c *gin.Context) *WebAPIError {
 		defer file.Close()
 
 		hashes := 0
+		detected := false
 		errors := make([]string, 0)
 		scanner := bufio.NewScanner(file)
 		for scanner.Scan() {
 			val := scanner.Text()
-			if err := hashvalidate.ValidateHash(ftint, val); err != nil {
+			// the format of the file is detected from its first line and every other line must match it
+			if !detected && val != "" {
+				tf.Format = hashfile.DetectFormat(ftint, val)
+				detected = true
+			}
+
+			username, hash, err := hashfile.ParseLine(tf.Format, ftint, val)
+			if err != nil {
+				errors = append(errors, err.Error())
+				continue
+			}
+
+			if username != "" {
+				accounts = append(accounts, storage.TaskFileAccount{
+					FileID:   tf.FileID,
+					Username: username,
+					Hash:     hash,
+				})
+			}
+
+			if err := hashvalidate.ValidateHash(ftint, hash); err != nil {
 				// TODO: We should raise some warning here
 				if strings.Contains(err.Error(), "does not exist") {
 					continue
@@ -201,6 +248,7 @@ func (s *Server) webUploadTaskFile(c *gin.Context) *WebAPIError {
 		}
 
 		tf.NumberOfPasswords = hashes
+		knownHashes = s.countKnownHashes(storage.User{UserUUID: claim.UserUUID, IsSuperUser: claim.IsAdmin}, ftint, fresp.SavedTo, tf.Format)
 	}
 
 	if txn, err = s.stor.NewTaskFileTransaction(); err != nil {
@@ -215,6 +263,12 @@ func (s *Server) webUploadTaskFile(c *gin.Context) *WebAPIError {
 		goto ServerError
 	}
 
+	if len(accounts) > 0 {
+		if err = txn.SaveTaskFileAccounts(accounts); err != nil {
+			goto ServerError
+		}
+	}
+
 	if err = txn.AddEntitlement(tf, claim.UserUUID); err != nil {
 		goto ServerError
 	}
@@ -224,10 +278,13 @@ func (s *Server) webUploadTaskFile(c *gin.Context) *WebAPIError {
 	}
 
 	c.JSON(http.StatusCreated, &UploadedFileResponse{
-		SHA1:       fresp.SHA1,
-		FileUUID:   tf.FileID,
-		FileSize:   fresp.Size,
-		UploadedAt: tf.UploadedAt,
+		SHA1:        fresp.SHA1,
+		FileUUID:    tf.FileID,
+		FileSize:    fresp.Size,
+		UploadedAt:  tf.UploadedAt,
+		KnownHashes: knownHashes,
+		Format:      APIFormat(tf.Format),
+		Accounts:    len(accounts),
 	})
 	return nil
 
@@ -0,0 +1,141 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mandiant/gocrack/server/storage"
+	"github.com/mandiant/gocrack/server/wordlist"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// CreateWordlistRequest asks for a dictionary of the passwords cracked by either a list of tasks or the tasks of a case
+type CreateWordlistRequest struct {
+	TaskIDs  []string `json:"task_ids"`
+	CaseCode string   `json:"case_code"`
+	FileName string   `json:"file_name"`
+	// AutoUpdate rebuilds the dictionary as the tasks crack more passwords
+	AutoUpdate bool `json:"auto_update"`
+}
+
+// CrackedWordlistItem describes a dictionary built from cracked passwords
+type CrackedWordlistItem struct {
+	EngineFileItem
+	TaskIDs     []string  `json:"task_ids"`
+	CaseCode    string    `json:"case_code,omitempty"`
+	AutoUpdate  bool      `json:"auto_update"`
+	LastBuiltAt time.Time `json:"last_built_at"`
+}
+
+// webCreateWordlist builds a dictionary engine file of the distinct passwords cracked by the tasks. Only the user and
+// the users entitled to all of the tasks are entitled to it
+func (s *Server) webCreateWordlist(c *gin.Context) *WebAPIError {
+	var req CreateWordlistRequest
+
+	if err := c.BindJSON(&req); err != nil {
+		return &WebAPIError{
+			StatusCode: http.StatusBadRequest,
+			Err:        err,
+			UserError:  "The request was not valid",
+		}
+	}
+
+	claim := getClaimInformation(c)
+	now := time.Now().UTC()
+	wl := storage.CrackedWordlist{
+		FileID:        uuid.NewString(),
+		CaseCode:      strings.TrimSpace(req.CaseCode),
+		AutoUpdate:    req.AutoUpdate,
+		CreatedByUUID: claim.UserUUID,
+		CreatedAt:     now,
+	}
+
+	var (
+		tasks []storage.Task
+		errs  []string
+		err   error
+	)
+	switch {
+	case wl.CaseCode != "" && len(req.TaskIDs) > 0:
+		errs = append(errs, "only one of task_ids or case_code may be set")
+	case wl.CaseCode != "":
+		if tasks, err = s.stor.GetTasksByCaseCode(wl.CaseCode, storage.User{
+			UserUUID:    claim.UserUUID,
+			IsSuperUser: claim.IsAdmin,
+		}); err != nil {
+			return internalServerError(err)
+		}
+
+		if len(tasks) == 0 {
+			errs = append(errs, fmt.Sprintf("case_code: `%s` does not exist", wl.CaseCode))
+		}
+	case len(req.TaskIDs) > 0:
+		if tasks, errs, err = s.entitledTasks(claim, req.TaskIDs); err != nil {
+			return internalServerError(err)
+		}
+		wl.TaskIDs = req.TaskIDs
+	default:
+		errs = append(errs, "either task_ids or case_code must be set")
+	}
+
+	if len(errs) > 0 {
+		c.JSON(http.StatusBadRequest, APIValidationErrors{
+			Valid:  false,
+			Errors: errs,
+		})
+		return nil
+	}
+
+	fileName := strings.TrimSpace(req.FileName)
+	if fileName == "" {
+		fileName = "cracked.dict"
+		if wl.CaseCode != "" {
+			fileName = exportFileName(wl.CaseCode) + ".dict"
+		}
+	}
+
+	description := fmt.Sprintf("Passwords cracked by %d task(s)", len(tasks))
+	if wl.CaseCode != "" {
+		description = fmt.Sprintf("Passwords cracked by the tasks of case %s", wl.CaseCode)
+	}
+
+	ef, err := wordlist.NewBuilder(s.stor, s.fm).Create(storage.EngineFile{
+		FileName:       fileName,
+		Description:    &description,
+		UploadedByUUID: claim.UserUUID,
+		UploadedAt:     now,
+	}, wl)
+	s.logPasswordViews(c, tasks)
+	if err != nil {
+		if err == wordlist.ErrNoPasswords {
+			return &WebAPIError{
+				StatusCode:            http.StatusBadRequest,
+				Err:                   errors.New("the tasks have not cracked any passwords"),
+				CanErrorBeShownToUser: true,
+			}
+		}
+		return internalServerError(err)
+	}
+
+	c.JSON(http.StatusCreated, &CrackedWordlistItem{
+		EngineFileItem: convStorageEngineFile(*ef),
+		TaskIDs:        emptyIfNil(wl.TaskIDs),
+		CaseCode:       wl.CaseCode,
+		AutoUpdate:     wl.AutoUpdate,
+		LastBuiltAt:    ef.LastUpdatedAt,
+	})
+	return nil
+}
+
+// emptyIfNil keeps nil lists from being returned as null
+func emptyIfNil(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+	return list
+}
@@ -0,0 +1,98 @@
+package web
+
+import (
+	"bufio"
+	"os"
+	"strings"
+
+	"github.com/mandiant/gocrack/server/hashfile"
+	"github.com/mandiant/gocrack/server/storage"
+
+	"github.com/rs/zerolog/log"
+)
+
+// readTaskFileHashes returns the unique hashes within a task file
+func readTaskFileHashes(path string, format storage.TaskFileFormat, hashType int) ([]string, error) {
+	fd, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+
+	seen := make(map[string]bool)
+	hashes := make([]string, 0)
+	scanner := bufio.NewScanner(fd)
+	for scanner.Scan() {
+		hash := hashfile.Hash(format, hashType, strings.TrimSpace(scanner.Text()))
+		if hash == "" || seen[hash] {
+			continue
+		}
+		seen[hash] = true
+		hashes = append(hashes, hash)
+	}
+	return hashes, scanner.Err()
+}
+
+// countKnownHashes returns the number of hashes in the uploaded task file that have already been cracked by tasks the
+// uploader is entitled to. The index is only consulted to inform the user so failures are logged rather than failing the upload
+func (s *Server) countKnownHashes(uploader storage.User, hashType int, path string, format storage.TaskFileFormat) int {
+	hashes, err := readTaskFileHashes(path, format, hashType)
+	if err != nil {
+		log.Warn().Err(err).Str("path", path).Msg("Failed to read task file hashes")
+		return 0
+	}
+
+	known, err := s.stor.LookupKnownHashes(uploader, hashType, hashes)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to lookup known hashes")
+		return 0
+	}
+	return len(known)
+}
+
+// creditKnownHashes credits a new task with the hashes of its task file that other tasks the creator of the task is
+// entitled to have already cracked. If every hash is known, the task is finished without being sent to a worker
+func (s *Server) creditKnownHashes(task *storage.Task, tf *storage.TaskFile) (int, error) {
+	hashType, ok := storage.TaskHashType(task)
+	if !ok {
+		return 0, nil
+	}
+
+	hashes, err := readTaskFileHashes(tf.SavedAt, tf.Format, hashType)
+	if err != nil {
+		return 0, err
+	}
+
+	creator, err := s.stor.GetUserByID(task.CreatedByUUID)
+	if err != nil {
+		return 0, err
+	}
+
+	known, err := s.stor.LookupKnownHashes(*creator, hashType, hashes)
+	if err != nil || len(known) == 0 {
+		return 0, err
+	}
+
+	credited, err := s.stor.CreditKnownHashes(task.TaskID, known)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(known) < len(hashes) {
+		return credited, nil
+	}
+
+	entry, err := s.stor.ChangeTaskStatus(task.TaskID, storage.TaskStatusChange{
+		Status:    storage.TaskStatusFinished,
+		ActorType: storage.StatusActorServer,
+	})
+	if err != nil {
+		return credited, err
+	}
+	task.Status = entry.Status
+
+	if err := s.wmgr.BroadcastTaskStatusChange(*entry); err != nil {
+		log.Warn().Err(err).Str("task_id", task.TaskID).Msg("Failed to broadcast task status change")
+	}
+	return credited, nil
+}
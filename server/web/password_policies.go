@@ -0,0 +1,450 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mandiant/gocrack/server/analytics"
+	"github.com/mandiant/gocrack/server/authentication"
+	"github.com/mandiant/gocrack/server/storage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// PasswordPolicyRequest creates or replaces a password policy
+type PasswordPolicyRequest struct {
+	Name             string   `json:"name"`
+	MinLength        int      `json:"min_length"`
+	RequiredClasses  []string `json:"required_classes"`
+	MinClasses       int      `json:"min_classes"`
+	MaxRepeatedChars int      `json:"max_repeated_chars"`
+	BannedWordFiles  []string `json:"banned_word_files"`
+}
+
+// PasswordPolicyItem describes a password policy and should mimic storage.PasswordPolicy
+type PasswordPolicyItem struct {
+	PolicyID         string            `json:"policy_id"`
+	Name             string            `json:"name"`
+	MinLength        int               `json:"min_length"`
+	RequiredClasses  []string          `json:"required_classes"`
+	MinClasses       int               `json:"min_classes"`
+	MaxRepeatedChars int               `json:"max_repeated_chars"`
+	BannedWordFiles  []*EngineFileItem `json:"banned_word_files"`
+	CreatedByUUID    string            `json:"created_by_uuid"`
+	CreatedAt        time.Time         `json:"created_at"`
+	LastUpdatedAt    time.Time         `json:"last_updated_at"`
+}
+
+// PolicyEvaluationResult is the outcome of evaluating the password of an account against a policy. Username is empty
+// if the task file does not contain usernames
+type PolicyEvaluationResult struct {
+	Username  string   `json:"username,omitempty"`
+	Hash      string   `json:"hash"`
+	Value     string   `json:"value"`
+	Compliant bool     `json:"compliant"`
+	Reasons   []string `json:"reasons"`
+}
+
+// PolicyEvaluationResponse is the evaluation of the cracked passwords of a task against a policy
+type PolicyEvaluationResponse struct {
+	PolicyID     string                   `json:"policy_id"`
+	TaskID       string                   `json:"task_id"`
+	Evaluated    int                      `json:"evaluated"`
+	Compliant    int                      `json:"compliant"`
+	NonCompliant int                      `json:"non_compliant"`
+	Results      []PolicyEvaluationResult `json:"results"`
+}
+
+// convStoragePasswordPolicy converts the policy for the user. Only the banned word lists the user can read are included
+func (s *Server) convStoragePasswordPolicy(policy storage.PasswordPolicy, claim *authentication.AuthClaim) (PasswordPolicyItem, error) {
+	item := PasswordPolicyItem{
+		PolicyID:         policy.PolicyID,
+		Name:             policy.Name,
+		MinLength:        policy.MinLength,
+		RequiredClasses:  policy.RequiredClasses,
+		MinClasses:       policy.MinClasses,
+		MaxRepeatedChars: policy.MaxRepeatedChars,
+		BannedWordFiles:  make([]*EngineFileItem, 0),
+		CreatedByUUID:    policy.CreatedByUUID,
+		CreatedAt:        policy.CreatedAt,
+		LastUpdatedAt:    policy.LastUpdatedAt,
+	}
+
+	if item.RequiredClasses == nil {
+		item.RequiredClasses = []string{}
+	}
+
+	files, _, err := s.readableBannedWordFiles(policy, claim)
+	if err != nil {
+		return item, err
+	}
+
+	for _, ef := range files {
+		sef := convStorageEngineFile(ef)
+		item.BannedWordFiles = append(item.BannedWordFiles, &sef)
+	}
+	return item, nil
+}
+
+// canReadEngineFile returns true if the user is an administrator, the file is shared or the user is entitled to it
+func (s *Server) canReadEngineFile(ef storage.EngineFile, claim *authentication.AuthClaim) (bool, error) {
+	if claim.IsAdmin || ef.IsShared {
+		return true, nil
+	}
+
+	entitled, err := s.stor.CheckEntitlement(claim.UserUUID, ef.FileID, storage.EntitlementEngineFile)
+	if err != nil && err != storage.ErrNotFound {
+		return false, err
+	}
+	return entitled, nil
+}
+
+// readableBannedWordFiles returns the banned word lists of the policy the user can read along with the number of lists
+// the user can't. Lists that were deleted after the policy was saved are left out
+func (s *Server) readableBannedWordFiles(policy storage.PasswordPolicy, claim *authentication.AuthClaim) (files []storage.EngineFile, denied int, err error) {
+	for _, fileID := range policy.BannedWordFiles {
+		ef, err := s.stor.GetEngineFileByID(fileID)
+		if err != nil {
+			if err == storage.ErrNotFound {
+				continue
+			}
+			return nil, 0, err
+		}
+
+		readable, err := s.canReadEngineFile(*ef, claim)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if !readable {
+			denied++
+			continue
+		}
+		files = append(files, *ef)
+	}
+	return files, denied, nil
+}
+
+// validatePolicyRequest returns the problems with the request. The banned word lists must be dictionaries the user can use
+func (s *Server) validatePolicyRequest(req PasswordPolicyRequest, claim *authentication.AuthClaim) ([]string, error) {
+	errs := analytics.ValidatePolicy(storage.PasswordPolicy{
+		MinLength:        req.MinLength,
+		RequiredClasses:  req.RequiredClasses,
+		MinClasses:       req.MinClasses,
+		MaxRepeatedChars: req.MaxRepeatedChars,
+	})
+
+	if strings.TrimSpace(req.Name) == "" {
+		errs = append(errs, "name is required")
+	}
+
+	for _, fileID := range req.BannedWordFiles {
+		ef, err := s.stor.GetEngineFileByID(fileID)
+		if err != nil {
+			if err == storage.ErrNotFound {
+				errs = append(errs, fmt.Sprintf("banned_word_files: `%s` does not exist", fileID))
+				continue
+			}
+			return nil, err
+		}
+
+		readable, err := s.canReadEngineFile(*ef, claim)
+		if err != nil {
+			return nil, err
+		}
+
+		// files the user can't read are reported the same as ones that don't exist so their IDs can't be probed
+		if !readable {
+			errs = append(errs, fmt.Sprintf("banned_word_files: `%s` does not exist", fileID))
+			continue
+		}
+
+		if ef.FileType != storage.EngineFileDictionary {
+			errs = append(errs, fmt.Sprintf("banned_word_files: `%s` is not a dictionary", fileID))
+		}
+	}
+	return errs, nil
+}
+
+func (s *Server) webCreatePasswordPolicy(c *gin.Context) *WebAPIError {
+	var req PasswordPolicyRequest
+
+	if err := c.BindJSON(&req); err != nil {
+		return &WebAPIError{
+			StatusCode: http.StatusBadRequest,
+			Err:        err,
+			UserError:  "The request was not valid",
+		}
+	}
+
+	now := time.Now().UTC()
+	policy := storage.PasswordPolicy{
+		PolicyID:      uuid.NewString(),
+		CreatedByUUID: getClaimInformation(c).UserUUID,
+		CreatedAt:     now,
+	}
+	return s.savePasswordPolicy(c, req, policy, http.StatusCreated)
+}
+
+func (s *Server) webUpdatePasswordPolicy(c *gin.Context) *WebAPIError {
+	var req PasswordPolicyRequest
+
+	policy, apiErr := s.getModifiablePasswordPolicy(c)
+	if apiErr != nil {
+		return apiErr
+	}
+
+	if err := c.BindJSON(&req); err != nil {
+		return &WebAPIError{
+			StatusCode: http.StatusBadRequest,
+			Err:        err,
+			UserError:  "The request was not valid",
+		}
+	}
+	return s.savePasswordPolicy(c, req, *policy, http.StatusOK)
+}
+
+// savePasswordPolicy replaces the rules of the policy with the ones in the request and saves it
+func (s *Server) savePasswordPolicy(c *gin.Context, req PasswordPolicyRequest, policy storage.PasswordPolicy, status int) *WebAPIError {
+	errs, err := s.validatePolicyRequest(req, getClaimInformation(c))
+	if err != nil {
+		return internalServerError(err)
+	}
+
+	if len(errs) > 0 {
+		c.JSON(http.StatusBadRequest, APIValidationErrors{
+			Valid:  false,
+			Errors: errs,
+		})
+		return nil
+	}
+
+	policy.Name = strings.TrimSpace(req.Name)
+	policy.MinLength = req.MinLength
+	policy.RequiredClasses = req.RequiredClasses
+	policy.MinClasses = req.MinClasses
+	policy.MaxRepeatedChars = req.MaxRepeatedChars
+	policy.BannedWordFiles = req.BannedWordFiles
+	policy.LastUpdatedAt = time.Now().UTC()
+
+	if err := s.stor.SavePasswordPolicy(policy); err != nil {
+		if err == storage.ErrAlreadyExists {
+			return &WebAPIError{
+				StatusCode: http.StatusBadRequest,
+				UserError:  "A password policy with that name already exists",
+			}
+		}
+		return internalServerError(err)
+	}
+
+	item, err := s.convStoragePasswordPolicy(policy, getClaimInformation(c))
+	if err != nil {
+		return internalServerError(err)
+	}
+
+	c.JSON(status, item)
+	return nil
+}
+
+func (s *Server) webGetPasswordPolicies(c *gin.Context) *WebAPIError {
+	policies, err := s.stor.GetPasswordPolicies()
+	if err != nil {
+		return internalServerError(err)
+	}
+
+	claim := getClaimInformation(c)
+	resp := make([]PasswordPolicyItem, len(policies))
+	for i, policy := range policies {
+		if resp[i], err = s.convStoragePasswordPolicy(policy, claim); err != nil {
+			return internalServerError(err)
+		}
+	}
+
+	c.JSON(http.StatusOK, resp)
+	return nil
+}
+
+func (s *Server) webGetPasswordPolicy(c *gin.Context) *WebAPIError {
+	policy, apiErr := s.getPasswordPolicy(c)
+	if apiErr != nil {
+		return apiErr
+	}
+
+	item, err := s.convStoragePasswordPolicy(*policy, getClaimInformation(c))
+	if err != nil {
+		return internalServerError(err)
+	}
+
+	c.JSON(http.StatusOK, item)
+	return nil
+}
+
+func (s *Server) webDeletePasswordPolicy(c *gin.Context) *WebAPIError {
+	policy, apiErr := s.getModifiablePasswordPolicy(c)
+	if apiErr != nil {
+		return apiErr
+	}
+
+	if err := s.stor.DeletePasswordPolicy(policy.PolicyID); err != nil && err != storage.ErrNotFound {
+		return internalServerError(err)
+	}
+
+	c.Status(http.StatusNoContent)
+	return nil
+}
+
+// getPasswordPolicy returns the policy in the route
+func (s *Server) getPasswordPolicy(c *gin.Context) (*storage.PasswordPolicy, *WebAPIError) {
+	policy, err := s.stor.GetPasswordPolicy(c.Param("policyid"))
+	if err != nil {
+		if err == storage.ErrNotFound {
+			return nil, &WebAPIError{
+				StatusCode: http.StatusNotFound,
+				Err:        err,
+				UserError:  "The requested password policy does not exist",
+			}
+		}
+		return nil, internalServerError(err)
+	}
+	return policy, nil
+}
+
+// getModifiablePasswordPolicy returns the policy in the route if the user created it or is an administrator
+func (s *Server) getModifiablePasswordPolicy(c *gin.Context) (*storage.PasswordPolicy, *WebAPIError) {
+	policy, apiErr := s.getPasswordPolicy(c)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	claim := getClaimInformation(c)
+	if !claim.IsAdmin && policy.CreatedByUUID != claim.UserUUID {
+		return nil, &WebAPIError{
+			StatusCode: http.StatusUnauthorized,
+			UserError:  "Only the creator of a password policy or an administrator can modify it",
+		}
+	}
+	return policy, nil
+}
+
+// webEvaluateTaskPasswordPolicy evaluates the cracked passwords of the task against the policy. A result is returned
+// for every account of the task file that shares a cracked hash
+func (s *Server) webEvaluateTaskPasswordPolicy(c *gin.Context) *WebAPIError {
+	task, err := s.stor.GetTaskByID(c.Param("taskid"))
+	if err != nil {
+		if err == storage.ErrNotFound {
+			return &WebAPIError{
+				StatusCode: http.StatusNotFound,
+				Err:        err,
+				UserError:  "The requested file does not exist or you do not have permissions to it",
+			}
+		}
+		return internalServerError(err)
+	}
+
+	policy, apiErr := s.getPasswordPolicy(c)
+	if apiErr != nil {
+		return apiErr
+	}
+
+	// evaluating against a list the user can't read would reveal whether its words are in the user's passwords
+	files, denied, err := s.readableBannedWordFiles(*policy, getClaimInformation(c))
+	if err != nil {
+		return internalServerError(err)
+	}
+
+	if denied > 0 {
+		return &WebAPIError{
+			StatusCode: http.StatusForbidden,
+			UserError:  "The password policy uses banned word lists you do not have permissions to",
+		}
+	}
+
+	resp := PolicyEvaluationResponse{
+		PolicyID: policy.PolicyID,
+		TaskID:   task.TaskID,
+		Results:  make([]PolicyEvaluationResult, 0),
+	}
+
+	cracked, err := s.stor.GetCrackedPasswords(task.TaskID)
+	if err != nil && err != storage.ErrNotFound {
+		return internalServerError(err)
+	}
+
+	if cracked == nil || len(*cracked) == 0 {
+		c.JSON(http.StatusOK, &resp)
+		return nil
+	}
+
+	accounts, err := s.taskFileAccounts(task.FileID)
+	if err != nil {
+		return internalServerError(err)
+	}
+
+	banned, err := s.findBannedWords(files, *cracked)
+	if err != nil {
+		return internalServerError(err)
+	}
+
+	for _, pass := range *cracked {
+		reasons := analytics.EvaluatePolicy(*policy, pass.Value, banned)
+
+		usernames := accounts[strings.ToLower(pass.Hash)]
+		if len(usernames) == 0 {
+			usernames = []string{""}
+		}
+
+		for _, username := range usernames {
+			resp.Results = append(resp.Results, PolicyEvaluationResult{
+				Username:  username,
+				Hash:      pass.Hash,
+				Value:     pass.Value,
+				Compliant: len(reasons) == 0,
+				Reasons:   reasons,
+			})
+
+			if len(reasons) == 0 {
+				resp.Compliant++
+			} else {
+				resp.NonCompliant++
+			}
+		}
+	}
+	resp.Evaluated = len(resp.Results)
+
+	c.JSON(http.StatusOK, &resp)
+	return nil
+}
+
+// findBannedWords returns the words of the banned word lists that the cracked passwords are based on. The lists are
+// streamed so only the words of the passwords are held in memory
+func (s *Server) findBannedWords(files []storage.EngineFile, cracked []storage.CrackedHash) (map[string]bool, error) {
+	found := make(map[string]bool)
+	if len(files) == 0 {
+		return found, nil
+	}
+
+	candidates := make(map[string]bool)
+	for _, pass := range cracked {
+		for _, word := range analytics.BannedWordCandidates(pass.Value) {
+			candidates[word] = true
+		}
+	}
+
+	for _, ef := range files {
+		fd, err := os.Open(ef.SavedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		err = analytics.FindWords(fd, candidates, found)
+		fd.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return found, nil
+}
@@ -0,0 +1,130 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mandiant/gocrack/server/authentication"
+	"github.com/mandiant/gocrack/server/storage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// policyFiles is the part of a PasswordPolicyItem the tests check
+type policyFiles struct {
+	PolicyID        string `json:"policy_id"`
+	BannedWordFiles []struct {
+		FileID string `json:"file_id"`
+	} `json:"banned_word_files"`
+}
+
+// policyRequest sends the request to the password policy API as the user
+func policyRequest(s *Server, user storage.User, method, path string, body interface{}) *httptest.ResponseRecorder {
+	e := gin.New()
+	e.Use(func(c *gin.Context) {
+		c.Set("claim", &authentication.AuthClaim{
+			Username: user.Username,
+			UserUUID: user.UserUUID,
+			IsAdmin:  user.IsSuperUser,
+		})
+		c.Next()
+	})
+	e.GET("/policies/", WrapAPIForError(s.webGetPasswordPolicies))
+	e.POST("/policies/", WrapAPIForError(s.webCreatePasswordPolicy))
+	e.GET("/policies/:policyid", WrapAPIForError(s.webGetPasswordPolicy))
+	e.GET("/task/:taskid/policies/:policyid", WrapAPIForError(s.webEvaluateTaskPasswordPolicy))
+
+	var buf bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&buf).Encode(body)
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, &buf)
+	e.ServeHTTP(w, req)
+	return w
+}
+
+func TestPasswordPolicyBannedWordFileEntitlements(t *testing.T) {
+	s, f, closer := newTestServer(t, "bdb")
+	defer closer()
+
+	owner := f.CreateUser(t, false)
+	other := f.CreateUser(t, false)
+	admin := f.CreateUser(t, true)
+
+	private := f.CreateEngineFile(t, owner, storage.EngineFileDictionary, false, "summer", "winter")
+	shared := f.CreateEngineFile(t, admin, storage.EngineFileDictionary, true, "password")
+
+	w := policyRequest(s, owner, "POST", "/policies/", PasswordPolicyRequest{
+		Name:            "No seasons",
+		MinLength:       8,
+		BannedWordFiles: []string{private.FileID, shared.FileID},
+	})
+	if !assert.Equal(t, http.StatusCreated, w.Code, w.Body.String()) {
+		return
+	}
+
+	var created policyFiles
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.Len(t, created.BannedWordFiles, 2)
+
+	bannedFiles := func(user storage.User) []string {
+		w := policyRequest(s, user, "GET", "/policies/"+created.PolicyID, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var item policyFiles
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &item))
+
+		ids := make([]string, 0)
+		for _, ef := range item.BannedWordFiles {
+			ids = append(ids, ef.FileID)
+		}
+		return ids
+	}
+
+	// the metadata of a dictionary is only shown to the users that can read it
+	assert.Equal(t, []string{private.FileID, shared.FileID}, bannedFiles(owner))
+	assert.Equal(t, []string{private.FileID, shared.FileID}, bannedFiles(admin))
+	assert.Equal(t, []string{shared.FileID}, bannedFiles(other))
+
+	w = policyRequest(s, other, "GET", "/policies/", nil)
+	var policies []policyFiles
+	if assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &policies)) && assert.Len(t, policies, 1) {
+		assert.Len(t, policies[0].BannedWordFiles, 1)
+	}
+
+	// a dictionary the user can't read can't be attached to a policy
+	w = policyRequest(s, other, "POST", "/policies/", PasswordPolicyRequest{
+		Name:            "Probe",
+		BannedWordFiles: []string{private.FileID},
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "`"+private.FileID+"` does not exist")
+
+	// nor can the user test their passwords against it
+	crackedAt := time.Now().UTC()
+	othersTask := newExportTask(t, f, other, 0, "", md5Summer)
+	assert.Nil(t, s.stor.SaveCrackedHash(othersTask.TaskID, md5Summer, "summer", crackedAt))
+
+	w = policyRequest(s, other, "GET", "/task/"+othersTask.TaskID+"/policies/"+created.PolicyID, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.NotContains(t, w.Body.String(), "summer")
+
+	ownersTask := newExportTask(t, f, owner, 0, "", md5Summer)
+	assert.Nil(t, s.stor.SaveCrackedHash(ownersTask.TaskID, md5Summer, "summer", crackedAt))
+
+	w = policyRequest(s, owner, "GET", "/task/"+ownersTask.TaskID+"/policies/"+created.PolicyID, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp PolicyEvaluationResponse
+	if assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &resp)) && assert.Len(t, resp.Results, 1) {
+		assert.False(t, resp.Results[0].Compliant)
+		assert.Equal(t, 1, resp.NonCompliant)
+	}
+}
@@ -0,0 +1,395 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mandiant/gocrack/server/authentication"
+	"github.com/mandiant/gocrack/server/storage"
+	"github.com/mandiant/gocrack/server/workmgr"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// PipelineStageRequest is an attack of a new pipeline. Its fields are the ones of CreateTaskRequest
+type PipelineStageRequest struct {
+	Name          string                    `json:"name"`
+	Engine        storage.WorkerCrackEngine `json:"engine"`
+	EnginePayload json.RawMessage           `json:"payload"` // The structure of EnginePayload differs based on Engine
+	TaskDuration  int                       `json:"task_duration"`
+	WorkUnits     int                       `json:"work_units,omitempty"`
+}
+
+// CreatePipelineRequest defines the request for a new pipeline of attacks against a task file
+type CreatePipelineRequest struct {
+	Name            string                  `json:"name"`
+	FileID          string                  `json:"file_id"`
+	CaseCode        *string                 `json:"case_code,omitempty"`
+	Priority        *storage.WorkerPriority `json:"priority,omitempty"`
+	AdditionalUsers []string                `json:"additional_users,omitempty"`
+	// Stages are run in order. Each stage after the first only attacks the hashes the stages before it did not crack
+	Stages []PipelineStageRequest `json:"stages"`
+}
+
+// PipelineStageItem describes a stage of a pipeline and the task it was started as
+type PipelineStageItem struct {
+	Name            string               `json:"name"`
+	Engine          TaskCrackEngineFancy `json:"engine"`
+	TaskID          string               `json:"task_id,omitempty"`
+	Status          storage.TaskStatus   `json:"status,omitempty"`
+	NumberCracked   int                  `json:"number_cracked"`
+	NumberPasswords int                  `json:"number_passwords"`
+}
+
+// PipelineItem describes a pipeline and the progress of its stages as a whole
+type PipelineItem struct {
+	PipelineID   string             `json:"pipeline_id"`
+	Name         string             `json:"name"`
+	FileID       string             `json:"file_id"`
+	CaseCode     *string            `json:"case_code,omitempty"`
+	Priority     TaskPriorityFancy  `json:"priority"`
+	Status       storage.TaskStatus `json:"status"`
+	Error        *string            `json:"error,omitempty"`
+	CurrentStage int                `json:"current_stage"`
+	// NumberCracked is the number of hashes of the task file cracked by every stage
+	NumberCracked   int                 `json:"number_cracked"`
+	NumberPasswords int                 `json:"number_passwords"`
+	Stages          []PipelineStageItem `json:"stages"`
+	CreatedByUUID   string              `json:"created_by_uuid"`
+	CreatedAt       time.Time           `json:"created_at"`
+	LastUpdatedAt   time.Time           `json:"last_updated_at"`
+}
+
+func (s CreatePipelineRequest) validate() []string {
+	errs := make([]string, 0)
+
+	if strings.TrimSpace(s.Name) == "" {
+		errs = append(errs, "name must not be empty")
+	}
+
+	if _, err := uuid.Parse(s.FileID); err != nil {
+		errs = append(errs, "file_id must be a valid UUID")
+	}
+
+	if len(s.Stages) == 0 {
+		errs = append(errs, "stages must contain at least one attack")
+	}
+
+	// the stages share the task file so its errors aren't repeated for each of them
+	if len(errs) > 0 {
+		return errs
+	}
+
+	p := s.pipeline()
+	for i, stage := range s.Stages {
+		if strings.TrimSpace(stage.Name) == "" {
+			errs = append(errs, fmt.Sprintf("stages[%d]: name must not be empty", i))
+		}
+
+		for _, err := range pipelineStageRequest(p, i).validate() {
+			errs = append(errs, fmt.Sprintf("stages[%d]: %s", i, err))
+		}
+	}
+	return errs
+}
+
+// pipeline returns the pipeline of the request before any of its stages are started
+func (s CreatePipelineRequest) pipeline() storage.Pipeline {
+	p := storage.Pipeline{
+		Name:            strings.TrimSpace(s.Name),
+		FileID:          s.FileID,
+		CaseCode:        s.CaseCode,
+		Priority:        storage.WorkerPriorityNormal,
+		AdditionalUsers: s.AdditionalUsers,
+		Stages:          make([]storage.PipelineStage, len(s.Stages)),
+		Status:          storage.TaskStatusRunning,
+	}
+
+	if s.Priority != nil {
+		p.Priority = *s.Priority
+	}
+
+	for i, stage := range s.Stages {
+		p.Stages[i] = storage.PipelineStage{
+			Name:          strings.TrimSpace(stage.Name),
+			Engine:        stage.Engine,
+			EnginePayload: stage.EnginePayload,
+			TaskDuration:  stage.TaskDuration,
+			WorkUnits:     stage.WorkUnits,
+		}
+	}
+	return p
+}
+
+// pipelineStageRequest returns the task creation request of a stage of the pipeline
+func pipelineStageRequest(p storage.Pipeline, i int) CreateTaskRequest {
+	stage := p.Stages[i]
+	comment := fmt.Sprintf("Stage %d of %d of pipeline %s", i+1, len(p.Stages), p.Name)
+
+	return CreateTaskRequest{
+		TaskName:        fmt.Sprintf("%s - %s", p.Name, stage.Name),
+		Engine:          stage.Engine,
+		FileID:          p.FileID,
+		CaseCode:        p.CaseCode,
+		Comment:         &comment,
+		EnginePayload:   stage.EnginePayload,
+		TaskDuration:    stage.TaskDuration,
+		Priority:        &p.Priority,
+		AdditionalUsers: &p.AdditionalUsers,
+		WorkUnits:       stage.WorkUnits,
+	}
+}
+
+// canViewPipeline returns true if the user is entitled to the tasks of the pipeline's stages
+func canViewPipeline(claim *authentication.AuthClaim, p storage.Pipeline) bool {
+	if claim.IsAdmin || claim.UserUUID == p.CreatedByUUID {
+		return true
+	}
+
+	for _, userUUID := range p.AdditionalUsers {
+		if userUUID == claim.UserUUID {
+			return true
+		}
+	}
+	return false
+}
+
+// convPipelineItem returns the pipeline along with the progress of the tasks its stages were started as
+func (s *Server) convPipelineItem(p storage.Pipeline) (*PipelineItem, error) {
+	item := &PipelineItem{
+		PipelineID:    p.PipelineID,
+		Name:          p.Name,
+		FileID:        p.FileID,
+		CaseCode:      p.CaseCode,
+		Priority:      TaskPriorityFancy(p.Priority),
+		Status:        p.Status,
+		Error:         p.Error,
+		CurrentStage:  p.CurrentStage,
+		Stages:        make([]PipelineStageItem, len(p.Stages)),
+		CreatedByUUID: p.CreatedByUUID,
+		CreatedAt:     p.CreatedAt,
+		LastUpdatedAt: p.LastUpdatedAt,
+	}
+
+	for i, stage := range p.Stages {
+		item.Stages[i] = PipelineStageItem{
+			Name:   stage.Name,
+			Engine: TaskCrackEngineFancy(stage.Engine),
+			TaskID: stage.TaskID,
+		}
+
+		if stage.TaskID == "" {
+			continue
+		}
+
+		task, err := s.stor.GetTaskByID(stage.TaskID)
+		if err != nil {
+			if err == storage.ErrNotFound {
+				// the task was deleted after the stage was started
+				continue
+			}
+			return nil, err
+		}
+
+		item.Stages[i].Status = task.Status
+
+		cracked, err := s.stor.GetCrackedPasswords(task.TaskID)
+		if err != nil && err != storage.ErrNotFound {
+			return nil, err
+		}
+
+		if cracked != nil {
+			item.Stages[i].NumberCracked = len(*cracked)
+			item.NumberCracked += len(*cracked)
+		}
+
+		tf, err := s.stor.GetTaskFileByID(task.FileID)
+		if err != nil && err != storage.ErrNotFound {
+			return nil, err
+		}
+
+		if tf != nil {
+			item.Stages[i].NumberPasswords = tf.NumberOfPasswords
+			// the first stage attacks every hash of the pipeline's task file
+			if i == 0 {
+				item.NumberPasswords = tf.NumberOfPasswords
+			}
+		}
+	}
+	return item, nil
+}
+
+// webCreatePipeline validates every stage of the pipeline and starts its first stage as a task
+func (s *Server) webCreatePipeline(c *gin.Context) *WebAPIError {
+	var request CreatePipelineRequest
+
+	if err := c.BindJSON(&request); err != nil {
+		return &WebAPIError{
+			StatusCode:            http.StatusBadRequest,
+			Err:                   err,
+			CanErrorBeShownToUser: true,
+			UserError:             "Your request is malformed",
+		}
+	}
+
+	if errs := request.validate(); len(errs) > 0 {
+		c.JSON(http.StatusBadRequest, APIValidationErrors{
+			Valid:  false,
+			Errors: errs,
+		})
+		return nil
+	}
+
+	claim := getClaimInformation(c)
+	noAccess := &WebAPIError{
+		StatusCode: http.StatusNotFound,
+		UserError:  "The requested file does not exist or you do not have permissions to it",
+	}
+
+	tf, err := s.stor.GetTaskFileByID(request.FileID)
+	if err != nil {
+		if err == storage.ErrNotFound {
+			return noAccess
+		}
+		return internalServerError(err)
+	}
+
+	if !claim.IsAdmin {
+		canAccess, err := s.stor.CheckEntitlement(claim.UserUUID, tf.FileID, storage.EntitlementTaskFile)
+		if err != nil && err != storage.ErrNotFound {
+			return internalServerError(err)
+		}
+
+		if !canAccess {
+			return noAccess
+		}
+	}
+
+	now := time.Now().UTC()
+	p := request.pipeline()
+	p.PipelineID = uuid.NewString()
+	p.CreatedByUUID = claim.UserUUID
+	p.CreatedAt = now
+	p.LastUpdatedAt = now
+
+	// every stage is checked against the task file up front so a pipeline doesn't fail halfway through on a bad payload
+	errs := make([]string, 0)
+	for i := range p.Stages {
+		_, stageErrs, err := decodeTaskPayload(pipelineStageRequest(p, i), tf)
+		if err != nil {
+			stageErrs = append(stageErrs, err.Error())
+		}
+
+		for _, e := range stageErrs {
+			errs = append(errs, fmt.Sprintf("stages[%d]: %s", i, e))
+		}
+	}
+
+	if len(errs) > 0 {
+		c.JSON(http.StatusBadRequest, APIValidationErrors{
+			Valid:  false,
+			Errors: errs,
+		})
+		return nil
+	}
+
+	// the pipeline is locked until it's saved so the status change of a first stage finished by the known hash index
+	// is not missed
+	s.pipelineMu.Lock()
+	defer s.pipelineMu.Unlock()
+
+	task, err := s.startPipelineStage(&p, 0)
+	if err != nil {
+		return &WebAPIError{
+			StatusCode:            http.StatusBadRequest,
+			Err:                   fmt.Errorf("the first stage could not be started: %w", err),
+			CanErrorBeShownToUser: true,
+		}
+	}
+
+	p.Stages[0].TaskID = task.TaskID
+	if err = s.stor.SavePipeline(p); err != nil {
+		return internalServerError(err)
+	}
+
+	item, err := s.convPipelineItem(p)
+	if err != nil {
+		return internalServerError(err)
+	}
+	s.broadcastPipelineProgress(item)
+
+	c.JSON(http.StatusCreated, item)
+	return nil
+}
+
+// webGetPipelines returns the pipelines the user is entitled to
+func (s *Server) webGetPipelines(c *gin.Context) *WebAPIError {
+	claim := getClaimInformation(c)
+
+	pipelines, err := s.stor.GetPipelines()
+	if err != nil {
+		return internalServerError(err)
+	}
+
+	resp := make([]*PipelineItem, 0)
+	for _, p := range pipelines {
+		if !canViewPipeline(claim, p) {
+			continue
+		}
+
+		item, err := s.convPipelineItem(p)
+		if err != nil {
+			return internalServerError(err)
+		}
+		resp = append(resp, item)
+	}
+
+	c.JSON(http.StatusOK, resp)
+	return nil
+}
+
+func (s *Server) webGetPipeline(c *gin.Context) *WebAPIError {
+	p, err := s.stor.GetPipeline(c.Param("pipelineid"))
+	if err != nil && err != storage.ErrNotFound {
+		return internalServerError(err)
+	}
+
+	if p == nil || !canViewPipeline(getClaimInformation(c), *p) {
+		return &WebAPIError{
+			StatusCode: http.StatusNotFound,
+			Err:        errors.New("pipeline does not exist"),
+			UserError:  "The requested pipeline does not exist or you do not have permissions to it",
+		}
+	}
+
+	item, err := s.convPipelineItem(*p)
+	if err != nil {
+		return internalServerError(err)
+	}
+
+	c.JSON(http.StatusOK, item)
+	return nil
+}
+
+// broadcastPipelineProgress notifies the subscribers of the progress of the pipeline. Failures are only logged
+func (s *Server) broadcastPipelineProgress(item *PipelineItem) {
+	current := item.Stages[item.CurrentStage]
+	if err := s.wmgr.BroadcastPipelineProgress(workmgr.PipelineProgressBroadcast{
+		PipelineID:      item.PipelineID,
+		Status:          item.Status,
+		CurrentStage:    item.CurrentStage,
+		NumberOfStages:  len(item.Stages),
+		TaskID:          current.TaskID,
+		TaskStatus:      current.Status,
+		NumberCracked:   item.NumberCracked,
+		NumberPasswords: item.NumberPasswords,
+	}); err != nil {
+		log.Warn().Err(err).Str("pipeline_id", item.PipelineID).Msg("Failed to broadcast pipeline progress")
+	}
+}
@@ -0,0 +1,314 @@
+package web
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mandiant/gocrack/server/hashfile"
+	"github.com/mandiant/gocrack/server/storage"
+	"github.com/mandiant/gocrack/server/workmgr"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// onPipelineTaskStatus advances the pipelines whose current stage has finished or exhausted and broadcasts the
+// progress of any pipeline whose current stage changed status
+func (s *Server) onPipelineTaskStatus(msg interface{}) {
+	m, ok := msg.(workmgr.TaskStatusChangeBroadcast)
+	if !ok {
+		return
+	}
+
+	s.pipelineMu.Lock()
+	defer s.pipelineMu.Unlock()
+
+	pipelines, err := s.stor.GetPipelines()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get pipelines")
+		return
+	}
+
+	for _, p := range pipelines {
+		if p.Status != storage.TaskStatusRunning || p.Stages[p.CurrentStage].TaskID != m.TaskID {
+			continue
+		}
+
+		if m.Status == storage.TaskStatusFinished || m.Status == storage.TaskStatusExhausted {
+			s.advancePipeline(&p)
+		}
+
+		item, err := s.convPipelineItem(p)
+		if err != nil {
+			log.Error().Err(err).Str("pipeline_id", p.PipelineID).Msg("Failed to get the progress of a pipeline")
+			continue
+		}
+		s.broadcastPipelineProgress(item)
+	}
+}
+
+// resumePipelines advances the pipelines whose current stage was finished or exhausted while the server was down
+func (s *Server) resumePipelines() {
+	s.pipelineMu.Lock()
+	defer s.pipelineMu.Unlock()
+
+	pipelines, err := s.stor.GetPipelines()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get pipelines")
+		return
+	}
+
+	for _, p := range pipelines {
+		if p.Status != storage.TaskStatusRunning {
+			continue
+		}
+
+		task, err := s.stor.GetTaskByID(p.Stages[p.CurrentStage].TaskID)
+		if err != nil {
+			if err != storage.ErrNotFound {
+				log.Error().Err(err).Str("pipeline_id", p.PipelineID).Msg("Failed to get the task of a pipeline stage")
+				continue
+			}
+
+			s.failPipeline(&p, fmt.Errorf("the task of stage %d was deleted", p.CurrentStage+1))
+			continue
+		}
+
+		if task.Status == storage.TaskStatusFinished || task.Status == storage.TaskStatusExhausted {
+			s.advancePipeline(&p)
+		}
+	}
+}
+
+// advancePipeline starts the stage after the current one of the pipeline. The pipeline is finished once its last stage
+// is done or the stages before the next one have cracked every hash. The caller must hold pipelineMu
+func (s *Server) advancePipeline(p *storage.Pipeline) {
+	next := p.CurrentStage + 1
+	if next >= len(p.Stages) {
+		s.finishPipeline(p)
+		return
+	}
+
+	task, err := s.startPipelineStage(p, next)
+	if err != nil {
+		s.failPipeline(p, err)
+		return
+	}
+
+	if task == nil {
+		// every hash of the task file has been cracked
+		s.finishPipeline(p)
+		return
+	}
+
+	p.CurrentStage = next
+	p.Stages[next].TaskID = task.TaskID
+	p.LastUpdatedAt = time.Now().UTC()
+	if err = s.stor.SavePipeline(*p); err != nil {
+		log.Error().Err(err).Str("pipeline_id", p.PipelineID).Msg("Failed to save the pipeline")
+	}
+}
+
+func (s *Server) finishPipeline(p *storage.Pipeline) {
+	p.Status = storage.TaskStatusFinished
+	p.LastUpdatedAt = time.Now().UTC()
+	if err := s.stor.SavePipeline(*p); err != nil {
+		log.Error().Err(err).Str("pipeline_id", p.PipelineID).Msg("Failed to save the pipeline")
+	}
+}
+
+func (s *Server) failPipeline(p *storage.Pipeline, cause error) {
+	log.Error().Err(cause).Str("pipeline_id", p.PipelineID).Msg("Failed to advance the pipeline")
+
+	msg := cause.Error()
+	p.Status = storage.TaskStatusError
+	p.Error = &msg
+	p.LastUpdatedAt = time.Now().UTC()
+	if err := s.stor.SavePipeline(*p); err != nil {
+		log.Error().Err(err).Str("pipeline_id", p.PipelineID).Msg("Failed to save the pipeline")
+	}
+}
+
+// startPipelineStage creates the task of a stage of the pipeline. Every stage after the first attacks a copy of the
+// pipeline's task file without the hashes cracked by the stages before it. No task is created if none are left
+func (s *Server) startPipelineStage(p *storage.Pipeline, i int) (*storage.Task, error) {
+	tf, err := s.stor.GetTaskFileByID(p.FileID)
+	if err != nil {
+		if err == storage.ErrNotFound {
+			return nil, errors.New("the task file of the pipeline no longer exists")
+		}
+		return nil, err
+	}
+
+	request := pipelineStageRequest(*p, i)
+	payload, errs, err := decodeTaskPayload(request, tf)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.New(strings.Join(errs, ", "))
+	}
+
+	now := time.Now().UTC()
+	task := storage.Task{
+		TaskName:      request.TaskName,
+		TaskID:        uuid.NewString(),
+		CreatedAt:     now,
+		CreatedByUUID: p.CreatedByUUID,
+		LastUpdatedAt: now,
+		Engine:        request.Engine,
+		TaskDuration:  request.TaskDuration,
+		EnginePayload: payload,
+		FileID:        tf.FileID,
+		CaseCode:      request.CaseCode,
+		Comment:       request.Comment,
+		Priority:      p.Priority,
+	}
+
+	if i > 0 {
+		hashType, _ := taskHashType(&task)
+		if tf, err = s.saveRemainingHashes(p, i, tf, hashType); err != nil || tf == nil {
+			return nil, err
+		}
+		task.FileID = tf.FileID
+	}
+
+	units, err := s.splitTask(&task, request.WorkUnits)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.saveTask(&task, units, p.AdditionalUsers); err != nil {
+		return nil, err
+	}
+
+	// The task has been created at this point so it's left for a worker to crack every hash if the index can't be consulted
+	if _, err = s.creditKnownHashes(&task, tf); err != nil {
+		log.Warn().Err(err).Str("task_id", task.TaskID).Msg("Failed to credit task with known hashes")
+	}
+	return &task, nil
+}
+
+// saveRemainingHashes saves a new task file of the lines of the pipeline's task file that were not cracked by the stages
+// before stage i. The creator of the pipeline & its additional users are entitled to it. nil is returned if every
+// hash has been cracked
+func (s *Server) saveRemainingHashes(p *storage.Pipeline, i int, tf *storage.TaskFile, hashType int) (*storage.TaskFile, error) {
+	cracked := make(map[string]bool)
+	for _, stage := range p.Stages[:i] {
+		if stage.TaskID == "" {
+			continue
+		}
+
+		passwords, err := s.stor.GetCrackedPasswords(stage.TaskID)
+		if err != nil {
+			if err == storage.ErrNotFound {
+				continue
+			}
+			return nil, err
+		}
+
+		for _, pass := range *passwords {
+			cracked[strings.ToLower(pass.Hash)] = true
+		}
+	}
+
+	remaining := storage.TaskFile{
+		FileID:         uuid.NewString(),
+		FileName:       fmt.Sprintf("%s (%s)", tf.FileName, p.Stages[i].Name),
+		UploadedAt:     time.Now().UTC(),
+		UploadedByUUID: p.CreatedByUUID,
+		ForEngine:      tf.ForEngine,
+		Format:         tf.Format,
+	}
+
+	pr, pw := io.Pipe()
+	left := &lineCounter{w: pw}
+	go func() {
+		pw.CloseWithError(hashfile.Filter(left, tf.SavedAt, tf.Format, hashType, cracked))
+	}()
+
+	fresp, err := s.fm.SaveFile(pr, remaining.FileName, remaining.FileID, remaining.ForEngine)
+	pr.Close() // unblocks the filter if the file manager stopped reading early
+	if err != nil {
+		return nil, err
+	}
+
+	if fresp == nil {
+		return nil, errors.New("failed to write the remaining hashes of the task file")
+	}
+
+	if left.lines == 0 {
+		os.Remove(fresp.SavedTo)
+		return nil, nil
+	}
+
+	remaining.SavedAt = fresp.SavedTo
+	remaining.FileSize = fresp.Size
+	remaining.SHA1Hash = fresp.SHA1
+	remaining.NumberOfPasswords = left.lines
+
+	if err = s.saveRemainingTaskFile(p, tf, remaining, cracked); err != nil {
+		os.Remove(fresp.SavedTo)
+		return nil, err
+	}
+	return &remaining, nil
+}
+
+// saveRemainingTaskFile saves the task file of the remaining hashes along with the accounts of the original file whose
+// hashes have not been cracked
+func (s *Server) saveRemainingTaskFile(p *storage.Pipeline, tf *storage.TaskFile, remaining storage.TaskFile, cracked map[string]bool) error {
+	accounts, err := s.stor.GetTaskFileAccounts(tf.FileID)
+	if err != nil && err != storage.ErrNotFound {
+		return err
+	}
+
+	left := make([]storage.TaskFileAccount, 0, len(accounts))
+	for _, account := range accounts {
+		if cracked[strings.ToLower(account.Hash)] {
+			continue
+		}
+		account.FileID = remaining.FileID
+		left = append(left, account)
+	}
+
+	txn, err := s.stor.NewTaskFileTransaction()
+	if err != nil {
+		return err
+	}
+	defer txn.Rollback() // wont be rolled back if the commit occurs
+
+	if err = txn.SaveTaskFile(remaining); err != nil {
+		return err
+	}
+
+	if len(left) > 0 {
+		if err = txn.SaveTaskFileAccounts(left); err != nil {
+			return err
+		}
+	}
+
+	for _, userUUID := range append([]string{p.CreatedByUUID}, p.AdditionalUsers...) {
+		if err = txn.AddEntitlement(remaining, userUUID); err != nil {
+			return err
+		}
+	}
+	return txn.Commit()
+}
+
+// lineCounter counts the lines written through it. Every line must end with a new line
+type lineCounter struct {
+	w     io.Writer
+	lines int
+}
+
+func (c *lineCounter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.lines += bytes.Count(p[:n], []byte("\n"))
+	return n, err
+}
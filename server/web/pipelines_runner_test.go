@@ -0,0 +1,143 @@
+package web
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mandiant/gocrack/server/servertest"
+	"github.com/mandiant/gocrack/server/storage"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	md5Password = "5f4dcc3b5aa765d61d8327deb882cf99"
+	md5Letmein  = "0d107d09f5bbe40cade3de5c71e9e9b7"
+	md5Summer   = "6b1628b016dff46e6fa35684be6acc96"
+)
+
+// newPipeline saves a task file of the hashes and returns a pipeline of a brute force stage per name against it
+func newPipeline(t *testing.T, s *Server, f *servertest.Fixture, hashes []string, stages ...string) storage.Pipeline {
+	user := f.CreateUser(t, false)
+	tf := f.CreateTaskFile(t, user, hashes...)
+
+	p := storage.Pipeline{
+		PipelineID:    uuid.NewString(),
+		Name:          "pipeline",
+		FileID:        tf.FileID,
+		Priority:      storage.WorkerPriorityNormal,
+		Status:        storage.TaskStatusRunning,
+		CreatedByUUID: user.UserUUID,
+		CreatedAt:     time.Now().UTC(),
+	}
+	for _, name := range stages {
+		p.Stages = append(p.Stages, storage.PipelineStage{
+			Name:          name,
+			Engine:        storage.WorkerHashcatEngine,
+			EnginePayload: json.RawMessage(`{"attack_mode": 3, "hash_type": 0, "masks": "` + uuid.NewString() + `"}`),
+		})
+	}
+
+	task, err := s.startPipelineStage(&p, 0)
+	if err != nil {
+		assert.FailNow(t, "failed to start the first stage", err.Error())
+	}
+	p.Stages[0].TaskID = task.TaskID
+	assert.Nil(t, s.stor.SavePipeline(p))
+	return p
+}
+
+func readTaskFile(t *testing.T, s *Server, taskID string) (*storage.TaskFile, string) {
+	task, err := s.stor.GetTaskByID(taskID)
+	if err != nil {
+		assert.FailNow(t, "failed to get task", err.Error())
+	}
+
+	tf, err := s.stor.GetTaskFileByID(task.FileID)
+	if err != nil {
+		assert.FailNow(t, "failed to get task file", err.Error())
+	}
+
+	b, err := ioutil.ReadFile(tf.SavedAt)
+	if err != nil {
+		assert.FailNow(t, "failed to read task file", err.Error())
+	}
+	return tf, string(b)
+}
+
+func TestAdvancePipeline(t *testing.T) {
+	s, f, closer := newTestServer(t, "bdb")
+	defer closer()
+
+	p := newPipeline(t, s, f, []string{md5Password, md5Letmein, md5Summer}, "quick", "best64", "masks")
+	first, contents := readTaskFile(t, s, p.Stages[0].TaskID)
+	assert.Equal(t, p.FileID, first.FileID)
+	assert.Equal(t, 3, strings.Count(contents, "\n"))
+
+	now := time.Now().UTC()
+	assert.Nil(t, s.stor.SaveCrackedHash(p.Stages[0].TaskID, md5Password, "password", now))
+
+	// the second stage only attacks the hashes the first did not crack
+	s.advancePipeline(&p)
+	assert.Equal(t, storage.TaskStatusRunning, p.Status)
+	assert.Equal(t, 1, p.CurrentStage)
+	if !assert.NotEmpty(t, p.Stages[1].TaskID) {
+		return
+	}
+
+	second, contents := readTaskFile(t, s, p.Stages[1].TaskID)
+	assert.NotEqual(t, p.FileID, second.FileID)
+	assert.Equal(t, md5Letmein+"\n"+md5Summer+"\n", contents)
+	assert.Equal(t, 2, second.NumberOfPasswords)
+
+	entitled, err := s.stor.CheckEntitlement(p.CreatedByUUID, p.Stages[1].TaskID, storage.EntitlementTask)
+	assert.Nil(t, err)
+	assert.True(t, entitled)
+
+	// nothing is left for the last stage once the second cracks the rest
+	assert.Nil(t, s.stor.SaveCrackedHash(p.Stages[1].TaskID, md5Letmein, "letmein", now))
+	assert.Nil(t, s.stor.SaveCrackedHash(p.Stages[1].TaskID, md5Summer, "summer", now))
+	s.advancePipeline(&p)
+	assert.Equal(t, storage.TaskStatusFinished, p.Status)
+	assert.Equal(t, 1, p.CurrentStage)
+	assert.Empty(t, p.Stages[2].TaskID)
+
+	saved, err := s.stor.GetPipeline(p.PipelineID)
+	if assert.Nil(t, err) {
+		assert.Equal(t, storage.TaskStatusFinished, saved.Status)
+	}
+
+	item, err := s.convPipelineItem(*saved)
+	if assert.Nil(t, err) {
+		assert.Equal(t, 3, item.NumberPasswords)
+		assert.Equal(t, 3, item.NumberCracked)
+	}
+}
+
+func TestAdvancePipelineLastStage(t *testing.T) {
+	s, f, closer := newTestServer(t, "bdb")
+	defer closer()
+
+	p := newPipeline(t, s, f, []string{md5Password, md5Letmein}, "quick")
+	s.advancePipeline(&p)
+	assert.Equal(t, storage.TaskStatusFinished, p.Status)
+	assert.Equal(t, 0, p.CurrentStage)
+}
+
+func TestAdvancePipelineWithoutTaskFile(t *testing.T) {
+	s, f, closer := newTestServer(t, "bdb")
+	defer closer()
+
+	p := newPipeline(t, s, f, []string{md5Password}, "quick", "masks")
+	assert.Nil(t, s.stor.DeleteTaskFile(p.FileID))
+
+	s.advancePipeline(&p)
+	assert.Equal(t, storage.TaskStatusError, p.Status)
+	if assert.NotNil(t, p.Error) {
+		assert.Equal(t, "the task file of the pipeline no longer exists", *p.Error)
+	}
+}
@@ -0,0 +1,100 @@
+package web
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/mandiant/gocrack/server/scheduler"
+	"github.com/mandiant/gocrack/server/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// QueueItem is a task waiting to be handed out to a worker along with its position in the queue
+type QueueItem struct {
+	Position          int                `json:"position"`
+	TaskID            string             `json:"task_id"`
+	TaskName          string             `json:"task_name"`
+	CaseCode          *string            `json:"case_code,omitempty"`
+	Status            storage.TaskStatus `json:"status"`
+	CreatedBy         string             `json:"created_by"`
+	CreatedByUUID     string             `json:"created_by_uuid"`
+	CreatedAt         time.Time          `json:"created_at"`
+	AssignedToHost    string             `json:"assigned_host,omitempty"`
+	Priority          TaskPriorityFancy  `json:"priority"`
+	EffectivePriority TaskPriorityFancy  `json:"effective_priority"` // EffectivePriority is the priority after the task was aged
+	// Reason the task is not being handed out. Empty if it's handed out to the next worker that can run it
+	Reason string `json:"reason,omitempty"`
+}
+
+// QueueResponse contains the queued tasks in the order they are handed out
+type QueueResponse struct {
+	Data  []QueueItem `json:"data"`
+	Count int         `json:"count"`
+}
+
+// queuedTasks returns the tasks waiting for a worker. Distributed tasks are waiting while they have queued work units
+func (s *Server) queuedTasks() ([]storage.Task, error) {
+	tasks, err := s.stor.GetTasksByStatus(storage.TaskStatusQueued, storage.TaskStatusDequeued, storage.TaskStatusRunning)
+	if err != nil {
+		return nil, err
+	}
+
+	queued := make([]storage.Task, 0, len(tasks))
+	for _, task := range tasks {
+		if task.Status != storage.TaskStatusQueued {
+			if task.WorkUnitCount == 0 {
+				continue
+			}
+
+			units, err := s.stor.GetWorkUnits(task.TaskID)
+			if err != nil {
+				return nil, err
+			}
+
+			if storage.SummarizeWorkUnits(task.Keyspace, units).Queued == 0 {
+				continue
+			}
+		}
+		queued = append(queued, task)
+	}
+	return queued, nil
+}
+
+func (s *Server) webGetQueue(c *gin.Context) *WebAPIError {
+	queued, err := s.queuedTasks()
+	if err != nil {
+		return internalServerError(err)
+	}
+
+	load, err := scheduler.CurrentLoad(s.stor, s.wmgr)
+	if err != nil {
+		return internalServerError(err)
+	}
+
+	entries := scheduler.BuildQueue(s.sched, queued, load, time.Now().UTC())
+	resp := QueueResponse{
+		Data:  make([]QueueItem, len(entries)),
+		Count: len(entries),
+	}
+
+	for i, entry := range entries {
+		resp.Data[i] = QueueItem{
+			Position:          entry.Position,
+			TaskID:            entry.Task.TaskID,
+			TaskName:          entry.Task.TaskName,
+			CaseCode:          entry.Task.CaseCode,
+			Status:            entry.Task.Status,
+			CreatedBy:         entry.Task.CreatedBy,
+			CreatedByUUID:     entry.Task.CreatedByUUID,
+			CreatedAt:         entry.Task.CreatedAt,
+			AssignedToHost:    entry.Task.AssignedToHost,
+			Priority:          TaskPriorityFancy(entry.Task.Priority),
+			EffectivePriority: TaskPriorityFancy(entry.Priority),
+			Reason:            entry.Reason,
+		}
+	}
+
+	c.JSON(http.StatusOK, &resp)
+	return nil
+}
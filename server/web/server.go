@@ -42,6 +42,15 @@ type WebAPIError struct {
 	Err                   error
 }
 
+// internalServerError hides the error from the user behind a generic message
+func internalServerError(err error) *WebAPIError {
+	return &WebAPIError{
+		StatusCode: http.StatusInternalServerError,
+		Err:        err,
+		UserError:  "The server was unable to process your request. Please try again later",
+	}
+}
+
 // MarshalJSON builds a JSON version of WebAPIError and modifies the error message based on the struct
 func (e *WebAPIError) MarshalJSON() ([]byte, error) {
 	// If Gin is in debug mode, go ahead and return the error in the response
@@ -144,10 +153,35 @@ func newHTTPServer(cfg Config, s *Server) *http.Server {
 			granularTaskV2.PATCH("", s.logAction(storage.ActivityModifiedTask, "taskid"), WrapAPIForError(s.webModifyTask))
 			granularTaskV2.DELETE("", checkIfUserIsAdmin(), WrapAPIForError(s.webDeleteTask))
 			granularTaskV2.GET("/passwords", s.logAction(storage.ActivityViewPasswords, "taskid"), WrapAPIForError(s.webGetTaskPasswords))
+			granularTaskV2.GET("/passwords/export", s.logAction(storage.ActivityViewPasswords, "taskid"), WrapAPIForError(s.webExportTaskPasswords))
+			granularTaskV2.GET("/analytics", s.logAction(storage.ActivityViewPasswords, "taskid"), WrapAPIForError(s.webGetTaskAnalytics))
+			granularTaskV2.GET("/policies/:policyid", checkParamValidUUID("policyid"), s.logAction(storage.ActivityViewPasswords, "taskid"), WrapAPIForError(s.webEvaluateTaskPasswordPolicy))
 			granularTaskV2.GET("/entitlements", WrapAPIForError(s.webGetTaskEntitlements))
 			granularTaskV2.PATCH("/status", s.logAction(storage.ActivityModifiedTask, "taskid"), WrapAPIForError(s.webChangeTaskStatus))
+			granularTaskV2.GET("/history", WrapAPIForError(s.webGetTaskStatusHistory))
+			granularTaskV2.POST("/clone", WrapAPIForError(s.webCloneTask))
 		}
 
+		rootAPIG.GET("/cases/:casecode/passwords/export", WrapAPIForError(s.webExportCasePasswords))
+		rootAPIG.GET("/cases/:casecode/analytics", WrapAPIForError(s.webGetCaseAnalytics))
+
+		rootAPIG.GET("/policies/", WrapAPIForError(s.webGetPasswordPolicies))
+		rootAPIG.POST("/policies/", WrapAPIForError(s.webCreatePasswordPolicy))
+		rootAPIG.GET("/policies/:policyid", checkParamValidUUID("policyid"), WrapAPIForError(s.webGetPasswordPolicy))
+		rootAPIG.PUT("/policies/:policyid", checkParamValidUUID("policyid"), WrapAPIForError(s.webUpdatePasswordPolicy))
+		rootAPIG.DELETE("/policies/:policyid", checkParamValidUUID("policyid"), WrapAPIForError(s.webDeletePasswordPolicy))
+
+		rootAPIG.GET("/pipelines/", WrapAPIForError(s.webGetPipelines))
+		rootAPIG.POST("/pipelines/", WrapAPIForError(s.webCreatePipeline))
+		rootAPIG.GET("/pipelines/:pipelineid", checkParamValidUUID("pipelineid"), WrapAPIForError(s.webGetPipeline))
+
+		rootAPIG.GET("/templates/", WrapAPIForError(s.webGetTaskTemplates))
+		rootAPIG.POST("/templates/", WrapAPIForError(s.webCreateTaskTemplate))
+		rootAPIG.GET("/templates/:templateid", checkParamValidUUID("templateid"), WrapAPIForError(s.webGetTaskTemplate))
+		rootAPIG.PUT("/templates/:templateid", checkParamValidUUID("templateid"), WrapAPIForError(s.webUpdateTaskTemplate))
+		rootAPIG.DELETE("/templates/:templateid", checkParamValidUUID("templateid"), WrapAPIForError(s.webDeleteTaskTemplate))
+		rootAPIG.POST("/templates/:templateid/task", checkParamValidUUID("templateid"), WrapAPIForError(s.webCreateTaskFromTemplate))
+
 		rootAPIG.GET("/files/task/", WrapAPIForError(s.webListAvailableTaskFiles))
 		rootAPIG.DELETE("/files/task/:fileid", checkParamValidUUID("fileid"), WrapAPIForError(s.webDeleteFile(deleteTaskFileAPI)))
 		rootAPIG.GET("/files/task/:fileid/download", checkParamValidUUID("fileid"), s.checkIfUserIsEntitled("fileid", storage.EntitlementTaskFile), WrapAPIForError(s.webDownloadTaskFile))
@@ -157,6 +191,8 @@ func newHTTPServer(cfg Config, s *Server) *http.Server {
 		rootAPIG.DELETE("/files/engine/:fileid", checkParamValidUUID("fileid"), WrapAPIForError(s.webDeleteFile(deleteEngineFileAPI)))
 		rootAPIG.GET("/files/engine/:fileid/download", checkParamValidUUID("fileid"), WrapAPIForError(s.webDownloadEngineFile))
 		rootAPIG.PUT("/files/engine/:filename", WrapAPIForError(s.webUploadEngineFile))
+		rootAPIG.POST("/files/engine/generate", WrapAPIForError(s.webGenerateEngineFile))
+		rootAPIG.POST("/files/engine/wordlist", WrapAPIForError(s.webCreateWordlist))
 
 		rootAPIG.GET("/engine/hashcat/hash_modes", s.apiHashcatGetTaskModes)
 
@@ -165,6 +201,10 @@ func newHTTPServer(cfg Config, s *Server) *http.Server {
 		rootAPIG.PATCH("/users/:user_uuid", checkParamValidUUID("user_uuid"), WrapAPIForError(s.webEditUser))
 
 		rootAPIG.GET("/audit/:entityid", checkParamValidUUID("entityid"), checkIfUserIsAdmin(), WrapAPIForError(s.webGetAuditLog))
+
+		rootAPIG.GET("/admin/queue", checkIfUserIsAdmin(), WrapAPIForError(s.webGetQueue))
+		rootAPIG.GET("/admin/backup/snapshot", checkIfUserIsAdmin(), WrapAPIForError(s.webDownloadSnapshot))
+		rootAPIG.GET("/admin/backup/export", checkIfUserIsAdmin(), WrapAPIForError(s.webDownloadExport))
 	}
 
 	// SSE Endpoint
@@ -7,6 +7,9 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/mandiant/gocrack/server/servertest"
+	"github.com/mandiant/gocrack/server/workmgr"
+
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 )
@@ -16,6 +19,16 @@ func init() {
 	gin.SetMode(gin.ReleaseMode)
 }
 
+// newTestServer creates a server on top of a fixture of the storage backend
+func newTestServer(t *testing.T, backend string) (*Server, *servertest.Fixture, func()) {
+	f, closer := servertest.New(t, backend)
+	wmgr := workmgr.NewWorkerManager()
+	return &Server{stor: f.Stor, wmgr: wmgr, fm: f.FM}, f, func() {
+		wmgr.Stop()
+		closer()
+	}
+}
+
 func TestWrapAPIForError(t *testing.T) {
 	e := gin.New()
 	e.GET("/test", WrapAPIForError(func(c *gin.Context) *WebAPIError {
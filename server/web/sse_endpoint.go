@@ -16,7 +16,8 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
-var realtimeTopics = []workmgr.ChannelTopic{workmgr.EngineStatusTopic, workmgr.FinalStatusTopic, workmgr.TaskStatusTopic}
+var realtimeTopics = []workmgr.ChannelTopic{workmgr.EngineStatusTopic, workmgr.FinalStatusTopic, workmgr.TaskStatusTopic, workmgr.TaskProgressTopic,
+	workmgr.PipelineProgressTopic}
 
 type streamPayload struct {
 	Topic   string      `json:"topic"`
@@ -102,6 +103,13 @@ func (s *RealtimeServer) onManagerMessage(msg interface{}) {
 	case workmgr.TaskStatusChangeBroadcast:
 		topicName = "task_status"
 		taskid = m.TaskID
+	case workmgr.TaskProgressBroadcast:
+		topicName = "task_progress"
+		taskid = m.TaskID
+	case workmgr.PipelineProgressBroadcast:
+		// users entitled to the task of the pipeline's current stage are entitled to its progress
+		topicName = "pipeline_progress"
+		taskid = m.TaskID
 	default:
 		return
 	}
@@ -3,6 +3,7 @@ package web
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
@@ -10,20 +11,20 @@ import (
 
 	"github.com/mandiant/gocrack/server/storage"
 	"github.com/mandiant/gocrack/shared"
+	"github.com/mandiant/gocrack/shared/enginedef"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
 )
 
 type TaskCrackEngineFancy storage.WorkerCrackEngine
 
 func (s TaskCrackEngineFancy) MarshalJSON() ([]byte, error) {
-	switch storage.WorkerCrackEngine(s) {
-	case storage.WorkerHashcatEngine:
-		return []byte("\"Hashcat\""), nil
-	default:
-		return []byte("\"Unknown\""), nil
+	if def, ok := enginedef.Lookup(storage.WorkerCrackEngine(s)); ok {
+		return json.Marshal(def.DisplayName)
 	}
+	return []byte("\"Unknown\""), nil
 }
 
 type TaskPriorityFancy storage.WorkerPriority
@@ -54,6 +55,17 @@ type CreateTaskRequest struct {
 	TaskDuration      int                       `json:"task_duration"`
 	Priority          *storage.WorkerPriority   `json:"priority,omitempty"`
 	AdditionalUsers   *[]string                 `json:"additional_users,omitempty"`
+	WorkUnits         int                       `json:"work_units,omitempty"`         // WorkUnits splits the task across this many workers
+	DisableHashReuse  bool                      `json:"disable_hash_reuse,omitempty"` // DisableHashReuse can only be set by admins
+	Schedule          *storage.TaskSchedule     `json:"schedule,omitempty"`           // Schedule restricts when the task can run
+
+	// keepOutOfIndex is set on the clone of a task kept out of the known hash index so the clone is too, whoever creates it
+	keepOutOfIndex bool
+}
+
+// hashReuseDisabled returns true if the task created from the request should be kept out of the known hash index
+func (r CreateTaskRequest) hashReuseDisabled(isAdmin bool) bool {
+	return r.keepOutOfIndex || (isAdmin && r.DisableHashReuse)
 }
 
 // CreateTaskResponse defines response on a successful task creation event
@@ -61,6 +73,8 @@ type CreateTaskResponse struct {
 	TaskID    string             `json:"taskid"`
 	CreatedAt time.Time          `json:"created_at"`
 	Status    storage.TaskStatus `json:"status"`
+	// KnownHashes is the number of hashes the task was credited with as they were cracked by another task
+	KnownHashes int `json:"known_hashes"`
 }
 
 // HashcatEnginePayload defines the structure of task.EnginePayload for jobs created for the hashcat engine
@@ -69,29 +83,59 @@ type HashcatEnginePayload struct {
 	AttackMode             string          `json:"attack_mode"`
 	Masks                  *EngineFileItem `json:"masks,omitempty"`
 	DictionaryFile         *EngineFileItem `json:"dictionary_file,omitempty"`
+	RightDictionaryFile    *EngineFileItem `json:"right_dictionary_file,omitempty"`
 	ManglingRuleFile       *EngineFileItem `json:"mangling_file,omitempty"`
+	RuleLeft               *string         `json:"rule_left,omitempty"`
+	RuleRight              *string         `json:"rule_right,omitempty"`
+	InlineMasks            []string        `json:"inline_masks,omitempty"`
+	CustomCharsets         []CustomCharset `json:"custom_charsets,omitempty"`
+	Increment              bool            `json:"increment,omitempty"`
+	IncrementMin           *int            `json:"increment_min,omitempty"`
+	IncrementMax           *int            `json:"increment_max,omitempty"`
 	DisableOptimizedEngine bool            `json:"disable_optimizations"`
+	// Tuning are the options requested by the task; a worker may lower them to its ceilings
+	Tuning *shared.HashcatTuningOptions `json:"tuning,omitempty"`
+}
+
+// CustomCharset is a custom charset (?1 to ?4) of a hashcat task. File is set when the charset is read from an engine file
+type CustomCharset struct {
+	Charset string          `json:"charset,omitempty"`
+	File    *EngineFileItem `json:"file,omitempty"`
+}
+
+// CPUEnginePayload defines the structure of task.EnginePayload for jobs created for the CPU engine
+type CPUEnginePayload struct {
+	HashType         string          `json:"hash_type"`
+	AttackMode       string          `json:"attack_mode"`
+	Masks            *EngineFileItem `json:"masks,omitempty"`
+	DictionaryFile   *EngineFileItem `json:"dictionary_file,omitempty"`
+	ManglingRuleFile *EngineFileItem `json:"mangling_file,omitempty"`
 }
 
 // TaskInfoResponseItem defines the response for all the information possible about a given task
 type TaskInfoResponseItem struct {
-	TaskID            string               `json:"task_id"`
-	TaskName          string               `json:"task_name"`
-	CaseCode          *string              `json:"case_code,omitempty"`
-	Comment           *string              `json:"comment,omitempty"`
-	AssignedToHost    string               `json:"assigned_host,omitempty"`
-	AssignedToDevices *storage.CLDevices   `json:"assigned_devices,omitempty"`
-	Status            storage.TaskStatus   `json:"status"`
-	CreatedBy         string               `json:"created_by"`
-	CreatedByUUID     string               `json:"created_by_uuid"`
-	CreatedAt         time.Time            `json:"created_at"`
-	Engine            TaskCrackEngineFancy `json:"engine"`
-	FileID            string               `json:"-"` // FileID is a reference to TaskFile via TaskFile.FileID
-	Priority          TaskPriorityFancy    `json:"priority"`
-	EnginePayload     interface{}          `json:"engine_options"`
-	TaskDuration      int                  `json:"task_duration"`
-	FileInfo          *TaskFileItem        `json:"password_file"`
-	Error             *string              `json:"error,omitempty"`
+	TaskID            string                   `json:"task_id"`
+	TaskName          string                   `json:"task_name"`
+	CaseCode          *string                  `json:"case_code,omitempty"`
+	Comment           *string                  `json:"comment,omitempty"`
+	AssignedToHost    string                   `json:"assigned_host,omitempty"`
+	AssignedToDevices *storage.CLDevices       `json:"assigned_devices,omitempty"`
+	Status            storage.TaskStatus       `json:"status"`
+	CreatedBy         string                   `json:"created_by"`
+	CreatedByUUID     string                   `json:"created_by_uuid"`
+	CreatedAt         time.Time                `json:"created_at"`
+	Engine            TaskCrackEngineFancy     `json:"engine"`
+	FileID            string                   `json:"-"` // FileID is a reference to TaskFile via TaskFile.FileID
+	Priority          TaskPriorityFancy        `json:"priority"`
+	EnginePayload     interface{}              `json:"engine_options"`
+	TaskDuration      int                      `json:"task_duration"`
+	FileInfo          *TaskFileItem            `json:"password_file"`
+	Error             *string                  `json:"error,omitempty"`
+	Progress          *float64                 `json:"progress,omitempty"`
+	WorkUnits         *storage.WorkUnitSummary `json:"work_units,omitempty"`
+	DisableHashReuse  bool                     `json:"disable_hash_reuse"`
+	Schedule          *storage.TaskSchedule    `json:"schedule,omitempty"`
+	Runtime           int                      `json:"runtime"` // Runtime is the number of seconds the task has been processed for
 }
 
 // TaskListingResponseItem includes the "bare minimum" information about a task for listing purposes
@@ -104,6 +148,7 @@ type TaskListingResponseItem struct {
 	CreatedBy      string             `json:"created_by"`
 	PasswordsTotal int                `json:"passwords_total"`
 	CrackedTotal   int                `json:"cracked_total"`
+	Progress       *float64           `json:"progress,omitempty"`
 }
 
 type TaskListingResponse struct {
@@ -112,9 +157,11 @@ type TaskListingResponse struct {
 }
 
 type PasswordResponseItem struct {
-	Hash      string    `json:"hash"`
-	Value     string    `json:"value"`
-	CrackedAt time.Time `json:"cracked_at"`
+	Hash             string    `json:"hash"`
+	Value            string    `json:"value"`
+	CrackedAt        time.Time `json:"cracked_at"`
+	ReusedFromTaskID string    `json:"reused_from_task_id,omitempty"` // ReusedFromTaskID is the task that originally cracked the hash
+	Usernames        []string  `json:"usernames,omitempty"`           // Usernames are the accounts of the task file that share the hash
 }
 
 type PasswordListResponse struct {
@@ -127,29 +174,24 @@ type ChangeTaskStatusRequest struct {
 }
 
 type ModifyTaskRequest struct {
-	AssignedToHost    *string             `json:"assigned_host,omitempty"`
-	AssignedToDevices *storage.CLDevices  `json:"assigned_devices,omitempty"`
-	Status            *storage.TaskStatus `json:"task_status,omitempty"`
-	TaskDuration      *int                `json:"task_duration,omitempty"`
+	AssignedToHost    *string               `json:"assigned_host,omitempty"`
+	AssignedToDevices *storage.CLDevices    `json:"assigned_devices,omitempty"`
+	Status            *storage.TaskStatus   `json:"task_status,omitempty"`
+	TaskDuration      *int                  `json:"task_duration,omitempty"`
+	DisableHashReuse  *bool                 `json:"disable_hash_reuse,omitempty"`
+	Schedule          *storage.TaskSchedule `json:"schedule,omitempty"`
 }
 
-// HashcatTaskPayload defines the structure of a Task request which should be executed in a worker with the hashcat engine
-type HashcatTaskPayload shared.HashcatUserOptions
-
-func (hcp HashcatTaskPayload) validate() []string {
+func (s ModifyTaskRequest) validate() []string {
 	errs := make([]string, 0)
 
-	switch hcp.AttackMode {
-	case shared.AttackModeStraight:
-		if hcp.DictionaryFile == nil || *hcp.DictionaryFile == "" {
-			errs = append(errs, "dictionary_file must be set on a straight/dictionary attack mode")
-		}
-	case shared.AttackModeBruteForce:
-		if hcp.Masks == nil || *hcp.Masks == "" {
-			errs = append(errs, "masks must be set on a brute force attack mode")
-		}
+	if s.TaskDuration != nil && *s.TaskDuration < 0 {
+		errs = append(errs, "task_duration must not be negative")
 	}
 
+	if s.Schedule != nil {
+		errs = append(errs, s.Schedule.Validate()...)
+	}
 	return errs
 }
 
@@ -164,32 +206,76 @@ func (s CreateTaskRequest) validate() []string {
 		errs = append(errs, "file_id must be a valid UUID")
 	}
 
-	switch s.Engine {
-	case storage.WorkerHashcatEngine:
-	// do nothing for supported engines
-	default:
-		errs = append(errs, "engine must be hashcat")
+	if s.TaskDuration < 0 {
+		errs = append(errs, "task_duration must not be negative")
+	}
+
+	if s.Schedule != nil {
+		errs = append(errs, s.Schedule.Validate()...)
+	}
+
+	return append(errs, validateEngineOptions(s.Engine, s.WorkUnits, s.AssignedToHost)...)
+}
+
+// validateEngineOptions validates the engine of a task and whether it can be split into the work units
+func validateEngineOptions(engine storage.WorkerCrackEngine, workUnits int, assignedToHost *string) []string {
+	errs := make([]string, 0)
+
+	if _, ok := enginedef.Lookup(engine); !ok {
+		names := make([]string, 0)
+		for _, def := range enginedef.Definitions() {
+			names = append(names, fmt.Sprintf("%d (%s)", def.ID, def.Name))
+		}
+		errs = append(errs, "engine must be one of "+strings.Join(names, ", "))
+	}
+
+	if workUnits < 0 {
+		errs = append(errs, "work_units must not be negative")
+	}
+
+	if workUnits > 1 {
+		if def, ok := enginedef.Lookup(engine); ok && def.Keyspace == nil {
+			errs = append(errs, fmt.Sprintf("the %s engine does not support splitting a task into work units", def.Name))
+		}
+
+		if assignedToHost != nil && *assignedToHost != "" {
+			errs = append(errs, "assigned_host cannot be used when a task is split into work units")
+		}
 	}
 
 	return errs
 }
 
 func (s *Server) webCreateTask(c *gin.Context) *WebAPIError {
+	var request CreateTaskRequest
+
+	if err := c.BindJSON(&request); err != nil {
+		return &WebAPIError{
+			StatusCode:            http.StatusBadRequest,
+			Err:                   err,
+			CanErrorBeShownToUser: true,
+			UserError:             "Your request is malformed",
+		}
+	}
+	return s.createTask(c, request)
+}
+
+// createTask validates the request and creates the task on behalf of the user if they can access its task file
+func (s *Server) createTask(c *gin.Context, request CreateTaskRequest) *WebAPIError {
 	var (
-		request CreateTaskRequest
-		err     error
-		tf      *storage.TaskFile
-		payload interface{}
-		task    storage.Task
-		now     = time.Now().UTC()
+		err      error
+		tf       *storage.TaskFile
+		payload  interface{}
+		task     storage.Task
+		units    []storage.WorkUnit
+		credited int
+		now      = time.Now().UTC()
+
+		additionalUsers []string
 	)
 
 	claim := getClaimInformation(c)
 
-	if err := c.BindJSON(&request); err != nil {
-		goto BadRequest
-	}
-
 	if errs := request.validate(); len(errs) > 0 {
 		c.JSON(http.StatusBadRequest, APIValidationErrors{
 			Valid:  false,
@@ -219,24 +305,17 @@ func (s *Server) webCreateTask(c *gin.Context) *WebAPIError {
 		}
 	}
 
-	switch request.Engine {
-	case storage.WorkerHashcatEngine:
-		var hcp HashcatTaskPayload
-		if err := json.Unmarshal(request.EnginePayload, &hcp); err != nil {
-			goto BadRequest
-		}
-
-		if errs := hcp.validate(); len(errs) > 0 {
-			c.JSON(http.StatusBadRequest, APIValidationErrors{
-				Valid:  false,
-				Errors: errs,
-			})
-			return nil
-		}
-		// XXX(cschmitt): Check the entitlement on any of the fields that are a remote file
-		payload = hcp
-	default:
+	if p, errs, e := decodeTaskPayload(request, tf); e != nil {
+		err = e
 		goto BadRequest
+	} else if len(errs) > 0 {
+		c.JSON(http.StatusBadRequest, APIValidationErrors{
+			Valid:  false,
+			Errors: errs,
+		})
+		return nil
+	} else {
+		payload = p
 	}
 
 	task = storage.Task{
@@ -253,6 +332,12 @@ func (s *Server) webCreateTask(c *gin.Context) *WebAPIError {
 		Comment:       request.Comment,
 	}
 
+	task.DisableHashReuse = request.hashReuseDisabled(claim.IsAdmin)
+
+	if request.Schedule != nil {
+		task.Schedule = *request.Schedule
+	}
+
 	// Set the device affinity if the request contains valid data
 	if request.AssignedToHost != nil && request.AssignedToDevices != nil {
 		task.AssignedToDevices = request.AssignedToDevices
@@ -266,30 +351,28 @@ func (s *Server) webCreateTask(c *gin.Context) *WebAPIError {
 		task.Priority = *request.Priority
 	}
 
-	// Save the task, it's creator, and any additional users
-	if txn, e := s.stor.NewTaskCreateTransaction(); e != nil {
-		err = e
+	if units, err = s.splitTask(&task, request.WorkUnits); err != nil {
+		goto BadRequest
+	}
+
+	if request.AdditionalUsers != nil {
+		additionalUsers = *request.AdditionalUsers
+	}
+
+	if err = s.saveTask(&task, units, additionalUsers); err != nil {
 		goto ServerError
-	} else {
-		if err = txn.CreateTask(&task); err != nil {
-			goto ServerError
-		}
-		defer txn.Rollback() // wont be rolled back if the commit occurs
+	}
 
-		if request.AdditionalUsers != nil && len(*request.AdditionalUsers) > 0 {
-			for _, userid := range *request.AdditionalUsers {
-				if err = txn.GrantEntitlement(userid, task); err != nil {
-					goto ServerError
-				}
-			}
-		}
-		txn.Commit()
+	// The task has been created at this point so it's left for a worker to crack every hash if the index can't be consulted
+	if credited, err = s.creditKnownHashes(&task, tf); err != nil {
+		log.Warn().Err(err).Str("task_id", task.TaskID).Msg("Failed to credit task with known hashes")
 	}
 
 	c.JSON(http.StatusCreated, &CreateTaskResponse{
-		TaskID:    task.TaskID,
-		CreatedAt: task.CreatedAt,
-		Status:    task.Status,
+		TaskID:      task.TaskID,
+		CreatedAt:   task.CreatedAt,
+		Status:      task.Status,
+		KnownHashes: credited,
 	})
 	return nil
 
@@ -314,6 +397,70 @@ ServerError:
 	}
 }
 
+// decodeTaskPayload decodes & validates the engine payload of the request against the task file it attacks. Validation
+// failures are returned as errs while err is set if the payload could not be decoded
+func decodeTaskPayload(request CreateTaskRequest, tf *storage.TaskFile) (payload interface{}, errs []string, err error) {
+	enginePayload, err := enginedef.DecodePayload(request.Engine, request.EnginePayload)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if errs = enginePayload.Validate(); len(errs) > 0 {
+		return nil, errs, nil
+	}
+
+	// the CPU engine reads the task file itself and only understands a hash per line
+	if request.Engine == storage.WorkerCPUEngine && tf.Format != storage.TaskFileFormatHashes {
+		return nil, []string{"the cpu engine only supports task files that contain a hash per line"}, nil
+	}
+	// XXX(cschmitt): Check the entitlement on any of the fields that are a remote file
+	return hashcatUsernameOption(enginePayload, tf), nil, nil
+}
+
+// splitTask splits the keyspace of the task so it can be processed by multiple workers at once. No work units are
+// returned if the task runs on a single worker
+func (s *Server) splitTask(task *storage.Task, workUnits int) ([]storage.WorkUnit, error) {
+	if workUnits <= 1 {
+		return nil, nil
+	}
+
+	var err error
+	def, _ := enginedef.Lookup(task.Engine)
+	if task.Keyspace, err = def.Keyspace(task.EnginePayload.(enginedef.Payload), s.stor.GetEngineFileByID); err != nil {
+		return nil, err
+	}
+
+	units := storage.SplitKeyspace(task.TaskID, task.Keyspace, workUnits)
+	task.WorkUnitCount = len(units)
+	return units, nil
+}
+
+// saveTask saves the task, its work units, and entitles its creator & the additional users to it
+func (s *Server) saveTask(task *storage.Task, units []storage.WorkUnit, additionalUsers []string) error {
+	txn, err := s.stor.NewTaskCreateTransaction()
+	if err != nil {
+		return err
+	}
+	defer txn.Rollback() // wont be rolled back if the commit occurs
+
+	if err = txn.CreateTask(task); err != nil {
+		return err
+	}
+
+	if len(units) > 0 {
+		if err = txn.CreateWorkUnits(units); err != nil {
+			return err
+		}
+	}
+
+	for _, userid := range additionalUsers {
+		if err = txn.GrantEntitlement(userid, *task); err != nil {
+			return err
+		}
+	}
+	return txn.Commit()
+}
+
 func (s *Server) webGetTaskInfo(c *gin.Context) *WebAPIError {
 	var (
 		taskid   = c.Param("taskid")
@@ -423,6 +570,9 @@ func (s *Server) getAvailableTasks(c *gin.Context) *WebAPIError {
 			PasswordsTotal: task.NumberPasswords,
 			CrackedTotal:   task.NumberCracked,
 		}
+		if task.WorkUnitCount > 0 {
+			item.Progress = &tasks[i].Progress
+		}
 		// Add the case code if the pointer is not nil
 		if task.CaseCode != nil {
 			item.CaseCode = *task.CaseCode
@@ -469,8 +619,23 @@ func (s *Server) webGetTaskPasswords(c *gin.Context) *WebAPIError {
 			return nil
 		}
 
+		accounts, err := s.taskFileAccounts(task.FileID)
+		if err != nil {
+			return &WebAPIError{
+				StatusCode: http.StatusInternalServerError,
+				Err:        err,
+				UserError:  "The server was unable to process your request. Please try again later",
+			}
+		}
+
 		for _, pass := range *storpass {
-			passwords = append(passwords, PasswordResponseItem(pass))
+			passwords = append(passwords, PasswordResponseItem{
+				Hash:             pass.Hash,
+				Value:            pass.Value,
+				CrackedAt:        pass.CrackedAt,
+				ReusedFromTaskID: pass.ReusedFromTaskID,
+				Usernames:        accounts[strings.ToLower(pass.Hash)],
+			})
 		}
 		c.JSON(http.StatusOK, &PasswordListResponse{
 			Data:  passwords,
@@ -539,7 +704,7 @@ func (s *Server) webChangeTaskStatus(c *gin.Context) *WebAPIError {
 				UserError:  "The task is already in the processing of starting",
 			}
 		}
-	case storage.TaskStatusStopped, storage.TaskStatusError:
+	case storage.TaskStatusStopped, storage.TaskStatusError, storage.TaskStatusOutOfTime:
 		// do nothing
 	case storage.TaskStatusStopping:
 		return &WebAPIError{
@@ -553,6 +718,13 @@ func (s *Server) webChangeTaskStatus(c *gin.Context) *WebAPIError {
 		}
 	}
 
+	if newStatus == storage.TaskStatusQueued && task.IsOutOfTime(time.Now().UTC()) {
+		return &WebAPIError{
+			StatusCode: http.StatusBadRequest,
+			UserError:  "The task has used up its time budget. Increase the task duration before resuming it",
+		}
+	}
+
 	if newStatus == storage.TaskStatusQueued {
 		ok, err := s.checkIfTaskFilesArePresent(task)
 		if err != nil {
@@ -571,7 +743,44 @@ func (s *Server) webChangeTaskStatus(c *gin.Context) *WebAPIError {
 		}
 	}
 
-	if err := s.stor.ChangeTaskStatus(taskid, newStatus, nil); err != nil {
+	if task.WorkUnitCount > 0 {
+		if err := s.changeWorkUnitsStatus(task, &newStatus); err != nil {
+			return &WebAPIError{
+				StatusCode: http.StatusInternalServerError,
+				Err:        err,
+				UserError:  "The server was unable to process your request. Please try again later",
+			}
+		}
+	}
+
+	// The user has taken over from the execution windows so they will no longer resume the task
+	if task.StoppedByWindow {
+		if err := s.stor.SetTaskStoppedByWindow(taskid, false); err != nil {
+			return &WebAPIError{
+				StatusCode: http.StatusInternalServerError,
+				Err:        err,
+				UserError:  "The server was unable to process your request. Please try again later",
+			}
+		}
+	}
+
+	// Likewise, a task the user changes while it's being preempted is no longer queued again by the server
+	if task.Preempted {
+		if err := s.stor.SetTaskPreempted(taskid, false); err != nil {
+			return &WebAPIError{
+				StatusCode: http.StatusInternalServerError,
+				Err:        err,
+				UserError:  "The server was unable to process your request. Please try again later",
+			}
+		}
+	}
+
+	entry, err := s.stor.ChangeTaskStatus(taskid, storage.TaskStatusChange{
+		Status:    newStatus,
+		ActorType: storage.StatusActorUser,
+		Actor:     getClaimInformation(c).UserUUID,
+	})
+	if err != nil {
 		return &WebAPIError{
 			StatusCode: http.StatusInternalServerError,
 			Err:        err,
@@ -579,10 +788,29 @@ func (s *Server) webChangeTaskStatus(c *gin.Context) *WebAPIError {
 		}
 	}
 
+	// The status has been changed at this point so failing to notify the subscribers is only logged
+	if err := s.wmgr.BroadcastTaskStatusChange(*entry); err != nil {
+		log.Warn().Err(err).Str("task_id", taskid).Msg("Failed to broadcast task status change")
+	}
+
 	c.Status(http.StatusNoContent)
 	return nil
 }
 
+func (s *Server) webGetTaskStatusHistory(c *gin.Context) *WebAPIError {
+	history, err := s.stor.GetTaskStatusHistory(c.Param("taskid"))
+	if err != nil {
+		return &WebAPIError{
+			StatusCode: http.StatusInternalServerError,
+			Err:        err,
+			UserError:  "The server was unable to process your request. Please try again later",
+		}
+	}
+
+	c.JSON(http.StatusOK, &history)
+	return nil
+}
+
 func (s *Server) webModifyTask(c *gin.Context) *WebAPIError {
 	var taskid = c.Param("taskid")
 	var request ModifyTaskRequest
@@ -595,11 +823,20 @@ func (s *Server) webModifyTask(c *gin.Context) *WebAPIError {
 		}
 	}
 
+	if errs := request.validate(); len(errs) > 0 {
+		c.JSON(http.StatusBadRequest, APIValidationErrors{
+			Valid:  false,
+			Errors: errs,
+		})
+		return nil
+	}
+
 	claim := getClaimInformation(c)
 
-	// Only admins can override the task status
-	if !claim.IsAdmin && request.Status != nil {
+	// Only admins can override the task status or keep its hashes out of the known hash index
+	if !claim.IsAdmin {
 		request.Status = nil
+		request.DisableHashReuse = nil
 	}
 
 	if err := s.stor.UpdateTask(taskid, storage.ModifiableTaskRequest(request)); err != nil {
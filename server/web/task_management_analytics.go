@@ -0,0 +1,117 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/mandiant/gocrack/server/analytics"
+	"github.com/mandiant/gocrack/server/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxAnalyticsTop is the most entries a user may request in each of the ranked lists of a report
+const maxAnalyticsTop = 100
+
+// AnalyticsResponse is the analysis of the cracked passwords of one or more tasks
+type AnalyticsResponse struct {
+	Tasks []string `json:"tasks"`
+	*analytics.Report
+}
+
+func (s *Server) webGetTaskAnalytics(c *gin.Context) *WebAPIError {
+	task, err := s.stor.GetTaskByID(c.Param("taskid"))
+	if err != nil {
+		if err == storage.ErrNotFound {
+			return &WebAPIError{
+				StatusCode: http.StatusNotFound,
+				Err:        err,
+				UserError:  "The requested file does not exist or you do not have permissions to it",
+			}
+		}
+		return internalServerError(err)
+	}
+	return s.analyzeTasks(c, []storage.Task{*task})
+}
+
+// webGetCaseAnalytics analyzes the passwords of every task with the case code that the user is entitled to
+func (s *Server) webGetCaseAnalytics(c *gin.Context) *WebAPIError {
+	claim := getClaimInformation(c)
+
+	tasks, err := s.stor.GetTasksByCaseCode(c.Param("casecode"), storage.User{
+		UserUUID:    claim.UserUUID,
+		IsSuperUser: claim.IsAdmin,
+	})
+	if err != nil {
+		return internalServerError(err)
+	}
+
+	if len(tasks) == 0 {
+		return &WebAPIError{
+			StatusCode: http.StatusNotFound,
+			UserError:  "The requested case does not exist or you do not have permissions to any of its tasks",
+		}
+	}
+
+	apiErr := s.analyzeTasks(c, tasks)
+	s.logPasswordViews(c, tasks)
+	return apiErr
+}
+
+// analyzeTasks responds with a report of the cracked passwords of the tasks. Passwords are weighted by the number of
+// accounts in the task file that use them
+func (s *Server) analyzeTasks(c *gin.Context, tasks []storage.Task) *WebAPIError {
+	top, err := strconv.Atoi(c.DefaultQuery("top", strconv.Itoa(analytics.DefaultTop)))
+	if err != nil || top <= 0 || top > maxAnalyticsTop {
+		return &WebAPIError{
+			StatusCode:            http.StatusBadRequest,
+			Err:                   errors.New("top must be an integer between 1 and 100"),
+			CanErrorBeShownToUser: true,
+		}
+	}
+
+	resp := AnalyticsResponse{Tasks: make([]string, len(tasks))}
+	for i, task := range tasks {
+		resp.Tasks[i] = task.TaskID
+	}
+
+	entries, err := s.crackedEntries(tasks)
+	if err != nil {
+		return internalServerError(err)
+	}
+
+	resp.Report = analytics.Analyze(entries, top)
+	c.JSON(http.StatusOK, &resp)
+	return nil
+}
+
+// crackedEntries returns the cracked passwords of the tasks weighted by the number of accounts in the task file that
+// use them
+func (s *Server) crackedEntries(tasks []storage.Task) ([]analytics.Entry, error) {
+	entries := make([]analytics.Entry, 0)
+	for _, task := range tasks {
+		cracked, err := s.stor.GetCrackedPasswords(task.TaskID)
+		if err != nil {
+			if err == storage.ErrNotFound {
+				continue
+			}
+			return nil, err
+		}
+
+		accounts, err := s.taskFileAccounts(task.FileID)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, pass := range *cracked {
+			entries = append(entries, analytics.Entry{
+				Value:     pass.Value,
+				CrackedAt: pass.CrackedAt,
+				Accounts:  len(accounts[strings.ToLower(pass.Hash)]),
+			})
+		}
+	}
+	return entries, nil
+}
@@ -1,18 +1,33 @@
 package web
 
 import (
-	"fmt"
 	"net/http"
+	"os"
+	"strconv"
 
 	"github.com/mandiant/gocrack/server/storage"
 
 	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
 )
 
 func (s *Server) webDeleteTask(c *gin.Context) *WebAPIError {
+	var opts storage.DeleteTaskOptions
 	taskid := c.Param("taskid")
 
-	if err := s.stor.DeleteTask(taskid); err != nil {
+	if val := c.Query("remove_task_file"); val != "" {
+		var err error
+		if opts.RemoveUnusedTaskFile, err = strconv.ParseBool(val); err != nil {
+			return &WebAPIError{
+				StatusCode: http.StatusBadRequest,
+				Err:        err,
+				UserError:  "remove_task_file must be a boolean",
+			}
+		}
+	}
+
+	removedFile, err := s.stor.DeleteTask(taskid, opts)
+	if err != nil {
 		if err == storage.ErrNotFound {
 			return &WebAPIError{
 				UserError:  "Could not delete task as it does not exist",
@@ -28,19 +43,10 @@ func (s *Server) webDeleteTask(c *gin.Context) *WebAPIError {
 		}
 	}
 
-	if err := s.stor.RemoveEntitlements(taskid, storage.EntitlementTask); err != nil {
-		return &WebAPIError{
-			UserError:  "An error occurred while trying to delete your task",
-			StatusCode: http.StatusInternalServerError,
-			Err:        fmt.Errorf("failed to clear entitlement entries for %s: %v", taskid, err),
-		}
-	}
-
-	if err := s.stor.RemoveActivityEntries(taskid); err != nil {
-		return &WebAPIError{
-			UserError:  "An error occurred while trying to delete your task",
-			StatusCode: http.StatusInternalServerError,
-			Err:        fmt.Errorf("failed to clear activity entries for %s: %v", taskid, err),
+	// The task has been deleted at this point so failing to remove the file is only logged
+	if removedFile != nil {
+		if err := os.Remove(removedFile.SavedAt); err != nil && !os.IsNotExist(err) {
+			log.Warn().Err(err).Str("file_id", removedFile.FileID).Str("path", removedFile.SavedAt).Msg("Failed to remove task file from disk")
 		}
 	}
 
@@ -0,0 +1,317 @@
+package web
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mandiant/gocrack/server/hashfile"
+	"github.com/mandiant/gocrack/server/storage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	exportFormatCSV     = "csv"
+	exportFormatJSONL   = "jsonl"
+	exportFormatPotfile = "potfile"
+	exportFormatJohn    = "john"
+	exportFormatLeft    = "left"
+)
+
+// exportContentTypes is the content type & file extension of every export format
+var exportContentTypes = map[string][2]string{
+	exportFormatCSV:     {"text/csv", "csv"},
+	exportFormatJSONL:   {"application/x-ndjson", "jsonl"},
+	exportFormatPotfile: {"text/plain", "pot"},
+	exportFormatJohn:    {"text/plain", "pot"},
+	exportFormatLeft:    {"text/plain", "left"},
+}
+
+// johnHashPrefixes are the tags John the Ripper prepends to hashes of the hashcat mode in its pot file.
+// Hashes of any other mode are written as is
+var johnHashPrefixes = map[int]string{
+	0:    "$dynamic_0$",
+	100:  "$dynamic_26$",
+	1000: "$NT$",
+	1400: "$SHA256$",
+	3000: "$LM$",
+}
+
+// ExportedPassword is a line of a JSON lines export
+type ExportedPassword struct {
+	TaskID string `json:"task_id"`
+	PasswordResponseItem
+}
+
+// passwordExporter writes the cracked passwords of one or more tasks in an export format
+type passwordExporter struct {
+	s      *Server
+	format string
+	w      *bufio.Writer
+	csv    *csv.Writer
+}
+
+func (s *Server) webExportTaskPasswords(c *gin.Context) *WebAPIError {
+	task, err := s.stor.GetTaskByID(c.Param("taskid"))
+	if err != nil {
+		if err == storage.ErrNotFound {
+			return &WebAPIError{
+				StatusCode: http.StatusNotFound,
+				Err:        err,
+				UserError:  "The requested file does not exist or you do not have permissions to it",
+			}
+		}
+		return &WebAPIError{
+			StatusCode: http.StatusInternalServerError,
+			Err:        err,
+			UserError:  "The server was unable to process your request. Please try again later",
+		}
+	}
+	return s.exportPasswords(c, task.TaskID, []storage.Task{*task})
+}
+
+// webExportCasePasswords exports the passwords of every task with the case code that the user is entitled to. As the
+// route is not specific to a task, a view of the passwords is logged against each of them
+func (s *Server) webExportCasePasswords(c *gin.Context) *WebAPIError {
+	claim := getClaimInformation(c)
+	caseCode := c.Param("casecode")
+
+	tasks, err := s.stor.GetTasksByCaseCode(caseCode, storage.User{
+		UserUUID:    claim.UserUUID,
+		IsSuperUser: claim.IsAdmin,
+	})
+	if err != nil {
+		return &WebAPIError{
+			StatusCode: http.StatusInternalServerError,
+			Err:        err,
+			UserError:  "The server was unable to process your request. Please try again later",
+		}
+	}
+
+	if len(tasks) == 0 {
+		return &WebAPIError{
+			StatusCode: http.StatusNotFound,
+			UserError:  "The requested case does not exist or you do not have permissions to any of its tasks",
+		}
+	}
+
+	apiErr := s.exportPasswords(c, caseCode, tasks)
+	s.logPasswordViews(c, tasks)
+	return apiErr
+}
+
+// logPasswordViews logs a view of the passwords of every task for routes that are not specific to a single task
+func (s *Server) logPasswordViews(c *gin.Context, tasks []storage.Task) {
+	claim := getClaimInformation(c)
+	for _, task := range tasks {
+		record := storage.ActivityLogEntry{
+			OccuredAt:  time.Now().UTC(),
+			UserUUID:   claim.UserUUID,
+			Type:       storage.ActivityViewPasswords,
+			EntityID:   task.TaskID,
+			StatusCode: c.Writer.Status(),
+			Path:       c.Request.URL.EscapedPath(),
+			IPAddress:  c.ClientIP(),
+		}
+		if err := s.stor.LogActivity(record); err != nil {
+			log.Error().Interface("record", record).Err(err).Msg("Failed to write activity log to database")
+		}
+	}
+}
+
+// exportPasswords streams the export of the tasks in the format requested by the query. Once the response has started,
+// errors can no longer be returned to the user so they're logged and the response is cut short
+func (s *Server) exportPasswords(c *gin.Context, name string, tasks []storage.Task) *WebAPIError {
+	format := strings.ToLower(c.DefaultQuery("format", exportFormatCSV))
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		return &WebAPIError{
+			StatusCode:            http.StatusBadRequest,
+			Err:                   errors.New("format must be one of csv, jsonl, potfile, john or left"),
+			CanErrorBeShownToUser: true,
+		}
+	}
+
+	c.Header("Content-Type", contentType[0])
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.%s\"", exportFileName(name), contentType[1]))
+	c.Status(http.StatusOK)
+
+	exp := &passwordExporter{s: s, format: format, w: bufio.NewWriter(c.Writer)}
+	if err := exp.export(tasks); err != nil {
+		log.Error().Err(err).Str("export", name).Str("format", format).Msg("Failed to export passwords")
+	}
+	return nil
+}
+
+// exportFileName replaces the characters of a case code that are not safe to use in a file name
+func exportFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+func (e *passwordExporter) export(tasks []storage.Task) error {
+	if e.format == exportFormatLeft {
+		if err := e.writeLeft(tasks); err != nil {
+			return err
+		}
+		return e.w.Flush()
+	}
+
+	if e.format == exportFormatCSV {
+		e.csv = csv.NewWriter(e.w)
+		if err := e.csv.Write([]string{"task_id", "task_name", "hash", "value", "cracked_at", "usernames", "reused_from_task_id"}); err != nil {
+			return err
+		}
+	}
+
+	for _, task := range tasks {
+		if err := e.writeTask(task); err != nil {
+			return err
+		}
+	}
+
+	if e.csv != nil {
+		e.csv.Flush()
+		if err := e.csv.Error(); err != nil {
+			return err
+		}
+	}
+	return e.w.Flush()
+}
+
+// writeTask writes the cracked passwords of the task
+func (e *passwordExporter) writeTask(task storage.Task) error {
+	cracked, err := e.s.stor.GetCrackedPasswords(task.TaskID)
+	if err != nil {
+		if err == storage.ErrNotFound {
+			return nil
+		}
+		return err
+	}
+
+	accounts, err := e.s.taskFileAccounts(task.FileID)
+	if err != nil {
+		return err
+	}
+
+	hashType, _ := taskHashType(&task)
+	enc := json.NewEncoder(e.w)
+	for _, pass := range *cracked {
+		usernames := accounts[strings.ToLower(pass.Hash)]
+
+		switch e.format {
+		case exportFormatCSV:
+			err = e.csv.Write([]string{
+				task.TaskID,
+				task.TaskName,
+				pass.Hash,
+				pass.Value,
+				pass.CrackedAt.Format(time.RFC3339),
+				strings.Join(usernames, ";"),
+				pass.ReusedFromTaskID,
+			})
+		case exportFormatJSONL:
+			err = enc.Encode(&ExportedPassword{
+				TaskID: task.TaskID,
+				PasswordResponseItem: PasswordResponseItem{
+					Hash:             pass.Hash,
+					Value:            pass.Value,
+					CrackedAt:        pass.CrackedAt,
+					ReusedFromTaskID: pass.ReusedFromTaskID,
+					Usernames:        usernames,
+				},
+			})
+		case exportFormatPotfile:
+			_, err = e.w.WriteString(pass.Hash + ":" + potfileValue(pass.Value) + "\n")
+		case exportFormatJohn:
+			_, err = e.w.WriteString(johnHashPrefixes[hashType] + pass.Hash + ":" + potfileValue(pass.Value) + "\n")
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeLeft writes the lines of the task files that have not been cracked. Tasks that share a task file have it written
+// once without the hashes cracked by any of them
+func (e *passwordExporter) writeLeft(tasks []storage.Task) error {
+	var fileIDs []string
+	hashTypes := make(map[string]int)
+	cracked := make(map[string]map[string]bool)
+
+	for _, task := range tasks {
+		if _, ok := cracked[task.FileID]; !ok {
+			fileIDs = append(fileIDs, task.FileID)
+			hashTypes[task.FileID], _ = taskHashType(&task)
+			cracked[task.FileID] = make(map[string]bool)
+		}
+
+		passwords, err := e.s.stor.GetCrackedPasswords(task.TaskID)
+		if err != nil {
+			if err == storage.ErrNotFound {
+				continue
+			}
+			return err
+		}
+
+		for _, pass := range *passwords {
+			cracked[task.FileID][strings.ToLower(pass.Hash)] = true
+		}
+	}
+
+	for _, fileID := range fileIDs {
+		tf, err := e.s.stor.GetTaskFileByID(fileID)
+		if err != nil {
+			if err == storage.ErrNotFound {
+				// the file was removed along with a task that used it
+				continue
+			}
+			return err
+		}
+
+		if err = hashfile.Filter(e.w, tf.SavedAt, tf.Format, hashTypes[fileID], cracked[fileID]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// taskHashType returns the hashcat mode of the task's hashes. Unlike storage.TaskHashType, it does not take into
+// account whether the task shares its hashes with other tasks
+func taskHashType(task *storage.Task) (int, bool) {
+	ht, ok := task.EnginePayload.(storage.HashTyper)
+	if !ok {
+		return 0, false
+	}
+	return ht.GetHashType(), true
+}
+
+// potfileValue encodes the password as $HEX[...] if it contains characters that can't be written to a pot file as is.
+// Hashcat reports such passwords already encoded so they're passed through unchanged
+func potfileValue(value string) string {
+	if strings.HasPrefix(value, "$HEX[") {
+		return value
+	}
+
+	for i := 0; i < len(value); i++ {
+		if value[i] < 0x20 || value[i] > 0x7e {
+			return "$HEX[" + hex.EncodeToString([]byte(value)) + "]"
+		}
+	}
+	return value
+}
@@ -0,0 +1,251 @@
+package web
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mandiant/gocrack/server/authentication"
+	"github.com/mandiant/gocrack/server/servertest"
+	"github.com/mandiant/gocrack/server/storage"
+	"github.com/mandiant/gocrack/shared"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	ntlmPassword = "8846f7eaee8fb117ad06bdd830b7586c"
+	ntlmEmpty    = "31d6cfe0d16ae931b73c59d7e0c089c0"
+)
+
+// newExportTask creates a finished hashcat task of the hash type against a task file of the hashes
+func newExportTask(t *testing.T, f *servertest.Fixture, creator storage.User, hashType int, caseCode string, hashes ...string) storage.Task {
+	task := storage.Task{
+		FileID: f.CreateTaskFile(t, creator, hashes...).FileID,
+		Engine: storage.WorkerHashcatEngine,
+		EnginePayload: shared.HashcatUserOptions{
+			HashType:   hashType,
+			AttackMode: shared.AttackModeBruteForce,
+			Masks:      shared.GetStrPtr("?a?a?a?a"),
+		},
+		Priority: storage.WorkerPriorityNormal,
+		Status:   storage.TaskStatusFinished,
+	}
+	if caseCode != "" {
+		task.CaseCode = shared.GetStrPtr(caseCode)
+	}
+
+	f.CreateTask(t, creator, &task)
+	return task
+}
+
+// exportPasswords requests the export at path as the user
+func exportPasswords(s *Server, user storage.User, path string) *httptest.ResponseRecorder {
+	e := gin.New()
+	e.Use(func(c *gin.Context) {
+		c.Set("claim", &authentication.AuthClaim{
+			Username: user.Username,
+			UserUUID: user.UserUUID,
+			IsAdmin:  user.IsSuperUser,
+		})
+		c.Next()
+	})
+	e.GET("/task/:taskid/passwords/export", WrapAPIForError(s.webExportTaskPasswords))
+	e.GET("/cases/:casecode/passwords/export", WrapAPIForError(s.webExportCasePasswords))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", path, nil)
+	e.ServeHTTP(w, req)
+	return w
+}
+
+func TestPotfileValue(t *testing.T) {
+	assert.Equal(t, "password", potfileValue("password"))
+	assert.Equal(t, "pass:word", potfileValue("pass:word"))
+	assert.Equal(t, "$HEX[70c3a4737300]", potfileValue("päss\x00"))
+	assert.Equal(t, "$HEX[70c3a4737300]", potfileValue("$HEX[70c3a4737300]"))
+}
+
+func TestExportFileName(t *testing.T) {
+	assert.Equal(t, "IR-1234", exportFileName("IR-1234"))
+	assert.Equal(t, "case_1______", exportFileName("case 1/\";\r\n_"))
+}
+
+func TestExportTaskPasswordsCSV(t *testing.T) {
+	s, f, closer := newTestServer(t, "bdb")
+	defer closer()
+
+	user := f.CreateUser(t, false)
+	task := newExportTask(t, f, user, 0, "", md5Password, md5Letmein)
+	txn, err := s.stor.NewTaskFileTransaction()
+	if err != nil {
+		assert.FailNow(t, "failed to create task file transaction", err.Error())
+	}
+	assert.Nil(t, txn.SaveTaskFileAccounts([]storage.TaskFileAccount{{FileID: task.FileID, Username: "alice", Hash: md5Password}}))
+	assert.Nil(t, txn.Commit())
+
+	crackedAt := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	assert.Nil(t, s.stor.SaveCrackedHash(task.TaskID, md5Password, "password", crackedAt))
+
+	w := exportPasswords(s, user, "/task/"+task.TaskID+"/passwords/export")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv", w.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="`+task.TaskID+`.csv"`, w.Header().Get("Content-Disposition"))
+
+	rows, err := csv.NewReader(w.Body).ReadAll()
+	if assert.Nil(t, err) && assert.Len(t, rows, 2) {
+		assert.Equal(t, []string{"task_id", "task_name", "hash", "value", "cracked_at", "usernames", "reused_from_task_id"}, rows[0])
+		assert.Equal(t, []string{task.TaskID, task.TaskName, md5Password, "password", "2024-03-01T12:00:00Z", "alice", ""}, rows[1])
+	}
+
+	w = exportPasswords(s, user, "/task/"+task.TaskID+"/passwords/export?format=xml")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestExportTaskPasswordsJSONL(t *testing.T) {
+	s, f, closer := newTestServer(t, "bdb")
+	defer closer()
+
+	user := f.CreateUser(t, false)
+	task := newExportTask(t, f, user, 0, "", md5Password, md5Letmein, md5Summer)
+
+	crackedAt := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	assert.Nil(t, s.stor.SaveCrackedHash(task.TaskID, md5Password, "password", crackedAt))
+	assert.Nil(t, s.stor.SaveCrackedHash(task.TaskID, md5Letmein, "letmein", crackedAt))
+
+	w := exportPasswords(s, user, "/task/"+task.TaskID+"/passwords/export?format=jsonl")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+
+	values := map[string]string{}
+	scanner := bufio.NewScanner(w.Body)
+	for scanner.Scan() {
+		var exported ExportedPassword
+		if !assert.Nil(t, json.Unmarshal(scanner.Bytes(), &exported)) {
+			return
+		}
+		assert.Equal(t, task.TaskID, exported.TaskID)
+		assert.True(t, crackedAt.Equal(exported.CrackedAt))
+		values[exported.Hash] = exported.Value
+	}
+	assert.Equal(t, map[string]string{md5Password: "password", md5Letmein: "letmein"}, values)
+}
+
+func TestExportTaskPasswordsJohn(t *testing.T) {
+	s, f, closer := newTestServer(t, "bdb")
+	defer closer()
+
+	user := f.CreateUser(t, false)
+	ntlm := newExportTask(t, f, user, 1000, "", ntlmPassword)
+	md5 := newExportTask(t, f, user, 0, "", md5Password)
+	sha1 := newExportTask(t, f, user, 100, "", "5baa61e4c9b93f3f0682250b6cf8331b7ee68fd8")
+	sha512 := newExportTask(t, f, user, 1700, "", "b109f3bbbc244eb82441917ed06d618b9008dd09b3befd1b5e07394c706a8bb980b1d7785e5976ec049b46df5f1326af5a2ea6d103fd07c95385ffab0cacbc86")
+
+	crackedAt := time.Now().UTC()
+	assert.Nil(t, s.stor.SaveCrackedHash(ntlm.TaskID, ntlmPassword, "password", crackedAt))
+	assert.Nil(t, s.stor.SaveCrackedHash(md5.TaskID, md5Password, "$HEX[70c3a4737300]", crackedAt))
+	assert.Nil(t, s.stor.SaveCrackedHash(sha1.TaskID, "5baa61e4c9b93f3f0682250b6cf8331b7ee68fd8", "password", crackedAt))
+	assert.Nil(t, s.stor.SaveCrackedHash(sha512.TaskID, "b109f3bbbc244eb82441917ed06d618b9008dd09b3befd1b5e07394c706a8bb980b1d7785e5976ec049b46df5f1326af5a2ea6d103fd07c95385ffab0cacbc86", "password", crackedAt))
+
+	for _, tc := range []struct {
+		task     storage.Task
+		expected string
+	}{
+		{ntlm, "$NT$" + ntlmPassword + ":password\n"},
+		{md5, "$dynamic_0$" + md5Password + ":$HEX[70c3a4737300]\n"},
+		{sha1, "$dynamic_26$5baa61e4c9b93f3f0682250b6cf8331b7ee68fd8:password\n"},
+		// modes john has no tag for are written as is
+		{sha512, "b109f3bbbc244eb82441917ed06d618b9008dd09b3befd1b5e07394c706a8bb980b1d7785e5976ec049b46df5f1326af5a2ea6d103fd07c95385ffab0cacbc86:password\n"},
+	} {
+		w := exportPasswords(s, user, "/task/"+tc.task.TaskID+"/passwords/export?format=john")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, tc.expected, w.Body.String())
+	}
+}
+
+func TestExportTaskPasswordsLeft(t *testing.T) {
+	s, f, closer := newTestServer(t, "bdb")
+	defer closer()
+
+	user := f.CreateUser(t, false)
+	task := newExportTask(t, f, user, 0, "", md5Password, md5Letmein, md5Summer)
+
+	// cracked hashes are removed regardless of their case
+	assert.Nil(t, s.stor.SaveCrackedHash(task.TaskID, strings.ToUpper(md5Letmein), "letmein", time.Now().UTC()))
+
+	w := exportPasswords(s, user, "/task/"+task.TaskID+"/passwords/export?format=left")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `attachment; filename="`+task.TaskID+`.left"`, w.Header().Get("Content-Disposition"))
+	assert.Equal(t, md5Password+"\n"+md5Summer+"\n", w.Body.String())
+}
+
+func TestExportCasePasswords(t *testing.T) {
+	for _, backend := range servertest.Backends {
+		t.Run(backend, func(t *testing.T) {
+			testExportCasePasswords(t, backend)
+		})
+	}
+}
+
+func testExportCasePasswords(t *testing.T, backend string) {
+	s, f, closer := newTestServer(t, backend)
+	defer closer()
+
+	user := f.CreateUser(t, false)
+	other := f.CreateUser(t, false)
+	admin := f.CreateUser(t, true)
+
+	first := newExportTask(t, f, user, 1000, "IR-1", ntlmPassword, ntlmEmpty)
+	second := newExportTask(t, f, user, 0, "IR-1", md5Letmein, md5Password)
+	others := newExportTask(t, f, other, 0, "IR-1", md5Summer)
+	unrelated := newExportTask(t, f, user, 0, "IR-2", md5Summer)
+
+	crackedAt := time.Now().UTC()
+	assert.Nil(t, s.stor.SaveCrackedHash(first.TaskID, ntlmPassword, "password", crackedAt))
+	assert.Nil(t, s.stor.SaveCrackedHash(second.TaskID, md5Letmein, "letmein", crackedAt))
+	assert.Nil(t, s.stor.SaveCrackedHash(others.TaskID, md5Summer, "summer", crackedAt))
+	assert.Nil(t, s.stor.SaveCrackedHash(unrelated.TaskID, md5Summer, "summer", crackedAt))
+
+	lines := func(w *httptest.ResponseRecorder) []string {
+		return strings.Split(strings.TrimSuffix(w.Body.String(), "\n"), "\n")
+	}
+
+	// only the tasks of the case the user is entitled to are exported
+	w := exportPasswords(s, user, "/cases/IR-1/passwords/export?format=potfile")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `attachment; filename="IR-1.pot"`, w.Header().Get("Content-Disposition"))
+	assert.ElementsMatch(t, []string{ntlmPassword + ":password", md5Letmein + ":letmein"}, lines(w))
+
+	w = exportPasswords(s, other, "/cases/IR-1/passwords/export?format=potfile")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{md5Summer + ":summer"}, lines(w))
+
+	w = exportPasswords(s, admin, "/cases/IR-1/passwords/export?format=potfile")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.ElementsMatch(t, []string{ntlmPassword + ":password", md5Letmein + ":letmein", md5Summer + ":summer"}, lines(w))
+
+	// each task's hashes are tagged with its own hash type
+	w = exportPasswords(s, user, "/cases/IR-1/passwords/export?format=john")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.ElementsMatch(t, []string{"$NT$" + ntlmPassword + ":password", "$dynamic_0$" + md5Letmein + ":letmein"}, lines(w))
+
+	w = exportPasswords(s, user, "/cases/IR-1/passwords/export?format=left")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `attachment; filename="IR-1.left"`, w.Header().Get("Content-Disposition"))
+	assert.ElementsMatch(t, []string{ntlmEmpty, md5Password}, lines(w))
+
+	// a view of the passwords is logged against every exported task
+	entries, err := s.stor.GetActivityLog(second.TaskID)
+	if assert.Nil(t, err) && assert.Len(t, entries, 4) {
+		assert.Equal(t, storage.ActivityViewPasswords, entries[0].Type)
+	}
+
+	w = exportPasswords(s, other, "/cases/IR-2/passwords/export")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -2,6 +2,8 @@ package web
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/mandiant/gocrack/server/storage"
 	"github.com/mandiant/gocrack/shared"
@@ -17,6 +19,22 @@ func setEngineFile(stor storage.Backend, engineFileID string) *EngineFileItem {
 	return &sef
 }
 
+// taskFileAccounts returns the usernames of the accounts within a task file keyed by the lower cased hash of their password
+// so cracked hashes can be joined back to every account that shares them
+func (s *Server) taskFileAccounts(fileID string) (map[string][]string, error) {
+	accounts, err := s.stor.GetTaskFileAccounts(fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[string][]string)
+	for _, account := range accounts {
+		hash := strings.ToLower(account.Hash)
+		out[hash] = append(out[hash], account.Username)
+	}
+	return out, nil
+}
+
 func convertStorageTaskToItem(stor storage.Backend, t storage.Task) TaskInfoResponseItem {
 	item := TaskInfoResponseItem{
 		TaskID:            t.TaskID,
@@ -34,36 +52,117 @@ func convertStorageTaskToItem(stor storage.Backend, t storage.Task) TaskInfoResp
 		FileID:            t.FileID,
 		Priority:          TaskPriorityFancy(t.Priority),
 		Error:             t.Error,
+		DisableHashReuse:  t.DisableHashReuse,
+		Runtime:           t.RuntimeAt(time.Now().UTC()),
+	}
+
+	if !t.Schedule.IsEmpty() {
+		item.Schedule = &t.Schedule
+	}
+
+	if t.WorkUnitCount > 0 {
+		item.Progress = &t.Progress
+		if units, err := stor.GetWorkUnits(t.TaskID); err == nil {
+			summary := storage.SummarizeWorkUnits(t.Keyspace, units)
+			item.WorkUnits = &summary
+		}
 	}
+
 	switch ep := t.EnginePayload.(type) {
 	case shared.HashcatUserOptions:
-		hcitem := HashcatEnginePayload{}
+		hcitem := HashcatEnginePayload{
+			AttackMode: ep.AttackMode.String(),
+			RuleLeft:   ep.RuleLeft,
+			RuleRight:  ep.RuleRight,
+			Tuning:     ep.Tuning,
+		}
+
+		if ep.Tuning != nil && ep.Tuning.OptimizedKernels != nil {
+			hcitem.DisableOptimizedEngine = !*ep.Tuning.OptimizedKernels
+		}
+
+		// every attack mode other than brute force is run with a dictionary
+		if ep.DictionaryFile != nil && ep.AttackMode != shared.AttackModeBruteForce {
+			hcitem.DictionaryFile = setEngineFile(stor, *ep.DictionaryFile)
+		}
+
+		if ep.RightDictionaryFile != nil && ep.AttackMode == shared.AttackModeCombinator {
+			hcitem.RightDictionaryFile = setEngineFile(stor, *ep.RightDictionaryFile)
+		}
 
 		switch ep.AttackMode {
-		case shared.AttackModeBruteForce:
-			hcitem.AttackMode = "Brute Force"
+		case shared.AttackModeBruteForce, shared.AttackModeHybridDictMask, shared.AttackModeHybridMaskDict:
 			if ep.Masks != nil {
 				hcitem.Masks = setEngineFile(stor, *ep.Masks)
 			}
-		case shared.AttackModeStraight:
-			hcitem.AttackMode = "Straight"
-			if ep.DictionaryFile != nil {
-				hcitem.DictionaryFile = setEngineFile(stor, *ep.DictionaryFile)
-			}
 
+			hcitem.InlineMasks = ep.InlineMasks
+			hcitem.Increment = ep.Increment
+			hcitem.IncrementMin = ep.IncrementMin
+			hcitem.IncrementMax = ep.IncrementMax
+			for _, charset := range ep.CustomCharsets {
+				cs := CustomCharset{Charset: charset.Charset}
+				if charset.FileID != "" {
+					cs.File = setEngineFile(stor, charset.FileID)
+				}
+				hcitem.CustomCharsets = append(hcitem.CustomCharsets, cs)
+			}
+		case shared.AttackModeStraight, shared.AttackModeAssociation:
 			if ep.ManglingRuleFile != nil {
 				hcitem.ManglingRuleFile = setEngineFile(stor, *ep.ManglingRuleFile)
 			}
-		default:
-			hcitem.AttackMode = "Unknown"
 		}
 
 		// TODO: Due to some duplicate hash type IDs, we need to come back to this
 		hcitem.HashType = fmt.Sprintf("Type %d", ep.HashType)
 
 		item.EnginePayload = hcitem
+	case shared.CPUUserOptions:
+		cpuitem := CPUEnginePayload{
+			HashType: fmt.Sprintf("Type %d", ep.HashType),
+		}
+
+		switch ep.AttackMode {
+		case shared.AttackModeBruteForce:
+			cpuitem.AttackMode = "Brute Force"
+			if ep.Masks != nil {
+				cpuitem.Masks = setEngineFile(stor, *ep.Masks)
+			}
+		case shared.AttackModeStraight:
+			cpuitem.AttackMode = "Straight"
+			if ep.DictionaryFile != nil {
+				cpuitem.DictionaryFile = setEngineFile(stor, *ep.DictionaryFile)
+			}
+
+			if ep.ManglingRuleFile != nil {
+				cpuitem.ManglingRuleFile = setEngineFile(stor, *ep.ManglingRuleFile)
+			}
+		default:
+			cpuitem.AttackMode = "Unknown"
+		}
+
+		item.EnginePayload = cpuitem
 	default:
 		item.EnginePayload = ep
 	}
 	return item
 }
+
+// changeWorkUnitsStatus prepares the units of a distributed task for a status change requested by a user. Units that did not
+// complete are handed out again when the task is resumed and a task without any running units is stopped immediately
+func (s *Server) changeWorkUnitsStatus(task *storage.Task, newStatus *storage.TaskStatus) error {
+	switch *newStatus {
+	case storage.TaskStatusQueued:
+		return s.stor.RequeueWorkUnits(task.TaskID)
+	case storage.TaskStatusStopping:
+		units, err := s.stor.GetWorkUnits(task.TaskID)
+		if err != nil {
+			return err
+		}
+
+		if storage.SummarizeWorkUnits(task.Keyspace, units).Active == 0 {
+			*newStatus = storage.TaskStatusStopped
+		}
+	}
+	return nil
+}
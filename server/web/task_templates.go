@@ -0,0 +1,426 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mandiant/gocrack/server/storage"
+	"github.com/mandiant/gocrack/shared"
+	"github.com/mandiant/gocrack/shared/enginedef"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// TaskTemplateRequest creates or replaces a task template. Its fields are the ones of CreateTaskRequest that do not
+// depend on the task file
+type TaskTemplateRequest struct {
+	Name              string                    `json:"name"`
+	Description       *string                   `json:"description,omitempty"`
+	Engine            storage.WorkerCrackEngine `json:"engine"`
+	EnginePayload     json.RawMessage           `json:"payload"` // The structure of EnginePayload differs based on Engine
+	TaskDuration      int                       `json:"task_duration"`
+	Priority          *storage.WorkerPriority   `json:"priority,omitempty"`
+	WorkUnits         int                       `json:"work_units,omitempty"`
+	AssignedToHost    *string                   `json:"assigned_host,omitempty"`
+	AssignedToDevices *storage.CLDevices        `json:"assigned_devices,omitempty"`
+	IsShared          bool                      `json:"is_shared"`
+	// AdditionalUsers are entitled to the template along with its creator
+	AdditionalUsers []string `json:"additional_users,omitempty"`
+}
+
+// TaskTemplateItem describes a task template and should mimic storage.TaskTemplate
+type TaskTemplateItem struct {
+	TemplateID        string                    `json:"template_id"`
+	Name              string                    `json:"name"`
+	Description       *string                   `json:"description,omitempty"`
+	Engine            storage.WorkerCrackEngine `json:"engine"`
+	EnginePayload     json.RawMessage           `json:"payload"`
+	TaskDuration      int                       `json:"task_duration"`
+	Priority          storage.WorkerPriority    `json:"priority"`
+	WorkUnits         int                       `json:"work_units"`
+	AssignedToHost    string                    `json:"assigned_host,omitempty"`
+	AssignedToDevices *storage.CLDevices        `json:"assigned_devices,omitempty"`
+	IsShared          bool                      `json:"is_shared"`
+	CreatedByUUID     string                    `json:"created_by_uuid"`
+	CreatedAt         time.Time                 `json:"created_at"`
+	LastUpdatedAt     time.Time                 `json:"last_updated_at"`
+}
+
+// CreateTaskFromTemplateRequest creates a task of the template against a task file
+type CreateTaskFromTemplateRequest struct {
+	TaskName        string    `json:"task_name,omitempty"` // TaskName defaults to the name of the template
+	FileID          string    `json:"file_id"`
+	CaseCode        *string   `json:"case_code,omitempty"`
+	Comment         *string   `json:"comment,omitempty"`
+	AdditionalUsers *[]string `json:"additional_users,omitempty"`
+}
+
+// CloneTaskRequest creates a task with the configuration of an existing one. Every field is optional
+type CloneTaskRequest struct {
+	TaskName        string    `json:"task_name,omitempty"` // TaskName defaults to the name of the task followed by (clone)
+	FileID          string    `json:"file_id,omitempty"`   // FileID defaults to the task file of the task
+	CaseCode        *string   `json:"case_code,omitempty"` // CaseCode & Comment default to the ones of the task
+	Comment         *string   `json:"comment,omitempty"`
+	AdditionalUsers *[]string `json:"additional_users,omitempty"`
+}
+
+func (s TaskTemplateRequest) validate() []string {
+	errs := make([]string, 0)
+
+	if strings.TrimSpace(s.Name) == "" {
+		errs = append(errs, "name must not be empty")
+	}
+
+	if engineErrs := validateEngineOptions(s.Engine, s.WorkUnits, s.AssignedToHost); len(engineErrs) > 0 {
+		return append(errs, engineErrs...)
+	}
+
+	// the payload is validated again against the task file of every task created from the template
+	payload, err := enginedef.DecodePayload(s.Engine, s.EnginePayload)
+	if err != nil {
+		return append(errs, fmt.Sprintf("payload is not valid: %s", err))
+	}
+	return append(errs, payload.Validate()...)
+}
+
+func convTaskTemplateItem(tmpl storage.TaskTemplate) TaskTemplateItem {
+	return TaskTemplateItem{
+		TemplateID:        tmpl.TemplateID,
+		Name:              tmpl.Name,
+		Description:       tmpl.Description,
+		Engine:            tmpl.Engine,
+		EnginePayload:     tmpl.EnginePayload,
+		TaskDuration:      tmpl.TaskDuration,
+		Priority:          tmpl.Priority,
+		WorkUnits:         tmpl.WorkUnits,
+		AssignedToHost:    tmpl.AssignedToHost,
+		AssignedToDevices: tmpl.AssignedToDevices,
+		IsShared:          tmpl.IsShared,
+		CreatedByUUID:     tmpl.CreatedByUUID,
+		CreatedAt:         tmpl.CreatedAt,
+		LastUpdatedAt:     tmpl.LastUpdatedAt,
+	}
+}
+
+// taskTemplateRequest returns the task creation request of a task of the template
+func taskTemplateRequest(tmpl storage.TaskTemplate, req CreateTaskFromTemplateRequest) CreateTaskRequest {
+	request := CreateTaskRequest{
+		TaskName:          req.TaskName,
+		Engine:            tmpl.Engine,
+		FileID:            req.FileID,
+		CaseCode:          req.CaseCode,
+		Comment:           req.Comment,
+		EnginePayload:     tmpl.EnginePayload,
+		TaskDuration:      tmpl.TaskDuration,
+		Priority:          &tmpl.Priority,
+		AdditionalUsers:   req.AdditionalUsers,
+		WorkUnits:         tmpl.WorkUnits,
+		AssignedToDevices: tmpl.AssignedToDevices,
+	}
+
+	if request.TaskName == "" {
+		request.TaskName = tmpl.Name
+	}
+
+	if tmpl.AssignedToHost != "" {
+		request.AssignedToHost = &tmpl.AssignedToHost
+	}
+	return request
+}
+
+// cloneTaskRequest returns the task creation request of a copy of the task
+func cloneTaskRequest(task storage.Task, req CloneTaskRequest) (CreateTaskRequest, error) {
+	payload, err := taskRequestPayload(task)
+	if err != nil {
+		return CreateTaskRequest{}, err
+	}
+
+	request := CreateTaskRequest{
+		TaskName:          req.TaskName,
+		Engine:            task.Engine,
+		FileID:            req.FileID,
+		CaseCode:          task.CaseCode,
+		Comment:           task.Comment,
+		EnginePayload:     payload,
+		TaskDuration:      task.TaskDuration,
+		Priority:          &task.Priority,
+		AdditionalUsers:   req.AdditionalUsers,
+		WorkUnits:         task.WorkUnitCount,
+		AssignedToDevices: task.AssignedToDevices,
+		DisableHashReuse:  task.DisableHashReuse,
+		keepOutOfIndex:    task.DisableHashReuse,
+	}
+
+	if request.TaskName == "" {
+		request.TaskName = task.TaskName + " (clone)"
+	}
+
+	if request.FileID == "" {
+		request.FileID = task.FileID
+	}
+
+	if req.CaseCode != nil {
+		request.CaseCode = req.CaseCode
+	}
+
+	if req.Comment != nil {
+		request.Comment = req.Comment
+	}
+
+	if task.AssignedToHost != "" {
+		request.AssignedToHost = &task.AssignedToHost
+	}
+
+	if !task.Schedule.IsEmpty() {
+		request.Schedule = &task.Schedule
+	}
+	return request, nil
+}
+
+// taskRequestPayload returns the engine payload of the task as it would have been requested. The username option that
+// hashcat tasks get from their task file is removed so it's set according to the task file of the new task
+func taskRequestPayload(task storage.Task) (json.RawMessage, error) {
+	payload := task.EnginePayload
+	if opts, ok := payload.(shared.HashcatUserOptions); ok && opts.Tuning != nil && opts.Tuning.Username {
+		tuning := *opts.Tuning
+		tuning.Username = false
+		opts.Tuning = &tuning
+		if tuning == (shared.HashcatTuningOptions{}) {
+			opts.Tuning = nil
+		}
+		payload = opts
+	}
+	return json.Marshal(payload)
+}
+
+func (s *Server) webCreateTaskTemplate(c *gin.Context) *WebAPIError {
+	var req TaskTemplateRequest
+
+	if err := c.BindJSON(&req); err != nil {
+		return &WebAPIError{
+			StatusCode: http.StatusBadRequest,
+			Err:        err,
+			UserError:  "The request was not valid",
+		}
+	}
+
+	now := time.Now().UTC()
+	tmpl := storage.TaskTemplate{
+		TemplateID:    uuid.NewString(),
+		CreatedByUUID: getClaimInformation(c).UserUUID,
+		CreatedAt:     now,
+	}
+	req.AdditionalUsers = append(req.AdditionalUsers, tmpl.CreatedByUUID)
+	return s.saveTaskTemplate(c, req, tmpl, http.StatusCreated)
+}
+
+func (s *Server) webUpdateTaskTemplate(c *gin.Context) *WebAPIError {
+	var req TaskTemplateRequest
+
+	tmpl, apiErr := s.getModifiableTaskTemplate(c)
+	if apiErr != nil {
+		return apiErr
+	}
+
+	if err := c.BindJSON(&req); err != nil {
+		return &WebAPIError{
+			StatusCode: http.StatusBadRequest,
+			Err:        err,
+			UserError:  "The request was not valid",
+		}
+	}
+	return s.saveTaskTemplate(c, req, *tmpl, http.StatusOK)
+}
+
+// saveTaskTemplate replaces the configuration of the template with the one in the request, saves it and entitles the
+// additional users to it
+func (s *Server) saveTaskTemplate(c *gin.Context, req TaskTemplateRequest, tmpl storage.TaskTemplate, status int) *WebAPIError {
+	if errs := req.validate(); len(errs) > 0 {
+		c.JSON(http.StatusBadRequest, APIValidationErrors{
+			Valid:  false,
+			Errors: errs,
+		})
+		return nil
+	}
+
+	tmpl.Name = strings.TrimSpace(req.Name)
+	tmpl.Description = req.Description
+	tmpl.Engine = req.Engine
+	tmpl.EnginePayload = req.EnginePayload
+	tmpl.TaskDuration = req.TaskDuration
+	tmpl.Priority = storage.WorkerPriorityNormal
+	tmpl.WorkUnits = req.WorkUnits
+	tmpl.AssignedToHost = ""
+	tmpl.AssignedToDevices = nil
+	tmpl.IsShared = req.IsShared
+	tmpl.LastUpdatedAt = time.Now().UTC()
+
+	if req.Priority != nil {
+		tmpl.Priority = *req.Priority
+	}
+
+	// Set the device affinity the same way a task does
+	if req.AssignedToHost != nil && req.AssignedToDevices != nil {
+		tmpl.AssignedToHost = *req.AssignedToHost
+		tmpl.AssignedToDevices = req.AssignedToDevices
+	}
+
+	if err := s.stor.SaveTaskTemplate(tmpl); err != nil {
+		return internalServerError(err)
+	}
+
+	for _, userUUID := range req.AdditionalUsers {
+		if err := s.stor.GrantEntitlement(storage.User{UserUUID: userUUID}, tmpl); err != nil {
+			return internalServerError(err)
+		}
+	}
+
+	c.JSON(status, convTaskTemplateItem(tmpl))
+	return nil
+}
+
+func (s *Server) webGetTaskTemplates(c *gin.Context) *WebAPIError {
+	claim := getClaimInformation(c)
+
+	templates, err := s.stor.GetTaskTemplatesForUser(storage.User{UserUUID: claim.UserUUID, IsSuperUser: claim.IsAdmin})
+	if err != nil {
+		return internalServerError(err)
+	}
+
+	resp := make([]TaskTemplateItem, len(templates))
+	for i, tmpl := range templates {
+		resp[i] = convTaskTemplateItem(tmpl)
+	}
+
+	c.JSON(http.StatusOK, resp)
+	return nil
+}
+
+func (s *Server) webGetTaskTemplate(c *gin.Context) *WebAPIError {
+	tmpl, apiErr := s.getTaskTemplate(c)
+	if apiErr != nil {
+		return apiErr
+	}
+
+	c.JSON(http.StatusOK, convTaskTemplateItem(*tmpl))
+	return nil
+}
+
+func (s *Server) webDeleteTaskTemplate(c *gin.Context) *WebAPIError {
+	tmpl, apiErr := s.getModifiableTaskTemplate(c)
+	if apiErr != nil {
+		return apiErr
+	}
+
+	if err := s.stor.DeleteTaskTemplate(tmpl.TemplateID); err != nil && err != storage.ErrNotFound {
+		return internalServerError(err)
+	}
+
+	c.Status(http.StatusNoContent)
+	return nil
+}
+
+// webCreateTaskFromTemplate creates a task of the template against the task file in the request
+func (s *Server) webCreateTaskFromTemplate(c *gin.Context) *WebAPIError {
+	var req CreateTaskFromTemplateRequest
+
+	tmpl, apiErr := s.getTaskTemplate(c)
+	if apiErr != nil {
+		return apiErr
+	}
+
+	if err := c.BindJSON(&req); err != nil {
+		return &WebAPIError{
+			StatusCode: http.StatusBadRequest,
+			Err:        err,
+			UserError:  "The request was not valid",
+		}
+	}
+	return s.createTask(c, taskTemplateRequest(*tmpl, req))
+}
+
+// webCloneTask creates a task with the configuration of the one in the route against its task file or the one in the request
+func (s *Server) webCloneTask(c *gin.Context) *WebAPIError {
+	var req CloneTaskRequest
+
+	task, err := s.stor.GetTaskByID(c.Param("taskid"))
+	if err != nil {
+		if err == storage.ErrNotFound {
+			return &WebAPIError{
+				StatusCode: http.StatusNotFound,
+				Err:        err,
+				UserError:  "The requested file does not exist or you do not have permissions to it",
+			}
+		}
+		return internalServerError(err)
+	}
+
+	// the request is optional as the clone defaults to the configuration of the task
+	if c.Request.ContentLength != 0 {
+		if err = c.BindJSON(&req); err != nil {
+			return &WebAPIError{
+				StatusCode: http.StatusBadRequest,
+				Err:        err,
+				UserError:  "The request was not valid",
+			}
+		}
+	}
+
+	request, err := cloneTaskRequest(*task, req)
+	if err != nil {
+		return internalServerError(err)
+	}
+	return s.createTask(c, request)
+}
+
+// getTaskTemplate returns the template in the route if it's shared, the user is entitled to it or is an administrator
+func (s *Server) getTaskTemplate(c *gin.Context) (*storage.TaskTemplate, *WebAPIError) {
+	notFound := &WebAPIError{
+		StatusCode: http.StatusNotFound,
+		UserError:  "The requested task template does not exist or you do not have permissions to it",
+	}
+
+	tmpl, err := s.stor.GetTaskTemplate(c.Param("templateid"))
+	if err != nil {
+		if err == storage.ErrNotFound {
+			return nil, notFound
+		}
+		return nil, internalServerError(err)
+	}
+
+	claim := getClaimInformation(c)
+	if claim.IsAdmin || tmpl.IsShared {
+		return tmpl, nil
+	}
+
+	entitled, err := s.stor.CheckEntitlement(claim.UserUUID, tmpl.TemplateID, storage.EntitlementTaskTemplate)
+	if err != nil && err != storage.ErrNotFound {
+		return nil, internalServerError(err)
+	}
+
+	if !entitled {
+		return nil, notFound
+	}
+	return tmpl, nil
+}
+
+// getModifiableTaskTemplate returns the template in the route if the user created it or is an administrator
+func (s *Server) getModifiableTaskTemplate(c *gin.Context) (*storage.TaskTemplate, *WebAPIError) {
+	tmpl, apiErr := s.getTaskTemplate(c)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	claim := getClaimInformation(c)
+	if !claim.IsAdmin && tmpl.CreatedByUUID != claim.UserUUID {
+		return nil, &WebAPIError{
+			StatusCode: http.StatusUnauthorized,
+			UserError:  "Only the creator of a task template or an administrator can modify it",
+		}
+	}
+	return tmpl, nil
+}
@@ -0,0 +1,88 @@
+package web
+
+import (
+	"testing"
+
+	"github.com/mandiant/gocrack/server/storage"
+	"github.com/mandiant/gocrack/shared"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCloneTaskRequest(t *testing.T) {
+	task := storage.Task{
+		TaskID:   "ed40ee34-aaa7-4a4d-a5c1-7fb0c1d5ee2c",
+		TaskName: "ntlm dump",
+		FileID:   "9c8b3c43-2f4b-4ba5-a4c5-cbac8c8b4dc1",
+		CaseCode: shared.GetStrPtr("CASE-1"),
+		Engine:   storage.WorkerHashcatEngine,
+		EnginePayload: shared.HashcatUserOptions{
+			HashType:   1000,
+			AttackMode: shared.AttackModeBruteForce,
+			Masks:      shared.GetStrPtr("?a?a?a?a"),
+			Tuning:     &shared.HashcatTuningOptions{Username: true},
+		},
+		Priority:       storage.WorkerPriorityHigh,
+		TaskDuration:   3600,
+		AssignedToHost: "cracker-1",
+		Schedule: storage.TaskSchedule{
+			Windows: []storage.ExecutionWindow{{Start: "20:00", End: "06:00"}},
+		},
+	}
+
+	request, err := cloneTaskRequest(task, CloneTaskRequest{})
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, "ntlm dump (clone)", request.TaskName)
+	assert.Equal(t, task.FileID, request.FileID)
+	assert.Equal(t, "CASE-1", *request.CaseCode)
+	assert.Equal(t, storage.WorkerPriorityHigh, *request.Priority)
+	assert.Equal(t, 3600, request.TaskDuration)
+	assert.Equal(t, "cracker-1", *request.AssignedToHost)
+	if assert.NotNil(t, request.Schedule) {
+		assert.Equal(t, task.Schedule.Windows, request.Schedule.Windows)
+	}
+	// the username option is set again from the task file of the clone
+	assert.NotContains(t, string(request.EnginePayload), "tuning")
+	assert.False(t, request.hashReuseDisabled(true))
+
+	// a clone of a task kept out of the known hash index stays out of it even if a user clones it
+	task.DisableHashReuse = true
+	request, err = cloneTaskRequest(task, CloneTaskRequest{})
+	if assert.Nil(t, err) {
+		assert.True(t, request.hashReuseDisabled(false))
+		assert.True(t, request.hashReuseDisabled(true))
+	}
+
+	// only admins can keep a new task out of the index
+	assert.False(t, CreateTaskRequest{DisableHashReuse: true}.hashReuseDisabled(false))
+	assert.True(t, CreateTaskRequest{DisableHashReuse: true}.hashReuseDisabled(true))
+
+	request, err = cloneTaskRequest(task, CloneTaskRequest{
+		TaskName: "ntlm dump again",
+		FileID:   "4f3a1b0e-2d7c-4e44-9b8f-2e8a7e1c9f10",
+		CaseCode: shared.GetStrPtr("CASE-2"),
+	})
+	if assert.Nil(t, err) {
+		assert.Equal(t, "ntlm dump again", request.TaskName)
+		assert.Equal(t, "4f3a1b0e-2d7c-4e44-9b8f-2e8a7e1c9f10", request.FileID)
+		assert.Equal(t, "CASE-2", *request.CaseCode)
+	}
+}
+
+func TestTaskRequestPayloadKeepsTuning(t *testing.T) {
+	workload := 3
+	payload, err := taskRequestPayload(storage.Task{
+		Engine: storage.WorkerHashcatEngine,
+		EnginePayload: shared.HashcatUserOptions{
+			HashType:   0,
+			AttackMode: shared.AttackModeBruteForce,
+			Masks:      shared.GetStrPtr("?d?d?d?d"),
+			Tuning:     &shared.HashcatTuningOptions{WorkloadProfile: &workload, Username: true},
+		},
+	})
+	if assert.Nil(t, err) {
+		assert.Contains(t, string(payload), `"tuning":{"workload_profile":3}`)
+	}
+}
@@ -0,0 +1,339 @@
+// Package wordlist builds dictionary engine files out of the passwords cracked by tasks and keeps them up to date as
+// the tasks crack more of them
+package wordlist
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mandiant/gocrack/server/filemanager"
+	"github.com/mandiant/gocrack/server/storage"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ErrNoPasswords is returned when the tasks of a new wordlist have not cracked any passwords
+var ErrNoPasswords = errors.New("wordlist: the tasks have not cracked any passwords")
+
+// Builder writes the dictionaries of cracked wordlists through the file manager
+type Builder struct {
+	stor storage.Backend
+	fm   *filemanager.Context
+}
+
+// NewBuilder returns a Builder that saves wordlists within the file manager
+func NewBuilder(stor storage.Backend, fm *filemanager.Context) *Builder {
+	return &Builder{stor: stor, fm: fm}
+}
+
+// Tasks returns the tasks the wordlist is built from. The tasks of a case are limited to the ones the creator of the
+// wordlist is entitled to so the wordlist can't leak the passwords of other tasks
+func (b *Builder) Tasks(wl storage.CrackedWordlist) ([]storage.Task, error) {
+	if wl.CaseCode != "" {
+		user, err := b.stor.GetUserByID(wl.CreatedByUUID)
+		if err != nil {
+			return nil, err
+		}
+		return b.stor.GetTasksByCaseCode(wl.CaseCode, *user)
+	}
+
+	tasks := make([]storage.Task, 0, len(wl.TaskIDs))
+	for _, taskID := range wl.TaskIDs {
+		task, err := b.stor.GetTaskByID(taskID)
+		if err != nil {
+			if err == storage.ErrNotFound {
+				// the task was deleted after the wordlist was built
+				continue
+			}
+			return nil, err
+		}
+		tasks = append(tasks, *task)
+	}
+	return tasks, nil
+}
+
+// Create builds the wordlist into a new dictionary engine file and entitles its creator & the users entitled to every
+// one of its tasks to it. The engine file is never shared
+func (b *Builder) Create(ef storage.EngineFile, wl storage.CrackedWordlist) (*storage.EngineFile, error) {
+	tasks, err := b.Tasks(wl)
+	if err != nil {
+		return nil, err
+	}
+
+	contents, n, err := b.dictionary(tasks)
+	if err != nil {
+		return nil, err
+	}
+
+	if n == 0 {
+		return nil, ErrNoPasswords
+	}
+
+	members, _, err := b.members(wl, tasks)
+	if err != nil {
+		return nil, err
+	}
+
+	ef.FileID = wl.FileID
+	ef.FileType = storage.EngineFileDictionary
+	ef.IsShared = false
+	if err = b.save(&ef, contents, n); err != nil {
+		return nil, err
+	}
+
+	txn, err := b.stor.NewEngineFileTransaction()
+	if err != nil {
+		return nil, err
+	}
+	defer txn.Rollback() // wont be rolled back if the commit occurs
+
+	if err = txn.SaveEngineFile(ef); err != nil {
+		return nil, err
+	}
+
+	for _, userUUID := range members {
+		if err = txn.AddEntitlement(ef, userUUID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = txn.Commit(); err != nil {
+		return nil, err
+	}
+
+	wl.LastBuiltAt = ef.LastUpdatedAt
+	if err = b.stor.SaveCrackedWordlist(wl); err != nil {
+		return nil, err
+	}
+	return &ef, nil
+}
+
+// Rebuild rewrites the dictionary of an existing wordlist with every password its tasks have cracked. Users that have
+// been entitled to all of its tasks since the last build are entitled to it as well while users that are no longer
+// entitled to all of them, such as when a task is added to its case, lose access
+func (b *Builder) Rebuild(wl storage.CrackedWordlist) error {
+	ef, err := b.stor.GetEngineFileByID(wl.FileID)
+	if err != nil {
+		return err
+	}
+
+	tasks, err := b.Tasks(wl)
+	if err != nil {
+		return err
+	}
+
+	contents, n, err := b.dictionary(tasks)
+	if err != nil {
+		return err
+	}
+
+	members, outsiders, err := b.members(wl, tasks)
+	if err != nil {
+		return err
+	}
+
+	if err = b.save(ef, contents, n); err != nil {
+		return err
+	}
+
+	if err = b.stor.UpdateEngineFile(*ef); err != nil {
+		return err
+	}
+
+	for _, userUUID := range members {
+		if err = b.stor.GrantEntitlement(storage.User{UserUUID: userUUID}, *ef); err != nil {
+			return err
+		}
+	}
+
+	for _, userUUID := range outsiders {
+		if err = b.stor.RevokeEntitlement(storage.User{UserUUID: userUUID}, *ef); err != nil && err != storage.ErrNotFound {
+			return err
+		}
+	}
+
+	wl.LastBuiltAt = ef.LastUpdatedAt
+	return b.stor.SaveCrackedWordlist(wl)
+}
+
+// save writes the n passwords of the dictionary to the engine file & updates its metadata
+func (b *Builder) save(ef *storage.EngineFile, contents io.Reader, n int) error {
+	fresp, err := b.fm.SaveFile(io.NopCloser(contents), ef.FileName, ef.FileID, storage.EngineFileDictionary)
+	if err != nil {
+		return err
+	}
+
+	if fresp == nil {
+		return errors.New("wordlist: failed to write the dictionary")
+	}
+
+	ef.FileSize = fresp.Size
+	ef.NumberOfEntries = int64(n)
+	ef.SavedAt = fresp.SavedTo
+	ef.SHA1Hash = fresp.SHA1
+	ef.LastUpdatedAt = time.Now().UTC()
+	return nil
+}
+
+// dictionary returns the distinct passwords cracked by the tasks one per line, the most commonly cracked first
+func (b *Builder) dictionary(tasks []storage.Task) (io.Reader, int, error) {
+	counts := make(map[string]int)
+	for _, task := range tasks {
+		cracked, err := b.stor.GetCrackedPasswords(task.TaskID)
+		if err != nil {
+			if err == storage.ErrNotFound {
+				continue
+			}
+			return nil, 0, err
+		}
+
+		for _, pass := range *cracked {
+			// a password that spans lines can't be written to a dictionary
+			if pass.Value == "" || strings.ContainsAny(pass.Value, "\r\n") {
+				continue
+			}
+			counts[pass.Value]++
+		}
+	}
+
+	values := make([]string, 0, len(counts))
+	for value := range counts {
+		values = append(values, value)
+	}
+
+	sort.Slice(values, func(i, j int) bool {
+		if counts[values[i]] != counts[values[j]] {
+			return counts[values[i]] > counts[values[j]]
+		}
+		return values[i] < values[j]
+	})
+
+	var buf bytes.Buffer
+	for _, value := range values {
+		buf.WriteString(value + "\n")
+	}
+	return &buf, len(values), nil
+}
+
+// members returns the creator of the wordlist and the users entitled to every one of its tasks. The users entitled to
+// only some of the tasks are returned as outsiders as the wordlist would give them the passwords of the other tasks
+func (b *Builder) members(wl storage.CrackedWordlist, tasks []storage.Task) (members, outsiders []string, err error) {
+	var users []string
+	counts := make(map[string]int)
+
+	for _, task := range tasks {
+		entitlements, err := b.stor.GetEntitlementsForTask(task.TaskID)
+		if err != nil && err != storage.ErrNotFound {
+			return nil, nil, err
+		}
+
+		for _, ent := range entitlements {
+			if counts[ent.UserUUID] == 0 {
+				users = append(users, ent.UserUUID)
+			}
+			counts[ent.UserUUID]++
+		}
+	}
+
+	members = []string{wl.CreatedByUUID}
+	for _, userUUID := range users {
+		switch {
+		case userUUID == wl.CreatedByUUID:
+		case counts[userUUID] == len(tasks):
+			members = append(members, userUUID)
+		default:
+			outsiders = append(outsiders, userUUID)
+		}
+	}
+	return members, outsiders, nil
+}
+
+// Updater rebuilds the auto updated wordlists whose tasks have cracked passwords. Cracks are batched until Flush is
+// called so a burst of passwords rewrites a wordlist once
+type Updater struct {
+	b       *Builder
+	mu      sync.Mutex
+	pending map[string]bool
+}
+
+// NewUpdater returns an Updater that rebuilds wordlists with the builder
+func NewUpdater(b *Builder) *Updater {
+	return &Updater{b: b, pending: make(map[string]bool)}
+}
+
+// Cracked records that the task has cracked a password
+func (u *Updater) Cracked(taskID string) {
+	u.mu.Lock()
+	u.pending[taskID] = true
+	u.mu.Unlock()
+}
+
+// Flush rebuilds the auto updated wordlists of the tasks that have cracked passwords since the last flush and returns
+// the number of wordlists that were rebuilt. A wordlist that fails to rebuild is logged and skipped
+func (u *Updater) Flush() (int, error) {
+	u.mu.Lock()
+	pending := u.pending
+	u.pending = make(map[string]bool)
+	u.mu.Unlock()
+
+	if len(pending) == 0 {
+		return 0, nil
+	}
+
+	wordlists, err := u.b.stor.GetAutoUpdatedWordlists()
+	if err != nil {
+		return 0, err
+	}
+
+	if len(wordlists) == 0 {
+		return 0, nil
+	}
+
+	caseCodes := make(map[string]bool)
+	for taskID := range pending {
+		task, err := u.b.stor.GetTaskByID(taskID)
+		if err != nil {
+			if err == storage.ErrNotFound {
+				continue
+			}
+			return 0, err
+		}
+
+		if task.CaseCode != nil && *task.CaseCode != "" {
+			caseCodes[*task.CaseCode] = true
+		}
+	}
+
+	rebuilt := 0
+	for _, wl := range wordlists {
+		if !affected(wl, pending, caseCodes) {
+			continue
+		}
+
+		if err := u.b.Rebuild(wl); err != nil {
+			log.Error().Err(err).Str("file_id", wl.FileID).Msg("Failed to rebuild the wordlist of cracked passwords")
+			continue
+		}
+		rebuilt++
+	}
+	return rebuilt, nil
+}
+
+// affected returns true if the wordlist is built from one of the tasks or cases
+func affected(wl storage.CrackedWordlist, taskIDs, caseCodes map[string]bool) bool {
+	if wl.CaseCode != "" {
+		return caseCodes[wl.CaseCode]
+	}
+
+	for _, taskID := range wl.TaskIDs {
+		if taskIDs[taskID] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,181 @@
+package wordlist
+
+import (
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/mandiant/gocrack/server/servertest"
+	"github.com/mandiant/gocrack/server/storage"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func newBuilder(t *testing.T) (*Builder, *servertest.Fixture, func()) {
+	f, closer := servertest.New(t, "bdb")
+	return NewBuilder(f.Stor, f.FM), f, closer
+}
+
+func createTask(t *testing.T, f *servertest.Fixture, creator storage.User, caseCode string) storage.Task {
+	task := storage.Task{TaskName: "wordlist task", CaseCode: &caseCode}
+	f.CreateTask(t, creator, &task)
+	return task
+}
+
+func readDictionary(t *testing.T, stor storage.Backend, fileID string) (*storage.EngineFile, string) {
+	ef, err := stor.GetEngineFileByID(fileID)
+	if err != nil {
+		assert.FailNow(t, "failed to get engine file", err.Error())
+	}
+
+	b, err := ioutil.ReadFile(ef.SavedAt)
+	if err != nil {
+		assert.FailNow(t, "failed to read dictionary", err.Error())
+	}
+	return ef, string(b)
+}
+
+func TestCreateAndUpdateCaseWordlist(t *testing.T) {
+	b, f, closer := newBuilder(t)
+	defer closer()
+
+	creator := f.CreateUser(t, false)
+	member := f.CreateUser(t, false)
+	partial := f.CreateUser(t, false)
+	outsider := f.CreateUser(t, false)
+
+	first := createTask(t, f, creator, "CASE-1")
+	second := createTask(t, f, creator, "CASE-1")
+	other := createTask(t, f, outsider, "CASE-2")
+	assert.Nil(t, b.stor.GrantEntitlement(member, first))
+	assert.Nil(t, b.stor.GrantEntitlement(member, second))
+	assert.Nil(t, b.stor.GrantEntitlement(partial, second))
+
+	now := time.Now().UTC()
+	assert.Nil(t, b.stor.SaveCrackedHash(first.TaskID, "hash1", "Summer2023!", now))
+	assert.Nil(t, b.stor.SaveCrackedHash(first.TaskID, "hash2", "password", now))
+	assert.Nil(t, b.stor.SaveCrackedHash(second.TaskID, "hash3", "password", now))
+	assert.Nil(t, b.stor.SaveCrackedHash(other.TaskID, "hash4", "outsider", now))
+
+	wl := storage.CrackedWordlist{
+		FileID:        uuid.NewString(),
+		CaseCode:      "CASE-1",
+		AutoUpdate:    true,
+		CreatedByUUID: creator.UserUUID,
+		CreatedAt:     now,
+	}
+	ef, err := b.Create(storage.EngineFile{FileName: "case.dict", UploadedByUUID: creator.UserUUID}, wl)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, storage.EngineFileDictionary, ef.FileType)
+	assert.False(t, ef.IsShared)
+
+	saved, contents := readDictionary(t, b.stor, wl.FileID)
+	assert.Equal(t, "password\nSummer2023!\n", contents)
+	assert.Equal(t, int64(2), saved.NumberOfEntries)
+
+	// a user entitled to only some of the tasks would be given the passwords of the others
+	for user, expected := range map[string]bool{creator.UserUUID: true, member.UserUUID: true, partial.UserUUID: false, outsider.UserUUID: false} {
+		entitled, err := b.stor.CheckEntitlement(user, wl.FileID, storage.EntitlementEngineFile)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, entitled, user)
+	}
+
+	// cracks of tasks outside of the case leave the wordlist alone
+	u := NewUpdater(b)
+	u.Cracked(other.TaskID)
+	n, err := u.Flush()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, n)
+
+	// a task added to the case after the wordlist was built is included and its users are only entitled to the wordlist
+	// if they are entitled to the rest of the case
+	third := createTask(t, f, creator, "CASE-1")
+	assert.Nil(t, b.stor.GrantEntitlement(partial, first))
+	assert.Nil(t, b.stor.GrantEntitlement(partial, third))
+	assert.Nil(t, b.stor.GrantEntitlement(outsider, third))
+	assert.Nil(t, b.stor.SaveCrackedHash(third.TaskID, "hash5", "letmein", now))
+
+	u.Cracked(third.TaskID)
+	n, err = u.Flush()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+
+	saved, contents = readDictionary(t, b.stor, wl.FileID)
+	assert.Equal(t, "password\nSummer2023!\nletmein\n", contents)
+	assert.Equal(t, int64(3), saved.NumberOfEntries)
+
+	for user, expected := range map[string]bool{creator.UserUUID: true, member.UserUUID: false, partial.UserUUID: true, outsider.UserUUID: false} {
+		entitled, err := b.stor.CheckEntitlement(user, wl.FileID, storage.EntitlementEngineFile)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, entitled, user)
+	}
+
+	// nothing has been cracked since the last flush
+	n, err = u.Flush()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, n)
+}
+
+func TestCreateWithoutPasswords(t *testing.T) {
+	b, f, closer := newBuilder(t)
+	defer closer()
+
+	creator := f.CreateUser(t, false)
+	task := createTask(t, f, creator, "")
+
+	_, err := b.Create(storage.EngineFile{FileName: "empty.dict", UploadedByUUID: creator.UserUUID}, storage.CrackedWordlist{
+		FileID:        uuid.NewString(),
+		TaskIDs:       []string{task.TaskID},
+		CreatedByUUID: creator.UserUUID,
+	})
+	assert.Equal(t, ErrNoPasswords, err)
+}
+
+func TestWordlistOfTasksEntitlements(t *testing.T) {
+	b, f, closer := newBuilder(t)
+	defer closer()
+
+	creator := f.CreateUser(t, false)
+	member := f.CreateUser(t, false)
+	partial := f.CreateUser(t, false)
+
+	first := createTask(t, f, creator, "")
+	second := createTask(t, f, creator, "")
+	assert.Nil(t, b.stor.GrantEntitlement(member, first))
+	assert.Nil(t, b.stor.GrantEntitlement(member, second))
+	assert.Nil(t, b.stor.GrantEntitlement(partial, second))
+
+	now := time.Now().UTC()
+	assert.Nil(t, b.stor.SaveCrackedHash(first.TaskID, "hash1", "first", now))
+	assert.Nil(t, b.stor.SaveCrackedHash(second.TaskID, "hash2", "second", now))
+
+	wl := storage.CrackedWordlist{
+		FileID:        uuid.NewString(),
+		TaskIDs:       []string{first.TaskID, second.TaskID},
+		CreatedByUUID: creator.UserUUID,
+		CreatedAt:     now,
+	}
+	_, err := b.Create(storage.EngineFile{FileName: "tasks.dict", UploadedByUUID: creator.UserUUID}, wl)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	// rebuilding the wordlist must not entitle the user to it either
+	assert.Nil(t, b.Rebuild(wl))
+
+	for user, expected := range map[string]bool{creator.UserUUID: true, member.UserUUID: true, partial.UserUUID: false} {
+		entitled, err := b.stor.CheckEntitlement(user, wl.FileID, storage.EntitlementEngineFile)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, entitled, user)
+	}
+
+	files, err := b.stor.GetEngineFilesForUser(partial)
+	if assert.Nil(t, err) {
+		for _, ef := range files {
+			assert.NotEqual(t, wl.FileID, ef.FileID)
+		}
+	}
+}
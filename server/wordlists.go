@@ -0,0 +1,52 @@
+package server
+
+import (
+	"time"
+
+	"github.com/mandiant/gocrack/server/wordlist"
+	"github.com/mandiant/gocrack/server/workmgr"
+
+	"github.com/rs/zerolog/log"
+)
+
+// wordlistUpdateInterval is how often the auto updated wordlists are rebuilt with the passwords cracked since. Rebuilding
+// on every crack would rewrite a large wordlist for each password of a burst
+const wordlistUpdateInterval = time.Minute
+
+// updateWordlists rebuilds the auto updated wordlists of cracked passwords as their tasks crack more of them
+func (s *Server) updateWordlists() {
+	defer s.wg.Done()
+
+	updater := wordlist.NewUpdater(wordlist.NewBuilder(s.stor, s.fm))
+	hndl, err := s.workers.Subscribe(workmgr.CrackedTopic, func(payload interface{}) {
+		crackedPassword, ok := payload.(workmgr.CrackedPasswordBroadcast)
+		if !ok {
+			log.Error().Msg("CrackedTopic message is not the correct type")
+			return
+		}
+		updater.Cracked(crackedPassword.TaskID)
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to subscribe to cracked passwords. Wordlists will not be updated")
+		return
+	}
+	defer s.workers.Unsubscribe(hndl)
+
+	tickEvery := time.NewTicker(wordlistUpdateInterval)
+	defer tickEvery.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-tickEvery.C:
+		}
+
+		n, err := updater.Flush()
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to update the wordlists of cracked passwords")
+		} else if n > 0 {
+			log.Info().Int("wordlists", n).Msg("Updated the wordlists of cracked passwords")
+		}
+	}
+}
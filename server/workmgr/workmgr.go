@@ -24,6 +24,10 @@ var (
 	LogTopic = ChannelTopic("LogTopic")
 	// FinalStatusTopic is the topic that indicates when a task is finished on a worker and includes its final status payload
 	FinalStatusTopic = ChannelTopic("FinalStatusTopic")
+	// TaskProgressTopic is the topic for the overall progress of tasks that are split into work units
+	TaskProgressTopic = ChannelTopic("TaskProgressTopic")
+	// PipelineProgressTopic is the topic for the progress of pipelines as their stages change status
+	PipelineProgressTopic = ChannelTopic("PipelineProgressTopic")
 )
 
 // ConnectedHost is an active, connected host to the WorkManager
@@ -70,10 +74,30 @@ type CrackedPasswordBroadcast struct {
 	CrackedAt time.Time `json:"cracked_at"`
 }
 
-// TaskStatusChangeBroadcast contains information about a recent task status change from a worker
+// TaskStatusChangeBroadcast contains information about a recent task status change
 type TaskStatusChangeBroadcast struct {
-	TaskID string             `json:"task_id"`
-	Status storage.TaskStatus `json:"status"`
+	TaskID  string                         `json:"task_id"`
+	Status  storage.TaskStatus             `json:"status"`
+	History storage.TaskStatusHistoryEntry `json:"history"`
+}
+
+// TaskProgressBroadcast contains the overall progress of a distributed task and the state of its work units
+type TaskProgressBroadcast struct {
+	TaskID    string                  `json:"task_id"`
+	Progress  float64                 `json:"progress"`
+	WorkUnits storage.WorkUnitSummary `json:"work_units"`
+}
+
+// PipelineProgressBroadcast contains the progress of a pipeline along with the task of the stage it's on
+type PipelineProgressBroadcast struct {
+	PipelineID      string             `json:"pipeline_id"`
+	Status          storage.TaskStatus `json:"status"`
+	CurrentStage    int                `json:"current_stage"`
+	NumberOfStages  int                `json:"number_of_stages"`
+	TaskID          string             `json:"task_id"`
+	TaskStatus      storage.TaskStatus `json:"task_status"`
+	NumberCracked   int                `json:"number_cracked"`
+	NumberPasswords int                `json:"number_passwords"`
 }
 
 // NewWorkerManager creates a new remote worker manager
@@ -164,14 +188,31 @@ func (s *WorkerManager) BroadcastCrackedPassword(taskID, hash, value string, cra
 }
 
 // BroadcastTaskStatusChange notifies all subscribers that the actual task status has changed
-func (s *WorkerManager) BroadcastTaskStatusChange(taskid string, status storage.TaskStatus) error {
+func (s *WorkerManager) BroadcastTaskStatusChange(entry storage.TaskStatusHistoryEntry) error {
 	broadcastsSent.WithLabelValues(string(TaskStatusTopic)).Inc()
 	return s.exch.Publish(exchange.Topic(TaskStatusTopic), TaskStatusChangeBroadcast{
-		TaskID: taskid,
-		Status: status,
+		TaskID:  entry.TaskID,
+		Status:  entry.Status,
+		History: entry,
 	})
 }
 
+// BroadcastTaskProgress notifies all subscribers that the progress of a distributed task has changed
+func (s *WorkerManager) BroadcastTaskProgress(taskid string, summary storage.WorkUnitSummary) error {
+	broadcastsSent.WithLabelValues(string(TaskProgressTopic)).Inc()
+	return s.exch.Publish(exchange.Topic(TaskProgressTopic), TaskProgressBroadcast{
+		TaskID:    taskid,
+		Progress:  summary.Progress,
+		WorkUnits: summary,
+	})
+}
+
+// BroadcastPipelineProgress notifies all subscribers that a stage of a pipeline has changed status
+func (s *WorkerManager) BroadcastPipelineProgress(progress PipelineProgressBroadcast) error {
+	broadcastsSent.WithLabelValues(string(PipelineProgressTopic)).Inc()
+	return s.exch.Publish(exchange.Topic(PipelineProgressTopic), progress)
+}
+
 // Subscribe to a channel topic and get called asynchronously everytime a new event occurs. If successful, the handle is returned.
 func (s *WorkerManager) Subscribe(topic ChannelTopic, f CallbackFunc) (uint, error) {
 	hndl, err := s.exch.Subscribe(exchange.Topic(topic), func(t exchange.Topic, e exchange.Event) {
@@ -106,17 +106,45 @@ func (suite *TestWorkManagerSuite) TestBroadcastCrackedPassword() {
 func (suite *TestWorkManagerSuite) TestBroadcastTaskStatusChange() {
 	var taskID = "1337"
 	var status = storage.TaskStatusStopped
+	var entry = storage.TaskStatusHistoryEntry{
+		TaskID:         taskID,
+		PreviousStatus: storage.TaskStatusRunning,
+		TaskStatusChange: storage.TaskStatusChange{
+			Status:    status,
+			ActorType: storage.StatusActorWorker,
+			Actor:     "worker01",
+		},
+	}
 
 	hndl, err := suite.Subscribe(TaskStatusTopic, func(payload interface{}) {
 		taskstatus, ok := payload.(TaskStatusChangeBroadcast)
 		suite.True(ok)
 		suite.Equal(taskstatus.TaskID, taskID)
 		suite.Equal(taskstatus.Status, status)
+		suite.Equal(entry, taskstatus.History)
+	})
+	suite.Nil(err)
+	defer suite.Unsubscribe(hndl)
+
+	err = suite.BroadcastTaskStatusChange(entry)
+	suite.Nil(err)
+}
+
+func (suite *TestWorkManagerSuite) TestBroadcastTaskProgress() {
+	var taskID = "1337"
+	var summary = storage.WorkUnitSummary{Total: 4, Active: 2, Exhausted: 2, Progress: 62.5}
+
+	hndl, err := suite.Subscribe(TaskProgressTopic, func(payload interface{}) {
+		progress, ok := payload.(TaskProgressBroadcast)
+		suite.True(ok)
+		suite.Equal(taskID, progress.TaskID)
+		suite.Equal(62.5, progress.Progress)
+		suite.Equal(summary, progress.WorkUnits)
 	})
 	suite.Nil(err)
 	defer suite.Unsubscribe(hndl)
 
-	err = suite.BroadcastTaskStatusChange(taskID, status)
+	err = suite.BroadcastTaskProgress(taskID, summary)
 	suite.Nil(err)
 }
 
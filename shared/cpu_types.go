@@ -0,0 +1,41 @@
+package shared
+
+// CPUUserOptions defines the user settable options of a task executed by the pure Go CPU engine.
+// Hash types, attack modes, masks and mangling rules follow hashcat's conventions so engine files can be shared between engines
+type CPUUserOptions struct {
+	AttackMode       HashcatAttackMode `json:"attack_mode"`
+	HashType         int               `json:"hash_type"`
+	Masks            *string           `json:"masks,omitempty"`
+	DictionaryFile   *string           `json:"dictionary_file,omitempty"`
+	ManglingRuleFile *string           `json:"mangling_file,omitempty"`
+}
+
+// Validate the options and return a list of user friendly errors
+func (s CPUUserOptions) Validate() []string {
+	errs := make([]string, 0)
+
+	switch s.AttackMode {
+	case AttackModeStraight:
+		if s.DictionaryFile == nil || *s.DictionaryFile == "" {
+			errs = append(errs, "dictionary_file must be set on a straight/dictionary attack mode")
+		}
+	case AttackModeBruteForce:
+		if s.Masks == nil || *s.Masks == "" {
+			errs = append(errs, "masks must be set on a brute force attack mode")
+		}
+	default:
+		errs = append(errs, "attack_mode must be a straight or brute force attack on the cpu engine")
+	}
+
+	return errs
+}
+
+// EngineFiles returns the IDs of the engine files used by the task
+func (s CPUUserOptions) EngineFiles() []string {
+	return engineFileIDs(s.DictionaryFile, s.ManglingRuleFile, s.Masks)
+}
+
+// GetHashType returns the hashcat mode of the hashes being cracked
+func (s CPUUserOptions) GetHashType() int {
+	return s.HashType
+}
@@ -0,0 +1,31 @@
+// Package cpu defines the pure Go CPU engine. It is imported by the server to accept CPU tasks and by the worker
+// engine that runs them
+package cpu
+
+import (
+	"encoding/json"
+
+	"github.com/mandiant/gocrack/server/storage"
+	"github.com/mandiant/gocrack/shared"
+	"github.com/mandiant/gocrack/shared/enginedef"
+)
+
+func init() {
+	enginedef.Register(enginedef.Definition{
+		ID:          storage.WorkerCPUEngine,
+		Name:        "cpu",
+		DisplayName: "CPU",
+		CPUOnly:     true,
+		DecodePayload: func(raw json.RawMessage) (enginedef.Payload, error) {
+			var opts shared.CPUUserOptions
+			if err := json.Unmarshal(raw, &opts); err != nil {
+				return nil, err
+			}
+			return opts, nil
+		},
+		Keyspace: func(pl enginedef.Payload, getEngineFile enginedef.EngineFileGetter) (uint64, error) {
+			opts := pl.(shared.CPUUserOptions)
+			return enginedef.AttackKeyspace(opts.AttackMode, opts.DictionaryFile, opts.Masks, getEngineFile)
+		},
+	})
+}
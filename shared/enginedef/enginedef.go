@@ -0,0 +1,137 @@
+// Package enginedef is the registry of the password cracking engines GoCrack knows about. The server decodes, validates
+// and splits tasks using the definitions registered here without linking against the engines themselves
+package enginedef
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/mandiant/gocrack/server/storage"
+	"github.com/mandiant/gocrack/shared"
+)
+
+// Payload is the engine specific options of a task (storage.Task.EnginePayload)
+type Payload interface {
+	// Validate the payload and return a list of user friendly errors
+	Validate() []string
+	// EngineFiles returns the IDs of every engine file the task needs downloaded before it can start
+	EngineFiles() []string
+}
+
+// EngineFileGetter returns the metadata of an engine file by its ID
+type EngineFileGetter func(fileID string) (*storage.EngineFile, error)
+
+// Definition describes a password cracking engine. It is used by the server to decode & validate tasks
+// and by the worker to run them
+type Definition struct {
+	ID storage.WorkerCrackEngine
+	// Name is the short, lowercase name of the engine used in beacons and configuration
+	Name string
+	// DisplayName is shown to users
+	DisplayName string
+	// CPUOnly indicates the engine runs on the host CPU rather than OpenCL devices
+	CPUOnly bool
+	// DecodePayload decodes the JSON form of a task's engine payload
+	DecodePayload func(json.RawMessage) (Payload, error)
+	// Keyspace returns the number of dictionary words or masks of a payload so the task can be split into work units.
+	// Engines that cannot split a task leave this nil
+	Keyspace func(pl Payload, getEngineFile EngineFileGetter) (uint64, error)
+}
+
+var (
+	mu          sync.RWMutex
+	definitions = make(map[storage.WorkerCrackEngine]Definition)
+)
+
+// Register makes an engine definition available to the server and worker. If Register is called twice with the same ID, it panics
+func Register(def Definition) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if def.DecodePayload == nil {
+		panic("enginedef: Register payload decoder is nil")
+	}
+
+	if _, dup := definitions[def.ID]; dup {
+		panic(fmt.Sprintf("enginedef: Register called twice for engine %d", def.ID))
+	}
+	definitions[def.ID] = def
+}
+
+// Lookup returns the definition of an engine
+func Lookup(id storage.WorkerCrackEngine) (Definition, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	def, ok := definitions[id]
+	return def, ok
+}
+
+// LookupByName returns the definition of the engine with the name
+func LookupByName(name string) (Definition, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	for _, def := range definitions {
+		if def.Name == name {
+			return def, true
+		}
+	}
+	return Definition{}, false
+}
+
+// Definitions returns every registered engine definition ordered by ID
+func Definitions() []Definition {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	defs := make([]Definition, 0, len(definitions))
+	for _, def := range definitions {
+		defs = append(defs, def)
+	}
+
+	sort.Slice(defs, func(i, j int) bool { return defs[i].ID < defs[j].ID })
+	return defs
+}
+
+// DecodePayload decodes the engine payload of a task using the definition of engine
+func DecodePayload(id storage.WorkerCrackEngine, raw json.RawMessage) (Payload, error) {
+	def, ok := Lookup(id)
+	if !ok {
+		return nil, fmt.Errorf("unknown engine %d", id)
+	}
+	return def.DecodePayload(raw)
+}
+
+// AttackKeyspace returns the number of lines in the (left) dictionary of a straight, combinator or hybrid dictionary + mask
+// attack or the masks of a brute force attack. Engines that take hashcat's attack modes split tasks on these lines as
+// hashcat cannot skip into the middle of a mask file
+func AttackKeyspace(mode shared.HashcatAttackMode, dictionary, masks *string, getEngineFile EngineFileGetter) (uint64, error) {
+	var fileID *string
+
+	switch {
+	case mode.SplitsOnDictionary():
+		fileID = dictionary
+	case mode == shared.AttackModeBruteForce:
+		fileID = masks
+	default:
+		return 0, errors.New("the attack mode cannot be split into work units")
+	}
+
+	if fileID == nil || *fileID == "" {
+		return 0, errors.New("the task does not have a file to split into work units")
+	}
+
+	ef, err := getEngineFile(*fileID)
+	if err != nil {
+		return 0, err
+	}
+
+	if ef.NumberOfEntries <= 0 {
+		return 0, errors.New("the engine file is empty")
+	}
+	return uint64(ef.NumberOfEntries), nil
+}
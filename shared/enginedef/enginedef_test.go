@@ -0,0 +1,180 @@
+package enginedef_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/mandiant/gocrack/server/storage"
+	"github.com/mandiant/gocrack/shared"
+	"github.com/mandiant/gocrack/shared/enginedef"
+	_ "github.com/mandiant/gocrack/shared/enginedef/cpu"
+	_ "github.com/mandiant/gocrack/shared/enginedef/hashcat"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testEngineID storage.WorkerCrackEngine = 1 << 7
+
+type testPayload struct {
+	Dictionary string `json:"dictionary"`
+}
+
+func (s testPayload) Validate() []string {
+	if s.Dictionary == "" {
+		return []string{"dictionary must be set"}
+	}
+	return nil
+}
+
+func (s testPayload) EngineFiles() []string {
+	return []string{s.Dictionary}
+}
+
+func TestBuiltinDefinitions(t *testing.T) {
+	pl, err := enginedef.DecodePayload(storage.WorkerHashcatEngine, json.RawMessage(`{"attack_mode": 3, "hash_type": 1000, "masks": "abc"}`))
+	assert.Nil(t, err)
+	assert.Equal(t, shared.HashcatUserOptions{
+		AttackMode: shared.AttackModeBruteForce,
+		HashType:   1000,
+		Masks:      shared.GetStrPtr("abc"),
+	}, pl)
+	assert.Empty(t, pl.Validate())
+	assert.Equal(t, []string{"abc"}, pl.EngineFiles())
+
+	pl, err = enginedef.DecodePayload(storage.WorkerHashcatEngine, json.RawMessage(`{"attack_mode": 1, "hash_type": 1000, "dictionary_file": "left", "right_dictionary_file": "right", "rule_left": "c"}`))
+	assert.Nil(t, err)
+	assert.Empty(t, pl.Validate())
+	assert.Equal(t, []string{"left", "right"}, pl.EngineFiles())
+
+	pl, err = enginedef.DecodePayload(storage.WorkerHashcatEngine, json.RawMessage(`{"attack_mode": 7, "hash_type": 0, "dictionary_file": "words",
+		"inline_masks": ["?1?2?d"], "custom_charsets": [{"charset": "?l?u"}, {"file_id": "german"}], "increment": true, "increment_min": 2}`))
+	assert.Nil(t, err)
+	assert.Empty(t, pl.Validate())
+	assert.Equal(t, []string{"words", "german"}, pl.EngineFiles())
+
+	for raw, expected := range map[string][]string{
+		`{"attack_mode": 1, "dictionary_file": "left"}`:                                    {"dictionary_file and right_dictionary_file must be set on a combinator attack mode"},
+		`{"attack_mode": 6, "masks": "abc"}`:                                               {"dictionary_file and masks or inline_masks must be set on a hybrid attack mode"},
+		`{"attack_mode": 9, "dictionary_file": "words", "right_dictionary_file": "right"}`: {"right_dictionary_file can only be set on a combinator attack mode"},
+		`{"attack_mode": 0, "dictionary_file": "words", "rule_left": "c"}`:                 {"rule_left and rule_right can only be set on a combinator or hybrid attack mode"},
+		`{"attack_mode": 2}`: {"attack_mode 2 is not supported by the hashcat engine"},
+		`{"attack_mode": 3, "masks": "abc", "inline_masks": ["?d"]}`: {"masks and inline_masks cannot both be set"},
+		`{"attack_mode": 3, "inline_masks": ["?d?1", "?x"]}`: {
+			"inline_masks[0]: ?1 is used but custom charset 1 is not set",
+			"inline_masks[1]: ?x is not a valid charset",
+		},
+		`{"attack_mode": 3, "inline_masks": ["?1"], "custom_charsets": [{"charset": "?l", "file_id": "abc"}]}`: {
+			"custom_charsets[0] must set either charset or file_id, not both",
+		},
+		`{"attack_mode": 3, "inline_masks": ["?d"], "increment": true, "increment_min": 4, "increment_max": 2}`: {
+			"increment_min must not be greater than increment_max",
+		},
+		`{"attack_mode": 0, "dictionary_file": "words", "tuning": {"workload_profile": 9, "hex_charset": true}}`: {
+			"tuning.workload_profile must be between 1 and 4",
+			"tuning.hex_charset can only be set on a brute force or hybrid attack mode",
+		},
+		`{"attack_mode": 0, "dictionary_file": "words", "inline_masks": ["?d"]}`: {
+			"inline_masks, custom_charsets and increment can only be set on a brute force or hybrid attack mode",
+		},
+	} {
+		pl, err = enginedef.DecodePayload(storage.WorkerHashcatEngine, json.RawMessage(raw))
+		assert.Nil(t, err)
+		assert.Equal(t, expected, pl.Validate(), raw)
+	}
+
+	pl, err = enginedef.DecodePayload(storage.WorkerCPUEngine, json.RawMessage(`{"attack_mode": 0}`))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"dictionary_file must be set on a straight/dictionary attack mode"}, pl.Validate())
+
+	_, err = enginedef.DecodePayload(storage.WorkerCrackEngine(255), json.RawMessage(`{}`))
+	assert.NotNil(t, err)
+}
+
+func TestRegisterEngine(t *testing.T) {
+	enginedef.Register(enginedef.Definition{
+		ID:          testEngineID,
+		Name:        "test",
+		DisplayName: "Test",
+		DecodePayload: func(raw json.RawMessage) (enginedef.Payload, error) {
+			var pl testPayload
+			err := json.Unmarshal(raw, &pl)
+			return pl, err
+		},
+	})
+
+	def, ok := enginedef.Lookup(testEngineID)
+	assert.True(t, ok)
+	assert.Equal(t, "Test", def.DisplayName)
+	assert.Panics(t, func() { enginedef.Register(def) })
+
+	def, ok = enginedef.LookupByName("test")
+	assert.True(t, ok)
+	assert.Equal(t, testEngineID, def.ID)
+	_, ok = enginedef.LookupByName("john")
+	assert.False(t, ok)
+
+	pl, err := enginedef.DecodePayload(testEngineID, json.RawMessage(`{"dictionary": "words"}`))
+	assert.Nil(t, err)
+	assert.Equal(t, testPayload{Dictionary: "words"}, pl)
+
+	ids := make([]storage.WorkerCrackEngine, 0)
+	for _, def := range enginedef.Definitions() {
+		ids = append(ids, def.ID)
+	}
+	assert.Equal(t, []storage.WorkerCrackEngine{storage.WorkerHashcatEngine, storage.WorkerCPUEngine, testEngineID}, ids)
+}
+
+func TestBuiltinKeyspace(t *testing.T) {
+	getEngineFile := func(fileID string) (*storage.EngineFile, error) {
+		if fileID != "dict" {
+			return nil, storage.ErrNotFound
+		}
+		return &storage.EngineFile{FileID: fileID, NumberOfEntries: 1000}, nil
+	}
+
+	def, ok := enginedef.Lookup(storage.WorkerCPUEngine)
+	assert.True(t, ok)
+
+	keyspace, err := def.Keyspace(shared.CPUUserOptions{
+		AttackMode:     shared.AttackModeStraight,
+		DictionaryFile: shared.GetStrPtr("dict"),
+	}, getEngineFile)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1000), keyspace)
+
+	_, err = def.Keyspace(shared.CPUUserOptions{
+		AttackMode: shared.AttackModeBruteForce,
+		Masks:      shared.GetStrPtr("missing"),
+	}, getEngineFile)
+	assert.Equal(t, storage.ErrNotFound, err)
+
+	_, err = def.Keyspace(shared.CPUUserOptions{AttackMode: shared.HashcatAttackMode(1)}, getEngineFile)
+	assert.NotNil(t, err)
+
+	def, ok = enginedef.Lookup(storage.WorkerHashcatEngine)
+	assert.True(t, ok)
+
+	// combinator & hybrid dictionary + mask attacks are split on the words of the left dictionary
+	for _, mode := range []shared.HashcatAttackMode{shared.AttackModeCombinator, shared.AttackModeHybridDictMask} {
+		keyspace, err = def.Keyspace(shared.HashcatUserOptions{
+			AttackMode:     mode,
+			DictionaryFile: shared.GetStrPtr("dict"),
+			Masks:          shared.GetStrPtr("masks"),
+		}, getEngineFile)
+		assert.Nil(t, err)
+		assert.Equal(t, uint64(1000), keyspace)
+	}
+
+	// inline masks are counted without an engine file
+	keyspace, err = def.Keyspace(shared.HashcatUserOptions{
+		AttackMode:  shared.AttackModeBruteForce,
+		InlineMasks: []string{"?d?d", "?l?l", "?u?u"},
+	}, getEngineFile)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(3), keyspace)
+
+	for _, mode := range []shared.HashcatAttackMode{shared.AttackModeHybridMaskDict, shared.AttackModeAssociation} {
+		_, err = def.Keyspace(shared.HashcatUserOptions{AttackMode: mode, DictionaryFile: shared.GetStrPtr("dict")}, getEngineFile)
+		assert.NotNil(t, err)
+	}
+}
@@ -0,0 +1,34 @@
+// Package hashcat defines the hashcat engine. It is imported by the server to accept hashcat tasks and by the worker
+// engine that runs them
+package hashcat
+
+import (
+	"encoding/json"
+
+	"github.com/mandiant/gocrack/server/storage"
+	"github.com/mandiant/gocrack/shared"
+	"github.com/mandiant/gocrack/shared/enginedef"
+)
+
+func init() {
+	enginedef.Register(enginedef.Definition{
+		ID:          storage.WorkerHashcatEngine,
+		Name:        "hashcat",
+		DisplayName: "Hashcat",
+		DecodePayload: func(raw json.RawMessage) (enginedef.Payload, error) {
+			var opts shared.HashcatUserOptions
+			if err := json.Unmarshal(raw, &opts); err != nil {
+				return nil, err
+			}
+			return opts, nil
+		},
+		Keyspace: func(pl enginedef.Payload, getEngineFile enginedef.EngineFileGetter) (uint64, error) {
+			opts := pl.(shared.HashcatUserOptions)
+			if opts.AttackMode == shared.AttackModeBruteForce && len(opts.InlineMasks) > 0 {
+				// inline masks are written to a mask file by the worker and split the same way
+				return uint64(len(opts.InlineMasks)), nil
+			}
+			return enginedef.AttackKeyspace(opts.AttackMode, opts.DictionaryFile, opts.Masks, getEngineFile)
+		},
+	})
+}
@@ -0,0 +1,126 @@
+package shared
+
+import "fmt"
+
+// HashcatTuningOptions are the performance & input handling options of hashcat a task may set. Only these options
+// are passed through to hashcat; a worker may set defaults for the numeric options and cap them with HashcatTuningCeilings
+type HashcatTuningOptions struct {
+	// WorkloadProfile is hashcat's -w (1 low to 4 nightmare)
+	WorkloadProfile *int `json:"workload_profile,omitempty" yaml:"workload_profile,omitempty"`
+	// OptimizedKernels enables the optimized kernels (-O) which are faster but limit the password length. Defaults to true
+	OptimizedKernels *bool `json:"optimized_kernels,omitempty" yaml:"optimized_kernels,omitempty"`
+	// KernelAccel & KernelLoops are tuned automatically by hashcat when not set
+	KernelAccel *int `json:"kernel_accel,omitempty" yaml:"kernel_accel,omitempty"`
+	KernelLoops *int `json:"kernel_loops,omitempty" yaml:"kernel_loops,omitempty"`
+	// HWMonTempAbort aborts the task when a device reaches the temperature in celsius
+	HWMonTempAbort *int `json:"hwmon_temp_abort,omitempty" yaml:"hwmon_temp_abort,omitempty"`
+	// RuntimeSeconds stops the task after the number of seconds
+	RuntimeSeconds *int `json:"runtime,omitempty" yaml:"runtime,omitempty"`
+	// Username, HexSalt & HexCharset describe the task's input so they have no worker default
+	Username   bool `json:"username,omitempty" yaml:"-"`
+	HexSalt    bool `json:"hex_salt,omitempty" yaml:"-"`
+	HexCharset bool `json:"hex_charset,omitempty" yaml:"-"`
+}
+
+// HashcatTuningCeilings are the highest values of the tuning options a worker allows
+type HashcatTuningCeilings struct {
+	WorkloadProfile *int `yaml:"workload_profile,omitempty"`
+	KernelAccel     *int `yaml:"kernel_accel,omitempty"`
+	KernelLoops     *int `yaml:"kernel_loops,omitempty"`
+	HWMonTempAbort  *int `yaml:"hwmon_temp_abort,omitempty"`
+	RuntimeSeconds  *int `yaml:"runtime,omitempty"`
+}
+
+const (
+	maxWorkloadProfile = 4
+	maxKernelTuning    = 1024
+	// hashcat's defaults are capped when a worker sets a lower ceiling
+	defWorkloadProfile = 2
+	defHWMonTempAbort  = 90
+)
+
+// Validate the options and return a list of user friendly errors
+func (s HashcatTuningOptions) Validate() []string {
+	errs := make([]string, 0)
+
+	if s.WorkloadProfile != nil && (*s.WorkloadProfile < 1 || *s.WorkloadProfile > maxWorkloadProfile) {
+		errs = append(errs, "tuning.workload_profile must be between 1 and 4")
+	}
+
+	if s.KernelAccel != nil && (*s.KernelAccel < 1 || *s.KernelAccel > maxKernelTuning) {
+		errs = append(errs, "tuning.kernel_accel must be between 1 and 1024")
+	}
+
+	if s.KernelLoops != nil && (*s.KernelLoops < 1 || *s.KernelLoops > maxKernelTuning) {
+		errs = append(errs, "tuning.kernel_loops must be between 1 and 1024")
+	}
+
+	if s.HWMonTempAbort != nil && *s.HWMonTempAbort < 1 {
+		errs = append(errs, "tuning.hwmon_temp_abort must be greater than 0")
+	}
+
+	if s.RuntimeSeconds != nil && *s.RuntimeSeconds < 1 {
+		errs = append(errs, "tuning.runtime must be greater than 0")
+	}
+	return errs
+}
+
+// Validate the ceilings of a worker config
+func (s HashcatTuningCeilings) Validate() error {
+	for _, ceiling := range []struct {
+		name  string
+		value *int
+	}{
+		{"workload_profile", s.WorkloadProfile},
+		{"kernel_accel", s.KernelAccel},
+		{"kernel_loops", s.KernelLoops},
+		{"hwmon_temp_abort", s.HWMonTempAbort},
+		{"runtime", s.RuntimeSeconds},
+	} {
+		if ceiling.value != nil && *ceiling.value < 1 {
+			return fmt.Errorf("hashcat.tuning.ceilings.%s must be greater than 0", ceiling.name)
+		}
+	}
+	return nil
+}
+
+// Apply the worker's defaults to the options the task did not set and lower any option above the worker's ceilings.
+// When neither the task nor the worker sets an option that has a ceiling, hashcat's default is capped instead
+func (s HashcatTuningOptions) Apply(defaults HashcatTuningOptions, ceilings HashcatTuningCeilings) HashcatTuningOptions {
+	out := s
+	out.WorkloadProfile = capOption(s.WorkloadProfile, defaults.WorkloadProfile, ceilings.WorkloadProfile, GetIntPtr(defWorkloadProfile))
+	out.HWMonTempAbort = capOption(s.HWMonTempAbort, defaults.HWMonTempAbort, ceilings.HWMonTempAbort, GetIntPtr(defHWMonTempAbort))
+	// hashcat does not limit the runtime by default so the ceiling becomes the limit
+	out.RuntimeSeconds = capOption(s.RuntimeSeconds, defaults.RuntimeSeconds, ceilings.RuntimeSeconds, ceilings.RuntimeSeconds)
+	// kernel accel & loops are left to hashcat's autotuning unless they are set
+	out.KernelAccel = capOption(s.KernelAccel, defaults.KernelAccel, ceilings.KernelAccel, nil)
+	out.KernelLoops = capOption(s.KernelLoops, defaults.KernelLoops, ceilings.KernelLoops, nil)
+
+	if out.OptimizedKernels == nil {
+		out.OptimizedKernels = defaults.OptimizedKernels
+	}
+	return out
+}
+
+// capOption returns the value, or the default if it is not set, lowered to the ceiling
+func capOption(value, def, ceiling, hashcatDefault *int) *int {
+	if value == nil {
+		value = def
+	}
+
+	if ceiling == nil {
+		return value
+	}
+
+	if value == nil {
+		if hashcatDefault == nil {
+			return nil
+		}
+		value = hashcatDefault
+	}
+
+	if *value > *ceiling {
+		return GetIntPtr(*ceiling)
+	}
+	return GetIntPtr(*value)
+}
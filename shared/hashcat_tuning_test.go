@@ -0,0 +1,64 @@
+package shared
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashcatTuningValidate(t *testing.T) {
+	assert.Empty(t, HashcatTuningOptions{WorkloadProfile: GetIntPtr(4), KernelAccel: GetIntPtr(64)}.Validate())
+	assert.Equal(t, []string{
+		"tuning.workload_profile must be between 1 and 4",
+		"tuning.kernel_loops must be between 1 and 1024",
+		"tuning.runtime must be greater than 0",
+	}, HashcatTuningOptions{
+		WorkloadProfile: GetIntPtr(5),
+		KernelLoops:     GetIntPtr(2048),
+		RuntimeSeconds:  GetIntPtr(0),
+	}.Validate())
+
+	assert.Nil(t, HashcatTuningCeilings{RuntimeSeconds: GetIntPtr(3600)}.Validate())
+	assert.NotNil(t, HashcatTuningCeilings{KernelAccel: GetIntPtr(0)}.Validate())
+}
+
+func TestHashcatTuningApply(t *testing.T) {
+	defaults := HashcatTuningOptions{
+		WorkloadProfile:  GetIntPtr(3),
+		OptimizedKernels: GetBoolPtr(false),
+		KernelLoops:      GetIntPtr(256),
+	}
+	ceilings := HashcatTuningCeilings{
+		WorkloadProfile: GetIntPtr(3),
+		KernelAccel:     GetIntPtr(128),
+		HWMonTempAbort:  GetIntPtr(80),
+		RuntimeSeconds:  GetIntPtr(3600),
+	}
+
+	// the worker's defaults & ceilings are used when the task does not set an option
+	out := HashcatTuningOptions{}.Apply(defaults, ceilings)
+	assert.Equal(t, 3, *out.WorkloadProfile)
+	assert.False(t, *out.OptimizedKernels)
+	assert.Nil(t, out.KernelAccel)
+	assert.Equal(t, 256, *out.KernelLoops)
+	assert.Equal(t, 80, *out.HWMonTempAbort)
+	assert.Equal(t, 3600, *out.RuntimeSeconds)
+
+	// options above the ceilings are lowered
+	out = HashcatTuningOptions{
+		WorkloadProfile:  GetIntPtr(4),
+		OptimizedKernels: GetBoolPtr(true),
+		KernelAccel:      GetIntPtr(1024),
+		RuntimeSeconds:   GetIntPtr(60),
+		Username:         true,
+	}.Apply(defaults, ceilings)
+	assert.Equal(t, 3, *out.WorkloadProfile)
+	assert.True(t, *out.OptimizedKernels)
+	assert.Equal(t, 128, *out.KernelAccel)
+	assert.Equal(t, 60, *out.RuntimeSeconds)
+	assert.True(t, out.Username)
+
+	// without a worker config the task's options are used as is
+	out = HashcatTuningOptions{}.Apply(HashcatTuningOptions{}, HashcatTuningCeilings{})
+	assert.Equal(t, HashcatTuningOptions{}, out)
+}
@@ -1,15 +1,62 @@
 package shared
 
+import "fmt"
+
 // HashcatAttackMode describes the various supported password cracking attacks available in hashcat
 type HashcatAttackMode uint32
 
 const (
 	// AttackModeStraight is a dictionary attack with optional mangling rules applied
 	AttackModeStraight HashcatAttackMode = 0
+	// AttackModeCombinator appends each word of the right dictionary to each word of the left dictionary
+	AttackModeCombinator HashcatAttackMode = 1
 	// AttackModeBruteForce is a brute force attack using a list of masks to guess the password(s)
 	AttackModeBruteForce HashcatAttackMode = 3
+	// AttackModeHybridDictMask appends the masks to each word of the dictionary
+	AttackModeHybridDictMask HashcatAttackMode = 6
+	// AttackModeHybridMaskDict prepends the masks to each word of the dictionary
+	AttackModeHybridMaskDict HashcatAttackMode = 7
+	// AttackModeAssociation tries each word of the dictionary against the hash on the same line of the task file
+	AttackModeAssociation HashcatAttackMode = 9
 )
 
+func (s HashcatAttackMode) String() string {
+	switch s {
+	case AttackModeStraight:
+		return "Straight"
+	case AttackModeCombinator:
+		return "Combinator"
+	case AttackModeBruteForce:
+		return "Brute Force"
+	case AttackModeHybridDictMask:
+		return "Hybrid Dictionary + Mask"
+	case AttackModeHybridMaskDict:
+		return "Hybrid Mask + Dictionary"
+	case AttackModeAssociation:
+		return "Association"
+	}
+	return "Unknown"
+}
+
+// SplitsOnDictionary returns true if the attack iterates over the words of the (left) dictionary so a task can be split
+// into work units with --skip & --limit
+func (s HashcatAttackMode) SplitsOnDictionary() bool {
+	switch s {
+	case AttackModeStraight, AttackModeCombinator, AttackModeHybridDictMask:
+		return true
+	}
+	return false
+}
+
+// UsesMasks returns true if the attack mode generates candidates from masks
+func (s HashcatAttackMode) UsesMasks() bool {
+	switch s {
+	case AttackModeBruteForce, AttackModeHybridDictMask, AttackModeHybridMaskDict:
+		return true
+	}
+	return false
+}
+
 // HashcatUserOptions defines the user settable options of a hashcat task
 type HashcatUserOptions struct {
 	AttackMode       HashcatAttackMode `json:"attack_mode"`
@@ -17,6 +64,145 @@ type HashcatUserOptions struct {
 	Masks            *string           `json:"masks,omitempty"`
 	DictionaryFile   *string           `json:"dictionary_file,omitempty"`
 	ManglingRuleFile *string           `json:"mangling_file,omitempty"`
+	// RightDictionaryFile is combined with DictionaryFile (the left dictionary) in a combinator attack
+	RightDictionaryFile *string `json:"right_dictionary_file,omitempty"`
+	// RuleLeft & RuleRight are single rules applied to each word of the left & right side of a combinator or hybrid attack
+	RuleLeft  *string `json:"rule_left,omitempty"`
+	RuleRight *string `json:"rule_right,omitempty"`
+	// InlineMasks are masks sent with the task instead of an uploaded masks file
+	InlineMasks []string `json:"inline_masks,omitempty"`
+	// CustomCharsets are the charsets referenced in masks as ?1 to ?4, in that order
+	CustomCharsets []CustomCharset `json:"custom_charsets,omitempty"`
+	// Increment tries every length of the masks from IncrementMin (default 1) to IncrementMax (default the mask length)
+	Increment    bool `json:"increment,omitempty"`
+	IncrementMin *int `json:"increment_min,omitempty"`
+	IncrementMax *int `json:"increment_max,omitempty"`
+	// Tuning are the allow-listed hashcat options that change its performance or how it reads the input
+	Tuning *HashcatTuningOptions `json:"tuning,omitempty"`
+}
+
+// Validate the options and return a list of user friendly errors
+func (s HashcatUserOptions) Validate() []string {
+	errs := make([]string, 0)
+	hasDictionary := s.DictionaryFile != nil && *s.DictionaryFile != ""
+	hasMasks := s.Masks != nil && *s.Masks != "" || len(s.InlineMasks) > 0
+
+	switch s.AttackMode {
+	case AttackModeStraight:
+		if !hasDictionary {
+			errs = append(errs, "dictionary_file must be set on a straight/dictionary attack mode")
+		}
+	case AttackModeCombinator:
+		if !hasDictionary || s.RightDictionaryFile == nil || *s.RightDictionaryFile == "" {
+			errs = append(errs, "dictionary_file and right_dictionary_file must be set on a combinator attack mode")
+		}
+	case AttackModeBruteForce:
+		if !hasMasks {
+			errs = append(errs, "masks or inline_masks must be set on a brute force attack mode")
+		}
+	case AttackModeHybridDictMask, AttackModeHybridMaskDict:
+		if !hasDictionary || !hasMasks {
+			errs = append(errs, "dictionary_file and masks or inline_masks must be set on a hybrid attack mode")
+		}
+	case AttackModeAssociation:
+		if !hasDictionary {
+			errs = append(errs, "dictionary_file must be set on an association attack mode")
+		}
+	default:
+		errs = append(errs, fmt.Sprintf("attack_mode %d is not supported by the hashcat engine", s.AttackMode))
+	}
+
+	if s.RightDictionaryFile != nil && *s.RightDictionaryFile != "" && s.AttackMode != AttackModeCombinator {
+		errs = append(errs, "right_dictionary_file can only be set on a combinator attack mode")
+	}
+
+	switch s.AttackMode {
+	case AttackModeCombinator, AttackModeHybridDictMask, AttackModeHybridMaskDict:
+	default:
+		if s.RuleLeft != nil || s.RuleRight != nil {
+			errs = append(errs, "rule_left and rule_right can only be set on a combinator or hybrid attack mode")
+		}
+	}
+
+	if s.Tuning != nil {
+		errs = append(errs, s.Tuning.Validate()...)
+		if s.Tuning.HexCharset && !s.AttackMode.UsesMasks() {
+			errs = append(errs, "tuning.hex_charset can only be set on a brute force or hybrid attack mode")
+		}
+	}
+
+	return append(errs, s.validateMasks()...)
+}
+
+// validateMasks checks the inline masks, custom charsets & increment options
+func (s HashcatUserOptions) validateMasks() []string {
+	errs := make([]string, 0)
+
+	if !s.AttackMode.UsesMasks() {
+		if len(s.InlineMasks) > 0 || len(s.CustomCharsets) > 0 || s.Increment || s.IncrementMin != nil || s.IncrementMax != nil {
+			errs = append(errs, "inline_masks, custom_charsets and increment can only be set on a brute force or hybrid attack mode")
+		}
+		return errs
+	}
+
+	if s.Masks != nil && *s.Masks != "" && len(s.InlineMasks) > 0 {
+		errs = append(errs, "masks and inline_masks cannot both be set")
+	}
+
+	var defined [4]bool
+	if len(s.CustomCharsets) > len(defined) {
+		errs = append(errs, "no more than 4 custom_charsets can be set")
+	}
+
+	for i, charset := range s.CustomCharsets {
+		if i >= len(defined) {
+			break
+		}
+
+		switch {
+		case charset.Charset != "" && charset.FileID != "":
+			errs = append(errs, fmt.Sprintf("custom_charsets[%d] must set either charset or file_id, not both", i))
+		case charset.Charset != "":
+			if err := ValidateCharset(charset.Charset); err != nil {
+				errs = append(errs, fmt.Sprintf("custom_charsets[%d]: %s", i, err))
+			}
+		}
+		defined[i] = charset.IsSet()
+	}
+
+	for i, mask := range s.InlineMasks {
+		if err := ValidateMask(mask, defined); err != nil {
+			errs = append(errs, fmt.Sprintf("inline_masks[%d]: %s", i, err))
+		}
+	}
+
+	if !s.Increment {
+		if s.IncrementMin != nil || s.IncrementMax != nil {
+			errs = append(errs, "increment_min and increment_max require increment to be set")
+		}
+		return errs
+	}
+
+	if s.IncrementMin != nil && *s.IncrementMin < 1 || s.IncrementMax != nil && *s.IncrementMax < 1 {
+		errs = append(errs, "increment_min and increment_max must be greater than 0")
+	} else if s.IncrementMin != nil && s.IncrementMax != nil && *s.IncrementMin > *s.IncrementMax {
+		errs = append(errs, "increment_min must not be greater than increment_max")
+	}
+	return errs
+}
+
+// EngineFiles returns the IDs of the engine files used by the task
+func (s HashcatUserOptions) EngineFiles() []string {
+	fileIDs := []*string{s.DictionaryFile, s.RightDictionaryFile, s.ManglingRuleFile, s.Masks}
+	for i := range s.CustomCharsets {
+		fileIDs = append(fileIDs, &s.CustomCharsets[i].FileID)
+	}
+	return engineFileIDs(fileIDs...)
+}
+
+// GetHashType returns the hashcat mode of the hashes being cracked
+func (s HashcatUserOptions) GetHashType() int {
+	return s.HashType
 }
 
 // HModeInfo describes the hashcat mode
@@ -25,3 +211,14 @@ type HModeInfo struct {
 	Name    string `json:"name"`
 	Example string `json:"example,omitempty"`
 }
+
+// engineFileIDs returns every engine file ID that is set
+func engineFileIDs(fileIDs ...*string) []string {
+	out := make([]string, 0, len(fileIDs))
+	for _, fileID := range fileIDs {
+		if fileID != nil && *fileID != "" {
+			out = append(out, *fileID)
+		}
+	}
+	return out
+}
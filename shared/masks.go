@@ -0,0 +1,124 @@
+package shared
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// maxMaskLength is the longest password hashcat can generate from a mask
+const maxMaskLength = 256
+
+// builtinCharsets are the charsets hashcat defines (e.g. ?l is every lowercase letter)
+const builtinCharsets = "lLuUdhHsab"
+
+// CustomCharset is one of hashcat's four user defined charsets (-1 to -4) referenced in masks as ?1 to ?4.
+// The charset is either set inline (e.g. "?l?d_") or read from a .hcchr engine file
+type CustomCharset struct {
+	Charset string `json:"charset,omitempty"`
+	FileID  string `json:"file_id,omitempty"`
+}
+
+// IsSet returns true if the charset has been defined
+func (s CustomCharset) IsSet() bool {
+	return s.Charset != "" || s.FileID != ""
+}
+
+// ValidateCharset checks the placeholders of an inline custom charset. Custom charsets may only reference hashcat's
+// built-in charsets
+func ValidateCharset(charset string) error {
+	for i := 0; i < len(charset); i++ {
+		if charset[i] != '?' {
+			continue
+		}
+
+		if i+1 == len(charset) {
+			return errors.New("charset ends with an incomplete placeholder")
+		}
+
+		i++
+		if charset[i] != '?' && !strings.ContainsRune(builtinCharsets, rune(charset[i])) {
+			return fmt.Errorf("?%c is not a built-in charset", charset[i])
+		}
+	}
+	return nil
+}
+
+// ValidateMask checks the syntax of a hashcat mask. defined contains the custom charsets (?1 to ?4) that have been
+// set and may be used by the mask
+func ValidateMask(mask string, defined [4]bool) error {
+	if mask == "" {
+		return errors.New("mask must not be empty")
+	}
+
+	positions := 0
+	for i := 0; i < len(mask); i++ {
+		positions++
+		if mask[i] != '?' {
+			continue
+		}
+
+		if i+1 == len(mask) {
+			return errors.New("mask ends with an incomplete placeholder")
+		}
+
+		i++
+		switch c := mask[i]; {
+		case c == '?' || strings.ContainsRune(builtinCharsets, rune(c)):
+		case c >= '1' && c <= '4':
+			if !defined[c-'1'] {
+				return fmt.Errorf("?%c is used but custom charset %c is not set", c, c)
+			}
+		default:
+			return fmt.Errorf("?%c is not a valid charset", c)
+		}
+	}
+
+	if positions > maxMaskLength {
+		return fmt.Errorf("mask must not be longer than %d characters", maxMaskLength)
+	}
+	return nil
+}
+
+// ValidateHcmaskLine checks a line of a hashcat mask file. A line contains up to four custom charsets separated by
+// commas followed by the mask (e.g. "?l?d,?1?1?1?1"). Comments & empty lines are valid
+func ValidateHcmaskLine(line string) error {
+	if line == "" || strings.HasPrefix(line, "#") {
+		return nil
+	}
+
+	fields := splitHcmaskLine(line)
+	if len(fields) > 5 {
+		return errors.New("a mask may only be preceded by up to 4 custom charsets")
+	}
+
+	var defined [4]bool
+	for i, charset := range fields[:len(fields)-1] {
+		// the charset may be the path of a .hcchr file on the worker
+		if err := ValidateCharset(charset); err != nil {
+			return fmt.Errorf("custom charset %d: %w", i+1, err)
+		}
+		defined[i] = charset != ""
+	}
+	return ValidateMask(fields[len(fields)-1], defined)
+}
+
+// splitHcmaskLine splits the line on the commas that have not been escaped with a backslash
+func splitHcmaskLine(line string) []string {
+	var fields []string
+	var cur strings.Builder
+
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && i+1 < len(line) && line[i+1] == ',':
+			cur.WriteByte(',')
+			i++
+		case line[i] == ',':
+			fields = append(fields, cur.String())
+			cur.Reset()
+		default:
+			cur.WriteByte(line[i])
+		}
+	}
+	return append(fields, cur.String())
+}
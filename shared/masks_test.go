@@ -0,0 +1,50 @@
+package shared
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateMask(t *testing.T) {
+	defined := [4]bool{true, false, false, false}
+
+	for mask, valid := range map[string]bool{
+		"?u?l?l?l?d?d":     true,
+		"Summer?d?d?d?d":   true,
+		"??literal":        true,
+		"?1?1?1?H?h?b?s?a": true,
+		"?2?d":             false,
+		"?x":               false,
+		"abc?":             false,
+		"":                 false,
+	} {
+		err := ValidateMask(mask, defined)
+		assert.Equal(t, valid, err == nil, mask)
+	}
+}
+
+func TestValidateCharset(t *testing.T) {
+	assert.Nil(t, ValidateCharset("?l?d_-"))
+	assert.Nil(t, ValidateCharset("??"))
+	assert.NotNil(t, ValidateCharset("?1"))
+	assert.NotNil(t, ValidateCharset("ab?"))
+}
+
+func TestValidateHcmaskLine(t *testing.T) {
+	for line, valid := range map[string]bool{
+		"":                       true,
+		"# ?z is ignored":        true,
+		"?d?d?d":                 true,
+		"?l?d,?1?1?1":            true,
+		"?l,?u,?2?1":             true,
+		"charsets/de.hcchr,?1?1": true,
+		"?l,?2?1":                false,
+		"a,b,c,d,e,?1":           false,
+		"?l\\,?d":                true,
+		"?z,?1":                  false,
+	} {
+		err := ValidateHcmaskLine(line)
+		assert.Equal(t, valid, err == nil, line)
+	}
+}
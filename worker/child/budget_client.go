@@ -0,0 +1,31 @@
+package child
+
+import (
+	"sync/atomic"
+
+	"github.com/mandiant/gocrack/server/rpc"
+	"github.com/mandiant/gocrack/server/storage"
+)
+
+// budgetClient reports the task as out of time rather than stopped once its time budget has run out so the server can
+// tell it apart from a task stopped by a user
+type budgetClient struct {
+	expired int32
+	rpc.GoCrackRPC
+}
+
+func newBudgetClient(c rpc.GoCrackRPC) *budgetClient {
+	return &budgetClient{GoCrackRPC: c}
+}
+
+// expire marks the time budget of the task as used up
+func (s *budgetClient) expire() {
+	atomic.StoreInt32(&s.expired, 1)
+}
+
+func (s *budgetClient) ChangeTaskStatus(req rpc.ChangeTaskStatusRequest) error {
+	if req.NewStatus == storage.TaskStatusStopped && atomic.LoadInt32(&s.expired) == 1 {
+		req.NewStatus = storage.TaskStatusOutOfTime
+	}
+	return s.GoCrackRPC.ChangeTaskStatus(req)
+}
@@ -0,0 +1,42 @@
+package child
+
+import (
+	"testing"
+
+	"github.com/mandiant/gocrack/server/rpc"
+	"github.com/mandiant/gocrack/server/storage"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// statusRecorder records the statuses sent to the server
+type statusRecorder struct {
+	rpc.GoCrackRPC
+	statuses []storage.TaskStatus
+}
+
+func (s *statusRecorder) ChangeTaskStatus(req rpc.ChangeTaskStatusRequest) error {
+	s.statuses = append(s.statuses, req.NewStatus)
+	return nil
+}
+
+func TestBudgetClientChangeTaskStatus(t *testing.T) {
+	recorder := &statusRecorder{}
+	c := newBudgetClient(recorder)
+
+	// a task stopped before its budget runs out was stopped by a user
+	assert.Nil(t, c.ChangeTaskStatus(rpc.ChangeTaskStatusRequest{TaskID: "task", NewStatus: storage.TaskStatusRunning}))
+	assert.Nil(t, c.ChangeTaskStatus(rpc.ChangeTaskStatusRequest{TaskID: "task", NewStatus: storage.TaskStatusStopped}))
+
+	c.expire()
+	assert.Nil(t, c.ChangeTaskStatus(rpc.ChangeTaskStatusRequest{TaskID: "task", NewStatus: storage.TaskStatusStopped}))
+	// other statuses are sent as is
+	assert.Nil(t, c.ChangeTaskStatus(rpc.ChangeTaskStatusRequest{TaskID: "task", NewStatus: storage.TaskStatusError}))
+
+	assert.Equal(t, []storage.TaskStatus{
+		storage.TaskStatusRunning,
+		storage.TaskStatusStopped,
+		storage.TaskStatusOutOfTime,
+		storage.TaskStatusError,
+	}, recorder.statuses)
+}
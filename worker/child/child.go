@@ -15,16 +15,18 @@ import (
 type Worker struct {
 	// Unexported fields below
 	taskid  string
+	unitid  string
 	devices []int
 	cfg     *worker.Config
 	rc      rpc.GoCrackRPC
 	t       *Task
 }
 
-// New instantiates the child worker process
-func New(cfg *worker.Config, taskid string, devices []int) *Worker {
+// New instantiates the child worker process. If unitid is set, only that work unit of the distributed task is processed
+func New(cfg *worker.Config, taskid, unitid string, devices []int) *Worker {
 	return &Worker{
 		taskid:  taskid,
+		unitid:  unitid,
 		devices: devices,
 		cfg:     cfg,
 	}
@@ -32,17 +34,24 @@ func New(cfg *worker.Config, taskid string, devices []int) *Worker {
 
 // Start the processing of the task
 func (s *Worker) Start() error {
-	client, err := worker.InitRPCChannel(*s.cfg)
+	conn, err := worker.InitRPCChannel(*s.cfg)
 	if err != nil {
 		return err
 	}
+
+	hostname, _ := os.Hostname()
+	var client rpc.GoCrackRPC = newHostClient(conn, hostname, s.devices)
+	if s.unitid != "" {
+		client = newUnitClient(client, s.unitid)
+	}
+	budget := newBudgetClient(client)
+	client = budget
 	s.rc = client
 
 	defer func() {
 		if r := recover(); r != nil {
 			if client != nil {
 				// we dont really care about the error here...
-				hostname, _ := os.Hostname()
 				errStr := fmt.Sprintf("A panic occurred. Check logs on %s for more details", hostname)
 				client.ChangeTaskStatus(rpc.ChangeTaskStatusRequest{
 					TaskID:    s.taskid,
@@ -59,11 +68,13 @@ func (s *Worker) Start() error {
 		TaskID: s.t.taskid,
 	})
 
-	if resp.TaskDuration != 0 { //If the task duration is 0 (not set), we don't run the timer
+	// TaskDuration is what's left of the time budget of the task. If it's 0 (not set), we don't run the timer
+	if resp.TaskDuration != 0 {
 		timer := time.NewTimer(time.Second * time.Duration(resp.TaskDuration))
 		go func() {
 			<-timer.C
-			log.Warn().Msg("Timer expired, stopping task")
+			log.Warn().Msg("Time budget of the task has run out, stopping task")
+			budget.expire()
 			s.t.Stop()
 			timer.Stop()
 		}()
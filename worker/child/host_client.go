@@ -0,0 +1,28 @@
+package child
+
+import (
+	"github.com/mandiant/gocrack/server/rpc"
+	"github.com/mandiant/gocrack/server/storage"
+)
+
+// hostClient tags the status changes of the engine with the host & devices running the task so the server can record
+// who changed the status in the task's history
+type hostClient struct {
+	hostname string
+	devices  storage.CLDevices
+	rpc.GoCrackRPC
+}
+
+func newHostClient(c rpc.GoCrackRPC, hostname string, devices []int) *hostClient {
+	return &hostClient{
+		hostname:   hostname,
+		devices:    devices,
+		GoCrackRPC: c,
+	}
+}
+
+func (s *hostClient) ChangeTaskStatus(req rpc.ChangeTaskStatusRequest) error {
+	req.Hostname = s.hostname
+	req.Devices = s.devices
+	return s.GoCrackRPC.ChangeTaskStatus(req)
+}
@@ -1,9 +1,7 @@
 package child
 
 import (
-	"encoding/json"
 	"errors"
-	"fmt"
 	"io"
 	"os"
 	"path/filepath"
@@ -12,10 +10,9 @@ import (
 
 	"github.com/mandiant/gocrack/server/rpc"
 	"github.com/mandiant/gocrack/server/storage"
-	"github.com/mandiant/gocrack/shared"
+	"github.com/mandiant/gocrack/shared/enginedef"
 	"github.com/mandiant/gocrack/worker"
 	"github.com/mandiant/gocrack/worker/engines"
-	"github.com/mandiant/gocrack/worker/engines/hashcat"
 
 	"github.com/rs/zerolog/log"
 )
@@ -43,6 +40,11 @@ func NewTask(taskid string, devices []int, cfg *worker.Config, c rpc.GoCrackRPC)
 	}
 }
 
+// taskFileDir is the directory the task's own copy of its task file is saved in
+func (t *Task) taskFileDir() string {
+	return filepath.Join(t.cfg.SaveTaskFilePath, t.taskid)
+}
+
 // DownloadFile grabs a file from the server and stores it in the appropriate folder
 func (t *Task) DownloadFile(fileid string, filetype rpc.FileType) (string, error) {
 	var fp string
@@ -51,7 +53,12 @@ func (t *Task) DownloadFile(fileid string, filetype rpc.FileType) (string, error
 	case rpc.FileTypeEngine:
 		fp = filepath.Join(t.cfg.SaveEngineFilePath, fileid)
 	case rpc.FileTypeTask:
-		fp = filepath.Join(t.cfg.SaveTaskFilePath, fileid)
+		// the server removes the hashes a task was credited with from its task file so each task gets its own copy
+		dir := t.taskFileDir()
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return "", err
+		}
+		fp = filepath.Join(dir, fileid)
 	default:
 		return "", errors.New("unknown file type")
 	}
@@ -71,6 +78,7 @@ func (t *Task) DownloadFile(fileid string, filetype rpc.FileType) (string, error
 	filebody, serverHash, err := t.c.GetFile(rpc.TaskFileGetRequest{
 		FileID: fileid,
 		Type:   filetype,
+		TaskID: t.taskid,
 	})
 	if err != nil {
 		return "", err
@@ -136,11 +144,18 @@ func (t *Task) sendPeriodicStatus(engine storage.WorkerCrackEngine) {
 				continue
 			}
 
-			if err := t.c.SendTaskStatus(rpc.TaskStatusUpdate{
+			update := rpc.TaskStatusUpdate{
 				TaskID:  t.taskid,
 				Payload: status,
 				Engine:  engine,
-			}); err != nil {
+			}
+
+			if pr, ok := t.impl.(engines.ProgressReporter); ok {
+				progress := pr.Progress()
+				update.Progress = &progress
+			}
+
+			if err := t.c.SendTaskStatus(update); err != nil {
 				log.Error().Err(err).Msg("Failed to send task status update to server")
 			}
 		}
@@ -167,59 +182,42 @@ func (t *Task) Start() error {
 	if err != nil {
 		return err
 	}
-	defer os.Remove(taskFilePath)
+	defer os.RemoveAll(t.taskFileDir())
 
 	log.Debug().Str("task_file", taskFilePath).Msg("Downloaded Task File to temporary directory")
 
-	switch resp.Engine {
-	case storage.WorkerHashcatEngine:
-		var hashcatOpts shared.HashcatUserOptions
-		if err := json.Unmarshal(resp.EnginePayload, &hashcatOpts); err != nil {
-			return err
-		}
-		hc := &hashcat.HashcatEngine{
-			TaskID:            t.taskid,
-			SessionPath:       t.cfg.Hashcat.SessionPath,
-			HashcatSharedPath: t.cfg.Hashcat.SharedPath,
-			TaskFilePath:      taskFilePath,
-			Options:           hashcatOpts,
-			CLDevices:         t.devices,
-			Upstream:          t.c,
-		}
-
-		if hashcatOpts.DictionaryFile != nil {
-			dictFilePath, err := t.DownloadFile(*hashcatOpts.DictionaryFile, rpc.FileTypeEngine)
-			if err != nil {
-				return err
-			}
-			hc.DictionaryFile = dictFilePath
-		}
+	payload, err := enginedef.DecodePayload(resp.Engine, resp.EnginePayload)
+	if err != nil {
+		return err
+	}
 
-		if hashcatOpts.Masks != nil {
-			masksFilePath, err := t.DownloadFile(*hashcatOpts.Masks, rpc.FileTypeEngine)
-			if err != nil {
-				return err
-			}
-			hc.MasksFile = masksFilePath
+	engineFiles := make(map[string]string)
+	for _, fileID := range payload.EngineFiles() {
+		fp, err := t.DownloadFile(fileID, rpc.FileTypeEngine)
+		if err != nil {
+			return err
 		}
+		engineFiles[fileID] = fp
+	}
 
-		if hashcatOpts.ManglingRuleFile != nil {
-			manglingRuleFile, err := t.DownloadFile(*hashcatOpts.ManglingRuleFile, rpc.FileTypeEngine)
-			if err != nil {
-				return err
-			}
-			hc.RulesFile = manglingRuleFile
-		}
-		t.impl = hc
-	default:
-		return fmt.Errorf("unknown engine %d", resp.Engine)
+	if t.impl, err = engines.New(resp.Engine, engines.TaskContext{
+		TaskID:       t.taskid,
+		TaskFilePath: taskFilePath,
+		Payload:      payload,
+		EngineFiles:  engineFiles,
+		Devices:      t.devices,
+		Upstream:     t.c,
+		Config:       t.cfg,
+		WorkUnit:     resp.WorkUnit,
+	}); err != nil {
+		return err
 	}
 
 	if err = t.impl.Initialize(); err != nil {
 		return err
 	}
 
-	t.wg.Add(2) // periodic status goroutine + 1 indicating hashcat is running
+	t.wg.Add(2) // periodic status goroutine + 1 indicating the engine is running
 	go t.sendPeriodicStatus(resp.Engine)
 	defer func() {
 		t.impl.Cleanup()
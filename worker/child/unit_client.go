@@ -0,0 +1,44 @@
+package child
+
+import (
+	"github.com/mandiant/gocrack/server/rpc"
+)
+
+// unitClient tags the task related RPC calls of the engine with the work unit being processed so the server can track
+// each slice of a distributed task independently
+type unitClient struct {
+	unitid string
+	rpc.GoCrackRPC
+}
+
+func newUnitClient(c rpc.GoCrackRPC, unitid string) *unitClient {
+	return &unitClient{
+		unitid:     unitid,
+		GoCrackRPC: c,
+	}
+}
+
+func (s *unitClient) ChangeTaskStatus(req rpc.ChangeTaskStatusRequest) error {
+	req.WorkUnitID = s.unitid
+	return s.GoCrackRPC.ChangeTaskStatus(req)
+}
+
+func (s *unitClient) GetTask(req rpc.RequestTaskPayload) (*rpc.NewTaskPayloadResponse, error) {
+	req.WorkUnitID = s.unitid
+	return s.GoCrackRPC.GetTask(req)
+}
+
+func (s *unitClient) SendTaskStatus(req rpc.TaskStatusUpdate) error {
+	req.WorkUnitID = s.unitid
+	return s.GoCrackRPC.SendTaskStatus(req)
+}
+
+// GetCheckpointFile never returns a checkpoint as they are saved per task and a unit may be reissued to any worker
+func (s *unitClient) GetCheckpointFile(string) ([]byte, error) {
+	return nil, rpc.ErrNoCheckpoint
+}
+
+// SendCheckpointFile discards the checkpoint. A stopped unit is processed from the beginning when it's reissued
+func (s *unitClient) SendCheckpointFile(rpc.TaskCheckpointSaveRequest) error {
+	return nil
+}
@@ -2,6 +2,8 @@ package worker
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/mandiant/gocrack/shared"
@@ -38,6 +40,11 @@ type Config struct {
 		PotfilePath string `yaml:"potfile_path"`
 		SessionPath string `yaml:"session_path"`
 		SharedPath  string `yaml:"shared_path"`
+		// Tuning sets the defaults of the tuning options tasks do not set and the highest values tasks may use on this worker
+		Tuning struct {
+			Defaults shared.HashcatTuningOptions  `yaml:"defaults,omitempty"`
+			Ceilings shared.HashcatTuningCeilings `yaml:"ceilings,omitempty"`
+		} `yaml:"tuning,omitempty"`
 	} `yaml:"hashcat"`
 	Intervals struct {
 		// Beacon interval sets how frequently we beacon to the server with an overview of what we're doing
@@ -72,6 +79,14 @@ func (s *Config) Validate() error {
 		return errors.New("server.ssl_certficate and server.ssl_private_key must not be empty")
 	}
 
+	if errs := s.Hashcat.Tuning.Defaults.Validate(); len(errs) > 0 {
+		return fmt.Errorf("hashcat.tuning.defaults: %s", strings.Join(errs, ", "))
+	}
+
+	if err := s.Hashcat.Tuning.Ceilings.Validate(); err != nil {
+		return err
+	}
+
 	if s.Intervals.Beacon == nil {
 		s.Intervals.Beacon = defBeaconInterval
 	}
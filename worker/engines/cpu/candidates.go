@@ -0,0 +1,148 @@
+package cpu
+
+import (
+	"bufio"
+	"errors"
+	"math"
+	"os"
+	"sort"
+	"strings"
+)
+
+// candidateSource generates the password candidates of an attack. Every candidate has a stable position within
+// the keyspace so that a task can be checkpointed and resumed
+type candidateSource interface {
+	Keyspace() uint64
+	// Generate calls fn for every candidate in [start, end). fn must not retain the candidate and may return false to stop early
+	Generate(start, end uint64, fn func(candidate []byte) bool)
+}
+
+// lineRange selects the lines of a dictionary or mask file that belong to a work unit. A zero limit selects every line after skip
+type lineRange struct {
+	skip  uint64
+	limit uint64
+}
+
+func (s lineRange) contains(line uint64) bool {
+	return line >= s.skip && (s.limit == 0 || line-s.skip < s.limit)
+}
+
+// isSet returns true if the range restricts the lines of the file
+func (s lineRange) isSet() bool {
+	return s.skip != 0 || s.limit != 0
+}
+
+// dictionarySource applies every rule to every word in the dictionary. Position p is word p / len(rules) mangled by rule p % len(rules)
+type dictionarySource struct {
+	words [][]byte
+	rules []Rule
+}
+
+func newDictionarySource(dictionaryPath, rulesPath string, lines lineRange) (*dictionarySource, error) {
+	rules, err := LoadRules(rulesPath)
+	if err != nil {
+		return nil, err
+	}
+
+	fd, err := os.Open(dictionaryPath)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+
+	words := make([][]byte, 0)
+	lineNo := uint64(0)
+	scanner := bufio.NewScanner(fd)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for ; scanner.Scan(); lineNo++ {
+		if !lines.contains(lineNo) {
+			continue
+		}
+
+		word := strings.TrimRight(scanner.Text(), "\r")
+		if len(word) > maxCandidateLength {
+			continue
+		}
+		words = append(words, []byte(word))
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// the last work unit of a file may not contain any words
+	if len(words) == 0 && !lines.isSet() {
+		return nil, errors.New("dictionary file does not contain any words")
+	}
+
+	if uint64(len(words)) > math.MaxUint64/uint64(len(rules)) {
+		return nil, errKeyspaceOverflow
+	}
+
+	return &dictionarySource{
+		words: words,
+		rules: rules,
+	}, nil
+}
+
+func (s *dictionarySource) Keyspace() uint64 {
+	return uint64(len(s.words)) * uint64(len(s.rules))
+}
+
+func (s *dictionarySource) Generate(start, end uint64, fn func([]byte) bool) {
+	nrules := uint64(len(s.rules))
+	for pos := start; pos < end; pos++ {
+		if !fn(s.rules[pos%nrules].Apply(s.words[pos/nrules])) {
+			return
+		}
+	}
+}
+
+// maskSource enumerates the masks in order. offsets[i] is the position of the first candidate of masks[i]
+type maskSource struct {
+	masks    []*Mask
+	offsets  []uint64
+	keyspace uint64
+}
+
+func newMaskSource(masksPath string, lines lineRange) (*maskSource, error) {
+	masks, err := loadMasks(masksPath, lines)
+	if err != nil {
+		return nil, err
+	}
+
+	src := &maskSource{
+		masks:   masks,
+		offsets: make([]uint64, len(masks)),
+	}
+
+	for i, mask := range masks {
+		if src.keyspace > math.MaxUint64-mask.Keyspace() {
+			return nil, errKeyspaceOverflow
+		}
+		src.offsets[i] = src.keyspace
+		src.keyspace += mask.Keyspace()
+	}
+	return src, nil
+}
+
+func (s *maskSource) Keyspace() uint64 {
+	return s.keyspace
+}
+
+func (s *maskSource) Generate(start, end uint64, fn func([]byte) bool) {
+	buf := make([]byte, 0, maxCandidateLength)
+
+	// locate the mask containing start
+	idx := sort.Search(len(s.offsets), func(i int) bool { return s.offsets[i] > start }) - 1
+	for pos := start; pos < end && idx < len(s.masks); idx++ {
+		mask := s.masks[idx]
+		maskEnd := s.offsets[idx] + mask.Keyspace()
+
+		for ; pos < end && pos < maskEnd; pos++ {
+			if !fn(mask.Candidate(pos-s.offsets[idx], buf)) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,392 @@
+package cpu
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mandiant/gocrack/server/rpc"
+	"github.com/mandiant/gocrack/server/storage"
+	"github.com/mandiant/gocrack/shared"
+
+	"github.com/rs/zerolog/log"
+)
+
+// EngineVersion is the version of the CPU engine reported to the server
+const EngineVersion = "1.0.0"
+
+// chunkSize is the number of candidates a thread processes before the checkpoint position can advance
+const chunkSize = 1 << 14
+
+// checkpoint is the state sent to the server when the engine is stopped so that the task can be resumed
+type checkpoint struct {
+	AttackMode shared.HashcatAttackMode `json:"attack_mode"`
+	HashType   int                      `json:"hash_type"`
+	Keyspace   uint64                   `json:"keyspace"`
+	Position   uint64                   `json:"position"`
+}
+
+// CPUEngine implements engines.EngineImpl with a pure Go password cracker running on the CPU.
+// It supports a small number of hash types and is primarily intended as a reference engine and for hosts without OpenCL
+type CPUEngine struct {
+	TaskID string
+
+	// Task Options
+	TaskFilePath   string
+	DictionaryFile string
+	MasksFile      string
+	RulesFile      string
+	Options        shared.CPUUserOptions
+	Upstream       rpc.GoCrackRPC
+	// Threads is the number of goroutines used to crack the task. If 0, runtime.NumCPU() is used
+	Threads int
+	// Skip & Limit restrict the attack to a range of dictionary words or masks when the task is split into work units.
+	// A Limit of 0 attacks every word or mask after Skip
+	Skip  uint64
+	Limit uint64
+
+	algo    HashAlgorithm
+	hashes  *hashList
+	source  candidateSource
+	stop    chan struct{}
+	stopped int32
+	once    sync.Once
+
+	// progress tracking
+	mu        sync.Mutex
+	status    string
+	startedAt time.Time
+	completed map[uint64]uint64 // chunk start -> chunk end for chunks completed out of order
+	position  uint64            // every candidate before position has been tested
+	tested    uint64            // atomic; candidates tested by this run
+}
+
+// Initialize the CPU engine by loading the hashes and building the candidate generator for the attack
+func (s *CPUEngine) Initialize() error {
+	algo, err := GetHashAlgorithm(s.Options.HashType)
+	if err != nil {
+		return err
+	}
+	s.algo = algo
+
+	if s.hashes, err = loadHashList(algo, s.TaskFilePath); err != nil {
+		return err
+	}
+
+	switch s.Options.AttackMode {
+	case shared.AttackModeStraight:
+		if s.DictionaryFile == "" {
+			return errors.New("a dictionary file is required for a straight attack")
+		}
+		s.source, err = newDictionarySource(s.DictionaryFile, s.RulesFile, lineRange{s.Skip, s.Limit})
+	case shared.AttackModeBruteForce:
+		if s.MasksFile == "" {
+			return errors.New("a masks file is required for a brute force attack")
+		}
+		s.source, err = newMaskSource(s.MasksFile, lineRange{s.Skip, s.Limit})
+	default:
+		err = fmt.Errorf("attack mode %d is not supported by the cpu engine", s.Options.AttackMode)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	s.stop = make(chan struct{})
+	s.completed = make(map[uint64]uint64)
+	s.status = StatusInitializing
+	return nil
+}
+
+// restore loads the checkpoint from the server (if any) and returns the position to resume from
+func (s *CPUEngine) restore() (uint64, error) {
+	b, err := s.Upstream.GetCheckpointFile(s.TaskID)
+	if err != nil {
+		if err == rpc.ErrNoCheckpoint {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	var cp checkpoint
+	if err := json.Unmarshal(b, &cp); err != nil {
+		log.Warn().Err(err).Str("task_id", s.TaskID).Msg("Ignoring checkpoint that was not created by the cpu engine")
+		return 0, nil
+	}
+
+	if cp.AttackMode != s.Options.AttackMode || cp.HashType != s.Options.HashType || cp.Keyspace != s.source.Keyspace() || cp.Position > cp.Keyspace {
+		log.Warn().Str("task_id", s.TaskID).Msg("Ignoring checkpoint as it does not match the task")
+		return 0, nil
+	}
+
+	log.Debug().
+		Uint64("position", cp.Position).
+		Uint64("keyspace", cp.Keyspace).
+		Msg("Resuming task from checkpoint")
+	return cp.Position, nil
+}
+
+func (s *CPUEngine) saveCheckpoint() error {
+	s.mu.Lock()
+	cp := checkpoint{
+		AttackMode: s.Options.AttackMode,
+		HashType:   s.Options.HashType,
+		Keyspace:   s.source.Keyspace(),
+		Position:   s.position,
+	}
+	s.mu.Unlock()
+
+	b, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+
+	return s.Upstream.SendCheckpointFile(rpc.TaskCheckpointSaveRequest{
+		TaskID: s.TaskID,
+		Data:   b,
+	})
+}
+
+// markCompleted records a finished chunk and advances the checkpoint position past every contiguous completed chunk
+func (s *CPUEngine) markCompleted(start, end uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.completed[start] = end
+	for {
+		next, ok := s.completed[s.position]
+		if !ok {
+			return
+		}
+		delete(s.completed, s.position)
+		s.position = next
+	}
+}
+
+func (s *CPUEngine) isStopping() bool {
+	return atomic.LoadInt32(&s.stopped) == 1
+}
+
+func (s *CPUEngine) reportCracked(lines []string, candidate []byte) {
+	now := time.Now().UTC()
+	value := formatPlain(candidate)
+
+	for _, line := range lines {
+		if err := s.Upstream.SavedCrackedPassword(rpc.CrackedPasswordRequest{
+			TaskID:    s.TaskID,
+			Hash:      line,
+			Value:     value,
+			CrackedAt: now,
+		}); err != nil {
+			log.Error().Err(err).Str("task_id", s.TaskID).Msg("Failed to send cracked password to server")
+		}
+	}
+}
+
+// formatPlain encodes the plaintext the same way hashcat does when it contains unprintable characters
+func formatPlain(plain []byte) string {
+	printable := utf8.Valid(plain)
+	if printable {
+		for _, r := range string(plain) {
+			if !unicode.IsPrint(r) {
+				printable = false
+				break
+			}
+		}
+	}
+
+	if printable {
+		return string(plain)
+	}
+	return fmt.Sprintf("$HEX[%s]", hex.EncodeToString(plain))
+}
+
+func (s *CPUEngine) crack(chunks <-chan uint64, keyspace uint64, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	for start := range chunks {
+		end := start + chunkSize
+		if end > keyspace || end < start {
+			end = keyspace
+		}
+
+		finished := true
+		s.source.Generate(start, end, func(candidate []byte) bool {
+			if s.isStopping() {
+				finished = false
+				return false
+			}
+
+			if cracked := s.hashes.check(candidate); len(cracked) > 0 {
+				s.reportCracked(cracked, candidate)
+				if s.hashes.allCracked() {
+					s.Stop()
+				}
+			}
+			atomic.AddUint64(&s.tested, 1)
+			return true
+		})
+
+		if finished {
+			s.markCompleted(start, end)
+		}
+	}
+}
+
+// Start the CPU engine and block until the keyspace is exhausted, all hashes are cracked or the engine is stopped
+func (s *CPUEngine) Start() error {
+	start, err := s.restore()
+	if err != nil {
+		return err
+	}
+
+	threads := s.Threads
+	if threads <= 0 {
+		threads = runtime.NumCPU()
+	}
+
+	keyspace := s.source.Keyspace()
+
+	s.mu.Lock()
+	s.position = start
+	s.startedAt = time.Now().UTC()
+	s.status = StatusRunning
+	s.mu.Unlock()
+
+	chunks := make(chan uint64, threads)
+	wg := &sync.WaitGroup{}
+	for i := 0; i < threads; i++ {
+		wg.Add(1)
+		go s.crack(chunks, keyspace, wg)
+	}
+
+Dispatch:
+	for pos := start; pos < keyspace; pos += chunkSize {
+		select {
+		case chunks <- pos:
+		case <-s.stop:
+			break Dispatch
+		}
+
+		// guard against wrapping around on a keyspace near math.MaxUint64
+		if pos+chunkSize < pos {
+			break
+		}
+	}
+	close(chunks)
+	wg.Wait()
+
+	var tstatus storage.TaskStatus
+	switch {
+	case s.hashes.allCracked():
+		s.setStatus(StatusCracked)
+		tstatus = storage.TaskStatusFinished
+	case s.isStopping():
+		s.setStatus(StatusAbortedCheckpoint)
+		tstatus = storage.TaskStatusStopped
+
+		if err := s.saveCheckpoint(); err != nil {
+			log.Error().Err(err).Str("task_id", s.TaskID).Msg("Failed to save checkpoint")
+		}
+	default:
+		s.setStatus(StatusExhausted)
+		tstatus = storage.TaskStatusExhausted
+	}
+
+	if err := s.Upstream.ChangeTaskStatus(rpc.ChangeTaskStatusRequest{
+		TaskID:    s.TaskID,
+		NewStatus: tstatus,
+	}); err != nil {
+		log.Error().Err(err).Str("new_status", string(tstatus)).Msg("Failed to change task status")
+	}
+
+	if err := s.Upstream.SendTaskStatus(rpc.TaskStatusUpdate{
+		TaskID:  s.TaskID,
+		Payload: s.GetStatus(),
+		Engine:  storage.WorkerCPUEngine,
+		Final:   true,
+	}); err != nil {
+		log.Error().Err(err).Msg("Failed to send task status update to server")
+	}
+	return nil
+}
+
+func (s *CPUEngine) setStatus(status string) {
+	s.mu.Lock()
+	s.status = status
+	s.mu.Unlock()
+}
+
+// Stop the CPU engine. Candidates that have not been fully tested are retried when the task resumes from its checkpoint
+func (s *CPUEngine) Stop() error {
+	if s.stop == nil {
+		return nil
+	}
+
+	s.once.Do(func() {
+		atomic.StoreInt32(&s.stopped, 1)
+		close(s.stop)
+	})
+	return nil
+}
+
+// GetStatus returns the status of the engine
+func (s *CPUEngine) GetStatus() interface{} {
+	if s.hashes == nil {
+		return nil
+	}
+
+	recovered, total := s.hashes.counts()
+	tested := atomic.LoadUint64(&s.tested)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := &Status{
+		Status:          s.status,
+		HashType:        s.algo.Name,
+		StartedAt:       s.startedAt,
+		Progress:        s.position,
+		Keyspace:        s.source.Keyspace(),
+		RecoveredHashes: recovered,
+		TotalHashes:     total,
+	}
+
+	if !s.startedAt.IsZero() {
+		if elapsed := time.Since(s.startedAt).Seconds(); elapsed > 0 {
+			status.Speed = uint64(float64(tested) / elapsed)
+		}
+	}
+
+	if status.Keyspace > 0 {
+		status.ProgressPercent = float64(status.Progress) / float64(status.Keyspace) * 100
+	}
+	return status
+}
+
+// Progress returns the percentage of the keyspace that has been tested
+func (s *CPUEngine) Progress() float64 {
+	if s.source == nil {
+		return 0
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if keyspace := s.source.Keyspace(); keyspace > 0 {
+		return float64(s.position) / float64(keyspace) * 100
+	}
+	return 0
+}
+
+// Cleanup ensures the cracking goroutines have been signalled to exit
+func (s *CPUEngine) Cleanup() {
+	s.Stop()
+}
@@ -0,0 +1,275 @@
+package cpu
+
+import (
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/mandiant/gocrack/server/rpc"
+	"github.com/mandiant/gocrack/server/storage"
+	"github.com/mandiant/gocrack/shared"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeUpstream records everything the engine sends to the server
+type fakeUpstream struct {
+	mu         sync.Mutex
+	cracked    map[string]string
+	statuses   []storage.TaskStatus
+	final      *rpc.TaskStatusUpdate
+	checkpoint []byte
+}
+
+func (s *fakeUpstream) Beacon(rpc.BeaconRequest) (*rpc.BeaconResponse, error) { return nil, nil }
+func (s *fakeUpstream) GetTask(rpc.RequestTaskPayload) (*rpc.NewTaskPayloadResponse, error) {
+	return nil, nil
+}
+func (s *fakeUpstream) GetFile(rpc.TaskFileGetRequest) (io.ReadCloser, string, error) {
+	return nil, "", nil
+}
+
+func (s *fakeUpstream) ChangeTaskStatus(req rpc.ChangeTaskStatusRequest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.statuses = append(s.statuses, req.NewStatus)
+	return nil
+}
+
+func (s *fakeUpstream) SavedCrackedPassword(req rpc.CrackedPasswordRequest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cracked == nil {
+		s.cracked = make(map[string]string)
+	}
+	s.cracked[req.Hash] = req.Value
+	return nil
+}
+
+func (s *fakeUpstream) SendTaskStatus(req rpc.TaskStatusUpdate) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if req.Final {
+		s.final = &req
+	}
+	return nil
+}
+
+func (s *fakeUpstream) GetCheckpointFile(string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.checkpoint == nil {
+		return nil, rpc.ErrNoCheckpoint
+	}
+	return s.checkpoint, nil
+}
+
+func (s *fakeUpstream) SendCheckpointFile(req rpc.TaskCheckpointSaveRequest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checkpoint = req.Data
+	return nil
+}
+
+func writeTestFile(t *testing.T, dir, name string, lines ...string) string {
+	fp := filepath.Join(dir, name)
+	if err := os.WriteFile(fp, []byte(strings.Join(lines, "\n")+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return fp
+}
+
+func TestHashAlgorithms(t *testing.T) {
+	for _, test := range []struct {
+		Mode     int
+		Password string
+		Salt     string
+		Expected string
+	}{
+		{Mode: 0, Password: "password", Expected: "5f4dcc3b5aa765d61d8327deb882cf99"},
+		{Mode: 10, Password: "password", Salt: "salt", Expected: "b305cadbb3bce54f3aa59c64fec00dea"},
+		{Mode: 100, Password: "password", Expected: "5baa61e4c9b93f3f0682250b6cf8331b7ee68fd8"},
+		{Mode: 1000, Password: "password", Expected: "8846f7eaee8fb117ad06bdd830b7586c"},
+		{Mode: 1400, Password: "password", Expected: "5e884898da28047151d0e56f8dc6292773603d0d6aabbdd62a11ef721d1542d8"},
+	} {
+		algo, err := GetHashAlgorithm(test.Mode)
+		if !assert.Nil(t, err) {
+			continue
+		}
+		assert.Equalf(t, test.Expected, hex.EncodeToString(algo.Digest([]byte(test.Password), []byte(test.Salt))), "mode %d", test.Mode)
+	}
+
+	_, err := GetHashAlgorithm(3200)
+	assert.NotNil(t, err)
+}
+
+func TestCPUEngineDictionaryAttack(t *testing.T) {
+	dir := t.TempDir()
+	up := &fakeUpstream{}
+
+	engine := &CPUEngine{
+		TaskID: "test",
+		TaskFilePath: writeTestFile(t, dir, "hashes.txt",
+			"5f4dcc3b5aa765d61d8327deb882cf99", // password
+			"2ac9cb7dc02b3c0083eb70898e549b63", // Password1
+			"2AC9CB7DC02B3C0083EB70898E549B63", // Password1 with an uppercase digest
+		),
+		DictionaryFile: writeTestFile(t, dir, "dict.txt", "letmein", "password", "dragon"),
+		RulesFile:      writeTestFile(t, dir, "rules.txt", "# comment", ":", "c $1", "X"),
+		Options: shared.CPUUserOptions{
+			AttackMode: shared.AttackModeStraight,
+			HashType:   0,
+		},
+		Upstream: up,
+		Threads:  2,
+	}
+
+	assert.Nil(t, engine.Initialize())
+	assert.Nil(t, engine.Start())
+	engine.Cleanup()
+
+	assert.Equal(t, map[string]string{
+		"5f4dcc3b5aa765d61d8327deb882cf99": "password",
+		"2ac9cb7dc02b3c0083eb70898e549b63": "Password1",
+		"2AC9CB7DC02B3C0083EB70898E549B63": "Password1",
+	}, up.cracked)
+	assert.Equal(t, []storage.TaskStatus{storage.TaskStatusFinished}, up.statuses)
+	if assert.NotNil(t, up.final) {
+		assert.Equal(t, storage.WorkerCPUEngine, up.final.Engine)
+		assert.Equal(t, StatusCracked, up.final.Payload.(*Status).Status)
+	}
+}
+
+func TestCPUEngineMaskAttackExhausted(t *testing.T) {
+	dir := t.TempDir()
+	up := &fakeUpstream{}
+
+	engine := &CPUEngine{
+		TaskID: "test",
+		TaskFilePath: writeTestFile(t, dir, "hashes.txt",
+			"b305cadbb3bce54f3aa59c64fec00dea:salt", // password
+			hex.EncodeToString(algorithms[10].Digest([]byte("ab1"), []byte("NaCl")))+":NaCl",
+			hex.EncodeToString(algorithms[10].Digest([]byte("notinmask"), []byte("NaCl")))+":NaCl",
+		),
+		MasksFile: writeTestFile(t, dir, "masks.hcmask", "?l?l?d", "bcd,passwor?1"),
+		Options: shared.CPUUserOptions{
+			AttackMode: shared.AttackModeBruteForce,
+			HashType:   10,
+		},
+		Upstream: up,
+	}
+
+	assert.Nil(t, engine.Initialize())
+	assert.Nil(t, engine.Start())
+
+	values := make([]string, 0)
+	for _, value := range up.cracked {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+
+	assert.Equal(t, []string{"ab1", "password"}, values)
+	assert.Equal(t, []storage.TaskStatus{storage.TaskStatusExhausted}, up.statuses)
+
+	status := engine.GetStatus().(*Status)
+	assert.Equal(t, uint64(26*26*10+3), status.Keyspace)
+	assert.Equal(t, status.Keyspace, status.Progress)
+	assert.Equal(t, 2, status.RecoveredHashes)
+	assert.Equal(t, 3, status.TotalHashes)
+	assert.Nil(t, up.checkpoint)
+}
+
+func TestCPUEngineStopAndResume(t *testing.T) {
+	dir := t.TempDir()
+	up := &fakeUpstream{}
+
+	// zz9 is the last candidate of the mask so the first run can never reach it
+	hashFile := writeTestFile(t, dir, "hashes.txt", "e0df37ad690a0effed4b5accbe85f047")
+	masksFile := writeTestFile(t, dir, "masks.hcmask", "?l?l?d")
+
+	newEngine := func() *CPUEngine {
+		return &CPUEngine{
+			TaskID:       "test",
+			TaskFilePath: hashFile,
+			MasksFile:    masksFile,
+			Options: shared.CPUUserOptions{
+				AttackMode: shared.AttackModeBruteForce,
+				HashType:   0,
+			},
+			Upstream: up,
+			Threads:  1,
+		}
+	}
+
+	engine := newEngine()
+	assert.Nil(t, engine.Initialize())
+	// Stopping before the engine starts means no candidates are tested
+	engine.Stop()
+	assert.Nil(t, engine.Start())
+	assert.Equal(t, []storage.TaskStatus{storage.TaskStatusStopped}, up.statuses)
+	assert.JSONEq(t, `{"attack_mode":3,"hash_type":0,"keyspace":6760,"position":0}`, string(up.checkpoint))
+
+	// Pretend the first run made it part of the way through the keyspace
+	up.checkpoint = []byte(`{"attack_mode":3,"hash_type":0,"keyspace":6760,"position":6000}`)
+
+	engine = newEngine()
+	assert.Nil(t, engine.Initialize())
+	assert.Nil(t, engine.Start())
+	assert.Equal(t, map[string]string{"e0df37ad690a0effed4b5accbe85f047": "zz9"}, up.cracked)
+	assert.Equal(t, storage.TaskStatusFinished, up.statuses[len(up.statuses)-1])
+	assert.True(t, atomic.LoadUint64(&engine.tested) <= 760)
+}
+
+func TestCPUEngineWorkUnits(t *testing.T) {
+	dir := t.TempDir()
+	hashFile := writeTestFile(t, dir, "hashes.txt",
+		"5f4dcc3b5aa765d61d8327deb882cf99", // password
+		"0d107d09f5bbe40cade3de5c71e9e9b7", // letmein
+	)
+	dictFile := writeTestFile(t, dir, "dict.txt", "letmein", "dragon", "password", "monkey")
+
+	for _, test := range []struct {
+		Skip     uint64
+		Limit    uint64
+		Keyspace uint64
+		Cracked  map[string]string
+	}{
+		{Skip: 0, Limit: 2, Keyspace: 2, Cracked: map[string]string{"0d107d09f5bbe40cade3de5c71e9e9b7": "letmein"}},
+		{Skip: 2, Limit: 2, Keyspace: 2, Cracked: map[string]string{"5f4dcc3b5aa765d61d8327deb882cf99": "password"}},
+		// the file ends with a new line so the final unit may be empty
+		{Skip: 4, Limit: 1, Keyspace: 0, Cracked: nil},
+	} {
+		up := &fakeUpstream{}
+		engine := &CPUEngine{
+			TaskID:         "test",
+			TaskFilePath:   hashFile,
+			DictionaryFile: dictFile,
+			Options: shared.CPUUserOptions{
+				AttackMode: shared.AttackModeStraight,
+				HashType:   0,
+			},
+			Upstream: up,
+			Skip:     test.Skip,
+			Limit:    test.Limit,
+		}
+
+		assert.Nil(t, engine.Initialize())
+		assert.Equal(t, test.Keyspace, engine.source.Keyspace())
+		assert.Nil(t, engine.Start())
+		engine.Cleanup()
+
+		assert.Equal(t, test.Cracked, up.cracked, "skip %d", test.Skip)
+		assert.Equal(t, []storage.TaskStatus{storage.TaskStatusExhausted}, up.statuses, "skip %d", test.Skip)
+	}
+}
+
+func TestFormatPlain(t *testing.T) {
+	assert.Equal(t, "pässword", formatPlain([]byte("pässword")))
+	assert.Equal(t, "$HEX[7061737300]", formatPlain([]byte("pass\x00")))
+}
@@ -0,0 +1,209 @@
+package cpu
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf16"
+
+	"golang.org/x/crypto/md4"
+)
+
+// HashAlgorithm describes a hash type supported by the CPU engine. Modes use the same numbers as hashcat
+type HashAlgorithm struct {
+	Mode   int
+	Name   string
+	Salted bool
+	Size   int
+	digest func(password, salt []byte) []byte
+}
+
+var algorithms = map[int]HashAlgorithm{
+	0: {Mode: 0, Name: "MD5", Size: md5.Size, digest: func(password, _ []byte) []byte {
+		sum := md5.Sum(password)
+		return sum[:]
+	}},
+	10: {Mode: 10, Name: "md5($pass.$salt)", Salted: true, Size: md5.Size, digest: func(password, salt []byte) []byte {
+		h := md5.New()
+		h.Write(password)
+		h.Write(salt)
+		return h.Sum(nil)
+	}},
+	100: {Mode: 100, Name: "SHA1", Size: sha1.Size, digest: func(password, _ []byte) []byte {
+		sum := sha1.Sum(password)
+		return sum[:]
+	}},
+	1000: {Mode: 1000, Name: "NTLM", Size: md4.Size, digest: func(password, _ []byte) []byte {
+		h := md4.New()
+		for _, r := range utf16.Encode([]rune(string(password))) {
+			h.Write([]byte{byte(r), byte(r >> 8)})
+		}
+		return h.Sum(nil)
+	}},
+	1400: {Mode: 1400, Name: "SHA2-256", Size: sha256.Size, digest: func(password, _ []byte) []byte {
+		sum := sha256.Sum256(password)
+		return sum[:]
+	}},
+}
+
+// SupportedHashTypes returns all of the hash types supported by the CPU engine, ordered by mode
+func SupportedHashTypes() []HashAlgorithm {
+	out := make([]HashAlgorithm, 0, len(algorithms))
+	for _, algo := range algorithms {
+		out = append(out, algo)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Mode < out[j].Mode })
+	return out
+}
+
+// GetHashAlgorithm returns the algorithm for a hashcat mode number
+func GetHashAlgorithm(mode int) (HashAlgorithm, error) {
+	algo, ok := algorithms[mode]
+	if !ok {
+		return HashAlgorithm{}, fmt.Errorf("hash type %d is not supported by the cpu engine", mode)
+	}
+	return algo, nil
+}
+
+// Digest returns the raw digest of password (and salt if the algorithm is salted)
+func (s HashAlgorithm) Digest(password, salt []byte) []byte {
+	return s.digest(password, salt)
+}
+
+// hashList contains the uncracked hashes of a task, grouped by salt
+type hashList struct {
+	algo  HashAlgorithm
+	mu    sync.RWMutex
+	salts [][]byte
+	// bySalt maps salt -> digest -> the original lines from the task file
+	bySalt    map[string]map[string][]string
+	total     int
+	remaining int
+}
+
+// loadHashList reads the hashes of a task file. Unsalted hashes are one hex digest per line while salted hashes use `hash:salt`
+func loadHashList(algo HashAlgorithm, path string) (*hashList, error) {
+	fd, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+
+	hl := &hashList{
+		algo:   algo,
+		bySalt: make(map[string]map[string][]string),
+	}
+
+	lineNo := 0
+	scanner := bufio.NewScanner(fd)
+	for scanner.Scan() {
+		lineNo++
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		hexDigest, salt := line, ""
+		if algo.Salted {
+			idx := strings.Index(line, ":")
+			if idx == -1 {
+				return nil, fmt.Errorf("line %d: expected hash:salt", lineNo)
+			}
+			hexDigest, salt = line[:idx], line[idx+1:]
+		}
+
+		digest, err := hex.DecodeString(hexDigest)
+		if err != nil || len(digest) != algo.Size {
+			return nil, fmt.Errorf("line %d: not a valid %s hash", lineNo, algo.Name)
+		}
+
+		digests, ok := hl.bySalt[salt]
+		if !ok {
+			digests = make(map[string][]string)
+			hl.bySalt[salt] = digests
+			hl.salts = append(hl.salts, []byte(salt))
+		}
+
+		if len(digests[string(digest)]) == 0 {
+			hl.total++
+		}
+		digests[string(digest)] = append(digests[string(digest)], line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if hl.total == 0 {
+		return nil, fmt.Errorf("task file does not contain any %s hashes", algo.Name)
+	}
+	hl.remaining = hl.total
+	return hl, nil
+}
+
+// check hashes the candidate against every salt and returns the lines from the task file that it cracked.
+// Cracked hashes are removed from the list so they are only reported once
+func (s *hashList) check(candidate []byte) []string {
+	var cracked []string
+
+	s.mu.RLock()
+	salts := s.salts
+	s.mu.RUnlock()
+
+	for _, salt := range salts {
+		digest := s.algo.digest(candidate, salt)
+
+		s.mu.RLock()
+		_, found := s.bySalt[string(salt)][string(digest)]
+		s.mu.RUnlock()
+		if !found {
+			continue
+		}
+
+		s.mu.Lock()
+		if lines, ok := s.bySalt[string(salt)][string(digest)]; ok {
+			cracked = append(cracked, lines...)
+			delete(s.bySalt[string(salt)], string(digest))
+			s.remaining--
+
+			if len(s.bySalt[string(salt)]) == 0 {
+				delete(s.bySalt, string(salt))
+				s.removeSalt(salt)
+			}
+		}
+		s.mu.Unlock()
+	}
+	return cracked
+}
+
+// removeSalt must be called with the write lock held
+func (s *hashList) removeSalt(salt []byte) {
+	salts := make([][]byte, 0, len(s.salts))
+	for _, existing := range s.salts {
+		if !bytes.Equal(existing, salt) {
+			salts = append(salts, existing)
+		}
+	}
+	s.salts = salts
+}
+
+func (s *hashList) counts() (recovered, total int) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.total - s.remaining, s.total
+}
+
+func (s *hashList) allCracked() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.remaining == 0
+}
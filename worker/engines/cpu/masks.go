@@ -0,0 +1,231 @@
+package cpu
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+)
+
+var builtinCharsets = map[byte][]byte{
+	'l': []byte("abcdefghijklmnopqrstuvwxyz"),
+	'u': []byte("ABCDEFGHIJKLMNOPQRSTUVWXYZ"),
+	'd': []byte("0123456789"),
+	'h': []byte("0123456789abcdef"),
+	'H': []byte("0123456789ABCDEF"),
+	's': []byte(" !\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~"),
+}
+
+func init() {
+	all := make([]byte, 0, 95)
+	for _, cs := range []byte("luds") {
+		all = append(all, builtinCharsets[cs]...)
+	}
+	builtinCharsets['a'] = all
+
+	binary := make([]byte, 256)
+	for i := range binary {
+		binary[i] = byte(i)
+	}
+	builtinCharsets['b'] = binary
+}
+
+// errKeyspaceOverflow is returned when a mask's keyspace cannot be represented as a uint64
+var errKeyspaceOverflow = errors.New("mask keyspace is too large")
+
+// Mask is a parsed hashcat mask where each position contains the characters that may be used in it
+type Mask struct {
+	Raw       string
+	positions [][]byte
+	keyspace  uint64
+}
+
+// expandCharset expands the placeholders in a charset definition (e.g. `?l?d_`) into the characters it represents
+func expandCharset(def string, custom [4][]byte) ([]byte, error) {
+	var out []byte
+	seen := make(map[byte]bool)
+
+	add := func(chars []byte) {
+		for _, c := range chars {
+			if !seen[c] {
+				seen[c] = true
+				out = append(out, c)
+			}
+		}
+	}
+
+	for i := 0; i < len(def); i++ {
+		if def[i] != '?' {
+			add([]byte{def[i]})
+			continue
+		}
+
+		i++
+		if i >= len(def) {
+			return nil, errors.New("charset ends with an incomplete placeholder")
+		}
+
+		chars, err := lookupPlaceholder(def[i], custom)
+		if err != nil {
+			return nil, err
+		}
+		add(chars)
+	}
+	return out, nil
+}
+
+func lookupPlaceholder(c byte, custom [4][]byte) ([]byte, error) {
+	if c == '?' {
+		return []byte{'?'}, nil
+	}
+
+	if c >= '1' && c <= '4' {
+		chars := custom[c-'1']
+		if len(chars) == 0 {
+			return nil, fmt.Errorf("custom charset ?%c is not defined", c)
+		}
+		return chars, nil
+	}
+
+	chars, ok := builtinCharsets[c]
+	if !ok {
+		return nil, fmt.Errorf("unknown charset ?%c", c)
+	}
+	return chars, nil
+}
+
+// splitMaskLine splits a .hcmask line on unescaped commas
+func splitMaskLine(line string) []string {
+	var (
+		fields []string
+		cur    strings.Builder
+	)
+
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && i+1 < len(line) && line[i+1] == ',':
+			cur.WriteByte(',')
+			i++
+		case line[i] == ',':
+			fields = append(fields, cur.String())
+			cur.Reset()
+		default:
+			cur.WriteByte(line[i])
+		}
+	}
+	return append(fields, cur.String())
+}
+
+// ParseMask parses a single line of a hashcat mask file. Up to four custom charsets may precede the mask, separated by commas
+func ParseMask(line string) (*Mask, error) {
+	var custom [4][]byte
+
+	fields := splitMaskLine(line)
+	if len(fields) > 5 {
+		return nil, errors.New("mask lines may only define up to four custom charsets")
+	}
+
+	for i, def := range fields[:len(fields)-1] {
+		chars, err := expandCharset(def, custom)
+		if err != nil {
+			return nil, err
+		}
+		custom[i] = chars
+	}
+
+	raw := fields[len(fields)-1]
+	m := &Mask{Raw: raw, keyspace: 1}
+	for i := 0; i < len(raw); i++ {
+		chars := []byte{raw[i]}
+
+		if raw[i] == '?' {
+			i++
+			if i >= len(raw) {
+				return nil, errors.New("mask ends with an incomplete placeholder")
+			}
+
+			var err error
+			if chars, err = lookupPlaceholder(raw[i], custom); err != nil {
+				return nil, err
+			}
+		}
+
+		if m.keyspace > math.MaxUint64/uint64(len(chars)) {
+			return nil, errKeyspaceOverflow
+		}
+		m.keyspace *= uint64(len(chars))
+		m.positions = append(m.positions, chars)
+	}
+
+	if len(m.positions) == 0 {
+		return nil, errors.New("mask is empty")
+	}
+	return m, nil
+}
+
+// LoadMasks parses a hashcat mask (.hcmask) file. Comments and empty lines are ignored
+func LoadMasks(path string) ([]*Mask, error) {
+	return loadMasks(path, lineRange{})
+}
+
+// loadMasks parses the masks on the lines of the file selected by lines
+func loadMasks(path string, lines lineRange) ([]*Mask, error) {
+	fd, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+
+	masks := make([]*Mask, 0)
+	lineNo := 0
+	scanner := bufio.NewScanner(fd)
+	for scanner.Scan() {
+		lineNo++
+		if !lines.contains(uint64(lineNo - 1)) {
+			continue
+		}
+
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		mask, err := ParseMask(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", lineNo, err)
+		}
+		masks = append(masks, mask)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// the last work unit of a file may not contain any masks
+	if len(masks) == 0 && !lines.isSet() {
+		return nil, errors.New("mask file does not contain any masks")
+	}
+	return masks, nil
+}
+
+// Keyspace returns the number of candidates the mask generates
+func (s *Mask) Keyspace() uint64 {
+	return s.keyspace
+}
+
+// Candidate writes the candidate at index into buf and returns it. The right most position changes the fastest
+func (s *Mask) Candidate(index uint64, buf []byte) []byte {
+	buf = buf[:0]
+	for range s.positions {
+		buf = append(buf, 0)
+	}
+
+	for i := len(s.positions) - 1; i >= 0; i-- {
+		chars := s.positions[i]
+		buf[i] = chars[index%uint64(len(chars))]
+		index /= uint64(len(chars))
+	}
+	return buf
+}
@@ -0,0 +1,59 @@
+package cpu
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMask(t *testing.T) {
+	for _, test := range []struct {
+		Line     string
+		Keyspace uint64
+		First    string
+		Last     string
+	}{
+		{Line: "?l?l?d", Keyspace: 26 * 26 * 10, First: "aa0", Last: "zz9"},
+		{Line: "Pass?d?d", Keyspace: 100, First: "Pass00", Last: "Pass99"},
+		{Line: "?a", Keyspace: 95, First: "a", Last: "~"},
+		{Line: "?h?H", Keyspace: 256, First: "00", Last: "fF"},
+		{Line: "??", Keyspace: 1, First: "?", Last: "?"},
+		{Line: "abc,?1?1", Keyspace: 9, First: "aa", Last: "cc"},
+		{Line: "?d,?u,?1?2", Keyspace: 260, First: "0A", Last: "9Z"},
+		{Line: "\\,x,?1", Keyspace: 2, First: ",", Last: "x"},
+	} {
+		mask, err := ParseMask(test.Line)
+		if !assert.Nilf(t, err, "mask %q", test.Line) {
+			continue
+		}
+
+		assert.Equalf(t, test.Keyspace, mask.Keyspace(), "mask %q", test.Line)
+		assert.Equalf(t, test.First, string(mask.Candidate(0, nil)), "mask %q", test.Line)
+		assert.Equalf(t, test.Last, string(mask.Candidate(mask.Keyspace()-1, nil)), "mask %q", test.Line)
+	}
+}
+
+func TestParseMaskErrors(t *testing.T) {
+	for _, line := range []string{"", "?l?", "?x", "?1", "a,b,c,d,e,?1"} {
+		_, err := ParseMask(line)
+		assert.NotNilf(t, err, "expected mask %q to fail", line)
+	}
+}
+
+func TestMaskSourceGenerate(t *testing.T) {
+	src := &maskSource{}
+	for _, line := range []string{"?d", "x?d"} {
+		mask, err := ParseMask(line)
+		assert.Nil(t, err)
+		src.offsets = append(src.offsets, src.keyspace)
+		src.masks = append(src.masks, mask)
+		src.keyspace += mask.Keyspace()
+	}
+
+	var candidates []string
+	src.Generate(8, 12, func(candidate []byte) bool {
+		candidates = append(candidates, string(candidate))
+		return true
+	})
+	assert.Equal(t, []string{"8", "9", "x0", "x1"}, candidates)
+}
@@ -0,0 +1,42 @@
+package cpu
+
+import (
+	"github.com/mandiant/gocrack/server/storage"
+	"github.com/mandiant/gocrack/shared"
+	// the engine must be defined before its implementation is registered
+	_ "github.com/mandiant/gocrack/shared/enginedef/cpu"
+	"github.com/mandiant/gocrack/worker/engines"
+)
+
+func init() {
+	engines.RegisterImplementation(storage.WorkerCPUEngine, EngineVersion, newEngine)
+}
+
+func newEngine(ctx engines.TaskContext) (engines.EngineImpl, error) {
+	opts := ctx.Payload.(shared.CPUUserOptions)
+
+	ce := &CPUEngine{
+		TaskID:       ctx.TaskID,
+		TaskFilePath: ctx.TaskFilePath,
+		Options:      opts,
+		Upstream:     ctx.Upstream,
+	}
+
+	if ctx.WorkUnit != nil {
+		ce.Skip = ctx.WorkUnit.Skip
+		ce.Limit = ctx.WorkUnit.Limit
+	}
+
+	if opts.DictionaryFile != nil {
+		ce.DictionaryFile = ctx.EngineFiles[*opts.DictionaryFile]
+	}
+
+	if opts.Masks != nil {
+		ce.MasksFile = ctx.EngineFiles[*opts.Masks]
+	}
+
+	if opts.ManglingRuleFile != nil {
+		ce.RulesFile = ctx.EngineFiles[*opts.ManglingRuleFile]
+	}
+	return ce, nil
+}
@@ -0,0 +1,304 @@
+package cpu
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// maxCandidateLength is the longest candidate a rule is allowed to produce, mirroring hashcat's limit
+const maxCandidateLength = 256
+
+// ruleOp is a single function within a mangling rule (e.g. `$1`)
+type ruleOp struct {
+	fn   byte
+	args []byte
+}
+
+// Rule is a parsed hashcat compatible mangling rule
+type Rule []ruleOp
+
+// number of arguments each supported rule function takes
+var ruleArity = map[byte]int{
+	':': 0, 'l': 0, 'u': 0, 'c': 0, 'C': 0, 't': 0, 'r': 0, 'd': 0, 'f': 0,
+	'{': 0, '}': 0, '[': 0, ']': 0, 'q': 0, 'k': 0, 'K': 0,
+	'T': 1, 'p': 1, '$': 1, '^': 1, 'D': 1, '\'': 1, '@': 1, 'z': 1, 'Z': 1,
+	'x': 2, 'O': 2, 'i': 2, 'o': 2, 's': 2,
+}
+
+// functions whose first argument is a position rather than a character
+var rulePositional = map[byte]bool{
+	'T': true, 'p': true, 'D': true, '\'': true, 'z': true, 'Z': true,
+	'x': true, 'O': true, 'i': true, 'o': true,
+}
+
+// ParseRule parses a single line from a rule file
+func ParseRule(line string) (Rule, error) {
+	var rule Rule
+
+	for i := 0; i < len(line); {
+		fn := line[i]
+		i++
+
+		if fn == ' ' || fn == '\t' {
+			continue
+		}
+
+		arity, ok := ruleArity[fn]
+		if !ok {
+			return nil, fmt.Errorf("unsupported rule function %q", fn)
+		}
+
+		if i+arity > len(line) {
+			return nil, fmt.Errorf("rule function %q expects %d argument(s)", fn, arity)
+		}
+
+		op := ruleOp{fn: fn, args: []byte(line[i : i+arity])}
+		i += arity
+
+		if rulePositional[fn] {
+			if _, ok := rulePosition(op.args[0]); !ok {
+				return nil, fmt.Errorf("rule function %q has an invalid position %q", fn, op.args[0])
+			}
+			// x and O take two positions
+			if (fn == 'x' || fn == 'O') && !isRulePosition(op.args[1]) {
+				return nil, fmt.Errorf("rule function %q has an invalid length %q", fn, op.args[1])
+			}
+		}
+
+		rule = append(rule, op)
+	}
+	return rule, nil
+}
+
+// LoadRules parses a hashcat rule file. Comments and empty lines are ignored while unsupported rules are skipped.
+// If path is empty, a single no-op rule is returned
+func LoadRules(path string) ([]Rule, error) {
+	if path == "" {
+		return []Rule{{ruleOp{fn: ':'}}}, nil
+	}
+
+	fd, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+
+	rules := make([]Rule, 0)
+	lineNo := 0
+	scanner := bufio.NewScanner(fd)
+	for scanner.Scan() {
+		lineNo++
+
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rule, err := ParseRule(line)
+		if err != nil {
+			log.Warn().Err(err).Int("line", lineNo).Msg("Skipping rule unsupported by the cpu engine")
+			continue
+		}
+		rules = append(rules, rule)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("rule file does not contain any rules supported by the cpu engine")
+	}
+	return rules, nil
+}
+
+func isRulePosition(c byte) bool {
+	_, ok := rulePosition(c)
+	return ok
+}
+
+// rulePosition converts a hashcat position (0-9, A-Z) into an integer
+func rulePosition(c byte) (int, bool) {
+	switch {
+	case c >= '0' && c <= '9':
+		return int(c - '0'), true
+	case c >= 'A' && c <= 'Z':
+		return int(c-'A') + 10, true
+	}
+	return 0, false
+}
+
+func toLower(c byte) byte {
+	if c >= 'A' && c <= 'Z' {
+		return c + 32
+	}
+	return c
+}
+
+func toUpper(c byte) byte {
+	if c >= 'a' && c <= 'z' {
+		return c - 32
+	}
+	return c
+}
+
+func toggle(c byte) byte {
+	switch {
+	case c >= 'a' && c <= 'z':
+		return c - 32
+	case c >= 'A' && c <= 'Z':
+		return c + 32
+	}
+	return c
+}
+
+// Apply the rule to word, returning the new candidate. The input is never modified
+func (s Rule) Apply(word []byte) []byte {
+	out := make([]byte, len(word), len(word)*2+8)
+	copy(out, word)
+
+	for _, op := range s {
+		var n int
+		if rulePositional[op.fn] {
+			n, _ = rulePosition(op.args[0])
+		}
+
+		switch op.fn {
+		case ':':
+		case 'l':
+			for i := range out {
+				out[i] = toLower(out[i])
+			}
+		case 'u':
+			for i := range out {
+				out[i] = toUpper(out[i])
+			}
+		case 'c':
+			for i := range out {
+				if i == 0 {
+					out[i] = toUpper(out[i])
+				} else {
+					out[i] = toLower(out[i])
+				}
+			}
+		case 'C':
+			for i := range out {
+				if i == 0 {
+					out[i] = toLower(out[i])
+				} else {
+					out[i] = toUpper(out[i])
+				}
+			}
+		case 't':
+			for i := range out {
+				out[i] = toggle(out[i])
+			}
+		case 'T':
+			if n < len(out) {
+				out[n] = toggle(out[n])
+			}
+		case 'r':
+			for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+				out[i], out[j] = out[j], out[i]
+			}
+		case 'd':
+			out = append(out, out...)
+		case 'p':
+			base := append([]byte(nil), out...)
+			for i := 0; i < n; i++ {
+				out = append(out, base...)
+			}
+		case 'f':
+			for i := len(out) - 1; i >= 0; i-- {
+				out = append(out, out[i])
+			}
+		case '{':
+			if len(out) > 0 {
+				out = append(out[1:], out[0])
+			}
+		case '}':
+			if len(out) > 0 {
+				last := out[len(out)-1]
+				out = append([]byte{last}, out[:len(out)-1]...)
+			}
+		case '$':
+			out = append(out, op.args[0])
+		case '^':
+			out = append([]byte{op.args[0]}, out...)
+		case '[':
+			if len(out) > 0 {
+				out = out[1:]
+			}
+		case ']':
+			if len(out) > 0 {
+				out = out[:len(out)-1]
+			}
+		case 'D':
+			if n < len(out) {
+				out = append(out[:n], out[n+1:]...)
+			}
+		case 'x':
+			m, _ := rulePosition(op.args[1])
+			if n+m <= len(out) {
+				out = append([]byte(nil), out[n:n+m]...)
+			}
+		case 'O':
+			m, _ := rulePosition(op.args[1])
+			if n+m <= len(out) {
+				out = append(out[:n], out[n+m:]...)
+			}
+		case 'i':
+			if n <= len(out) {
+				out = append(out[:n], append([]byte{op.args[1]}, out[n:]...)...)
+			}
+		case 'o':
+			if n < len(out) {
+				out[n] = op.args[1]
+			}
+		case '\'':
+			if n < len(out) {
+				out = out[:n]
+			}
+		case 's':
+			for i := range out {
+				if out[i] == op.args[0] {
+					out[i] = op.args[1]
+				}
+			}
+		case '@':
+			out = bytes.ReplaceAll(out, op.args[:1], nil)
+		case 'z':
+			if len(out) > 0 {
+				out = append(bytes.Repeat(out[:1], n), out...)
+			}
+		case 'Z':
+			if len(out) > 0 {
+				out = append(out, bytes.Repeat(out[len(out)-1:], n)...)
+			}
+		case 'q':
+			doubled := make([]byte, 0, len(out)*2)
+			for _, c := range out {
+				doubled = append(doubled, c, c)
+			}
+			out = doubled
+		case 'k':
+			if len(out) >= 2 {
+				out[0], out[1] = out[1], out[0]
+			}
+		case 'K':
+			if l := len(out); l >= 2 {
+				out[l-1], out[l-2] = out[l-2], out[l-1]
+			}
+		}
+
+		if len(out) > maxCandidateLength {
+			out = out[:maxCandidateLength]
+		}
+	}
+	return out
+}
@@ -0,0 +1,70 @@
+package cpu
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRuleApply(t *testing.T) {
+	for _, test := range []struct {
+		Rule     string
+		Word     string
+		Expected string
+	}{
+		{Rule: ":", Word: "p@ssW0rd", Expected: "p@ssW0rd"},
+		{Rule: "l", Word: "p@ssW0rd", Expected: "p@ssw0rd"},
+		{Rule: "u", Word: "p@ssW0rd", Expected: "P@SSW0RD"},
+		{Rule: "c", Word: "p@ssW0rd", Expected: "P@ssw0rd"},
+		{Rule: "C", Word: "p@ssW0rd", Expected: "p@SSW0RD"},
+		{Rule: "t", Word: "p@ssW0rd", Expected: "P@SSw0RD"},
+		{Rule: "T3", Word: "p@ssW0rd", Expected: "p@sSW0rd"},
+		{Rule: "r", Word: "p@ssW0rd", Expected: "dr0Wss@p"},
+		{Rule: "d", Word: "p@ssW0rd", Expected: "p@ssW0rdp@ssW0rd"},
+		{Rule: "p2", Word: "abc", Expected: "abcabcabc"},
+		{Rule: "f", Word: "abc", Expected: "abccba"},
+		{Rule: "{", Word: "p@ssW0rd", Expected: "@ssW0rdp"},
+		{Rule: "}", Word: "p@ssW0rd", Expected: "dp@ssW0r"},
+		{Rule: "$1 $2", Word: "p@ssW0rd", Expected: "p@ssW0rd12"},
+		{Rule: "^2^1", Word: "p@ssW0rd", Expected: "12p@ssW0rd"},
+		{Rule: "[", Word: "p@ssW0rd", Expected: "@ssW0rd"},
+		{Rule: "]", Word: "p@ssW0rd", Expected: "p@ssW0r"},
+		{Rule: "D3", Word: "p@ssW0rd", Expected: "p@sW0rd"},
+		{Rule: "x04", Word: "p@ssW0rd", Expected: "p@ss"},
+		{Rule: "O12", Word: "p@ssW0rd", Expected: "psW0rd"},
+		{Rule: "i4!", Word: "p@ssW0rd", Expected: "p@ss!W0rd"},
+		{Rule: "o0$", Word: "p@ssW0rd", Expected: "$@ssW0rd"},
+		{Rule: "'4", Word: "p@ssW0rd", Expected: "p@ss"},
+		{Rule: "ss$", Word: "p@ssW0rd", Expected: "p@$$W0rd"},
+		{Rule: "@s", Word: "p@ssW0rd", Expected: "p@W0rd"},
+		{Rule: "z2", Word: "abc", Expected: "aaabc"},
+		{Rule: "Z2", Word: "abc", Expected: "abccc"},
+		{Rule: "q", Word: "abc", Expected: "aabbcc"},
+		{Rule: "k", Word: "abc", Expected: "bac"},
+		{Rule: "K", Word: "abc", Expected: "acb"},
+		{Rule: "c $2 $0 $2 $4", Word: "summer", Expected: "Summer2024"},
+		// out of bounds positions leave the word untouched
+		{Rule: "DA", Word: "abc", Expected: "abc"},
+		{Rule: "x25", Word: "abc", Expected: "abc"},
+	} {
+		rule, err := ParseRule(test.Rule)
+		assert.Nilf(t, err, "rule %q", test.Rule)
+		assert.Equalf(t, test.Expected, string(rule.Apply([]byte(test.Word))), "rule %q", test.Rule)
+	}
+}
+
+func TestParseRuleErrors(t *testing.T) {
+	for _, rule := range []string{"$", "sa", "Ta", "X", "x0a"} {
+		_, err := ParseRule(rule)
+		assert.NotNilf(t, err, "expected rule %q to fail", rule)
+	}
+}
+
+func TestRuleApplyDoesNotModifyWord(t *testing.T) {
+	word := []byte("password")
+	rule, err := ParseRule("u r $1")
+	assert.Nil(t, err)
+
+	assert.Equal(t, "DROWSSAP1", string(rule.Apply(word)))
+	assert.Equal(t, "password", string(word))
+}
@@ -0,0 +1,26 @@
+package cpu
+
+import "time"
+
+// Status values use the same wording as hashcat so they are displayed consistently
+const (
+	StatusInitializing      = "Initializing"
+	StatusRunning           = "Running"
+	StatusCracked           = "Cracked"
+	StatusExhausted         = "Exhausted"
+	StatusAbortedCheckpoint = "Aborted (Checkpoint)"
+)
+
+// Status is the periodic status of a task running in the CPU engine
+type Status struct {
+	Status          string    `json:"status"`
+	HashType        string    `json:"hash_type"`
+	StartedAt       time.Time `json:"started_at"`
+	Progress        uint64    `json:"progress"`
+	Keyspace        uint64    `json:"keyspace"`
+	ProgressPercent float64   `json:"progress_percent"`
+	RecoveredHashes int       `json:"recovered_hashes"`
+	TotalHashes     int       `json:"total_hashes"`
+	// Speed is the average number of candidates tested per second
+	Speed uint64 `json:"speed"`
+}
@@ -13,3 +13,10 @@ type EngineImpl interface {
 	// Cleanup is called after the engine stops and should release all resources
 	Cleanup()
 }
+
+// ProgressReporter is implemented by engines that can report how much of their keyspace has been processed.
+// It is used to calculate the overall progress of a task that has been split into work units
+type ProgressReporter interface {
+	// Progress returns the percentage (0-100) of the keyspace that has been processed
+	Progress() float64
+}
@@ -32,15 +32,27 @@ type HashcatEngine struct {
 	// Task Options
 	TaskFilePath   string
 	DictionaryFile string
-	MasksFile      string
-	RulesFile      string
-	Options        shared.HashcatUserOptions
-	Upstream       rpc.GoCrackRPC
-	CLDevices      storage.CLDevices
+	// RightDictionaryFile is the dictionary combined with DictionaryFile in a combinator attack
+	RightDictionaryFile string
+	MasksFile           string
+	RulesFile           string
+	Options             shared.HashcatUserOptions
+	Upstream            rpc.GoCrackRPC
+	CLDevices           storage.CLDevices
+	// Skip & Limit restrict the attack to a range of dictionary words or masks when the task is split into work units.
+	// A Limit of 0 attacks every word or mask after Skip
+	Skip  uint64
+	Limit uint64
+	// CustomCharsets are the values of -1 to -4; either an inline charset or the path of a .hcchr file
+	CustomCharsets [4]string
+	// Tuning are the task's tuning options after the worker's defaults & ceilings have been applied
+	Tuning shared.HashcatTuningOptions
 
 	engine *gocat.Hashcat
 	// if isBruteForce is true, we'll allow for a checkpoint
 	isBruteForce bool
+	// extraInputs are the positional arguments after the first dictionary or mask
+	extraInputs []string
 }
 
 // Initialize the hashcat engine
@@ -172,27 +184,76 @@ func (s *HashcatEngine) Start() error {
 	} else {
 		// Not a restore
 		opts = hcargp.HashcatSessionOptions{
-			AttackMode:             hcargp.GetIntPtr(int(s.Options.AttackMode)),
-			HashType:               hcargp.GetIntPtr(s.Options.HashType),
-			PotfileDisable:         hcargp.GetBoolPtr(true),
-			InputFile:              s.TaskFilePath,
-			SessionName:            hcargp.GetStringPtr(s.TaskID),
-			RestoreFilePath:        hcargp.GetStringPtr(restoreFilePath),
-			OutfilePath:            hcargp.GetStringPtr(outFilePath),
-			OptimizedKernelEnabled: hcargp.GetBoolPtr(true),
+			AttackMode:      hcargp.GetIntPtr(int(s.Options.AttackMode)),
+			HashType:        hcargp.GetIntPtr(s.Options.HashType),
+			PotfileDisable:  hcargp.GetBoolPtr(true),
+			InputFile:       s.TaskFilePath,
+			SessionName:     hcargp.GetStringPtr(s.TaskID),
+			RestoreFilePath: hcargp.GetStringPtr(restoreFilePath),
+			OutfilePath:     hcargp.GetStringPtr(outFilePath),
 		}
+		s.setTuning(&opts)
 
-		if s.MasksFile != "" && s.Options.AttackMode == shared.AttackModeBruteForce {
-			opts.DictionaryMaskDirectoryInput = hcargp.GetStringPtr(s.MasksFile)
-			s.isBruteForce = true
+		if len(s.Options.InlineMasks) > 0 {
+			s.MasksFile = filepath.Join(s.SessionPath, fmt.Sprintf("%s.inline.hcmask", s.TaskID))
+			if err := writeInlineMasks(s.MasksFile, s.Options.InlineMasks); err != nil {
+				return err
+			}
+			defer os.Remove(s.MasksFile)
 		}
 
-		if s.RulesFile != "" && s.Options.AttackMode == shared.AttackModeStraight {
-			opts.RulesFile = hcargp.GetStringPtr(s.RulesFile)
+		if s.Options.AttackMode.UsesMasks() {
+			s.setMaskOptions(&opts)
+		}
+
+		switch s.Options.AttackMode {
+		case shared.AttackModeBruteForce:
+			if s.MasksFile == "" {
+				break
+			}
+			masksFile := s.MasksFile
+
+			// hashcat does not allow --skip/--limit with a mask file so the unit's masks are written to their own file
+			if s.Skip != 0 || s.Limit != 0 {
+				masksFile = filepath.Join(s.SessionPath, fmt.Sprintf("%s.hcmask", s.TaskID))
+				numMasks, err := writeMaskRange(s.MasksFile, masksFile, s.Skip, s.Limit)
+				if err != nil {
+					return err
+				}
+				defer os.Remove(masksFile)
+
+				if numMasks == 0 {
+					return s.exhaustEmptyUnit()
+				}
+			}
+
+			opts.DictionaryMaskDirectoryInput = hcargp.GetStringPtr(masksFile)
+			s.isBruteForce = true
+		case shared.AttackModeStraight, shared.AttackModeAssociation:
+			if s.RulesFile != "" {
+				opts.RulesFile = hcargp.GetStringPtr(s.RulesFile)
+			}
+			s.setInputs(&opts, s.DictionaryFile)
+		case shared.AttackModeCombinator:
+			s.setRules(&opts)
+			s.setInputs(&opts, s.DictionaryFile, s.RightDictionaryFile)
+		case shared.AttackModeHybridDictMask:
+			s.setRules(&opts)
+			s.setInputs(&opts, s.DictionaryFile, s.MasksFile)
+		case shared.AttackModeHybridMaskDict:
+			s.setRules(&opts)
+			s.setInputs(&opts, s.MasksFile, s.DictionaryFile)
 		}
 
-		if s.DictionaryFile != "" && s.Options.AttackMode == shared.AttackModeStraight {
-			opts.DictionaryMaskDirectoryInput = hcargp.GetStringPtr(s.DictionaryFile)
+		// the left dictionary is the base of these attacks so work units are split on its words
+		if s.Options.AttackMode.SplitsOnDictionary() {
+			if s.Skip != 0 {
+				opts.Skip = hcargp.GetIntPtr(int(s.Skip))
+			}
+
+			if s.Limit != 0 {
+				opts.Limit = hcargp.GetIntPtr(int(s.Limit))
+			}
 		}
 
 		if len(s.CLDevices) > 0 {
@@ -227,8 +288,71 @@ func (s *HashcatEngine) Start() error {
 	runtime.LockOSThread()
 	defer runtime.UnlockOSThread()
 
+	args, err := opts.MarshalArgs()
+	if err != nil {
+		return err
+	}
+
 	defer saveCheckpoint(s.Upstream, s.TaskID, restoreFilePath)
-	return s.engine.RunJobWithOptions(opts)
+	return s.engine.RunJob(append(args, s.extraInputs...)...)
+}
+
+// setInputs sets the dictionaries and/or masks the attack is run with. hcargp only has a single input so any
+// others (e.g. the right dictionary of a combinator attack) are appended to the arguments after they are marshalled
+func (s *HashcatEngine) setInputs(opts *hcargp.HashcatSessionOptions, inputs ...string) {
+	opts.DictionaryMaskDirectoryInput = hcargp.GetStringPtr(inputs[0])
+	s.extraInputs = inputs[1:]
+}
+
+// setRules sets the single rules applied to the left & right side of a combinator or hybrid attack
+func (s *HashcatEngine) setRules(opts *hcargp.HashcatSessionOptions) {
+	if s.Options.RuleLeft != nil && *s.Options.RuleLeft != "" {
+		opts.RuleLeft = s.Options.RuleLeft
+	}
+
+	if s.Options.RuleRight != nil && *s.Options.RuleRight != "" {
+		opts.RuleRight = s.Options.RuleRight
+	}
+}
+
+// setTuning sets the allow-listed tuning options. Optimized kernels are enabled unless the task or worker disables them
+func (s *HashcatEngine) setTuning(opts *hcargp.HashcatSessionOptions) {
+	opts.OptimizedKernelEnabled = hcargp.GetBoolPtr(s.Tuning.OptimizedKernels == nil || *s.Tuning.OptimizedKernels)
+	opts.WorkloadProfile = s.Tuning.WorkloadProfile
+	opts.KernelAccel = s.Tuning.KernelAccel
+	opts.KernelLoops = s.Tuning.KernelLoops
+	opts.HWMonitorTempAbort = s.Tuning.HWMonTempAbort
+	opts.MaxRuntimeSeconds = s.Tuning.RuntimeSeconds
+
+	if s.Tuning.Username {
+		opts.IgnoreUsername = hcargp.GetBoolPtr(true)
+	}
+
+	if s.Tuning.HexSalt {
+		opts.IsHexSalt = hcargp.GetBoolPtr(true)
+	}
+
+	if s.Tuning.HexCharset {
+		opts.IsHexCharset = hcargp.GetBoolPtr(true)
+	}
+}
+
+// setMaskOptions sets the custom charsets & increment options used by the masks
+func (s *HashcatEngine) setMaskOptions(opts *hcargp.HashcatSessionOptions) {
+	charsets := []**string{&opts.CustomCharset1, &opts.CustomCharset2, &opts.CustomCharset3, &opts.CustomCharset4}
+	for i, charset := range s.CustomCharsets {
+		if charset != "" {
+			*charsets[i] = hcargp.GetStringPtr(charset)
+		}
+	}
+
+	if !s.Options.Increment {
+		return
+	}
+
+	opts.IncrementMask = hcargp.GetBoolPtr(true)
+	opts.IncrementMaskMin = s.Options.IncrementMin
+	opts.IncrementMaskMax = s.Options.IncrementMax
 }
 
 // Stop the hashcat engine. If the engine is brute forcing, we attempt to stop at a checkpoint otherwise we abort
@@ -253,6 +377,27 @@ func (s *HashcatEngine) GetStatus() interface{} {
 	return s.engine.GetStatus()
 }
 
+// Progress returns the percentage of the keyspace that has been processed as reported by hashcat
+func (s *HashcatEngine) Progress() float64 {
+	if s.engine == nil {
+		return 0
+	}
+
+	status := s.engine.GetStatus()
+	if status == nil {
+		return 0
+	}
+	return parseProgress(status.Progress)
+}
+
+// exhaustEmptyUnit reports a work unit that does not contain any masks as exhausted without starting hashcat
+func (s *HashcatEngine) exhaustEmptyUnit() error {
+	return s.Upstream.ChangeTaskStatus(rpc.ChangeTaskStatusRequest{
+		TaskID:    s.TaskID,
+		NewStatus: storage.TaskStatusExhausted,
+	})
+}
+
 // Cleanup releases the engine and cleans up any allocated resources
 func (s *HashcatEngine) Cleanup() {
 	s.engine.Free()
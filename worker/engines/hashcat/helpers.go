@@ -1,8 +1,11 @@
 package hashcat
 
 import (
+	"bufio"
+	"fmt"
 	"io/ioutil"
 	"os"
+	"strings"
 
 	"github.com/mandiant/gocat/v6/restoreutil"
 	"github.com/mandiant/gocrack/server/rpc"
@@ -84,3 +87,76 @@ Cleanup:
 	}
 	return err
 }
+
+// writeMaskRange copies the masks on lines [skip, skip+limit) of src to dst and returns the number of masks written.
+// A limit of 0 copies every line after skip
+func writeMaskRange(src, dst string, skip, limit uint64) (int, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return 0, err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return 0, err
+	}
+	defer out.Close()
+
+	numMasks := 0
+	lineNo := uint64(0)
+	scanner := bufio.NewScanner(in)
+	for ; scanner.Scan(); lineNo++ {
+		if lineNo < skip || (limit != 0 && lineNo-skip >= limit) {
+			continue
+		}
+
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if _, err := fmt.Fprintln(out, line); err != nil {
+			return 0, err
+		}
+		numMasks++
+	}
+
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return numMasks, out.Sync()
+}
+
+// writeInlineMasks writes the masks of a task to a mask file. Commas & leading hashes are escaped so hashcat does not
+// read them as custom charset separators or comments
+func writeInlineMasks(dst string, masks []string) error {
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	for _, mask := range masks {
+		mask = strings.ReplaceAll(mask, ",", "\\,")
+		if strings.HasPrefix(mask, "#") {
+			mask = "\\" + mask
+		}
+
+		if _, err := fmt.Fprintln(out, mask); err != nil {
+			return err
+		}
+	}
+	return out.Sync()
+}
+
+// parseProgress extracts the percentage from hashcat's progress string (e.g. "1024/4096 (25.00%)")
+func parseProgress(progress string) float64 {
+	var cur, total uint64
+	var percent float64
+
+	if _, err := fmt.Sscanf(progress, "%d/%d (%f%%)", &cur, &total, &percent); err != nil {
+		return 0
+	}
+	return percent
+}
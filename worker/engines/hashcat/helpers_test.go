@@ -0,0 +1,52 @@
+package hashcat
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteMaskRange(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "masks.hcmask")
+	if err := os.WriteFile(src, []byte("?d?d\n# comment\n?l?l\n?u?u\n?s?s\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		Skip     uint64
+		Limit    uint64
+		Expected string
+	}{
+		{Skip: 0, Limit: 2, Expected: "?d?d\n"},
+		{Skip: 2, Limit: 2, Expected: "?l?l\n?u?u\n"},
+		{Skip: 3, Limit: 0, Expected: "?u?u\n?s?s\n"},
+		{Skip: 5, Limit: 1, Expected: ""},
+	} {
+		dst := filepath.Join(dir, "unit.hcmask")
+		numMasks, err := writeMaskRange(src, dst, test.Skip, test.Limit)
+		assert.Nil(t, err)
+
+		b, err := os.ReadFile(dst)
+		assert.Nil(t, err)
+		assert.Equal(t, test.Expected, string(b))
+		assert.Equal(t, len(test.Expected)/5, numMasks)
+	}
+}
+
+func TestParseProgress(t *testing.T) {
+	assert.Equal(t, 25.0, parseProgress("1024/4096 (25.00%)"))
+	assert.Equal(t, 0.0, parseProgress("1024"))
+	assert.Equal(t, 0.0, parseProgress(""))
+}
+
+func TestWriteInlineMasks(t *testing.T) {
+	dst := filepath.Join(t.TempDir(), "inline.hcmask")
+	assert.Nil(t, writeInlineMasks(dst, []string{"?d?d", "?l,?u", "#?1"}))
+
+	b, err := os.ReadFile(dst)
+	assert.Nil(t, err)
+	assert.Equal(t, "?d?d\n?l\\,?u\n\\#?1\n", string(b))
+}
@@ -0,0 +1,68 @@
+package hashcat
+
+import (
+	"github.com/mandiant/gocrack/server/storage"
+	"github.com/mandiant/gocrack/shared"
+	// the engine must be defined before its implementation is registered
+	_ "github.com/mandiant/gocrack/shared/enginedef/hashcat"
+	"github.com/mandiant/gocrack/worker/engines"
+)
+
+func init() {
+	engines.RegisterImplementation(storage.WorkerHashcatEngine, HashcatVersion, newEngine)
+}
+
+func newEngine(ctx engines.TaskContext) (engines.EngineImpl, error) {
+	opts := ctx.Payload.(shared.HashcatUserOptions)
+
+	hc := &HashcatEngine{
+		TaskID:            ctx.TaskID,
+		SessionPath:       ctx.Config.Hashcat.SessionPath,
+		HashcatSharedPath: ctx.Config.Hashcat.SharedPath,
+		TaskFilePath:      ctx.TaskFilePath,
+		Options:           opts,
+		CLDevices:         ctx.Devices,
+		Upstream:          ctx.Upstream,
+	}
+
+	var tuning shared.HashcatTuningOptions
+	if opts.Tuning != nil {
+		tuning = *opts.Tuning
+	}
+	hc.Tuning = tuning.Apply(ctx.Config.Hashcat.Tuning.Defaults, ctx.Config.Hashcat.Tuning.Ceilings)
+
+	if ctx.WorkUnit != nil {
+		hc.Skip = ctx.WorkUnit.Skip
+		hc.Limit = ctx.WorkUnit.Limit
+	}
+
+	if opts.DictionaryFile != nil {
+		hc.DictionaryFile = ctx.EngineFiles[*opts.DictionaryFile]
+	}
+
+	if opts.RightDictionaryFile != nil {
+		hc.RightDictionaryFile = ctx.EngineFiles[*opts.RightDictionaryFile]
+	}
+
+	if opts.Masks != nil {
+		hc.MasksFile = ctx.EngineFiles[*opts.Masks]
+	}
+
+	if opts.ManglingRuleFile != nil {
+		hc.RulesFile = ctx.EngineFiles[*opts.ManglingRuleFile]
+	}
+
+	for i, charset := range opts.CustomCharsets {
+		if i >= len(hc.CustomCharsets) {
+			break
+		}
+
+		if charset.FileID != "" {
+			// hashcat reads the charset from the file when given a path
+			hc.CustomCharsets[i] = ctx.EngineFiles[charset.FileID]
+		} else {
+			hc.CustomCharsets[i] = charset.Charset
+		}
+	}
+	return hc, nil
+}
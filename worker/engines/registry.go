@@ -0,0 +1,85 @@
+package engines
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/mandiant/gocrack/server/rpc"
+	"github.com/mandiant/gocrack/server/storage"
+	"github.com/mandiant/gocrack/shared"
+	"github.com/mandiant/gocrack/shared/enginedef"
+	"github.com/mandiant/gocrack/worker"
+)
+
+// TaskContext contains everything a Factory needs to create an engine for a task
+type TaskContext struct {
+	TaskID       string
+	TaskFilePath string
+	Payload      enginedef.Payload
+	// EngineFiles maps the IDs returned from Payload.EngineFiles() to the downloaded file on disk
+	EngineFiles map[string]string
+	Devices     storage.CLDevices
+	Upstream    rpc.GoCrackRPC
+	Config      *worker.Config
+	// WorkUnit restricts the engine to a range of the task's dictionary words or masks. It is nil unless the task is distributed
+	WorkUnit *rpc.WorkUnitRange
+}
+
+// Factory creates the engine implementation that runs a task
+type Factory func(TaskContext) (EngineImpl, error)
+
+// implementation is registered by the package containing the engine as it may not be linked into every binary
+type implementation struct {
+	version string
+	factory Factory
+}
+
+var (
+	mu              sync.RWMutex
+	implementations = make(map[storage.WorkerCrackEngine]implementation)
+)
+
+// RegisterImplementation links the worker side of an engine to its definition in enginedef. If RegisterImplementation is
+// called twice with the same ID or the engine has not been defined, it panics
+func RegisterImplementation(id storage.WorkerCrackEngine, version string, factory Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if factory == nil {
+		panic("engines: RegisterImplementation factory is nil")
+	}
+
+	if _, ok := enginedef.Lookup(id); !ok {
+		panic(fmt.Sprintf("engines: RegisterImplementation called for undefined engine %d", id))
+	}
+
+	if _, dup := implementations[id]; dup {
+		panic(fmt.Sprintf("engines: RegisterImplementation called twice for engine %d", id))
+	}
+	implementations[id] = implementation{version: version, factory: factory}
+}
+
+// Versions returns the version of every engine implementation linked into this binary, keyed by the engine's name
+func Versions() shared.EngineVersion {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	out := make(shared.EngineVersion, len(implementations))
+	for id, impl := range implementations {
+		def, _ := enginedef.Lookup(id)
+		out[def.Name] = impl.version
+	}
+	return out
+}
+
+// New creates an instance of the engine for a task
+func New(id storage.WorkerCrackEngine, ctx TaskContext) (EngineImpl, error) {
+	mu.RLock()
+	impl, ok := implementations[id]
+	mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("engine %d is not available on this worker", id)
+	}
+	return impl.factory(ctx)
+}
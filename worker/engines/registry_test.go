@@ -0,0 +1,72 @@
+package engines
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/mandiant/gocrack/server/storage"
+	"github.com/mandiant/gocrack/shared/enginedef"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testEngineID storage.WorkerCrackEngine = 1 << 7
+
+type testPayload struct {
+	Dictionary string `json:"dictionary"`
+}
+
+func (s testPayload) Validate() []string {
+	return nil
+}
+
+func (s testPayload) EngineFiles() []string {
+	return []string{s.Dictionary}
+}
+
+type testEngine struct {
+	ctx TaskContext
+}
+
+func (s *testEngine) Initialize() error      { return nil }
+func (s *testEngine) Start() error           { return nil }
+func (s *testEngine) Stop() error            { return nil }
+func (s *testEngine) GetStatus() interface{} { return nil }
+func (s *testEngine) Cleanup()               {}
+
+func TestRegisterImplementation(t *testing.T) {
+	enginedef.Register(enginedef.Definition{
+		ID:          testEngineID,
+		Name:        "test",
+		DisplayName: "Test",
+		DecodePayload: func(raw json.RawMessage) (enginedef.Payload, error) {
+			var pl testPayload
+			err := json.Unmarshal(raw, &pl)
+			return pl, err
+		},
+	})
+
+	// the definition is usable by the server before any implementation is linked in
+	_, err := New(testEngineID, TaskContext{})
+	assert.NotNil(t, err)
+	_, hasVersion := Versions()["test"]
+	assert.False(t, hasVersion)
+
+	RegisterImplementation(testEngineID, "1.2.3", func(ctx TaskContext) (EngineImpl, error) {
+		return &testEngine{ctx: ctx}, nil
+	})
+	assert.Equal(t, "1.2.3", Versions()["test"])
+	assert.Panics(t, func() {
+		RegisterImplementation(testEngineID, "1.2.3", func(TaskContext) (EngineImpl, error) { return nil, nil })
+	})
+	assert.Panics(t, func() {
+		RegisterImplementation(storage.WorkerCrackEngine(255), "1.0", func(TaskContext) (EngineImpl, error) { return nil, nil })
+	})
+
+	pl, err := enginedef.DecodePayload(testEngineID, json.RawMessage(`{"dictionary": "words"}`))
+	assert.Nil(t, err)
+
+	impl, err := New(testEngineID, TaskContext{TaskID: "task", Payload: pl})
+	assert.Nil(t, err)
+	assert.Equal(t, "task", impl.(*testEngine).ctx.TaskID)
+}
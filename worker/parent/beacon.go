@@ -9,14 +9,14 @@ import (
 	"github.com/mandiant/gocrack/opencl"
 	"github.com/mandiant/gocrack/server/rpc"
 	"github.com/mandiant/gocrack/server/storage"
-	"github.com/mandiant/gocrack/shared"
+	"github.com/mandiant/gocrack/shared/enginedef"
 	"github.com/mandiant/gocrack/worker"
-	"github.com/mandiant/gocrack/worker/engines/hashcat"
+	"github.com/mandiant/gocrack/worker/engines"
 
 	"github.com/rs/zerolog/log"
 )
 
-func (s *Worker) createTask(newTask rpc.NewTask) {
+func (s *Worker) createTask(hostname string, newTask rpc.NewTask) {
 	maxNumGPUs := *s.cfg.GPUPriorityAssignment.Normal
 
 	switch newTask.Priority {
@@ -28,6 +28,20 @@ func (s *Worker) createTask(newTask rpc.NewTask) {
 		maxNumGPUs = *s.cfg.GPUPriorityAssignment.Low
 	}
 
+	// Engines that run on every core of the host reserve a CPU device instead of GPUs
+	if def, ok := enginedef.Lookup(newTask.Engine); ok && def.CPUOnly && newTask.Devices == nil {
+		freeCPUs := s.devices.PickFreeDevices(opencl.DeviceTypeCPU, 1)
+		if len(freeCPUs) == 0 {
+			return
+		}
+
+		log.Info().
+			Interface("devices", freeCPUs).
+			Str("task_id", newTask.ID).
+			Msg("Assigned CPU to task running in a CPU only engine")
+		newTask.Devices = freeCPUs
+	}
+
 	// Pick some GPUs to run the task on...
 	if newTask.Devices == nil {
 		freeGPUs := s.devices.PickFreeDevices(opencl.DeviceTypeGPU, maxNumGPUs)
@@ -56,8 +70,11 @@ func (s *Worker) createTask(newTask rpc.NewTask) {
 	}
 
 	if err := s.rc.ChangeTaskStatus(rpc.ChangeTaskStatusRequest{
-		TaskID:    newTask.ID,
-		NewStatus: storage.TaskStatusDequeued,
+		TaskID:     newTask.ID,
+		WorkUnitID: newTask.WorkUnitID,
+		NewStatus:  storage.TaskStatusDequeued,
+		Hostname:   hostname,
+		Devices:    newTask.Devices,
 	}); err != nil {
 		log.Error().
 			Err(err).
@@ -71,8 +88,13 @@ func (s *Worker) createTask(newTask rpc.NewTask) {
 	s.procs.RegisterProcess(m, newTask.ID, []int(newTask.Devices))
 
 	args := append(os.Args[1:], []string{"--worker", "--taskid", newTask.ID, "--devices", newTask.Devices.String()}...)
+	if newTask.WorkUnitID != "" {
+		args = append(args, "--unit", newTask.WorkUnitID)
+	}
+
 	log.Info().
 		Str("task_id", newTask.ID).
+		Str("unit_id", newTask.WorkUnitID).
 		Str("devices", newTask.Devices.String()).
 		Str("process", os.Args[0]).
 		Str("args", strings.Join(args, " ")).
@@ -118,9 +140,7 @@ Loop:
 			Devices:        s.devices,
 			RequestNewTask: s.devices.HasFreeDevices(),
 			Processes:      s.procs.GetBeaconInfo(),
-			Engines: shared.EngineVersion{ // XXX(cschmitt): This should probably be defined automatically
-				"hashcat": hashcat.HashcatVersion,
-			},
+			Engines:        engines.Versions(),
 		})
 
 		if err != nil {
@@ -142,7 +162,7 @@ Loop:
 					continue
 				}
 				log.Debug().Str("TaskID", pl.ID).Msg("Beacon contains request to process a new task")
-				s.createTask(pl)
+				s.createTask(hostname, pl)
 			case rpc.BeaconChangeTaskStatus:
 				var pl rpc.ChangeTaskStatus
 				if err := json.Unmarshal(item.Data, &pl); err != nil {